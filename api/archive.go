@@ -0,0 +1,175 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alonecandies/golwarc/warc"
+)
+
+// ArchiveHandler serves CDX lookups and replays archived records
+type ArchiveHandler struct {
+	index *warc.CDXIndex
+}
+
+// NewArchiveHandler creates a handler over a loaded CDX index
+func NewArchiveHandler(index *warc.CDXIndex) *ArchiveHandler {
+	return &ArchiveHandler{index: index}
+}
+
+// Register adds the archive routes
+func (h *ArchiveHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/cdx", h.lookup)
+	mux.HandleFunc("GET /api/v1/replay", h.replay)
+}
+
+// Capture is the JSON form of a CDX entry
+type Capture struct {
+	URL       string `json:"url"`
+	Timestamp string `json:"timestamp"`
+	MIME      string `json:"mime,omitempty"`
+	Status    int    `json:"status,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	Filename  string `json:"filename"`
+	Offset    int64  `json:"offset"`
+	Length    int64  `json:"length"`
+}
+
+// Endpoints documents the archive routes
+func (h *ArchiveHandler) Endpoints() []Endpoint {
+	urlParam := QueryParam("url", "string", "Archived URL", true)
+	return []Endpoint{
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/cdx",
+			Operation: Operation{
+				OperationID: "listCaptures",
+				Summary:     "List archived captures of a URL, oldest first",
+				Tags:        []string{"archive"},
+				Parameters: []Parameter{
+					urlParam,
+					QueryParam("limit", "integer", "Return only the most recent captures", false),
+				},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Captures of the URL", ArrayOf(ModelSchema(Capture{}))),
+					"400": ErrorResponseDoc("Missing or invalid url"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/replay",
+			Operation: Operation{
+				OperationID: "replayCapture",
+				Summary:     "Replay the capture of a URL closest to a timestamp",
+				Tags:        []string{"archive"},
+				Parameters: []Parameter{
+					urlParam,
+					QueryParam("timestamp", "string", "CDX timestamp (yyyyMMddHHmmss prefix); defaults to now", false),
+				},
+				Responses: map[string]*Response{
+					"200": {
+						Description: "Archived record content",
+						Headers: map[string]*Header{
+							"Memento-Datetime":    {Description: "Capture time", Schema: &Schema{Type: "string"}},
+							"X-Archive-Record-ID": {Description: "WARC record ID", Schema: &Schema{Type: "string"}},
+						},
+						Content: map[string]*MediaType{
+							"application/http": {Schema: &Schema{Type: "string", Format: "binary"}},
+							"*/*":              {Schema: &Schema{Type: "string", Format: "binary"}},
+						},
+					},
+					"400": ErrorResponseDoc("Missing url or invalid timestamp"),
+					"404": ErrorResponseDoc("No capture found, or the original of a revisit is missing"),
+				},
+			},
+		},
+	}
+}
+
+// lookup lists captures of ?url=, optionally limited by ?limit=
+func (h *ArchiveHandler) lookup(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("url")
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "url parameter is required")
+		return
+	}
+
+	entries, err := h.index.Lookup(target)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit > 0 && limit < len(entries) {
+		entries = entries[len(entries)-limit:] // Most recent captures
+	}
+
+	captures := make([]Capture, 0, len(entries))
+	for _, e := range entries {
+		captures = append(captures, Capture{
+			URL:       e.URL,
+			Timestamp: e.Timestamp.UTC().Format(warc.CDXTimestampFormat),
+			MIME:      e.MIME,
+			Status:    e.Status,
+			Digest:    e.Digest,
+			Filename:  e.Filename,
+			Offset:    e.Offset,
+			Length:    e.Length,
+		})
+	}
+	writeJSON(w, http.StatusOK, captures)
+}
+
+// replay streams the capture of ?url= closest to ?timestamp= (default: latest)
+func (h *ArchiveHandler) replay(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("url")
+	if target == "" {
+		writeError(w, http.StatusBadRequest, "url parameter is required")
+		return
+	}
+
+	at := time.Now()
+	if stamp := r.URL.Query().Get("timestamp"); stamp != "" {
+		parsed, err := warc.ParseCDXTimestamp(stamp)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		at = parsed
+	}
+
+	entry, found, err := h.index.Closest(target, at)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "no capture found")
+		return
+	}
+
+	// Revisits are served from the capture holding their payload
+	original, rec, err := h.index.Resolve(entry)
+	if errors.Is(err, warc.ErrUnresolvedRevisit) {
+		writeError(w, http.StatusNotFound, "original capture not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to read archived record")
+		return
+	}
+
+	w.Header().Set("Memento-Datetime", entry.Timestamp.UTC().Format(http.TimeFormat))
+	w.Header().Set("X-Archive-Record-ID", rec.Header.Get(warc.HeaderRecordID))
+	if rec.Type() == warc.TypeResponse {
+		// Response records carry the full HTTP message
+		w.Header().Set("Content-Type", "application/http; msgtype=response")
+	} else if original.MIME != "" {
+		w.Header().Set("Content-Type", original.MIME)
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(rec.Content) // Error intentionally ignored; client may disconnect
+}
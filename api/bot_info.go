@@ -0,0 +1,81 @@
+package api
+
+import (
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// BotInfoPath is where BotInfoHandler describes the crawler; point
+// crawler.identity.info_url at it when the API is public
+const BotInfoPath = "/.well-known/crawler-info"
+
+// BotInfoHandler serves a page describing the crawler to site owners, as
+// HTML, or as JSON when the request accepts application/json
+type BotInfoHandler struct {
+	info BotInfo
+}
+
+// BotInfo is the JSON form of the crawler description
+type BotInfo struct {
+	Name        string `json:"name"`
+	Version     string `json:"version,omitempty"`
+	UserAgent   string `json:"user_agent"`
+	RobotsAgent string `json:"robots_agent"` // Name to address in robots.txt groups and robots meta tags
+	InfoURL     string `json:"info_url,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Operator    string `json:"operator,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// NewBotInfoHandler creates a handler describing identity
+func NewBotInfoHandler(identity *crawlers.BotIdentity) *BotInfoHandler {
+	return &BotInfoHandler{info: BotInfo{
+		Name:        identity.Name,
+		Version:     identity.Version,
+		UserAgent:   identity.UserAgent(),
+		RobotsAgent: identity.Agent(),
+		InfoURL:     identity.InfoURL,
+		Email:       identity.Email,
+		Operator:    identity.Operator,
+		Description: identity.Description,
+	}}
+}
+
+// Register adds the bot info route
+func (h *BotInfoHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+BotInfoPath, h.serve)
+}
+
+// serve writes the description in the format the request accepts
+func (h *BotInfoHandler) serve(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.Header.Get("Accept"), "application/json") {
+		writeJSON(w, http.StatusOK, h.info)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = botInfoPage.Execute(w, h.info) // Error intentionally ignored; headers are already sent
+}
+
+// botInfoPage is the HTML description
+var botInfoPage = template.Must(template.New("bot").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.Name}}</title></head>
+<body>
+<h1>{{.Name}}</h1>
+{{if .Description}}<p>{{.Description}}</p>{{end}}
+{{if .Operator}}<p>Operated by {{.Operator}}.</p>{{end}}
+<h2>Identifying requests</h2>
+<p>Requests carry the user agent <code>{{.UserAgent}}</code>.</p>
+<h2>Controlling access</h2>
+<p>The crawler obeys robots.txt. Rules for <code>{{.RobotsAgent}}</code> apply to it, else those for <code>*</code>:</p>
+<pre>User-agent: {{.RobotsAgent}}
+Disallow: /private/</pre>
+<p>It also honors <code>Crawl-delay</code>, and <code>&lt;meta name="robots"&gt;</code> or <code>X-Robots-Tag</code> directives addressed to <code>{{.RobotsAgent}}</code>.</p>
+{{if .Email}}<h2>Contact</h2>
+<p>Write to <a href="mailto:{{.Email}}">{{.Email}}</a> to report a problem or ask for the crawler to be allowed or blocked.</p>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,250 @@
+// Package client is a typed Go client for the golwarc HTTP API
+// Each method corresponds to one operation of the OpenAPI document served at
+// /api/v1/openapi.json and is named after its operationId. The methods are
+// generated from docs/openapi.json into client_gen.go; this file holds the
+// runtime they call
+package client
+
+//go:generate go run ../../scripts/openapi -client ../../docs/openapi.json -out client_gen.go
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Config holds API client configuration
+type Config struct {
+	BaseURL    string       // e.g. http://localhost:8080
+	HTTPClient *http.Client // Defaults to a client without timeout so exports can stream
+	UserAgent  string
+}
+
+// Client calls the golwarc HTTP API
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+}
+
+// APIError is returned for non-2xx responses
+type APIError struct {
+	StatusCode int
+	Code       errs.Code // Empty when the server sent no code
+	Message    string
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	return fmt.Sprintf("api error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// ErrorCode implements errs.Coder so errs.CodeOf works on client errors
+func (e *APIError) ErrorCode() errs.Code {
+	if e.Code == "" {
+		return errs.CodeForHTTPStatus(e.StatusCode)
+	}
+	return e.Code
+}
+
+// NewClient creates a new API client
+func NewClient(config Config) (*Client, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+	base, err := url.Parse(strings.TrimSuffix(config.BaseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{}
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "golwarc-client/" + api.APIVersion
+	}
+
+	return &Client{
+		baseURL:    base,
+		httpClient: config.HTTPClient,
+		userAgent:  config.UserAgent,
+	}, nil
+}
+
+// ExportStream decodes an NDJSON export one row at a time
+type ExportStream[T any] struct {
+	resp    *http.Response
+	scanner *bufio.Scanner
+	current T
+	err     error
+	done    bool
+}
+
+// openStream starts an NDJSON request
+func openStream[T any](ctx context.Context, c *Client, method, path string, query url.Values) (*ExportStream[T], error) {
+	resp, err := c.do(ctx, method, path, query, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// The transport decompresses transparently unless compression is disabled on it
+	body := io.Reader(resp.Body)
+	if resp.Header.Get("Content-Encoding") == "gzip" && !resp.Uncompressed {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			_ = resp.Body.Close() // Best effort cleanup
+			return nil, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		body = gz
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024) // Pages may carry large HTML bodies
+	return &ExportStream[T]{resp: resp, scanner: scanner}, nil
+}
+
+// Next advances to the next row; it returns false at the end or on error
+func (s *ExportStream[T]) Next() bool {
+	if s.done {
+		return false
+	}
+	for s.scanner.Scan() {
+		line := s.scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var row T
+		if err := json.Unmarshal(line, &row); err != nil {
+			s.err = fmt.Errorf("failed to decode export row: %w", err)
+			s.done = true
+			return false
+		}
+		s.current = row
+		return true
+	}
+	s.err = s.scanner.Err()
+	s.done = true
+	return false
+}
+
+// Row returns the current row
+func (s *ExportStream[T]) Row() T {
+	return s.current
+}
+
+// Err returns the error that stopped the stream, if any
+func (s *ExportStream[T]) Err() error {
+	return s.err
+}
+
+// NextCursor returns the resume cursor when the server stopped early
+// It is only available after Next has returned false
+func (s *ExportStream[T]) NextCursor() string {
+	if !s.done {
+		return ""
+	}
+	return s.resp.Trailer.Get("X-Next-Cursor")
+}
+
+// Close releases the underlying connection
+func (s *ExportStream[T]) Close() error {
+	return s.resp.Body.Close()
+}
+
+// doJSON performs a request, sending in (if not nil) as a JSON body and
+// decoding the JSON response into out (if not nil)
+func (c *Client) doJSON(ctx context.Context, method, path string, query url.Values, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	resp, err := c.do(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// readAll performs a request and reads the whole response body
+func (c *Client) readAll(ctx context.Context, method, path string, query url.Values) (*http.Response, []byte, error) {
+	resp, err := c.do(ctx, method, path, query, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	content, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return resp, content, nil
+}
+
+// do performs a request and converts error statuses to *APIError
+// A non-nil body is sent as JSON
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader) (*http.Response, error) {
+	endpoint := *c.baseURL
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.userAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer func() {
+			_ = resp.Body.Close() // Error intentionally ignored on close
+		}()
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		var body api.ErrorResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.Error != "" {
+			apiErr.Message = body.Error
+			apiErr.Code = errs.Code(body.Code)
+		}
+		return nil, apiErr
+	}
+	return resp, nil
+}
+
+// IsNotFound reports whether err is an API 404 response
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
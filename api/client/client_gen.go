@@ -0,0 +1,338 @@
+// Code generated by scripts/openapi from docs/openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+)
+
+// CompareCrawlsParams holds the query parameters of CompareCrawls
+type CompareCrawlsParams struct {
+	Base   string // Job ID or crawl ID of the earlier crawl (required)
+	Target string // Job ID or crawl ID of the later crawl (required)
+	Domain string // Only compare this domain
+}
+
+// CompareCrawls calls GET /api/v1/crawls/compare
+// Diff the URLs, statuses and content of two crawls
+func (c *Client) CompareCrawls(ctx context.Context, params CompareCrawlsParams) (*services.SnapshotDiff, error) {
+	query := url.Values{}
+	query.Set("base", params.Base)
+	query.Set("target", params.Target)
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+
+	var out services.SnapshotDiff
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/crawls/compare", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ExportPagesParams holds the query parameters of ExportPages
+type ExportPagesParams struct {
+	Project string // Only pages of this project
+	Domain  string // Only pages of this domain
+	Cursor  string // Resume after the row encoded by a previous X-Next-Cursor
+	Batch   int    // Rows fetched per database query (max 5000)
+	Limit   int    // Maximum number of rows to export
+	Gzip    bool   // Gzip the stream (1 or true)
+}
+
+// ExportPages calls GET /api/v1/export/pages
+// Stream pages as NDJSON
+func (c *Client) ExportPages(ctx context.Context, params ExportPagesParams) (*ExportStream[models.Page], error) {
+	query := url.Values{}
+	if params.Project != "" {
+		query.Set("project", params.Project)
+	}
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	if params.Batch != 0 {
+		query.Set("batch", strconv.Itoa(params.Batch))
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Gzip {
+		query.Set("gzip", strconv.FormatBool(params.Gzip))
+	}
+
+	return openStream[models.Page](ctx, c, http.MethodGet, "/api/v1/export/pages", query)
+}
+
+// ExportProductsParams holds the query parameters of ExportProducts
+type ExportProductsParams struct {
+	Category string // Only products in this category
+	Brand    string // Only products of this brand
+	Cursor   string // Resume after the row encoded by a previous X-Next-Cursor
+	Batch    int    // Rows fetched per database query (max 5000)
+	Limit    int    // Maximum number of rows to export
+	Gzip     bool   // Gzip the stream (1 or true)
+}
+
+// ExportProducts calls GET /api/v1/export/products
+// Stream products as NDJSON
+func (c *Client) ExportProducts(ctx context.Context, params ExportProductsParams) (*ExportStream[models.Product], error) {
+	query := url.Values{}
+	if params.Category != "" {
+		query.Set("category", params.Category)
+	}
+	if params.Brand != "" {
+		query.Set("brand", params.Brand)
+	}
+	if params.Cursor != "" {
+		query.Set("cursor", params.Cursor)
+	}
+	if params.Batch != 0 {
+		query.Set("batch", strconv.Itoa(params.Batch))
+	}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+	if params.Gzip {
+		query.Set("gzip", strconv.FormatBool(params.Gzip))
+	}
+
+	return openStream[models.Product](ctx, c, http.MethodGet, "/api/v1/export/products", query)
+}
+
+// GetCrawl calls GET /api/v1/crawls/{id}
+// Get the progress of a crawl job
+func (c *Client) GetCrawl(ctx context.Context, id string) (*api.CrawlJob, error) {
+	var out api.CrawlJob
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/crawls/"+id, nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetDomainStatsParams holds the query parameters of GetDomainStats
+type GetDomainStatsParams struct {
+	Project string    // Project of the crawls (default none)
+	Domain  string    // Only report this domain
+	Since   time.Time // Start of the window (default 24 hours ago)
+}
+
+// GetDomainStats calls GET /api/v1/stats/domains
+// Crawl latency percentiles, error rate, average page size and last success per domain, busiest first
+func (c *Client) GetDomainStats(ctx context.Context, params GetDomainStatsParams) ([]services.DomainCrawlStats, error) {
+	query := url.Values{}
+	if params.Project != "" {
+		query.Set("project", params.Project)
+	}
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+	if !params.Since.IsZero() {
+		query.Set("since", params.Since.Format(time.RFC3339))
+	}
+
+	var out []services.DomainCrawlStats
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/stats/domains", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOpenAPI calls GET /api/v1/openapi.json
+// OpenAPI document describing this API
+func (c *Client) GetOpenAPI(ctx context.Context) (*api.OpenAPIDocument, error) {
+	var out api.OpenAPIDocument
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/openapi.json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetScreenshot calls GET /api/v1/screenshots/{name}
+// Download a job screenshot
+func (c *Client) GetScreenshot(ctx context.Context, name string) ([]byte, error) {
+	_, content, err := c.readAll(ctx, http.MethodGet, "/api/v1/screenshots/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// GetSecurityReportParams holds the query parameters of GetSecurityReport
+type GetSecurityReportParams struct {
+	Project string // Project of the audited pages (default none)
+	Domain  string // Only report this domain
+}
+
+// GetSecurityReport calls GET /api/v1/security/report
+// Score the security headers of audited pages per domain, worst first
+func (c *Client) GetSecurityReport(ctx context.Context, params GetSecurityReportParams) ([]services.DomainSecurityReport, error) {
+	query := url.Values{}
+	if params.Project != "" {
+		query.Set("project", params.Project)
+	}
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+
+	var out []services.DomainSecurityReport
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/security/report", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetSkipStatsParams holds the query parameters of GetSkipStats
+type GetSkipStatsParams struct {
+	Project string    // Project of the crawls (default none)
+	Domain  string    // Only report this domain
+	Since   time.Time // Start of the window (default 24 hours ago)
+}
+
+// GetSkipStats calls GET /api/v1/stats/skips
+// Count the URLs crawls skipped by reason and rule (robots, url_rule, budget, duplicate, ssrf...), most frequent first
+func (c *Client) GetSkipStats(ctx context.Context, params GetSkipStatsParams) ([]services.SkipStats, error) {
+	query := url.Values{}
+	if params.Project != "" {
+		query.Set("project", params.Project)
+	}
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+	if !params.Since.IsZero() {
+		query.Set("since", params.Since.Format(time.RFC3339))
+	}
+
+	var out []services.SkipStats
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/stats/skips", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCapturesParams holds the query parameters of ListCaptures
+type ListCapturesParams struct {
+	URL   string // Archived URL (required)
+	Limit int    // Return only the most recent captures
+}
+
+// ListCaptures calls GET /api/v1/cdx
+// List archived captures of a URL, oldest first
+func (c *Client) ListCaptures(ctx context.Context, params ListCapturesParams) ([]api.Capture, error) {
+	query := url.Values{}
+	query.Set("url", params.URL)
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var out []api.Capture
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/cdx", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListCrawls calls GET /api/v1/crawls
+// List crawl jobs, newest first
+func (c *Client) ListCrawls(ctx context.Context) ([]api.CrawlJob, error) {
+	var out []api.CrawlJob
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/crawls", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListDomainsParams holds the query parameters of ListDomains
+type ListDomainsParams struct {
+	Domain string // Only return this host
+}
+
+// ListDomains calls GET /api/v1/domains
+// List the name, favicon and manifest metadata of crawled sites
+func (c *Client) ListDomains(ctx context.Context, params ListDomainsParams) ([]models.Domain, error) {
+	query := url.Values{}
+	if params.Domain != "" {
+		query.Set("domain", params.Domain)
+	}
+
+	var out []models.Domain
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/domains", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListRecentPagesParams holds the query parameters of ListRecentPages
+type ListRecentPagesParams struct {
+	Limit int // Number of pages (default 20, max 100)
+}
+
+// ListRecentPages calls GET /api/v1/pages
+// List the most recently stored pages
+func (c *Client) ListRecentPages(ctx context.Context, params ListRecentPagesParams) ([]api.PageSummary, error) {
+	query := url.Values{}
+	if params.Limit != 0 {
+		query.Set("limit", strconv.Itoa(params.Limit))
+	}
+
+	var out []api.PageSummary
+	if err := c.doJSON(ctx, http.MethodGet, "/api/v1/pages", query, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReplayCaptureParams holds the query parameters of ReplayCapture
+type ReplayCaptureParams struct {
+	URL       string // Archived URL (required)
+	Timestamp string // CDX timestamp (yyyyMMddHHmmss prefix); defaults to now
+}
+
+// ReplayCaptureResponse is the response of ReplayCapture
+type ReplayCaptureResponse struct {
+	ContentType     string
+	MementoDatetime string // Capture time (Memento-Datetime header)
+	ArchiveRecordID string // WARC record ID (X-Archive-Record-ID header)
+	Content         []byte
+}
+
+// ReplayCapture calls GET /api/v1/replay
+// Replay the capture of a URL closest to a timestamp
+func (c *Client) ReplayCapture(ctx context.Context, params ReplayCaptureParams) (*ReplayCaptureResponse, error) {
+	query := url.Values{}
+	query.Set("url", params.URL)
+	if params.Timestamp != "" {
+		query.Set("timestamp", params.Timestamp)
+	}
+
+	resp, content, err := c.readAll(ctx, http.MethodGet, "/api/v1/replay", query)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayCaptureResponse{
+		ContentType:     resp.Header.Get("Content-Type"),
+		MementoDatetime: resp.Header.Get("Memento-Datetime"),
+		ArchiveRecordID: resp.Header.Get("X-Archive-Record-ID"),
+		Content:         content,
+	}, nil
+}
+
+// SubmitCrawl calls POST /api/v1/crawls
+// Queue a crawl of a URL
+func (c *Client) SubmitCrawl(ctx context.Context, body api.CrawlRequest) (*api.CrawlJob, error) {
+	var out api.CrawlJob
+	if err := c.doJSON(ctx, http.MethodPost, "/api/v1/crawls", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
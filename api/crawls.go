@@ -0,0 +1,661 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap"
+)
+
+// Crawl job states
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// CrawlJob is a URL submitted through the API and its progress
+type CrawlJob struct {
+	ID         string     `json:"id"`
+	CrawlID    string     `json:"crawl_id"` // Tags every log line of the crawl
+	URL        string     `json:"url"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	Screenshot string     `json:"screenshot,omitempty"` // File name under /api/v1/screenshots/
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	options crawlers.RequestOptions // Not listed; headers may carry credentials
+}
+
+// CrawlRequest is the body of a crawl submission
+// Headers and query parameters are added to every request of the job
+type CrawlRequest struct {
+	URL string `json:"url"`
+	crawlers.RequestOptions
+}
+
+// PageSummary is a stored page without its body
+type PageSummary struct {
+	ID        uint      `json:"id"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Domain    string    `json:"domain"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CrawlHandlerConfig holds crawl job settings
+type CrawlHandlerConfig struct {
+	Crawler       Crawler                 // Runs submitted crawls (required)
+	DB            database.DatabaseClient // Optional; enables the recent pages, compare, security report, domain and stats endpoints
+	Screenshotter Screenshotter           // Optional; captures a screenshot per job
+	ScreenshotDir string                  // Where screenshots are written and served from
+	QueueSize     int                     // Pending jobs before submissions are rejected (default 100)
+	HistorySize   int                     // Finished jobs kept for listing (default 200)
+	ValidateURL   func(string) error      // Defaults to crawlers.ValidateURL
+}
+
+// CrawlHandler accepts crawl submissions and runs them one at a time
+// Jobs are kept in memory; pages are persisted by the Crawler
+type CrawlHandler struct {
+	crawler       Crawler
+	db            database.DatabaseClient
+	snapshots     *services.SnapshotService
+	security      *services.SecurityReportService
+	sites         *services.SiteMetadataService
+	stats         *services.CrawlStatsService
+	screenshotter Screenshotter
+	screenshotDir string
+	historySize   int
+	validateURL   func(string) error
+
+	queue  chan *CrawlJob
+	mu     sync.Mutex
+	jobs   map[string]*CrawlJob
+	order  []string
+	nextID int
+}
+
+// NewCrawlHandler creates a new crawl job handler
+// Jobs only run while Start is active
+func NewCrawlHandler(config CrawlHandlerConfig) *CrawlHandler {
+	if config.QueueSize <= 0 {
+		config.QueueSize = 100
+	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = 200
+	}
+	if config.ValidateURL == nil {
+		config.ValidateURL = crawlers.ValidateURL
+	}
+
+	var snapshots *services.SnapshotService
+	var security *services.SecurityReportService
+	var sites *services.SiteMetadataService
+	var stats *services.CrawlStatsService
+	if config.DB != nil {
+		snapshots = services.NewSnapshotService(zap.NewNop(), config.DB)
+		security = services.NewSecurityReportService(zap.NewNop(), config.DB)
+		sites = services.NewSiteMetadataService(zap.NewNop(), config.DB, services.SiteMetadataConfig{})
+		stats = services.NewCrawlStatsService(zap.NewNop(), config.DB)
+	}
+
+	return &CrawlHandler{
+		crawler:       config.Crawler,
+		db:            config.DB,
+		snapshots:     snapshots,
+		security:      security,
+		sites:         sites,
+		stats:         stats,
+		screenshotter: config.Screenshotter,
+		screenshotDir: config.ScreenshotDir,
+		historySize:   config.HistorySize,
+		validateURL:   config.ValidateURL,
+		queue:         make(chan *CrawlJob, config.QueueSize),
+		jobs:          make(map[string]*CrawlJob),
+	}
+}
+
+// Register adds the crawl routes
+func (h *CrawlHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/crawls", h.submit)
+	mux.HandleFunc("GET /api/v1/crawls", h.list)
+	mux.HandleFunc("GET /api/v1/crawls/{id}", h.get)
+	mux.HandleFunc("GET /api/v1/crawls/compare", h.compare)
+	mux.HandleFunc("GET /api/v1/pages", h.recentPages)
+	mux.HandleFunc("GET /api/v1/security/report", h.securityReport)
+	mux.HandleFunc("GET /api/v1/domains", h.listDomains)
+	mux.HandleFunc("GET /api/v1/stats/domains", h.domainStats)
+	mux.HandleFunc("GET /api/v1/stats/skips", h.skipStats)
+	mux.HandleFunc("GET /api/v1/screenshots/{name}", h.screenshot)
+}
+
+// Start runs queued jobs until ctx is done
+func (h *CrawlHandler) Start(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-h.queue:
+			h.run(ctx, job)
+		}
+	}
+}
+
+// Job returns a copy of a job by ID
+func (h *CrawlHandler) Job(id string) (CrawlJob, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	job, ok := h.jobs[id]
+	if !ok {
+		return CrawlJob{}, false
+	}
+	return *job, true
+}
+
+// run executes a single job
+func (h *CrawlHandler) run(ctx context.Context, job *CrawlJob) {
+	h.update(job, func(j *CrawlJob) {
+		now := time.Now()
+		j.Status = JobRunning
+		j.StartedAt = &now
+	})
+
+	ctx = libs.WithCrawlID(ctx, job.CrawlID)
+	ctx = crawlers.WithRequestOptions(ctx, job.options)
+	var err error
+	if crawler, ok := h.crawler.(ContextCrawler); ok {
+		err = crawler.CrawlAndStoreContext(ctx, job.URL)
+	} else {
+		err = h.crawler.CrawlAndStore(job.URL)
+	}
+
+	var screenshot string
+	if err == nil && h.screenshotter != nil && h.screenshotDir != "" {
+		name := "job-" + job.ID + ".png"
+		if shotErr := h.screenshotter.CaptureScreenshot(ctx, job.URL, filepath.Join(h.screenshotDir, name)); shotErr != nil {
+			fmt.Printf("warning: failed to capture screenshot for %s (crawl %s): %v\n", job.URL, job.CrawlID, shotErr)
+		} else {
+			screenshot = name
+		}
+	}
+
+	h.update(job, func(j *CrawlJob) {
+		now := time.Now()
+		j.FinishedAt = &now
+		j.Screenshot = screenshot
+		if err != nil {
+			j.Status = JobFailed
+			j.Error = err.Error()
+		} else {
+			j.Status = JobDone
+		}
+	})
+}
+
+// update mutates a job under the lock
+func (h *CrawlHandler) update(job *CrawlJob, fn func(*CrawlJob)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	fn(job)
+}
+
+// submit queues a crawl of the posted URL
+// Accepts a JSON CrawlRequest or a url form field; an X-Crawl-ID header
+// sets the crawl ID, otherwise one is generated. Headers and query
+// parameters can only be sent as JSON
+func (h *CrawlHandler) submit(w http.ResponseWriter, r *http.Request) {
+	var req CrawlRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64*1024)).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body")
+			return
+		}
+	} else {
+		req.URL = r.FormValue("url")
+	}
+
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	if err := h.validateURL(req.URL); err != nil {
+		writeErrorCode(w, http.StatusBadRequest, errs.CodeInvalidURL, err.Error())
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeErrorCode(w, http.StatusBadRequest, errs.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	h.mu.Lock()
+	h.nextID++
+	job := &CrawlJob{
+		ID:        strconv.Itoa(h.nextID),
+		CrawlID:   crawlID(r),
+		URL:       req.URL,
+		Status:    JobQueued,
+		CreatedAt: time.Now(),
+		options:   req.RequestOptions,
+	}
+
+	select {
+	case h.queue <- job:
+	default:
+		h.mu.Unlock()
+		writeErrorCode(w, http.StatusServiceUnavailable, errs.CodeQueueFull, "crawl queue is full")
+		return
+	}
+
+	h.jobs[job.ID] = job
+	h.order = append(h.order, job.ID)
+	h.pruneLocked()
+	snapshot := *job
+	h.mu.Unlock()
+
+	w.Header().Set("Location", "/api/v1/crawls/"+job.ID)
+	w.Header().Set(libs.CrawlIDHeader, job.CrawlID)
+	writeJSON(w, http.StatusAccepted, snapshot)
+}
+
+// crawlID returns the caller's X-Crawl-ID if usable, otherwise a new ID
+func crawlID(r *http.Request) string {
+	id := r.Header.Get(libs.CrawlIDHeader)
+	if id == "" || len(id) > 64 || strings.ContainsFunc(id, func(c rune) bool { return c <= ' ' || c > '~' }) {
+		return libs.NewCrawlID()
+	}
+	return id
+}
+
+// pruneLocked forgets the oldest finished jobs beyond the history size
+func (h *CrawlHandler) pruneLocked() {
+	for len(h.order) > h.historySize {
+		oldest := h.jobs[h.order[0]]
+		if oldest.Status == JobQueued || oldest.Status == JobRunning {
+			return
+		}
+		delete(h.jobs, h.order[0])
+		h.order = h.order[1:]
+	}
+}
+
+// list returns jobs newest first
+func (h *CrawlHandler) list(w http.ResponseWriter, _ *http.Request) {
+	h.mu.Lock()
+	jobs := make([]CrawlJob, 0, len(h.order))
+	for i := len(h.order) - 1; i >= 0; i-- {
+		jobs = append(jobs, *h.jobs[h.order[i]])
+	}
+	h.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+// get returns a single job
+func (h *CrawlHandler) get(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.Job(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "crawl job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+// compare diffs two crawls of the same site
+// ?base= and ?target= take job IDs or crawl IDs; ?domain= limits the diff
+func (h *CrawlHandler) compare(w http.ResponseWriter, r *http.Request) {
+	if h.snapshots == nil {
+		writeError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	diff, err := h.snapshots.Compare(r.Context(), h.resolveCrawlID(query.Get("base")), h.resolveCrawlID(query.Get("target")), query.Get("domain"))
+	if err != nil {
+		if code := errs.CodeOf(err); code == errs.CodeInvalidRequest || code == errs.CodeNotFound {
+			writeErrorCode(w, errs.HTTPStatus(err), code, err.Error())
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "failed to compare crawls")
+		return
+	}
+	writeJSON(w, http.StatusOK, diff)
+}
+
+// resolveCrawlID returns the crawl ID of a known job, or id itself
+func (h *CrawlHandler) resolveCrawlID(id string) string {
+	if job, ok := h.Job(id); ok {
+		return job.CrawlID
+	}
+	return id
+}
+
+// securityReport scores the security headers of audited pages per domain
+// ?project= selects the project (default none); ?domain= limits the report
+func (h *CrawlHandler) securityReport(w http.ResponseWriter, r *http.Request) {
+	if h.security == nil {
+		writeError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	query := r.URL.Query()
+	reports, err := h.security.Report(r.Context(), query.Get("project"), query.Get("domain"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to build security report")
+		return
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+// listDomains lists the recorded site metadata of crawled domains, ?domain= for one
+func (h *CrawlHandler) listDomains(w http.ResponseWriter, r *http.Request) {
+	if h.sites == nil {
+		writeError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	domains, err := h.sites.List(r.Context(), r.URL.Query().Get("domain"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch domains")
+		return
+	}
+	writeJSON(w, http.StatusOK, domains)
+}
+
+// domainStats reports crawl latency percentiles, error rates and page sizes
+// per domain; ?project=, ?domain= and ?since= (RFC 3339, default 24h ago)
+func (h *CrawlHandler) domainStats(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		writeError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	q, ok := statsQuery(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.stats.DomainStats(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to compute crawl statistics")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// skipStats reports why URLs were not crawled, grouped by skip reason and
+// rule; it takes the same parameters as domainStats
+func (h *CrawlHandler) skipStats(w http.ResponseWriter, r *http.Request) {
+	if h.stats == nil {
+		writeError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	q, ok := statsQuery(w, r)
+	if !ok {
+		return
+	}
+	stats, err := h.stats.SkipStats(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to compute skip statistics")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// statsQuery parses ?project=, ?domain= and ?since=, writing a 400 response
+// for an invalid since
+func statsQuery(w http.ResponseWriter, r *http.Request) (services.CrawlStatsQuery, bool) {
+	query := r.URL.Query()
+	q := services.CrawlStatsQuery{Project: query.Get("project"), Domain: query.Get("domain")}
+	if since := query.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			writeErrorCode(w, http.StatusBadRequest, errs.CodeInvalidRequest, "since must be an RFC 3339 time")
+			return q, false
+		}
+		q.Since = t
+	}
+	return q, true
+}
+
+// recentPages lists the most recently stored pages, ?limit= (default 20, max 100)
+func (h *CrawlHandler) recentPages(w http.ResponseWriter, r *http.Request) {
+	if h.db == nil {
+		writeError(w, http.StatusServiceUnavailable, "database not configured")
+		return
+	}
+
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = min(v, 100)
+	}
+
+	var pages []models.Page
+	err := h.db.GetDB().
+		WithContext(r.Context()).
+		Select("id", "url", "title", "domain", "status", "created_at").
+		Order("id DESC").
+		Limit(limit).
+		Find(&pages).Error
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "failed to fetch pages")
+		return
+	}
+
+	summaries := make([]PageSummary, 0, len(pages))
+	for i := range pages {
+		summaries = append(summaries, PageSummary{
+			ID:        pages[i].ID,
+			URL:       pages[i].URL,
+			Title:     pages[i].Title,
+			Domain:    pages[i].Domain,
+			Status:    pages[i].Status,
+			CreatedAt: pages[i].CreatedAt,
+		})
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// screenshot serves a captured screenshot by file name
+func (h *CrawlHandler) screenshot(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if h.screenshotDir == "" || !fs.ValidPath(name) || filepath.Base(name) != name {
+		writeError(w, http.StatusNotFound, "screenshot not found")
+		return
+	}
+
+	data, err := os.ReadFile(filepath.Join(h.screenshotDir, name))
+	if err != nil {
+		writeError(w, http.StatusNotFound, "screenshot not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data) // Error intentionally ignored; client may disconnect
+}
+
+// Endpoints documents the crawl routes
+func (h *CrawlHandler) Endpoints() []Endpoint {
+	return []Endpoint{
+		{
+			Method: http.MethodPost,
+			Path:   "/api/v1/crawls",
+			Operation: Operation{
+				OperationID: "submitCrawl",
+				Summary:     "Queue a crawl of a URL",
+				Tags:        []string{"crawls"},
+				RequestBody: JSONBody(ModelSchema(CrawlRequest{})),
+				Responses: map[string]*Response{
+					"202": JSONResponse("Queued job", ModelSchema(CrawlJob{})),
+					"400": ErrorResponseDoc("Missing or rejected url, or invalid headers"),
+					"503": ErrorResponseDoc("Queue is full"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/crawls",
+			Operation: Operation{
+				OperationID: "listCrawls",
+				Summary:     "List crawl jobs, newest first",
+				Tags:        []string{"crawls"},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Crawl jobs", ArrayOf(ModelSchema(CrawlJob{}))),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/crawls/{id}",
+			Operation: Operation{
+				OperationID: "getCrawl",
+				Summary:     "Get the progress of a crawl job",
+				Tags:        []string{"crawls"},
+				Parameters:  []Parameter{PathParam("id", "Job ID")},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Crawl job", ModelSchema(CrawlJob{})),
+					"404": ErrorResponseDoc("Unknown job"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/crawls/compare",
+			Operation: Operation{
+				OperationID: "compareCrawls",
+				Summary:     "Diff the URLs, statuses and content of two crawls",
+				Tags:        []string{"crawls"},
+				Parameters: []Parameter{
+					QueryParam("base", "string", "Job ID or crawl ID of the earlier crawl", true),
+					QueryParam("target", "string", "Job ID or crawl ID of the later crawl", true),
+					QueryParam("domain", "string", "Only compare this domain", false),
+				},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Diff report", ModelSchema(services.SnapshotDiff{})),
+					"400": ErrorResponseDoc("Missing crawls, or crawls without a common domain"),
+					"404": ErrorResponseDoc("A crawl has no logged fetches"),
+					"503": ErrorResponseDoc("Database not configured"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/pages",
+			Operation: Operation{
+				OperationID: "listRecentPages",
+				Summary:     "List the most recently stored pages",
+				Tags:        []string{"crawls"},
+				Parameters:  []Parameter{QueryParam("limit", "integer", "Number of pages (default 20, max 100)", false)},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Recent pages", ArrayOf(ModelSchema(PageSummary{}))),
+					"503": ErrorResponseDoc("Database not configured"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/security/report",
+			Operation: Operation{
+				OperationID: "getSecurityReport",
+				Summary:     "Score the security headers of audited pages per domain, worst first",
+				Tags:        []string{"crawls"},
+				Parameters: []Parameter{
+					QueryParam("project", "string", "Project of the audited pages (default none)", false),
+					QueryParam("domain", "string", "Only report this domain", false),
+				},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Domain reports", ArrayOf(ModelSchema(services.DomainSecurityReport{}))),
+					"503": ErrorResponseDoc("Database not configured"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/domains",
+			Operation: Operation{
+				OperationID: "listDomains",
+				Summary:     "List the name, favicon and manifest metadata of crawled sites",
+				Tags:        []string{"crawls"},
+				Parameters:  []Parameter{QueryParam("domain", "string", "Only return this host", false)},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Domains ordered by host", ArrayOf(ModelSchema(models.Domain{}))),
+					"503": ErrorResponseDoc("Database not configured"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/stats/domains",
+			Operation: Operation{
+				OperationID: "getDomainStats",
+				Summary:     "Crawl latency percentiles, error rate, average page size and last success per domain, busiest first",
+				Tags:        []string{"crawls"},
+				Parameters: []Parameter{
+					QueryParam("project", "string", "Project of the crawls (default none)", false),
+					QueryParam("domain", "string", "Only report this domain", false),
+					DateTimeQueryParam("since", "Start of the window (default 24 hours ago)", false),
+				},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Domain statistics", ArrayOf(ModelSchema(services.DomainCrawlStats{}))),
+					"400": ErrorResponseDoc("Invalid since"),
+					"503": ErrorResponseDoc("Database not configured"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/stats/skips",
+			Operation: Operation{
+				OperationID: "getSkipStats",
+				Summary:     "Count the URLs crawls skipped by reason and rule (robots, url_rule, budget, duplicate, ssrf...), most frequent first",
+				Tags:        []string{"crawls"},
+				Parameters: []Parameter{
+					QueryParam("project", "string", "Project of the crawls (default none)", false),
+					QueryParam("domain", "string", "Only report this domain", false),
+					DateTimeQueryParam("since", "Start of the window (default 24 hours ago)", false),
+				},
+				Responses: map[string]*Response{
+					"200": JSONResponse("Skip statistics", ArrayOf(ModelSchema(services.SkipStats{}))),
+					"400": ErrorResponseDoc("Invalid since"),
+					"503": ErrorResponseDoc("Database not configured"),
+				},
+			},
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/screenshots/{name}",
+			Operation: Operation{
+				OperationID: "getScreenshot",
+				Summary:     "Download a job screenshot",
+				Tags:        []string{"crawls"},
+				Parameters:  []Parameter{PathParam("name", "Screenshot file name from CrawlJob.screenshot")},
+				Responses: map[string]*Response{
+					"200": {
+						Description: "PNG image",
+						Content:     map[string]*MediaType{"image/png": {Schema: &Schema{Type: "string", Format: "binary"}}},
+					},
+					"404": ErrorResponseDoc("Unknown screenshot"),
+				},
+			},
+		},
+	}
+}
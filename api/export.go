@@ -0,0 +1,250 @@
+package api
+
+import (
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"gorm.io/gorm"
+)
+
+// Export limits
+const (
+	defaultExportBatch = 500
+	maxExportBatch     = 5000
+)
+
+// ExportHandler streams large result sets as NDJSON using keyset pagination
+// Rows are fetched in batches and flushed as they are written, so memory use
+// does not grow with the export size
+type ExportHandler struct {
+	db database.DatabaseClient
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(dbClient database.DatabaseClient) *ExportHandler {
+	return &ExportHandler{db: dbClient}
+}
+
+// Register adds the export routes
+func (h *ExportHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/export/pages", h.exportPages)
+	mux.HandleFunc("GET /api/v1/export/products", h.exportProducts)
+}
+
+// Endpoints documents the export routes
+func (h *ExportHandler) Endpoints() []Endpoint {
+	return []Endpoint{
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/export/pages",
+			Operation: exportOperation("exportPages", "Stream pages as NDJSON", models.Page{},
+				QueryParam("project", "string", "Only pages of this project", false),
+				QueryParam("domain", "string", "Only pages of this domain", false)),
+		},
+		{
+			Method: http.MethodGet,
+			Path:   "/api/v1/export/products",
+			Operation: exportOperation("exportProducts", "Stream products as NDJSON", models.Product{},
+				QueryParam("category", "string", "Only products in this category", false),
+				QueryParam("brand", "string", "Only products of this brand", false)),
+		},
+	}
+}
+
+// exportOperation documents an NDJSON export of rows shaped like model
+func exportOperation(id, summary string, model interface{}, filters ...Parameter) Operation {
+	params := append(filters,
+		QueryParam("cursor", "string", "Resume after the row encoded by a previous X-Next-Cursor", false),
+		QueryParam("batch", "integer", "Rows fetched per database query (max 5000)", false),
+		QueryParam("limit", "integer", "Maximum number of rows to export", false),
+		QueryParam("gzip", "boolean", "Gzip the stream (1 or true)", false),
+	)
+	return Operation{
+		OperationID: id,
+		Summary:     summary,
+		Tags:        []string{"export"},
+		Parameters:  params,
+		Responses: map[string]*Response{
+			"200": {
+				Description: "One JSON object per line",
+				Headers: map[string]*Header{
+					"X-Next-Cursor": {Description: "Trailer with the resume cursor when the export stopped early", Schema: &Schema{Type: "string"}},
+				},
+				Content: map[string]*MediaType{
+					"application/x-ndjson": {Schema: ModelSchema(model)},
+				},
+			},
+			"400": ErrorResponseDoc("Invalid cursor"),
+		},
+	}
+}
+
+// EncodeCursor returns the opaque resume cursor for the last exported ID
+func EncodeCursor(lastID uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("id:" + strconv.FormatUint(uint64(lastID), 10)))
+}
+
+// DecodeCursor parses a cursor produced by EncodeCursor
+func DecodeCursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	value, ok := strings.CutPrefix(string(raw), "id:")
+	if !ok {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	id, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid cursor")
+	}
+	return uint(id), nil
+}
+
+// exportPages streams pages, optionally filtered by ?project= and ?domain=
+func (h *ExportHandler) exportPages(w http.ResponseWriter, r *http.Request) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		if project := r.URL.Query().Get("project"); project != "" {
+			db = db.Where("project = ?", project)
+		}
+		if domain := r.URL.Query().Get("domain"); domain != "" {
+			db = db.Where("domain = ?", domain)
+		}
+		return db
+	}
+	streamExport(h.db, w, r, filter, func(p *models.Page) uint { return p.ID })
+}
+
+// exportProducts streams products, optionally filtered by ?category= and ?brand=
+func (h *ExportHandler) exportProducts(w http.ResponseWriter, r *http.Request) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		if category := r.URL.Query().Get("category"); category != "" {
+			db = db.Where("category = ?", category)
+		}
+		if brand := r.URL.Query().Get("brand"); brand != "" {
+			db = db.Where("brand = ?", brand)
+		}
+		return db
+	}
+	streamExport(h.db, w, r, filter, func(p *models.Product) uint { return p.ID })
+}
+
+// streamExport writes rows of T as NDJSON in ID order starting after ?cursor=
+// ?batch= sets the page size and ?limit= caps the number of rows
+// When the export stops early the resume cursor is sent as the X-Next-Cursor trailer
+func streamExport[T any](
+	dbClient database.DatabaseClient,
+	w http.ResponseWriter,
+	r *http.Request,
+	filter func(*gorm.DB) *gorm.DB,
+	idOf func(*T) uint,
+) {
+	query := r.URL.Query()
+
+	lastID, err := DecodeCursor(query.Get("cursor"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	batch := defaultExportBatch
+	if v, err := strconv.Atoi(query.Get("batch")); err == nil && v > 0 {
+		batch = min(v, maxExportBatch)
+	}
+	limit := 0
+	if v, err := strconv.Atoi(query.Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Trailer", "X-Next-Cursor")
+
+	var out io.Writer = w
+	if acceptsGzip(r) {
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Add("Vary", "Accept-Encoding")
+		gz := gzip.NewWriter(w)
+		defer func() {
+			_ = gz.Close() // Error intentionally ignored; client may disconnect
+		}()
+		out = gz
+	}
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	enc := json.NewEncoder(out)
+	written := 0
+	complete := false
+
+	defer func() {
+		if !complete {
+			w.Header().Set("X-Next-Cursor", EncodeCursor(lastID))
+		}
+	}()
+
+	for {
+		size := batch
+		if limit > 0 {
+			size = min(size, limit-written)
+			if size <= 0 {
+				return
+			}
+		}
+
+		var rows []T
+		err := dbClient.GetDB().
+			WithContext(r.Context()).
+			Scopes(filter).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(size).
+			Find(&rows).Error
+		if err != nil {
+			// Headers are sent; the missing trailer-terminated end signals failure
+			return
+		}
+
+		for i := range rows {
+			if err := enc.Encode(&rows[i]); err != nil {
+				return
+			}
+			lastID = idOf(&rows[i])
+			written++
+		}
+
+		if gz, ok := out.(*gzip.Writer); ok {
+			_ = gz.Flush() // Error intentionally ignored; next write will fail too
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		if len(rows) < size {
+			complete = true
+			return
+		}
+	}
+}
+
+// acceptsGzip reports whether the client asked for a gzip-compressed stream
+func acceptsGzip(r *http.Request) bool {
+	if compressed, _ := strconv.ParseBool(r.URL.Query().Get("gzip")); compressed {
+		return true
+	}
+	for _, enc := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		if strings.TrimSpace(strings.SplitN(enc, ";", 2)[0]) == "gzip" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+)
+
+// Routes is implemented by handlers that expose API endpoints
+type Routes interface {
+	// Register adds the handler's routes to mux
+	Register(mux *http.ServeMux)
+}
+
+// Documented is implemented by handlers that describe their endpoints
+// for the generated OpenAPI document
+type Documented interface {
+	// Endpoints returns the handler's routes with their operations
+	Endpoints() []Endpoint
+}
+
+// Crawler runs a crawl submitted through the API and stores the page
+type Crawler interface {
+	CrawlAndStore(url string) error
+}
+
+// ContextCrawler is a Crawler that also accepts a context
+// The context carries the job's crawl ID for log correlation
+type ContextCrawler interface {
+	Crawler
+	CrawlAndStoreContext(ctx context.Context, url string) error
+}
+
+// Screenshotter captures a screenshot of a URL to a PNG file
+type Screenshotter interface {
+	CaptureScreenshot(ctx context.Context, url, path string) error
+}
+
+// Ensure all handlers implement the interfaces
+var (
+	_ Routes     = (*ArchiveHandler)(nil)
+	_ Routes     = (*ExportHandler)(nil)
+	_ Routes     = (*CrawlHandler)(nil)
+	_ Routes     = (*UIHandler)(nil)
+	_ Routes     = (*BotInfoHandler)(nil)
+	_ Documented = (*ArchiveHandler)(nil)
+	_ Documented = (*ExportHandler)(nil)
+	_ Documented = (*CrawlHandler)(nil)
+	_ Documented = (*openAPIHandler)(nil)
+)
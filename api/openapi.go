@@ -0,0 +1,341 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OpenAPI document metadata
+const (
+	OpenAPIVersion = "3.0.3"
+	APITitle       = "golwarc API"
+	APIVersion     = "1.0.0"
+)
+
+// OpenAPIDocument is an OpenAPI 3 document
+// Only the subset of the specification used by golwarc is modelled
+type OpenAPIDocument struct {
+	OpenAPI    string                           `json:"openapi"`
+	Info       OpenAPIInfo                      `json:"info"`
+	Paths      map[string]map[string]*Operation `json:"paths"`
+	Components OpenAPIComponents                `json:"components"`
+}
+
+// OpenAPIInfo describes the API
+type OpenAPIInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenAPIComponents holds reusable schemas
+type OpenAPIComponents struct {
+	Schemas map[string]*Schema `json:"schemas"`
+}
+
+// Endpoint documents one route served by a handler
+type Endpoint struct {
+	Method    string
+	Path      string
+	Operation Operation
+}
+
+// Operation describes a single API operation
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Tags        []string             `json:"tags,omitempty"`
+	Parameters  []Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+// Parameter describes a query or path parameter
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// RequestBody describes an operation request body
+type RequestBody struct {
+	Required bool                  `json:"required,omitempty"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// Response describes an operation response
+type Response struct {
+	Description string                `json:"description"`
+	Headers     map[string]*Header    `json:"headers,omitempty"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+// Header describes a response header
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+// MediaType describes a response body
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Schema is a JSON schema object
+// Model types are referenced through Ref; BuildOpenAPI fills the components
+type Schema struct {
+	Ref        string             `json:"$ref,omitempty"`
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	GoType     string             `json:"x-go-type,omitempty"` // Import path qualified Go type the schema was generated from, used by the client generator
+
+	model reflect.Type // Go type to reflect into components
+}
+
+// QueryParam documents a query parameter of the given JSON schema type
+func QueryParam(name, schemaType, description string, required bool) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "query",
+		Description: description,
+		Required:    required,
+		Schema:      &Schema{Type: schemaType},
+	}
+}
+
+// DateTimeQueryParam documents an RFC 3339 time query parameter
+func DateTimeQueryParam(name, description string, required bool) Parameter {
+	param := QueryParam(name, "string", description, required)
+	param.Schema.Format = "date-time"
+	return param
+}
+
+// PathParam documents a required string path parameter
+func PathParam(name, description string) Parameter {
+	return Parameter{
+		Name:        name,
+		In:          "path",
+		Description: description,
+		Required:    true,
+		Schema:      &Schema{Type: "string"},
+	}
+}
+
+// JSONBody documents a required JSON request body
+func JSONBody(schema *Schema) *RequestBody {
+	return &RequestBody{
+		Required: true,
+		Content:  map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// ModelSchema references the schema generated from the Go type of v
+func ModelSchema(v interface{}) *Schema {
+	return &Schema{model: reflect.TypeOf(v)}
+}
+
+// ArrayOf returns an array schema of items
+func ArrayOf(items *Schema) *Schema {
+	return &Schema{Type: "array", Items: items}
+}
+
+// JSONResponse documents a JSON response body
+func JSONResponse(description string, schema *Schema) *Response {
+	return &Response{
+		Description: description,
+		Content:     map[string]*MediaType{"application/json": {Schema: schema}},
+	}
+}
+
+// ErrorResponseDoc documents the standard JSON error body
+func ErrorResponseDoc(description string) *Response {
+	return JSONResponse(description, ModelSchema(ErrorResponse{}))
+}
+
+// BuildOpenAPI generates an OpenAPI document from the endpoints of documented handlers
+// Schemas of referenced Go types are derived from their json struct tags
+func BuildOpenAPI(handlers ...Documented) *OpenAPIDocument {
+	doc := &OpenAPIDocument{
+		OpenAPI:    OpenAPIVersion,
+		Info:       OpenAPIInfo{Title: APITitle, Version: APIVersion},
+		Paths:      make(map[string]map[string]*Operation),
+		Components: OpenAPIComponents{Schemas: make(map[string]*Schema)},
+	}
+
+	for _, h := range handlers {
+		for _, ep := range h.Endpoints() {
+			op := ep.Operation
+			for i := range op.Parameters {
+				op.Parameters[i].Schema = doc.resolve(op.Parameters[i].Schema)
+			}
+			if op.RequestBody != nil {
+				for _, media := range op.RequestBody.Content {
+					media.Schema = doc.resolve(media.Schema)
+				}
+			}
+			for _, resp := range op.Responses {
+				for _, media := range resp.Content {
+					media.Schema = doc.resolve(media.Schema)
+				}
+			}
+
+			if doc.Paths[ep.Path] == nil {
+				doc.Paths[ep.Path] = make(map[string]*Operation)
+			}
+			doc.Paths[ep.Path][strings.ToLower(ep.Method)] = &op
+		}
+	}
+
+	return doc
+}
+
+// WriteOpenAPI writes the indented JSON document of the server's handlers
+func (s *Server) WriteOpenAPI(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s.OpenAPI())
+}
+
+// Operations returns every operation of the document sorted by operation ID
+func (d *OpenAPIDocument) Operations() []*Operation {
+	var ops []*Operation
+	for _, methods := range d.Paths {
+		for _, op := range methods {
+			ops = append(ops, op)
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationID < ops[j].OperationID })
+	return ops
+}
+
+// resolve replaces model references with $ref schemas, registering components
+func (d *OpenAPIDocument) resolve(s *Schema) *Schema {
+	if s == nil {
+		return nil
+	}
+	if s.model != nil {
+		return d.schemaForType(s.model)
+	}
+	if s.Items != nil {
+		s.Items = d.resolve(s.Items)
+	}
+	return s
+}
+
+// Types with custom JSON encodings
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+)
+
+// schemaForType reflects a Go type into a schema
+func (d *OpenAPIDocument) schemaForType(t reflect.Type) *Schema {
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		nullable = true
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: nullable}
+	case t == deletedAtType:
+		return &Schema{Type: "string", Format: "date-time", Nullable: true}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, seen := d.Components.Schemas[name]; !seen {
+			obj := &Schema{Type: "object", Properties: make(map[string]*Schema), GoType: goTypeName(t)}
+			d.Components.Schemas[name] = obj // Registered first so recursive types terminate
+			d.addFields(obj, t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return &Schema{Type: "string", Format: "byte"}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return &Schema{Type: "array", Items: d.schemaForType(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return &Schema{Type: "object"}
+	case t.Kind() == reflect.Bool:
+		return &Schema{Type: "boolean", Nullable: nullable}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		format := "int32"
+		if t.Bits() == 64 {
+			format = "int64"
+		}
+		return &Schema{Type: "integer", Format: format, Nullable: nullable}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return &Schema{Type: "number", Nullable: nullable}
+	default:
+		return &Schema{Type: "string", Nullable: nullable}
+	}
+}
+
+// goTypeName returns the import path qualified name of a named type,
+// e.g. github.com/alonecandies/golwarc/models.Page
+func goTypeName(t reflect.Type) string {
+	return t.PkgPath() + "." + t.Name()
+}
+
+// addFields adds the JSON-visible fields of struct t, flattening embedded structs
+func (d *OpenAPIDocument) addFields(obj *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+
+		name, _, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				d.addFields(obj, embedded)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+		obj.Properties[name] = d.schemaForType(field.Type)
+	}
+}
+
+// openAPIHandler serves the document of the registered handlers
+type openAPIHandler struct {
+	server *Server
+}
+
+// Endpoints documents the spec endpoint itself
+func (h *openAPIHandler) Endpoints() []Endpoint {
+	return []Endpoint{{
+		Method: http.MethodGet,
+		Path:   "/api/v1/openapi.json",
+		Operation: Operation{
+			OperationID: "getOpenAPI",
+			Summary:     "OpenAPI document describing this API",
+			Tags:        []string{"meta"},
+			Responses: map[string]*Response{
+				"200": JSONResponse("OpenAPI 3 document", &Schema{Type: "object", GoType: goTypeName(reflect.TypeOf(OpenAPIDocument{}))}),
+			},
+		},
+	}}
+}
+
+// serve writes the OpenAPI document
+func (h *openAPIHandler) serve(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.server.OpenAPI())
+}
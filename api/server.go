@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// ServerConfig holds HTTP API server settings
+type ServerConfig struct {
+	Port         int           // Listen port (default 8080)
+	ReadTimeout  time.Duration // Default 15s
+	WriteTimeout time.Duration // Default 60s; streamed responses may need more
+}
+
+// Server is the HTTP API server
+// Feature handlers register their routes on it with Register
+type Server struct {
+	server     *http.Server
+	mux        *http.ServeMux
+	documented []Documented
+}
+
+// NewServer creates a new API server
+func NewServer(config ServerConfig) *Server {
+	if config.Port <= 0 {
+		config.Port = 8080
+	}
+	if config.ReadTimeout <= 0 {
+		config.ReadTimeout = 15 * time.Second
+	}
+	if config.WriteTimeout <= 0 {
+		config.WriteTimeout = 60 * time.Second
+	}
+
+	mux := http.NewServeMux()
+	s := &Server{
+		mux: mux,
+		server: &http.Server{
+			Addr:         fmt.Sprintf(":%d", config.Port),
+			Handler:      mux,
+			ReadTimeout:  config.ReadTimeout,
+			WriteTimeout: config.WriteTimeout,
+		},
+	}
+
+	spec := &openAPIHandler{server: s}
+	mux.HandleFunc("GET /api/v1/openapi.json", spec.serve)
+	s.documented = append(s.documented, spec)
+	return s
+}
+
+// Register adds the routes of one or more handlers
+// Handlers implementing Documented are included in the OpenAPI document
+func (s *Server) Register(routes ...Routes) {
+	for _, r := range routes {
+		r.Register(s.mux)
+		if d, ok := r.(Documented); ok {
+			s.documented = append(s.documented, d)
+		}
+	}
+}
+
+// OpenAPI returns the OpenAPI document of all registered handlers
+func (s *Server) OpenAPI() *OpenAPIDocument {
+	return BuildOpenAPI(s.documented...)
+}
+
+// Handler returns the root HTTP handler (useful for tests)
+func (s *Server) Handler() http.Handler {
+	return s.mux
+}
+
+// Start starts the API server
+func (s *Server) Start() error {
+	return s.server.ListenAndServe()
+}
+
+// Shutdown gracefully stops the API server
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// writeJSON writes v as a JSON response
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v) // Error intentionally ignored; headers are already sent
+}
+
+// ErrorResponse is the JSON body of every API error
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"` // Stable error code, e.g. GOLWARC-API-001
+}
+
+// writeError writes a JSON error response with the generic code for status
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeErrorCode(w, status, errs.CodeForHTTPStatus(status), message)
+}
+
+// writeErrorCode writes a JSON error response with a specific code
+func writeErrorCode(w http.ResponseWriter, status int, code errs.Code, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message, Code: string(code)})
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// UIHandler serves the embedded web UI at /ui/
+// The UI talks to the crawl endpoints, so register a CrawlHandler alongside it
+type UIHandler struct {
+	files http.Handler
+}
+
+// NewUIHandler creates a handler for the embedded web UI
+func NewUIHandler() *UIHandler {
+	sub, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err) // The embedded directory is fixed at build time
+	}
+	return &UIHandler{files: http.StripPrefix("/ui/", http.FileServerFS(sub))}
+}
+
+// Register adds the UI routes
+func (h *UIHandler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /ui/", h.files)
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ui/", http.StatusFound)
+	})
+}
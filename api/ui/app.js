@@ -0,0 +1,111 @@
+// golwarc web UI: submits crawls and polls their progress
+"use strict";
+
+const POLL_INTERVAL_MS = 2000;
+
+async function api(path, options) {
+  const resp = await fetch(path, options);
+  const body = await resp.json().catch(() => ({}));
+  if (!resp.ok) {
+    throw new Error(body.error || resp.statusText);
+  }
+  return body;
+}
+
+function cell(row, content) {
+  const td = document.createElement("td");
+  if (content instanceof Node) {
+    td.appendChild(content);
+  } else {
+    td.textContent = content ?? "";
+  }
+  row.appendChild(td);
+  return td;
+}
+
+function link(href, text) {
+  const a = document.createElement("a");
+  a.href = href;
+  a.textContent = text;
+  a.target = "_blank";
+  a.rel = "noopener";
+  return a;
+}
+
+function duration(job) {
+  if (!job.started_at) {
+    return "";
+  }
+  const end = job.finished_at ? new Date(job.finished_at) : new Date();
+  return ((end - new Date(job.started_at)) / 1000).toFixed(1) + "s";
+}
+
+function renderJobs(jobs) {
+  const tbody = document.getElementById("jobs");
+  tbody.replaceChildren();
+  for (const job of jobs) {
+    const row = document.createElement("tr");
+    cell(row, job.id);
+    cell(row, link(job.url, job.url));
+    const status = cell(row, job.status);
+    status.className = "status-" + job.status;
+    if (job.error) {
+      status.title = job.error;
+    }
+    cell(row, duration(job));
+    cell(row, job.screenshot ? link("/api/v1/screenshots/" + encodeURIComponent(job.screenshot), "view") : "");
+    tbody.appendChild(row);
+  }
+  document.getElementById("jobs-empty").hidden = jobs.length > 0;
+}
+
+function renderPages(pages) {
+  const tbody = document.getElementById("pages");
+  tbody.replaceChildren();
+  for (const page of pages) {
+    const row = document.createElement("tr");
+    cell(row, page.title);
+    cell(row, link(page.url, page.url));
+    cell(row, page.domain);
+    cell(row, page.status);
+    cell(row, new Date(page.created_at).toLocaleString());
+    tbody.appendChild(row);
+  }
+  document.getElementById("pages-empty").hidden = pages.length > 0;
+}
+
+async function refresh() {
+  try {
+    renderJobs(await api("/api/v1/crawls"));
+  } catch (err) {
+    console.error("failed to load jobs:", err);
+  }
+  try {
+    renderPages(await api("/api/v1/pages?limit=20"));
+  } catch (err) {
+    document.getElementById("pages-empty").textContent = err.message;
+  }
+}
+
+document.getElementById("submit-form").addEventListener("submit", async (event) => {
+  event.preventDefault();
+  const input = document.getElementById("url");
+  const errorText = document.getElementById("submit-error");
+  errorText.hidden = true;
+
+  try {
+    await api("/api/v1/crawls", {
+      method: "POST",
+      headers: { "Content-Type": "application/json" },
+      body: JSON.stringify({ url: input.value }),
+    });
+    input.value = "";
+    refresh();
+  } catch (err) {
+    errorText.textContent = err.message;
+    errorText.hidden = false;
+  }
+});
+
+refresh();
+setInterval(refresh, POLL_INTERVAL_MS);
@@ -0,0 +1,254 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// invalidateChannel is the channel Redis publishes tracking invalidations on
+const invalidateChannel = "__redis__:invalidate"
+
+// retiredClientGrace is how long a replaced tracking client stays open for
+// commands already running on it
+const retiredClientGrace = 5 * time.Second
+
+// ClientCacheConfig enables client-side caching of Get results
+// Redis tracks the keys the client reads and pushes an invalidation when one
+// changes, so hot keys such as domain settings or robots.txt rules are served
+// from memory without a round trip
+type ClientCacheConfig struct {
+	MaxKeys  int           // Local entries kept (default: 10000)
+	TTL      time.Duration // Upper bound on local entry age (default: 1 minute)
+	Prefixes []string      // Only cache keys with these prefixes (broadcast tracking); empty caches every key read
+	Clock    clock.Clock   // Entry expiry; defaults to the wall clock
+}
+
+// ClientCacheStats reports client-side cache activity
+type ClientCacheStats struct {
+	Hits          int64
+	Misses        int64
+	Invalidations int64 // Keys dropped on invalidation messages
+	Keys          int
+}
+
+// clientCacheEntry is a locally cached value
+type clientCacheEntry struct {
+	value   string
+	expires time.Time
+}
+
+// clientCache serves Get from memory and drops entries when Redis reports
+// that they changed
+//
+// Reads that miss go through a dedicated tracking client whose connections
+// redirect invalidations to a pub/sub connection. When that connection is
+// replaced the tracking client is rebuilt for the new redirect target and
+// the local entries are dropped, since invalidations may have been lost
+type clientCache struct {
+	options  redis.Options
+	config   ClientCacheConfig
+	entries  *lru.Cache[string, clientCacheEntry]
+	tracked  atomic.Pointer[redis.Client]
+	notifier *redis.Client
+	pubsub   *redis.PubSub
+	healthy  atomic.Bool   // False while invalidations may be missed
+	epoch    atomic.Uint64 // Bumped on every invalidation; reads racing one are not stored
+	closed   atomic.Bool
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+// newClientCache subscribes to invalidations and starts tracking
+func newClientCache(options redis.Options, config ClientCacheConfig) (*clientCache, error) {
+	if config.MaxKeys <= 0 {
+		config.MaxKeys = 10000
+	}
+	if config.TTL <= 0 {
+		config.TTL = time.Minute
+	}
+	config.Clock = clock.Or(config.Clock)
+
+	entries, err := lru.New[string, clientCacheEntry](config.MaxKeys)
+	if err != nil {
+		return nil, err
+	}
+	c := &clientCache{options: options, config: config, entries: entries}
+
+	notifierOptions := options
+	notifierOptions.OnConnect = c.onNotifierConnect
+	c.notifier = redis.NewClient(&notifierOptions)
+
+	ctx := context.Background()
+	c.pubsub = c.notifier.Subscribe(ctx, invalidateChannel)
+	if _, err := c.pubsub.Receive(ctx); err != nil {
+		_ = c.close() // Best effort cleanup
+		return nil, fmt.Errorf("failed to subscribe to invalidations: %w", err)
+	}
+
+	go c.listen()
+	return c, nil
+}
+
+// onNotifierConnect runs on every new invalidation connection and points a
+// fresh tracking client at it
+func (c *clientCache) onNotifierConnect(ctx context.Context, cn *redis.Conn) error {
+	id, err := cn.ClientID(ctx).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get client ID: %w", err)
+	}
+
+	trackedOptions := c.options
+	trackedOptions.OnConnect = func(ctx context.Context, cn *redis.Conn) error {
+		return cn.Do(ctx, c.trackingArgs(id)...).Err()
+	}
+	if old := c.tracked.Swap(redis.NewClient(&trackedOptions)); old != nil {
+		time.AfterFunc(retiredClientGrace, func() {
+			_ = old.Close() // Error intentionally ignored on close
+		})
+	}
+
+	c.purge()
+	c.healthy.Store(true)
+	return nil
+}
+
+// trackingArgs builds the CLIENT TRACKING command redirecting to id
+func (c *clientCache) trackingArgs(id int64) []interface{} {
+	args := []interface{}{"CLIENT", "TRACKING", "ON", "REDIRECT", id}
+	if len(c.config.Prefixes) > 0 {
+		args = append(args, "BCAST")
+		for _, prefix := range c.config.Prefixes {
+			args = append(args, "PREFIX", prefix)
+		}
+	}
+	return args
+}
+
+// listen applies invalidation messages until the cache is closed
+func (c *clientCache) listen() {
+	ctx := context.Background()
+	for {
+		msg, err := c.pubsub.ReceiveMessage(ctx)
+		if c.closed.Load() {
+			return
+		}
+		if err != nil {
+			// Invalidations may have been lost; serve from Redis until the
+			// connection is known to be up. A broken connection is replaced
+			// and onNotifierConnect restores the cache; an unreadable
+			// message (FLUSHALL sends a null key list) only costs the purge
+			c.healthy.Store(false)
+			c.purge()
+			if errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			if c.pubsub.Ping(ctx) == nil {
+				c.healthy.Store(true)
+			} else {
+				time.Sleep(100 * time.Millisecond)
+			}
+			continue
+		}
+
+		// A nil key list (sent on FLUSHALL/FLUSHDB) drops everything
+		keys := msg.PayloadSlice
+		if keys == nil && msg.Payload != "" {
+			keys = []string{msg.Payload}
+		}
+		if keys == nil {
+			c.purge()
+			continue
+		}
+		c.forget(keys...)
+		c.invalidations.Add(int64(len(keys)))
+	}
+}
+
+// cacheable reports whether key may be cached locally
+func (c *clientCache) cacheable(key string) bool {
+	if len(c.config.Prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range c.config.Prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns key from memory, or reads it through the tracking client
+// Errors, including redis.Nil, come straight from Redis
+func (c *clientCache) get(ctx context.Context, key string) (string, error) {
+	if c.healthy.Load() {
+		if entry, ok := c.entries.Get(key); ok && c.config.Clock.Now().Before(entry.expires) {
+			c.hits.Add(1)
+			return entry.value, nil
+		}
+	}
+	c.misses.Add(1)
+
+	epoch := c.epoch.Load()
+	val, err := c.tracked.Load().Get(ctx, key).Result()
+	if err != nil {
+		return "", err
+	}
+	if c.healthy.Load() && c.epoch.Load() == epoch {
+		c.entries.Add(key, clientCacheEntry{value: val, expires: c.config.Clock.Now().Add(c.config.TTL)})
+	}
+	return val, nil
+}
+
+// forget drops keys from memory
+func (c *clientCache) forget(keys ...string) {
+	c.epoch.Add(1)
+	for _, key := range keys {
+		c.entries.Remove(key)
+	}
+}
+
+// purge drops every local entry
+func (c *clientCache) purge() {
+	c.epoch.Add(1)
+	c.entries.Purge()
+}
+
+// stats returns a snapshot of cache activity
+func (c *clientCache) stats() ClientCacheStats {
+	return ClientCacheStats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+		Keys:          c.entries.Len(),
+	}
+}
+
+// close stops listening and closes the cache's connections
+func (c *clientCache) close() error {
+	c.closed.Store(true)
+	c.healthy.Store(false)
+
+	var firstErr error
+	if c.pubsub != nil {
+		firstErr = c.pubsub.Close()
+	}
+	if err := c.notifier.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	if tracked := c.tracked.Load(); tracked != nil {
+		if err := tracked.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -24,6 +24,26 @@ type CacheClient interface {
 	Ping() error
 }
 
+// LocalCache defines the interface of in-process caches
+// LRUCache and ShardedLRUCache are interchangeable through it
+type LocalCache interface {
+	Get(key string) (interface{}, bool)
+	Set(key string, value interface{}) bool
+	Delete(key string) bool
+	Contains(key string) bool
+	Peek(key string) (interface{}, bool)
+	Keys() []string
+	Len() int
+	Clear()
+	Resize(size int) (int, error)
+}
+
+// Ensure the LRU caches implement the LocalCache interface
+var (
+	_ LocalCache = (*LRUCache)(nil)
+	_ LocalCache = (*ShardedLRUCache)(nil)
+)
+
 // JSONCacheClient extends CacheClient with JSON serialization support
 type JSONCacheClient interface {
 	CacheClient
@@ -37,3 +57,9 @@ type JSONCacheClient interface {
 
 // Ensure RedisClient implements the JSONCacheClient interface
 var _ JSONCacheClient = (*RedisClient)(nil)
+
+// Ensure the serializers implement the Serializer interface
+var (
+	_ Serializer = JSONSerializer{}
+	_ Serializer = MsgpackSerializer{}
+)
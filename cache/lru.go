@@ -1,8 +1,7 @@
 package cache
 
 import (
-	"errors"
-
+	"github.com/alonecandies/golwarc/errs"
 	lru "github.com/hashicorp/golang-lru/v2"
 )
 
@@ -14,7 +13,7 @@ type LRUCache struct {
 // NewLRUCache creates a new LRU cache with the specified size
 func NewLRUCache(size int) (*LRUCache, error) {
 	if size <= 0 {
-		return nil, errors.New("cache size must be positive")
+		return nil, errs.New(errs.CodeCacheConfig, "cache size must be positive")
 	}
 
 	cache, err := lru.New[string, interface{}](size)
@@ -70,7 +69,7 @@ func (c *LRUCache) Keys() []string {
 // Resize changes the cache size (evicts if necessary)
 func (c *LRUCache) Resize(size int) (int, error) {
 	if size <= 0 {
-		return 0, errors.New("cache size must be positive")
+		return 0, errs.New(errs.CodeCacheConfig, "cache size must be positive")
 	}
 	return c.cache.Resize(size), nil
 }
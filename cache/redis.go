@@ -2,11 +2,10 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
+	"github.com/alonecandies/golwarc/errs"
 	"github.com/alonecandies/golwarc/libs"
 	"github.com/redis/go-redis/v9"
 )
@@ -15,8 +14,10 @@ import (
 // It provides a simplified interface for Redis operations with JSON support,
 // automatic error handling, and TLS configuration support.
 type RedisClient struct {
-	client *redis.Client
-	ctx    context.Context
+	client     *redis.Client
+	ctx        context.Context
+	serializer Serializer
+	local      *clientCache
 }
 
 // RedisConfig holds Redis connection configuration.
@@ -24,11 +25,15 @@ type RedisClient struct {
 // Password: Authentication password (empty for no auth)
 // DB: Database number (0-15)
 // TLS: Optional TLS configuration for secure connections
+// Serializer: Encoding used by SetJSON/GetJSON (default: JSONSerializer)
+// ClientCache: Optional client-side caching of Get/GetJSON results
 type RedisConfig struct {
-	Addr     string
-	Password string
-	DB       int
-	TLS      *libs.TLSConfig
+	Addr        string
+	Password    string
+	DB          int
+	TLS         *libs.TLSConfig
+	Serializer  Serializer
+	ClientCache *ClientCacheConfig
 }
 
 // NewRedisClient creates a new Redis client with the provided configuration.
@@ -66,38 +71,66 @@ func NewRedisClient(config RedisConfig) (*RedisClient, error) {
 		return nil, err
 	}
 
-	return &RedisClient{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	serializer := config.Serializer
+	if serializer == nil {
+		serializer = JSONSerializer{}
+	}
+
+	r := &RedisClient{
+		client:     client,
+		ctx:        ctx,
+		serializer: serializer,
+	}
+
+	if config.ClientCache != nil {
+		local, err := newClientCache(*options, *config.ClientCache)
+		if err != nil {
+			_ = client.Close() // Best effort cleanup
+			return nil, fmt.Errorf("failed to enable client-side caching: %w", err)
+		}
+		r.local = local
+	}
+
+	return r, nil
 }
 
-// Get retrieves a value from Redis
+// ErrCacheMiss is returned when a key does not exist
+var ErrCacheMiss = errs.New(errs.CodeCacheMiss, "key does not exist")
+
+// Get retrieves a value from Redis, or from memory when client-side caching
+// is enabled and the key is cached
 func (r *RedisClient) Get(key string) (string, error) {
-	val, err := r.client.Get(r.ctx, key).Result()
+	var val string
+	var err error
+	if r.local != nil && r.local.cacheable(key) {
+		val, err = r.local.get(r.ctx, key)
+	} else {
+		val, err = r.client.Get(r.ctx, key).Result()
+	}
 	if err == redis.Nil {
-		return "", errors.New("key does not exist")
+		return "", ErrCacheMiss
 	}
 	return val, err
 }
 
-// GetJSON retrieves a JSON value and unmarshals it
+// GetJSON retrieves a value and decodes it with the client's serializer
 func (r *RedisClient) GetJSON(key string, dest interface{}) error {
 	val, err := r.Get(key)
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal([]byte(val), dest)
+	return r.serializer.Unmarshal([]byte(val), dest)
 }
 
 // Set stores a value in Redis with optional TTL
 func (r *RedisClient) Set(key string, value interface{}, ttl time.Duration) error {
+	defer r.forget(key)
 	return r.client.Set(r.ctx, key, value, ttl).Err()
 }
 
-// SetJSON stores a JSON value in Redis
+// SetJSON encodes a value with the client's serializer and stores it in Redis
 func (r *RedisClient) SetJSON(key string, value interface{}, ttl time.Duration) error {
-	data, err := json.Marshal(value)
+	data, err := r.serializer.Marshal(value)
 	if err != nil {
 		return err
 	}
@@ -106,11 +139,13 @@ func (r *RedisClient) SetJSON(key string, value interface{}, ttl time.Duration)
 
 // Delete removes a key from Redis
 func (r *RedisClient) Delete(key string) error {
+	defer r.forget(key)
 	return r.client.Del(r.ctx, key).Err()
 }
 
 // DeleteMany removes multiple keys from Redis
 func (r *RedisClient) DeleteMany(keys ...string) error {
+	defer r.forget(keys...)
 	return r.client.Del(r.ctx, keys...).Err()
 }
 
@@ -147,21 +182,25 @@ func (r *RedisClient) GetWithTTL(key string) (string, time.Duration, error) {
 
 // Increment increments a key's value by 1
 func (r *RedisClient) Increment(key string) (int64, error) {
+	defer r.forget(key)
 	return r.client.Incr(r.ctx, key).Result()
 }
 
 // IncrementBy increments a key's value by the specified amount
 func (r *RedisClient) IncrementBy(key string, value int64) (int64, error) {
+	defer r.forget(key)
 	return r.client.IncrBy(r.ctx, key, value).Result()
 }
 
 // Decrement decrements a key's value by 1
 func (r *RedisClient) Decrement(key string) (int64, error) {
+	defer r.forget(key)
 	return r.client.Decr(r.ctx, key).Result()
 }
 
 // SetNX sets a key only if it doesn't exist (atomic)
 func (r *RedisClient) SetNX(key string, value interface{}, ttl time.Duration) (bool, error) {
+	defer r.forget(key)
 	return r.client.SetNX(r.ctx, key, value, ttl).Result()
 }
 
@@ -173,27 +212,58 @@ func (r *RedisClient) MGet(keys ...string) ([]interface{}, error) {
 // MSet sets multiple keys at once
 func (r *RedisClient) MSet(pairs map[string]interface{}) error {
 	args := make([]interface{}, 0, len(pairs)*2)
+	keys := make([]string, 0, len(pairs))
 	for k, v := range pairs {
 		args = append(args, k, v)
+		keys = append(keys, k)
 	}
+	defer r.forget(keys...)
 	return r.client.MSet(r.ctx, args...).Err()
 }
 
 // FlushDB clears all keys in the current database
 func (r *RedisClient) FlushDB() error {
+	if r.local != nil {
+		defer r.local.purge()
+	}
 	return r.client.FlushDB(r.ctx).Err()
 }
 
 // Close closes the Redis connection
 func (r *RedisClient) Close() error {
+	if r.local != nil {
+		_ = r.local.close() // Error intentionally ignored on close
+	}
 	return r.client.Close()
 }
 
+// forget drops keys written through this client from the client-side cache
+// so the next read sees the write without waiting for the invalidation
+func (r *RedisClient) forget(keys ...string) {
+	if r.local != nil {
+		r.local.forget(keys...)
+	}
+}
+
+// ClientCacheStats reports client-side cache activity
+// It returns zero stats when client-side caching is disabled
+func (r *RedisClient) ClientCacheStats() ClientCacheStats {
+	if r.local == nil {
+		return ClientCacheStats{}
+	}
+	return r.local.stats()
+}
+
 // Ping checks if the Redis connection is alive
 func (r *RedisClient) Ping() error {
 	return r.client.Ping(r.ctx).Err()
 }
 
+// Serializer returns the serializer used by SetJSON and GetJSON
+func (r *RedisClient) Serializer() Serializer {
+	return r.serializer
+}
+
 // GetClient returns the underlying Redis client for advanced operations
 func (r *RedisClient) GetClient() *redis.Client {
 	return r.client
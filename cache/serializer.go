@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Serializer names accepted by SerializerByName
+const (
+	SerializerJSON    = "json"
+	SerializerMsgpack = "msgpack"
+)
+
+// Serializer encodes values stored with SetJSON and decodes them in GetJSON
+type Serializer interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	Name() string
+}
+
+// JSONSerializer encodes values with encoding/json
+type JSONSerializer struct{}
+
+// Marshal implements Serializer
+func (JSONSerializer) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements Serializer
+func (JSONSerializer) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// Name implements Serializer
+func (JSONSerializer) Name() string {
+	return SerializerJSON
+}
+
+// MsgpackSerializer encodes values as MessagePack, which is faster and
+// smaller than JSON. Struct fields are named by their json tags so types
+// cached as JSON need no extra tags
+type MsgpackSerializer struct{}
+
+// Marshal implements Serializer
+func (MsgpackSerializer) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal implements Serializer
+func (MsgpackSerializer) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// Name implements Serializer
+func (MsgpackSerializer) Name() string {
+	return SerializerMsgpack
+}
+
+// SerializerByName returns the serializer for a config name
+// An empty name selects JSON
+func SerializerByName(name string) (Serializer, error) {
+	switch name {
+	case "", SerializerJSON:
+		return JSONSerializer{}, nil
+	case SerializerMsgpack:
+		return MsgpackSerializer{}, nil
+	default:
+		return nil, errs.Newf(errs.CodeCacheConfig, "unknown cache serializer %q", name)
+	}
+}
@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"hash/maphash"
+	"runtime"
+
+	"github.com/alonecandies/golwarc/errs"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// ShardedLRUCache splits an LRU cache into shards hashed by key, each with
+// its own lock, so hot concurrent workloads such as URL dedup do not contend
+// on a single mutex. Eviction is per shard, so the least recently used key
+// overall is not always the first evicted
+type ShardedLRUCache struct {
+	shards []*lru.Cache[string, interface{}]
+	mask   uint64
+	seed   maphash.Seed
+}
+
+// NewShardedLRUCache creates a sharded LRU cache holding about size items
+// shards is rounded up to a power of two; 0 uses one shard per CPU. Each
+// shard holds size/shards items, rounded up
+func NewShardedLRUCache(size, shards int) (*ShardedLRUCache, error) {
+	if size <= 0 {
+		return nil, errs.New(errs.CodeCacheConfig, "cache size must be positive")
+	}
+	if shards < 0 {
+		return nil, errs.New(errs.CodeCacheConfig, "shard count must not be negative")
+	}
+	if shards == 0 {
+		shards = runtime.NumCPU()
+	}
+	shards = min(nextPowerOfTwo(shards), nextPowerOfTwo(size))
+
+	c := &ShardedLRUCache{
+		shards: make([]*lru.Cache[string, interface{}], shards),
+		mask:   uint64(shards - 1),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range c.shards {
+		shard, err := lru.New[string, interface{}](shardSize(size, shards))
+		if err != nil {
+			return nil, err
+		}
+		c.shards[i] = shard
+	}
+	return c, nil
+}
+
+// nextPowerOfTwo returns the smallest power of two >= n
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// shardSize returns the capacity of each shard
+func shardSize(size, shards int) int {
+	return (size + shards - 1) / shards
+}
+
+// shard returns the shard that owns key
+func (c *ShardedLRUCache) shard(key string) *lru.Cache[string, interface{}] {
+	return c.shards[maphash.String(c.seed, key)&c.mask]
+}
+
+// Get retrieves a value from the cache
+func (c *ShardedLRUCache) Get(key string) (interface{}, bool) {
+	return c.shard(key).Get(key)
+}
+
+// Set stores a value in the cache and reports whether an eviction occurred
+func (c *ShardedLRUCache) Set(key string, value interface{}) bool {
+	return c.shard(key).Add(key, value)
+}
+
+// Delete removes a value from the cache
+func (c *ShardedLRUCache) Delete(key string) bool {
+	return c.shard(key).Remove(key)
+}
+
+// Clear removes all items from the cache
+func (c *ShardedLRUCache) Clear() {
+	for _, shard := range c.shards {
+		shard.Purge()
+	}
+}
+
+// Len returns the number of items in the cache
+func (c *ShardedLRUCache) Len() int {
+	n := 0
+	for _, shard := range c.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+// Contains checks if a key exists in the cache
+func (c *ShardedLRUCache) Contains(key string) bool {
+	return c.shard(key).Contains(key)
+}
+
+// Peek returns the value without updating the LRU
+func (c *ShardedLRUCache) Peek(key string) (interface{}, bool) {
+	return c.shard(key).Peek(key)
+}
+
+// Keys returns all keys in the cache, shard by shard
+// Keys are ordered newest to oldest within a shard only
+func (c *ShardedLRUCache) Keys() []string {
+	keys := make([]string, 0, c.Len())
+	for _, shard := range c.shards {
+		keys = append(keys, shard.Keys()...)
+	}
+	return keys
+}
+
+// Resize changes the total cache size (evicts if necessary)
+// It returns the number of evicted items
+func (c *ShardedLRUCache) Resize(size int) (int, error) {
+	if size <= 0 {
+		return 0, errs.New(errs.CodeCacheConfig, "cache size must be positive")
+	}
+	evicted := 0
+	for _, shard := range c.shards {
+		evicted += shard.Resize(shardSize(size, len(c.shards)))
+	}
+	return evicted, nil
+}
+
+// Shards returns the number of shards
+func (c *ShardedLRUCache) Shards() int {
+	return len(c.shards)
+}
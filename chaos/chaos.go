@@ -0,0 +1,163 @@
+// Package chaos injects latency and failures into calls to dependencies such
+// as Redis, the database and message queues, so the crawl pipeline's
+// fallbacks, retries and circuit breakers can be exercised without taking
+// the real services down
+//
+// An Injector describes the faults of one dependency. Hooks adapt it to a
+// client: RedisHook for go-redis, GormPlugin for gorm, and the Faults field
+// of the message-queue client configs. A nil Injector injects nothing, so
+// production code can call it unconditionally
+package chaos
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Dependency names used by ParseSpec and the chaos config section
+const (
+	DependencyCache    = "cache"
+	DependencyDatabase = "database"
+	DependencyQueue    = "queue"
+)
+
+// ErrInjected is the default error of a failed call
+var ErrInjected = errs.New(errs.CodeInjectedFault, "injected fault")
+
+// Fault describes how calls to a dependency misbehave
+type Fault struct {
+	Latency   time.Duration // Added to every call
+	Jitter    time.Duration // Random extra latency up to this much
+	ErrorRate float64       // Fraction of calls that fail, 0 to 1
+	Err       error         // Returned by failed calls; defaults to ErrInjected
+}
+
+// IsZero reports whether the fault injects nothing
+func (f Fault) IsZero() bool {
+	return f.Latency <= 0 && f.Jitter <= 0 && f.ErrorRate <= 0
+}
+
+// InjectorConfig holds injector configuration
+type InjectorConfig struct {
+	Name  string      // Dependency name prefixed to injected errors, e.g. "cache"
+	Fault Fault       // Initial fault; change it later with SetFault
+	Seed  uint64      // Makes failures reproducible; 0 picks a random seed
+	Clock clock.Clock // Latency sleeps; defaults to the wall clock
+}
+
+// Stats counts calls that went through an injector
+type Stats struct {
+	Calls    int64         `json:"calls"`
+	Failures int64         `json:"failures"` // Calls failed with the fault's error
+	Delay    time.Duration `json:"delay"`    // Total latency added
+}
+
+// Injector injects the faults of one dependency; it is safe for concurrent
+// use and its fault may be changed while clients use it
+type Injector struct {
+	name  string
+	clock clock.Clock
+
+	mu    sync.Mutex
+	fault Fault
+	rng   *rand.Rand
+	stats Stats
+}
+
+// NewInjector creates an injector
+func NewInjector(config InjectorConfig) *Injector {
+	seed := config.Seed
+	if seed == 0 {
+		seed = rand.Uint64()
+	}
+	return &Injector{
+		name:  config.Name,
+		clock: clock.Or(config.Clock),
+		fault: config.Fault,
+		rng:   rand.New(rand.NewPCG(seed, seed)),
+	}
+}
+
+// Name returns the dependency name
+func (i *Injector) Name() string {
+	if i == nil {
+		return ""
+	}
+	return i.name
+}
+
+// Fault returns the current fault
+func (i *Injector) Fault() Fault {
+	if i == nil {
+		return Fault{}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.fault
+}
+
+// SetFault replaces the fault; the zero Fault turns injection off
+func (i *Injector) SetFault(fault Fault) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.fault = fault
+}
+
+// Stats returns the calls seen so far
+func (i *Injector) Stats() Stats {
+	if i == nil {
+		return Stats{}
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.stats
+}
+
+// Inject runs before a call to the dependency: it sleeps for the fault's
+// latency and then fails the call at the fault's error rate. The context's
+// error is returned when ctx is done during the sleep, as a slow dependency
+// would time out. A nil injector returns nil at once
+func (i *Injector) Inject(ctx context.Context) error {
+	if i == nil {
+		return nil
+	}
+
+	i.mu.Lock()
+	fault := i.fault
+	delay := fault.Latency
+	if fault.Jitter > 0 {
+		delay += time.Duration(i.rng.Int64N(int64(fault.Jitter) + 1))
+	}
+	fail := fault.ErrorRate > 0 && i.rng.Float64() < fault.ErrorRate
+	i.stats.Calls++
+	i.stats.Delay += max(delay, 0)
+	if fail {
+		i.stats.Failures++
+	}
+	i.mu.Unlock()
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if err := clock.Sleep(ctx, i.clock, delay); err != nil {
+		return err
+	}
+	if !fail {
+		return nil
+	}
+
+	err := fault.Err
+	if err == nil {
+		err = ErrInjected
+	}
+	if i.name == "" {
+		return err
+	}
+	return fmt.Errorf("%s: %w", i.name, err)
+}
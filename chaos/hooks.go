@@ -0,0 +1,97 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+// RedisHook returns a go-redis hook that injects i's faults into every
+// command and pipeline; add it with (*redis.Client).AddHook. Failed
+// commands never reach the server
+func RedisHook(i *Injector) redis.Hook {
+	return redisHook{injector: i}
+}
+
+// redisHook implements redis.Hook
+type redisHook struct {
+	injector *Injector
+}
+
+// DialHook leaves connecting alone; a failed dial would only be retried by
+// the pool
+func (h redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+// ProcessHook injects a fault before each command
+func (h redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if err := h.injector.Inject(ctx); err != nil {
+			cmd.SetErr(err)
+			return err
+		}
+		return next(ctx, cmd)
+	}
+}
+
+// ProcessPipelineHook injects one fault per pipeline or transaction, failing
+// all of its commands together
+func (h redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		if err := h.injector.Inject(ctx); err != nil {
+			for _, cmd := range cmds {
+				cmd.SetErr(err)
+			}
+			return err
+		}
+		return next(ctx, cmds)
+	}
+}
+
+// GormPlugin returns a gorm plugin that injects i's faults into every
+// create, query, update, delete, row and raw statement; register it with
+// (*gorm.DB).Use. Failed statements are never sent to the database
+func GormPlugin(i *Injector) gorm.Plugin {
+	return gormPlugin{injector: i}
+}
+
+// gormPlugin implements gorm.Plugin
+type gormPlugin struct {
+	injector *Injector
+}
+
+// Name implements gorm.Plugin
+func (p gormPlugin) Name() string {
+	return "golwarc:chaos"
+}
+
+// Initialize registers a callback ahead of every other one for each
+// statement type, so a failed statement does not even open a transaction
+func (p gormPlugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("chaos:create", p.inject),
+		callbacks.Query().Before("*").Register("chaos:query", p.inject),
+		callbacks.Update().Before("*").Register("chaos:update", p.inject),
+		callbacks.Delete().Before("*").Register("chaos:delete", p.inject),
+		callbacks.Row().Before("*").Register("chaos:row", p.inject),
+		callbacks.Raw().Before("*").Register("chaos:raw", p.inject),
+	)
+}
+
+// inject adds the injected error to the statement, which stops gorm from
+// executing it
+func (p gormPlugin) inject(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	if err := p.injector.Inject(db.Statement.Context); err != nil {
+		_ = db.AddError(err) // Returned to the caller through db.Error
+	}
+}
@@ -0,0 +1,91 @@
+package chaos
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// EnvVar names the environment variable holding a fault spec; when set it
+// replaces the faults of the chaos config section
+const EnvVar = "GOLWARC_CHAOS"
+
+// ParseSpec parses faults by dependency from a spec such as
+//
+//	cache:latency=50ms,jitter=10ms,error_rate=0.2;database:error_rate=0.1
+//
+// Dependencies are separated by semicolons. Each has latency and jitter
+// durations and an error_rate between 0 and 1; omitted settings are zero
+func ParseSpec(spec string) (map[string]Fault, error) {
+	faults := make(map[string]Fault)
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, settings, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, errs.Newf(errs.CodeInvalidConfig, "chaos spec %q: want dependency:setting=value,...", entry)
+		}
+		if _, exists := faults[name]; exists {
+			return nil, errs.Newf(errs.CodeInvalidConfig, "chaos spec: %s is listed twice", name)
+		}
+
+		fault, err := parseFault(settings)
+		if err != nil {
+			return nil, errs.Wrapf(err, errs.CodeInvalidConfig, "chaos spec for %s", name)
+		}
+		faults[name] = fault
+	}
+	return faults, nil
+}
+
+// parseFault parses the comma-separated settings of one dependency
+func parseFault(settings string) (Fault, error) {
+	var fault Fault
+	for _, setting := range strings.Split(settings, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return Fault{}, errs.Newf(errs.CodeInvalidConfig, "setting %q has no value", setting)
+		}
+
+		var err error
+		switch strings.TrimSpace(key) {
+		case "latency":
+			fault.Latency, err = parseDuration(value)
+		case "jitter":
+			fault.Jitter, err = parseDuration(value)
+		case "error_rate":
+			fault.ErrorRate, err = strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err == nil && (fault.ErrorRate < 0 || fault.ErrorRate > 1) {
+				err = errs.Newf(errs.CodeInvalidConfig, "error_rate %s is not between 0 and 1", value)
+			}
+		default:
+			err = errs.Newf(errs.CodeInvalidConfig, "unknown setting %q", key)
+		}
+		if err != nil {
+			return Fault{}, err
+		}
+	}
+	return fault, nil
+}
+
+// parseDuration parses a non-negative duration such as 50ms
+func parseDuration(value string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errs.Newf(errs.CodeInvalidConfig, "duration %s is negative", value)
+	}
+	return d, nil
+}
@@ -0,0 +1,119 @@
+// Package clock abstracts time so rate limiters, cooldowns, leases, TTLs
+// and heartbeat timeouts can be tested deterministically
+//
+// Production code takes a Clock in its config and falls back to Real. Tests
+// pass a Fake and move time forward with Advance instead of sleeping
+package clock
+
+import (
+	"context"
+	"time"
+)
+
+// Clock tells the time and creates timers
+type Clock interface {
+	// Now returns the current time
+	Now() time.Time
+
+	// Since returns the time elapsed since t
+	Since(t time.Time) time.Duration
+
+	// Until returns the duration until t
+	Until(t time.Time) time.Duration
+
+	// NewTimer creates a timer that fires once after d
+	NewTimer(d time.Duration) Timer
+
+	// NewTicker creates a ticker that fires every d
+	NewTicker(d time.Duration) Ticker
+
+	// AfterFunc calls f once d has elapsed
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a single event created by a Clock
+type Timer interface {
+	// C returns the channel the time is delivered on; nil for AfterFunc timers
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing; false if it already fired or was stopped
+	Stop() bool
+
+	// Reset changes the timer to fire after d; false if it had fired or was stopped
+	Reset(d time.Duration) bool
+}
+
+// Ticker delivers ticks at intervals
+type Ticker interface {
+	// C returns the channel ticks are delivered on
+	C() <-chan time.Time
+
+	// Stop turns off the ticker
+	Stop()
+
+	// Reset changes the ticker period to d
+	Reset(d time.Duration)
+}
+
+// Real is the wall clock
+var Real Clock = realClock{}
+
+// Or returns c, or Real when c is nil
+// Constructors use it to default optional Clock config fields
+func Or(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+// Sleep pauses for d on the clock, returning early with the context's error
+// when ctx is done first
+func Sleep(ctx context.Context, c Clock, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := c.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C():
+		return nil
+	}
+}
+
+// realClock implements Clock with the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time                  { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration { return time.Since(t) }
+func (realClock) Until(t time.Time) time.Duration { return time.Until(t) }
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return realTimer{time.AfterFunc(d, f)}
+}
+
+// realTimer adapts *time.Timer to Timer
+type realTimer struct {
+	*time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time { return t.Timer.C }
+
+// realTicker adapts *time.Ticker to Ticker
+type realTicker struct {
+	*time.Ticker
+}
+
+func (t realTicker) C() <-chan time.Time { return t.Ticker.C }
@@ -0,0 +1,226 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock that only moves when told to
+// Timers, tickers and AfterFunc callbacks due at or before the new time fire
+// during Advance or Set, in deadline order, and a ticker fires at most once
+// per move. AfterFunc callbacks run on the goroutine calling Advance, so
+// their effects are visible when it returns
+type Fake struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*fakeTimer
+}
+
+// fakeTimer is a pending timer, ticker or callback on a Fake clock
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	period   time.Duration // Non-zero for tickers
+	ch       chan time.Time
+	fn       func()
+}
+
+// NewFake creates a fake clock set to start
+// A zero start uses 2025-01-01 00:00:00 UTC
+func NewFake(start time.Time) *Fake {
+	if start.IsZero() {
+		start = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	f := &Fake{now: start}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the fake time
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// Since returns the fake time elapsed since t
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Until returns the fake duration until t
+func (f *Fake) Until(t time.Time) time.Duration {
+	return t.Sub(f.Now())
+}
+
+// NewTimer creates a timer that fires once the clock passes d from now
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: f, ch: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return t
+}
+
+// NewTicker creates a ticker that fires each time the clock passes a multiple of d
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	t := &fakeTimer{clock: f, period: d, ch: make(chan time.Time, 1)}
+	f.schedule(t, d)
+	return fakeTicker{t}
+}
+
+// AfterFunc calls fn once the clock passes d from now
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	t := &fakeTimer{clock: f, fn: fn}
+	f.schedule(t, d)
+	return t
+}
+
+// Advance moves the clock forward by d and fires everything that became due
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	target := f.now.Add(d)
+	f.mu.Unlock()
+	f.Set(target)
+}
+
+// Set moves the clock to t and fires everything that became due
+// Moving backwards only changes Now
+func (f *Fake) Set(t time.Time) {
+	for {
+		f.mu.Lock()
+		next := f.nextDueLocked(t)
+		if next == nil {
+			f.now = t
+			f.mu.Unlock()
+			return
+		}
+
+		// Step to each deadline so callbacks observe the time they were due
+		if next.deadline.After(f.now) {
+			f.now = next.deadline
+		}
+		if next.period > 0 {
+			// Ticks missed within this move are dropped, as a slow receiver would
+			missed := t.Sub(next.deadline)/next.period + 1
+			next.deadline = next.deadline.Add(missed * next.period)
+		} else {
+			f.removeLocked(next)
+		}
+		now := f.now
+		f.mu.Unlock()
+
+		next.fire(now)
+	}
+}
+
+// Waiters returns the number of pending timers, tickers and callbacks
+func (f *Fake) Waiters() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.waiters)
+}
+
+// BlockUntil waits until at least n timers, tickers or callbacks are pending
+// Tests call it before Advance to be sure the code under test is waiting
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.waiters) < n {
+		f.cond.Wait()
+	}
+}
+
+// schedule registers t to fire d from now; due timers fire immediately
+func (f *Fake) schedule(t *fakeTimer, d time.Duration) {
+	f.mu.Lock()
+	t.deadline = f.now.Add(d)
+	if d <= 0 && t.period == 0 {
+		now := f.now
+		f.mu.Unlock()
+		t.fire(now)
+		return
+	}
+	f.waiters = append(f.waiters, t)
+	f.cond.Broadcast()
+	f.mu.Unlock()
+}
+
+// nextDueLocked returns the earliest waiter due at or before t
+func (f *Fake) nextDueLocked(t time.Time) *fakeTimer {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].deadline.Before(f.waiters[j].deadline)
+	})
+	if len(f.waiters) == 0 || f.waiters[0].deadline.After(t) {
+		return nil
+	}
+	return f.waiters[0]
+}
+
+// removeLocked drops t from the waiters; false if it was not pending
+func (f *Fake) removeLocked(t *fakeTimer) bool {
+	for i, w := range f.waiters {
+		if w == t {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			f.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+// fire delivers the time or runs the callback
+// Like time.Ticker, a tick is dropped when the previous one was not received
+func (t *fakeTimer) fire(now time.Time) {
+	if t.fn != nil {
+		t.fn()
+		return
+	}
+	select {
+	case t.ch <- now:
+	default:
+	}
+}
+
+// C returns the channel the time is delivered on
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop implements Timer
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	return t.clock.removeLocked(t)
+}
+
+// Reset implements Timer
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	active := t.Stop()
+	t.clock.schedule(t, d)
+	return active
+}
+
+// fakeTicker adapts a periodic fakeTimer to Ticker
+type fakeTicker struct {
+	*fakeTimer
+}
+
+// Stop implements Ticker
+func (t fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+// Reset implements Ticker
+func (t fakeTicker) Reset(d time.Duration) {
+	if d <= 0 {
+		panic("clock: non-positive interval for Ticker.Reset")
+	}
+	t.clock.mu.Lock()
+	t.period = d
+	t.clock.mu.Unlock()
+	t.fakeTimer.Reset(d)
+}
@@ -4,30 +4,45 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/spf13/viper"
 )
 
 // Config holds all application configuration
 type Config struct {
 	App          AppConfig          `mapstructure:"app"`
+	API          APIConfig          `mapstructure:"api"`
 	Logger       LoggerConfig       `mapstructure:"logger"`
 	Cache        CacheConfig        `mapstructure:"cache"`
 	Database     DatabaseConfig     `mapstructure:"database"`
 	MessageQueue MessageQueueConfig `mapstructure:"message_queue"`
 	Temporal     TemporalConfig     `mapstructure:"temporal"`
 	Crawler      CrawlerConfig      `mapstructure:"crawler"`
+	Storage      StorageConfig      `mapstructure:"storage"`
+	ControlPlane ControlPlaneConfig `mapstructure:"control_plane"`
+	Chaos        ChaosConfig        `mapstructure:"chaos"`
 }
 
 // AppConfig holds general application settings
 type AppConfig struct {
 	Name        string `mapstructure:"name"`
 	Environment string `mapstructure:"environment"`
-	Port        int    `mapstructure:"port"`
+	Port        int    `mapstructure:"port" validate:"omitempty,min=1,max=65535"`
+	// Port of /metrics, /livez and /readyz; 0 disables the metrics server
+	MetricsPort int `mapstructure:"metrics_port" validate:"omitempty,min=1,max=65535"`
+	// Seconds between background health checks feeding /readyz and metrics
+	HealthInterval int `mapstructure:"health_interval" validate:"min=0"`
+}
+
+// APIConfig holds HTTP API server settings
+type APIConfig struct {
+	// Port of the API, web UI and archive endpoints; 0 disables the API server
+	Port int `mapstructure:"port" validate:"omitempty,min=1,max=65535"`
 }
 
 // LoggerConfig holds logging configuration
 type LoggerConfig struct {
-	Level       string   `mapstructure:"level"`
+	Level       string   `mapstructure:"level" validate:"omitempty,oneof=debug info warn error"`
 	Development bool     `mapstructure:"development"`
 	OutputPaths []string `mapstructure:"output_paths"`
 }
@@ -40,29 +55,49 @@ type CacheConfig struct {
 
 // LRUConfig holds LRU cache settings
 type LRUConfig struct {
-	Size int `mapstructure:"size"`
+	Size   int `mapstructure:"size" validate:"min=0"`
+	Shards int `mapstructure:"shards" validate:"min=0"` // Lock shards; 0 or 1 uses a single LRU
 }
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
-	Addr     string    `mapstructure:"addr"`
-	Password string    `mapstructure:"password"`
-	DB       int       `mapstructure:"db"`
-	TLS      TLSConfig `mapstructure:"tls"`
+	Addr        string                 `mapstructure:"addr"`
+	Password    string                 `mapstructure:"password"`
+	DB          int                    `mapstructure:"db" validate:"min=0,max=15"`
+	TLS         TLSConfig              `mapstructure:"tls"`
+	Serializer  string                 `mapstructure:"serializer" validate:"omitempty,oneof=json msgpack"` // Encoding of cached values (default: json)
+	ClientCache RedisClientCacheConfig `mapstructure:"client_cache"`
+}
+
+// RedisClientCacheConfig holds client-side caching settings for hot Redis keys
+type RedisClientCacheConfig struct {
+	Enabled  bool     `mapstructure:"enabled"`
+	MaxKeys  int      `mapstructure:"max_keys" validate:"min=0"` // local entries kept (default 10000)
+	TTL      int      `mapstructure:"ttl" validate:"min=0"`      // seconds a local entry may live (default 60)
+	Prefixes []string `mapstructure:"prefixes"`                  // only cache these key prefixes; empty caches every key read
 }
 
 // DatabaseConfig holds database configurations
 type DatabaseConfig struct {
-	MySQL      MySQLConfig      `mapstructure:"mysql"`
-	PostgreSQL PostgreSQLConfig `mapstructure:"postgresql"`
-	ClickHouse ClickHouseConfig `mapstructure:"clickhouse"`
-	BigTable   BigTableConfig   `mapstructure:"bigtable"`
+	MySQL        MySQLConfig        `mapstructure:"mysql"`
+	PostgreSQL   PostgreSQLConfig   `mapstructure:"postgresql"`
+	ClickHouse   ClickHouseConfig   `mapstructure:"clickhouse"`
+	BigTable     BigTableConfig     `mapstructure:"bigtable"`
+	Partitioning PartitioningConfig `mapstructure:"partitioning"`
+}
+
+// PartitioningConfig holds time-based partitioning settings for crawl_logs
+type PartitioningConfig struct {
+	Enabled   bool   `mapstructure:"enabled"`
+	Interval  string `mapstructure:"interval" validate:"omitempty,oneof=daily monthly"` // daily or monthly
+	Ahead     int    `mapstructure:"ahead" validate:"min=0"`                            // future partitions to pre-create
+	Retention int    `mapstructure:"retention" validate:"min=0"`                        // partitions to keep; 0 keeps all
 }
 
 // MySQLConfig holds MySQL connection settings
 type MySQLConfig struct {
 	Host     string    `mapstructure:"host"`
-	Port     int       `mapstructure:"port"`
+	Port     int       `mapstructure:"port" validate:"omitempty,min=1,max=65535"`
 	User     string    `mapstructure:"user"`
 	Password string    `mapstructure:"password"`
 	Database string    `mapstructure:"database"`
@@ -73,11 +108,11 @@ type MySQLConfig struct {
 // PostgreSQLConfig holds PostgreSQL connection settings
 type PostgreSQLConfig struct {
 	Host     string    `mapstructure:"host"`
-	Port     int       `mapstructure:"port"`
+	Port     int       `mapstructure:"port" validate:"omitempty,min=1,max=65535"`
 	User     string    `mapstructure:"user"`
 	Password string    `mapstructure:"password"`
 	Database string    `mapstructure:"database"`
-	SSLMode  string    `mapstructure:"sslmode"`
+	SSLMode  string    `mapstructure:"sslmode" validate:"omitempty,oneof=disable allow prefer require verify-ca verify-full"`
 	TimeZone string    `mapstructure:"timezone"`
 	TLS      TLSConfig `mapstructure:"tls"`
 }
@@ -85,7 +120,7 @@ type PostgreSQLConfig struct {
 // ClickHouseConfig holds ClickHouse connection settings
 type ClickHouseConfig struct {
 	Host     string `mapstructure:"host"`
-	Port     int    `mapstructure:"port"`
+	Port     int    `mapstructure:"port" validate:"omitempty,min=1,max=65535"`
 	User     string `mapstructure:"user"`
 	Password string `mapstructure:"password"`
 	Database string `mapstructure:"database"`
@@ -99,20 +134,87 @@ type BigTableConfig struct {
 
 // MessageQueueConfig holds message queue configurations
 type MessageQueueConfig struct {
-	Kafka    KafkaConfig    `mapstructure:"kafka"`
-	RabbitMQ RabbitMQConfig `mapstructure:"rabbitmq"`
+	Kafka    KafkaConfig          `mapstructure:"kafka"`
+	RabbitMQ RabbitMQConfig       `mapstructure:"rabbitmq"`
+	Payload  MessagePayloadConfig `mapstructure:"payload"`
+}
+
+// MessagePayloadConfig holds compression and size limits for published messages
+type MessagePayloadConfig struct {
+	CompressAbove  int    `mapstructure:"compress_above" validate:"min=0"`   // bytes; larger payloads are gzipped; 0 disables
+	MaxMessageSize int    `mapstructure:"max_message_size" validate:"min=0"` // bytes; larger payloads are offloaded; 0 disables
+	OffloadDir     string `mapstructure:"offload_dir"`                       // object store for oversized payloads; empty rejects them
+}
+
+// ControlPlaneConfig holds gRPC coordinator/worker control-plane settings
+type ControlPlaneConfig struct {
+	Address           string `mapstructure:"address"`                             // Coordinator listen/dial address; empty disables
+	WorkerID          string `mapstructure:"worker_id"`                           // Defaults to the hostname
+	HeartbeatInterval int    `mapstructure:"heartbeat_interval" validate:"min=0"` // seconds
+	HeartbeatTimeout  int    `mapstructure:"heartbeat_timeout" validate:"min=0"`  // seconds
+}
+
+// ChaosConfig holds fault injection settings for resilience testing; the
+// GOLWARC_CHAOS environment variable overrides the faults
+type ChaosConfig struct {
+	Enabled  bool        `mapstructure:"enabled"`
+	Seed     uint64      `mapstructure:"seed"` // makes failures reproducible; 0 picks a random seed
+	Cache    FaultConfig `mapstructure:"cache"`
+	Database FaultConfig `mapstructure:"database"`
+	Queue    FaultConfig `mapstructure:"queue"`
+}
+
+// FaultConfig holds the faults injected into one dependency
+type FaultConfig struct {
+	Latency   int     `mapstructure:"latency" validate:"min=0"`          // milliseconds added to every call
+	Jitter    int     `mapstructure:"jitter" validate:"min=0"`           // milliseconds of random extra latency
+	ErrorRate float64 `mapstructure:"error_rate" validate:"min=0,max=1"` // fraction of calls that fail
 }
 
 // KafkaConfig holds Kafka connection settings
 type KafkaConfig struct {
-	Brokers []string `mapstructure:"brokers"`
-	GroupID string   `mapstructure:"group_id"`
-	Topic   string   `mapstructure:"topic"`
+	Brokers     []string `mapstructure:"brokers"`
+	GroupID     string   `mapstructure:"group_id"`
+	Topic       string   `mapstructure:"topic"`
+	PartitionBy string   `mapstructure:"partition_by" validate:"omitempty,oneof=domain key least_bytes"` // domain (default), key, or least_bytes
 }
 
 // RabbitMQConfig holds RabbitMQ connection settings
 type RabbitMQConfig struct {
-	URL string `mapstructure:"url"`
+	URL      string                 `mapstructure:"url"`
+	Topology RabbitMQTopologyConfig `mapstructure:"topology"` // Declared at startup
+}
+
+// RabbitMQTopologyConfig declares exchanges, queues and bindings
+type RabbitMQTopologyConfig struct {
+	Exchanges []RabbitMQExchangeConfig `mapstructure:"exchanges"`
+	Queues    []RabbitMQQueueConfig    `mapstructure:"queues"`
+	Bindings  []RabbitMQBindingConfig  `mapstructure:"bindings"`
+}
+
+// RabbitMQExchangeConfig describes an exchange
+type RabbitMQExchangeConfig struct {
+	Name    string `mapstructure:"name" validate:"required"`
+	Kind    string `mapstructure:"kind" validate:"omitempty,oneof=direct fanout topic headers"` // direct (default), fanout, topic, or headers
+	Durable bool   `mapstructure:"durable"`
+}
+
+// RabbitMQQueueConfig describes a queue with its TTL and dead-letter policies
+type RabbitMQQueueConfig struct {
+	Name                 string `mapstructure:"name" validate:"required"`
+	Durable              bool   `mapstructure:"durable"`
+	MessageTTL           int    `mapstructure:"message_ttl" validate:"min=0"` // seconds; 0 keeps messages
+	MaxLength            int    `mapstructure:"max_length" validate:"min=0"`  // messages; 0 is unlimited
+	DeadLetterQueue      string `mapstructure:"dead_letter_queue"`            // declared and fed through the default exchange
+	DeadLetterExchange   string `mapstructure:"dead_letter_exchange"`         // used when dead_letter_queue is empty
+	DeadLetterRoutingKey string `mapstructure:"dead_letter_routing_key"`
+}
+
+// RabbitMQBindingConfig binds a queue to an exchange
+type RabbitMQBindingConfig struct {
+	Queue      string `mapstructure:"queue" validate:"required"`
+	Exchange   string `mapstructure:"exchange" validate:"required"`
+	RoutingKey string `mapstructure:"routing_key"`
 }
 
 // TemporalConfig holds Temporal connection settings
@@ -123,14 +225,97 @@ type TemporalConfig struct {
 
 // CrawlerConfig holds crawler settings
 type CrawlerConfig struct {
-	UserAgent         string          `mapstructure:"user_agent"`
-	MaxDepth          int             `mapstructure:"max_depth"`
-	Concurrency       int             `mapstructure:"concurrency"`
-	RequestTimeout    int             `mapstructure:"request_timeout"`
-	RateLimitDelay    int             `mapstructure:"rate_limit_delay"`
-	SeleniumURL       string          `mapstructure:"selenium_url"`
-	PlaywrightBrowser string          `mapstructure:"playwright_browser"`
-	RateLimit         RateLimitConfig `mapstructure:"rate_limit"`
+	UserAgent         string               `mapstructure:"user_agent"`
+	MaxDepth          int                  `mapstructure:"max_depth" validate:"omitempty,min=1,max=10"`
+	Concurrency       int                  `mapstructure:"concurrency" validate:"omitempty,min=1,max=100"`
+	RequestTimeout    int                  `mapstructure:"request_timeout" validate:"omitempty,min=1,max=300"`
+	RateLimitDelay    int                  `mapstructure:"rate_limit_delay" validate:"min=0"`
+	SeleniumURL       string               `mapstructure:"selenium_url"`
+	PlaywrightBrowser string               `mapstructure:"playwright_browser" validate:"omitempty,oneof=chromium firefox webkit"`
+	PlaywrightStealth StealthConfig        `mapstructure:"playwright_stealth"`
+	Emulation         EmulationConfig      `mapstructure:"emulation"`
+	Engine            string               `mapstructure:"engine" validate:"omitempty,oneof=colly soup spider playwright puppeteer selenium"` // Engine of Container.NewCrawler; default colly
+	RateLimit         RateLimitConfig      `mapstructure:"rate_limit"`
+	Cooldown          CooldownConfig       `mapstructure:"cooldown"`
+	Project           string               `mapstructure:"project"`
+	SharedCorpus      bool                 `mapstructure:"shared_corpus"`                                                       // Deduplicate page bodies across projects
+	Dedup             string               `mapstructure:"dedup" validate:"omitempty,oneof=exact normalized content canonical"` // What makes two pages of the project the same: exact (default), normalized, content or canonical
+	Conditional       string               `mapstructure:"conditional" validate:"omitempty,oneof=cache database"`               // Where re-crawl validators are stored; empty disables
+	Proxies           []string             `mapstructure:"proxies"`
+	ProxyStrategy     string               `mapstructure:"proxy_strategy" validate:"omitempty,oneof=round_robin random sticky scored"` // round_robin, random, sticky, or scored
+	Frontier          FrontierConfig       `mapstructure:"frontier"`
+	ContentTypes      ContentTypeConfig    `mapstructure:"content_types"`
+	Extractors        string               `mapstructure:"extractors"`         // Path to a YAML or JSON extraction rules file; empty disables
+	StructuredData    bool                 `mapstructure:"structured_data"`    // Store products and articles described by JSON-LD, microdata, OpenGraph or Twitter Cards
+	FeedDiscovery     bool                 `mapstructure:"feed_discovery"`     // Register RSS, Atom and JSON feeds that crawled pages link to
+	SecurityAudit     bool                 `mapstructure:"security_audit"`     // Record and score the security headers (CSP, HSTS, X-Frame-Options) of every page
+	IgnoreRobotsMeta  bool                 `mapstructure:"ignore_robots_meta"` // Store noindex pages and follow nofollow links, for archival crawls
+	Assets            AssetConfig          `mapstructure:"assets"`
+	Certificates      CertificateConfig    `mapstructure:"certificates"`
+	SiteMetadata      SiteMetadataConfig   `mapstructure:"site_metadata"`
+	HSTS              HSTSConfig           `mapstructure:"hsts"`
+	Robots            RobotsConfig         `mapstructure:"robots"`
+	Identity          BotIdentityConfig    `mapstructure:"identity"`
+	BlockDetection    bool                 `mapstructure:"block_detection"` // Fail CAPTCHA and bot-wall pages of Container.NewCrawler with a BlockedError
+	Canonical         CanonicalConfig      `mapstructure:"canonical"`
+	QueryLearning     QueryLearningConfig  `mapstructure:"query_learning"`
+	CircuitBreaker    CircuitBreakerConfig `mapstructure:"circuit_breaker"`
+	DNSCache          DNSCacheConfig       `mapstructure:"dns_cache"`
+	HTTPCache         HTTPCacheConfig      `mapstructure:"http_cache"`
+	Priority          PriorityConfig       `mapstructure:"priority"`
+	CacheTTL          CacheTTLConfig       `mapstructure:"cache_ttl"`
+}
+
+// StealthConfig holds the fingerprint overrides of Playwright pages
+type StealthConfig struct {
+	Enabled        bool   `mapstructure:"enabled"`                          // Mask navigator.webdriver, HeadlessChrome and other headless tells
+	UserAgent      string `mapstructure:"user_agent"`                       // Browser user agent; default the browser's own without "Headless"
+	ViewportWidth  int    `mapstructure:"viewport_width" validate:"min=0"`  // CSS pixels; default 1280
+	ViewportHeight int    `mapstructure:"viewport_height" validate:"min=0"` // CSS pixels; default 720
+	Locale         string `mapstructure:"locale"`                           // e.g. en-US
+	Timezone       string `mapstructure:"timezone"`                         // IANA zone, e.g. America/New_York
+	Platform       string `mapstructure:"platform"`                         // navigator.platform, e.g. Win32
+	WebGLVendor    string `mapstructure:"webgl_vendor"`
+	WebGLRenderer  string `mapstructure:"webgl_renderer"`
+}
+
+// EmulationConfig selects the device and region presets browser engines emulate
+type EmulationConfig struct {
+	Device string `mapstructure:"device"` // e.g. iphone-15, pixel-7, ipad-air; see crawlers.Devices
+	Region string `mapstructure:"region"` // Locale key such as de-DE; sets locale, time zone and geolocation
+}
+
+// AssetConfig holds settings for downloading the images and files crawled
+// pages reference
+type AssetConfig struct {
+	Enabled    bool     `mapstructure:"enabled"`
+	Dir        string   `mapstructure:"dir"`                           // Object store directory; defaults to storage.object_dir
+	MaxSize    int64    `mapstructure:"max_size" validate:"min=0"`     // bytes; larger assets are skipped
+	Types      []string `mapstructure:"types"`                         // Media types or type/* wildcards; default image/* and application/pdf
+	MaxPerPage int      `mapstructure:"max_per_page" validate:"min=0"` // Assets downloaded per page
+}
+
+// FrontierConfig holds shared Redis crawl queue settings
+type FrontierConfig struct {
+	Enabled           bool   `mapstructure:"enabled"`                             // Requires cache.redis
+	Name              string `mapstructure:"name"`                                // Queue shared by cooperating processes
+	VisibilityTimeout int    `mapstructure:"visibility_timeout" validate:"min=0"` // seconds before an unacked URL is handed out again
+	MaxRetries        int    `mapstructure:"max_retries" validate:"min=0"`        // claims before a URL is dead-lettered
+}
+
+// StorageConfig holds page body storage settings
+type StorageConfig struct {
+	InlineMaxSize       int    `mapstructure:"inline_max_size" validate:"min=0"`                          // bytes; larger bodies are compressed
+	CompressedMaxSize   int    `mapstructure:"compressed_max_size" validate:"min=0"`                      // bytes; larger bodies use large_backend
+	LargeBackend        string `mapstructure:"large_backend" validate:"omitempty,oneof=gzip object warc"` // gzip, object, or warc
+	ObjectDir           string `mapstructure:"object_dir"`
+	WARCPath            string `mapstructure:"warc_path"`
+	WARCDedup           bool   `mapstructure:"warc_dedup"`                            // write revisit records for repeated payloads
+	WARCMaxSize         int64  `mapstructure:"warc_max_size" validate:"min=0"`        // bytes; a larger WARC file is closed and a new one started; 0 disables
+	WARCMaxAge          int    `mapstructure:"warc_max_age" validate:"min=0"`         // minutes before a WARC file is closed and a new one started; 0 disables
+	CompactBelow        int64  `mapstructure:"compact_below" validate:"min=0"`        // bytes; smaller WARC files are compacted together
+	ColdAfterDays       int    `mapstructure:"cold_after_days" validate:"min=0"`      // objects older than this move to cold storage; 0 disables
+	MaintenanceInterval int    `mapstructure:"maintenance_interval" validate:"min=0"` // minutes between maintenance runs; 0 disables
 }
 
 // LoadConfig loads configuration from file
@@ -155,9 +340,20 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
+// Validate checks every section against its validate tags
+// The error is a libs.ValidationErrors naming each invalid field, e.g.
+// "crawler.max_depth must be at most 10"
+func (c *Config) Validate() error {
+	return libs.NewValidator().ValidateStruct(c)
+}
+
 // TLSConfig holds TLS/SSL configuration
 type TLSConfig struct {
 	Enabled            bool   `mapstructure:"enabled"`
@@ -170,10 +366,142 @@ type TLSConfig struct {
 // RateLimitConfig holds rate limiting configuration
 type RateLimitConfig struct {
 	Enabled        bool `mapstructure:"enabled"`
-	Delay          int  `mapstructure:"delay"`            // milliseconds
-	RandomDelay    int  `mapstructure:"random_delay"`     // milliseconds
-	MaxConcurrent  int  `mapstructure:"max_concurrent"`   // max concurrent requests
-	RequestsPerSec int  `mapstructure:"requests_per_sec"` // max requests per second
+	Delay          int  `mapstructure:"delay" validate:"min=0"`            // milliseconds
+	RandomDelay    int  `mapstructure:"random_delay" validate:"min=0"`     // milliseconds
+	MaxConcurrent  int  `mapstructure:"max_concurrent" validate:"min=0"`   // max concurrent requests
+	RequestsPerSec int  `mapstructure:"requests_per_sec" validate:"min=0"` // max requests per second
+	Adaptive       bool `mapstructure:"adaptive"`                          // raise a domain's delay when it answers 429/503 or sends Retry-After
+	MaxDelay       int  `mapstructure:"max_delay" validate:"min=0"`        // milliseconds; upper bound of adaptive delays, default 60000
+}
+
+// CooldownConfig holds per-domain backoff settings for 429 and 503
+// responses; cooldowns are shared through Redis when cache.redis is set
+type CooldownConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	BaseDelay  int  `mapstructure:"base_delay" validate:"min=0"`  // seconds; first backoff when no Retry-After is sent, doubled per repeat, default 5
+	MaxDelay   int  `mapstructure:"max_delay" validate:"min=0"`   // seconds; upper bound of any cooldown, default 600
+	MaxRetries int  `mapstructure:"max_retries" validate:"min=0"` // throttled requeues of a URL before the spider skips it; default 5
+}
+
+// ContentTypeConfig holds crawler content type filtering settings
+type ContentTypeConfig struct {
+	Enabled      bool     `mapstructure:"enabled"`
+	Allow        []string `mapstructure:"allow"`         // Media types parsed as HTML, or type/* wildcards; default text/html and application/xhtml+xml
+	HeadRequests bool     `mapstructure:"head_requests"` // HEAD before GET so rejected URLs are not downloaded
+}
+
+// HSTSConfig holds HSTS and http to https upgrade settings
+type HSTSConfig struct {
+	Enabled      bool `mapstructure:"enabled"`                        // Crawl http URLs of hosts that sent an HSTS header over https
+	ProbeHTTPS   bool `mapstructure:"probe_https"`                    // Also upgrade http URLs whose host answers over https
+	ProbeTimeout int  `mapstructure:"probe_timeout" validate:"min=0"` // seconds; default 5
+}
+
+// BotIdentityConfig describes the crawler to site owners; with a name set
+// the user agent is built from it and replaces user_agent
+type BotIdentityConfig struct {
+	Name        string `mapstructure:"name"`    // Product token, e.g. AcmeBot; letters, '_' and '-'
+	Version     string `mapstructure:"version"` // e.g. 1.0
+	InfoURL     string `mapstructure:"info_url" validate:"omitempty,url"`
+	Email       string `mapstructure:"email" validate:"omitempty,email"`
+	Operator    string `mapstructure:"operator"`
+	Description string `mapstructure:"description"`
+}
+
+// RobotsConfig holds robots.txt settings; with Redis configured the files
+// are shared by every worker
+type RobotsConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	TTL           int  `mapstructure:"ttl" validate:"min=0"`             // seconds a fetched robots.txt is used; default 86400
+	ErrorTTL      int  `mapstructure:"error_ttl" validate:"min=0"`       // seconds a host whose robots.txt failed with 5xx stays disallowed; default 600
+	CrawlDelay    bool `mapstructure:"crawl_delay"`                      // Slow domains down to their Crawl-delay and Request-rate in the shared rate limiter
+	MaxCrawlDelay int  `mapstructure:"max_crawl_delay" validate:"min=0"` // seconds; longer delays are capped, 0 leaves them uncapped
+}
+
+// CircuitBreakerConfig holds per-host circuit breaker settings
+type CircuitBreakerConfig struct {
+	Enabled          bool `mapstructure:"enabled"`
+	FailureThreshold int  `mapstructure:"failure_threshold" validate:"min=0"` // consecutive failures that open a host's circuit; default 5
+	OpenTimeout      int  `mapstructure:"open_timeout" validate:"min=0"`      // seconds before an open circuit lets a probe through; default 30
+}
+
+// DNSCacheConfig holds shared DNS cache settings
+type DNSCacheConfig struct {
+	Enabled     bool     `mapstructure:"enabled"`
+	Servers     []string `mapstructure:"servers"`                       // host:port nameservers queried directly so record TTLs are honored; empty uses the system resolver
+	TTL         int      `mapstructure:"ttl" validate:"min=0"`          // seconds system resolver answers are cached; default 300
+	MaxTTL      int      `mapstructure:"max_ttl" validate:"min=0"`      // seconds; longer record TTLs are capped, default 3600
+	NegativeTTL int      `mapstructure:"negative_ttl" validate:"min=0"` // seconds missing hosts are cached when their zone sets no TTL; default 30
+	MaxEntries  int      `mapstructure:"max_entries" validate:"min=0"`  // hosts kept; default 10000
+}
+
+// HTTPCacheConfig holds Soup client response cache settings
+type HTTPCacheConfig struct {
+	Enabled      bool   `mapstructure:"enabled"`
+	Backend      string `mapstructure:"backend" validate:"omitempty,oneof=memory redis file"` // memory (default), redis (needs cache.redis) or file
+	Dir          string `mapstructure:"dir"`                                                  // directory of the file backend; default ./data/http-cache
+	MaxEntries   int    `mapstructure:"max_entries" validate:"min=0"`                         // responses kept by the memory backend; default 1000
+	MaxBodySize  int64  `mapstructure:"max_body_size" validate:"min=0"`                       // bytes; larger responses are not stored, default 10485760
+	HeuristicTTL int    `mapstructure:"heuristic_ttl"`                                        // seconds; cap of the freshness guessed from Last-Modified, default 86400, negative disables guessing
+	KeepStale    int    `mapstructure:"keep_stale" validate:"min=0"`                          // seconds responses with validators are kept for revalidation once stale; default 86400
+}
+
+// PriorityConfig weighs the Spider's crawl order; all zero is first in,
+// first out. URL rule priorities always count
+type PriorityConfig struct {
+	Depth             float64 `mapstructure:"depth" validate:"min=0"`               // score lost per link level
+	Domain            float64 `mapstructure:"domain" validate:"min=0"`              // score lost per URL already queued for the host
+	Sitemap           float64 `mapstructure:"sitemap" validate:"min=0"`             // score per unit of sitemap <priority>
+	Freshness         float64 `mapstructure:"freshness" validate:"min=0"`           // score of a page modified just now
+	FreshnessHalfLife int     `mapstructure:"freshness_half_life" validate:"min=0"` // hours; default 168
+}
+
+// CacheTTLConfig holds the page cache TTL policy
+type CacheTTLConfig struct {
+	Default  int             `mapstructure:"default" validate:"min=0"` // seconds pages no rule matches are cached; default 86400
+	Rules    []TTLRuleConfig `mapstructure:"rules"`                    // first match wins
+	Adaptive bool            `mapstructure:"adaptive"`                 // cache pages for half the interval they were seen to change at
+	MinTTL   int             `mapstructure:"min_ttl" validate:"min=0"` // seconds; adaptive lower bound, default 300
+	MaxTTL   int             `mapstructure:"max_ttl" validate:"min=0"` // seconds; adaptive upper bound, default 30 days
+}
+
+// TTLRuleConfig sets the cache TTL of matching pages; empty fields match any page
+type TTLRuleConfig struct {
+	ContentType string `mapstructure:"content_type"`                                         // e.g. text/html or application/*
+	Kind        string `mapstructure:"kind" validate:"omitempty,oneof=page article product"` // kind of record extracted with the page
+	Pattern     string `mapstructure:"pattern"`                                              // URL rule pattern, e.g. news.example.com or */docs/
+	TTL         int    `mapstructure:"ttl" validate:"min=1"`                                 // seconds
+}
+
+// CanonicalConfig holds URL canonical folding settings
+type CanonicalConfig struct {
+	WWW           string              `mapstructure:"www" validate:"omitempty,oneof=strip add"`            // strip or add the www. prefix; empty keeps hosts
+	TrailingSlash string              `mapstructure:"trailing_slash" validate:"omitempty,oneof=strip add"` // strip or add; empty keeps paths
+	IndexPages    []string            `mapstructure:"index_pages"`                                         // e.g. index.html, index.php; dropped from paths
+	QueryParams   map[string][]string `mapstructure:"query_params"`                                        // Query parameter whitelist per host, e.g. learned by query_learning
+	Dedupe        bool                `mapstructure:"dedupe"`                                              // Store pages under their canonical URL (rel=canonical, 301/308 redirects) and skip pages already stored under it
+}
+
+// CertificateConfig holds TLS certificate monitoring settings
+type CertificateConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	AlertDays  int  `mapstructure:"alert_days" validate:"min=0"`  // Warn about certificates expiring within this many days; default 14
+	CheckEvery int  `mapstructure:"check_every" validate:"min=0"` // hours between checks of one host; default 24
+}
+
+// SiteMetadataConfig holds settings for recording the name, favicon and web
+// app manifest of crawled sites
+type SiteMetadataConfig struct {
+	Enabled      bool `mapstructure:"enabled"`
+	RefreshEvery int  `mapstructure:"refresh_every" validate:"min=0"` // hours between updates of one host; default 24
+}
+
+// QueryLearningConfig holds query parameter whitelist learning settings
+type QueryLearningConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	SamplesPerParam int  `mapstructure:"samples_per_param" validate:"min=0"` // URLs tested per parameter; default 5
+	MinSamples      int  `mapstructure:"min_samples" validate:"min=0"`       // conclusive samples needed to decide; default 2
+	MaxURLs         int  `mapstructure:"max_urls" validate:"min=0"`          // stored URLs sampled per run; default 500
 }
 
 // LoadConfigOrDefault loads config from file or returns default config
@@ -189,9 +517,11 @@ func LoadConfigOrDefault(path string) *Config {
 func GetDefaultConfig() *Config {
 	return &Config{
 		App: AppConfig{
-			Name:        "golwarc",
-			Environment: "development",
-			Port:        8080,
+			Name:           "golwarc",
+			Environment:    "development",
+			Port:           8080,
+			MetricsPort:    9090,
+			HealthInterval: 15,
 		},
 		Logger: LoggerConfig{
 			Level:       "info",
@@ -217,5 +547,10 @@ func GetDefaultConfig() *Config {
 			SeleniumURL:       "http://localhost:4444/wd/hub",
 			PlaywrightBrowser: "chromium",
 		},
+		Storage: StorageConfig{
+			InlineMaxSize:     64 * 1024,
+			CompressedMaxSize: 1024 * 1024,
+			LargeBackend:      "gzip",
+		},
 	}
 }
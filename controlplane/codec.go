@@ -0,0 +1,59 @@
+package controlplane
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// codecName is the gRPC content-subtype of control-plane messages
+const codecName = "json"
+
+// jsonCodec encodes control-plane messages as JSON
+// Using plain structs keeps the package free of generated protobuf code
+type jsonCodec struct{}
+
+var _ encoding.Codec = jsonCodec{}
+
+// Marshal implements encoding.Codec
+func (jsonCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal implements encoding.Codec
+func (jsonCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// Name implements encoding.Codec
+func (jsonCodec) Name() string {
+	return codecName
+}
+
+// serviceName is the fully qualified gRPC service name
+const serviceName = "golwarc.controlplane.v1.ControlPlane"
+
+// connectMethod is the full method name of the bidirectional stream
+const connectMethod = "/" + serviceName + "/Connect"
+
+// controlPlaneServer is implemented by Coordinator
+type controlPlaneServer interface {
+	connect(stream grpc.ServerStream) error
+}
+
+// serviceDesc describes the control-plane service for grpc.Server
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*controlPlaneServer)(nil),
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName: "Connect",
+			Handler: func(srv any, stream grpc.ServerStream) error {
+				return srv.(controlPlaneServer).connect(stream)
+			},
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+}
@@ -0,0 +1,398 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/errs"
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"google.golang.org/grpc"
+)
+
+// Coordinator errors
+var (
+	ErrUnknownWorker  = errs.New(errs.CodeUnknownWorker, "unknown worker")
+	ErrUnknownTask    = errs.New(errs.CodeUnknownTask, "unknown task")
+	ErrNoWorkers      = errs.New(errs.CodeNoWorkers, "no healthy workers connected")
+	ErrWorkerBackedUp = errs.New(errs.CodeWorkerBackedUp, "worker send queue is full")
+)
+
+// CoordinatorConfig holds coordinator configuration
+type CoordinatorConfig struct {
+	HeartbeatTimeout time.Duration                            // Workers silent for longer are unhealthy
+	SendBuffer       int                                      // Queued messages per worker
+	OnResult         func(workerID string, result TaskResult) // Optional task outcome callback
+	Clock            clock.Clock                              // Heartbeat times; defaults to the wall clock
+}
+
+// WorkerStatus describes a connected worker
+type WorkerStatus struct {
+	ID          string
+	Tasks       []string
+	Health      Health
+	LastSeen    time.Time
+	ConnectedAt time.Time
+	Healthy     bool
+	TaskSchema  int // Task schema version negotiated with the worker
+}
+
+// Coordinator accepts worker streams and dispatches tasks over them
+type Coordinator struct {
+	heartbeatTimeout time.Duration
+	sendBuffer       int
+	onResult         func(workerID string, result TaskResult)
+	clock            clock.Clock
+
+	mu      sync.Mutex
+	workers map[string]*workerConn
+	config  map[string]string
+}
+
+// workerConn is the coordinator side of one worker stream
+type workerConn struct {
+	id          string
+	send        chan *Message
+	tasks       map[string]Task // Unfinished tasks by ID, as assigned
+	health      Health
+	lastSeen    time.Time
+	connectedAt time.Time
+	taskSchema  int // Version tasks are sent at
+}
+
+// NewCoordinator creates a new control-plane coordinator
+func NewCoordinator(config CoordinatorConfig) *Coordinator {
+	if config.HeartbeatTimeout == 0 {
+		config.HeartbeatTimeout = 30 * time.Second
+	}
+	if config.SendBuffer == 0 {
+		config.SendBuffer = 64
+	}
+
+	return &Coordinator{
+		heartbeatTimeout: config.HeartbeatTimeout,
+		sendBuffer:       config.SendBuffer,
+		onResult:         config.OnResult,
+		clock:            clock.Or(config.Clock),
+		workers:          make(map[string]*workerConn),
+	}
+}
+
+// NewServer creates a gRPC server with the control-plane codec and registers the coordinator
+func (c *Coordinator) NewServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.ForceServerCodec(jsonCodec{}))
+	server := grpc.NewServer(opts...)
+	c.Register(server)
+	return server
+}
+
+// Register registers the control-plane service on an existing gRPC server
+// The server must be created with grpc.ForceServerCodec or use NewServer
+func (c *Coordinator) Register(server *grpc.Server) {
+	server.RegisterService(&serviceDesc, c)
+}
+
+// connect serves one worker stream until it disconnects
+func (c *Coordinator) connect(stream grpc.ServerStream) error {
+	var hello Message
+	if err := stream.RecvMsg(&hello); err != nil {
+		return err
+	}
+	if hello.Type != TypeHello || hello.WorkerID == "" {
+		return errs.ToGRPC(errs.New(errs.CodeBadHandshake, "first message must be hello with a worker ID"))
+	}
+
+	// Workers older than task versioning send no range and read version 1
+	var workerSchema messagequeue.VersionRange
+	if hello.Schema != nil {
+		workerSchema = *hello.Schema
+	}
+	taskSchema, err := TaskSchema.Negotiate(workerSchema)
+	if err != nil {
+		return errs.ToGRPC(err)
+	}
+
+	conn, err := c.addWorker(hello.WorkerID, taskSchema)
+	if err != nil {
+		return err
+	}
+	defer c.removeWorker(conn)
+
+	// Only this goroutine writes to the stream
+	sendErr := make(chan error, 1)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for {
+			select {
+			case msg := <-conn.send:
+				if err := stream.SendMsg(msg); err != nil {
+					sendErr <- err
+					return
+				}
+			case <-done:
+				return
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			var msg Message
+			if err := stream.RecvMsg(&msg); err != nil {
+				recvErr <- err
+				return
+			}
+			c.handle(conn, &msg)
+		}
+	}()
+
+	select {
+	case err := <-recvErr:
+		return err
+	case err := <-sendErr:
+		return err
+	}
+}
+
+// addWorker registers a worker and queues the current config for it
+func (c *Coordinator) addWorker(id string, taskSchema int) (*workerConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.workers[id]; exists {
+		return nil, errs.ToGRPC(errs.Newf(errs.CodeWorkerConnected, "worker %s is already connected", id))
+	}
+
+	now := c.clock.Now()
+	conn := &workerConn{
+		id:          id,
+		send:        make(chan *Message, c.sendBuffer),
+		tasks:       make(map[string]Task),
+		lastSeen:    now,
+		connectedAt: now,
+		taskSchema:  taskSchema,
+	}
+	if c.config != nil {
+		conn.send <- &Message{Type: TypeConfig, Config: maps.Clone(c.config)}
+	}
+	c.workers[id] = conn
+	return conn, nil
+}
+
+// removeWorker forgets a disconnected worker and hands its unfinished tasks
+// to the remaining workers; tasks none can take are reported to OnResult as
+// failed so no crawl is lost silently
+func (c *Coordinator) removeWorker(conn *workerConn) {
+	c.mu.Lock()
+	if c.workers[conn.id] != conn {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.workers, conn.id)
+
+	var lost []TaskResult
+	for _, id := range slices.Sorted(maps.Keys(conn.tasks)) {
+		if _, err := c.assignAnyLocked(conn.tasks[id]); err != nil {
+			lost = append(lost, TaskResult{
+				TaskID: id,
+				Status: StatusFailed,
+				Error:  fmt.Sprintf("worker %s disconnected and the task could not be requeued: %v", conn.id, err),
+			})
+		}
+	}
+	c.mu.Unlock()
+
+	if c.onResult != nil {
+		for _, result := range lost {
+			c.onResult(conn.id, result)
+		}
+	}
+}
+
+// handle processes a message received from a worker
+func (c *Coordinator) handle(conn *workerConn, msg *Message) {
+	c.mu.Lock()
+	conn.lastSeen = c.clock.Now()
+
+	var result *TaskResult
+	switch msg.Type {
+	case TypeHeartbeat:
+		if msg.Health != nil {
+			conn.health = *msg.Health
+		}
+	case TypeResult:
+		if msg.Result != nil {
+			delete(conn.tasks, msg.Result.TaskID)
+			result = msg.Result
+		}
+	}
+	c.mu.Unlock()
+
+	if result != nil && c.onResult != nil {
+		c.onResult(conn.id, *result)
+	}
+}
+
+// Assign sends a task to a specific worker
+func (c *Coordinator) Assign(workerID string, task Task) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conn, ok := c.workers[workerID]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownWorker, workerID)
+	}
+	return c.assignLocked(conn, task)
+}
+
+// AssignAny hands a task to the healthy worker with the fewest running tasks
+// Workers whose task schema cannot express the task are passed over
+func (c *Coordinator) AssignAny(task Task) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.assignAnyLocked(task)
+}
+
+// assignAnyLocked is AssignAny; c.mu must be held
+func (c *Coordinator) assignAnyLocked(task Task) (string, error) {
+	encodable := make(map[int]bool) // Task schema version -> task can be sent at it
+	var best *workerConn
+	var outdated bool
+	for _, conn := range c.workers {
+		if !c.healthyLocked(conn) {
+			continue
+		}
+		ok, checked := encodable[conn.taskSchema]
+		if !checked {
+			_, err := encodeTask(task, conn.taskSchema)
+			ok = err == nil
+			encodable[conn.taskSchema] = ok
+		}
+		if !ok {
+			outdated = true
+			continue
+		}
+		if best == nil || len(conn.tasks) < len(best.tasks) ||
+			(len(conn.tasks) == len(best.tasks) && conn.id < best.id) {
+			best = conn
+		}
+	}
+	if best == nil && outdated {
+		return "", errs.Newf(errs.CodeSchemaUnsupported, "no healthy worker reads the task schema version task %s needs", task.ID)
+	}
+	if best == nil {
+		return "", ErrNoWorkers
+	}
+	if err := c.assignLocked(best, task); err != nil {
+		return "", err
+	}
+	return best.id, nil
+}
+
+// assignLocked queues an assign message; c.mu must be held
+func (c *Coordinator) assignLocked(conn *workerConn, task Task) error {
+	sent, err := encodeTask(task, conn.taskSchema)
+	if err != nil {
+		return fmt.Errorf("cannot assign task %s to worker %s: %w", task.ID, conn.id, err)
+	}
+	if err := trySend(conn, &Message{Type: TypeAssign, Task: sent}); err != nil {
+		return err
+	}
+	conn.tasks[task.ID] = task
+	return nil
+}
+
+// encodeTask converts a task to the given schema version
+// Older versions only ever drop fields, so the result is still a Task
+func encodeTask(task Task, version int) (*Task, error) {
+	if version == TaskSchema.Current() {
+		return &task, nil
+	}
+	data, err := TaskSchema.Marshal(task, version)
+	if err != nil {
+		return nil, err
+	}
+	var sent Task
+	if err := json.Unmarshal(data, &sent); err != nil {
+		return nil, fmt.Errorf("failed to decode task: %w", err)
+	}
+	return &sent, nil
+}
+
+// Cancel stops a running task on whichever worker holds it
+func (c *Coordinator) Cancel(taskID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, conn := range c.workers {
+		if _, ok := conn.tasks[taskID]; ok {
+			return trySend(conn, &Message{Type: TypeCancel, TaskID: taskID})
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrUnknownTask, taskID)
+}
+
+// PushConfig sends runtime configuration to every connected worker
+// Workers connecting later receive the latest config on connect
+func (c *Coordinator) PushConfig(config map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.config = maps.Clone(config)
+	for _, conn := range c.workers {
+		if err := trySend(conn, &Message{Type: TypeConfig, Config: maps.Clone(config)}); err != nil {
+			fmt.Printf("warning: failed to push config to worker %s: %v\n", conn.id, err)
+		}
+	}
+}
+
+// Workers returns the status of every connected worker sorted by ID
+func (c *Coordinator) Workers() []WorkerStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	statuses := make([]WorkerStatus, 0, len(c.workers))
+	for _, conn := range c.workers {
+		tasks := make([]string, 0, len(conn.tasks))
+		for id := range conn.tasks {
+			tasks = append(tasks, id)
+		}
+		sort.Strings(tasks)
+
+		statuses = append(statuses, WorkerStatus{
+			ID:          conn.id,
+			Tasks:       tasks,
+			Health:      conn.health,
+			LastSeen:    conn.lastSeen,
+			ConnectedAt: conn.connectedAt,
+			Healthy:     c.healthyLocked(conn),
+			TaskSchema:  conn.taskSchema,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// healthyLocked reports whether a worker has been heard from recently; c.mu must be held
+func (c *Coordinator) healthyLocked(conn *workerConn) bool {
+	return c.clock.Since(conn.lastSeen) <= c.heartbeatTimeout
+}
+
+// trySend queues a message without blocking the coordinator
+func trySend(conn *workerConn, msg *Message) error {
+	select {
+	case conn.send <- msg:
+		return nil
+	default:
+		return fmt.Errorf("%w: %s", ErrWorkerBackedUp, conn.id)
+	}
+}
@@ -0,0 +1,17 @@
+package controlplane
+
+// Dispatcher sends work and configuration to crawler workers
+// It lets schedulers switch between the gRPC control plane and a message queue
+type Dispatcher interface {
+	// AssignAny hands a task to the least busy healthy worker and returns its ID
+	AssignAny(task Task) (string, error)
+
+	// Cancel stops a running task wherever it was assigned
+	Cancel(taskID string) error
+
+	// PushConfig sends runtime configuration to every connected worker
+	PushConfig(config map[string]string)
+}
+
+// Ensure Coordinator implements Dispatcher
+var _ Dispatcher = (*Coordinator)(nil)
@@ -0,0 +1,83 @@
+package controlplane
+
+import (
+	"fmt"
+
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+)
+
+// MessageType identifies a control-plane message
+type MessageType string
+
+// Messages sent from workers to the coordinator
+const (
+	TypeHello     MessageType = "hello"     // First message on a stream; carries the worker ID
+	TypeHeartbeat MessageType = "heartbeat" // Periodic health report
+	TypeResult    MessageType = "result"    // Outcome of an assigned task
+)
+
+// Messages sent from the coordinator to workers
+const (
+	TypeAssign MessageType = "assign" // Start a task
+	TypeCancel MessageType = "cancel" // Stop a running task
+	TypeConfig MessageType = "config" // Replace the worker's runtime config
+)
+
+// Task outcome statuses
+const (
+	StatusDone     = "done"
+	StatusFailed   = "failed"
+	StatusCanceled = "canceled"
+)
+
+// Message is the single envelope exchanged on the control stream
+// Only the fields relevant to Type are set
+type Message struct {
+	Type     MessageType                `json:"type"`
+	WorkerID string                     `json:"worker_id,omitempty"`
+	Schema   *messagequeue.VersionRange `json:"schema,omitempty"` // Task schema versions the worker reads; sent with hello
+	Task     *Task                      `json:"task,omitempty"`
+	TaskID   string                     `json:"task_id,omitempty"`
+	Config   map[string]string          `json:"config,omitempty"`
+	Health   *Health                    `json:"health,omitempty"`
+	Result   *TaskResult                `json:"result,omitempty"`
+}
+
+// Task is a unit of crawl work assigned to a worker
+type Task struct {
+	ID      string `json:"id"`
+	URL     string `json:"url"`
+	Project string `json:"project,omitempty"`
+	Engine  string `json:"engine,omitempty"` // Crawler engine, e.g. crawlers.CrawlerTypePlaywright; empty uses the worker's default. Schema version 2
+}
+
+// TaskSchema versions tasks sent over the control stream or a queue
+//
+//	1: id, url, project
+//	2: adds engine; tasks that set it cannot be sent to version 1 workers,
+//	   which would crawl them with their default engine
+var TaskSchema = messagequeue.NewSchema("task", 2, map[int]messagequeue.Migration{
+	1: {
+		Down: func(doc map[string]any) error {
+			if engine, _ := doc["engine"].(string); engine != "" {
+				return fmt.Errorf("engine %q requires task schema version 2", engine)
+			}
+			delete(doc, "engine")
+			return nil
+		},
+	},
+})
+
+// TaskResult reports how a task ended
+type TaskResult struct {
+	TaskID     string `json:"task_id"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Health is a worker's self-reported state
+type Health struct {
+	ActiveTasks int    `json:"active_tasks"`
+	Status      string `json:"status,omitempty"`
+}
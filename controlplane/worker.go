@@ -0,0 +1,242 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// WorkerConfig holds worker configuration
+type WorkerConfig struct {
+	ID                string
+	Address           string                                     // Coordinator address (gRPC target)
+	HeartbeatInterval time.Duration                              // How often health is reported
+	DialOptions       []grpc.DialOption                          // Defaults to insecure transport credentials
+	OnTask            func(ctx context.Context, task Task) error // Runs an assigned task; ctx is canceled on cancel
+	OnConfig          func(config map[string]string)             // Optional config push callback
+	Clock             clock.Clock                                // Heartbeats and task durations; defaults to the wall clock
+}
+
+// Worker connects to a coordinator and runs the tasks it assigns
+type Worker struct {
+	id                string
+	address           string
+	heartbeatInterval time.Duration
+	dialOptions       []grpc.DialOption
+	onTask            func(ctx context.Context, task Task) error
+	onConfig          func(config map[string]string)
+	clock             clock.Clock
+
+	mu     sync.Mutex
+	tasks  map[string]context.CancelFunc
+	config map[string]string
+}
+
+// NewWorker creates a new control-plane worker
+func NewWorker(config WorkerConfig) (*Worker, error) {
+	if config.ID == "" {
+		return nil, fmt.Errorf("worker ID is required")
+	}
+	if config.Address == "" {
+		return nil, fmt.Errorf("coordinator address is required")
+	}
+	if config.OnTask == nil {
+		return nil, fmt.Errorf("task handler is required")
+	}
+	if config.HeartbeatInterval == 0 {
+		config.HeartbeatInterval = 10 * time.Second
+	}
+	if len(config.DialOptions) == 0 {
+		config.DialOptions = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+
+	return &Worker{
+		id:                config.ID,
+		address:           config.Address,
+		heartbeatInterval: config.HeartbeatInterval,
+		dialOptions:       config.DialOptions,
+		onTask:            config.OnTask,
+		onConfig:          config.OnConfig,
+		clock:             clock.Or(config.Clock),
+		tasks:             make(map[string]context.CancelFunc),
+	}, nil
+}
+
+// Run connects to the coordinator and processes messages until ctx is
+// canceled or the stream breaks; running tasks are canceled on return
+// Callers wanting reconnects should call Run in a retry loop
+func (w *Worker) Run(ctx context.Context) error {
+	opts := append([]grpc.DialOption{grpc.WithDefaultCallOptions(grpc.ForceCodec(jsonCodec{}))}, w.dialOptions...)
+	conn, err := grpc.NewClient(w.address, opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create coordinator client: %w", err)
+	}
+	defer func() {
+		_ = conn.Close() // Error intentionally ignored on close
+	}()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := conn.NewStream(ctx, &serviceDesc.Streams[0], connectMethod)
+	if err != nil {
+		return fmt.Errorf("failed to open control stream: %w", err)
+	}
+
+	var sendMu sync.Mutex
+	send := func(msg *Message) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		return stream.SendMsg(msg)
+	}
+
+	taskSchema := TaskSchema.Range()
+	if err := send(&Message{Type: TypeHello, WorkerID: w.id, Schema: &taskSchema}); err != nil {
+		return fmt.Errorf("failed to register with coordinator: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.heartbeat(ctx, send)
+	}()
+
+	for {
+		var msg Message
+		if err := stream.RecvMsg(&msg); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("control stream closed: %w", err)
+		}
+
+		switch msg.Type {
+		case TypeAssign:
+			if msg.Task == nil {
+				continue
+			}
+			task := *msg.Task
+			taskCtx, ok := w.startTask(ctx, task.ID)
+			if !ok {
+				continue // Already running
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				w.runTask(taskCtx, task, send)
+			}()
+		case TypeCancel:
+			w.cancelTask(msg.TaskID)
+		case TypeConfig:
+			w.mu.Lock()
+			w.config = maps.Clone(msg.Config)
+			w.mu.Unlock()
+			if w.onConfig != nil {
+				w.onConfig(maps.Clone(msg.Config))
+			}
+		}
+	}
+}
+
+// heartbeat reports health until ctx is canceled
+func (w *Worker) heartbeat(ctx context.Context, send func(*Message) error) {
+	ticker := w.clock.NewTicker(w.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			health := &Health{ActiveTasks: w.ActiveTasks(), Status: "ok"}
+			if err := send(&Message{Type: TypeHeartbeat, Health: health}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// runTask runs the task handler and reports its outcome
+func (w *Worker) runTask(ctx context.Context, task Task, send func(*Message) error) {
+	defer w.finishTask(task.ID)
+
+	started := w.clock.Now()
+	err := w.onTask(ctx, task)
+
+	result := &TaskResult{
+		TaskID:     task.ID,
+		Status:     StatusDone,
+		DurationMs: w.clock.Since(started).Milliseconds(),
+	}
+	switch {
+	case errors.Is(err, context.Canceled) || (err != nil && ctx.Err() != nil):
+		result.Status = StatusCanceled
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	}
+
+	if err := send(&Message{Type: TypeResult, Result: result}); err != nil {
+		fmt.Printf("warning: failed to report result for task %s: %v\n", task.ID, err)
+	}
+}
+
+// startTask tracks a task and returns its cancelable context
+// Returns false when a task with the same ID is already running
+func (w *Worker) startTask(ctx context.Context, taskID string) (context.Context, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, running := w.tasks[taskID]; running {
+		return nil, false
+	}
+	taskCtx, cancel := context.WithCancel(ctx)
+	w.tasks[taskID] = cancel
+	return taskCtx, true
+}
+
+// cancelTask cancels a running task
+func (w *Worker) cancelTask(taskID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cancel, ok := w.tasks[taskID]; ok {
+		cancel()
+	}
+}
+
+// finishTask forgets a completed task
+func (w *Worker) finishTask(taskID string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if cancel, ok := w.tasks[taskID]; ok {
+		cancel()
+		delete(w.tasks, taskID)
+	}
+}
+
+// ActiveTasks returns the number of tasks currently running
+func (w *Worker) ActiveTasks() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.tasks)
+}
+
+// Config returns the latest config pushed by the coordinator
+func (w *Worker) Config() map[string]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return maps.Clone(w.config)
+}
@@ -0,0 +1,542 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+)
+
+// AuthConfig describes a form login
+type AuthConfig struct {
+	// LoginURL is the page holding the login form. A path such as "/login"
+	// is resolved against each host the flow covers, so one flow can log in
+	// to several sites running the same software
+	LoginURL string
+
+	// Domains are the hosts the flow logs in to, e.g. "shop.example.com" or
+	// "*.example.com" for subdomains. Defaults to the host of an absolute
+	// LoginURL; with a path every host is covered
+	Domains []string
+
+	FormSelector string            // Selects the login form (default the first form with a password field)
+	Fields       map[string]string // Credentials and other fields, e.g. {"username": ..., "password": ...}
+
+	// CSRFSelector selects an element holding a CSRF token outside the form,
+	// e.g. `meta[name="csrf-token"]`; its content or value attribute is sent
+	// as CSRFField and in CSRFHeader. Hidden inputs of the form, tokens
+	// included, are always submitted
+	CSRFSelector string
+	CSRFField    string // Defaults to the element's name attribute
+	CSRFHeader   string // Optional, e.g. X-CSRF-Token
+
+	// TwoFactorSelector selects the second factor form on the page after the
+	// login; when it is there TwoFactor returns the fields to submit with it,
+	// e.g. {"code": totp}
+	TwoFactorSelector string
+	TwoFactor         func(ctx context.Context, challenge TwoFactorChallenge) (map[string]string, error)
+
+	// LoggedInSelector must match the page after a successful login, e.g.
+	// "a.logout". Without it a login fails when that page has an error
+	// status or still shows the login form
+	LoggedInSelector string
+
+	// LoginRequired reports whether a response means the session expired;
+	// defaults to a 401 or a redirect to the login page
+	LoginRequired func(resp *http.Response) bool
+
+	// Session holds the session cookies, e.g. NewCookieJar(store) to keep
+	// sessions across restarts; defaults to an in-memory jar
+	Session *CookieJar
+
+	UserAgent string        // Sent with login requests (default the GolwarcBot user agent)
+	Timeout   time.Duration // Per login request (default 30s)
+}
+
+// TwoFactorChallenge is the second factor page handed to AuthConfig.TwoFactor
+type TwoFactorChallenge struct {
+	URL      string
+	Document *goquery.Document
+}
+
+// LoginError is returned when a login flow fails
+type LoginError struct {
+	URL    string
+	Reason string
+	Err    error
+}
+
+// Error implements the error interface
+func (e *LoginError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("login at %s failed: %s: %v", e.URL, e.Reason, e.Err)
+	}
+	return fmt.Sprintf("login at %s failed: %s", e.URL, e.Reason)
+}
+
+// Unwrap returns the underlying error
+func (e *LoginError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode implements errs.Coder
+func (e *LoginError) ErrorCode() errs.Code {
+	return errs.CodeLoginFailed
+}
+
+// AuthFlow logs in to a site once per host, adds the session cookies to
+// every request for it and logs in again when a response shows the session
+// expired. Clients use it through the Auth field of their config; one flow
+// may be shared by several clients
+// A nil AuthFlow adds nothing
+type AuthFlow struct {
+	config  AuthConfig
+	login   *url.URL
+	domains libs.ValidationPolicy
+	session *CookieJar
+
+	mu    sync.Mutex
+	hosts map[string]*authHost
+}
+
+// authHost is the login state of one host
+type authHost struct {
+	mu         sync.Mutex
+	generation int   // Logins so far, including a session restored from the jar
+	err        error // Why the last login failed; returned until Login succeeds
+}
+
+// Compile-time check: the session can be shared with browser engines
+var _ CookieTransfer = (*AuthFlow)(nil)
+
+// NewAuthFlow creates a login flow
+func NewAuthFlow(config AuthConfig) (*AuthFlow, error) {
+	login, err := url.Parse(config.LoginURL)
+	if err != nil || config.LoginURL == "" {
+		return nil, errs.Newf(errs.CodeInvalidConfig, "invalid login URL %q", config.LoginURL)
+	}
+	if login.IsAbs() && len(config.Domains) == 0 {
+		config.Domains = []string{login.Hostname()}
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = "Mozilla/5.0 (compatible; GolwarcBot/1.0)"
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.TwoFactorSelector != "" && config.TwoFactor == nil {
+		return nil, errs.New(errs.CodeInvalidConfig, "TwoFactorSelector requires a TwoFactor hook")
+	}
+
+	session := config.Session
+	if session == nil {
+		if session, err = NewCookieJar(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return &AuthFlow{
+		config:  config,
+		login:   login,
+		domains: libs.ValidationPolicy{AllowedHosts: config.Domains},
+		session: session,
+		hosts:   make(map[string]*authHost),
+	}, nil
+}
+
+// Session returns the jar holding the session cookies
+func (f *AuthFlow) Session() *CookieJar {
+	return f.session
+}
+
+// ExportCookies implements CookieTransfer with the session cookies
+func (f *AuthFlow) ExportCookies() ([]Cookie, error) {
+	return f.session.ExportCookies()
+}
+
+// ImportCookies implements CookieTransfer, e.g. with the cookies of a
+// browser that logged in; the hosts they cover are not logged in to again
+// until their session expires
+func (f *AuthFlow) ImportCookies(cookies []Cookie) error {
+	return f.session.ImportCookies(cookies)
+}
+
+// Login logs in to the host of rawURL now instead of on its first request,
+// clearing an earlier failure
+func (f *AuthFlow) Login(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errs.Newf(errs.CodeInvalidURL, "invalid URL %q", rawURL)
+	}
+	host := f.host(u)
+	host.mu.Lock()
+	defer host.mu.Unlock()
+	return f.loginLocked(ctx, host, u, http.DefaultTransport)
+}
+
+// Transport wraps base so requests to the flow's hosts carry the session,
+// logging in first and again when the session expires. Login requests are
+// sent through base
+func (f *AuthFlow) Transport(base http.RoundTripper) http.RoundTripper {
+	if f == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &authTransport{flow: f, base: base}
+}
+
+// covers reports whether the flow logs in to the host of u
+func (f *AuthFlow) covers(u *url.URL) bool {
+	return len(f.config.Domains) == 0 || f.domains.AllowsHost(u.Hostname())
+}
+
+// host returns the login state of the host of u
+func (f *AuthFlow) host(u *url.URL) *authHost {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	host, ok := f.hosts[u.Host]
+	if !ok {
+		host = &authHost{}
+		f.hosts[u.Host] = host
+	}
+	return host
+}
+
+// loginURL resolves the login page for the host of u
+func (f *AuthFlow) loginURL(u *url.URL) *url.URL {
+	if f.login.IsAbs() {
+		return f.login
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).ResolveReference(f.login)
+}
+
+// ensure logs in to the host of u unless it has a session, returning the
+// generation of the session
+func (f *AuthFlow) ensure(ctx context.Context, u *url.URL, base http.RoundTripper) (int, error) {
+	host := f.host(u)
+	host.mu.Lock()
+	defer host.mu.Unlock()
+
+	if host.err != nil {
+		return 0, host.err
+	}
+	if host.generation == 0 {
+		if len(f.session.Cookies(f.loginURL(u))) > 0 {
+			host.generation = 1 // Restored from the jar
+		} else if err := f.loginLocked(ctx, host, u, base); err != nil {
+			return 0, err
+		}
+	}
+	return host.generation, nil
+}
+
+// refresh logs in to the host of u again unless another request already did
+// since the session of generation was used
+func (f *AuthFlow) refresh(ctx context.Context, u *url.URL, base http.RoundTripper, generation int) error {
+	host := f.host(u)
+	host.mu.Lock()
+	defer host.mu.Unlock()
+
+	if host.generation != generation {
+		return nil
+	}
+	return f.loginLocked(ctx, host, u, base)
+}
+
+// loginLocked logs in to host and records the outcome; host.mu is held
+// A failure is kept so wrong credentials are not retried on every request,
+// unless the request was cancelled
+func (f *AuthFlow) loginLocked(ctx context.Context, host *authHost, u *url.URL, base http.RoundTripper) error {
+	err := f.runLogin(ctx, u, base)
+	switch {
+	case err == nil:
+		host.generation++
+		host.err = nil
+	case ctx.Err() == nil:
+		host.err = err
+	}
+	return err
+}
+
+// runLogin fetches the login page, submits the form and the second factor,
+// and checks the result
+func (f *AuthFlow) runLogin(ctx context.Context, u *url.URL, base http.RoundTripper) error {
+	loginURL := f.loginURL(u).String()
+	client := &http.Client{Transport: base, Jar: f.session, Timeout: f.config.Timeout}
+
+	page, err := f.fetch(ctx, client, http.MethodGet, loginURL, nil, nil)
+	if err != nil {
+		return &LoginError{URL: loginURL, Reason: "fetching the login page", Err: err}
+	}
+	form := f.loginForm(page.doc)
+	if form.Length() == 0 {
+		return &LoginError{URL: loginURL, Reason: "no login form found"}
+	}
+
+	values := formValues(form)
+	header := http.Header{}
+	if f.config.CSRFSelector != "" {
+		token := page.doc.Find(f.config.CSRFSelector).First()
+		value, ok := token.Attr("content")
+		if !ok {
+			value, ok = token.Attr("value")
+		}
+		if !ok {
+			return &LoginError{URL: loginURL, Reason: "no CSRF token matches " + f.config.CSRFSelector}
+		}
+		field := f.config.CSRFField
+		if field == "" {
+			field, _ = token.Attr("name")
+		}
+		if field != "" {
+			values.Set(field, value)
+		}
+		if f.config.CSRFHeader != "" {
+			header.Set(f.config.CSRFHeader, value)
+		}
+	}
+	for name, value := range f.config.Fields {
+		values.Set(name, value)
+	}
+
+	result, err := f.submit(ctx, client, page, form, values, header)
+	if err != nil {
+		return &LoginError{URL: loginURL, Reason: "submitting the login form", Err: err}
+	}
+
+	if f.config.TwoFactorSelector != "" {
+		if challenge := result.doc.Find(f.config.TwoFactorSelector).First(); challenge.Length() > 0 {
+			fields, err := f.config.TwoFactor(ctx, TwoFactorChallenge{URL: result.url.String(), Document: result.doc})
+			if err != nil {
+				return &LoginError{URL: loginURL, Reason: "second factor", Err: err}
+			}
+			values := formValues(challenge)
+			for name, value := range fields {
+				values.Set(name, value)
+			}
+			if result, err = f.submit(ctx, client, result, challenge, values, header); err != nil {
+				return &LoginError{URL: loginURL, Reason: "submitting the second factor", Err: err}
+			}
+		}
+	}
+
+	switch {
+	case result.status >= http.StatusBadRequest:
+		return &LoginError{URL: loginURL, Reason: fmt.Sprintf("status %d", result.status)}
+	case f.config.LoggedInSelector != "" && result.doc.Find(f.config.LoggedInSelector).Length() == 0:
+		return &LoginError{URL: loginURL, Reason: "no element matches " + f.config.LoggedInSelector}
+	case f.config.LoggedInSelector == "" && f.loginForm(result.doc).Length() > 0:
+		return &LoginError{URL: loginURL, Reason: "the login form was shown again"}
+	}
+	return nil
+}
+
+// loginForm finds the login form of a page
+func (f *AuthFlow) loginForm(doc *goquery.Document) *goquery.Selection {
+	if f.config.FormSelector != "" {
+		return doc.Find(f.config.FormSelector).First()
+	}
+	return doc.Find("form").FilterFunction(func(_ int, form *goquery.Selection) bool {
+		return form.Find(`input[type="password"]`).Length() > 0
+	}).First()
+}
+
+// authPage is a page fetched during a login
+type authPage struct {
+	url    *url.URL // After redirects
+	status int
+	doc    *goquery.Document
+}
+
+// submit posts form, or sends it with its method, to its action
+func (f *AuthFlow) submit(ctx context.Context, client *http.Client, page *authPage, form *goquery.Selection, values url.Values, header http.Header) (*authPage, error) {
+	action := page.url
+	if href, ok := form.Attr("action"); ok && href != "" {
+		resolved, err := page.url.Parse(href)
+		if err != nil {
+			return nil, fmt.Errorf("invalid form action %q: %w", href, err)
+		}
+		action = resolved
+	}
+
+	if method, _ := form.Attr("method"); strings.EqualFold(method, http.MethodGet) {
+		target := *action
+		target.RawQuery = values.Encode()
+		return f.fetch(ctx, client, http.MethodGet, target.String(), nil, header)
+	}
+	return f.fetch(ctx, client, http.MethodPost, action.String(), strings.NewReader(values.Encode()), header)
+}
+
+// fetch sends a login request and parses the page it ends on
+func (f *AuthFlow) fetch(ctx context.Context, client *http.Client, method, rawURL string, body io.Reader, header http.Header) (*authPage, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", f.config.UserAgent)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", resp.Request.URL, err)
+	}
+	return &authPage{url: resp.Request.URL, status: resp.StatusCode, doc: doc}, nil
+}
+
+// formValues collects the fields a browser would submit with a form, hidden
+// inputs such as CSRF tokens included
+func formValues(form *goquery.Selection) url.Values {
+	values := url.Values{}
+	form.Find("input[name], select[name], textarea[name]").Each(func(_ int, field *goquery.Selection) {
+		name, _ := field.Attr("name")
+		switch goquery.NodeName(field) {
+		case "select":
+			option := field.Find("option[selected]").First()
+			if option.Length() == 0 {
+				option = field.Find("option").First()
+			}
+			if value, ok := option.Attr("value"); ok {
+				values.Add(name, value)
+			} else if option.Length() > 0 {
+				values.Add(name, option.Text())
+			}
+		case "textarea":
+			values.Add(name, field.Text())
+		default:
+			kind, _ := field.Attr("type")
+			switch strings.ToLower(kind) {
+			case "submit", "button", "image", "reset", "file":
+				return
+			case "checkbox", "radio":
+				if _, checked := field.Attr("checked"); !checked {
+					return
+				}
+			}
+			value, _ := field.Attr("value")
+			values.Add(name, value)
+		}
+	})
+	return values
+}
+
+// loginRequired reports whether resp, a response to a request for u, means
+// the session expired
+func (f *AuthFlow) loginRequired(u *url.URL, resp *http.Response) bool {
+	if f.config.LoginRequired != nil {
+		return f.config.LoginRequired(resp)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return false
+	}
+	location, err := resp.Location()
+	if err != nil {
+		return false
+	}
+	login := f.loginURL(u)
+	return location.Host == login.Host && location.Path == login.Path
+}
+
+// withSession returns req carrying the session cookies; they replace
+// cookies of the same name the client's own jar sent, which may be from an
+// expired session
+func (f *AuthFlow) withSession(req *http.Request) *http.Request {
+	cookies := f.session.Cookies(req.URL)
+	if len(cookies) == 0 {
+		return req
+	}
+	names := make(map[string]bool, len(cookies))
+	for _, c := range cookies {
+		names[c.Name] = true
+	}
+
+	sent := req.Cookies()
+	req = req.Clone(req.Context())
+	req.Header.Del("Cookie")
+	for _, c := range sent {
+		if !names[c.Name] {
+			req.AddCookie(c)
+		}
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+// authTransport adds the session to requests and refreshes it
+type authTransport struct {
+	flow *AuthFlow
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f := t.flow
+	if !f.covers(req.URL) {
+		return t.base.RoundTrip(req)
+	}
+	ctx := req.Context()
+
+	generation, err := f.ensure(ctx, req.URL, t.base)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.send(req)
+	if err != nil || !f.loginRequired(req.URL, resp) {
+		return resp, err
+	}
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil // The body cannot be sent again
+	}
+
+	// The session expired: log in again and retry once
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if err := f.refresh(ctx, req.URL, t.base, generation); err != nil {
+		return nil, err
+	}
+	retry := req.Clone(ctx)
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	return t.send(retry)
+}
+
+// send sends req with the session and keeps cookies the site rotates
+func (t *authTransport) send(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(t.flow.withSession(req))
+	if err == nil {
+		if cookies := resp.Cookies(); len(cookies) > 0 {
+			t.flow.session.SetCookies(req.URL, cookies)
+		}
+	}
+	return resp, err
+}
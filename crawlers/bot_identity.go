@@ -0,0 +1,101 @@
+package crawlers
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// botName matches product tokens robots.txt groups can name (RFC 9309)
+var botName = regexp.MustCompile(`^[A-Za-z_-]+$`)
+
+// botVersion matches an HTTP token (RFC 9110), the version after the slash
+var botVersion = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+.^_`|~-]+$")
+
+// BotIdentity tells site owners who runs a crawler and how to reach them
+// Many sites only allow a crawler once its user agent names it and links to
+// a page describing it
+type BotIdentity struct {
+	Name        string // Product token, e.g. AcmeBot; robots.txt groups match it case-insensitively
+	Version     string // Optional, e.g. 1.0
+	InfoURL     string // Page describing the crawler, e.g. https://acme.example/bot
+	Email       string // Contact address for site owners
+	Operator    string // Who runs the crawler
+	Description string // What the crawler collects and why
+}
+
+// NewBotIdentity creates an identity from application config, or returns
+// nil when no name is configured
+func NewBotIdentity(config configs.BotIdentityConfig) (*BotIdentity, error) {
+	if config.Name == "" {
+		return nil, nil
+	}
+	id := &BotIdentity{
+		Name:        config.Name,
+		Version:     config.Version,
+		InfoURL:     config.InfoURL,
+		Email:       config.Email,
+		Operator:    config.Operator,
+		Description: config.Description,
+	}
+	if err := id.Validate(); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// Validate checks that the identity can be sent in a User-Agent header
+func (id *BotIdentity) Validate() error {
+	if !botName.MatchString(id.Name) {
+		return errs.Newf(errs.CodeInvalidConfig, "invalid bot name %q: use letters, '_' and '-' only", id.Name)
+	}
+	if id.Version != "" && !botVersion.MatchString(id.Version) {
+		return errs.Newf(errs.CodeInvalidConfig, "invalid bot version %q", id.Version)
+	}
+	if id.InfoURL != "" {
+		u, err := url.Parse(id.InfoURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errs.Newf(errs.CodeInvalidConfig, "invalid bot info URL %q: use an absolute http or https URL", id.InfoURL)
+		}
+	}
+	if id.Email != "" {
+		local, domain, ok := strings.Cut(id.Email, "@")
+		if !ok || local == "" || domain == "" || strings.ContainsAny(id.Email, " ;()") {
+			return errs.Newf(errs.CodeInvalidConfig, "invalid bot contact email %q", id.Email)
+		}
+	}
+	return nil
+}
+
+// Token returns the product token and version, e.g. AcmeBot/1.0
+func (id *BotIdentity) Token() string {
+	if id.Version == "" {
+		return id.Name
+	}
+	return id.Name + "/" + id.Version
+}
+
+// Agent returns the lowercase name robots.txt groups and robots meta tags
+// address the crawler by, e.g. acmebot
+func (id *BotIdentity) Agent() string {
+	return strings.ToLower(id.Name)
+}
+
+// UserAgent builds the User-Agent header in the form the major search
+// engine crawlers use, with the info URL after a '+' and the contact
+// address last, e.g.
+//
+//	Mozilla/5.0 (compatible; AcmeBot/1.0; +https://acme.example/bot; bot@acme.example)
+func (id *BotIdentity) UserAgent() string {
+	parts := []string{"compatible", id.Token()}
+	if id.InfoURL != "" {
+		parts = append(parts, "+"+id.InfoURL)
+	}
+	if id.Email != "" {
+		parts = append(parts, id.Email)
+	}
+	return "Mozilla/5.0 (" + strings.Join(parts, "; ") + ")"
+}
@@ -0,0 +1,249 @@
+package crawlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Kinds of anti-bot responses, the kind label of BlockMetrics
+const (
+	BlockKindCloudflare = "cloudflare" // Cloudflare challenge or Turnstile interstitial
+	BlockKindRecaptcha  = "recaptcha"
+	BlockKindHCaptcha   = "hcaptcha"
+	BlockKindDataDome   = "datadome"
+	BlockKindPerimeterX = "perimeterx"
+	BlockKindAkamai     = "akamai"    // Akamai Bot Manager "Access Denied" page
+	BlockKindChallenge  = "challenge" // Another 403/429/503 asking to prove a human is there
+)
+
+// blockSniffSize is how much of a body is searched for markers; challenge
+// pages put them in the head
+const blockSniffSize = 64 << 10
+
+// blockMarker is a body substring, lowercased, that identifies a kind
+type blockMarker struct {
+	kind   string
+	marker string
+}
+
+// challengeMarkers identify vendor challenge pages whatever their status
+var challengeMarkers = []blockMarker{
+	{BlockKindCloudflare, "/cdn-cgi/challenge-platform/"},
+	{BlockKindCloudflare, "challenges.cloudflare.com/turnstile"},
+	{BlockKindCloudflare, "window._cf_chl_opt"},
+	{BlockKindDataDome, "captcha-delivery.com"},
+	{BlockKindPerimeterX, "px-captcha"},
+	{BlockKindPerimeterX, "_pxcaptcha"},
+}
+
+// captchaMarkers identify CAPTCHA widgets, which ordinary pages such as
+// login forms embed too; they only count on error statuses and pages whose
+// title asks for a human
+var captchaMarkers = []blockMarker{
+	{BlockKindRecaptcha, "www.google.com/recaptcha/"},
+	{BlockKindRecaptcha, "www.recaptcha.net/recaptcha/"},
+	{BlockKindRecaptcha, `class="g-recaptcha"`},
+	{BlockKindHCaptcha, "hcaptcha.com/1/api.js"},
+	{BlockKindHCaptcha, `class="h-captcha"`},
+}
+
+// cloudflareTitles are the titles of Cloudflare interstitials
+var cloudflareTitles = []string{"just a moment...", "attention required! | cloudflare"}
+
+// humanCheckPhrases are what challenge pages ask in their title or text
+var humanCheckPhrases = []string{
+	"just a moment...",
+	"attention required! | cloudflare",
+	"verify you are human",
+	"are you a robot",
+	"are you a human",
+	"unusual traffic",
+	"captcha",
+}
+
+// Block describes a response that is an anti-bot wall instead of the page
+type Block struct {
+	URL        string `json:"url"`
+	StatusCode int    `json:"status_code"`
+	Kind       string `json:"kind"`   // One of the BlockKind constants
+	Marker     string `json:"marker"` // The header or body marker that matched
+}
+
+// DetectBlock reports whether a response is a CAPTCHA or bot wall; it
+// returns nil for ordinary pages. Only the first 64 KiB of body are searched
+func DetectBlock(statusCode int, header http.Header, body []byte) *Block {
+	if mitigated := header.Get("Cf-Mitigated"); strings.EqualFold(mitigated, "challenge") {
+		return &Block{StatusCode: statusCode, Kind: BlockKindCloudflare, Marker: "cf-mitigated: challenge"}
+	}
+	if header.Get("X-Datadome") != "" && statusCode == http.StatusForbidden {
+		return &Block{StatusCode: statusCode, Kind: BlockKindDataDome, Marker: "x-datadome"}
+	}
+
+	if len(body) > blockSniffSize {
+		body = body[:blockSniffSize]
+	}
+	lower := string(bytes.ToLower(body))
+	for _, m := range challengeMarkers {
+		if strings.Contains(lower, m.marker) {
+			return &Block{StatusCode: statusCode, Kind: m.kind, Marker: m.marker}
+		}
+	}
+
+	blockingStatus := statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+	askingHuman := blockingStatus || containsAny(htmlTitle(lower), humanCheckPhrases)
+	if askingHuman {
+		for _, m := range captchaMarkers {
+			if strings.Contains(lower, m.marker) {
+				return &Block{StatusCode: statusCode, Kind: m.kind, Marker: m.marker}
+			}
+		}
+	}
+	if !blockingStatus {
+		return nil
+	}
+
+	if statusCode == http.StatusForbidden && strings.Contains(strings.ToLower(header.Get("Server")), "akamaighost") {
+		return &Block{StatusCode: statusCode, Kind: BlockKindAkamai, Marker: "server: akamaighost"}
+	}
+	if strings.Contains(strings.ToLower(header.Get("Server")), "cloudflare") && containsAny(lower, cloudflareTitles) {
+		return &Block{StatusCode: statusCode, Kind: BlockKindCloudflare, Marker: "server: cloudflare"}
+	}
+	for _, phrase := range humanCheckPhrases {
+		if strings.Contains(lower, phrase) {
+			return &Block{StatusCode: statusCode, Kind: BlockKindChallenge, Marker: phrase}
+		}
+	}
+	return nil
+}
+
+// htmlTitle returns the contents of the first <title> of a lowercased page
+func htmlTitle(lower string) string {
+	_, rest, ok := strings.Cut(lower, "<title")
+	if !ok {
+		return ""
+	}
+	_, rest, ok = strings.Cut(rest, ">")
+	if !ok {
+		return ""
+	}
+	title, _, _ := strings.Cut(rest, "</title")
+	return title
+}
+
+// containsAny reports whether s contains any of substrs
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// BlockMetrics counts blocked responses by kind; *libs.Metrics implements it
+type BlockMetrics interface {
+	RecordBlocked(kind string)
+}
+
+// BlockedError is returned for a response DetectBlock flagged
+type BlockedError struct {
+	Block Block
+}
+
+// Error implements the error interface
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s blocked by %s bot wall (status %d, %s)", e.Block.URL, e.Block.Kind, e.Block.StatusCode, e.Block.Marker)
+}
+
+// ErrorCode implements errs.Coder
+func (e *BlockedError) ErrorCode() errs.Code {
+	return errs.CodeBlocked
+}
+
+// BlockDetector checks responses for bot walls, counts them by kind and
+// hands them to OnBlocked callbacks, e.g. to queue the URL for a browser
+// engine or a CAPTCHA solver. One detector may be shared by several clients
+type BlockDetector struct {
+	metrics BlockMetrics
+
+	mu        sync.RWMutex
+	onBlocked []func(b *Block)
+	counts    map[string]int64 // Kind -> blocked responses
+}
+
+// NewBlockDetector creates a detector; metrics is optional
+func NewBlockDetector(metrics BlockMetrics) *BlockDetector {
+	return &BlockDetector{
+		metrics: metrics,
+		counts:  make(map[string]int64),
+	}
+}
+
+// OnBlocked registers a callback run for every blocked response
+func (d *BlockDetector) OnBlocked(handler func(b *Block)) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.onBlocked = append(d.onBlocked, handler)
+}
+
+// Check detects a bot wall in the response to url, counting it and running
+// the callbacks; it returns nil for ordinary pages
+func (d *BlockDetector) Check(url string, statusCode int, header http.Header, body []byte) *Block {
+	b := DetectBlock(statusCode, header, body)
+	if b == nil {
+		return nil
+	}
+	b.URL = url
+
+	d.mu.Lock()
+	d.counts[b.Kind]++
+	handlers := d.onBlocked
+	d.mu.Unlock()
+
+	if d.metrics != nil {
+		d.metrics.RecordBlocked(b.Kind)
+	}
+	for _, handler := range handlers {
+		handler(b)
+	}
+	return b
+}
+
+// Counts returns the number of blocked responses by kind
+func (d *BlockDetector) Counts() map[string]int64 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	counts := make(map[string]int64, len(d.counts))
+	for kind, n := range d.counts {
+		counts[kind] = n
+	}
+	return counts
+}
+
+// BlockMiddleware checks fetched pages with d. A blocked page is fetched
+// again with fallback, e.g. a browser Crawler, or fails with a
+// BlockedError when fallback is nil
+func BlockMiddleware(d *BlockDetector, fallback Fetcher) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			result, err := next.Fetch(ctx, url)
+			if err != nil {
+				return nil, err
+			}
+			b := d.Check(url, result.StatusCode, result.Header, result.Body)
+			if b == nil {
+				return result, nil
+			}
+			if fallback == nil {
+				return nil, &BlockedError{Block: *b}
+			}
+			return fallback.Fetch(ctx, url)
+		})
+	}
+}
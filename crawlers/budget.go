@@ -0,0 +1,267 @@
+package crawlers
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// maxSkippedURLs caps the skipped URLs kept in a BudgetSummary
+const maxSkippedURLs = 100
+
+// Budget limits that can stop a crawl
+const (
+	BudgetMaxPages    = "max_pages"
+	BudgetMaxBytes    = "max_bytes"
+	BudgetMaxDuration = "max_duration"
+
+	// BudgetMaxPagesPerDomain is the skip rule of URLs over a domain quota;
+	// it never stops the crawl
+	BudgetMaxPagesPerDomain = "max_pages_per_domain"
+)
+
+// BudgetSummary reports what a budgeted crawl fetched and what it skipped
+type BudgetSummary struct {
+	Pages       int           // Requests started, including failed ones
+	Bytes       int64         // Response body bytes read
+	Elapsed     time.Duration // Time since the first request
+	Exhausted   string        // Limit that stopped the crawl; empty if none did
+	Skipped     int           // URLs not fetched because the budget ran out
+	SkippedURLs []string      // The first skipped URLs, at most 100
+
+	// DomainSkips counts the URLs of each domain not fetched because the
+	// domain reached MaxPagesPerDomain
+	DomainSkips map[string]int
+}
+
+// crawlBudget enforces MaxPages, MaxBytes and MaxDuration across a crawl
+// A zero limit is unlimited; a nil *crawlBudget allows everything
+type crawlBudget struct {
+	maxPages    int
+	maxBytes    int64
+	maxDuration time.Duration
+
+	mu        sync.Mutex
+	started   time.Time
+	pages     int
+	bytes     int64
+	exhausted string
+	skipped   int
+	skipURLs  []string
+}
+
+// newCrawlBudget returns a budget, or nil when no limit is set
+func newCrawlBudget(maxPages int, maxBytes int64, maxDuration time.Duration) *crawlBudget {
+	if maxPages <= 0 && maxBytes <= 0 && maxDuration <= 0 {
+		return nil
+	}
+	return &crawlBudget{maxPages: maxPages, maxBytes: maxBytes, maxDuration: maxDuration}
+}
+
+// reserve reports whether url may be fetched and counts it as a page if so
+// Once a limit is hit every later URL is recorded as skipped
+func (b *crawlBudget) reserve(url string) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.takeLocked() {
+		b.skipLocked(url)
+		return false
+	}
+	return true
+}
+
+// take counts a page against the budget without recording a skip when it is
+// exhausted, for callers that hand the URL back to be skipped later
+func (b *crawlBudget) take() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.takeLocked()
+}
+
+// takeLocked counts a page if the budget allows one; b.mu must be held
+func (b *crawlBudget) takeLocked() bool {
+	if !b.availableLocked() {
+		return false
+	}
+	b.pages++
+	return true
+}
+
+// available reports whether another page may be fetched without reserving it
+func (b *crawlBudget) available() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.availableLocked()
+}
+
+// availableLocked checks the limits and latches the first one hit; b.mu must be held
+func (b *crawlBudget) availableLocked() bool {
+	if b.started.IsZero() {
+		b.started = time.Now()
+	}
+	if b.exhausted == "" {
+		switch {
+		case b.maxPages > 0 && b.pages >= b.maxPages:
+			b.exhausted = BudgetMaxPages
+		case b.maxBytes > 0 && b.bytes >= b.maxBytes:
+			b.exhausted = BudgetMaxBytes
+		case b.maxDuration > 0 && time.Since(b.started) >= b.maxDuration:
+			b.exhausted = BudgetMaxDuration
+		}
+	}
+	return b.exhausted == ""
+}
+
+// skip records url as not fetched because the budget ran out
+func (b *crawlBudget) skip(url string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.skipLocked(url)
+}
+
+// skipLocked records a skipped URL; b.mu must be held
+func (b *crawlBudget) skipLocked(url string) {
+	b.skipped++
+	if len(b.skipURLs) < maxSkippedURLs {
+		b.skipURLs = append(b.skipURLs, url)
+	}
+}
+
+// addBytes counts response body bytes
+func (b *crawlBudget) addBytes(n int64) {
+	if b == nil || n <= 0 {
+		return
+	}
+	b.mu.Lock()
+	b.bytes += n
+	b.mu.Unlock()
+}
+
+// summary returns a snapshot of the budget
+func (b *crawlBudget) summary() BudgetSummary {
+	if b == nil {
+		return BudgetSummary{}
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	summary := BudgetSummary{
+		Pages:       b.pages,
+		Bytes:       b.bytes,
+		Exhausted:   b.exhausted,
+		Skipped:     b.skipped,
+		SkippedURLs: append([]string(nil), b.skipURLs...),
+	}
+	if !b.started.IsZero() {
+		summary.Elapsed = time.Since(b.started)
+	}
+	return summary
+}
+
+// domainQuota caps the pages fetched per registrable domain, so one huge
+// site cannot use up a crawl started from many seeds
+// A nil *domainQuota allows everything
+type domainQuota struct {
+	max int
+
+	mu      sync.Mutex
+	pages   map[string]int // Pages reserved per domain
+	skipped map[string]int // URLs refused per domain
+}
+
+// newDomainQuota returns a quota, or nil when max is not positive
+func newDomainQuota(max int) *domainQuota {
+	if max <= 0 {
+		return nil
+	}
+	return &domainQuota{max: max, pages: make(map[string]int), skipped: make(map[string]int)}
+}
+
+// full reports whether the domain of url has no pages left, counting url
+// as skipped if so
+func (q *domainQuota) full(url string) bool {
+	if q == nil {
+		return false
+	}
+	domain := RateLimitDomain(url)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pages[domain] < q.max {
+		return false
+	}
+	q.skipped[domain]++
+	return true
+}
+
+// reserve reports whether url may be fetched and counts it against its
+// domain if so; refused URLs are counted as skipped
+func (q *domainQuota) reserve(url string) bool {
+	if q == nil {
+		return true
+	}
+	domain := RateLimitDomain(url)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pages[domain] >= q.max {
+		q.skipped[domain]++
+		return false
+	}
+	q.pages[domain]++
+	return true
+}
+
+// release returns a page reserved for url that was not fetched
+func (q *domainQuota) release(url string) {
+	if q == nil {
+		return
+	}
+	domain := RateLimitDomain(url)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.pages[domain] > 0 {
+		q.pages[domain]--
+	}
+}
+
+// skips returns the URLs refused per domain, or nil if there were none
+func (q *domainQuota) skips() map[string]int {
+	if q == nil {
+		return nil
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.skipped) == 0 {
+		return nil
+	}
+	skips := make(map[string]int, len(q.skipped))
+	for domain, n := range q.skipped {
+		skips[domain] = n
+	}
+	return skips
+}
+
+// countingReader counts the bytes read from a response body into a budget
+type countingReader struct {
+	io.Reader
+	budget *crawlBudget
+}
+
+// Read implements io.Reader
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.budget.addBytes(int64(n))
+	return n, err
+}
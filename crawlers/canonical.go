@@ -0,0 +1,222 @@
+package crawlers
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Canonical folding policies for WWW and TrailingSlash
+const (
+	FoldKeep  = ""      // Leave URLs as they are
+	FoldStrip = "strip" // www.example.com -> example.com, /docs/ -> /docs
+	FoldAdd   = "add"   // example.com -> www.example.com, /docs -> /docs/
+)
+
+// CanonicalConfig holds URL canonicalization settings
+type CanonicalConfig struct {
+	WWW           string   // keep (default), strip or add the www. prefix
+	TrailingSlash string   // keep (default), strip or add; the root path is always /
+	IndexPages    []string // File names dropped from paths, e.g. index.html makes /a/index.html /a/
+
+	// QueryParams whitelists query parameters per host; other parameters of
+	// URLs on these hosts are dropped and the rest sorted. Hosts without an
+	// entry keep their query as is
+	QueryParams map[string][]string
+}
+
+// Canonicalizer folds the variants of a URL into one, so a page reachable
+// as example.com/docs, www.example.com/docs/ and example.com/docs/index.html
+// is crawled and stored once
+//
+// Every URL is also normalized: the scheme and host are lowercased, default
+// ports and fragments removed and an empty path becomes /
+// A nil Canonicalizer only normalizes
+type Canonicalizer struct {
+	www           string
+	trailingSlash string
+	indexPages    map[string]bool
+
+	queryMu     sync.RWMutex
+	queryParams map[string]map[string]bool // Host -> whitelisted parameters
+}
+
+// NewCanonicalizer validates the folding policies
+func NewCanonicalizer(config CanonicalConfig) (*Canonicalizer, error) {
+	for name, policy := range map[string]string{"www": config.WWW, "trailing slash": config.TrailingSlash} {
+		if policy != FoldKeep && policy != FoldStrip && policy != FoldAdd {
+			return nil, errs.Newf(errs.CodeInvalidConfig, "invalid %s policy %q: use strip or add", name, policy)
+		}
+	}
+
+	c := &Canonicalizer{
+		www:           config.WWW,
+		trailingSlash: config.TrailingSlash,
+		indexPages:    make(map[string]bool),
+		queryParams:   make(map[string]map[string]bool),
+	}
+	for _, page := range config.IndexPages {
+		if page = strings.ToLower(strings.Trim(page, "/ ")); page != "" {
+			c.indexPages[page] = true
+		}
+	}
+	for host, params := range config.QueryParams {
+		c.SetQueryParams(host, params)
+	}
+	return c, nil
+}
+
+// NewCanonicalizerFromConfig creates a Canonicalizer from application config
+// Returns nil when no folding is configured
+func NewCanonicalizerFromConfig(config configs.CanonicalConfig) (*Canonicalizer, error) {
+	if config.WWW == FoldKeep && config.TrailingSlash == FoldKeep && len(config.IndexPages) == 0 && len(config.QueryParams) == 0 {
+		return nil, nil
+	}
+	return NewCanonicalizer(CanonicalConfig{
+		WWW:           config.WWW,
+		TrailingSlash: config.TrailingSlash,
+		IndexPages:    config.IndexPages,
+		QueryParams:   config.QueryParams,
+	})
+}
+
+// SetQueryParams replaces the query parameter whitelist of a host; an empty
+// whitelist drops every parameter. It is safe to call while URLs are being
+// canonicalized, e.g. with rules learned during a crawl
+func (c *Canonicalizer) SetQueryParams(host string, params []string) {
+	keep := make(map[string]bool, len(params))
+	for _, p := range params {
+		keep[p] = true
+	}
+	c.queryMu.Lock()
+	defer c.queryMu.Unlock()
+	c.queryParams[strings.ToLower(host)] = keep
+}
+
+// QueryParams returns the sorted whitelist of a host, and whether it has one
+func (c *Canonicalizer) QueryParams(host string) ([]string, bool) {
+	c.queryMu.RLock()
+	defer c.queryMu.RUnlock()
+	keep, ok := c.queryParams[strings.ToLower(host)]
+	if !ok {
+		return nil, false
+	}
+	params := make([]string, 0, len(keep))
+	for p := range keep {
+		params = append(params, p)
+	}
+	sort.Strings(params)
+	return params, true
+}
+
+// Canonicalize returns the canonical form of an http or https URL
+// Other URLs, and URLs that cannot be parsed, are returned unchanged
+func (c *Canonicalizer) Canonicalize(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return rawURL
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		port = ""
+	}
+	u.Fragment, u.RawFragment = "", ""
+	if u.Path == "" {
+		u.Path, u.RawPath = "/", ""
+	}
+
+	if c != nil {
+		host = c.foldHost(host)
+		c.foldPath(u)
+		c.foldQuery(u, host)
+	}
+
+	u.Host = host
+	if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]" // IPv6 literal
+	}
+	if port != "" {
+		u.Host = net.JoinHostPort(host, port)
+	}
+	return u.String()
+}
+
+// foldHost applies the www policy; IP addresses and single-label hosts such
+// as localhost are left alone
+func (c *Canonicalizer) foldHost(host string) string {
+	if net.ParseIP(host) != nil || !strings.Contains(host, ".") {
+		return host
+	}
+	switch c.www {
+	case FoldStrip:
+		if apex, ok := strings.CutPrefix(host, "www."); ok && strings.Contains(apex, ".") {
+			return apex
+		}
+	case FoldAdd:
+		if !strings.HasPrefix(host, "www.") {
+			return "www." + host
+		}
+	}
+	return host
+}
+
+// foldPath drops index pages and applies the trailing slash policy
+// Paths whose last segment looks like a file, e.g. /a/page.html, never get
+// a trailing slash
+func (c *Canonicalizer) foldPath(u *url.URL) {
+	p := u.Path
+	if dir, file := path.Split(p); c.indexPages[strings.ToLower(file)] {
+		p = dir
+	}
+
+	if p != "/" {
+		switch c.trailingSlash {
+		case FoldStrip:
+			p = strings.TrimRight(p, "/")
+			if p == "" {
+				p = "/"
+			}
+		case FoldAdd:
+			if !strings.HasSuffix(p, "/") && !strings.Contains(path.Base(p), ".") {
+				p += "/"
+			}
+		}
+	}
+
+	if p != u.Path {
+		u.Path, u.RawPath = p, "" // RawPath is recomputed from Path
+	}
+}
+
+// foldQuery drops query parameters outside the host's whitelist and sorts
+// the rest
+func (c *Canonicalizer) foldQuery(u *url.URL, host string) {
+	c.queryMu.RLock()
+	keep, ok := c.queryParams[host]
+	c.queryMu.RUnlock()
+	if !ok || u.RawQuery == "" {
+		return
+	}
+
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return // Leave queries url.ParseQuery cannot read alone
+	}
+	for name := range query {
+		if !keep[name] {
+			query.Del(name)
+		}
+	}
+	u.RawQuery = query.Encode()
+}
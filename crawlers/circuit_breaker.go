@@ -0,0 +1,218 @@
+package crawlers
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Circuit states, the state label of CircuitMetrics
+const (
+	CircuitClosed   = "closed"    // Requests flow
+	CircuitOpen     = "open"      // Requests fail fast until OpenTimeout passes
+	CircuitHalfOpen = "half_open" // One probe request decides whether to close
+)
+
+// CircuitMetrics records circuit state changes; *libs.Metrics implements it
+type CircuitMetrics interface {
+	RecordCircuitState(state string)
+}
+
+// CircuitBreakerConfig holds per-host circuit breaker settings
+type CircuitBreakerConfig struct {
+	FailureThreshold int            // Consecutive failures that open a host's circuit; defaults to 5
+	OpenTimeout      time.Duration  // How long a circuit stays open before a probe; defaults to 30s
+	Metrics          CircuitMetrics // Optional
+	Clock            clock.Clock    // Defaults to the wall clock
+}
+
+// CircuitBreaker stops requests to hosts that keep failing, so a dead or
+// blocking site does not tie up workers and retries for the whole crawl
+//
+// FailureThreshold failures in a row open a host's circuit: requests fail
+// with a CircuitOpenError without being sent. After OpenTimeout the circuit
+// is half-open and lets a single probe through; its success closes the
+// circuit, its failure opens it for another OpenTimeout. Network errors,
+// 5xx and 429 responses and bot walls count as failures
+type CircuitBreaker struct {
+	threshold int
+	timeout   time.Duration
+	metrics   CircuitMetrics
+	clock     clock.Clock
+
+	mu    sync.Mutex
+	hosts map[string]*circuit
+}
+
+// circuit is the breaker state of one host
+type circuit struct {
+	state    string
+	failures int // Consecutive failures while closed
+	openedAt time.Time
+	probing  bool // A half-open probe is in flight
+}
+
+// NewCircuitBreaker creates a per-host circuit breaker
+func NewCircuitBreaker(config CircuitBreakerConfig) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	return &CircuitBreaker{
+		threshold: config.FailureThreshold,
+		timeout:   config.OpenTimeout,
+		metrics:   config.Metrics,
+		clock:     clock.Or(config.Clock),
+		hosts:     make(map[string]*circuit),
+	}
+}
+
+// NewCircuitBreakerFromConfig creates a circuit breaker from application
+// config; metrics is optional; returns nil when it is disabled
+func NewCircuitBreakerFromConfig(config configs.CircuitBreakerConfig, metrics CircuitMetrics) *CircuitBreaker {
+	if !config.Enabled {
+		return nil
+	}
+	return NewCircuitBreaker(CircuitBreakerConfig{
+		FailureThreshold: config.FailureThreshold,
+		OpenTimeout:      time.Duration(config.OpenTimeout) * time.Second,
+		Metrics:          metrics,
+	})
+}
+
+// CircuitOpenError is returned for requests to a host whose circuit is open
+type CircuitOpenError struct {
+	URL     string
+	Host    string
+	RetryIn time.Duration // Until the circuit lets a probe through; zero while one is in flight
+}
+
+// Error implements the error interface
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit open for %s, not fetching %s", e.Host, e.URL)
+}
+
+// ErrorCode implements errs.Coder
+func (e *CircuitOpenError) ErrorCode() errs.Code {
+	return errs.CodeCircuitOpen
+}
+
+// IsCircuitFailure reports whether a response status counts as a failure
+// of its host: a 5xx or 429
+func IsCircuitFailure(statusCode int) bool {
+	return statusCode >= 500 || statusCode == http.StatusTooManyRequests
+}
+
+// Allow reports whether a request to rawURL may be sent, returning a
+// CircuitOpenError when not. Every allowed request must be followed by
+// Report, or by Abandon when it ended without telling anything about the
+// host; a nil breaker allows everything
+func (b *CircuitBreaker) Allow(rawURL string) error {
+	if b == nil {
+		return nil
+	}
+	host := urlHost(rawURL)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.hosts[host]
+	if c == nil || c.state == CircuitClosed {
+		return nil
+	}
+
+	if c.state == CircuitOpen {
+		if retryIn := b.timeout - b.clock.Since(c.openedAt); retryIn > 0 {
+			return &CircuitOpenError{URL: rawURL, Host: host, RetryIn: retryIn}
+		}
+		b.setState(c, CircuitHalfOpen)
+	}
+	if c.probing {
+		return &CircuitOpenError{URL: rawURL, Host: host}
+	}
+	c.probing = true
+	return nil
+}
+
+// Report records the outcome of an allowed request to rawURL
+func (b *CircuitBreaker) Report(rawURL string, failed bool) {
+	if b == nil {
+		return
+	}
+	host := urlHost(rawURL)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.hosts[host]
+	if c == nil {
+		if !failed {
+			return
+		}
+		c = &circuit{state: CircuitClosed}
+		b.hosts[host] = c
+	}
+
+	c.probing = false
+	switch {
+	case !failed:
+		c.failures = 0
+		if c.state != CircuitClosed {
+			b.setState(c, CircuitClosed)
+		}
+	case c.state == CircuitHalfOpen:
+		c.openedAt = b.clock.Now()
+		b.setState(c, CircuitOpen)
+	case c.state == CircuitClosed:
+		c.failures++
+		if c.failures >= b.threshold {
+			c.failures = 0
+			c.openedAt = b.clock.Now()
+			b.setState(c, CircuitOpen)
+		}
+	}
+}
+
+// Abandon releases an allowed request to rawURL that ended without an
+// outcome, e.g. because its context was canceled, so a half-open circuit
+// lets the next probe through
+func (b *CircuitBreaker) Abandon(rawURL string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if c := b.hosts[urlHost(rawURL)]; c != nil {
+		c.probing = false
+	}
+}
+
+// State returns the circuit state of rawURL's host
+func (b *CircuitBreaker) State(rawURL string) string {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.hosts[urlHost(rawURL)]
+	if c == nil {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && b.clock.Since(c.openedAt) >= b.timeout {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+// setState moves c to state and records the change; b.mu must be held
+func (b *CircuitBreaker) setState(c *circuit, state string) {
+	c.state = state
+	if b.metrics != nil {
+		b.metrics.RecordCircuitState(state)
+	}
+}
@@ -1,17 +1,34 @@
 package crawlers
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/gocolly/colly/v2"
 )
 
 // ValidateURL validates a URL for crawling
-// Returns an error if the URL is invalid or potentially dangerous
+// Returns an error coded errs.CodeInvalidURL if the URL is invalid or potentially dangerous
 func ValidateURL(rawURL string) error {
+	return ValidateURLWithPolicy(rawURL, libs.ValidationPolicy{})
+}
+
+// ValidateURLWithPolicy validates a URL like ValidateURL, with the SSRF checks
+// relaxed or tightened by policy. Hostnames are not resolved, so BlockedCIDRs
+// only match IP literals; libs.Validator checks resolved addresses
+func ValidateURLWithPolicy(rawURL string, policy libs.ValidationPolicy) error {
+	return errs.Wrap(validateURL(rawURL, policy), errs.CodeInvalidURL, "")
+}
+
+// validateURL runs the checks of ValidateURLWithPolicy
+func validateURL(rawURL string, policy libs.ValidationPolicy) error {
 	if rawURL == "" {
 		return fmt.Errorf("URL cannot be empty")
 	}
@@ -36,8 +53,19 @@ func ValidateURL(rawURL string) error {
 		return fmt.Errorf("URL must have a host")
 	}
 
-	// Block localhost and private IPs for security (SSRF protection)
+	// Trusted hosts skip the address checks
 	host := strings.ToLower(parsed.Hostname())
+	if policy.AllowsHost(host) {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && policy.Blocks(ip) {
+		return fmt.Errorf("blocked IP address: %s", host)
+	}
+	if policy.AllowPrivate {
+		return nil
+	}
+
+	// Block localhost and private IPs for security (SSRF protection)
 	if host == "localhost" || host == "127.0.0.1" || host == "::1" {
 		return fmt.Errorf("localhost URLs are not allowed")
 	}
@@ -70,6 +98,12 @@ func ValidateURL(rawURL string) error {
 // CollyClient wraps Colly crawler operations
 type CollyClient struct {
 	collector *colly.Collector
+	proxies   *ProxyPool
+	visits    *visitRegistry
+	budget    *crawlBudget
+	cookies   *CookieJar
+	redirects *redirectHandlers
+	skips     *skipLog
 }
 
 // CollyConfig holds Colly crawler configuration
@@ -80,6 +114,60 @@ type CollyConfig struct {
 	Async          bool
 	Parallelism    int
 	Delay          time.Duration
+	Cooldown       *DomainCooldown // Optional per-domain backoff on 429/503
+	RateLimiter    *RateLimiter    // Optional limiter shared with other clients
+	Proxies        []string        // Optional proxy URLs to rotate through
+	ProxyStrategy  string          // round_robin (default), random, sticky, or scored
+	Cookies        *CookieJar      // Optional jar, e.g. persisted with NewCookieJar(store); defaults to an in-memory jar
+
+	// AllowURLRevisit fetches URLs the client visited before, e.g. to retry
+	// failed crawls; by default a second visit fails with colly's
+	// ErrAlreadyVisited
+	AllowURLRevisit bool
+
+	// HTTPClient sends requests instead of colly's own client, e.g. one set
+	// up for mTLS or instrumentation. It is copied, so its transport is
+	// wrapped for proxies, HSTS and metrics without changing the caller's
+	// client; its Timeout is used as is, its Jar and CheckRedirect are
+	// replaced by Cookies and OnRedirect
+	HTTPClient *http.Client
+
+	// RoundTripper replaces the default transport, or that of HTTPClient
+	// Proxies are only rotated through an *http.Transport
+	RoundTripper http.RoundTripper
+
+	// ContentTypes aborts responses whose media type it rejects once their
+	// headers arrive, before the body is downloaded; HeadRequests is not used
+	ContentTypes *ContentTypeFilter
+
+	// HSTS sends requests for hosts known to support https over https and
+	// records the HSTS headers of responses; may be shared with other clients
+	HSTS *HSTS
+
+	// Auth logs in with a form before the first request to its hosts and
+	// again when their session expires; may be shared with other clients
+	Auth *AuthFlow
+
+	// Metrics records every request by status; optional, e.g. *libs.Metrics
+	Metrics Metrics
+
+	// BlockDetector checks every response for CAPTCHA and bot walls; its
+	// OnBlocked callbacks run before the client's own callbacks
+	BlockDetector *BlockDetector
+
+	// Robots aborts requests robots.txt disallows for the user agent's
+	// product token; may be shared with other clients
+	Robots *RobotsTxt
+
+	// DNSCache resolves the hostnames of new connections from a cache; may
+	// be shared with other clients. Only an *http.Transport is wrapped
+	DNSCache *DNSCache
+
+	// Crawl budget; once a limit is hit later requests are aborted and
+	// recorded as skipped. Zero means unlimited
+	MaxPages    int
+	MaxBytes    int64         // Response body bytes
+	MaxDuration time.Duration // Measured from the first request
 }
 
 // NewCollyClient creates a new Colly-based crawler
@@ -90,6 +178,10 @@ func NewCollyClient(config CollyConfig) *CollyClient {
 		colly.MaxDepth(config.MaxDepth),
 		colly.Async(config.Async),
 	)
+	c.AllowURLRevisit = config.AllowURLRevisit
+	if config.HTTPClient != nil {
+		c.SetClient(clientFrom(config.HTTPClient, nil, 0))
+	}
 
 	// Set parallelism and delay
 	if config.Parallelism > 0 {
@@ -103,9 +195,211 @@ func NewCollyClient(config CollyConfig) *CollyClient {
 		}
 	}
 
-	return &CollyClient{
+	// Registered first so cancelled visits abort before waiting on limits
+	visits := &visitRegistry{}
+	registerVisitContext(c, visits)
+	skips := &skipLog{}
+
+	// Disallowed URLs neither count against the budget nor wait on limits
+	if config.Robots != nil {
+		registerRobots(c, config.Robots, visits, skips)
+	}
+
+	budget := newCrawlBudget(config.MaxPages, config.MaxBytes, config.MaxDuration)
+	if budget != nil {
+		registerBudget(c, budget, skips)
+	}
+
+	if config.RateLimiter != nil || config.Cooldown != nil {
+		registerThrottle(c, config.RateLimiter, config.Cooldown, visits)
+	}
+
+	if config.ContentTypes != nil {
+		registerContentTypes(c, config.ContentTypes, skips)
+	}
+
+	if config.BlockDetector != nil {
+		registerBlockDetector(c, config.BlockDetector)
+	}
+
+	cookies := config.Cookies
+	if cookies == nil {
+		var err error
+		if cookies, err = NewCookieJar(nil); err != nil {
+			fmt.Printf("warning: failed to create cookie jar: %v\n", err)
+		}
+	}
+	if cookies != nil {
+		c.SetCookieJar(cookies)
+	}
+
+	client := &CollyClient{
 		collector: c,
+		visits:    visits,
+		budget:    budget,
+		cookies:   cookies,
+		redirects: &redirectHandlers{},
+		skips:     skips,
+	}
+	c.SetRedirectHandler(client.redirects.checkRedirect)
+
+	var transport http.RoundTripper = http.DefaultTransport
+	if config.RoundTripper != nil {
+		transport = config.RoundTripper
+	} else if config.HTTPClient != nil && config.HTTPClient.Transport != nil {
+		transport = config.HTTPClient.Transport
+	}
+	transport = config.DNSCache.Transport(transport)
+
+	if len(config.Proxies) > 0 {
+		pool, err := NewProxyPool(ProxyPoolConfig{
+			Proxies:  config.Proxies,
+			Strategy: config.ProxyStrategy,
+		})
+		if err != nil {
+			// Crawl directly rather than failing client construction
+			fmt.Printf("warning: failed to configure proxies: %v\n", err)
+		} else if proxied, ok := proxiedTransport(pool, transport); ok {
+			transport = proxied
+			client.proxies = pool
+		} else {
+			fmt.Printf("warning: proxies need an *http.Transport, got %T\n", transport)
+		}
+	}
+	transport = config.Auth.Transport(transport)
+	transport = config.HSTS.Transport(transport)
+	c.WithTransport(&visitTransport{base: instrumentTransport(transport, config.Metrics, CrawlerTypeColly), visits: visits})
+
+	return client
+}
+
+// registerRobots aborts requests robots.txt disallows
+func registerRobots(c *colly.Collector, robots *RobotsTxt, visits *visitRegistry, skips *skipLog) {
+	c.OnRequest(func(r *colly.Request) {
+		if isAborted(r) {
+			return
+		}
+		agent := RobotsAgent(r.Headers.Get("User-Agent"))
+		allowed, err := robots.Allowed(visits.requestContext(r), r.URL.String(), agent)
+		switch {
+		case err != nil:
+			abortRequest(r)
+		case !allowed:
+			skipRequest(r, skips, SkipDecision{URL: r.URL.String(), Reason: SkipRobots, Rule: "user-agent " + agent})
+		}
+	})
+}
+
+// skipDecisionKey is the colly.Context key holding the SkipDecision of a
+// visit's first request
+const skipDecisionKey = "golwarc_skip_decision"
+
+// skipRequest aborts a request and records why. The decision of a visit's
+// own request is kept for VisitContext; links followed from it share its
+// context, so theirs are only recorded
+func skipRequest(r *colly.Request, skips *skipLog, d SkipDecision) {
+	abortRequest(r)
+	if r.Depth <= 1 {
+		r.Ctx.Put(skipDecisionKey, d)
+	}
+	skips.record(d)
+}
+
+// registerBlockDetector checks responses, error statuses included, for
+// bot walls
+func registerBlockDetector(c *colly.Collector, detector *BlockDetector) {
+	check := func(r *colly.Response) {
+		var header http.Header
+		if r.Headers != nil {
+			header = *r.Headers
+		}
+		detector.Check(r.Request.URL.String(), r.StatusCode, header, r.Body)
+	}
+	c.OnResponse(check)
+	c.OnError(func(r *colly.Response, err error) {
+		if r != nil && r.Request != nil && r.StatusCode > 0 {
+			check(r)
+		}
+	})
+}
+
+// skippedContentKey is the colly.Context key holding the rejected content type
+const skippedContentKey = "golwarc_skipped_content"
+
+// registerContentTypes aborts responses the filter rejects after their headers
+func registerContentTypes(c *colly.Collector, types *ContentTypeFilter, skips *skipLog) {
+	c.OnResponseHeaders(func(r *colly.Response) {
+		if contentType := r.Headers.Get("Content-Type"); !types.Allowed(contentType) {
+			skips.record(SkipDecision{URL: r.Request.URL.String(), Reason: SkipContentType, Rule: contentType})
+			r.Ctx.Put(skippedContentKey, contentType)
+			r.Request.Abort()
+		}
+	})
+}
+
+// registerBudget aborts requests once the crawl budget is exhausted and
+// counts response bytes
+func registerBudget(c *colly.Collector, budget *crawlBudget, skips *skipLog) {
+	c.OnRequest(func(r *colly.Request) {
+		if !isAborted(r) && !budget.reserve(r.URL.String()) {
+			skipRequest(r, skips, SkipDecision{URL: r.URL.String(), Reason: SkipBudget, Rule: budget.summary().Exhausted})
+		}
+	})
+	c.OnResponse(func(r *colly.Response) {
+		budget.addBytes(int64(len(r.Body)))
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		if r != nil {
+			budget.addBytes(int64(len(r.Body)))
+		}
+	})
+}
+
+// registerThrottle makes the collector wait out domain cooldowns and
+// acquire a per-domain rate limiter slot before each request, and release
+// the slot once the response or error arrives, which is reported to both
+// through observeThrottle; either may be nil
+func registerThrottle(c *colly.Collector, limiter *RateLimiter, cooldown *DomainCooldown, visits *visitRegistry) {
+	key := func(r *colly.Request) string {
+		return fmt.Sprintf("rate_limit_release_%d", r.ID)
 	}
+	releaseFor := func(r *colly.Request) {
+		if release, ok := r.Ctx.GetAny(key(r)).(func()); ok {
+			release()
+		}
+	}
+
+	c.OnRequest(func(r *colly.Request) {
+		if isAborted(r) {
+			return
+		}
+		ctx := visits.requestContext(r)
+		if err := cooldown.Wait(ctx, r.URL.String()); err != nil {
+			abortRequest(r)
+			return
+		}
+		if limiter == nil {
+			return
+		}
+		release, err := limiter.Acquire(ctx, r.URL.String())
+		if err != nil {
+			abortRequest(r)
+			return
+		}
+		r.Ctx.Put(key(r), release)
+	})
+	c.OnResponse(func(r *colly.Response) {
+		observeThrottle(limiter, cooldown, r.Request.URL.String(), r.StatusCode, *r.Headers)
+		releaseFor(r.Request)
+	})
+	c.OnError(func(r *colly.Response, err error) {
+		if r != nil && r.Request != nil {
+			if r.Headers != nil {
+				observeThrottle(limiter, cooldown, r.Request.URL.String(), r.StatusCode, *r.Headers)
+			}
+			releaseFor(r.Request)
+		}
+	})
 }
 
 // NewDefaultCollyClient creates a Colly client with default settings
@@ -119,6 +413,11 @@ func NewDefaultCollyClient() *CollyClient {
 	})
 }
 
+// ProxyPool returns the proxy pool, or nil when no proxies are configured
+func (c *CollyClient) ProxyPool() *ProxyPool {
+	return c.proxies
+}
+
 // SetUserAgent sets the user agent
 func (c *CollyClient) SetUserAgent(ua string) {
 	c.collector.UserAgent = ua
@@ -159,11 +458,44 @@ func (c *CollyClient) OnScraped(handler func(r *colly.Response)) {
 	c.collector.OnScraped(handler)
 }
 
+// OnRedirect registers a callback for every redirect the client follows
+// Clones share their parent's redirect callbacks
+func (c *CollyClient) OnRedirect(handler func(r Redirect)) {
+	c.redirects.add(handler)
+}
+
 // Visit starts crawling from the given URL
 func (c *CollyClient) Visit(url string) error {
 	return c.collector.Visit(url)
 }
 
+// VisitContext crawls from the given URL until ctx is cancelled or expires
+// The context also covers requests queued from callbacks via e.Request.Visit;
+// in-flight requests are aborted and pending ones are skipped once it is done
+func (c *CollyClient) VisitContext(ctx context.Context, url string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	id := c.visits.register(ctx)
+	if !c.collector.Async {
+		defer c.visits.forget(id)
+	}
+
+	visitCtx := colly.NewContext()
+	visitCtx.Put(visitIDKey, id)
+	if err := c.collector.Request("GET", url, nil, visitCtx, nil); err != nil {
+		if contentType, skipped := visitCtx.GetAny(skippedContentKey).(string); skipped {
+			return &SkippedContentError{URL: url, ContentType: contentType}
+		}
+		return err
+	}
+	if decision, skipped := visitCtx.GetAny(skipDecisionKey).(SkipDecision); skipped {
+		return &SkippedError{Decision: decision}
+	}
+	return ctx.Err()
+}
+
 // VisitMultiple visits multiple URLs
 func (c *CollyClient) VisitMultiple(urls []string) error {
 	for _, url := range urls {
@@ -187,35 +519,77 @@ func (c *CollyClient) WithCache() error {
 }
 
 // Clone creates a new collector with the same configuration
+// Clones share their parent's cookie jar and redirect callbacks
 func (c *CollyClient) Clone() *CollyClient {
+	collector := c.collector.Clone()
+	registerVisitContext(collector, c.visits)
+	if c.budget != nil {
+		registerBudget(collector, c.budget, c.skips)
+	}
 	return &CollyClient{
-		collector: c.collector.Clone(),
+		collector: collector,
+		proxies:   c.proxies,
+		visits:    c.visits,
+		budget:    c.budget,
+		cookies:   c.cookies,
+		redirects: c.redirects,
+		skips:     c.skips,
 	}
 }
 
+// BudgetSummary reports what the crawl fetched and, once a MaxPages,
+// MaxBytes or MaxDuration limit stopped it, what it skipped
+// Clones share their parent's budget
+func (c *CollyClient) BudgetSummary() BudgetSummary {
+	return c.budget.summary()
+}
+
+// OnSkip registers a callback for every request aborted by robots.txt, the
+// crawl budget or the content type filter, with the reason and rule that
+// decided it. Clones share their parent's callbacks
+func (c *CollyClient) OnSkip(handler func(SkipDecision)) {
+	c.skips.onSkip(handler)
+}
+
+// SkipSummary counts the requests the client skipped by reason and rule
+// Clones share their parent's summary
+func (c *CollyClient) SkipSummary() SkipSummary {
+	return c.skips.snapshot()
+}
+
 // GetCollector returns the underlying Colly collector for advanced operations
 func (c *CollyClient) GetCollector() *colly.Collector {
 	return c.collector
 }
 
-// SetCookies sets cookies for requests
-func (c *CollyClient) SetCookies(url string, cookies map[string]string) error {
-	// Build cookie string from all cookies
-	var cookieParts []string
+// SetCookies adds cookies to the jar for rawURL
+// An empty rawURL sends the cookies with every request
+func (c *CollyClient) SetCookies(rawURL string, cookies map[string]string) error {
+	list := make([]*http.Cookie, 0, len(cookies))
 	for name, value := range cookies {
-		cookieParts = append(cookieParts, name+"="+value)
+		list = append(list, &http.Cookie{Name: name, Value: value})
+	}
+	if c.cookies == nil {
+		return colly.ErrNoCookieJar
 	}
-	cookieString := strings.Join(cookieParts, "; ")
 
-	// Register a single callback that sets all cookies
-	c.collector.OnRequest(func(r *colly.Request) {
-		if r.URL.String() == url || url == "" {
-			r.Headers.Set("Cookie", cookieString)
-		}
-	})
+	if rawURL == "" {
+		c.cookies.SetDefaultCookies(list)
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid cookie URL: %w", err)
+	}
+	c.cookies.SetCookies(u, list)
 	return nil
 }
 
+// CookieJar returns the client's cookie jar
+func (c *CollyClient) CookieJar() *CookieJar {
+	return c.cookies
+}
+
 // SetHeaders sets custom headers for requests
 func (c *CollyClient) SetHeaders(headers map[string]string) {
 	c.collector.OnRequest(func(r *colly.Request) {
@@ -0,0 +1,336 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/gocolly/colly/v2"
+	"github.com/gocolly/colly/v2/queue"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrQueueRunning is returned by CollyQueue.Run while another Run is active
+var ErrQueueRunning = errors.New("colly queue is already running")
+
+// CollyQueueConfig holds request queue settings
+type CollyQueueConfig struct {
+	Threads int // Requests in flight at once (default 4)
+	MaxSize int // Capacity of the default in-memory backlog (default 100000)
+
+	// Storage holds the backlog instead of process memory, e.g. a
+	// RedisQueueStorage so it survives restarts. Any colly queue.Storage
+	// works; it must be safe for concurrent use
+	Storage queue.Storage
+}
+
+// CollyQueue feeds a CollyClient from a backlog of serialized requests
+// with bounded concurrency. It uses the storage format of colly's queue
+// extension, so its storages are interchangeable, but binds every request
+// to the context passed to Run
+type CollyQueue struct {
+	client  *CollyClient
+	storage queue.Storage
+	threads int
+
+	wake    chan struct{} // Signals the dispatcher that a request finished or was added
+	active  atomic.Int64
+	done    atomic.Int64
+	failed  atomic.Int64
+	running atomic.Bool
+
+	mu   sync.Mutex
+	stop context.CancelFunc
+}
+
+// QueueStats holds request queue counters
+type QueueStats struct {
+	Pending int   // Requests in the backlog
+	Active  int   // Requests in flight
+	Done    int64 // Requests that completed since the queue was created
+	Failed  int64 // Requests that failed, were skipped or could not be decoded
+}
+
+// NewQueue creates a request queue that runs on the client
+// The client must be synchronous; queued requests run its callbacks like
+// those of Visit
+func (c *CollyClient) NewQueue(config CollyQueueConfig) (*CollyQueue, error) {
+	if c.collector.Async {
+		return nil, errs.New(errs.CodeInvalidConfig, "colly queue needs a synchronous client; unset CollyConfig.Async")
+	}
+	if config.Threads <= 0 {
+		config.Threads = 4
+	}
+	storage := config.Storage
+	if storage == nil {
+		if config.MaxSize <= 0 {
+			config.MaxSize = 100000
+		}
+		storage = &queue.InMemoryQueueStorage{MaxSize: config.MaxSize}
+	}
+	if err := storage.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize queue storage: %w", err)
+	}
+	return &CollyQueue{
+		client:  c,
+		storage: storage,
+		threads: config.Threads,
+		wake:    make(chan struct{}, 1),
+	}, nil
+}
+
+// AddURL queues a GET request for rawURL
+// It may be called from callbacks while Run is active, e.g. for discovered links
+func (q *CollyQueue) AddURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return errs.Newf(errs.CodeInvalidURL, "invalid URL %q", rawURL)
+	}
+	return q.AddRequest(&colly.Request{URL: u, Method: http.MethodGet, Depth: 1})
+}
+
+// AddRequest queues a request, keeping its method, depth, headers, body and
+// context values. Use it to queue links found in a callback one level deeper:
+//
+//	r, _ := e.Request.New(http.MethodGet, e.Request.AbsoluteURL(link), nil)
+//	r.Depth = e.Request.Depth + 1
+//	_ = q.AddRequest(r)
+func (q *CollyQueue) AddRequest(r *colly.Request) error {
+	data, err := r.Marshal()
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	if err := q.storage.AddRequest(data); err != nil {
+		if errors.Is(err, colly.ErrQueueFull) {
+			return errs.Wrap(err, errs.CodeQueueFull, "colly queue is full")
+		}
+		return err
+	}
+	q.signal()
+	return nil
+}
+
+// signal wakes the dispatcher without blocking
+func (q *CollyQueue) signal() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run processes the backlog with up to Threads requests in flight and
+// returns once it is empty and no request is running
+// Cancelling ctx aborts in-flight requests and returns ctx's error; Stop
+// lets them finish and returns nil. Requests not yet started stay queued
+func (q *CollyQueue) Run(ctx context.Context) error {
+	if !q.running.CompareAndSwap(false, true) {
+		return ErrQueueRunning
+	}
+	defer q.running.Store(false)
+
+	runCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	q.mu.Lock()
+	q.stop = stop
+	q.mu.Unlock()
+
+	id := q.client.visits.register(ctx)
+	defer q.client.visits.forget(id)
+
+	slots := make(chan struct{}, q.threads)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		// Take a slot before popping so a cancelled run loses no request
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-runCtx.Done():
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			<-slots
+			return err
+		}
+		if runCtx.Err() != nil {
+			<-slots
+			return nil
+		}
+
+		req, empty, err := q.next()
+		if err != nil {
+			<-slots
+			return err
+		}
+		if empty {
+			<-slots
+			if q.idle() {
+				return nil
+			}
+			select {
+			case <-q.wake:
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-runCtx.Done():
+				return nil
+			}
+			continue
+		}
+		if req == nil {
+			<-slots
+			continue
+		}
+
+		req.Ctx.Put(visitIDKey, id)
+		q.active.Add(1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := req.Do(); err != nil {
+				q.failed.Add(1)
+			} else {
+				q.done.Add(1)
+			}
+			q.active.Add(-1)
+			q.signal()
+		}()
+	}
+}
+
+// next pops the next request; empty reports an empty backlog and a nil
+// request one that could not be decoded
+func (q *CollyQueue) next() (req *colly.Request, empty bool, err error) {
+	size, err := q.storage.QueueSize()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read queue size: %w", err)
+	}
+	if size == 0 {
+		return nil, true, nil
+	}
+	data, err := q.storage.GetRequest()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to load queued request: %w", err)
+	}
+	if data == nil {
+		return nil, true, nil // Taken by another consumer of a shared storage
+	}
+	req, err = q.client.collector.UnmarshalRequest(append([]byte(nil), data...))
+	if err != nil {
+		q.failed.Add(1)
+		return nil, false, nil
+	}
+	return req, false, nil
+}
+
+// idle reports whether no request runs and none is queued
+// Running requests are counted first, as they may queue more before ending
+func (q *CollyQueue) idle() bool {
+	if q.active.Load() > 0 {
+		return false
+	}
+	size, err := q.storage.QueueSize()
+	return err == nil && size == 0
+}
+
+// Stop ends a running Run once its in-flight requests finish
+func (q *CollyQueue) Stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.stop != nil {
+		q.stop()
+	}
+}
+
+// Stats returns the queue's counters
+func (q *CollyQueue) Stats() QueueStats {
+	pending, _ := q.storage.QueueSize() // Zero when the storage cannot tell
+	return QueueStats{
+		Pending: pending,
+		Active:  int(q.active.Load()),
+		Done:    q.done.Load(),
+		Failed:  q.failed.Load(),
+	}
+}
+
+// RedisQueueStorage keeps a CollyQueue backlog in a Redis list so it
+// outlives the process and can be shared by workers
+// A request popped by a worker that crashes before finishing it is lost;
+// use the frontier package where every URL must be crawled
+type RedisQueueStorage struct {
+	client  redis.UniversalClient
+	key     string
+	maxSize int
+}
+
+// RedisQueueStorageConfig holds Redis queue storage settings
+type RedisQueueStorageConfig struct {
+	Client  redis.UniversalClient // Required; e.g. cache.RedisClient.GetClient()
+	Name    string                // Backlog shared by cooperating workers (default "default")
+	Prefix  string                // Key prefix (default "golwarc:colly:queue")
+	MaxSize int                   // Requests beyond it are rejected; 0 is unlimited
+}
+
+// NewRedisQueueStorage creates a Redis-backed queue storage
+func NewRedisQueueStorage(config RedisQueueStorageConfig) (*RedisQueueStorage, error) {
+	if config.Client == nil {
+		return nil, errs.New(errs.CodeInvalidConfig, "redis queue storage requires a client")
+	}
+	if config.Name == "" {
+		config.Name = "default"
+	}
+	if config.Prefix == "" {
+		config.Prefix = "golwarc:colly:queue"
+	}
+	return &RedisQueueStorage{
+		client:  config.Client,
+		key:     config.Prefix + ":" + config.Name,
+		maxSize: config.MaxSize,
+	}, nil
+}
+
+// Init implements queue.Storage; the list needs no setup
+func (s *RedisQueueStorage) Init() error {
+	return nil
+}
+
+// AddRequest implements queue.Storage
+func (s *RedisQueueStorage) AddRequest(data []byte) error {
+	ctx := context.Background()
+	if s.maxSize > 0 {
+		size, err := s.client.LLen(ctx, s.key).Result()
+		if err != nil {
+			return err
+		}
+		if size >= int64(s.maxSize) {
+			return colly.ErrQueueFull
+		}
+	}
+	return s.client.RPush(ctx, s.key, data).Err()
+}
+
+// GetRequest implements queue.Storage; it returns nil when the list is empty
+func (s *RedisQueueStorage) GetRequest() ([]byte, error) {
+	data, err := s.client.LPop(context.Background(), s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// QueueSize implements queue.Storage
+func (s *RedisQueueStorage) QueueSize() (int, error) {
+	size, err := s.client.LLen(context.Background(), s.key).Result()
+	return int(size), err
+}
+
+// Reset drops the backlog
+func (s *RedisQueueStorage) Reset(ctx context.Context) error {
+	return s.client.Del(ctx, s.key).Err()
+}
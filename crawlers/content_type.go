@@ -0,0 +1,144 @@
+package crawlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// sniffLen is how much of a body is inspected when Content-Type is missing
+const sniffLen = 512
+
+// ContentTypeFilterConfig holds content type filter settings
+type ContentTypeFilterConfig struct {
+	Allow        []string // Media types such as text/html, or type/* wildcards; default text/html and application/xhtml+xml
+	HeadRequests bool     // Send a HEAD request first so rejected URLs are never downloaded
+}
+
+// ContentTypeFilter decides from a response's media type whether it is
+// parsed as HTML. Rejected responses, such as PDFs, images and binaries,
+// are skipped instead of being fed to goquery or HTML callbacks
+// A nil filter allows everything
+type ContentTypeFilter struct {
+	exact    map[string]bool
+	prefixes []string // "image/" for image/*
+	head     bool
+}
+
+// NewContentTypeFilter creates a content type filter
+func NewContentTypeFilter(config ContentTypeFilterConfig) *ContentTypeFilter {
+	allow := config.Allow
+	if len(allow) == 0 {
+		allow = []string{"text/html", "application/xhtml+xml"}
+	}
+
+	f := &ContentTypeFilter{exact: make(map[string]bool), head: config.HeadRequests}
+	for _, pattern := range allow {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			f.prefixes = append(f.prefixes, prefix+"/")
+		} else if pattern != "" {
+			f.exact[pattern] = true
+		}
+	}
+	return f
+}
+
+// NewContentTypeFilterFromConfig creates a content type filter from
+// application config. Returns nil when filtering is disabled
+func NewContentTypeFilterFromConfig(config configs.ContentTypeConfig) *ContentTypeFilter {
+	if !config.Enabled {
+		return nil
+	}
+	return NewContentTypeFilter(ContentTypeFilterConfig{
+		Allow:        config.Allow,
+		HeadRequests: config.HeadRequests,
+	})
+}
+
+// Allowed reports whether a Content-Type header value may be parsed
+// An empty value is allowed; callers sniff the body when the header is missing
+func (f *ContentTypeFilter) Allowed(contentType string) bool {
+	if f == nil || contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+		mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	}
+	if f.exact[mediaType] {
+		return true
+	}
+	for _, prefix := range f.prefixes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// HeadRequests reports whether a HEAD request should precede each GET
+func (f *ContentTypeFilter) HeadRequests() bool {
+	return f != nil && f.head
+}
+
+// SkippedContentError is returned for a response whose content type the
+// filter rejects
+type SkippedContentError struct {
+	URL         string
+	ContentType string
+}
+
+// Error implements the error interface
+func (e *SkippedContentError) Error() string {
+	if e.ContentType == "" {
+		return fmt.Sprintf("skipped %s: content type not allowed", e.URL)
+	}
+	return fmt.Sprintf("skipped %s: content type %s not allowed", e.URL, e.ContentType)
+}
+
+// ErrorCode implements errs.Coder
+func (e *SkippedContentError) ErrorCode() errs.Code {
+	return errs.CodeSkippedContent
+}
+
+// sniffContentType returns the response's Content-Type, detecting it from
+// the first bytes of body when the header is missing. Read the body through
+// the returned reader, which replays the sniffed bytes
+func sniffContentType(header http.Header, body io.Reader) (string, io.Reader) {
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		return contentType, body
+	}
+
+	buffered := bufio.NewReaderSize(body, sniffLen)
+	peek, _ := buffered.Peek(sniffLen) // Short bodies return what there is
+	return http.DetectContentType(peek), buffered
+}
+
+// headContentType sends a HEAD request and returns the Content-Type it
+// reports. ok is false when the server did not answer the HEAD request
+// usefully, in which case the GET decides
+func headContentType(client *http.Client, req *http.Request) (contentType string, ok bool) {
+	head := req.Clone(req.Context())
+	head.Method = http.MethodHead
+
+	resp, err := client.Do(head)
+	if err != nil {
+		return "", false
+	}
+	_ = resp.Body.Close() // Error intentionally ignored on close
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false
+	}
+	contentType = resp.Header.Get("Content-Type")
+	return contentType, contentType != ""
+}
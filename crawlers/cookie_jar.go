@@ -0,0 +1,306 @@
+package crawlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"golang.org/x/net/publicsuffix"
+)
+
+// CookieStore persists the cookies of a CookieJar
+type CookieStore interface {
+	// SaveCookies stores encoded cookies, replacing any earlier ones
+	SaveCookies(data []byte) error
+
+	// LoadCookies returns the stored cookies, or nil if there are none
+	LoadCookies() ([]byte, error)
+}
+
+// FileCookieStore keeps cookies in a local file
+type FileCookieStore struct {
+	path string
+}
+
+// NewFileCookieStore creates a cookie store writing to path
+func NewFileCookieStore(path string) *FileCookieStore {
+	return &FileCookieStore{path: path}
+}
+
+// SaveCookies stores encoded cookies
+func (s *FileCookieStore) SaveCookies(data []byte) error {
+	return writeFileAtomic(s.path, data)
+}
+
+// LoadCookies returns the stored cookies, or nil if the file does not exist
+func (s *FileCookieStore) LoadCookies() ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookie file: %w", err)
+	}
+	return data, nil
+}
+
+// CacheCookieStore keeps cookies under one key of a shared cache such as
+// Redis, so workers on several hosts share a session
+type CacheCookieStore struct {
+	client cache.CacheClient
+	key    string
+	ttl    time.Duration
+}
+
+// NewCacheCookieStore creates a cookie store writing to key
+// A zero ttl keeps the cookies until they are overwritten
+func NewCacheCookieStore(client cache.CacheClient, key string, ttl time.Duration) *CacheCookieStore {
+	return &CacheCookieStore{client: client, key: key, ttl: ttl}
+}
+
+// SaveCookies stores encoded cookies
+func (s *CacheCookieStore) SaveCookies(data []byte) error {
+	return s.client.Set(s.key, string(data), s.ttl)
+}
+
+// LoadCookies returns the stored cookies, or nil if the key does not exist
+func (s *CacheCookieStore) LoadCookies() ([]byte, error) {
+	exists, err := s.client.Exists(s.key)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	val, err := s.client.Get(s.key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil, nil // Expired since the check
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(val), nil
+}
+
+// storedCookie is a cookie together with the URL that set it
+type storedCookie struct {
+	URL      string        `json:"url"`
+	Name     string        `json:"name"`
+	Value    string        `json:"value"`
+	Domain   string        `json:"domain,omitempty"` // Empty for host-only cookies
+	Path     string        `json:"path,omitempty"`
+	Expires  time.Time     `json:"expires,omitempty"` // Zero for session cookies
+	Secure   bool          `json:"secure,omitempty"`
+	HttpOnly bool          `json:"http_only,omitempty"`
+	SameSite http.SameSite `json:"same_site,omitempty"`
+}
+
+// cookie returns the http.Cookie to replay into a jar
+func (c storedCookie) cookie() *http.Cookie {
+	return &http.Cookie{
+		Name:     c.Name,
+		Value:    c.Value,
+		Domain:   c.Domain,
+		Path:     c.Path,
+		Expires:  c.Expires,
+		Secure:   c.Secure,
+		HttpOnly: c.HttpOnly,
+		SameSite: c.SameSite,
+	}
+}
+
+// CookieJar is an http.CookieJar with RFC 6265 domain and path matching that
+// can persist its cookies, so authenticated crawls keep their sessions across
+// requests, clones and restarts
+//
+// Session cookies are persisted too; they last until the server expires them
+type CookieJar struct {
+	jar   *cookiejar.Jar
+	store CookieStore
+
+	mu       sync.Mutex
+	cookies  map[string]storedCookie // Keyed by domain, path and name
+	defaults []*http.Cookie
+
+	saveMu sync.Mutex
+}
+
+// NewCookieJar creates a cookie jar; store is optional
+// Cookies saved in store are loaded, and every change is written back
+func NewCookieJar(store CookieStore) (*CookieJar, error) {
+	jar, err := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cookie jar: %w", err)
+	}
+	j := &CookieJar{jar: jar, store: store, cookies: make(map[string]storedCookie)}
+
+	if store != nil {
+		if err := j.load(); err != nil {
+			return nil, err
+		}
+	}
+	return j, nil
+}
+
+// load replays the cookies saved in the store
+func (j *CookieJar) load() error {
+	data, err := j.store.LoadCookies()
+	if err != nil {
+		return fmt.Errorf("failed to load cookies: %w", err)
+	}
+	if data == nil {
+		return nil
+	}
+
+	var stored []storedCookie
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("failed to unmarshal cookies: %w", err)
+	}
+
+	now := time.Now()
+	for _, c := range stored {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		u, err := url.Parse(c.URL)
+		if err != nil {
+			continue
+		}
+		j.jar.SetCookies(u, []*http.Cookie{c.cookie()})
+		j.cookies[cookieKey(u, c.cookie())] = c
+	}
+	return nil
+}
+
+// SetCookies implements http.CookieJar
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) {
+	if len(cookies) == 0 {
+		return
+	}
+	j.jar.SetCookies(u, cookies)
+
+	now := time.Now()
+	j.mu.Lock()
+	for _, c := range cookies {
+		key := cookieKey(u, c)
+		expires := c.Expires
+		if c.MaxAge > 0 {
+			expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		}
+		if c.MaxAge < 0 || (!expires.IsZero() && !expires.After(now)) {
+			delete(j.cookies, key)
+			continue
+		}
+		j.cookies[key] = storedCookie{
+			URL:      u.Scheme + "://" + u.Host + "/",
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     cookiePath(u, c),
+			Expires:  expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: c.SameSite,
+		}
+	}
+	j.mu.Unlock()
+
+	if j.store != nil {
+		if err := j.Save(); err != nil {
+			fmt.Printf("warning: failed to save cookies: %v\n", err)
+		}
+	}
+}
+
+// Cookies implements http.CookieJar
+// Default cookies are added unless the jar holds one with the same name
+func (j *CookieJar) Cookies(u *url.URL) []*http.Cookie {
+	cookies := j.jar.Cookies(u)
+
+	j.mu.Lock()
+	defaults := j.defaults
+	j.mu.Unlock()
+
+	for _, d := range defaults {
+		found := false
+		for _, c := range cookies {
+			if c.Name == d.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			cookies = append(cookies, d)
+		}
+	}
+	return cookies
+}
+
+// SetDefaultCookies sends cookies with every request, whatever its URL
+// They are not persisted and are overridden by cookies the site sets
+func (j *CookieJar) SetDefaultCookies(cookies []*http.Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.defaults = append(j.defaults, cookies...)
+}
+
+// Save writes the current cookies to the store
+func (j *CookieJar) Save() error {
+	if j.store == nil {
+		return nil
+	}
+	// Serialize saves so an older snapshot never overwrites a newer one
+	j.saveMu.Lock()
+	defer j.saveMu.Unlock()
+
+	j.mu.Lock()
+	stored := make([]storedCookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		stored = append(stored, c)
+	}
+	j.mu.Unlock()
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookies: %w", err)
+	}
+	return j.store.SaveCookies(data)
+}
+
+// Len returns the number of stored cookies, excluding defaults
+func (j *CookieJar) Len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.cookies)
+}
+
+// cookieKey identifies a cookie the way a jar does: by domain, path and name
+func cookieKey(u *url.URL, c *http.Cookie) string {
+	domain := strings.TrimPrefix(strings.ToLower(c.Domain), ".")
+	if domain == "" {
+		domain = strings.ToLower(u.Hostname())
+	}
+	return domain + ";" + cookiePath(u, c) + ";" + c.Name
+}
+
+// cookiePath returns the cookie's path, or the default path of u (RFC 6265 5.1.4)
+func cookiePath(u *url.URL, c *http.Cookie) string {
+	if strings.HasPrefix(c.Path, "/") {
+		return c.Path
+	}
+	dir := path.Dir(u.Path)
+	if u.Path == "" || !strings.HasPrefix(u.Path, "/") || dir == "." {
+		return "/"
+	}
+	return dir
+}
+
+// Ensure CookieJar implements the http.CookieJar interface
+var _ http.CookieJar = (*CookieJar)(nil)
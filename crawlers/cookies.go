@@ -0,0 +1,448 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/chromedp"
+	"github.com/gocolly/colly/v2"
+	"github.com/playwright-community/playwright-go"
+	"github.com/tebeka/selenium"
+)
+
+// Cookie is a cookie in a form every crawler engine can export and import,
+// so a login performed in a browser can be reused by the HTTP crawlers
+type Cookie struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Domain is the host of a host-only cookie; a leading dot makes it a
+	// domain cookie that subdomains receive as well, as browsers report it
+	Domain string `json:"domain"`
+
+	Path     string    `json:"path,omitempty"`    // Defaults to /
+	Expires  time.Time `json:"expires,omitempty"` // Zero for session cookies
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+	SameSite string    `json:"same_site,omitempty"` // Strict, Lax, None, or empty
+}
+
+// CookieTransfer is implemented by every crawler client whose cookies can
+// be exported and imported
+type CookieTransfer interface {
+	// ExportCookies returns the client's cookies
+	ExportCookies() ([]Cookie, error)
+
+	// ImportCookies adds cookies to the client, replacing ones with the same
+	// domain, path and name
+	ImportCookies(cookies []Cookie) error
+}
+
+// Ensure every client supports cookie transfer
+var (
+	_ CookieTransfer = (*CookieJar)(nil)
+	_ CookieTransfer = (*CollyClient)(nil)
+	_ CookieTransfer = (*SoupClient)(nil)
+	_ CookieTransfer = (*PlaywrightClient)(nil)
+	_ CookieTransfer = (*PuppeteerClient)(nil)
+	_ CookieTransfer = (*SeleniumClient)(nil)
+)
+
+// CopyCookies imports the cookies exported by one client into another
+func CopyCookies(dst, src CookieTransfer) error {
+	cookies, err := src.ExportCookies()
+	if err != nil {
+		return fmt.Errorf("failed to export cookies: %w", err)
+	}
+	if err := dst.ImportCookies(cookies); err != nil {
+		return fmt.Errorf("failed to import cookies: %w", err)
+	}
+	return nil
+}
+
+// host returns the domain without its leading dot
+func (c Cookie) host() string {
+	return strings.TrimPrefix(c.Domain, ".")
+}
+
+// hostOnly reports whether only the exact host receives the cookie
+func (c Cookie) hostOnly() bool {
+	return !strings.HasPrefix(c.Domain, ".")
+}
+
+// path returns the cookie's path, defaulting to /
+func (c Cookie) path() string {
+	if c.Path == "" {
+		return "/"
+	}
+	return c.Path
+}
+
+// url returns a URL the cookie could have been set from
+func (c Cookie) url() string {
+	scheme := "http"
+	if c.Secure {
+		scheme = "https"
+	}
+	return scheme + "://" + c.host() + c.path()
+}
+
+// expired reports whether the cookie has a past expiry
+func (c Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+// unixExpiry returns the expiry in Unix seconds, or -1 for session cookies
+func (c Cookie) unixExpiry() float64 {
+	if c.Expires.IsZero() {
+		return -1
+	}
+	return float64(c.Expires.UnixMilli()) / 1000
+}
+
+// unixTime converts a browser expiry in Unix seconds; zero and negative
+// values mark session cookies
+func unixTime(seconds float64) time.Time {
+	if seconds <= 0 {
+		return time.Time{}
+	}
+	sec, frac := math.Modf(seconds)
+	return time.Unix(int64(sec), int64(frac*1e9))
+}
+
+// parseSameSite converts a SameSite name to its http.SameSite mode
+func parseSameSite(value string) http.SameSite {
+	switch strings.ToLower(value) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
+
+// sameSiteName returns the SameSite name of an http.SameSite mode
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteNoneMode:
+		return "None"
+	default:
+		return ""
+	}
+}
+
+// ExportCookies returns the cookies in the jar, excluding defaults
+func (j *CookieJar) ExportCookies() ([]Cookie, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := time.Now()
+	cookies := make([]Cookie, 0, len(j.cookies))
+	for _, c := range j.cookies {
+		if !c.Expires.IsZero() && !c.Expires.After(now) {
+			continue
+		}
+		domain := strings.TrimPrefix(c.Domain, ".")
+		if domain != "" {
+			domain = "." + domain
+		} else if u, err := url.Parse(c.URL); err == nil {
+			domain = u.Hostname()
+		}
+		cookies = append(cookies, Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   domain,
+			Path:     c.Path,
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: sameSiteName(c.SameSite),
+		})
+	}
+	return cookies, nil
+}
+
+// ImportCookies adds cookies to the jar; expired ones are skipped
+func (j *CookieJar) ImportCookies(cookies []Cookie) error {
+	// Grouped by URL so a persisted jar is saved once per site
+	var urls []string
+	byURL := make(map[string][]*http.Cookie)
+
+	now := time.Now()
+	for _, c := range cookies {
+		if c.expired(now) {
+			continue
+		}
+		if c.Name == "" || c.host() == "" {
+			return fmt.Errorf("invalid cookie %q for domain %q", c.Name, c.Domain)
+		}
+		cookie := &http.Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Path:     c.path(),
+			Expires:  c.Expires,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+			SameSite: parseSameSite(c.SameSite),
+		}
+		if !c.hostOnly() {
+			cookie.Domain = c.host()
+		}
+
+		u := c.url()
+		if _, ok := byURL[u]; !ok {
+			urls = append(urls, u)
+		}
+		byURL[u] = append(byURL[u], cookie)
+	}
+
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return fmt.Errorf("invalid cookie domain: %w", err)
+		}
+		j.SetCookies(u, byURL[raw])
+	}
+	return nil
+}
+
+// ExportCookies returns the cookies in the client's jar
+func (c *CollyClient) ExportCookies() ([]Cookie, error) {
+	if c.cookies == nil {
+		return nil, colly.ErrNoCookieJar
+	}
+	return c.cookies.ExportCookies()
+}
+
+// ImportCookies adds cookies to the client's jar
+func (c *CollyClient) ImportCookies(cookies []Cookie) error {
+	if c.cookies == nil {
+		return colly.ErrNoCookieJar
+	}
+	return c.cookies.ImportCookies(cookies)
+}
+
+// ExportCookies returns the cookies in the client's jar
+// It fails with colly.ErrNoCookieJar unless SoupConfig.Cookies is set
+func (c *SoupClient) ExportCookies() ([]Cookie, error) {
+	if c.cookies == nil {
+		return nil, colly.ErrNoCookieJar
+	}
+	return c.cookies.ExportCookies()
+}
+
+// ImportCookies adds cookies to the client's jar
+// It fails with colly.ErrNoCookieJar unless SoupConfig.Cookies is set
+func (c *SoupClient) ImportCookies(cookies []Cookie) error {
+	if c.cookies == nil {
+		return colly.ErrNoCookieJar
+	}
+	return c.cookies.ImportCookies(cookies)
+}
+
+// ExportCookies returns the cookies of the page's browser context
+func (p *PlaywrightClient) ExportCookies() ([]Cookie, error) {
+	list, err := p.page.Context().Cookies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+
+	cookies := make([]Cookie, 0, len(list))
+	for _, c := range list {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Expires:  unixTime(c.Expires),
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if c.SameSite != nil {
+			cookie.SameSite = string(*c.SameSite)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// ImportCookies adds cookies to the page's browser context
+func (p *PlaywrightClient) ImportCookies(cookies []Cookie) error {
+	now := time.Now()
+	list := make([]playwright.OptionalCookie, 0, len(cookies))
+	for _, c := range cookies {
+		if c.expired(now) {
+			continue
+		}
+		cookie := playwright.OptionalCookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   playwright.String(c.Domain),
+			Path:     playwright.String(c.path()),
+			Expires:  playwright.Float(c.unixExpiry()),
+			Secure:   playwright.Bool(c.Secure),
+			HttpOnly: playwright.Bool(c.HttpOnly),
+		}
+		if sameSite := sameSiteName(parseSameSite(c.SameSite)); sameSite != "" {
+			attr := playwright.SameSiteAttribute(sameSite)
+			cookie.SameSite = &attr
+		}
+		list = append(list, cookie)
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	if err := p.page.Context().AddCookies(list); err != nil {
+		return fmt.Errorf("failed to set cookies: %w", err)
+	}
+	return nil
+}
+
+// ExportCookies returns every cookie of the browser
+func (p *PuppeteerClient) ExportCookies() ([]Cookie, error) {
+	var list []*network.Cookie
+	err := p.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		list, err = storage.GetCookies().Do(ctx)
+		return err
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+
+	cookies := make([]Cookie, 0, len(list))
+	for _, c := range list {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HTTPOnly,
+			SameSite: c.SameSite.String(),
+		}
+		if !c.Session {
+			cookie.Expires = unixTime(c.Expires)
+		}
+		cookies = append(cookies, cookie)
+	}
+	return cookies, nil
+}
+
+// ImportCookies adds cookies to the browser
+func (p *PuppeteerClient) ImportCookies(cookies []Cookie) error {
+	now := time.Now()
+	params := make([]*network.CookieParam, 0, len(cookies))
+	for _, c := range cookies {
+		if c.expired(now) {
+			continue
+		}
+		param := &network.CookieParam{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.path(),
+			Secure:   c.Secure,
+			HTTPOnly: c.HttpOnly,
+			SameSite: network.CookieSameSite(sameSiteName(parseSameSite(c.SameSite))),
+		}
+		if !c.Expires.IsZero() {
+			expires := cdp.TimeSinceEpoch(c.Expires)
+			param.Expires = &expires
+		}
+		params = append(params, param)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+
+	err := p.run(chromedp.ActionFunc(func(ctx context.Context) error {
+		return network.SetCookies(params).Do(ctx)
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to set cookies: %w", err)
+	}
+	return nil
+}
+
+// ExportCookies returns the cookies visible to the current page
+// WebDriver reports neither HttpOnly nor SameSite
+func (s *SeleniumClient) ExportCookies() ([]Cookie, error) {
+	list, err := s.driver.GetCookies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cookies: %w", err)
+	}
+
+	cookies := make([]Cookie, 0, len(list))
+	for _, c := range list {
+		cookies = append(cookies, Cookie{
+			Name:    c.Name,
+			Value:   c.Value,
+			Domain:  c.Domain,
+			Path:    c.Path,
+			Expires: unixTime(float64(c.Expiry)),
+			Secure:  c.Secure,
+		})
+	}
+	return cookies, nil
+}
+
+// ImportCookies adds the cookies for the current page's site
+// WebDriver only accepts cookies for the site it is on, so navigate there
+// first; cookies of other domains are skipped
+func (s *SeleniumClient) ImportCookies(cookies []Cookie) error {
+	current, err := s.driver.CurrentURL()
+	if err != nil {
+		return fmt.Errorf("failed to read current URL: %w", err)
+	}
+	u, err := url.Parse(current)
+	if err != nil {
+		return fmt.Errorf("invalid current URL: %w", err)
+	}
+	host := strings.ToLower(u.Hostname())
+
+	now := time.Now()
+	for _, c := range cookies {
+		if c.expired(now) || !cookieDomainMatch(host, c) {
+			continue
+		}
+		cookie := &selenium.Cookie{
+			Name:   c.Name,
+			Value:  c.Value,
+			Domain: c.Domain,
+			Path:   c.path(),
+			Secure: c.Secure,
+		}
+		if !c.Expires.IsZero() {
+			cookie.Expiry = uint(c.Expires.Unix())
+		}
+		if err := s.driver.AddCookie(cookie); err != nil {
+			return fmt.Errorf("failed to set cookie %q: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+// cookieDomainMatch reports whether host receives the cookie (RFC 6265 5.1.3)
+func cookieDomainMatch(host string, c Cookie) bool {
+	domain := strings.ToLower(c.host())
+	if c.hostOnly() {
+		return host == domain
+	}
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,289 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// CooldownStore persists per-domain cooldown deadlines
+// Sharing a store (e.g. Redis) lets every worker respect the same cooldowns
+type CooldownStore interface {
+	// SetCooldown blocks the domain until the given time
+	SetCooldown(domain string, until time.Time) error
+
+	// GetCooldown returns the time until which the domain is blocked (zero if none)
+	GetCooldown(domain string) (time.Time, error)
+}
+
+// MemoryCooldownStore keeps cooldowns in process memory
+type MemoryCooldownStore struct {
+	mu        sync.RWMutex
+	cooldowns map[string]time.Time
+}
+
+// NewMemoryCooldownStore creates an in-memory cooldown store
+func NewMemoryCooldownStore() *MemoryCooldownStore {
+	return &MemoryCooldownStore{
+		cooldowns: make(map[string]time.Time),
+	}
+}
+
+// SetCooldown blocks the domain until the given time
+func (s *MemoryCooldownStore) SetCooldown(domain string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cooldowns[domain] = until
+	return nil
+}
+
+// GetCooldown returns the time until which the domain is blocked
+func (s *MemoryCooldownStore) GetCooldown(domain string) (time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cooldowns[domain], nil
+}
+
+// CacheCooldownStore persists cooldowns in a shared cache such as Redis
+// Keys expire together with the cooldown so no cleanup is required
+type CacheCooldownStore struct {
+	client cache.CacheClient
+	prefix string
+}
+
+// NewCacheCooldownStore creates a cooldown store backed by a cache client
+func NewCacheCooldownStore(client cache.CacheClient) *CacheCooldownStore {
+	return &CacheCooldownStore{
+		client: client,
+		prefix: "cooldown:",
+	}
+}
+
+// SetCooldown blocks the domain until the given time
+func (s *CacheCooldownStore) SetCooldown(domain string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return s.client.Delete(s.prefix + domain)
+	}
+	return s.client.Set(s.prefix+domain, until.UTC().Format(time.RFC3339Nano), ttl)
+}
+
+// GetCooldown returns the time until which the domain is blocked
+func (s *CacheCooldownStore) GetCooldown(domain string) (time.Time, error) {
+	exists, err := s.client.Exists(s.prefix + domain)
+	if err != nil || !exists {
+		return time.Time{}, err
+	}
+
+	val, err := s.client.Get(s.prefix + domain)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	until, err := time.Parse(time.RFC3339Nano, val)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cooldown value for %s: %w", domain, err)
+	}
+	return until, nil
+}
+
+// CooldownConfig holds adaptive cooldown configuration
+type CooldownConfig struct {
+	Store     CooldownStore   // Defaults to an in-memory store
+	BaseDelay time.Duration   // First backoff when no Retry-After is sent
+	MaxDelay  time.Duration   // Upper bound for any cooldown
+	Clock     clock.Clock     // Defaults to the wall clock; shared stores expire keys on wall time
+	Metrics   ThrottleMetrics // Optional; records a "paused" action per cooldown
+}
+
+// DomainCooldown backs off individual domains that answer 429 or 503
+// Domains are keyed by registrable domain (eTLD+1), as the rate limiter is.
+// Repeated throttling doubles the cooldown until MaxDelay; a successful
+// response resets the backoff for that domain
+type DomainCooldown struct {
+	store     CooldownStore
+	baseDelay time.Duration
+	maxDelay  time.Duration
+	clock     clock.Clock
+	metrics   ThrottleMetrics
+	strikes   map[string]int
+	mu        sync.Mutex
+}
+
+// NewDomainCooldown creates a new per-domain cooldown tracker
+func NewDomainCooldown(config CooldownConfig) *DomainCooldown {
+	if config.Store == nil {
+		config.Store = NewMemoryCooldownStore()
+	}
+	if config.BaseDelay == 0 {
+		config.BaseDelay = 5 * time.Second
+	}
+	if config.MaxDelay == 0 {
+		config.MaxDelay = 10 * time.Minute
+	}
+
+	return &DomainCooldown{
+		store:     config.Store,
+		baseDelay: config.BaseDelay,
+		maxDelay:  config.MaxDelay,
+		clock:     clock.Or(config.Clock),
+		metrics:   config.Metrics,
+		strikes:   make(map[string]int),
+	}
+}
+
+// NewDomainCooldownFromConfig creates a cooldown tracker from application
+// config and a store chosen by the caller, nil for the in-memory one;
+// metrics is optional; returns nil when cooldowns are disabled
+func NewDomainCooldownFromConfig(config configs.CooldownConfig, store CooldownStore, metrics ThrottleMetrics) *DomainCooldown {
+	if !config.Enabled {
+		return nil
+	}
+	return NewDomainCooldown(CooldownConfig{
+		Store:     store,
+		BaseDelay: time.Duration(config.BaseDelay) * time.Second,
+		MaxDelay:  time.Duration(config.MaxDelay) * time.Second,
+		Metrics:   metrics,
+	})
+}
+
+// ThrottledError is returned when a host answers 429/503 and its domain is put on cooldown
+type ThrottledError struct {
+	URL        string
+	StatusCode int
+	Delay      time.Duration
+}
+
+// Error implements the error interface
+func (e *ThrottledError) Error() string {
+	return fmt.Sprintf("throttled by host (status %d), cooling down for %s", e.StatusCode, e.Delay)
+}
+
+// ErrorCode implements errs.Coder
+func (e *ThrottledError) ErrorCode() errs.Code {
+	return errs.CodeThrottled
+}
+
+// IsThrottleStatus reports whether a status code asks the client to slow down
+func IsThrottleStatus(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// HandleResponse records a response for the URL's domain
+// Returns the cooldown applied and true when the domain was throttled; a
+// nil DomainCooldown ignores responses
+func (d *DomainCooldown) HandleResponse(rawURL string, statusCode int, header http.Header) (time.Duration, bool) {
+	if d == nil {
+		return 0, false
+	}
+	domain := RateLimitDomain(rawURL)
+	if domain == "" {
+		return 0, false
+	}
+
+	d.mu.Lock()
+	if !IsThrottleResponse(statusCode, header) {
+		delete(d.strikes, domain)
+		d.mu.Unlock()
+		return 0, false
+	}
+	d.strikes[domain]++
+	strikes := d.strikes[domain]
+	d.mu.Unlock()
+
+	now := d.clock.Now()
+	delay, ok := ParseRetryAfter(header.Get("Retry-After"), now)
+	if !ok {
+		delay = d.baseDelay
+		for i := 1; i < strikes && delay < d.maxDelay; i++ {
+			delay *= 2
+		}
+	}
+	if delay > d.maxDelay {
+		delay = d.maxDelay
+	}
+
+	if err := d.store.SetCooldown(domain, now.Add(delay)); err != nil {
+		fmt.Printf("warning: failed to persist cooldown for %s: %v\n", domain, err)
+	}
+	if d.metrics != nil {
+		d.metrics.RecordThrottle(ThrottleActionPaused)
+	}
+	return delay, true
+}
+
+// Remaining returns how long the URL's domain is still cooling down
+func (d *DomainCooldown) Remaining(rawURL string) time.Duration {
+	if d == nil {
+		return 0
+	}
+	domain := RateLimitDomain(rawURL)
+	if domain == "" {
+		return 0
+	}
+
+	until, err := d.store.GetCooldown(domain)
+	if err != nil || until.IsZero() {
+		return 0
+	}
+
+	remaining := d.clock.Until(until)
+	if remaining < 0 {
+		return 0
+	}
+	return remaining
+}
+
+// Wait blocks until the URL's domain is no longer cooling down
+func (d *DomainCooldown) Wait(ctx context.Context, rawURL string) error {
+	if d == nil {
+		return ctx.Err()
+	}
+	return clock.Sleep(ctx, d.clock, d.Remaining(rawURL))
+}
+
+// observeThrottle is the one path engines report responses through: the
+// adaptive limiter lowers the domain's rate and the cooldown holds it for
+// Retry-After or its backoff. It returns the wait before the domain's next
+// request and whether the response throttled it; either may be nil
+func observeThrottle(limiter *RateLimiter, cooldown *DomainCooldown, rawURL string, statusCode int, header http.Header) (time.Duration, bool) {
+	delay, throttled := limiter.Observe(rawURL, statusCode, header)
+	if wait, cooled := cooldown.HandleResponse(rawURL, statusCode, header); cooled {
+		return max(delay, wait), true
+	}
+	return delay, throttled
+}
+
+// ParseRetryAfter parses a Retry-After header value (delay-seconds or HTTP-date)
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := date.Sub(now)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,183 @@
+package crawlers
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// CrawlDelay returns the gap robots.txt asks agent to keep between requests
+// to the host of rawURL: the larger of the Crawl-delay and Request-rate
+// directives of the agent's group, or zero when it sets neither. Errors are
+// returned only for invalid URLs and when ctx is done
+func (r *RobotsTxt) CrawlDelay(ctx context.Context, rawURL, agent string) (time.Duration, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0, err
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return 0, fmt.Errorf("cannot check robots.txt of %s", rawURL)
+	}
+
+	parsed, err := r.rules(ctx, strings.ToLower(u.Scheme+"://"+u.Host))
+	if err != nil {
+		return 0, err
+	}
+	return max(parsed.data.FindGroup(agent).CrawlDelay, parsed.requestRate(agent)), nil
+}
+
+// requestRate returns the Request-rate interval of agent's group, picked
+// like the robotstxt package picks groups: the longest user agent that is
+// a prefix of agent, else "*"
+func (p *parsedRobots) requestRate(agent string) time.Duration {
+	agent = strings.ToLower(agent)
+	interval, matched := p.rates["*"], 1
+	for a, i := range p.rates {
+		if a != "*" && strings.HasPrefix(agent, a) && len(a) > matched {
+			interval, matched = i, len(a)
+		}
+	}
+	return interval
+}
+
+// parseRequestRates reads the Request-rate directives of a 2xx robots.txt,
+// e.g. "Request-rate: 1/5" for one request every five seconds, by user
+// agent. Rates may carry a unit (1/10m) and a time window, which is ignored
+func parseRequestRates(entry *RobotsEntry) map[string]time.Duration {
+	if entry.Status < 200 || entry.Status >= 300 {
+		return nil
+	}
+
+	var rates map[string]time.Duration
+	var agents []string
+	inRules := false // A rule ended the user-agent lines of the group
+	scanner := bufio.NewScanner(strings.NewReader(entry.Body))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "request-rate":
+			inRules = true
+			interval, ok := parseRequestRate(value)
+			if !ok {
+				continue
+			}
+			if rates == nil {
+				rates = make(map[string]time.Duration)
+			}
+			for _, agent := range agents {
+				rates[agent] = interval
+			}
+		default:
+			inRules = true
+		}
+	}
+	return rates
+}
+
+// parseRequestRate turns "requests/period[unit] [window]" into the
+// interval between requests
+func parseRequestRate(value string) (time.Duration, bool) {
+	value, _, _ = strings.Cut(value, " ")
+	requests, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseFloat(requests, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	unit := time.Second
+	switch {
+	case strings.HasSuffix(period, "s"):
+		period = strings.TrimSuffix(period, "s")
+	case strings.HasSuffix(period, "m"):
+		period, unit = strings.TrimSuffix(period, "m"), time.Minute
+	case strings.HasSuffix(period, "h"):
+		period, unit = strings.TrimSuffix(period, "h"), time.Hour
+	case strings.HasSuffix(period, "d"):
+		period, unit = strings.TrimSuffix(period, "d"), 24*time.Hour
+	}
+	p, err := strconv.ParseFloat(period, 64)
+	if err != nil || p <= 0 {
+		return 0, false
+	}
+	return time.Duration(p * float64(unit) / n), true
+}
+
+// HonorCrawlDelay makes the limiter slow a domain down to the Crawl-delay
+// or Request-rate its robots.txt asks agent to keep, where that is slower
+// than the configured limit. Delays above maxDelay are capped to it; zero
+// leaves them uncapped. Call it before the limiter is used
+func (l *RateLimiter) HonorCrawlDelay(robots *RobotsTxt, agent string, maxDelay time.Duration) {
+	l.robots, l.robotsAgent, l.maxCrawlDelay = robots, agent, maxDelay
+}
+
+// applyCrawlDelay looks up the robots.txt delay of rawURL's host and
+// adjusts d's limit when it changed. robots.txt files that cannot be read
+// leave the limit alone; only ctx ending is an error
+func (l *RateLimiter) applyCrawlDelay(ctx context.Context, d *domainLimit, rawURL string) error {
+	if l.robots == nil {
+		return nil
+	}
+	delay, err := l.robots.CrawlDelay(ctx, rawURL, l.robotsAgent)
+	if err != nil {
+		return ctx.Err()
+	}
+	if l.maxCrawlDelay > 0 {
+		delay = min(delay, l.maxCrawlDelay)
+	}
+	origin := rawURL
+	if u, err := url.Parse(rawURL); err == nil {
+		origin = strings.ToLower(u.Scheme + "://" + u.Host)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.crawlDelays[origin] == delay {
+		return nil
+	}
+	if d.crawlDelays == nil {
+		d.crawlDelays = make(map[string]time.Duration)
+	}
+	d.crawlDelays[origin] = delay
+	d.limiter.SetLimitAt(l.clock.Now(), l.domainRate(d))
+	return nil
+}
+
+// minDelay is the slowest of the configured gap and the robots.txt delays
+// of d's hosts; d.mu must be held
+func (l *RateLimiter) minDelay(d *domainLimit) time.Duration {
+	gap := l.baseDelay()
+	for _, delay := range d.crawlDelays {
+		gap = max(gap, delay)
+	}
+	return gap
+}
+
+// domainRate is the limit d currently runs at: its raised delay, its
+// robots.txt delay or the configured limit; d.mu must be held
+func (l *RateLimiter) domainRate(d *domainLimit) rate.Limit {
+	gap := max(d.delay, l.minDelay(d))
+	if gap <= l.baseDelay() {
+		return l.limit
+	}
+	return rate.Every(gap)
+}
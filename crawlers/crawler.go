@@ -0,0 +1,119 @@
+package crawlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Result is a page fetched by a Crawler, whatever the engine
+type Result struct {
+	URL        string        `json:"url"`         // Final URL, after redirects
+	StatusCode int           `json:"status_code"` // 0 when the engine cannot see it (Selenium)
+	Header     http.Header   `json:"header,omitempty"`
+	Body       []byte        `json:"-"`      // HTML; the rendered DOM for browser engines
+	Engine     string        `json:"engine"` // One of the CrawlerType constants
+	Duration   time.Duration `json:"duration"`
+}
+
+// Document parses the body
+func (r *Result) Document() (*goquery.Document, error) {
+	return goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+}
+
+// Crawler fetches single pages with any engine, so services and the DI
+// container can swap engines without code changes. NewCollyCrawler,
+// NewSoupCrawler, NewSpiderCrawler, NewPlaywrightCrawler,
+// NewPuppeteerCrawler and NewSeleniumCrawler adapt the engine clients
+//
+// Responses with an error status are results, not errors; errors mean no
+// response arrived
+type Crawler interface {
+	// Fetch fetches url through the middleware, giving up when ctx is done
+	Fetch(ctx context.Context, url string) (*Result, error)
+
+	// Use appends middleware; the first registered runs outermost
+	Use(middleware ...Middleware)
+
+	// OnResult registers a callback run for every fetched page
+	OnResult(handler func(r *Result))
+
+	// OnFetchError registers a callback run for every failed fetch
+	OnFetchError(handler func(url string, err error))
+
+	// Engine returns the engine's CrawlerType constant
+	Engine() string
+
+	// Close closes the engine's browser, if any
+	Close() error
+}
+
+// crawlerBase implements the middleware and callbacks of Crawler adapters
+type crawlerBase struct {
+	mu         sync.RWMutex
+	middleware []Middleware
+	onResult   []func(r *Result)
+	onError    []func(url string, err error)
+}
+
+// Use appends middleware; the first registered runs outermost
+func (c *crawlerBase) Use(middleware ...Middleware) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.middleware = append(c.middleware, middleware...)
+}
+
+// OnResult registers a callback run for every fetched page
+func (c *crawlerBase) OnResult(handler func(r *Result)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onResult = append(c.onResult, handler)
+}
+
+// OnFetchError registers a callback run for every failed fetch
+func (c *crawlerBase) OnFetchError(handler func(url string, err error)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onError = append(c.onError, handler)
+}
+
+// run fetches url with an engine's fetch function through the middleware
+// and runs the callbacks. The engine's results are stamped and its errors
+// wrapped before the middleware sees them
+func (c *crawlerBase) run(ctx context.Context, engine, url string, fetch FetcherFunc) (*Result, error) {
+	c.mu.RLock()
+	middleware := c.middleware
+	c.mu.RUnlock()
+
+	result, err := Chain(FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+		start := time.Now()
+		result, err := fetch(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("%s fetch of %s failed: %w", engine, url, err)
+		}
+		result.Engine = engine
+		result.Duration = time.Since(start)
+		if result.URL == "" {
+			result.URL = url
+		}
+		return result, nil
+	}), middleware...).Fetch(ctx, url)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if err != nil {
+		for _, handler := range c.onError {
+			handler(url, err)
+		}
+		return nil, err
+	}
+	for _, handler := range c.onResult {
+		handler(result)
+	}
+	return result, nil
+}
@@ -0,0 +1,378 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/chromedp/chromedp"
+	"github.com/gocolly/colly/v2"
+)
+
+// errNoResponse is returned when an engine finished without a response,
+// e.g. because a budget, cooldown or content type filter aborted the request
+var errNoResponse = errors.New("no response received")
+
+// CollyCrawler adapts a CollyClient to the Crawler interface
+// The client's callbacks keep working; revisits are allowed so a URL can be
+// fetched again
+type CollyCrawler struct {
+	crawlerBase
+	client *CollyClient
+}
+
+// collyFetchKey is the VisitContext key of the fetch a request belongs to
+type collyFetchKey struct{}
+
+// collyFetch collects the outcome of one CollyCrawler.Fetch
+type collyFetch struct {
+	mu     sync.Mutex
+	result *Result
+	err    error
+}
+
+// NewCollyCrawler adapts client to the Crawler interface
+func NewCollyCrawler(client *CollyClient) *CollyCrawler {
+	a := &CollyCrawler{client: client}
+	client.collector.AllowURLRevisit = true
+	client.OnResponse(func(r *colly.Response) {
+		a.record(r, nil)
+	})
+	client.OnError(func(r *colly.Response, err error) {
+		if r != nil && r.StatusCode > 0 {
+			err = nil // Error statuses are results
+		}
+		a.record(r, err)
+	})
+	return a
+}
+
+// record stores the first outcome of the fetch r belongs to
+func (a *CollyCrawler) record(r *colly.Response, err error) {
+	if r == nil || r.Request == nil {
+		return
+	}
+	f, ok := a.client.visits.requestContext(r.Request).Value(collyFetchKey{}).(*collyFetch)
+	if !ok {
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.result != nil || f.err != nil {
+		return // A page this fetch led to, e.g. through an OnHTML callback
+	}
+	if err != nil {
+		f.err = err
+		return
+	}
+	f.result = &Result{URL: r.Request.URL.String(), StatusCode: r.StatusCode, Body: r.Body}
+	if r.Headers != nil {
+		f.result.Header = r.Headers.Clone()
+	}
+}
+
+// fetch fetches url and waits for it
+func (a *CollyCrawler) fetch(ctx context.Context, url string) (*Result, error) {
+	f := &collyFetch{}
+	err := a.client.VisitContext(context.WithValue(ctx, collyFetchKey{}, f), url)
+	a.client.Wait()
+
+	f.mu.Lock()
+	result, fetchErr := f.result, f.err
+	f.mu.Unlock()
+	switch {
+	case result != nil:
+		err = nil // Visit also fails on error statuses
+	case err == nil && fetchErr != nil:
+		err = fetchErr
+	case err == nil:
+		err = errNoResponse
+	}
+	return result, err
+}
+
+// Fetch fetches url through the middleware
+func (a *CollyCrawler) Fetch(ctx context.Context, url string) (*Result, error) {
+	return a.run(ctx, CrawlerTypeColly, url, a.fetch)
+}
+
+// Engine returns CrawlerTypeColly
+func (a *CollyCrawler) Engine() string {
+	return CrawlerTypeColly
+}
+
+// Close does nothing; CollyCrawler holds no browser
+func (a *CollyCrawler) Close() error {
+	return nil
+}
+
+// SoupCrawler adapts a SoupClient to the Crawler interface
+// MaxBodySize and ContentTypes apply as for SoupClient.Get
+type SoupCrawler struct {
+	crawlerBase
+	client *SoupClient
+}
+
+// NewSoupCrawler adapts client to the Crawler interface
+func NewSoupCrawler(client *SoupClient) *SoupCrawler {
+	return &SoupCrawler{client: client}
+}
+
+// fetch fetches url
+func (a *SoupCrawler) fetch(ctx context.Context, url string) (*Result, error) {
+	resp, body, err := a.client.fetch(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       []byte(body),
+	}, nil
+}
+
+// Fetch fetches url through the middleware
+func (a *SoupCrawler) Fetch(ctx context.Context, url string) (*Result, error) {
+	return a.run(ctx, CrawlerTypeSoup, url, a.fetch)
+}
+
+// Engine returns CrawlerTypeSoup
+func (a *SoupCrawler) Engine() string {
+	return CrawlerTypeSoup
+}
+
+// Close does nothing; SoupCrawler holds no browser
+func (a *SoupCrawler) Close() error {
+	return nil
+}
+
+// SpiderCrawler adapts a Spider to the Crawler interface
+// Fetches honor the spider's cooldown and rate limiter but are not queued,
+// counted against its budget or passed to its document callbacks
+type SpiderCrawler struct {
+	crawlerBase
+	spider *Spider
+}
+
+// NewSpiderCrawler adapts spider to the Crawler interface
+func NewSpiderCrawler(spider *Spider) *SpiderCrawler {
+	return &SpiderCrawler{spider: spider}
+}
+
+// fetch fetches url
+func (a *SpiderCrawler) fetch(ctx context.Context, url string) (*Result, error) {
+	resp, release, err := a.spider.get(ctx, url, false)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+	}, nil
+}
+
+// Fetch fetches url through the middleware
+func (a *SpiderCrawler) Fetch(ctx context.Context, url string) (*Result, error) {
+	return a.run(ctx, CrawlerTypeSpider, url, a.fetch)
+}
+
+// Engine returns CrawlerTypeSpider
+func (a *SpiderCrawler) Engine() string {
+	return CrawlerTypeSpider
+}
+
+// Close does nothing; SpiderCrawler holds no browser
+func (a *SpiderCrawler) Close() error {
+	return nil
+}
+
+// PlaywrightCrawler adapts a PlaywrightClient to the Crawler interface
+// Fetches run one at a time on the client's page; results carry the
+// rendered DOM and no headers
+type PlaywrightCrawler struct {
+	crawlerBase
+	client *PlaywrightClient
+	mu     sync.Mutex
+}
+
+// NewPlaywrightCrawler adapts client to the Crawler interface
+func NewPlaywrightCrawler(client *PlaywrightClient) *PlaywrightCrawler {
+	return &PlaywrightCrawler{client: client}
+}
+
+// fetch navigates to url and returns the rendered page
+func (a *PlaywrightCrawler) fetch(ctx context.Context, url string) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	release, err := a.client.throttle(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	status, err := a.client.gotoRecorded(ctx, a.client.page, url)
+	if err != nil {
+		return nil, err
+	}
+	html, err := a.client.page.Content()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		URL:        a.client.page.URL(),
+		StatusCode: status,
+		Body:       []byte(html),
+	}, nil
+}
+
+// Fetch fetches url through the middleware
+func (a *PlaywrightCrawler) Fetch(ctx context.Context, url string) (*Result, error) {
+	return a.run(ctx, CrawlerTypePlaywright, url, a.fetch)
+}
+
+// Engine returns CrawlerTypePlaywright
+func (a *PlaywrightCrawler) Engine() string {
+	return CrawlerTypePlaywright
+}
+
+// Close closes the client
+func (a *PlaywrightCrawler) Close() error {
+	return a.client.Close()
+}
+
+// PuppeteerCrawler adapts a PuppeteerClient to the Crawler interface
+// Fetches run concurrently in the client's pooled tabs, up to
+// PuppeteerConfig.Tabs; results carry the rendered DOM
+type PuppeteerCrawler struct {
+	crawlerBase
+	client *PuppeteerClient
+}
+
+// NewPuppeteerCrawler adapts client to the Crawler interface
+func NewPuppeteerCrawler(client *PuppeteerClient) *PuppeteerCrawler {
+	return &PuppeteerCrawler{client: client}
+}
+
+// fetch navigates a pooled tab to url and returns the rendered page
+func (a *PuppeteerCrawler) fetch(ctx context.Context, url string) (*Result, error) {
+	tab, err := a.client.Checkout(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer a.client.Checkin(tab)
+
+	resp, err := tab.Navigate(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	var html, location string
+	err = tab.Run(
+		chromedp.Evaluate("document.documentElement.outerHTML", &html),
+		chromedp.Location(&location),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header, len(resp.Headers))
+	for name, value := range resp.Headers {
+		header.Set(name, fmt.Sprint(value))
+	}
+	return &Result{
+		URL:        location,
+		StatusCode: int(resp.Status),
+		Header:     header,
+		Body:       []byte(html),
+	}, nil
+}
+
+// Fetch fetches url through the middleware
+func (a *PuppeteerCrawler) Fetch(ctx context.Context, url string) (*Result, error) {
+	return a.run(ctx, CrawlerTypePuppeteer, url, a.fetch)
+}
+
+// Engine returns CrawlerTypePuppeteer
+func (a *PuppeteerCrawler) Engine() string {
+	return CrawlerTypePuppeteer
+}
+
+// Close closes the client
+func (a *PuppeteerCrawler) Close() error {
+	return a.client.Close()
+}
+
+// SeleniumCrawler adapts a SeleniumClient to the Crawler interface
+// WebDriver exposes neither status codes nor headers, so results carry the
+// rendered DOM only. A navigation abandoned on ctx keeps loading in the
+// browser until the next one
+type SeleniumCrawler struct {
+	crawlerBase
+	client *SeleniumClient
+	mu     sync.Mutex
+}
+
+// NewSeleniumCrawler adapts client to the Crawler interface
+func NewSeleniumCrawler(client *SeleniumClient) *SeleniumCrawler {
+	return &SeleniumCrawler{client: client}
+}
+
+// fetch navigates to url and returns the rendered page
+func (a *SeleniumCrawler) fetch(ctx context.Context, url string) (*Result, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	done := make(chan error, 1)
+	go func() {
+		done <- a.client.Navigate(url)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	html, err := a.client.GetPageSource()
+	if err != nil {
+		return nil, err
+	}
+	location, err := a.client.GetCurrentURL()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{URL: location, Body: []byte(html)}, nil
+}
+
+// Fetch fetches url through the middleware
+func (a *SeleniumCrawler) Fetch(ctx context.Context, url string) (*Result, error) {
+	return a.run(ctx, CrawlerTypeSelenium, url, a.fetch)
+}
+
+// Engine returns CrawlerTypeSelenium
+func (a *SeleniumCrawler) Engine() string {
+	return CrawlerTypeSelenium
+}
+
+// Close closes the client
+func (a *SeleniumCrawler) Close() error {
+	return a.client.Close()
+}
@@ -0,0 +1,89 @@
+package crawlers
+
+import (
+	"net/url"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Dedup strategies: what makes two crawled pages the same page
+const (
+	DedupExact      = "exact"      // The URL as fetched (default)
+	DedupNormalized = "normalized" // The normalized URL; see NormalizeURL
+	DedupContent    = "content"    // The normalized URL and a hash of the body; changed pages are stored again
+	DedupCanonical  = "canonical"  // The canonical URL a page declares or redirects to
+)
+
+// DedupKey computes the identity a project deduplicates pages by, so the
+// frontier, the page cache and the page store agree on what was seen
+//
+// URLs are known before a fetch and bodies only after it, so the key has
+// two halves: URL is the key of a URL about to be queued or fetched, Page
+// the key of a fetched page. For the exact and normalized strategies they
+// are the same; the content and canonical strategies queue by normalized
+// URL and refine the identity once the page is in
+// A nil DedupKey uses the exact strategy
+type DedupKey struct {
+	strategy string
+}
+
+// NewDedupKey creates a dedup key for strategy; empty means exact
+func NewDedupKey(strategy string) (*DedupKey, error) {
+	switch strategy {
+	case "":
+		strategy = DedupExact
+	case DedupExact, DedupNormalized, DedupContent, DedupCanonical:
+	default:
+		return nil, errs.Newf(errs.CodeInvalidConfig, "invalid dedup strategy %q: use exact, normalized, content or canonical", strategy)
+	}
+	return &DedupKey{strategy: strategy}, nil
+}
+
+// Strategy returns the dedup strategy
+func (k *DedupKey) Strategy() string {
+	if k == nil {
+		return DedupExact
+	}
+	return k.strategy
+}
+
+// URL returns the key of rawURL before it is fetched, for frontier seen
+// sets and page cache keys
+func (k *DedupKey) URL(rawURL string) string {
+	if k.Strategy() == DedupExact {
+		return rawURL
+	}
+	return NormalizeURL(rawURL)
+}
+
+// Page returns the key of a fetched page: its URL, canonical URL and the
+// hash of its body. Pages with the same key are duplicates
+func (k *DedupKey) Page(rawURL, canonicalURL, contentHash string) string {
+	switch k.Strategy() {
+	case DedupContent:
+		return NormalizeURL(rawURL) + " " + contentHash
+	case DedupCanonical:
+		if canonicalURL != "" {
+			return NormalizeURL(canonicalURL)
+		}
+	}
+	return k.URL(rawURL)
+}
+
+// NormalizeURL returns rawURL with the scheme and host lowercased, default
+// ports and the fragment removed, an empty path made / and the query
+// parameters sorted. Unlike a Canonicalizer it never changes which page a
+// URL addresses. Other than http and https URLs are returned unchanged
+func NormalizeURL(rawURL string) string {
+	normalized := (*Canonicalizer)(nil).Canonicalize(rawURL)
+	u, err := url.Parse(normalized)
+	if err != nil || u.RawQuery == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return normalized
+	}
+	query, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return normalized // Leave queries url.ParseQuery cannot read alone
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
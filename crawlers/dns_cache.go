@@ -0,0 +1,478 @@
+package crawlers
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"golang.org/x/net/dns/dnsmessage"
+	"golang.org/x/sync/singleflight"
+)
+
+// DNSCacheConfig holds caching resolver settings
+type DNSCacheConfig struct {
+	// Servers are nameservers (host:port) queried directly, so answers are
+	// cached for their record TTLs and missing hosts for their zone's
+	// negative TTL. Names are queried as fully qualified, without search
+	// domains. Empty uses the system resolver, whose answers are cached for TTL
+	Servers []string
+
+	TTL         time.Duration // Cache time of system resolver answers (default 5m)
+	MinTTL      time.Duration // Record TTLs below it are raised to it (default 5s)
+	MaxTTL      time.Duration // Record and negative TTLs above it are capped (default 1h)
+	NegativeTTL time.Duration // Cache time of missing hosts whose zone sets none (default 30s)
+	MaxEntries  int           // Hosts kept; the least recently used are evicted (default 10000)
+	Timeout     time.Duration // Per lookup (default 5s)
+	Clock       clock.Clock   // Defaults to the wall clock
+}
+
+// DNSCache resolves hostnames for crawler transports and caches the answers,
+// so a large crawl does not resolve the same hosts for every connection
+// It is safe for concurrent use and meant to be shared by every client of a
+// process; concurrent lookups of one host share a single query
+//
+// Hosts that do not exist are cached too (negative caching). Timeouts and
+// server failures are not cached. A nil DNSCache leaves transports unchanged
+type DNSCache struct {
+	servers     []string
+	ttl         time.Duration
+	minTTL      time.Duration
+	maxTTL      time.Duration
+	negativeTTL time.Duration
+	timeout     time.Duration
+	clock       clock.Clock
+
+	entries *lru.Cache[string, dnsEntry]
+	group   singleflight.Group
+
+	hits         atomic.Int64
+	negativeHits atomic.Int64
+	misses       atomic.Int64
+}
+
+// dnsEntry is a cached answer; a nil ips slice records a missing host
+type dnsEntry struct {
+	ips     []net.IP
+	expires time.Time
+}
+
+// DNSStats holds DNS cache counters
+type DNSStats struct {
+	Entries      int   // Hosts cached, including expired ones not yet evicted
+	Hits         int64 // Lookups answered with addresses from the cache
+	NegativeHits int64 // Lookups answered from the cache that the host does not exist
+	Misses       int64 // Lookups that queried a resolver
+}
+
+// NewDNSCache creates an empty caching resolver
+func NewDNSCache(config DNSCacheConfig) *DNSCache {
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+	if config.MinTTL <= 0 {
+		config.MinTTL = 5 * time.Second
+	}
+	if config.MaxTTL <= 0 {
+		config.MaxTTL = time.Hour
+	}
+	if config.NegativeTTL <= 0 {
+		config.NegativeTTL = 30 * time.Second
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 10000
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 5 * time.Second
+	}
+	entries, _ := lru.New[string, dnsEntry](config.MaxEntries) // Only fails for a size below 1
+
+	return &DNSCache{
+		servers:     config.Servers,
+		ttl:         config.TTL,
+		minTTL:      config.MinTTL,
+		maxTTL:      config.MaxTTL,
+		negativeTTL: config.NegativeTTL,
+		timeout:     config.Timeout,
+		clock:       clock.Or(config.Clock),
+		entries:     entries,
+	}
+}
+
+// NewDNSCacheFromConfig creates a caching resolver from application config;
+// returns nil when it is disabled
+func NewDNSCacheFromConfig(config configs.DNSCacheConfig) *DNSCache {
+	if !config.Enabled {
+		return nil
+	}
+	return NewDNSCache(DNSCacheConfig{
+		Servers:     config.Servers,
+		TTL:         time.Duration(config.TTL) * time.Second,
+		MaxTTL:      time.Duration(config.MaxTTL) * time.Second,
+		NegativeTTL: time.Duration(config.NegativeTTL) * time.Second,
+		MaxEntries:  config.MaxEntries,
+	})
+}
+
+// LookupIP returns the addresses of host, IPv4 first, from the cache or a
+// resolver. A missing host fails with a *net.DNSError whose IsNotFound is
+// set; IP literals are returned as is
+func (d *DNSCache) LookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	if entry, ok := d.entries.Get(host); ok && d.clock.Now().Before(entry.expires) {
+		if entry.ips == nil {
+			d.negativeHits.Add(1)
+			return nil, notFoundError(host)
+		}
+		d.hits.Add(1)
+		return entry.ips, nil
+	}
+
+	// The query outlives a cancelled caller so others waiting on it still
+	// get an answer
+	result := d.group.DoChan(host, func() (interface{}, error) {
+		d.misses.Add(1)
+		lookupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), d.timeout)
+		defer cancel()
+		ips, ttl, err := d.resolve(lookupCtx, host)
+		if err != nil {
+			return nil, err
+		}
+		d.entries.Add(host, dnsEntry{ips: ips, expires: d.clock.Now().Add(ttl)})
+		return ips, nil
+	})
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		ips := res.Val.([]net.IP)
+		if ips == nil {
+			return nil, notFoundError(host)
+		}
+		return ips, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Forget drops the cached answer for host, e.g. after a site moved
+func (d *DNSCache) Forget(host string) {
+	d.entries.Remove(strings.ToLower(strings.TrimSuffix(host, ".")))
+}
+
+// Flush drops every cached answer
+func (d *DNSCache) Flush() {
+	d.entries.Purge()
+}
+
+// Stats returns the cache's counters
+func (d *DNSCache) Stats() DNSStats {
+	return DNSStats{
+		Entries:      d.entries.Len(),
+		Hits:         d.hits.Load(),
+		NegativeHits: d.negativeHits.Load(),
+		Misses:       d.misses.Load(),
+	}
+}
+
+// DialContext wraps dial so hostnames are resolved through the cache
+// The addresses of a host are tried in turn until one connects
+func (d *DNSCache) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+		ips, err := d.LookupIP(ctx, host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+
+		var firstErr error
+		for _, ip := range ips {
+			if (network == "tcp4" && ip.To4() == nil) || (network == "tcp6" && ip.To4() != nil) {
+				continue
+			}
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if firstErr == nil {
+			firstErr = &net.OpError{Op: "dial", Net: network, Err: fmt.Errorf("no %s address for %s", network, host)}
+		}
+		return nil, firstErr
+	}
+}
+
+// Transport returns a copy of base that resolves hostnames through the
+// cache. Connections are made by the transport's own DialContext, so dial
+// timeouts and keep-alives are kept. Round trippers other than
+// *http.Transport are returned unchanged
+func (d *DNSCache) Transport(base http.RoundTripper) http.RoundTripper {
+	transport, ok := base.(*http.Transport)
+	if d == nil || !ok {
+		return base
+	}
+	transport = transport.Clone()
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+	transport.DialContext = d.DialContext(dial)
+	return transport
+}
+
+// resolve looks host up; a missing host returns nil addresses
+func (d *DNSCache) resolve(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	if len(d.servers) == 0 {
+		return d.resolveSystem(ctx, host)
+	}
+
+	var lastErr error
+	for _, server := range d.servers {
+		ips, ttl, err := d.resolveServer(ctx, server, host)
+		if err == nil {
+			return ips, ttl, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, 0, lastErr
+}
+
+// resolveSystem looks host up with the system resolver, which does not
+// report TTLs
+func (d *DNSCache) resolveSystem(ctx context.Context, host string) ([]net.IP, time.Duration, error) {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return nil, d.negativeTTL, nil
+		}
+		return nil, 0, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	ips = sortIPv4First(ips)
+	if len(ips) == 0 {
+		return nil, d.negativeTTL, nil
+	}
+	return ips, d.ttl, nil
+}
+
+// dnsAnswer is the outcome of one query type; no addresses and no error
+// means the name or the record type does not exist
+type dnsAnswer struct {
+	ips []net.IP
+	ttl time.Duration
+	err error
+}
+
+// resolveServer queries A and AAAA records of host from server
+func (d *DNSCache) resolveServer(ctx context.Context, server, host string) ([]net.IP, time.Duration, error) {
+	name, err := dnsmessage.NewName(host + ".")
+	if err != nil {
+		return nil, 0, &net.DNSError{Err: "invalid hostname", Name: host}
+	}
+
+	answers := make(chan dnsAnswer, 2)
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		go func() {
+			answers <- d.query(ctx, server, name, qtype)
+		}()
+	}
+	first, second := <-answers, <-answers
+
+	var ips []net.IP
+	var ttl time.Duration
+	for _, answer := range []dnsAnswer{first, second} {
+		if len(answer.ips) == 0 {
+			continue
+		}
+		ips = append(ips, answer.ips...)
+		if ttl == 0 || answer.ttl < ttl {
+			ttl = answer.ttl
+		}
+	}
+	switch {
+	case len(ips) > 0:
+		// A failed query of the other type is retried when this one expires
+		return sortIPv4First(ips), ttl, nil
+	case first.err != nil:
+		return nil, 0, first.err
+	case second.err != nil:
+		return nil, 0, second.err
+	}
+	return nil, min(first.ttl, second.ttl), nil
+}
+
+// query sends one question to server and reads its answer
+func (d *DNSCache) query(ctx context.Context, server string, name dnsmessage.Name, qtype dnsmessage.Type) dnsAnswer {
+	resp, err := exchangeDNS(ctx, server, name, qtype)
+	if err != nil {
+		return dnsAnswer{err: &net.DNSError{Err: err.Error(), Name: name.String(), Server: server, IsTemporary: true}}
+	}
+
+	switch resp.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return dnsAnswer{ttl: d.negativeTTLOf(resp)}
+	default:
+		return dnsAnswer{err: &net.DNSError{Err: "server failure: " + resp.RCode.String(), Name: name.String(), Server: server, IsTemporary: true}}
+	}
+
+	var answer dnsAnswer
+	var ttl uint32
+	for i, record := range resp.Answers {
+		if i == 0 || record.Header.TTL < ttl {
+			ttl = record.Header.TTL
+		}
+		switch body := record.Body.(type) {
+		case *dnsmessage.AResource:
+			answer.ips = append(answer.ips, net.IP(body.A[:]))
+		case *dnsmessage.AAAAResource:
+			answer.ips = append(answer.ips, net.IP(body.AAAA[:]))
+		}
+	}
+	if len(answer.ips) == 0 {
+		return dnsAnswer{ttl: d.negativeTTLOf(resp)}
+	}
+	answer.ttl = d.clampTTL(time.Duration(ttl) * time.Second)
+	return answer
+}
+
+// negativeTTLOf returns how long a negative answer may be cached: the
+// smaller of its SOA record's TTL and minimum, as RFC 2308 specifies
+func (d *DNSCache) negativeTTLOf(resp *dnsmessage.Message) time.Duration {
+	for _, record := range resp.Authorities {
+		if soa, ok := record.Body.(*dnsmessage.SOAResource); ok {
+			return d.clampTTL(time.Duration(min(record.Header.TTL, soa.MinTTL)) * time.Second)
+		}
+	}
+	return d.negativeTTL
+}
+
+// clampTTL bounds a TTL by MinTTL and MaxTTL
+func (d *DNSCache) clampTTL(ttl time.Duration) time.Duration {
+	return max(d.minTTL, min(ttl, d.maxTTL))
+}
+
+// exchangeDNS sends a recursive query to server over UDP, and again over TCP
+// when the answer is truncated
+func exchangeDNS(ctx context.Context, server string, name dnsmessage.Name, qtype dnsmessage.Type) (*dnsmessage.Message, error) {
+	id := uint16(rand.Uint32())
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := exchangeDNSOver(ctx, "udp", server, id, packed)
+	if err == nil && resp.Truncated {
+		resp, err = exchangeDNSOver(ctx, "tcp", server, id, packed)
+	}
+	return resp, err
+}
+
+// exchangeDNSOver sends packed to server over network and returns the
+// response with a matching ID
+func exchangeDNSOver(ctx context.Context, network, server string, id uint16, packed []byte) (*dnsmessage.Message, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, server)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = conn.Close() }()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	msg := packed
+	if network == "tcp" {
+		// TCP messages carry a two-byte length prefix
+		msg = binary.BigEndian.AppendUint16(make([]byte, 0, 2+len(packed)), uint16(len(packed)))
+		msg = append(msg, packed...)
+	}
+	if _, err := conn.Write(msg); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 65535)
+	for {
+		var n int
+		if network == "tcp" {
+			if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+				return nil, err
+			}
+			n = int(binary.BigEndian.Uint16(buf[:2]))
+			if _, err := io.ReadFull(conn, buf[:n]); err != nil {
+				return nil, err
+			}
+		} else if n, err = conn.Read(buf); err != nil {
+			return nil, err
+		}
+
+		var resp dnsmessage.Message
+		if err := resp.Unpack(buf[:n]); err != nil || resp.ID != id || !resp.Response {
+			if network == "tcp" {
+				return nil, fmt.Errorf("malformed DNS response from %s", server)
+			}
+			continue // A late or forged UDP answer; keep waiting for ours
+		}
+		return &resp, nil
+	}
+}
+
+// sortIPv4First orders IPv4 addresses before IPv6 ones, keeping the
+// resolver's order otherwise, as many crawl hosts lack IPv6 routes
+func sortIPv4First(ips []net.IP) []net.IP {
+	sorted := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			sorted = append(sorted, ip)
+		}
+	}
+	for _, ip := range ips {
+		if ip.To4() == nil {
+			sorted = append(sorted, ip)
+		}
+	}
+	return sorted
+}
+
+// notFoundError is the error of a lookup for a host that does not exist
+func notFoundError(host string) error {
+	return &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
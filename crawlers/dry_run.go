@@ -0,0 +1,176 @@
+package crawlers
+
+import (
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+)
+
+// DryRunConfig describes the crawl a dry run simulates
+type DryRunConfig struct {
+	RateLimit   RateLimiterConfig // Per-domain politeness; Clock and Adaptive are ignored
+	Concurrency int               // Requests in flight across all domains; defaults to 1
+
+	URLRules  *urlmatch.RuleSet // Optional
+	Canonical *Canonicalizer    // Optional; URLs are deduplicated after folding
+
+	MaxPages    int           // 0 = unlimited
+	MaxBytes    int64         // 0 = unlimited
+	MaxDuration time.Duration // 0 = unlimited
+
+	Latency  time.Duration // Assumed response time of a request; defaults to 500ms
+	PageSize int64         // Assumed response body size in bytes; defaults to 100 KiB
+}
+
+// DryRunEstimate is what a dry run expects the crawl to cost
+type DryRunEstimate struct {
+	URLs      int           // URLs replayed
+	Requests  int           // URLs that would be fetched
+	Bytes     int64         // Response body bytes downloaded
+	Duration  time.Duration // Time from the first request to the last response
+	Exhausted string        // Budget limit that would stop the crawl; empty if none
+	Skips     SkipSummary   // URLs the filters, deduplication or budget would skip
+
+	Domains map[string]*DomainEstimate // Keyed by registrable domain, as the rate limiter is
+}
+
+// DomainEstimate is the share of one domain in a DryRunEstimate
+type DomainEstimate struct {
+	Requests int
+	Bytes    int64
+	Duration time.Duration // From the domain's first request to its last response
+}
+
+// dryRunDomain is the simulated politeness state of one domain
+type dryRunDomain struct {
+	next  time.Duration   // When the rate limiter hands out its next token
+	slots []time.Duration // When each MaxConcurrent slot frees up
+	first time.Duration
+	last  time.Duration
+}
+
+// DryRun replays urls, in crawl order, through the URL rules,
+// canonicalizer, deduplication, budget and politeness settings of config
+// without sending a request, and estimates the requests, duration and
+// bandwidth of the crawl
+//
+// Each request is assumed to take Latency and download PageSize bytes.
+// Workers take URLs first in, first out and wait for their domain's rate
+// limiter and MaxConcurrent slots as Spider workers do; RandomDelay is
+// counted at its mean, so the same input always gives the same estimate
+func DryRun(urls []string, config DryRunConfig) DryRunEstimate {
+	if config.Concurrency <= 0 {
+		config.Concurrency = 1
+	}
+	if config.Latency <= 0 {
+		config.Latency = 500 * time.Millisecond
+	}
+	if config.PageSize <= 0 {
+		config.PageSize = 100 << 10
+	}
+	config.RateLimit.Clock, config.RateLimit.Adaptive = nil, nil
+	limiter := NewRateLimiter(config.RateLimit)
+	interval := limiter.baseDelay()
+
+	estimate := DryRunEstimate{URLs: len(urls), Domains: make(map[string]*DomainEstimate)}
+	var skips skipLog
+	workers := make([]time.Duration, config.Concurrency) // When each worker is free
+	domains := make(map[string]*dryRunDomain)
+	seen := make(map[string]bool, len(urls))
+
+	for _, rawURL := range urls {
+		if config.Canonical != nil {
+			rawURL = config.Canonical.Canonicalize(rawURL)
+		}
+		if config.URLRules != nil {
+			if decision := config.URLRules.Decide(rawURL); !decision.Allowed {
+				rule := "invalid URL"
+				if decision.Rule != nil {
+					rule = decision.Rule.Action.String() + " " + decision.Rule.Pattern
+				}
+				skips.record(SkipDecision{URL: rawURL, Reason: SkipURLRule, Rule: rule})
+				continue
+			}
+		}
+		if seen[rawURL] {
+			skips.record(SkipDecision{URL: rawURL, Reason: SkipDuplicate, Rule: "visited"})
+			continue
+		}
+		seen[rawURL] = true
+
+		if estimate.Exhausted == "" {
+			switch {
+			case config.MaxPages > 0 && estimate.Requests >= config.MaxPages:
+				estimate.Exhausted = BudgetMaxPages
+			case config.MaxBytes > 0 && estimate.Bytes >= config.MaxBytes:
+				estimate.Exhausted = BudgetMaxBytes
+			}
+		}
+		if estimate.Exhausted != "" {
+			skips.record(SkipDecision{URL: rawURL, Reason: SkipBudget, Rule: estimate.Exhausted})
+			continue
+		}
+
+		// The earliest free worker takes the URL and waits for the domain
+		worker := 0
+		for i, free := range workers {
+			if free < workers[worker] {
+				worker = i
+			}
+		}
+		start := workers[worker]
+
+		name := RateLimitDomain(rawURL)
+		d, ok := domains[name]
+		if !ok {
+			d = &dryRunDomain{first: -1}
+			if limiter.maxConcurrent > 0 {
+				d.slots = make([]time.Duration, limiter.maxConcurrent)
+			}
+			domains[name] = d
+		}
+		slot := -1
+		for i, free := range d.slots {
+			if slot < 0 || free < d.slots[slot] {
+				slot = i
+			}
+		}
+		if slot >= 0 {
+			start = max(start, d.slots[slot])
+		}
+		start = max(start, d.next)
+		d.next = start + interval
+		start += limiter.randomDelay / 2
+
+		if config.MaxDuration > 0 && start >= config.MaxDuration {
+			estimate.Exhausted = BudgetMaxDuration
+			skips.record(SkipDecision{URL: rawURL, Reason: SkipBudget, Rule: estimate.Exhausted})
+			continue
+		}
+
+		end := start + config.Latency
+		workers[worker] = end
+		if slot >= 0 {
+			d.slots[slot] = end
+		}
+		if d.first < 0 {
+			d.first = start
+		}
+		d.last = max(d.last, end)
+
+		estimate.Requests++
+		estimate.Bytes += config.PageSize
+		estimate.Duration = max(estimate.Duration, end)
+		domain := estimate.Domains[name]
+		if domain == nil {
+			domain = &DomainEstimate{}
+			estimate.Domains[name] = domain
+		}
+		domain.Requests++
+		domain.Bytes += config.PageSize
+		domain.Duration = d.last - d.first
+	}
+
+	estimate.Skips = skips.snapshot()
+	return estimate
+}
@@ -0,0 +1,251 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/chromedp/cdproto/browser"
+	cdpemulation "github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/chromedp"
+	"github.com/playwright-community/playwright-go"
+)
+
+// Device is a device a browser page emulates
+type Device struct {
+	UserAgent         string
+	ViewportWidth     int // CSS pixels
+	ViewportHeight    int
+	DeviceScaleFactor float64
+	Mobile            bool   // Honour the meta viewport tag and mobile-only CSS
+	Touch             bool   // Touch events and navigator.maxTouchPoints
+	Platform          string // navigator.platform
+}
+
+// Geolocation is a position reported by navigator.geolocation
+type Geolocation struct {
+	Latitude  float64
+	Longitude float64
+	Accuracy  float64 // Meters
+}
+
+// Region is a locale, time zone and position a browser page emulates
+type Region struct {
+	City        string
+	Locale      string
+	TimezoneID  string
+	Geolocation Geolocation
+}
+
+// Devices are the built-in device presets, selected by EmulationConfig.Device
+// Callers may add their own before creating clients
+var Devices = map[string]Device{
+	"iphone-15": {
+		UserAgent:     "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ViewportWidth: 393, ViewportHeight: 659, DeviceScaleFactor: 3, Mobile: true, Touch: true, Platform: "iPhone",
+	},
+	"iphone-se": {
+		UserAgent:     "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ViewportWidth: 375, ViewportHeight: 548, DeviceScaleFactor: 2, Mobile: true, Touch: true, Platform: "iPhone",
+	},
+	"pixel-7": {
+		UserAgent:     "Mozilla/5.0 (Linux; Android 14; Pixel 7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+		ViewportWidth: 412, ViewportHeight: 839, DeviceScaleFactor: 2.625, Mobile: true, Touch: true, Platform: "Linux armv81",
+	},
+	"galaxy-s23": {
+		UserAgent:     "Mozilla/5.0 (Linux; Android 14; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Mobile Safari/537.36",
+		ViewportWidth: 360, ViewportHeight: 780, DeviceScaleFactor: 3, Mobile: true, Touch: true, Platform: "Linux armv81",
+	},
+	"ipad-air": {
+		UserAgent:     "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1",
+		ViewportWidth: 820, ViewportHeight: 1180, DeviceScaleFactor: 2, Mobile: true, Touch: true, Platform: "iPad",
+	},
+	"galaxy-tab-s9": {
+		UserAgent:     "Mozilla/5.0 (Linux; Android 14; SM-X710) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+		ViewportWidth: 800, ViewportHeight: 1280, DeviceScaleFactor: 2, Mobile: true, Touch: true, Platform: "Linux armv81",
+	},
+	"desktop-windows": {
+		UserAgent:     "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+		ViewportWidth: 1920, ViewportHeight: 1080, DeviceScaleFactor: 1, Platform: "Win32",
+	},
+	"desktop-mac": {
+		UserAgent:     "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36",
+		ViewportWidth: 1440, ViewportHeight: 900, DeviceScaleFactor: 2, Platform: "MacIntel",
+	},
+}
+
+// Regions are the built-in region presets keyed by locale, selected by
+// EmulationConfig.Region. Callers may add their own before creating clients
+var Regions = map[string]Region{
+	"en-US": {City: "New York", Locale: "en-US", TimezoneID: "America/New_York", Geolocation: Geolocation{Latitude: 40.7128, Longitude: -74.0060, Accuracy: 100}},
+	"en-GB": {City: "London", Locale: "en-GB", TimezoneID: "Europe/London", Geolocation: Geolocation{Latitude: 51.5074, Longitude: -0.1278, Accuracy: 100}},
+	"en-AU": {City: "Sydney", Locale: "en-AU", TimezoneID: "Australia/Sydney", Geolocation: Geolocation{Latitude: -33.8688, Longitude: 151.2093, Accuracy: 100}},
+	"en-IN": {City: "Mumbai", Locale: "en-IN", TimezoneID: "Asia/Kolkata", Geolocation: Geolocation{Latitude: 19.0760, Longitude: 72.8777, Accuracy: 100}},
+	"de-DE": {City: "Berlin", Locale: "de-DE", TimezoneID: "Europe/Berlin", Geolocation: Geolocation{Latitude: 52.5200, Longitude: 13.4050, Accuracy: 100}},
+	"fr-FR": {City: "Paris", Locale: "fr-FR", TimezoneID: "Europe/Paris", Geolocation: Geolocation{Latitude: 48.8566, Longitude: 2.3522, Accuracy: 100}},
+	"es-ES": {City: "Madrid", Locale: "es-ES", TimezoneID: "Europe/Madrid", Geolocation: Geolocation{Latitude: 40.4168, Longitude: -3.7038, Accuracy: 100}},
+	"it-IT": {City: "Rome", Locale: "it-IT", TimezoneID: "Europe/Rome", Geolocation: Geolocation{Latitude: 41.9028, Longitude: 12.4964, Accuracy: 100}},
+	"pt-BR": {City: "São Paulo", Locale: "pt-BR", TimezoneID: "America/Sao_Paulo", Geolocation: Geolocation{Latitude: -23.5505, Longitude: -46.6333, Accuracy: 100}},
+	"ja-JP": {City: "Tokyo", Locale: "ja-JP", TimezoneID: "Asia/Tokyo", Geolocation: Geolocation{Latitude: 35.6762, Longitude: 139.6503, Accuracy: 100}},
+	"ko-KR": {City: "Seoul", Locale: "ko-KR", TimezoneID: "Asia/Seoul", Geolocation: Geolocation{Latitude: 37.5665, Longitude: 126.9780, Accuracy: 100}},
+	"zh-CN": {City: "Shanghai", Locale: "zh-CN", TimezoneID: "Asia/Shanghai", Geolocation: Geolocation{Latitude: 31.2304, Longitude: 121.4737, Accuracy: 100}},
+	"vi-VN": {City: "Ho Chi Minh City", Locale: "vi-VN", TimezoneID: "Asia/Ho_Chi_Minh", Geolocation: Geolocation{Latitude: 10.8231, Longitude: 106.6297, Accuracy: 100}},
+}
+
+// EmulationConfig selects device and region presets by name, so mobile or
+// localized versions of sites can be crawled without hand-written settings
+// Overrides set in StealthConfig take precedence over the presets
+type EmulationConfig struct {
+	Device string // Key of Devices, e.g. iphone-15
+	Region string // Key of Regions, e.g. de-DE
+}
+
+// emulation is an EmulationConfig with its presets looked up
+type emulation struct {
+	device *Device
+	region *Region
+}
+
+// resolve looks up the presets of the config
+func (e EmulationConfig) resolve() (emulation, error) {
+	var resolved emulation
+	if e.Device != "" {
+		device, ok := Devices[e.Device]
+		if !ok {
+			return resolved, errs.Newf(errs.CodeInvalidConfig, "unknown device preset %q (known: %v)", e.Device, presetNames(Devices))
+		}
+		resolved.device = &device
+	}
+	if e.Region != "" {
+		region, ok := Regions[e.Region]
+		if !ok {
+			return resolved, errs.Newf(errs.CodeInvalidConfig, "unknown region preset %q (known: %v)", e.Region, presetNames(Regions))
+		}
+		resolved.region = &region
+	}
+	return resolved, nil
+}
+
+// presetNames lists the keys of a preset map in order
+func presetNames[T any](presets map[string]T) []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// stealth fills the overrides s leaves unset from the presets
+func (e emulation) stealth(s StealthConfig) StealthConfig {
+	if d := e.device; d != nil {
+		if s.UserAgent == "" {
+			s.UserAgent = d.UserAgent
+		}
+		if s.ViewportWidth == 0 || s.ViewportHeight == 0 {
+			s.ViewportWidth, s.ViewportHeight = d.ViewportWidth, d.ViewportHeight
+		}
+		if s.Platform == "" {
+			s.Platform = d.Platform
+		}
+	}
+	if r := e.region; r != nil {
+		if s.Locale == "" {
+			s.Locale = r.Locale
+		}
+		if s.TimezoneID == "" {
+			s.TimezoneID = r.TimezoneID
+		}
+	}
+	return s
+}
+
+// pageOptions applies what StealthConfig cannot express: scale, mobile and
+// touch input, and the geolocation with permission to read it. Firefox
+// cannot emulate mobile viewports, so Mobile is ignored there
+func (e emulation) pageOptions(opts *playwright.BrowserNewPageOptions, browserType string) {
+	if d := e.device; d != nil {
+		if d.DeviceScaleFactor > 0 {
+			opts.DeviceScaleFactor = playwright.Float(d.DeviceScaleFactor)
+		}
+		if browserType != "firefox" {
+			opts.IsMobile = playwright.Bool(d.Mobile)
+		}
+		opts.HasTouch = playwright.Bool(d.Touch)
+	}
+	if r := e.region; r != nil {
+		opts.Geolocation = &playwright.Geolocation{
+			Latitude:  r.Geolocation.Latitude,
+			Longitude: r.Geolocation.Longitude,
+			Accuracy:  playwright.Float(r.Geolocation.Accuracy),
+		}
+		opts.Permissions = append(opts.Permissions, "geolocation")
+	}
+}
+
+// contextOptions applies pageOptions to options of a new browser context
+func (e emulation) contextOptions(opts *playwright.BrowserNewContextOptions, browserType string) {
+	var page playwright.BrowserNewPageOptions
+	e.pageOptions(&page, browserType)
+	opts.DeviceScaleFactor = page.DeviceScaleFactor
+	opts.IsMobile = page.IsMobile
+	opts.HasTouch = page.HasTouch
+	opts.Geolocation = page.Geolocation
+	opts.Permissions = append(opts.Permissions, page.Permissions...)
+}
+
+// apply emulates the device and region in the current chromedp target
+// This starts the browser
+func (e emulation) apply(ctx context.Context) error {
+	var actions []chromedp.Action
+	if d := e.device; d != nil {
+		override := cdpemulation.SetUserAgentOverride(d.UserAgent).WithPlatform(d.Platform)
+		if e.region != nil {
+			override = override.WithAcceptLanguage(acceptLanguage(e.region.Locale))
+		}
+		actions = append(actions,
+			override,
+			cdpemulation.SetDeviceMetricsOverride(int64(d.ViewportWidth), int64(d.ViewportHeight), d.DeviceScaleFactor, d.Mobile),
+			cdpemulation.SetTouchEmulationEnabled(d.Touch),
+		)
+	}
+	if r := e.region; r != nil {
+		if e.device == nil {
+			// The user agent is kept; only the languages change
+			actions = append(actions, chromedp.ActionFunc(func(ctx context.Context) error {
+				_, _, _, userAgent, _, err := browser.GetVersion().Do(ctx)
+				if err != nil {
+					return err
+				}
+				return cdpemulation.SetUserAgentOverride(userAgent).WithAcceptLanguage(acceptLanguage(r.Locale)).Do(ctx)
+			}))
+		}
+		actions = append(actions,
+			cdpemulation.SetLocaleOverride().WithLocale(r.Locale),
+			cdpemulation.SetTimezoneOverride(r.TimezoneID),
+			cdpemulation.SetGeolocationOverride().
+				WithLatitude(r.Geolocation.Latitude).
+				WithLongitude(r.Geolocation.Longitude).
+				WithAccuracy(r.Geolocation.Accuracy),
+			browser.GrantPermissions([]browser.PermissionType{browser.PermissionTypeGeolocation}),
+		)
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	if err := chromedp.Run(ctx, actions...); err != nil {
+		return fmt.Errorf("failed to apply emulation: %w", err)
+	}
+	return nil
+}
+
+// acceptLanguage returns the Accept-Language header of a locale, e.g.
+// "de-DE,de;q=0.9"
+func acceptLanguage(locale string) string {
+	languages := localeLanguages(locale)
+	if len(languages) < 2 {
+		return locale
+	}
+	return languages[0] + "," + languages[1] + ";q=0.9"
+}
@@ -0,0 +1,104 @@
+package frontier
+
+import (
+	"context"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// Frontier is a crawl queue that can be shared by several workers
+// A claimed URL is leased for a visibility timeout; if the lease is not
+// acked, retried or failed in time the URL is handed out again
+type Frontier interface {
+	// Push enqueues URLs that have not been seen before and returns how many were added
+	Push(ctx context.Context, urls ...string) (int, error)
+	// Claim leases the next URL; it returns ErrEmpty when nothing is pending
+	Claim(ctx context.Context) (*Lease, error)
+	// Extend renews a lease for another visibility timeout
+	Extend(ctx context.Context, lease *Lease) error
+	// Ack marks a leased URL as done
+	Ack(ctx context.Context, lease *Lease) error
+	// Retry returns a leased URL to the queue, or dead-letters it once retries are exhausted
+	Retry(ctx context.Context, lease *Lease) error
+	// Fail dead-letters a leased URL without retrying
+	Fail(ctx context.Context, lease *Lease) error
+	// Stats returns queue sizes
+	Stats(ctx context.Context) (Stats, error)
+}
+
+// Inspector lets operators look into a frontier and repair it
+type Inspector interface {
+	// Peek returns up to limit entries of a queue in order; limit <= 0
+	// returns all of them. In-flight entries are ordered by lease deadline
+	Peek(ctx context.Context, queue Queue, limit int) ([]Entry, error)
+	// Requeue moves dead-lettered URLs back to pending with a fresh retry
+	// budget and returns how many were moved; no URLs requeues all of them
+	Requeue(ctx context.Context, urls ...string) (int, error)
+	// Purge drops every URL in a queue and returns how many were dropped
+	// Purged URLs stay seen, so pushing them again is a no-op
+	Purge(ctx context.Context, queue Queue) (int, error)
+}
+
+// Queue names one of the frontier's queues
+type Queue string
+
+// Frontier queues
+const (
+	QueuePending  Queue = "pending"
+	QueueInFlight Queue = "inflight"
+	QueueDead     Queue = "dead"
+)
+
+// ParseQueue returns the queue with the given name
+func ParseQueue(name string) (Queue, error) {
+	switch q := Queue(name); q {
+	case QueuePending, QueueInFlight, QueueDead:
+		return q, nil
+	}
+	return "", errs.Newf(errs.CodeInvalidRequest, "unknown frontier queue %q: use pending, inflight or dead", name)
+}
+
+// Entry is a URL in one of the frontier's queues
+type Entry struct {
+	URL      string
+	Attempts int       // Claims so far; reset when a URL is dead-lettered
+	Deadline time.Time // Lease deadline; zero unless in flight
+}
+
+// Lease is a claimed URL
+type Lease struct {
+	URL      string
+	Attempts int    // Claims so far, including this one
+	Token    string // Identifies this claim; stale tokens are rejected
+	Deadline time.Time
+}
+
+// Stats holds frontier queue sizes
+type Stats struct {
+	Pending  int64
+	InFlight int64
+	Dead     int64
+	Seen     int64
+}
+
+// Idle reports whether no work is pending or in flight
+func (s Stats) Idle() bool {
+	return s.Pending == 0 && s.InFlight == 0
+}
+
+var (
+	// ErrEmpty is returned by Claim when no URL is pending
+	ErrEmpty = errs.New(errs.CodeQueueEmpty, "frontier is empty")
+	// ErrLeaseLost is returned when a lease expired and was claimed again
+	ErrLeaseLost = errs.New(errs.CodeLeaseLost, "frontier lease lost")
+)
+
+// Ensure all frontiers implement the interface
+var (
+	_ Frontier = (*RedisFrontier)(nil)
+	_ Frontier = (*MemoryFrontier)(nil)
+
+	_ Inspector = (*RedisFrontier)(nil)
+	_ Inspector = (*MemoryFrontier)(nil)
+)
@@ -0,0 +1,305 @@
+package frontier
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// MemoryConfig holds in-process frontier settings
+type MemoryConfig struct {
+	VisibilityTimeout time.Duration // Lease duration (default 5m)
+	MaxRetries        int           // Claims before a URL is dead-lettered (default 3)
+	Clock             clock.Clock   // Lease deadlines; defaults to the wall clock
+
+	// Key maps a URL to the identity duplicates share, e.g. a normalized
+	// URL; defaults to the URL itself
+	Key func(url string) string
+}
+
+// memoryLease is the in-flight state of a URL
+type memoryLease struct {
+	token    string
+	deadline time.Time
+}
+
+// MemoryFrontier is a Frontier for a single process
+// It has the same semantics as RedisFrontier and is useful for tests and
+// local runs
+type MemoryFrontier struct {
+	visibility time.Duration
+	maxRetries int
+	clock      clock.Clock
+	key        func(string) string
+
+	mu       sync.Mutex
+	pending  []string
+	inFlight map[string]memoryLease
+	attempts map[string]int
+	seen     map[string]bool // Keyed by key(url)
+	dead     []string
+	tokens   uint64
+}
+
+// NewMemoryFrontier creates a new in-process frontier
+func NewMemoryFrontier(config MemoryConfig) *MemoryFrontier {
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = 5 * time.Minute
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+	if config.Key == nil {
+		config.Key = func(url string) string { return url }
+	}
+
+	return &MemoryFrontier{
+		visibility: config.VisibilityTimeout,
+		maxRetries: config.MaxRetries,
+		clock:      clock.Or(config.Clock),
+		key:        config.Key,
+		inFlight:   make(map[string]memoryLease),
+		attempts:   make(map[string]int),
+		seen:       make(map[string]bool),
+	}
+}
+
+// Push implements Frontier
+func (m *MemoryFrontier) Push(_ context.Context, urls ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	added := 0
+	for _, u := range urls {
+		key := m.key(u)
+		if m.seen[key] {
+			continue
+		}
+		m.seen[key] = true
+		m.pending = append(m.pending, u)
+		added++
+	}
+	return added, nil
+}
+
+// Claim implements Frontier
+func (m *MemoryFrontier) Claim(_ context.Context) (*Lease, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	m.requeueExpiredLocked(now)
+	if len(m.pending) == 0 {
+		return nil, ErrEmpty
+	}
+
+	u := m.pending[0]
+	m.pending = m.pending[1:]
+	m.tokens++
+	lease := &Lease{
+		URL:      u,
+		Attempts: m.attempts[u] + 1,
+		Token:    strconv.FormatUint(m.tokens, 10),
+		Deadline: now.Add(m.visibility),
+	}
+	m.attempts[u] = lease.Attempts
+	m.inFlight[u] = memoryLease{token: lease.Token, deadline: lease.Deadline}
+	return lease, nil
+}
+
+// requeueExpiredLocked returns URLs with expired leases to the queue
+func (m *MemoryFrontier) requeueExpiredLocked(now time.Time) {
+	for u, l := range m.inFlight {
+		if now.Before(l.deadline) {
+			continue
+		}
+		delete(m.inFlight, u)
+		m.retryLocked(u)
+	}
+}
+
+// retryLocked requeues u or dead-letters it once retries are exhausted
+func (m *MemoryFrontier) retryLocked(u string) {
+	if m.attempts[u] >= m.maxRetries {
+		delete(m.attempts, u)
+		m.dead = append(m.dead, u)
+		return
+	}
+	m.pending = append(m.pending, u)
+}
+
+// releaseLocked removes a lease if its token is current
+func (m *MemoryFrontier) releaseLocked(lease *Lease) error {
+	l, ok := m.inFlight[lease.URL]
+	if !ok || l.token != lease.Token {
+		return ErrLeaseLost
+	}
+	delete(m.inFlight, lease.URL)
+	return nil
+}
+
+// Extend implements Frontier
+func (m *MemoryFrontier) Extend(_ context.Context, lease *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l, ok := m.inFlight[lease.URL]
+	if !ok || l.token != lease.Token {
+		return ErrLeaseLost
+	}
+	l.deadline = m.clock.Now().Add(m.visibility)
+	m.inFlight[lease.URL] = l
+	lease.Deadline = l.deadline
+	return nil
+}
+
+// Ack implements Frontier
+func (m *MemoryFrontier) Ack(_ context.Context, lease *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.releaseLocked(lease); err != nil {
+		return err
+	}
+	delete(m.attempts, lease.URL)
+	return nil
+}
+
+// Retry implements Frontier
+func (m *MemoryFrontier) Retry(_ context.Context, lease *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.releaseLocked(lease); err != nil {
+		return err
+	}
+	m.retryLocked(lease.URL)
+	return nil
+}
+
+// Fail implements Frontier
+func (m *MemoryFrontier) Fail(_ context.Context, lease *Lease) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.releaseLocked(lease); err != nil {
+		return err
+	}
+	delete(m.attempts, lease.URL)
+	m.dead = append(m.dead, lease.URL)
+	return nil
+}
+
+// Stats implements Frontier
+func (m *MemoryFrontier) Stats(_ context.Context) (Stats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requeueExpiredLocked(m.clock.Now())
+	return Stats{
+		Pending:  int64(len(m.pending)),
+		InFlight: int64(len(m.inFlight)),
+		Dead:     int64(len(m.dead)),
+		Seen:     int64(len(m.seen)),
+	}, nil
+}
+
+// Dead returns the dead-lettered URLs
+func (m *MemoryFrontier) Dead(_ context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.dead...), nil
+}
+
+// Peek implements Inspector
+func (m *MemoryFrontier) Peek(_ context.Context, queue Queue, limit int) ([]Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var entries []Entry
+	switch queue {
+	case QueuePending:
+		for _, u := range m.pending {
+			entries = append(entries, Entry{URL: u, Attempts: m.attempts[u]})
+		}
+	case QueueInFlight:
+		for u, l := range m.inFlight {
+			entries = append(entries, Entry{URL: u, Attempts: m.attempts[u], Deadline: l.deadline})
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			if !entries[i].Deadline.Equal(entries[j].Deadline) {
+				return entries[i].Deadline.Before(entries[j].Deadline)
+			}
+			return entries[i].URL < entries[j].URL
+		})
+	case QueueDead:
+		for _, u := range m.dead {
+			entries = append(entries, Entry{URL: u})
+		}
+	default:
+		return nil, errs.Newf(errs.CodeInvalidRequest, "unknown frontier queue %q", queue)
+	}
+
+	if limit > 0 && len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return entries, nil
+}
+
+// Requeue implements Inspector
+func (m *MemoryFrontier) Requeue(_ context.Context, urls ...string) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	want := make(map[string]bool, len(urls))
+	for _, u := range urls {
+		want[u] = true
+	}
+
+	moved := 0
+	dead := m.dead[:0]
+	for _, u := range m.dead {
+		if len(urls) > 0 && !want[u] {
+			dead = append(dead, u)
+			continue
+		}
+		delete(m.attempts, u)
+		m.pending = append(m.pending, u)
+		moved++
+	}
+	m.dead = dead
+	return moved, nil
+}
+
+// Purge implements Inspector
+func (m *MemoryFrontier) Purge(_ context.Context, queue Queue) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var purged int
+	switch queue {
+	case QueuePending:
+		purged = len(m.pending)
+		for _, u := range m.pending {
+			delete(m.attempts, u)
+		}
+		m.pending = nil
+	case QueueInFlight:
+		purged = len(m.inFlight)
+		for u := range m.inFlight {
+			delete(m.attempts, u)
+		}
+		m.inFlight = make(map[string]memoryLease)
+	case QueueDead:
+		purged = len(m.dead)
+		m.dead = nil
+	default:
+		return 0, errs.Newf(errs.CodeInvalidRequest, "unknown frontier queue %q", queue)
+	}
+	return purged, nil
+}
@@ -0,0 +1,416 @@
+package frontier
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisConfig holds Redis frontier settings
+type RedisConfig struct {
+	Client            redis.UniversalClient // Required; e.g. cache.RedisClient.GetClient()
+	Name              string                // Queue name shared by cooperating workers (default "default")
+	Prefix            string                // Key prefix (default "golwarc:frontier")
+	VisibilityTimeout time.Duration         // Lease duration (default 5m)
+	MaxRetries        int                   // Claims before a URL is dead-lettered (default 3)
+
+	// Key maps a URL to the identity duplicates share, e.g. a normalized
+	// URL; defaults to the URL itself. Workers sharing a queue must agree on it
+	Key func(url string) string
+}
+
+// RedisFrontier is a Frontier shared through Redis
+// Every state change runs in a Lua script so concurrent workers in
+// different processes claim each URL exactly once per lease
+//
+// Keys (all in one cluster hash slot):
+//
+//	pending  list of queued URLs
+//	inflight sorted set of leased URLs scored by lease deadline (unix ms)
+//	leases   hash of URL -> current lease token
+//	attempts hash of URL -> claim count
+//	dead     list of dead-lettered URLs
+//	seen     set of the keys of every URL ever pushed
+type RedisFrontier struct {
+	client     redis.UniversalClient
+	visibility time.Duration
+	maxRetries int
+	keys       []string // pending, inflight, leases, attempts, dead
+	seenKey    string
+	key        func(string) string
+}
+
+// Indexes into RedisFrontier.keys
+const (
+	keyPending = iota
+	keyInFlight
+	keyLeases
+	keyAttempts
+	keyDead
+)
+
+// NewRedisFrontier creates a new Redis-backed frontier
+func NewRedisFrontier(config RedisConfig) (*RedisFrontier, error) {
+	if config.Client == nil {
+		return nil, fmt.Errorf("redis client is required")
+	}
+	if config.Name == "" {
+		config.Name = "default"
+	}
+	if config.Prefix == "" {
+		config.Prefix = "golwarc:frontier"
+	}
+	if config.VisibilityTimeout <= 0 {
+		config.VisibilityTimeout = 5 * time.Minute
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+	if config.Key == nil {
+		config.Key = func(url string) string { return url }
+	}
+
+	base := fmt.Sprintf("%s:{%s}:", config.Prefix, config.Name)
+	return &RedisFrontier{
+		client:     config.Client,
+		visibility: config.VisibilityTimeout,
+		maxRetries: config.MaxRetries,
+		keys: []string{
+			base + "pending",
+			base + "inflight",
+			base + "leases",
+			base + "attempts",
+			base + "dead",
+		},
+		seenKey: base + "seen",
+		key:     config.Key,
+	}, nil
+}
+
+// pushScript enqueues unseen URLs
+// KEYS: seen, pending; ARGV: key, url pairs
+var pushScript = redis.NewScript(`
+local added = 0
+for i = 1, #ARGV, 2 do
+  if redis.call('SADD', KEYS[1], ARGV[i]) == 1 then
+    redis.call('RPUSH', KEYS[2], ARGV[i + 1])
+    added = added + 1
+  end
+end
+return added
+`)
+
+// claimScript requeues expired leases, then leases the next pending URL
+// KEYS: pending, inflight, leases, attempts, dead
+// ARGV: now (ms), visibility (ms), max retries, token
+var claimScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local maxRetries = tonumber(ARGV[3])
+for _, u in ipairs(redis.call('ZRANGEBYSCORE', KEYS[2], '-inf', now)) do
+  redis.call('ZREM', KEYS[2], u)
+  redis.call('HDEL', KEYS[3], u)
+  if tonumber(redis.call('HGET', KEYS[4], u) or '0') >= maxRetries then
+    redis.call('HDEL', KEYS[4], u)
+    redis.call('RPUSH', KEYS[5], u)
+  else
+    redis.call('RPUSH', KEYS[1], u)
+  end
+end
+
+local u = redis.call('LPOP', KEYS[1])
+if not u then
+  return false
+end
+local deadline = now + tonumber(ARGV[2])
+redis.call('ZADD', KEYS[2], deadline, u)
+redis.call('HSET', KEYS[3], u, ARGV[4])
+local attempts = redis.call('HINCRBY', KEYS[4], u, 1)
+return {u, attempts, deadline}
+`)
+
+// releaseScript ends a lease
+// KEYS: pending, inflight, leases, attempts, dead
+// ARGV: url, token, mode (ack, retry, fail), max retries
+var releaseScript = redis.NewScript(`
+local u = ARGV[1]
+if redis.call('HGET', KEYS[3], u) ~= ARGV[2] then
+  return 0
+end
+redis.call('ZREM', KEYS[2], u)
+redis.call('HDEL', KEYS[3], u)
+if ARGV[3] == 'ack' then
+  redis.call('HDEL', KEYS[4], u)
+elseif ARGV[3] == 'fail' or tonumber(redis.call('HGET', KEYS[4], u) or '0') >= tonumber(ARGV[4]) then
+  redis.call('HDEL', KEYS[4], u)
+  redis.call('RPUSH', KEYS[5], u)
+else
+  redis.call('RPUSH', KEYS[1], u)
+end
+return 1
+`)
+
+// extendScript moves a lease deadline
+// KEYS: inflight, leases; ARGV: url, token, deadline (ms)
+var extendScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], ARGV[1]) ~= ARGV[2] then
+  return 0
+end
+redis.call('ZADD', KEYS[1], 'XX', ARGV[3], ARGV[1])
+return 1
+`)
+
+// requeueScript moves dead-lettered URLs back to pending; no URLs moves all
+// KEYS: dead, pending, attempts; ARGV: urls
+var requeueScript = redis.NewScript(`
+local urls = ARGV
+if #urls == 0 then
+  urls = redis.call('LRANGE', KEYS[1], 0, -1)
+end
+local moved = 0
+for _, u in ipairs(urls) do
+  if redis.call('LREM', KEYS[1], 0, u) > 0 then
+    redis.call('HDEL', KEYS[3], u)
+    redis.call('RPUSH', KEYS[2], u)
+    moved = moved + 1
+  end
+end
+return moved
+`)
+
+// purgeScript empties a queue
+// KEYS: pending, inflight, leases, attempts, dead; ARGV: queue
+var purgeScript = redis.NewScript(`
+local urls
+if ARGV[1] == 'pending' then
+  urls = redis.call('LRANGE', KEYS[1], 0, -1)
+  redis.call('DEL', KEYS[1])
+elseif ARGV[1] == 'inflight' then
+  urls = redis.call('ZRANGE', KEYS[2], 0, -1)
+  redis.call('DEL', KEYS[2])
+  for _, u in ipairs(urls) do
+    redis.call('HDEL', KEYS[3], u)
+  end
+else
+  local n = redis.call('LLEN', KEYS[5])
+  redis.call('DEL', KEYS[5])
+  return n
+end
+for _, u in ipairs(urls) do
+  redis.call('HDEL', KEYS[4], u)
+end
+return #urls
+`)
+
+// Push implements Frontier
+func (f *RedisFrontier) Push(ctx context.Context, urls ...string) (int, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	args := make([]interface{}, 0, 2*len(urls))
+	for _, u := range urls {
+		args = append(args, f.key(u), u)
+	}
+	added, err := pushScript.Run(ctx, f.client, []string{f.seenKey, f.keys[keyPending]}, args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to push URLs: %w", err)
+	}
+	return added, nil
+}
+
+// Claim implements Frontier
+func (f *RedisFrontier) Claim(ctx context.Context) (*Lease, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := claimScript.Run(ctx, f.client, f.keys,
+		time.Now().UnixMilli(), f.visibility.Milliseconds(), f.maxRetries, token).Slice()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrEmpty
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim URL: %w", err)
+	}
+	if len(res) != 3 {
+		return nil, fmt.Errorf("unexpected claim reply: %v", res)
+	}
+
+	u, _ := res[0].(string)
+	attempts, _ := res[1].(int64)
+	deadline, _ := res[2].(int64)
+	return &Lease{
+		URL:      u,
+		Attempts: int(attempts),
+		Token:    token,
+		Deadline: time.UnixMilli(deadline),
+	}, nil
+}
+
+// Extend implements Frontier
+func (f *RedisFrontier) Extend(ctx context.Context, lease *Lease) error {
+	deadline := time.Now().Add(f.visibility)
+	ok, err := extendScript.Run(ctx, f.client, []string{f.keys[keyInFlight], f.keys[keyLeases]},
+		lease.URL, lease.Token, deadline.UnixMilli()).Int()
+	if err != nil {
+		return fmt.Errorf("failed to extend lease: %w", err)
+	}
+	if ok == 0 {
+		return ErrLeaseLost
+	}
+	lease.Deadline = deadline
+	return nil
+}
+
+// Ack implements Frontier
+func (f *RedisFrontier) Ack(ctx context.Context, lease *Lease) error {
+	return f.release(ctx, lease, "ack")
+}
+
+// Retry implements Frontier
+func (f *RedisFrontier) Retry(ctx context.Context, lease *Lease) error {
+	return f.release(ctx, lease, "retry")
+}
+
+// Fail implements Frontier
+func (f *RedisFrontier) Fail(ctx context.Context, lease *Lease) error {
+	return f.release(ctx, lease, "fail")
+}
+
+// release ends a lease with the given mode
+func (f *RedisFrontier) release(ctx context.Context, lease *Lease, mode string) error {
+	ok, err := releaseScript.Run(ctx, f.client, f.keys, lease.URL, lease.Token, mode, f.maxRetries).Int()
+	if err != nil {
+		return fmt.Errorf("failed to %s URL: %w", mode, err)
+	}
+	if ok == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Stats implements Frontier
+// Expired leases count as in flight until the next Claim requeues them
+func (f *RedisFrontier) Stats(ctx context.Context) (Stats, error) {
+	pipe := f.client.Pipeline()
+	pending := pipe.LLen(ctx, f.keys[keyPending])
+	inFlight := pipe.ZCard(ctx, f.keys[keyInFlight])
+	dead := pipe.LLen(ctx, f.keys[keyDead])
+	seen := pipe.SCard(ctx, f.seenKey)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Stats{}, fmt.Errorf("failed to read frontier stats: %w", err)
+	}
+
+	return Stats{
+		Pending:  pending.Val(),
+		InFlight: inFlight.Val(),
+		Dead:     dead.Val(),
+		Seen:     seen.Val(),
+	}, nil
+}
+
+// Dead returns the dead-lettered URLs
+func (f *RedisFrontier) Dead(ctx context.Context) ([]string, error) {
+	return f.client.LRange(ctx, f.keys[keyDead], 0, -1).Result()
+}
+
+// Peek implements Inspector
+func (f *RedisFrontier) Peek(ctx context.Context, queue Queue, limit int) ([]Entry, error) {
+	stop := int64(limit) - 1
+	if limit <= 0 {
+		stop = -1
+	}
+
+	var entries []Entry
+	switch queue {
+	case QueuePending, QueueDead:
+		key := f.keys[keyPending]
+		if queue == QueueDead {
+			key = f.keys[keyDead]
+		}
+		urls, err := f.client.LRange(ctx, key, 0, stop).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to peek %s queue: %w", queue, err)
+		}
+		for _, u := range urls {
+			entries = append(entries, Entry{URL: u})
+		}
+	case QueueInFlight:
+		leases, err := f.client.ZRangeWithScores(ctx, f.keys[keyInFlight], 0, stop).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to peek %s queue: %w", queue, err)
+		}
+		for _, l := range leases {
+			u, _ := l.Member.(string)
+			entries = append(entries, Entry{URL: u, Deadline: time.UnixMilli(int64(l.Score))})
+		}
+	default:
+		return nil, errs.Newf(errs.CodeInvalidRequest, "unknown frontier queue %q", queue)
+	}
+	if len(entries) == 0 || queue == QueueDead {
+		return entries, nil
+	}
+
+	urls := make([]string, len(entries))
+	for i := range entries {
+		urls[i] = entries[i].URL
+	}
+	attempts, err := f.client.HMGet(ctx, f.keys[keyAttempts], urls...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attempts: %w", err)
+	}
+	for i, a := range attempts {
+		if s, ok := a.(string); ok {
+			entries[i].Attempts, _ = strconv.Atoi(s)
+		}
+	}
+	return entries, nil
+}
+
+// Requeue implements Inspector
+func (f *RedisFrontier) Requeue(ctx context.Context, urls ...string) (int, error) {
+	args := make([]interface{}, len(urls))
+	for i, u := range urls {
+		args[i] = u
+	}
+	moved, err := requeueScript.Run(ctx, f.client,
+		[]string{f.keys[keyDead], f.keys[keyPending], f.keys[keyAttempts]}, args...).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue URLs: %w", err)
+	}
+	return moved, nil
+}
+
+// Purge implements Inspector
+func (f *RedisFrontier) Purge(ctx context.Context, queue Queue) (int, error) {
+	if _, err := ParseQueue(string(queue)); err != nil {
+		return 0, err
+	}
+	purged, err := purgeScript.Run(ctx, f.client, f.keys, string(queue)).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge %s queue: %w", queue, err)
+	}
+	return purged, nil
+}
+
+// Reset deletes every key of the frontier
+func (f *RedisFrontier) Reset(ctx context.Context) error {
+	return f.client.Del(ctx, append([]string{f.seenKey}, f.keys...)...).Err()
+}
+
+// newToken returns a random lease token
+func newToken() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lease token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,284 @@
+package crawlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// HAR is an HTTP Archive 1.2 document
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the tool that recorded a HAR
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is one request and its response
+// Error is set for requests that failed, e.g. net::ERR_ABORTED for blocked
+// requests; such entries have a zero status
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"` // Total milliseconds
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ResourceType    string      `json:"_resourceType,omitempty"`
+	Error           string      `json:"_error,omitempty"`
+}
+
+// HARRequest is the request of a HAR entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"` // -1 when unknown
+	BodySize    int            `json:"bodySize"`    // -1 when unknown
+}
+
+// HARResponse is the response of a HAR entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"` // -1 when unknown
+	BodySize    int            `json:"bodySize"`    // -1 when unknown
+}
+
+// HARNameValue is a header, cookie or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent describes a response body; bodies themselves are not recorded
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+}
+
+// HARTimings splits the time of an entry into phases, in milliseconds
+// Optional phases are -1 when they did not happen, e.g. reused connections
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARRecorder records the network traffic of browser pages
+// Requests are recorded once they finish or fail, so a HAR taken while a
+// page is loading lists only completed requests
+type HARRecorder struct {
+	mu      sync.Mutex
+	entries []HAREntry
+	pending sync.WaitGroup
+}
+
+// NewHARRecorder creates an empty HAR recorder
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{}
+}
+
+// Attach records every request page makes from now on
+func (r *HARRecorder) Attach(page playwright.Page) {
+	// Events are delivered on Playwright's dispatch goroutine, which must not
+	// block on the driver, so requests are inspected on their own goroutines
+	handle := func(req playwright.Request) {
+		r.pending.Add(1)
+		go r.record(req)
+	}
+	page.OnRequestFinished(handle)
+	page.OnRequestFailed(handle)
+}
+
+// record turns a settled request into a HAR entry
+func (r *HARRecorder) record(req playwright.Request) {
+	defer r.pending.Done()
+
+	entry := HAREntry{
+		Request: HARRequest{
+			Method:      req.Method(),
+			URL:         req.URL(),
+			Cookies:     []HARNameValue{},
+			Headers:     harHeaders(req.HeadersArray()),
+			QueryString: harQuery(req.URL()),
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		Response: HARResponse{
+			Cookies:     []HARNameValue{},
+			Headers:     []HARNameValue{},
+			HeadersSize: -1,
+			BodySize:    -1,
+		},
+		ResourceType: req.ResourceType(),
+	}
+	if timing := req.Timing(); timing != nil {
+		entry.StartedDateTime = time.UnixMilli(int64(timing.StartTime))
+		entry.Timings, entry.Time = harTimings(timing)
+	}
+	if body, err := req.PostData(); err == nil && body != "" {
+		mimeType, _ := req.HeaderValue("content-type")
+		entry.Request.PostData = &HARPostData{MimeType: mimeType, Text: body}
+	}
+	if failure := req.Failure(); failure != nil {
+		entry.Error = failure.Error()
+	}
+
+	if resp, err := req.Response(); err == nil && resp != nil {
+		entry.Response.Status = resp.Status()
+		entry.Response.StatusText = resp.StatusText()
+		entry.Response.Headers = harHeaders(resp.HeadersArray())
+		entry.Response.Content.MimeType, _ = resp.HeaderValue("content-type")
+		entry.Response.RedirectURL, _ = resp.HeaderValue("location")
+		if sizes, err := req.Sizes(); err == nil {
+			entry.Request.HeadersSize = sizes.RequestHeadersSize
+			entry.Request.BodySize = sizes.RequestBodySize
+			entry.Response.HeadersSize = sizes.ResponseHeadersSize
+			entry.Response.BodySize = sizes.ResponseBodySize
+			entry.Response.Content.Size = sizes.ResponseBodySize
+		}
+	}
+
+	r.mu.Lock()
+	r.entries = append(r.entries, entry)
+	r.mu.Unlock()
+}
+
+// harHeaders converts Playwright headers, keeping repeated headers
+func harHeaders(headers []playwright.NameValue, err error) []HARNameValue {
+	values := []HARNameValue{}
+	if err != nil {
+		return values
+	}
+	for _, h := range headers {
+		values = append(values, HARNameValue{Name: h.Name, Value: h.Value})
+	}
+	return values
+}
+
+// harQuery lists the query parameters of a URL in order
+func harQuery(rawURL string) []HARNameValue {
+	values := []HARNameValue{}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return values
+	}
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range query[name] {
+			values = append(values, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return values
+}
+
+// harTimings converts Playwright's resource timing, whose marks are relative
+// to the request start and -1 when unavailable, into HAR phases and a total
+func harTimings(t *playwright.RequestTiming) (HARTimings, float64) {
+	span := func(start, end float64) float64 {
+		if start < 0 || end < 0 || end < start {
+			return -1
+		}
+		return end - start
+	}
+	nonNegative := func(v float64) float64 {
+		return math.Max(v, 0)
+	}
+
+	timings := HARTimings{
+		Blocked: -1,
+		DNS:     span(t.DomainLookupStart, t.DomainLookupEnd),
+		Connect: span(t.ConnectStart, t.ConnectEnd),
+		SSL:     span(t.SecureConnectionStart, t.ConnectEnd),
+		Send:    0,
+		Wait:    nonNegative(span(t.RequestStart, t.ResponseStart)),
+		Receive: nonNegative(span(t.ResponseStart, t.ResponseEnd)),
+	}
+	total := t.ResponseEnd
+	if total < 0 {
+		total = nonNegative(timings.DNS) + nonNegative(timings.Connect) + timings.Wait + timings.Receive
+	}
+	return timings, total
+}
+
+// HAR returns the requests recorded so far, oldest first
+// It waits for requests that settled but are still being recorded
+func (r *HARRecorder) HAR() *HAR {
+	r.pending.Wait()
+
+	r.mu.Lock()
+	entries := append([]HAREntry{}, r.entries...)
+	r.mu.Unlock()
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	return &HAR{Log: HARLog{
+		Version: "1.2",
+		Creator: HARCreator{Name: "golwarc", Version: "1.0"},
+		Entries: entries,
+	}}
+}
+
+// Reset forgets the recorded requests
+func (r *HARRecorder) Reset() {
+	r.pending.Wait()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// WriteFile writes the recorded requests to path as a HAR file
+func (r *HARRecorder) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r.HAR(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode HAR: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write HAR: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package crawlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+)
+
+// HSTSConfig holds HSTS and scheme upgrade settings
+type HSTSConfig struct {
+	// ProbeHTTPS also upgrades http URLs whose host answers over https,
+	// even without an HSTS header, so a site is not stored twice
+	ProbeHTTPS   bool
+	ProbeTimeout time.Duration     // Default 5s
+	ProbeTTL     time.Duration     // How long a probe result is kept (default 24h)
+	Transport    http.RoundTripper // Used for probes (default http.DefaultTransport)
+	Clock        clock.Clock       // Defaults to the real clock
+}
+
+// hstsEntry is a host's Strict-Transport-Security policy
+type hstsEntry struct {
+	expires           time.Time
+	includeSubDomains bool
+}
+
+// probeResult is the cached outcome of an https probe
+type probeResult struct {
+	secure  bool
+	expires time.Time
+}
+
+// HSTS records Strict-Transport-Security policies from https responses and
+// upgrades http URLs of known hosts to https, as browsers do
+// A nil HSTS upgrades nothing
+type HSTS struct {
+	clock    clock.Clock
+	probe    bool
+	probeTTL time.Duration
+	client   *http.Client
+
+	mu     sync.RWMutex
+	hosts  map[string]hstsEntry
+	probes map[string]probeResult
+}
+
+// NewHSTS creates an empty HSTS store
+func NewHSTS(config HSTSConfig) *HSTS {
+	if config.ProbeTimeout <= 0 {
+		config.ProbeTimeout = 5 * time.Second
+	}
+	if config.ProbeTTL <= 0 {
+		config.ProbeTTL = 24 * time.Hour
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport
+	}
+
+	return &HSTS{
+		clock:    clock.Or(config.Clock),
+		probe:    config.ProbeHTTPS,
+		probeTTL: config.ProbeTTL,
+		client: &http.Client{
+			Transport: config.Transport,
+			Timeout:   config.ProbeTimeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse // Any answer over https is enough
+			},
+		},
+		hosts:  make(map[string]hstsEntry),
+		probes: make(map[string]probeResult),
+	}
+}
+
+// NewHSTSFromConfig creates an HSTS store from application config
+// Returns nil when HSTS handling is disabled
+func NewHSTSFromConfig(config configs.HSTSConfig) *HSTS {
+	if !config.Enabled {
+		return nil
+	}
+	return NewHSTS(HSTSConfig{
+		ProbeHTTPS:   config.ProbeHTTPS,
+		ProbeTimeout: time.Duration(config.ProbeTimeout) * time.Second,
+	})
+}
+
+// Observe records the Strict-Transport-Security header of a response to
+// requestURL. Headers on http responses and for IP addresses are ignored,
+// and max-age=0 forgets the host
+func (h *HSTS) Observe(requestURL *url.URL, header http.Header) {
+	if h == nil || requestURL == nil || !strings.EqualFold(requestURL.Scheme, "https") {
+		return
+	}
+	value := header.Get("Strict-Transport-Security")
+	host := strings.ToLower(requestURL.Hostname())
+	if value == "" || host == "" || net.ParseIP(host) != nil {
+		return
+	}
+	maxAge, includeSubDomains, ok := parseHSTS(value)
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if maxAge == 0 {
+		delete(h.hosts, host)
+		return
+	}
+	h.hosts[host] = hstsEntry{
+		expires:           h.clock.Now().Add(time.Duration(maxAge) * time.Second),
+		includeSubDomains: includeSubDomains,
+	}
+}
+
+// parseHSTS parses a Strict-Transport-Security value (RFC 6797)
+// Headers without a valid max-age are invalid
+func parseHSTS(value string) (maxAge int64, includeSubDomains, ok bool) {
+	for _, directive := range strings.Split(value, ";") {
+		name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "max-age":
+			n, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(arg), `"`), 10, 64)
+			if err != nil || n < 0 {
+				return 0, false, false
+			}
+			maxAge, ok = n, true
+		case "includesubdomains":
+			includeSubDomains = true
+		}
+	}
+	return maxAge, includeSubDomains, ok
+}
+
+// Known reports whether host has an unexpired HSTS policy, directly or
+// through a parent domain that includes subdomains
+func (h *HSTS) Known(host string) bool {
+	if h == nil {
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	now := h.clock.Now()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for candidate, sub := host, false; candidate != ""; sub = true {
+		if entry, ok := h.hosts[candidate]; ok && now.Before(entry.expires) && (!sub || entry.includeSubDomains) {
+			return true
+		}
+		_, candidate, _ = strings.Cut(candidate, ".")
+	}
+	return false
+}
+
+// Upgrade returns the https form of an http URL whose host is known to
+// support https, and whether it changed. With ProbeHTTPS, hosts without an
+// HSTS policy are probed once per ProbeTTL
+func (h *HSTS) Upgrade(ctx context.Context, rawURL string) (string, bool) {
+	if h == nil {
+		return rawURL, false
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(parsed.Scheme, "http") || parsed.Hostname() == "" {
+		return rawURL, false
+	}
+
+	if !h.Known(parsed.Hostname()) && !(h.probe && h.probeHTTPS(ctx, parsed)) {
+		return rawURL, false
+	}
+	return upgradeScheme(parsed), true
+}
+
+// upgradeKnown is Upgrade without probing, for callers that must not block
+func (h *HSTS) upgradeKnown(rawURL string) string {
+	if h == nil {
+		return rawURL
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(parsed.Scheme, "http") || !h.Known(parsed.Hostname()) {
+		return rawURL
+	}
+	return upgradeScheme(parsed)
+}
+
+// upgradeScheme switches u to https; port 80 becomes the default https port
+func upgradeScheme(u *url.URL) string {
+	upgraded := *u
+	upgraded.Scheme = "https"
+	if upgraded.Port() == "80" {
+		upgraded.Host = upgraded.Hostname()
+		if strings.Contains(upgraded.Host, ":") {
+			upgraded.Host = "[" + upgraded.Host + "]" // IPv6 literal
+		}
+	}
+	return upgraded.String()
+}
+
+// probeHTTPS reports whether u's host answers over https on the default port
+// Hosts on other ports are not probed. Results are cached per host
+func (h *HSTS) probeHTTPS(ctx context.Context, u *url.URL) bool {
+	if port := u.Port(); port != "" && port != "80" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	now := h.clock.Now()
+
+	h.mu.RLock()
+	cached, ok := h.probes[host]
+	h.mu.RUnlock()
+	if ok && now.Before(cached.expires) {
+		return cached.secure
+	}
+
+	target := url.URL{Scheme: "https", Host: u.Host, Path: "/"}
+	if u.Port() == "80" {
+		target.Host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	secure := false
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target.String(), nil)
+	if err == nil {
+		if resp, err := h.client.Do(req); err == nil {
+			_ = resp.Body.Close() // Error intentionally ignored on close
+			h.Observe(resp.Request.URL, resp.Header)
+			secure = true
+		}
+	}
+	if ctx.Err() != nil {
+		return false // Cancelled probes say nothing about the host
+	}
+
+	h.mu.Lock()
+	h.probes[host] = probeResult{secure: secure, expires: now.Add(h.probeTTL)}
+	h.mu.Unlock()
+	return secure
+}
+
+// Transport wraps base so requests to known hosts go over https and HSTS
+// headers of every response, redirects included, are recorded
+func (h *HSTS) Transport(base http.RoundTripper) http.RoundTripper {
+	if h == nil {
+		return base
+	}
+	return &hstsTransport{hsts: h, base: base}
+}
+
+// hstsTransport upgrades requests and observes responses
+type hstsTransport struct {
+	hsts *HSTS
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *hstsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if upgraded, ok := t.hsts.Upgrade(req.Context(), req.URL.String()); ok {
+		if target, err := url.Parse(upgraded); err == nil {
+			req = req.Clone(req.Context())
+			req.URL = target
+			req.Host = ""
+		}
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err == nil {
+		t.hsts.Observe(req.URL, resp.Header)
+	}
+	return resp, err
+}
@@ -0,0 +1,579 @@
+package crawlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// CachedResponse is a response stored by HTTPCache
+type CachedResponse struct {
+	URL          string            `json:"url"`
+	StatusCode   int               `json:"status"`
+	Header       http.Header       `json:"header"`
+	Body         []byte            `json:"body"`
+	Vary         map[string]string `json:"vary,omitempty"` // Request header values the response was selected by
+	RequestTime  time.Time         `json:"request_time"`
+	ResponseTime time.Time         `json:"response_time"`
+}
+
+// HTTPCacheStore persists cached responses by URL
+type HTTPCacheStore interface {
+	// GetResponse returns the response stored under key, or nil if there is none
+	GetResponse(key string) (*CachedResponse, error)
+
+	// SetResponse stores a response under key for ttl
+	SetResponse(key string, resp *CachedResponse, ttl time.Duration) error
+
+	// DeleteResponse removes the response stored under key
+	DeleteResponse(key string) error
+}
+
+// MemoryHTTPCacheStore keeps responses in process memory, evicting the least
+// recently used ones
+type MemoryHTTPCacheStore struct {
+	entries *lru.Cache[string, memoryHTTPCacheEntry]
+	clock   clock.Clock
+}
+
+// memoryHTTPCacheEntry is a stored response and when it is dropped
+type memoryHTTPCacheEntry struct {
+	resp    *CachedResponse
+	removed time.Time
+}
+
+// NewMemoryHTTPCacheStore creates an in-memory store of up to maxEntries
+// responses (default 1000)
+func NewMemoryHTTPCacheStore(maxEntries int) *MemoryHTTPCacheStore {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	entries, _ := lru.New[string, memoryHTTPCacheEntry](maxEntries) // Only fails for a size below 1
+	return &MemoryHTTPCacheStore{entries: entries, clock: clock.Real}
+}
+
+// GetResponse returns the response stored under key, or nil if there is none
+func (s *MemoryHTTPCacheStore) GetResponse(key string) (*CachedResponse, error) {
+	entry, ok := s.entries.Get(key)
+	if !ok || !s.clock.Now().Before(entry.removed) {
+		return nil, nil
+	}
+	return entry.resp, nil
+}
+
+// SetResponse stores a response under key for ttl
+func (s *MemoryHTTPCacheStore) SetResponse(key string, resp *CachedResponse, ttl time.Duration) error {
+	s.entries.Add(key, memoryHTTPCacheEntry{resp: resp, removed: s.clock.Now().Add(ttl)})
+	return nil
+}
+
+// DeleteResponse removes the response stored under key
+func (s *MemoryHTTPCacheStore) DeleteResponse(key string) error {
+	s.entries.Remove(key)
+	return nil
+}
+
+// CacheHTTPCacheStore keeps responses in a shared cache such as Redis, as
+// JSON, so workers on several hosts share them. Keys expire on their own
+type CacheHTTPCacheStore struct {
+	client cache.CacheClient
+	prefix string
+}
+
+// NewCacheHTTPCacheStore creates a response store backed by a cache client
+func NewCacheHTTPCacheStore(client cache.CacheClient) *CacheHTTPCacheStore {
+	return &CacheHTTPCacheStore{
+		client: client,
+		prefix: "httpcache:",
+	}
+}
+
+// GetResponse returns the response stored under key, or nil if there is none
+func (s *CacheHTTPCacheStore) GetResponse(key string) (*CachedResponse, error) {
+	exists, err := s.client.Exists(s.prefix + key)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	val, err := s.client.Get(s.prefix + key)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp CachedResponse
+	if err := json.Unmarshal([]byte(val), &resp); err != nil {
+		return nil, fmt.Errorf("invalid cached response for %s: %w", key, err)
+	}
+	return &resp, nil
+}
+
+// SetResponse stores a response under key for ttl
+func (s *CacheHTTPCacheStore) SetResponse(key string, resp *CachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(s.prefix+key, string(data), ttl)
+}
+
+// DeleteResponse removes the response stored under key
+func (s *CacheHTTPCacheStore) DeleteResponse(key string) error {
+	return s.client.Delete(s.prefix + key)
+}
+
+// FileHTTPCacheStore keeps responses as JSON files in a directory, one per
+// URL, so they survive restarts without a cache server
+// Expired files are removed when they are next read
+type FileHTTPCacheStore struct {
+	dir   string
+	clock clock.Clock
+}
+
+// fileHTTPCacheEntry is the content of a cache file
+type fileHTTPCacheEntry struct {
+	Removed  time.Time       `json:"removed"`
+	Response *CachedResponse `json:"response"`
+}
+
+// NewFileHTTPCacheStore creates a response store writing to dir
+func NewFileHTTPCacheStore(dir string) *FileHTTPCacheStore {
+	return &FileHTTPCacheStore{dir: dir, clock: clock.Real}
+}
+
+// path returns the file of key
+func (s *FileHTTPCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.dir, name[:2], name+".json")
+}
+
+// GetResponse returns the response stored under key, or nil if there is none
+func (s *FileHTTPCacheStore) GetResponse(key string) (*CachedResponse, error) {
+	path := s.path(key)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache file: %w", err)
+	}
+
+	var entry fileHTTPCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("invalid cached response for %s: %w", key, err)
+	}
+	if !s.clock.Now().Before(entry.Removed) || entry.Response == nil || entry.Response.URL != key {
+		_ = os.Remove(path) // Best effort cleanup
+		return nil, nil
+	}
+	return entry.Response, nil
+}
+
+// SetResponse stores a response under key for ttl
+func (s *FileHTTPCacheStore) SetResponse(key string, resp *CachedResponse, ttl time.Duration) error {
+	data, err := json.Marshal(fileHTTPCacheEntry{Removed: s.clock.Now().Add(ttl), Response: resp})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path(key), data)
+}
+
+// DeleteResponse removes the response stored under key
+func (s *FileHTTPCacheStore) DeleteResponse(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// HTTPCacheConfig holds response cache settings
+type HTTPCacheConfig struct {
+	Store        HTTPCacheStore // Defaults to an in-memory store of 1000 responses on Clock
+	MaxBodySize  int64          // Larger responses are not stored (default 10 MiB)
+	HeuristicTTL time.Duration  // Cap of the freshness guessed from Last-Modified (default 24h); negative disables guessing
+	KeepStale    time.Duration  // How long responses with validators are kept past freshness for revalidation (default 24h)
+	Clock        clock.Clock    // Defaults to the wall clock; shared stores expire keys on wall time
+}
+
+// HTTPCache is a private HTTP cache for SoupClient following RFC 9111
+// (formerly RFC 7234), keyed by URL
+//
+// Responses to GET requests are stored when Cache-Control and Expires allow
+// it, and fresh ones are served without contacting the server, cooldowns or
+// rate limits. Stale responses with an ETag or Last-Modified are revalidated
+// with a conditional request; a 304 refreshes the stored response. Without
+// explicit freshness, 10% of the time since Last-Modified is used, up to
+// HeuristicTTL. Vary is honored and Vary: * is never stored. Requests with
+// an Authorization header bypass the cache
+type HTTPCache struct {
+	store     HTTPCacheStore
+	maxBody   int64
+	heuristic time.Duration
+	keepStale time.Duration
+	clock     clock.Clock
+
+	hits        atomic.Int64
+	revalidated atomic.Int64
+	misses      atomic.Int64
+	stored      atomic.Int64
+}
+
+// HTTPCacheStats holds response cache counters
+type HTTPCacheStats struct {
+	Hits        int64 // Fresh responses served from the cache
+	Revalidated int64 // Stale responses the server confirmed with a 304
+	Misses      int64 // Requests sent without a usable cached response
+	Stored      int64 // Responses written to the store
+}
+
+// NewHTTPCache creates a response cache
+func NewHTTPCache(config HTTPCacheConfig) *HTTPCache {
+	config.Clock = clock.Or(config.Clock)
+	if config.Store == nil {
+		store := NewMemoryHTTPCacheStore(0)
+		store.clock = config.Clock
+		config.Store = store
+	}
+	if config.MaxBodySize <= 0 {
+		config.MaxBodySize = 10 << 20
+	}
+	if config.HeuristicTTL == 0 {
+		config.HeuristicTTL = 24 * time.Hour
+	}
+	if config.KeepStale <= 0 {
+		config.KeepStale = 24 * time.Hour
+	}
+	return &HTTPCache{
+		store:     config.Store,
+		maxBody:   config.MaxBodySize,
+		heuristic: config.HeuristicTTL,
+		keepStale: config.KeepStale,
+		clock:     config.Clock,
+	}
+}
+
+// NewHTTPCacheFromConfig creates a response cache from application config
+// and a store chosen by the caller, nil for the in-memory one; returns nil
+// when it is disabled
+func NewHTTPCacheFromConfig(config configs.HTTPCacheConfig, store HTTPCacheStore) *HTTPCache {
+	if !config.Enabled {
+		return nil
+	}
+	if store == nil {
+		store = NewMemoryHTTPCacheStore(config.MaxEntries)
+	}
+	return NewHTTPCache(HTTPCacheConfig{
+		Store:        store,
+		MaxBodySize:  config.MaxBodySize,
+		HeuristicTTL: time.Duration(config.HeuristicTTL) * time.Second,
+		KeepStale:    time.Duration(config.KeepStale) * time.Second,
+	})
+}
+
+// Stats returns the cache's counters
+func (c *HTTPCache) Stats() HTTPCacheStats {
+	return HTTPCacheStats{
+		Hits:        c.hits.Load(),
+		Revalidated: c.revalidated.Load(),
+		Misses:      c.misses.Load(),
+		Stored:      c.stored.Load(),
+	}
+}
+
+// Invalidate drops the cached response of rawURL, e.g. after a POST to it
+func (c *HTTPCache) Invalidate(rawURL string) error {
+	if c == nil {
+		return nil
+	}
+	return c.store.DeleteResponse(httpCacheKey(rawURL))
+}
+
+// now returns the cache's time, or the zero time for a nil cache
+func (c *HTTPCache) now() time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+	return c.clock.Now()
+}
+
+// httpCacheKey is the store key of a URL: the URL without its fragment
+func httpCacheKey(rawURL string) string {
+	key, _, _ := strings.Cut(rawURL, "#")
+	return key
+}
+
+// cacheable reports whether req may be answered from or stored in the cache
+func (c *HTTPCache) cacheable(req *http.Request) bool {
+	if c == nil || req.Method != http.MethodGet || req.Header.Get("Authorization") != "" {
+		return false
+	}
+	_, noStore := parseCacheControl(req.Header.Values("Cache-Control"))["no-store"]
+	return !noStore
+}
+
+// lookup returns the stored response matching req, whether it is fresh, or
+// nil. A stale response with validators adds them to req
+func (c *HTTPCache) lookup(req *http.Request) (*CachedResponse, bool) {
+	if !c.cacheable(req) {
+		return nil, false
+	}
+	cached, err := c.store.GetResponse(httpCacheKey(req.URL.String()))
+	if err != nil || cached == nil || !cached.matches(req) {
+		c.misses.Add(1)
+		return nil, false
+	}
+
+	directives := parseCacheControl(cached.Header.Values("Cache-Control"))
+	_, noCache := directives["no-cache"]
+	_, reqNoCache := parseCacheControl(req.Header.Values("Cache-Control"))["no-cache"]
+	if !noCache && !reqNoCache && cached.age(c.clock.Now()) < c.freshness(cached) {
+		c.hits.Add(1)
+		return cached, true
+	}
+
+	etag, lastModified := cached.Header.Get("ETag"), cached.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		c.misses.Add(1)
+		return nil, false
+	}
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	return cached, false
+}
+
+// revalidate merges the headers of a 304 into the stored response, stores
+// it again and returns it as the response to req
+func (c *HTTPCache) revalidate(req *http.Request, cached *CachedResponse, notModified *http.Response, requestTime time.Time) *http.Response {
+	c.revalidated.Add(1)
+	refreshed := *cached
+	refreshed.Header = cached.Header.Clone()
+	for name, values := range notModified.Header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Content-Type":
+			continue // Describe the stored body, not the empty 304
+		}
+		refreshed.Header[name] = values
+	}
+	refreshed.RequestTime = requestTime
+	refreshed.ResponseTime = c.clock.Now()
+	c.save(&refreshed)
+	return refreshed.response(req)
+}
+
+// record returns a body recorder for resp when it may be stored, or nil
+func (c *HTTPCache) record(req *http.Request, resp *http.Response) *httpCacheRecorder {
+	if !c.cacheable(req) || !cacheableStatus(resp.StatusCode) {
+		return nil
+	}
+	directives := parseCacheControl(resp.Header.Values("Cache-Control"))
+	if _, noStore := directives["no-store"]; noStore || resp.Header.Get("Vary") == "*" {
+		return nil
+	}
+	if resp.ContentLength > c.maxBody {
+		return nil
+	}
+	recorder := &httpCacheRecorder{ReadCloser: resp.Body, limit: c.maxBody}
+	resp.Body = recorder
+	return recorder
+}
+
+// commit stores a response whose body recorder read it completely
+func (c *HTTPCache) commit(req *http.Request, resp *http.Response, recorder *httpCacheRecorder, requestTime time.Time) {
+	if recorder == nil || !recorder.complete || recorder.overflow {
+		return
+	}
+	cached := &CachedResponse{
+		URL:          httpCacheKey(req.URL.String()),
+		StatusCode:   resp.StatusCode,
+		Header:       resp.Header.Clone(),
+		Body:         recorder.buf.Bytes(),
+		RequestTime:  requestTime,
+		ResponseTime: c.clock.Now(),
+	}
+	for _, field := range varyFields(resp.Header) {
+		if cached.Vary == nil {
+			cached.Vary = make(map[string]string)
+		}
+		cached.Vary[field] = req.Header.Get(field)
+	}
+	c.save(cached)
+}
+
+// save stores a response for its freshness lifetime, plus KeepStale when it
+// can be revalidated
+func (c *HTTPCache) save(cached *CachedResponse) {
+	ttl := c.freshness(cached) - cached.age(c.clock.Now())
+	if cached.Header.Get("ETag") != "" || cached.Header.Get("Last-Modified") != "" {
+		ttl = max(ttl, 0) + c.keepStale
+	}
+	if ttl <= 0 {
+		return
+	}
+	if err := c.store.SetResponse(cached.URL, cached, ttl); err == nil {
+		c.stored.Add(1)
+	}
+}
+
+// freshness returns how long a response is fresh from when it was generated
+func (c *HTTPCache) freshness(cached *CachedResponse) time.Duration {
+	directives := parseCacheControl(cached.Header.Values("Cache-Control"))
+	if value, ok := directives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	date := cached.date()
+	if value := cached.Header.Get("Expires"); value != "" {
+		expires, err := http.ParseTime(value)
+		if err != nil {
+			return 0 // Invalid dates, such as "0", mean already expired
+		}
+		return max(expires.Sub(date), 0)
+	}
+
+	if c.heuristic < 0 || !cacheableStatus(cached.StatusCode) {
+		return 0
+	}
+	if value := cached.Header.Get("Last-Modified"); value != "" {
+		if modified, err := http.ParseTime(value); err == nil && modified.Before(date) {
+			return min(date.Sub(modified)/10, c.heuristic)
+		}
+	}
+	return 0
+}
+
+// date returns the Date header, or when the response arrived
+func (r *CachedResponse) date() time.Time {
+	if date, err := http.ParseTime(r.Header.Get("Date")); err == nil {
+		return date
+	}
+	return r.ResponseTime
+}
+
+// age computes the current age of a response (RFC 9111, section 4.2.3)
+func (r *CachedResponse) age(now time.Time) time.Duration {
+	apparent := max(r.ResponseTime.Sub(r.date()), 0)
+	var ageValue time.Duration
+	if seconds, err := strconv.ParseInt(r.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		ageValue = time.Duration(seconds) * time.Second
+	}
+	corrected := ageValue + r.ResponseTime.Sub(r.RequestTime)
+	return max(apparent, corrected) + now.Sub(r.ResponseTime)
+}
+
+// matches reports whether req sends the header values the response varies on
+func (r *CachedResponse) matches(req *http.Request) bool {
+	for field, value := range r.Vary {
+		if req.Header.Get(field) != value {
+			return false
+		}
+	}
+	return true
+}
+
+// response rebuilds an http.Response for req
+func (r *CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
+
+// httpCacheRecorder copies a response body while it is read
+type httpCacheRecorder struct {
+	io.ReadCloser
+	buf      bytes.Buffer
+	limit    int64
+	complete bool // The body was read to the end
+	overflow bool // The body exceeded limit
+}
+
+// Read implements io.Reader
+func (r *httpCacheRecorder) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	if !r.overflow {
+		if int64(r.buf.Len()+n) > r.limit {
+			r.overflow = true
+			r.buf = bytes.Buffer{}
+		} else {
+			r.buf.Write(p[:n])
+		}
+	}
+	if err == io.EOF {
+		r.complete = true
+	}
+	return n, err
+}
+
+// parseCacheControl parses Cache-Control values into lowercase directives
+// and their unquoted arguments
+func parseCacheControl(values []string) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range values {
+		for _, directive := range strings.Split(value, ",") {
+			name, arg, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				directives[name] = strings.Trim(strings.TrimSpace(arg), `"`)
+			}
+		}
+	}
+	return directives
+}
+
+// varyFields returns the request headers a response varies on
+func varyFields(header http.Header) []string {
+	var fields []string
+	for _, value := range header.Values("Vary") {
+		for _, field := range strings.Split(value, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				fields = append(fields, http.CanonicalHeaderKey(field))
+			}
+		}
+	}
+	return fields
+}
+
+// cacheableStatus reports whether responses with status may be stored; these
+// are the statuses cacheable by default (RFC 9110, section 15.1), so they
+// may also be given a guessed freshness
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK, http.StatusNonAuthoritativeInfo, http.StatusNoContent,
+		http.StatusMultipleChoices, http.StatusMovedPermanently, http.StatusPermanentRedirect,
+		http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusGone,
+		http.StatusRequestURITooLong, http.StatusNotImplemented:
+		return true
+	}
+	return false
+}
@@ -1,6 +1,10 @@
 package crawlers
 
-import "github.com/gocolly/colly/v2"
+import (
+	"context"
+
+	"github.com/gocolly/colly/v2"
+)
 
 // CrawlerClient defines the interface for web crawling operations
 // This enables mocking in tests and provides a consistent API across different crawler implementations
@@ -8,6 +12,9 @@ type CrawlerClient interface {
 	// Visit starts crawling from the given URL
 	Visit(url string) error
 
+	// VisitContext starts crawling from the given URL until ctx is done
+	VisitContext(ctx context.Context, url string) error
+
 	// VisitMultiple visits multiple URLs
 	VisitMultiple(urls []string) error
 
@@ -36,5 +43,15 @@ type CrawlerClient interface {
 	SetHeaders(headers map[string]string)
 }
 
-// Ensure CollyClient implements the CrawlerClient interface
-var _ CrawlerClient = (*CollyClient)(nil)
+// Ensure CollyClient implements the CrawlerClient and RedirectObserver
+// interfaces, and every adapter the Crawler interface
+var (
+	_ CrawlerClient    = (*CollyClient)(nil)
+	_ RedirectObserver = (*CollyClient)(nil)
+	_ Crawler          = (*CollyCrawler)(nil)
+	_ Crawler          = (*SoupCrawler)(nil)
+	_ Crawler          = (*SpiderCrawler)(nil)
+	_ Crawler          = (*PlaywrightCrawler)(nil)
+	_ Crawler          = (*PuppeteerCrawler)(nil)
+	_ Crawler          = (*SeleniumCrawler)(nil)
+)
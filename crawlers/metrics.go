@@ -0,0 +1,142 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/libs"
+)
+
+// Crawler types, the crawler_type label of crawler metrics and the
+// Result.Engine of Crawler adapters
+const (
+	CrawlerTypeColly      = "colly"
+	CrawlerTypeSpider     = "spider"
+	CrawlerTypeSoup       = "soup"
+	CrawlerTypePlaywright = "playwright"
+	CrawlerTypePuppeteer  = "puppeteer"
+	CrawlerTypeSelenium   = "selenium"
+)
+
+// Error types, the error_type label of crawler error metrics
+const (
+	ErrorTypeTimeout  = "timeout"
+	ErrorTypeCanceled = "canceled"
+	ErrorTypeNetwork  = "network"
+	ErrorTypeHTTP4xx  = "http_4xx"
+	ErrorTypeHTTP5xx  = "http_5xx"
+)
+
+// Metrics receives one observation per crawler request; *libs.Metrics
+// implements it
+type Metrics interface {
+	RecordCrawlerRequest(crawlerType, status string)
+	RecordCrawlerDuration(crawlerType string, duration time.Duration)
+	RecordCrawlerError(crawlerType, errorType string)
+}
+
+var (
+	_ Metrics         = (*libs.Metrics)(nil)
+	_ RobotsMetrics   = (*libs.Metrics)(nil)
+	_ BlockMetrics    = (*libs.Metrics)(nil)
+	_ ThrottleMetrics = (*libs.Metrics)(nil)
+	_ CircuitMetrics  = (*libs.Metrics)(nil)
+)
+
+// recordRequest reports a finished request to m, if not nil
+// The status label is the HTTP status class (2xx to 5xx), "error" when no
+// response arrived, or "success" when the client cannot see the status code
+func recordRequest(m Metrics, crawlerType string, start time.Time, statusCode int, err error) {
+	if m == nil {
+		return
+	}
+
+	status := "success"
+	switch {
+	case err != nil:
+		status = "error"
+	case statusCode > 0:
+		status = fmt.Sprintf("%dxx", statusCode/100)
+	}
+	m.RecordCrawlerRequest(crawlerType, status)
+	m.RecordCrawlerDuration(crawlerType, time.Since(start))
+
+	if errorType := requestErrorType(statusCode, err); errorType != "" {
+		m.RecordCrawlerError(crawlerType, errorType)
+	}
+}
+
+// requestErrorType classifies a failed request; "" means it did not fail
+func requestErrorType(statusCode int, err error) string {
+	var netErr net.Error
+	switch {
+	case err == nil && statusCode >= 500:
+		return ErrorTypeHTTP5xx
+	case err == nil && statusCode >= 400:
+		return ErrorTypeHTTP4xx
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled):
+		return ErrorTypeCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrorTypeTimeout
+	default:
+		return ErrorTypeNetwork
+	}
+}
+
+// metricsTransport records every round trip once its response body is
+// closed, so durations include the download
+type metricsTransport struct {
+	base        http.RoundTripper
+	metrics     Metrics
+	crawlerType string
+}
+
+// instrumentTransport wraps base to report to metrics; base is returned
+// unchanged when metrics is nil
+func instrumentTransport(base http.RoundTripper, metrics Metrics, crawlerType string) http.RoundTripper {
+	if metrics == nil {
+		return base
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &metricsTransport{base: base, metrics: metrics, crawlerType: crawlerType}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		recordRequest(t.metrics, t.crawlerType, start, 0, err)
+		return nil, err
+	}
+
+	statusCode := resp.StatusCode
+	resp.Body = &recordOnClose{ReadCloser: resp.Body, record: func() {
+		recordRequest(t.metrics, t.crawlerType, start, statusCode, nil)
+	}}
+	return resp, nil
+}
+
+// recordOnClose records a request the first time its body is closed
+type recordOnClose struct {
+	io.ReadCloser
+	once   sync.Once
+	record func()
+}
+
+// Close closes the body and records the request
+func (r *recordOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.record)
+	return err
+}
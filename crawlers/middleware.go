@@ -0,0 +1,154 @@
+package crawlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"go.uber.org/zap"
+)
+
+// Fetcher fetches one page; every Crawler is one
+type Fetcher interface {
+	Fetch(ctx context.Context, url string) (*Result, error)
+}
+
+// FetcherFunc adapts a function to the Fetcher interface
+type FetcherFunc func(ctx context.Context, url string) (*Result, error)
+
+// Fetch calls f
+func (f FetcherFunc) Fetch(ctx context.Context, url string) (*Result, error) {
+	return f(ctx, url)
+}
+
+// Middleware wraps a Fetcher with a cross-cutting concern, such as headers,
+// logging, metrics, rate limiting or caching, so it is written once for
+// every engine. Register it with Crawler.Use or compose it with Chain
+type Middleware func(next Fetcher) Fetcher
+
+// Chain wraps f in middleware; the first listed runs outermost
+func Chain(f Fetcher, middleware ...Middleware) Fetcher {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		f = middleware[i](f)
+	}
+	return f
+}
+
+// HeadersMiddleware adds headers, e.g. an Authorization token, to every
+// request. Headers already in the context's RequestOptions win. Only Colly,
+// Soup and Spider send RequestOptions; browser engines ignore them
+func HeadersMiddleware(headers map[string]string) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			opts := RequestOptionsFrom(ctx)
+			merged := make(map[string]string, len(headers)+len(opts.Headers))
+			for name, value := range headers {
+				merged[name] = value
+			}
+			for name, value := range opts.Headers {
+				merged[name] = value
+			}
+			opts.Headers = merged
+			return next.Fetch(WithRequestOptions(ctx, opts), url)
+		})
+	}
+}
+
+// LoggingMiddleware logs every fetch: successes at debug level, failures
+// at warn level
+func LoggingMiddleware(logger *zap.Logger) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			start := time.Now()
+			result, err := next.Fetch(ctx, url)
+			if err != nil {
+				logger.Warn("Fetch failed",
+					zap.String("url", url),
+					zap.Duration("duration", time.Since(start)),
+					zap.Error(err))
+				return nil, err
+			}
+			logger.Debug("Fetched page",
+				zap.String("url", url),
+				zap.String("final_url", result.URL),
+				zap.String("engine", result.Engine),
+				zap.Int("status", result.StatusCode),
+				zap.Duration("duration", time.Since(start)))
+			return result, nil
+		})
+	}
+}
+
+// MetricsMiddleware records every fetch as a request of crawlerType, for
+// engines whose client was built without Metrics; with both, requests are
+// counted twice
+func MetricsMiddleware(metrics Metrics, crawlerType string) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			start := time.Now()
+			result, err := next.Fetch(ctx, url)
+			var status int
+			if result != nil {
+				status = result.StatusCode
+			}
+			recordRequest(metrics, crawlerType, start, status, err)
+			return result, err
+		})
+	}
+}
+
+// RateLimitMiddleware waits out domain cooldowns and holds a slot of
+// limiter while a fetch runs, for engines whose client consults neither
+// itself (Puppeteer, Selenium), and reports the result's status to both;
+// either may be nil
+func RateLimitMiddleware(limiter *RateLimiter, cooldown *DomainCooldown) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			if err := cooldown.Wait(ctx, url); err != nil {
+				return nil, err
+			}
+			if limiter != nil {
+				release, err := limiter.Acquire(ctx, url)
+				if err != nil {
+					return nil, err
+				}
+				defer release()
+			}
+			result, err := next.Fetch(ctx, url)
+			if result != nil {
+				observeThrottle(limiter, cooldown, url, result.StatusCode, result.Header)
+			}
+			return result, err
+		})
+	}
+}
+
+// cachedResult is a CacheMiddleware entry
+type cachedResult struct {
+	result  Result
+	expires time.Time
+}
+
+// CacheMiddleware answers fetches of a URL from c for ttl after a 200
+// response to it. Cached results are copies sharing the cached body, which
+// must not be modified
+func CacheMiddleware(c cache.LocalCache, ttl time.Duration) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			if value, ok := c.Get(url); ok {
+				if entry, ok := value.(cachedResult); ok && time.Now().Before(entry.expires) {
+					result := entry.result
+					return &result, nil
+				}
+				c.Delete(url)
+			}
+
+			result, err := next.Fetch(ctx, url)
+			if err == nil && result.StatusCode == http.StatusOK {
+				c.Set(url, cachedResult{result: *result, expires: time.Now().Add(ttl)})
+			}
+			return result, err
+		})
+	}
+}
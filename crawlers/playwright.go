@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/alonecandies/golwarc/errs"
 	"github.com/playwright-community/playwright-go"
 )
 
@@ -14,7 +15,15 @@ type PlaywrightClient struct {
 	browser   playwright.Browser
 	page      playwright.Page
 	ctx       context.Context
+	timeout   time.Duration
 	rateLimit time.Duration
+	limiter   *RateLimiter
+	proxy     *browserProxy
+	network   *networkMonitor
+	har       *HARRecorder
+	harPath   string
+	metrics   Metrics
+	stealth   StealthConfig
 }
 
 // PlaywrightConfig holds Playwright configuration
@@ -23,6 +32,23 @@ type PlaywrightConfig struct {
 	Headless    bool
 	Timeout     time.Duration
 	RateLimit   time.Duration // Delay between navigation calls
+	RateLimiter *RateLimiter  // Optional per-domain limiter shared with other clients
+	Proxy       string        // Optional http, https or socks5 proxy URL, credentials included
+	RecordHAR   bool          // Record every request for HAR export
+	HARPath     string        // Write the HAR here on Close; implies RecordHAR
+	// Encrypted storage state (cookies and localStorage) the page starts
+	// with, e.g. a login exported with ExportSessionState
+	StatePath       string
+	StatePassphrase string
+
+	// Metrics records every navigation by status; optional, e.g. *libs.Metrics
+	Metrics Metrics
+
+	// Stealth overrides the fingerprint of the client's pages
+	Stealth StealthConfig
+
+	// Emulation picks device and region presets, e.g. to crawl mobile sites
+	Emulation EmulationConfig
 }
 
 // NewPlaywrightClient creates a new Playwright client
@@ -34,67 +60,224 @@ func NewPlaywrightClient(config PlaywrightConfig) (*PlaywrightClient, error) {
 		config.Timeout = 30 * time.Second
 	}
 
-	pw, err := playwright.Run()
+	emulation, err := config.Emulation.resolve()
 	if err != nil {
-		return nil, fmt.Errorf("failed to start Playwright: %w", err)
+		return nil, err
+	}
+	config.Stealth = emulation.stealth(config.Stealth)
+
+	var pageOpts playwright.BrowserNewPageOptions
+	emulation.pageOptions(&pageOpts, config.BrowserType)
+	if config.StatePath != "" {
+		state, err := LoadSessionState(config.StatePath, config.StatePassphrase)
+		if err != nil {
+			return nil, err
+		}
+		pageOpts.StorageState = state.ToOptionalStorageState()
 	}
 
-	var browser playwright.Browser
-
-	browserOpts := playwright.BrowserTypeLaunchOptions{
-		Headless: &config.Headless,
+	proxy, err := newBrowserProxy(config.Proxy, true)
+	if err != nil {
+		return nil, err
 	}
 
-	switch config.BrowserType {
-	case "chromium":
-		browser, err = pw.Chromium.Launch(browserOpts)
-	case "firefox":
-		browser, err = pw.Firefox.Launch(browserOpts)
-	case "webkit":
-		browser, err = pw.WebKit.Launch(browserOpts)
-	default:
-		_ = pw.Stop() // Best effort cleanup
-		return nil, fmt.Errorf("unsupported browser type: %s", config.BrowserType)
+	pw, err := playwright.Run()
+	if err != nil {
+		proxy.close()
+		return nil, fmt.Errorf("failed to start Playwright: %w", err)
 	}
 
+	browser, err := launchBrowser(pw, config.BrowserType, config.Headless, proxy, config.Stealth.launchArgs(config.BrowserType))
 	if err != nil {
 		_ = pw.Stop() // Best effort cleanup
-		return nil, fmt.Errorf("failed to launch browser: %w", err)
+		proxy.close()
+		return nil, err
 	}
 
-	page, err := browser.NewPage()
-	if err != nil {
+	cleanup := func() {
 		_ = browser.Close() // Best effort cleanup
 		_ = pw.Stop()       // Best effort cleanup
+		proxy.close()
+	}
+
+	stealth, err := config.Stealth.resolve(browser)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	stealth.pageOptions(&pageOpts)
+
+	page, err := browser.NewPage(pageOpts)
+	if err != nil {
+		cleanup()
 		return nil, fmt.Errorf("failed to create page: %w", err)
 	}
+	if err := stealth.install(page); err != nil {
+		cleanup()
+		return nil, err
+	}
 
 	page.SetDefaultTimeout(float64(config.Timeout.Milliseconds()))
 
+	var har *HARRecorder
+	if config.RecordHAR || config.HARPath != "" {
+		har = NewHARRecorder()
+		har.Attach(page)
+	}
+
 	return &PlaywrightClient{
 		pw:        pw,
 		browser:   browser,
 		page:      page,
 		ctx:       context.Background(),
+		timeout:   config.Timeout,
 		rateLimit: config.RateLimit,
+		limiter:   config.RateLimiter,
+		proxy:     proxy,
+		network:   watchNetwork(page),
+		har:       har,
+		harPath:   config.HARPath,
+		metrics:   config.Metrics,
+		stealth:   stealth,
 	}, nil
 }
 
-// NewPage creates a new page
+// NewPage creates a new page with the client's stealth settings
 func (p *PlaywrightClient) NewPage() (playwright.Page, error) {
-	return p.browser.NewPage()
+	var opts playwright.BrowserNewPageOptions
+	p.stealth.pageOptions(&opts)
+	page, err := p.browser.NewPage(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.stealth.install(page); err != nil {
+		_ = page.Close() // Best effort cleanup
+		return nil, err
+	}
+	return page, nil
 }
 
 // Navigate navigates to a URL with rate limiting
 func (p *PlaywrightClient) Navigate(url string) error {
-	// Apply rate limiting if configured
-	if p.rateLimit > 0 {
-		time.Sleep(p.rateLimit)
+	return p.NavigateContext(p.ctx, url)
+}
+
+// NavigateContext navigates to a URL, giving up when ctx is done
+// A ctx deadline also bounds the navigation timeout; on cancellation the
+// page is told to stop loading
+func (p *PlaywrightClient) NavigateContext(ctx context.Context, url string) error {
+	release, err := p.throttle(ctx, url)
+	if err != nil {
+		return err
 	}
-	_, err := p.page.Goto(url)
+	defer release()
+
+	_, err = p.gotoRecorded(ctx, p.page, url)
 	return err
 }
 
+// gotoRecorded navigates page to url with gotoContext, records the
+// navigation in the client's metrics and reports its status to the limiter
+func (p *PlaywrightClient) gotoRecorded(ctx context.Context, page playwright.Page, url string) (int, error) {
+	start := time.Now()
+	status, err := gotoContext(ctx, page, url)
+	recordRequest(p.metrics, CrawlerTypePlaywright, start, status, err)
+	p.limiter.Observe(url, status, nil)
+	return status, err
+}
+
+// throttle applies the client's rate limits before a navigation to url
+// The returned release func must be called once the navigation is done
+func (p *PlaywrightClient) throttle(ctx context.Context, url string) (func(), error) {
+	if p.rateLimit > 0 {
+		timer := time.NewTimer(p.rateLimit)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {}
+	if p.limiter != nil {
+		var err error
+		if release, err = p.limiter.Acquire(ctx, url); err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// gotoContext navigates page to url, giving up when ctx is done
+// It returns the status code of the main response, or 0 when there is none
+// such as for same-document navigations
+func gotoContext(ctx context.Context, page playwright.Page, url string) (int, error) {
+	var opts playwright.PageGotoOptions
+	if deadline, ok := ctx.Deadline(); ok {
+		opts.Timeout = playwright.Float(float64(time.Until(deadline).Milliseconds()))
+	}
+
+	type result struct {
+		status int
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := page.Goto(url, opts)
+		status := 0
+		if err == nil && resp != nil {
+			status = resp.Status()
+		}
+		done <- result{status, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.status, r.err
+	case <-ctx.Done():
+		_, _ = page.Evaluate("window.stop()") // Best effort; navigation may already be gone
+		return 0, ctx.Err()
+	}
+}
+
+// launchBrowser starts a browser of the given type, optionally behind proxy
+// and with extra command-line args
+func launchBrowser(pw *playwright.Playwright, browserType string, headless bool, proxy *browserProxy, args []string) (playwright.Browser, error) {
+	opts := playwright.BrowserTypeLaunchOptions{
+		Headless: &headless,
+		Args:     args,
+	}
+	if proxy != nil {
+		opts.Proxy = &playwright.Proxy{Server: proxy.server}
+		if proxy.username != "" {
+			opts.Proxy.Username = playwright.String(proxy.username)
+			opts.Proxy.Password = playwright.String(proxy.password)
+		}
+	}
+
+	var launcher playwright.BrowserType
+	switch browserType {
+	case "chromium":
+		launcher = pw.Chromium
+	case "firefox":
+		launcher = pw.Firefox
+	case "webkit":
+		launcher = pw.WebKit
+	default:
+		return nil, fmt.Errorf("unsupported browser type: %s", browserType)
+	}
+
+	browser, err := launcher.Launch(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to launch browser: %w", err)
+	}
+	return browser, nil
+}
+
 // Click clicks an element using locator-based API
 func (p *PlaywrightClient) Click(selector string) error {
 	return p.page.Locator(selector).Click()
@@ -133,6 +316,14 @@ func (p *PlaywrightClient) Screenshot(path string) error {
 	return err
 }
 
+// CaptureScreenshot navigates to url and saves a screenshot to path
+func (p *PlaywrightClient) CaptureScreenshot(ctx context.Context, url, path string) error {
+	if err := p.NavigateContext(ctx, url); err != nil {
+		return err
+	}
+	return p.Screenshot(path)
+}
+
 // ScreenshotBytes takes a screenshot and returns bytes
 func (p *PlaywrightClient) ScreenshotBytes() ([]byte, error) {
 	return p.page.Screenshot()
@@ -253,6 +444,14 @@ func (p *PlaywrightClient) GetCookies() ([]playwright.Cookie, error) {
 
 // Close closes the browser and Playwright
 func (p *PlaywrightClient) Close() error {
+	defer p.proxy.close()
+
+	// Written first so requests can still be inspected
+	var harErr error
+	if p.harPath != "" {
+		harErr = p.ExportHAR(p.harPath)
+	}
+
 	if err := p.page.Close(); err != nil {
 		return err
 	}
@@ -265,7 +464,24 @@ func (p *PlaywrightClient) Close() error {
 		return err
 	}
 
-	return nil
+	return harErr
+}
+
+// HAR returns the requests recorded so far; nil unless RecordHAR or
+// HARPath is set
+func (p *PlaywrightClient) HAR() *HAR {
+	if p.har == nil {
+		return nil
+	}
+	return p.har.HAR()
+}
+
+// ExportHAR writes the requests recorded so far to a HAR file
+func (p *PlaywrightClient) ExportHAR(path string) error {
+	if p.har == nil {
+		return errs.New(errs.CodeInvalidConfig, "HAR recording is not enabled; set PlaywrightConfig.RecordHAR")
+	}
+	return p.har.WriteFile(path)
 }
 
 // GetPage returns the current page for advanced operations
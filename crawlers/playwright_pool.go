@@ -0,0 +1,338 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/playwright-community/playwright-go"
+)
+
+// ErrPoolClosed is returned by Checkout after the pool is closed
+var ErrPoolClosed = errs.New(errs.CodeBrowserPoolClosed, "playwright pool is closed")
+
+// PlaywrightPoolConfig holds Playwright pool configuration
+type PlaywrightPoolConfig struct {
+	BrowserType string          // "chromium", "firefox", "webkit" (default chromium)
+	Headless    bool            // Run the browser without a window
+	Size        int             // Warm browser contexts, i.e. concurrent pages (default 4)
+	Timeout     time.Duration   // Default timeout of page operations (default 30s)
+	RateLimiter *RateLimiter    // Optional per-domain limiter applied by Render
+	Proxy       string          // Optional http, https or socks5 proxy URL, credentials included
+	Stealth     StealthConfig   // Fingerprint overrides of every pooled context
+	Emulation   EmulationConfig // Device and region presets of every pooled context
+}
+
+// PlaywrightPool shares one browser between concurrent renders
+// It keeps Size isolated browser contexts warm, each with one page, and
+// hands pages out with Checkout; Checkin resets the page for the next user
+type PlaywrightPool struct {
+	pw          *playwright.Playwright
+	browser     playwright.Browser
+	timeout     time.Duration
+	limiter     *RateLimiter
+	proxy       *browserProxy
+	stealth     StealthConfig
+	emulate     emulation
+	browserType string
+	size        int
+
+	slots chan *poolSlot // Idle slots; a slot without a context is rebuilt on checkout
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// poolSlot is one browser context and its page
+type poolSlot struct {
+	context playwright.BrowserContext
+	page    playwright.Page
+}
+
+// PooledPage is a page checked out of a PlaywrightPool
+// Use it as a playwright.Page and return it with Checkin
+type PooledPage struct {
+	playwright.Page
+	slot     *poolSlot
+	discard  bool
+	returned bool
+}
+
+// Discard marks the page as unusable so Checkin replaces its browser context
+// instead of reusing it, e.g. after a crash or a page left in a bad state
+func (p *PooledPage) Discard() {
+	p.discard = true
+}
+
+// PoolStats holds pool occupancy
+type PoolStats struct {
+	Size  int
+	Idle  int
+	InUse int
+}
+
+// NewPlaywrightPool starts a browser and warms Size browser contexts
+func NewPlaywrightPool(config PlaywrightPoolConfig) (*PlaywrightPool, error) {
+	if config.BrowserType == "" {
+		config.BrowserType = "chromium"
+	}
+	if config.Size <= 0 {
+		config.Size = 4
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	switch config.BrowserType {
+	case "chromium", "firefox", "webkit":
+	default:
+		return nil, fmt.Errorf("unsupported browser type: %s", config.BrowserType)
+	}
+
+	emulation, err := config.Emulation.resolve()
+	if err != nil {
+		return nil, err
+	}
+	config.Stealth = emulation.stealth(config.Stealth)
+
+	proxy, err := newBrowserProxy(config.Proxy, true)
+	if err != nil {
+		return nil, err
+	}
+
+	pw, err := playwright.Run()
+	if err != nil {
+		proxy.close()
+		return nil, fmt.Errorf("failed to start Playwright: %w", err)
+	}
+
+	browser, err := launchBrowser(pw, config.BrowserType, config.Headless, proxy, config.Stealth.launchArgs(config.BrowserType))
+	if err != nil {
+		_ = pw.Stop() // Best effort cleanup
+		proxy.close()
+		return nil, err
+	}
+
+	stealth, err := config.Stealth.resolve(browser)
+	if err != nil {
+		_ = browser.Close() // Best effort cleanup
+		_ = pw.Stop()       // Best effort cleanup
+		proxy.close()
+		return nil, err
+	}
+
+	pool := &PlaywrightPool{
+		pw:          pw,
+		browser:     browser,
+		timeout:     config.Timeout,
+		limiter:     config.RateLimiter,
+		proxy:       proxy,
+		stealth:     stealth,
+		emulate:     emulation,
+		browserType: config.BrowserType,
+		size:        config.Size,
+		slots:       make(chan *poolSlot, config.Size),
+		done:        make(chan struct{}),
+	}
+
+	for i := 0; i < config.Size; i++ {
+		slot := &poolSlot{}
+		if err := pool.warm(slot); err != nil {
+			_ = pool.Close() // Best effort cleanup
+			return nil, err
+		}
+		pool.slots <- slot
+	}
+
+	return pool, nil
+}
+
+// warm creates a fresh browser context and page for slot
+func (p *PlaywrightPool) warm(slot *poolSlot) error {
+	var opts playwright.BrowserNewContextOptions
+	p.stealth.contextOptions(&opts)
+	p.emulate.contextOptions(&opts, p.browserType)
+	browserContext, err := p.browser.NewContext(opts)
+	if err != nil {
+		return fmt.Errorf("failed to create browser context: %w", err)
+	}
+	if err := p.stealth.install(browserContext); err != nil {
+		_ = browserContext.Close() // Best effort cleanup
+		return err
+	}
+	page, err := browserContext.NewPage()
+	if err != nil {
+		_ = browserContext.Close() // Best effort cleanup
+		return fmt.Errorf("failed to create page: %w", err)
+	}
+	page.SetDefaultTimeout(float64(p.timeout.Milliseconds()))
+
+	slot.context = browserContext
+	slot.page = page
+	return nil
+}
+
+// retire closes the browser context of slot and leaves it empty
+func (p *PlaywrightPool) retire(slot *poolSlot) {
+	if slot.context != nil {
+		_ = slot.context.Close() // Best effort cleanup
+	}
+	slot.context = nil
+	slot.page = nil
+}
+
+// Checkout waits for an idle page until ctx is done
+// Every page must be returned with Checkin
+func (p *PlaywrightPool) Checkout(ctx context.Context) (*PooledPage, error) {
+	select {
+	case <-p.done:
+		return nil, ErrPoolClosed
+	default:
+	}
+
+	select {
+	case slot := <-p.slots:
+		if p.isClosed() {
+			p.retire(slot)
+			return nil, ErrPoolClosed
+		}
+		if slot.context == nil || slot.page.IsClosed() {
+			p.retire(slot)
+			if err := p.warm(slot); err != nil {
+				p.slots <- slot // Keep the slot; the next checkout retries
+				return nil, err
+			}
+		}
+		return &PooledPage{Page: slot.page, slot: slot}, nil
+	case <-p.done:
+		return nil, ErrPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Checkin returns a page to the pool
+// The page is reset to about:blank with its cookies cleared; if that fails or
+// the page was discarded, its browser context is replaced on the next checkout
+func (p *PlaywrightPool) Checkin(page *PooledPage) {
+	if page == nil || page.returned {
+		return
+	}
+	page.returned = true
+	slot := page.slot
+
+	if p.isClosed() {
+		p.retire(slot)
+		return
+	}
+
+	if page.discard || p.reset(slot) != nil {
+		p.retire(slot)
+	}
+	p.slots <- slot
+}
+
+// isClosed reports whether Close was called
+func (p *PlaywrightPool) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// reset clears the state a user left on the slot's page
+func (p *PlaywrightPool) reset(slot *poolSlot) error {
+	if slot.page.IsClosed() {
+		return fmt.Errorf("page is closed")
+	}
+	if _, err := slot.page.Goto("about:blank"); err != nil {
+		return err
+	}
+	return slot.context.ClearCookies()
+}
+
+// Render checks out a page, navigates to url and returns the rendered HTML
+func (p *PlaywrightPool) Render(ctx context.Context, url string) (string, error) {
+	var html string
+	err := p.withPage(ctx, url, func(page playwright.Page) error {
+		var err error
+		html, err = page.Content()
+		return err
+	})
+	return html, err
+}
+
+// CaptureScreenshot navigates a pooled page to url and saves a screenshot to path
+func (p *PlaywrightPool) CaptureScreenshot(ctx context.Context, url, path string) error {
+	return p.withPage(ctx, url, func(page playwright.Page) error {
+		_, err := page.Screenshot(playwright.PageScreenshotOptions{Path: &path})
+		return err
+	})
+}
+
+// withPage runs fn on a pooled page navigated to url
+func (p *PlaywrightPool) withPage(ctx context.Context, url string, fn func(playwright.Page) error) error {
+	if p.limiter != nil {
+		release, err := p.limiter.Acquire(ctx, url)
+		if err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	page, err := p.Checkout(ctx)
+	if err != nil {
+		return err
+	}
+	defer p.Checkin(page)
+
+	status, err := gotoContext(ctx, page, url)
+	p.limiter.Observe(url, status, nil)
+	if err != nil {
+		if ctx.Err() != nil {
+			page.Discard() // The page may still be loading
+		}
+		return fmt.Errorf("failed to render %s: %w", url, err)
+	}
+	return fn(page)
+}
+
+// drain retires every idle slot
+func (p *PlaywrightPool) drain() {
+	for {
+		select {
+		case slot := <-p.slots:
+			p.retire(slot)
+		default:
+			return
+		}
+	}
+}
+
+// Stats returns pool occupancy
+func (p *PlaywrightPool) Stats() PoolStats {
+	idle := len(p.slots)
+	return PoolStats{Size: p.size, Idle: idle, InUse: p.size - idle}
+}
+
+// Close closes idle contexts, the browser and Playwright
+// Pages still checked out are closed with the browser
+func (p *PlaywrightPool) Close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.done)
+	p.mu.Unlock()
+
+	p.drain()
+	defer p.proxy.close()
+
+	if err := p.browser.Close(); err != nil {
+		return err
+	}
+	return p.pw.Stop()
+}
@@ -0,0 +1,88 @@
+package crawlers
+
+import (
+	"fmt"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// MockResponse is a canned response served instead of a real request
+type MockResponse struct {
+	Status      int // Defaults to 200
+	ContentType string
+	Headers     map[string]string
+	Body        string
+}
+
+// Route registers a handler for requests whose URL matches pattern
+// pattern is a Playwright glob such as "**/api/**" or "**/*.{png,jpg}". The
+// handler must settle the request with Abort, Continue, Fallback or Fulfill;
+// when several routes match, the most recently added one runs first
+func (p *PlaywrightClient) Route(pattern string, handler func(playwright.Route)) error {
+	if err := p.page.Route(pattern, handler); err != nil {
+		return fmt.Errorf("failed to add route %s: %w", pattern, err)
+	}
+	return nil
+}
+
+// Unroute removes every route registered for pattern
+func (p *PlaywrightClient) Unroute(pattern string) error {
+	return p.page.Unroute(pattern)
+}
+
+// UnrouteAll removes every route of the client's page
+func (p *PlaywrightClient) UnrouteAll() error {
+	return p.page.UnrouteAll()
+}
+
+// BlockRequests aborts requests matching any of the patterns, e.g. analytics
+func (p *PlaywrightClient) BlockRequests(patterns ...string) error {
+	for _, pattern := range patterns {
+		if err := p.Route(pattern, abortRoute); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MockRequests answers requests matching pattern with a canned response
+// without touching the network, e.g. to stub an API during rendering
+func (p *PlaywrightClient) MockRequests(pattern string, mock MockResponse) error {
+	return p.Route(pattern, func(route playwright.Route) {
+		_ = route.Fulfill(mock.fulfillOptions()) // Error intentionally ignored; the page is gone
+	})
+}
+
+// SetRequestHeaders adds or overrides headers of requests matching pattern
+// Other routes matching the request still run afterwards
+func (p *PlaywrightClient) SetRequestHeaders(pattern string, headers map[string]string) error {
+	return p.Route(pattern, func(route playwright.Route) {
+		merged := route.Request().Headers()
+		for name, value := range headers {
+			merged[name] = value
+		}
+		_ = route.Fallback(playwright.RouteFallbackOptions{Headers: merged}) // Error intentionally ignored; the page is gone
+	})
+}
+
+// abortRoute fails a request as if the client had blocked it
+func abortRoute(route playwright.Route) {
+	_ = route.Abort("blockedbyclient") // Error intentionally ignored; the page is gone
+}
+
+// fulfillOptions converts the mock into Playwright fulfill options
+func (m MockResponse) fulfillOptions() playwright.RouteFulfillOptions {
+	status := m.Status
+	if status == 0 {
+		status = 200
+	}
+	opts := playwright.RouteFulfillOptions{
+		Status:  &status,
+		Headers: m.Headers,
+		Body:    m.Body,
+	}
+	if m.ContentType != "" {
+		opts.ContentType = &m.ContentType
+	}
+	return opts
+}
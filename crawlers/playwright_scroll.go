@@ -0,0 +1,175 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+const (
+	// networkIdleQuiet is how long the network must stay quiet after a scroll
+	networkIdleQuiet = 500 * time.Millisecond
+	// scrollStableRounds is how many scrolls in a row must leave the page
+	// height unchanged before it counts as fully loaded
+	scrollStableRounds = 2
+)
+
+// scrollScript scrolls to the bottom and returns the new document height
+const scrollScript = `() => {
+	const height = Math.max(document.body ? document.body.scrollHeight : 0, document.documentElement.scrollHeight);
+	window.scrollTo(0, height);
+	return height;
+}`
+
+// heightScript returns the document height
+const heightScript = `() => Math.max(document.body ? document.body.scrollHeight : 0, document.documentElement.scrollHeight)`
+
+// networkMonitor counts a page's requests in flight
+type networkMonitor struct {
+	mu       sync.Mutex
+	inFlight int
+	lastSeen time.Time     // Last request start or end
+	changed  chan struct{} // Closed and replaced on every change
+}
+
+// watchNetwork starts counting the requests of page
+func watchNetwork(page playwright.Page) *networkMonitor {
+	m := &networkMonitor{lastSeen: time.Now(), changed: make(chan struct{})}
+	page.OnRequest(func(playwright.Request) { m.add(1) })
+	page.OnRequestFinished(func(playwright.Request) { m.add(-1) })
+	page.OnRequestFailed(func(playwright.Request) { m.add(-1) })
+	return m
+}
+
+// add records a request starting (1) or ending (-1)
+func (m *networkMonitor) add(delta int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inFlight += delta
+	if m.inFlight < 0 {
+		m.inFlight = 0 // Requests started before the monitor was attached
+	}
+	m.lastSeen = time.Now()
+	close(m.changed)
+	m.changed = make(chan struct{})
+}
+
+// waitIdle returns once no request has been in flight for quiet
+func (m *networkMonitor) waitIdle(ctx context.Context, quiet time.Duration) error {
+	for {
+		m.mu.Lock()
+		inFlight, wait, changed := m.inFlight, quiet-time.Since(m.lastSeen), m.changed
+		m.mu.Unlock()
+		if inFlight == 0 && wait <= 0 {
+			return nil
+		}
+
+		var timer *time.Timer
+		var expired <-chan time.Time
+		if inFlight == 0 {
+			timer = time.NewTimer(wait)
+			expired = timer.C
+		}
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-changed:
+		case <-expired:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// WaitForNetworkIdle waits until the page has had no request in flight for
+// quiet, e.g. after an interaction that lazy-loads content
+// Unlike the networkidle load state it also works after navigation ended
+// Pages that keep a request open (long polling) never go idle, so bound the
+// wait with a ctx deadline
+func (p *PlaywrightClient) WaitForNetworkIdle(ctx context.Context, quiet time.Duration) error {
+	if quiet <= 0 {
+		quiet = networkIdleQuiet
+	}
+	return p.network.waitIdle(ctx, quiet)
+}
+
+// ScrollToBottomUntilStable scrolls an infinite-scroll page until it stops
+// growing so GetContent sees every lazy-loaded item
+// It returns the number of scrolls made
+func (p *PlaywrightClient) ScrollToBottomUntilStable(maxScrolls int, waitBetween time.Duration) (int, error) {
+	return p.ScrollToBottomUntilStableContext(p.ctx, maxScrolls, waitBetween)
+}
+
+// ScrollToBottomUntilStableContext scrolls to the bottom of the page, waits
+// waitBetween (default 500ms) and for the network to go idle, and repeats
+// until the page height stops changing or maxScrolls (default 50) is reached
+// Waiting for the network is bounded by the client timeout; pages that never
+// go idle are scrolled at waitBetween intervals
+func (p *PlaywrightClient) ScrollToBottomUntilStableContext(ctx context.Context, maxScrolls int, waitBetween time.Duration) (int, error) {
+	if maxScrolls <= 0 {
+		maxScrolls = 50
+	}
+	if waitBetween <= 0 {
+		waitBetween = 500 * time.Millisecond
+	}
+
+	stable := 0
+	for scrolls := 1; scrolls <= maxScrolls; scrolls++ {
+		before, err := p.evaluateHeight(scrollScript)
+		if err != nil {
+			return scrolls - 1, fmt.Errorf("failed to scroll: %w", err)
+		}
+
+		timer := time.NewTimer(waitBetween)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return scrolls, ctx.Err()
+		case <-timer.C:
+		}
+
+		idleCtx, cancel := context.WithTimeout(ctx, p.timeout)
+		err = p.network.waitIdle(idleCtx, networkIdleQuiet)
+		cancel()
+		if err != nil && ctx.Err() != nil {
+			return scrolls, ctx.Err()
+		}
+
+		after, err := p.evaluateHeight(heightScript)
+		if err != nil {
+			return scrolls, fmt.Errorf("failed to read page height: %w", err)
+		}
+		if after > before {
+			stable = 0
+			continue
+		}
+		if stable++; stable >= scrollStableRounds {
+			return scrolls, nil
+		}
+	}
+	return maxScrolls, nil
+}
+
+// evaluateHeight runs a script that returns a page height in pixels
+func (p *PlaywrightClient) evaluateHeight(script string) (int, error) {
+	result, err := p.page.Evaluate(script)
+	if err != nil {
+		return 0, err
+	}
+	switch h := result.(type) {
+	case int:
+		return h, nil
+	case int64:
+		return int(h), nil
+	case float64:
+		return int(h), nil
+	}
+	return 0, fmt.Errorf("unexpected page height %v", result)
+}
@@ -0,0 +1,166 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// SessionOptions holds settings for a Playwright session
+type SessionOptions struct {
+	Timeout          time.Duration     // Default timeout of page operations (default: the client's timeout)
+	UserAgent        string            // Overrides the browser's user agent
+	ExtraHTTPHeaders map[string]string // Sent with every request of the session
+	ViewportWidth    int               // Viewport size; both must be set to take effect
+	ViewportHeight   int
+	StatePath        string // Encrypted storage state to start from, see SaveSessionState
+	StatePassphrase  string // Passphrase of StatePath
+}
+
+// PlaywrightSession is an independent page in its own browser context
+// Sessions of one client share the browser and its rate limits but not
+// cookies, storage or timeouts, so they can be driven concurrently
+type PlaywrightSession struct {
+	client  *PlaywrightClient
+	context playwright.BrowserContext
+	page    playwright.Page
+}
+
+// NewSession opens a new browser context with one page
+// The session must be closed with Close
+func (p *PlaywrightClient) NewSession(opts SessionOptions) (*PlaywrightSession, error) {
+	var contextOpts playwright.BrowserNewContextOptions
+	if opts.UserAgent != "" {
+		contextOpts.UserAgent = playwright.String(opts.UserAgent)
+	}
+	if len(opts.ExtraHTTPHeaders) > 0 {
+		contextOpts.ExtraHttpHeaders = opts.ExtraHTTPHeaders
+	}
+	if opts.ViewportWidth > 0 && opts.ViewportHeight > 0 {
+		contextOpts.Viewport = &playwright.Size{Width: opts.ViewportWidth, Height: opts.ViewportHeight}
+	}
+	if opts.StatePath != "" {
+		state, err := LoadSessionState(opts.StatePath, opts.StatePassphrase)
+		if err != nil {
+			return nil, err
+		}
+		contextOpts.StorageState = state.ToOptionalStorageState()
+	}
+
+	browserContext, err := p.browser.NewContext(contextOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create browser context: %w", err)
+	}
+
+	page, err := browserContext.NewPage()
+	if err != nil {
+		_ = browserContext.Close() // Best effort cleanup
+		return nil, fmt.Errorf("failed to create page: %w", err)
+	}
+
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = p.timeout
+	}
+	if timeout > 0 {
+		page.SetDefaultTimeout(float64(timeout.Milliseconds()))
+	}
+
+	return &PlaywrightSession{
+		client:  p,
+		context: browserContext,
+		page:    page,
+	}, nil
+}
+
+// Navigate navigates to a URL with the client's rate limiting
+func (s *PlaywrightSession) Navigate(url string) error {
+	return s.NavigateContext(context.Background(), url)
+}
+
+// NavigateContext navigates to a URL, giving up when ctx is done
+func (s *PlaywrightSession) NavigateContext(ctx context.Context, url string) error {
+	release, err := s.client.throttle(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	_, err = s.client.gotoRecorded(ctx, s.page, url)
+	return err
+}
+
+// Locator returns a Playwright Locator for the given selector
+func (s *PlaywrightSession) Locator(selector string) playwright.Locator {
+	return s.page.Locator(selector)
+}
+
+// Evaluate executes JavaScript code
+func (s *PlaywrightSession) Evaluate(script string) (interface{}, error) {
+	return s.page.Evaluate(script)
+}
+
+// GetContent gets the HTML content of the page
+func (s *PlaywrightSession) GetContent() (string, error) {
+	return s.page.Content()
+}
+
+// GetTitle gets the page title
+func (s *PlaywrightSession) GetTitle() (string, error) {
+	return s.page.Title()
+}
+
+// GetURL gets the current URL
+func (s *PlaywrightSession) GetURL() string {
+	return s.page.URL()
+}
+
+// Screenshot takes a screenshot
+func (s *PlaywrightSession) Screenshot(path string) error {
+	_, err := s.page.Screenshot(playwright.PageScreenshotOptions{
+		Path: &path,
+	})
+	return err
+}
+
+// SetTimeout changes the default timeout of the session's page operations
+func (s *PlaywrightSession) SetTimeout(timeout time.Duration) {
+	s.page.SetDefaultTimeout(float64(timeout.Milliseconds()))
+}
+
+// AddCookie adds a cookie to the session
+func (s *PlaywrightSession) AddCookie(name, value, domain string) error {
+	return s.context.AddCookies([]playwright.OptionalCookie{{
+		Name:   name,
+		Value:  value,
+		Domain: &domain,
+		Path:   playwright.String("/"),
+	}})
+}
+
+// GetCookies gets all cookies of the session
+func (s *PlaywrightSession) GetCookies() ([]playwright.Cookie, error) {
+	return s.context.Cookies()
+}
+
+// ClearCookies removes all cookies of the session
+func (s *PlaywrightSession) ClearCookies() error {
+	return s.context.ClearCookies()
+}
+
+// GetPage returns the session's page for advanced operations
+func (s *PlaywrightSession) GetPage() playwright.Page {
+	return s.page
+}
+
+// GetContext returns the session's browser context
+func (s *PlaywrightSession) GetContext() playwright.BrowserContext {
+	return s.context
+}
+
+// Close closes the session's page and browser context
+func (s *PlaywrightSession) Close() error {
+	return s.context.Close()
+}
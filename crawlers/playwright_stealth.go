@@ -0,0 +1,181 @@
+package crawlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// StealthConfig makes headless Playwright pages harder to fingerprint as
+// automation. The overrides apply whenever set; Enabled adds the masking
+// patches, which most bot walls check first
+type StealthConfig struct {
+	// Enabled masks navigator.webdriver, the HeadlessChrome user agent, the
+	// missing window.chrome object and the notification permission mismatch
+	// of headless Chromium
+	Enabled bool
+
+	UserAgent      string // Replaces the browser's user agent
+	ViewportWidth  int    // Page size in CSS pixels; both must be set (Playwright default 1280x720)
+	ViewportHeight int
+	Locale         string // e.g. de-DE; sets navigator.language(s) and Accept-Language
+	TimezoneID     string // IANA zone such as Europe/Berlin
+	Platform       string // navigator.platform, e.g. Win32; should match UserAgent
+	WebGLVendor    string // Unmasked WebGL vendor, e.g. "Intel Inc."
+	WebGLRenderer  string // Unmasked WebGL renderer, e.g. "Intel Iris OpenGL Engine"
+}
+
+// stealthLaunchArgs stop Chromium from announcing automation
+var stealthLaunchArgs = []string{"--disable-blink-features=AutomationControlled"}
+
+// stealthScript patches the navigator and WebGL before any page script runs;
+// %s is the JSON of stealthOptions
+const stealthScript = `((opts) => {
+	const define = (target, prop, value) => {
+		try {
+			Object.defineProperty(target, prop, { get: () => value, configurable: true });
+		} catch (e) {}
+	};
+	if (opts.mask) {
+		define(Navigator.prototype, 'webdriver', false);
+		if (!window.chrome) {
+			window.chrome = { runtime: {}, app: { isInstalled: false } };
+		}
+		const permissions = window.navigator.permissions;
+		if (permissions && permissions.query) {
+			const query = permissions.query.bind(permissions);
+			permissions.query = (params) => params && params.name === 'notifications'
+				? Promise.resolve({ state: Notification.permission, onchange: null })
+				: query(params);
+		}
+	}
+	if (opts.languages) define(Navigator.prototype, 'languages', Object.freeze(opts.languages));
+	if (opts.platform) define(Navigator.prototype, 'platform', opts.platform);
+	if (opts.webglVendor || opts.webglRenderer) {
+		for (const context of [window.WebGLRenderingContext, window.WebGL2RenderingContext]) {
+			if (!context) continue;
+			const getParameter = context.prototype.getParameter;
+			context.prototype.getParameter = function (param) {
+				if (param === 37445 && opts.webglVendor) return opts.webglVendor; // UNMASKED_VENDOR_WEBGL
+				if (param === 37446 && opts.webglRenderer) return opts.webglRenderer; // UNMASKED_RENDERER_WEBGL
+				return getParameter.call(this, param);
+			};
+		}
+	}
+})(%s);`
+
+// stealthOptions are the settings stealthScript reads
+type stealthOptions struct {
+	Mask          bool     `json:"mask"`
+	Languages     []string `json:"languages,omitempty"`
+	Platform      string   `json:"platform,omitempty"`
+	WebGLVendor   string   `json:"webglVendor,omitempty"`
+	WebGLRenderer string   `json:"webglRenderer,omitempty"`
+}
+
+// launchArgs returns the extra browser arguments of the config
+func (s StealthConfig) launchArgs(browserType string) []string {
+	if s.Enabled && browserType == "chromium" {
+		return stealthLaunchArgs
+	}
+	return nil
+}
+
+// resolve fills in the user agent of an enabled config that sets none with
+// the browser's own, minus the "Headless" marker
+func (s StealthConfig) resolve(browser playwright.Browser) (StealthConfig, error) {
+	if !s.Enabled || s.UserAgent != "" {
+		return s, nil
+	}
+	page, err := browser.NewPage()
+	if err != nil {
+		return s, fmt.Errorf("failed to read the browser user agent: %w", err)
+	}
+	defer func() {
+		_ = page.Close() // Best effort cleanup
+	}()
+
+	userAgent, err := page.Evaluate("() => navigator.userAgent")
+	if err != nil {
+		return s, fmt.Errorf("failed to read the browser user agent: %w", err)
+	}
+	if ua, ok := userAgent.(string); ok && strings.Contains(ua, "HeadlessChrome") {
+		s.UserAgent = strings.Replace(ua, "HeadlessChrome", "Chrome", 1)
+	}
+	return s, nil
+}
+
+// pageOptions applies the overrides to options of a new page
+func (s StealthConfig) pageOptions(opts *playwright.BrowserNewPageOptions) {
+	if s.UserAgent != "" {
+		opts.UserAgent = playwright.String(s.UserAgent)
+	}
+	if s.ViewportWidth > 0 && s.ViewportHeight > 0 {
+		opts.Viewport = &playwright.Size{Width: s.ViewportWidth, Height: s.ViewportHeight}
+		opts.Screen = &playwright.Size{Width: s.ViewportWidth, Height: s.ViewportHeight}
+	}
+	if s.Locale != "" {
+		opts.Locale = playwright.String(s.Locale)
+	}
+	if s.TimezoneID != "" {
+		opts.TimezoneId = playwright.String(s.TimezoneID)
+	}
+}
+
+// contextOptions applies the overrides to options of a new browser context
+func (s StealthConfig) contextOptions(opts *playwright.BrowserNewContextOptions) {
+	var page playwright.BrowserNewPageOptions
+	s.pageOptions(&page)
+	opts.UserAgent = page.UserAgent
+	opts.Viewport = page.Viewport
+	opts.Screen = page.Screen
+	opts.Locale = page.Locale
+	opts.TimezoneId = page.TimezoneId
+}
+
+// script returns the init script of the config, or "" when no page patch is needed
+func (s StealthConfig) script() (string, error) {
+	opts := stealthOptions{
+		Mask:          s.Enabled,
+		Languages:     localeLanguages(s.Locale),
+		Platform:      s.Platform,
+		WebGLVendor:   s.WebGLVendor,
+		WebGLRenderer: s.WebGLRenderer,
+	}
+	if !opts.Mask && opts.Languages == nil && opts.Platform == "" && opts.WebGLVendor == "" && opts.WebGLRenderer == "" {
+		return "", nil
+	}
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(stealthScript, data), nil
+}
+
+// install adds the init script to a page or browser context, so it runs
+// before the scripts of every document loaded there
+func (s StealthConfig) install(target interface{ AddInitScript(playwright.Script) error }) error {
+	script, err := s.script()
+	if err != nil || script == "" {
+		return err
+	}
+	if err := target.AddInitScript(playwright.Script{Content: playwright.String(script)}); err != nil {
+		return fmt.Errorf("failed to install stealth script: %w", err)
+	}
+	return nil
+}
+
+// localeLanguages returns navigator.languages for a locale: the locale
+// followed by its bare language, e.g. [de-DE de]
+func localeLanguages(locale string) []string {
+	if locale == "" {
+		return nil
+	}
+	languages := []string{locale}
+	if language, _, ok := strings.Cut(locale, "-"); ok && language != "" {
+		languages = append(languages, language)
+	}
+	return languages
+}
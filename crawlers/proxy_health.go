@@ -0,0 +1,209 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+)
+
+// ProxyHealthConfig holds proxy health probe settings
+type ProxyHealthConfig struct {
+	// CanaryURL is fetched through every proxy on each round, e.g. a small
+	// page of your own that always answers 200; required
+	CanaryURL string
+
+	Interval     time.Duration // Between probe rounds (default 1m)
+	Timeout      time.Duration // Per probe (default 10s)
+	Concurrency  int           // Proxies probed at once (default 8)
+	ExpectStatus int           // Status a passing probe returns; 0 accepts any 2xx
+	RecoverAfter int           // Passed probes in a row that bring a removed proxy back (default 2)
+
+	// Transport is cloned for probes; its Proxy is replaced and keep-alives
+	// are disabled, so every probe measures a fresh connection. Defaults to
+	// http.DefaultTransport
+	Transport *http.Transport
+
+	Clock clock.Clock // Defaults to the wall clock
+}
+
+// MonitorHealth probes every proxy of the pool, removed ones included,
+// against the canary URL each interval until ctx is done
+//
+// A probe counts like a request: it updates the proxy's success rate and
+// latency, and therefore its score, and MaxFailures failed probes in a row
+// remove a proxy from rotation. A removed proxy returns after RecoverAfter
+// passed probes in a row, so proxies that died and came back are used again
+// Returns an error only for an invalid configuration
+func (p *ProxyPool) MonitorHealth(ctx context.Context, config ProxyHealthConfig) error {
+	prober, err := newProxyProber(p, config)
+	if err != nil {
+		return err
+	}
+
+	ticker := prober.clock.NewTicker(prober.interval)
+	defer ticker.Stop()
+	for {
+		prober.probeAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+		}
+	}
+}
+
+// CheckHealth probes every proxy of the pool once, like a round of
+// MonitorHealth, and returns the resulting stats
+func (p *ProxyPool) CheckHealth(ctx context.Context, config ProxyHealthConfig) ([]ProxyStats, error) {
+	prober, err := newProxyProber(p, config)
+	if err != nil {
+		return nil, err
+	}
+	prober.probeAll(ctx)
+	return p.Stats(), nil
+}
+
+// proxyProber sends health probes through a pool's proxies
+type proxyProber struct {
+	pool         *ProxyPool
+	canary       string
+	interval     time.Duration
+	concurrency  int
+	expectStatus int
+	recoverAfter int
+	clock        clock.Clock
+	client       *http.Client
+}
+
+// newProxyProber validates config and builds a prober
+func newProxyProber(pool *ProxyPool, config ProxyHealthConfig) (*proxyProber, error) {
+	// The canary may well be an internal service
+	if err := validateURL(config.CanaryURL, libs.ValidationPolicy{AllowPrivate: true}); err != nil {
+		return nil, errs.Wrap(err, errs.CodeInvalidConfig, "invalid proxy health canary URL")
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.Concurrency <= 0 {
+		config.Concurrency = 8
+	}
+	if config.RecoverAfter <= 0 {
+		config.RecoverAfter = 2
+	}
+	if config.Transport == nil {
+		config.Transport = http.DefaultTransport.(*http.Transport)
+	}
+
+	transport := config.Transport.Clone()
+	transport.Proxy = contextProxy
+	transport.DisableKeepAlives = true
+	return &proxyProber{
+		pool:         pool,
+		canary:       config.CanaryURL,
+		interval:     config.Interval,
+		concurrency:  config.Concurrency,
+		expectStatus: config.ExpectStatus,
+		recoverAfter: config.RecoverAfter,
+		clock:        clock.Or(config.Clock),
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse // The canary itself must answer
+			},
+		},
+	}, nil
+}
+
+// probeAll probes every proxy with up to concurrency probes at once
+func (pr *proxyProber) probeAll(ctx context.Context) {
+	pr.pool.mu.Lock()
+	states := append([]*proxyState(nil), pr.pool.proxies...)
+	pr.pool.mu.Unlock()
+
+	slots := make(chan struct{}, pr.concurrency)
+	var wg sync.WaitGroup
+	for _, state := range states {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			pr.probe(ctx, state)
+		}()
+	}
+	wg.Wait()
+}
+
+// probe fetches the canary through one proxy and records the outcome
+func (pr *proxyProber) probe(ctx context.Context, state *proxyState) {
+	req, err := http.NewRequestWithContext(context.WithValue(ctx, proxyContextKey{}, state.url), http.MethodGet, pr.canary, nil)
+	if err != nil {
+		return
+	}
+	start := pr.clock.Now()
+	resp, err := pr.client.Do(req)
+	latency := pr.clock.Since(start)
+	if err == nil {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		_ = resp.Body.Close()
+		if !pr.passes(resp.StatusCode) {
+			err = fmt.Errorf("canary returned status %d", resp.StatusCode)
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		return // Stopped, not failed
+	}
+
+	pool := pr.pool
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	state.probes++
+	state.lastProbe = pr.clock.Now()
+	state.observe(err == nil, latency)
+	if err != nil {
+		state.probeFailures++
+		state.probeStreak = 0
+		state.lastError = err.Error()
+		pool.fail(state)
+		return
+	}
+
+	state.lastError = ""
+	state.consecutive = 0
+	if state.removed {
+		state.probeStreak++
+		if state.probeStreak >= pr.recoverAfter {
+			// Back with a clean record; the moving averages of its dead
+			// period would keep it out of scored rotation for long
+			state.removed = false
+			state.probeStreak = 0
+			state.successRate = 1
+		}
+	}
+}
+
+// passes reports whether a canary status means the proxy works
+func (pr *proxyProber) passes(status int) bool {
+	if pr.expectStatus != 0 {
+		return status == pr.expectStatus
+	}
+	return status >= 200 && status < 300
+}
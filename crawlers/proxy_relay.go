@@ -0,0 +1,267 @@
+package crawlers
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/proxy"
+)
+
+// proxyDefaultPorts are used when a proxy URL has no port
+var proxyDefaultPorts = map[string]string{"http": "80", "https": "443", "socks5": "1080", "socks5h": "1080"}
+
+// hopHeaders are connection-specific headers a proxy must not forward
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// ProxyRelay is a local HTTP proxy without authentication that forwards
+// every request through an upstream proxy
+// Browsers cannot authenticate to SOCKS5 proxies, and chromedp and Selenium
+// not to HTTP ones either, so headless clients launch the browser against a
+// relay that holds the credentials
+type ProxyRelay struct {
+	upstream  *url.URL
+	address   string // Upstream host:port
+	listener  net.Listener
+	server    *http.Server
+	transport *http.Transport
+}
+
+// NewProxyRelay starts a relay on a random loopback port
+func NewProxyRelay(upstream string) (*ProxyRelay, error) {
+	parsed, err := ParseProxyURL(upstream)
+	if err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start proxy relay: %w", err)
+	}
+
+	address := parsed.Host
+	if parsed.Port() == "" {
+		address = net.JoinHostPort(parsed.Hostname(), proxyDefaultPorts[parsed.Scheme])
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyURL(parsed)
+
+	r := &ProxyRelay{
+		upstream:  parsed,
+		address:   address,
+		listener:  listener,
+		transport: transport,
+	}
+	r.server = &http.Server{Handler: r, ReadHeaderTimeout: 30 * time.Second}
+	go func() {
+		_ = r.server.Serve(listener) // Returns http.ErrServerClosed on Close
+	}()
+	return r, nil
+}
+
+// URL returns the address browsers are pointed at, e.g. http://127.0.0.1:41234
+func (r *ProxyRelay) URL() string {
+	return "http://" + r.listener.Addr().String()
+}
+
+// Close stops the relay and drops its connections
+func (r *ProxyRelay) Close() error {
+	r.transport.CloseIdleConnections()
+	return r.server.Close()
+}
+
+// ServeHTTP implements http.Handler
+func (r *ProxyRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodConnect {
+		r.tunnel(w, req)
+		return
+	}
+	if !req.URL.IsAbs() {
+		http.Error(w, "proxy relay requires an absolute URL", http.StatusBadRequest)
+		return
+	}
+
+	out := req.Clone(req.Context())
+	out.RequestURI = ""
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+
+	resp, err := r.transport.RoundTrip(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	for _, h := range hopHeaders {
+		resp.Header.Del(h)
+	}
+	for key, values := range resp.Header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, resp.Body) // The browser sees a truncated body on error
+}
+
+// tunnel serves a CONNECT request by opening a tunnel through the upstream
+func (r *ProxyRelay) tunnel(w http.ResponseWriter, req *http.Request) {
+	upstream, err := r.dial(req.Context(), req.Host)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		_ = upstream.Close() // Best effort cleanup
+		http.Error(w, "proxy relay cannot hijack the connection", http.StatusInternalServerError)
+		return
+	}
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		_ = upstream.Close() // Best effort cleanup
+		return
+	}
+	defer func() {
+		_ = client.Close()   // Error intentionally ignored on close
+		_ = upstream.Close() // Error intentionally ignored on close
+	}()
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(upstream, buffered) // Includes bytes read ahead by the server
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(client, upstream)
+		done <- struct{}{}
+	}()
+	<-done // Either side closing ends the tunnel
+}
+
+// dial connects to addr through the upstream proxy
+func (r *ProxyRelay) dial(ctx context.Context, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+
+	if r.upstream.Scheme == "socks5" || r.upstream.Scheme == "socks5h" {
+		socks, err := proxy.FromURL(r.upstream, dialer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure SOCKS5 proxy: %w", err)
+		}
+		conn, err := socks.(proxy.ContextDialer).DialContext(ctx, "tcp", addr) // SOCKS5 dialers support contexts
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect through SOCKS5 proxy: %w", err)
+		}
+		return conn, nil
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", r.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to proxy: %w", err)
+	}
+	if r.upstream.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: r.upstream.Hostname(), MinVersion: tls.VersionTLS12})
+	}
+	if err := r.connect(conn, addr); err != nil {
+		_ = conn.Close() // Best effort cleanup
+		return nil, err
+	}
+	return conn, nil
+}
+
+// connect asks an HTTP proxy on conn to open a tunnel to addr
+func (r *ProxyRelay) connect(conn net.Conn, addr string) error {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: http.Header{},
+	}
+	if user := r.upstream.User; user != nil {
+		password, _ := user.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(user.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err := req.Write(conn); err != nil {
+		return fmt.Errorf("failed to send CONNECT: %w", err)
+	}
+
+	// The proxy sends nothing after its answer until the client speaks, so
+	// the reader cannot swallow tunneled bytes
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		return fmt.Errorf("failed to read CONNECT response: %w", err)
+	}
+	_ = resp.Body.Close() // Error intentionally ignored on close
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("proxy refused CONNECT to %s: %s", addr, resp.Status)
+	}
+	return nil
+}
+
+// browserProxy is the proxy a headless browser is launched with
+type browserProxy struct {
+	server   string // Proxy server without credentials
+	username string // Set only when the browser authenticates itself
+	password string
+	relay    *ProxyRelay // Holds credentials the browser cannot use
+}
+
+// newBrowserProxy prepares a proxy URL for a browser; nil when raw is empty
+// httpAuth reports whether the browser can authenticate to HTTP proxies;
+// other credentials are handled by a local relay
+func newBrowserProxy(raw string, httpAuth bool) (*browserProxy, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := ParseProxyURL(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsed.User == nil {
+		server := *parsed
+		if server.Scheme == "socks5h" {
+			server.Scheme = "socks5" // Browsers resolve through SOCKS5 proxies anyway
+		}
+		return &browserProxy{server: server.String()}, nil
+	}
+
+	if httpAuth && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		password, _ := parsed.User.Password()
+		server := *parsed
+		server.User = nil
+		return &browserProxy{server: server.String(), username: parsed.User.Username(), password: password}, nil
+	}
+
+	relay, err := NewProxyRelay(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &browserProxy{server: relay.URL(), relay: relay}, nil
+}
+
+// close stops the relay, if any
+func (p *browserProxy) close() {
+	if p != nil && p.relay != nil {
+		_ = p.relay.Close() // Best effort cleanup
+	}
+}
@@ -5,42 +5,138 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 )
 
 // PuppeteerClient wraps chromedp (Chrome DevTools Protocol) operations
-// Provides a Puppeteer-like API for Go
+// Provides a Puppeteer-like API for Go. Its methods drive one default tab;
+// Checkout hands out further tabs of the same browser for concurrent pages
 type PuppeteerClient struct {
-	ctx    context.Context
-	cancel context.CancelFunc
+	ctx         context.Context
+	cancel      context.CancelFunc
+	allocCancel context.CancelFunc
+	timeout     time.Duration
+	blocker     *ResourceBlocker
+	emulate     emulation
+	proxy       *browserProxy
+	metrics     Metrics
+	tabs        *tabPool
 }
 
 // PuppeteerConfig holds Puppeteer client configuration
 type PuppeteerConfig struct {
 	Headless bool
-	Timeout  time.Duration
+	Timeout  time.Duration // Limit of each navigation and action; the client lives until Close
+	Tabs     int           // Tabs Checkout and PuppeteerCrawler use concurrently (default 1)
+	Proxy    string        // Optional http, https or socks5 proxy URL, credentials included
+
+	// Request interception; all empty disables it
+	BlockResourceTypes []string // e.g. image, font, stylesheet, media
+	BlockURLPatterns   []string // Wildcard URL patterns, e.g. *://*.example-ads.com/*
+	BlockTrackers      bool     // Also block DefaultTrackerPatterns
+
+	// Metrics records every navigation by status; optional, e.g. *libs.Metrics
+	Metrics Metrics
+
+	// Emulation picks device and region presets, e.g. to crawl mobile sites
+	Emulation EmulationConfig
 }
 
 // NewPuppeteerClient creates a new chromedp-based client (Puppeteer-like)
 func NewPuppeteerClient(config PuppeteerConfig) (*PuppeteerClient, error) {
+	if config.Tabs <= 0 {
+		config.Tabs = 1
+	}
+
+	emulation, err := config.Emulation.resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := newBrowserProxy(config.Proxy, false)
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := config.BlockURLPatterns
+	if config.BlockTrackers {
+		patterns = append(append([]string(nil), patterns...), DefaultTrackerPatterns...)
+	}
+	blocker, err := NewResourceBlocker(config.BlockResourceTypes, patterns)
+	if err != nil {
+		proxy.close()
+		return nil, err
+	}
+
 	opts := append(chromedp.DefaultExecAllocatorOptions[:],
 		chromedp.Flag("headless", config.Headless),
 		chromedp.Flag("disable-gpu", true),
 		chromedp.Flag("no-sandbox", true),
 		chromedp.Flag("disable-dev-shm-usage", true),
 	)
+	if proxy != nil {
+		opts = append(opts, chromedp.ProxyServer(proxy.server))
+	}
 
-	allocCtx, _ := chromedp.NewExecAllocator(context.Background(), opts...)
+	allocCtx, allocCancel := chromedp.NewExecAllocator(context.Background(), opts...)
 	ctx, cancel := chromedp.NewContext(allocCtx)
 
-	if config.Timeout > 0 {
-		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+	client := &PuppeteerClient{
+		ctx:         ctx,
+		cancel:      cancel,
+		allocCancel: allocCancel,
+		timeout:     config.Timeout,
+		blocker:     blocker,
+		emulate:     emulation,
+		proxy:       proxy,
+		metrics:     config.Metrics,
+	}
+	client.tabs = newTabPool(client, config.Tabs)
+	if err := client.prepare(ctx); err != nil {
+		_ = client.Close() // Best effort cleanup
+		return nil, err
 	}
+	return client, nil
+}
 
-	return &PuppeteerClient{
-		ctx:    ctx,
-		cancel: cancel,
-	}, nil
+// prepare sets up request interception and emulation in the tab of ctx
+// This starts the browser when either is configured
+func (p *PuppeteerClient) prepare(ctx context.Context) error {
+	if !p.blocker.Empty() {
+		if err := p.enableBlocking(ctx); err != nil {
+			return fmt.Errorf("failed to enable request interception: %w", err)
+		}
+	}
+	return p.emulate.apply(ctx)
+}
+
+// enableBlocking pauses every request of the tab of ctx and aborts those
+// the blocker matches
+func (p *PuppeteerClient) enableBlocking(ctx context.Context) error {
+	chromedp.ListenTarget(ctx, func(ev interface{}) {
+		paused, ok := ev.(*fetch.EventRequestPaused)
+		if !ok {
+			return
+		}
+		// Listeners must not block, so the decision is sent asynchronously
+		go func() {
+			execCtx := cdp.WithExecutor(ctx, chromedp.FromContext(ctx).Target)
+			if p.blocker.Blocks(paused.Request.URL, string(paused.ResourceType)) {
+				_ = fetch.FailRequest(paused.RequestID, network.ErrorReasonBlockedByClient).Do(execCtx) // Best effort; the page may be gone
+			} else {
+				_ = fetch.ContinueRequest(paused.RequestID).Do(execCtx) // Best effort; the page may be gone
+			}
+		}()
+	})
+	return chromedp.Run(ctx, fetch.Enable())
+}
+
+// BlockedRequests returns how many requests request interception aborted
+func (p *PuppeteerClient) BlockedRequests() int64 {
+	return p.blocker.Blocked()
 }
 
 // NewDefaultPuppeteerClient creates a Puppeteer client with default settings
@@ -53,49 +149,94 @@ func NewDefaultPuppeteerClient() (*PuppeteerClient, error) {
 
 // Navigate navigates to a URL
 func (p *PuppeteerClient) Navigate(url string) error {
-	return chromedp.Run(p.ctx, chromedp.Navigate(url))
+	_, err := p.navigate(context.Background(), p.ctx, url)
+	return err
+}
+
+// NavigateContext navigates to a URL, giving up when ctx is done
+func (p *PuppeteerClient) NavigateContext(ctx context.Context, url string) error {
+	_, err := p.navigate(ctx, p.ctx, url)
+	return err
+}
+
+// navigate navigates the tab of tabCtx to a URL, giving up when ctx is done
+// or after the configured timeout, and returns the main response
+func (p *PuppeteerClient) navigate(ctx, tabCtx context.Context, url string) (*network.Response, error) {
+	// Actions must run on a context derived from the tab's
+	runCtx, cancel := p.actionContext(tabCtx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+
+	start := time.Now()
+	resp, err := chromedp.RunResponse(runCtx, chromedp.Navigate(url))
+	if err != nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	status := 0
+	if resp != nil {
+		status = int(resp.Status)
+	}
+	recordRequest(p.metrics, CrawlerTypePuppeteer, start, status, err)
+	return resp, err
+}
+
+// actionContext derives the context of one navigation or action from the
+// tab of tabCtx; cancelling it leaves the tab open
+func (p *PuppeteerClient) actionContext(tabCtx context.Context) (context.Context, context.CancelFunc) {
+	if p.timeout > 0 {
+		return context.WithTimeout(tabCtx, p.timeout)
+	}
+	return context.WithCancel(tabCtx)
+}
+
+// run runs actions in the default tab within the configured timeout
+func (p *PuppeteerClient) run(actions ...chromedp.Action) error {
+	ctx, cancel := p.actionContext(p.ctx)
+	defer cancel()
+	return chromedp.Run(ctx, actions...)
 }
 
 // Click clicks an element
 func (p *PuppeteerClient) Click(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.Click(selector))
+	return p.run(chromedp.Click(selector))
 }
 
 // SendKeys sends keys to an element
 func (p *PuppeteerClient) SendKeys(selector, keys string) error {
-	return chromedp.Run(p.ctx, chromedp.SendKeys(selector, keys))
+	return p.run(chromedp.SendKeys(selector, keys))
 }
 
 // SetValue sets the value of an input element
 func (p *PuppeteerClient) SetValue(selector, value string) error {
-	return chromedp.Run(p.ctx, chromedp.SetValue(selector, value))
+	return p.run(chromedp.SetValue(selector, value))
 }
 
 // Clear clears an input element
 func (p *PuppeteerClient) Clear(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.Clear(selector))
+	return p.run(chromedp.Clear(selector))
 }
 
 // Evaluate executes JavaScript code
 func (p *PuppeteerClient) Evaluate(script string, res interface{}) error {
-	return chromedp.Run(p.ctx, chromedp.Evaluate(script, res))
+	return p.run(chromedp.Evaluate(script, res))
 }
 
 // EvaluateWithArgs executes JavaScript with arguments
 func (p *PuppeteerClient) EvaluateWithArgs(script string, res interface{}, args ...interface{}) error {
-	return chromedp.Run(p.ctx, chromedp.Evaluate(script, res))
+	return p.run(chromedp.Evaluate(script, res))
 }
 
 // Screenshot takes a screenshot and saves it to a file
 func (p *PuppeteerClient) Screenshot(path string) error {
 	var buf []byte
-	if err := chromedp.Run(p.ctx, chromedp.CaptureScreenshot(&buf)); err != nil {
+	if err := p.run(chromedp.CaptureScreenshot(&buf)); err != nil {
 		return err
 	}
 
 	// Save to file if path is provided
 	if path != "" {
-		return chromedp.Run(p.ctx, chromedp.FullScreenshot(&buf, 100))
+		return p.run(chromedp.FullScreenshot(&buf, 100))
 	}
 
 	return nil
@@ -104,58 +245,58 @@ func (p *PuppeteerClient) Screenshot(path string) error {
 // ScreenshotBytes takes a screenshot and returns bytes
 func (p *PuppeteerClient) ScreenshotBytes() ([]byte, error) {
 	var buf []byte
-	err := chromedp.Run(p.ctx, chromedp.CaptureScreenshot(&buf))
+	err := p.run(chromedp.CaptureScreenshot(&buf))
 	return buf, err
 }
 
 // FullScreenshot takes a full page screenshot
 func (p *PuppeteerClient) FullScreenshot() ([]byte, error) {
 	var buf []byte
-	err := chromedp.Run(p.ctx, chromedp.FullScreenshot(&buf, 100))
+	err := p.run(chromedp.FullScreenshot(&buf, 100))
 	return buf, err
 }
 
 // GetHTML gets the HTML content of an element
 func (p *PuppeteerClient) GetHTML(selector string) (string, error) {
 	var html string
-	err := chromedp.Run(p.ctx, chromedp.OuterHTML(selector, &html))
+	err := p.run(chromedp.OuterHTML(selector, &html))
 	return html, err
 }
 
 // GetInnerHTML gets the inner HTML of an element
 func (p *PuppeteerClient) GetInnerHTML(selector string) (string, error) {
 	var html string
-	err := chromedp.Run(p.ctx, chromedp.InnerHTML(selector, &html))
+	err := p.run(chromedp.InnerHTML(selector, &html))
 	return html, err
 }
 
 // GetText gets the text content of an element
 func (p *PuppeteerClient) GetText(selector string) (string, error) {
 	var text string
-	err := chromedp.Run(p.ctx, chromedp.Text(selector, &text))
+	err := p.run(chromedp.Text(selector, &text))
 	return text, err
 }
 
 // GetAttribute gets an attribute value from an element
 func (p *PuppeteerClient) GetAttribute(selector, attribute string) (string, error) {
 	var value string
-	err := chromedp.Run(p.ctx, chromedp.AttributeValue(selector, attribute, &value, nil))
+	err := p.run(chromedp.AttributeValue(selector, attribute, &value, nil))
 	return value, err
 }
 
 // WaitVisible waits for an element to be visible
 func (p *PuppeteerClient) WaitVisible(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.WaitVisible(selector))
+	return p.run(chromedp.WaitVisible(selector))
 }
 
 // WaitReady waits for an element to be ready
 func (p *PuppeteerClient) WaitReady(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.WaitReady(selector))
+	return p.run(chromedp.WaitReady(selector))
 }
 
 // WaitNotPresent waits for an element to be not present
 func (p *PuppeteerClient) WaitNotPresent(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.WaitNotPresent(selector))
+	return p.run(chromedp.WaitNotPresent(selector))
 }
 
 // Sleep waits for a specified duration
@@ -165,46 +306,46 @@ func (p *PuppeteerClient) Sleep(duration time.Duration) error {
 
 // SetViewport sets the viewport size
 func (p *PuppeteerClient) SetViewport(width, height int64) error {
-	return chromedp.Run(p.ctx, chromedp.EmulateViewport(width, height))
+	return p.run(chromedp.EmulateViewport(width, height))
 }
 
 // GetTitle gets the page title
 func (p *PuppeteerClient) GetTitle() (string, error) {
 	var title string
-	err := chromedp.Run(p.ctx, chromedp.Title(&title))
+	err := p.run(chromedp.Title(&title))
 	return title, err
 }
 
 // GetLocation gets the current URL
 func (p *PuppeteerClient) GetLocation() (string, error) {
 	var url string
-	err := chromedp.Run(p.ctx, chromedp.Location(&url))
+	err := p.run(chromedp.Location(&url))
 	return url, err
 }
 
 // Reload reloads the current page
 func (p *PuppeteerClient) Reload() error {
-	return chromedp.Run(p.ctx, chromedp.Reload())
+	return p.run(chromedp.Reload())
 }
 
 // ScrollTo scrolls to an element
 func (p *PuppeteerClient) ScrollTo(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.ScrollIntoView(selector))
+	return p.run(chromedp.ScrollIntoView(selector))
 }
 
 // Submit submits a form
 func (p *PuppeteerClient) Submit(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.Submit(selector))
+	return p.run(chromedp.Submit(selector))
 }
 
 // Focus focuses on an element
 func (p *PuppeteerClient) Focus(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.Focus(selector))
+	return p.run(chromedp.Focus(selector))
 }
 
 // Blur removes focus from an element
 func (p *PuppeteerClient) Blur(selector string) error {
-	return chromedp.Run(p.ctx, chromedp.Blur(selector))
+	return p.run(chromedp.Blur(selector))
 }
 
 // QuerySelectorAll returns multiple elements matching selector
@@ -213,14 +354,14 @@ func (p *PuppeteerClient) QuerySelectorAll(selector string) ([]string, error) {
 	script := fmt.Sprintf(`
 		Array.from(document.querySelectorAll('%s')).map(el => el.outerHTML)
 	`, selector)
-	err := chromedp.Run(p.ctx, chromedp.Evaluate(script, &nodes))
+	err := p.run(chromedp.Evaluate(script, &nodes))
 	return nodes, err
 }
 
 // AddCookie adds a cookie
 func (p *PuppeteerClient) AddCookie(name, value, domain string) error {
 	//  Set cookie using chromedp.ActionFunc
-	return chromedp.Run(p.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+	return p.run(chromedp.ActionFunc(func(ctx context.Context) error {
 		// Cookie setting via JavaScript
 		script := fmt.Sprintf(`document.cookie = "%s=%s; domain=%s"`, name, value, domain)
 		var res interface{}
@@ -228,18 +369,23 @@ func (p *PuppeteerClient) AddCookie(name, value, domain string) error {
 	}))
 }
 
-// Close closes the browser context
+// Close closes every tab and the browser
 func (p *PuppeteerClient) Close() error {
+	p.tabs.close()
 	p.cancel()
+	p.allocCancel()
+	p.proxy.close()
 	return nil
 }
 
-// Run executes a chromedp action
+// Run executes chromedp actions in the default tab within the configured
+// timeout
 func (p *PuppeteerClient) Run(actions ...chromedp.Action) error {
-	return chromedp.Run(p.ctx, actions...)
+	return p.run(actions...)
 }
 
-// GetContext returns the context for advanced operations
+// GetContext returns the context of the default tab for advanced
+// operations; it lives until Close
 func (p *PuppeteerClient) GetContext() context.Context {
 	return p.ctx
 }
@@ -0,0 +1,222 @@
+package crawlers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrTabPoolClosed is returned by PuppeteerClient.Checkout after Close
+var ErrTabPoolClosed = errs.New(errs.CodeBrowserPoolClosed, "puppeteer tab pool is closed")
+
+// tabPool hands out up to size tabs of a PuppeteerClient's browser
+// Tabs share the browser's cookies; each has its own request interception
+// and emulation
+type tabPool struct {
+	client *PuppeteerClient
+	size   int
+
+	slots chan *tabSlot // Idle slots; a slot without a tab is opened on checkout
+
+	startMu sync.Mutex
+	started bool
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// tabSlot is one browser tab
+type tabSlot struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// PuppeteerTab is a browser tab checked out of a PuppeteerClient
+// Navigations and actions run with the client's timeout, so a slow page
+// only fails its own tab. Return it with Checkin
+type PuppeteerTab struct {
+	client   *PuppeteerClient
+	slot     *tabSlot
+	discard  bool
+	returned bool
+}
+
+// TabStats holds tab pool occupancy
+type TabStats struct {
+	Size  int
+	Idle  int
+	InUse int
+}
+
+// newTabPool creates an empty pool; tabs are opened on first checkout
+func newTabPool(client *PuppeteerClient, size int) *tabPool {
+	pool := &tabPool{
+		client: client,
+		size:   size,
+		slots:  make(chan *tabSlot, size),
+		done:   make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		pool.slots <- &tabSlot{}
+	}
+	return pool
+}
+
+// start starts the browser of the default tab so pooled tabs open in it
+// rather than in browsers of their own
+func (t *tabPool) start() error {
+	t.startMu.Lock()
+	defer t.startMu.Unlock()
+	if t.started {
+		return nil
+	}
+	if err := chromedp.Run(t.client.ctx); err != nil {
+		return fmt.Errorf("failed to start browser: %w", err)
+	}
+	t.started = true
+	return nil
+}
+
+// open opens a tab for slot
+func (t *tabPool) open(slot *tabSlot) error {
+	if err := t.start(); err != nil {
+		return err
+	}
+	ctx, cancel := chromedp.NewContext(t.client.ctx)
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return fmt.Errorf("failed to open tab: %w", err)
+	}
+	if err := t.client.prepare(ctx); err != nil {
+		cancel()
+		return err
+	}
+	slot.ctx = ctx
+	slot.cancel = cancel
+	return nil
+}
+
+// retire closes the tab of slot and leaves it empty
+func (t *tabPool) retire(slot *tabSlot) {
+	if slot.cancel != nil {
+		slot.cancel()
+	}
+	slot.ctx = nil
+	slot.cancel = nil
+}
+
+// isClosed reports whether close was called
+func (t *tabPool) isClosed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.closed
+}
+
+// close retires every idle tab; tabs still checked out are closed with the
+// browser
+func (t *tabPool) close() {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return
+	}
+	t.closed = true
+	close(t.done)
+	t.mu.Unlock()
+
+	for {
+		select {
+		case slot := <-t.slots:
+			t.retire(slot)
+		default:
+			return
+		}
+	}
+}
+
+// Checkout waits for an idle tab until ctx is done
+// Every tab must be returned with Checkin
+func (p *PuppeteerClient) Checkout(ctx context.Context) (*PuppeteerTab, error) {
+	select {
+	case <-p.tabs.done:
+		return nil, ErrTabPoolClosed
+	default:
+	}
+
+	select {
+	case slot := <-p.tabs.slots:
+		if p.tabs.isClosed() {
+			p.tabs.retire(slot)
+			return nil, ErrTabPoolClosed
+		}
+		if slot.ctx == nil || slot.ctx.Err() != nil {
+			p.tabs.retire(slot)
+			if err := p.tabs.open(slot); err != nil {
+				p.tabs.slots <- slot // Keep the slot; the next checkout retries
+				return nil, err
+			}
+		}
+		return &PuppeteerTab{client: p, slot: slot}, nil
+	case <-p.tabs.done:
+		return nil, ErrTabPoolClosed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Checkin returns a tab to the pool
+// The tab is reset to about:blank; if that fails or the tab was discarded,
+// it is closed and a new one is opened on the next checkout
+func (p *PuppeteerClient) Checkin(tab *PuppeteerTab) {
+	if tab == nil || tab.returned {
+		return
+	}
+	tab.returned = true
+	slot := tab.slot
+
+	if p.tabs.isClosed() {
+		p.tabs.retire(slot)
+		return
+	}
+
+	if tab.discard || tab.Run(chromedp.Navigate("about:blank")) != nil {
+		p.tabs.retire(slot)
+	}
+	p.tabs.slots <- slot
+}
+
+// TabStats returns tab pool occupancy
+func (p *PuppeteerClient) TabStats() TabStats {
+	idle := len(p.tabs.slots)
+	return TabStats{Size: p.tabs.size, Idle: idle, InUse: p.tabs.size - idle}
+}
+
+// Discard marks the tab as unusable so Checkin closes it instead of reusing
+// it, e.g. after a crash or a page left in a bad state
+func (t *PuppeteerTab) Discard() {
+	t.discard = true
+}
+
+// Navigate navigates the tab to a URL, giving up when ctx is done or after
+// the client's timeout, and returns the main response
+func (t *PuppeteerTab) Navigate(ctx context.Context, url string) (*network.Response, error) {
+	return t.client.navigate(ctx, t.slot.ctx, url)
+}
+
+// Run executes chromedp actions in the tab within the client's timeout
+func (t *PuppeteerTab) Run(actions ...chromedp.Action) error {
+	ctx, cancel := t.client.actionContext(t.slot.ctx)
+	defer cancel()
+	return chromedp.Run(ctx, actions...)
+}
+
+// Context returns the tab's context for advanced operations; it lives until
+// the tab is closed
+func (t *PuppeteerTab) Context() context.Context {
+	return t.slot.ctx
+}
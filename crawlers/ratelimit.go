@@ -0,0 +1,210 @@
+package crawlers
+
+import (
+	"context"
+	"math/rand"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"golang.org/x/net/publicsuffix"
+	"golang.org/x/time/rate"
+)
+
+// RateLimiterConfig holds per-domain politeness settings
+type RateLimiterConfig struct {
+	RequestsPerSecond float64       // Max requests per second per domain (0 = unlimited)
+	Delay             time.Duration // Minimum gap between requests to a domain
+	RandomDelay       time.Duration // Extra random delay up to this value
+	MaxConcurrent     int           // Max in-flight requests per domain (0 = unlimited)
+	Clock             clock.Clock   // Defaults to the wall clock
+
+	// Adaptive raises the delay of a domain that answers 429 or 503, or
+	// sends Retry-After, and lowers it again as responses succeed; clients
+	// report responses with Observe. Optional
+	Adaptive *AdaptiveConfig
+}
+
+// RateLimiter throttles requests per registrable domain (eTLD+1)
+// One limiter shared by several clients keeps them from collectively
+// hammering the same site, e.g. www.example.com and cdn.example.com
+type RateLimiter struct {
+	limit         rate.Limit
+	randomDelay   time.Duration
+	maxConcurrent int
+	adaptive      *AdaptiveConfig
+	robots        *RobotsTxt
+	robotsAgent   string
+	maxCrawlDelay time.Duration
+	clock         clock.Clock
+	domains       map[string]*domainLimit
+	mu            sync.Mutex
+}
+
+// domainLimit is the throttling state of one domain
+type domainLimit struct {
+	limiter *rate.Limiter
+	slots   chan struct{}
+
+	// Adaptive state, guarded by mu
+	mu        sync.Mutex
+	delay     time.Duration // Raised delay; zero while the configured limit applies
+	successes int           // Successful responses since the last change of delay
+
+	crawlDelays map[string]time.Duration // robots.txt delays by origin, guarded by mu
+}
+
+// NewRateLimiter creates a new per-domain rate limiter
+// The stricter of RequestsPerSecond and Delay wins
+func NewRateLimiter(config RateLimiterConfig) *RateLimiter {
+	limit := rate.Inf
+	if config.RequestsPerSecond > 0 {
+		limit = rate.Limit(config.RequestsPerSecond)
+	}
+	if config.Delay > 0 {
+		limit = min(limit, rate.Every(config.Delay))
+	}
+
+	return &RateLimiter{
+		limit:         limit,
+		randomDelay:   config.RandomDelay,
+		maxConcurrent: config.MaxConcurrent,
+		adaptive:      config.Adaptive.withDefaults(),
+		clock:         clock.Or(config.Clock),
+		domains:       make(map[string]*domainLimit),
+	}
+}
+
+// NewRateLimiterFromConfig creates a rate limiter from application config;
+// metrics is optional and only used by adaptive limiting
+// Returns nil when rate limiting is disabled
+func NewRateLimiterFromConfig(config configs.RateLimitConfig, metrics ThrottleMetrics) *RateLimiter {
+	if !config.Enabled {
+		return nil
+	}
+	limiterConfig := RateLimiterConfig{
+		RequestsPerSecond: float64(config.RequestsPerSec),
+		Delay:             time.Duration(config.Delay) * time.Millisecond,
+		RandomDelay:       time.Duration(config.RandomDelay) * time.Millisecond,
+		MaxConcurrent:     config.MaxConcurrent,
+	}
+	if config.Adaptive {
+		limiterConfig.Adaptive = &AdaptiveConfig{
+			MaxDelay: time.Duration(config.MaxDelay) * time.Millisecond,
+			Metrics:  metrics,
+		}
+	}
+	return NewRateLimiter(limiterConfig)
+}
+
+// Acquire blocks until a request to the URL's domain is allowed
+// The returned release function must be called once the request completes
+func (l *RateLimiter) Acquire(ctx context.Context, rawURL string) (func(), error) {
+	d := l.domainLimit(RateLimitDomain(rawURL))
+	if err := l.applyCrawlDelay(ctx, d, rawURL); err != nil {
+		return nil, err
+	}
+
+	release := func() {}
+	if d.slots != nil {
+		select {
+		case d.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		var once sync.Once
+		release = func() {
+			once.Do(func() { <-d.slots })
+		}
+	}
+
+	if err := l.waitLimiter(ctx, d.limiter); err != nil {
+		release()
+		return nil, err
+	}
+
+	if l.randomDelay > 0 {
+		if err := clock.Sleep(ctx, l.clock, time.Duration(rand.Int63n(int64(l.randomDelay)))); err != nil {
+			release()
+			return nil, err
+		}
+	}
+
+	return release, nil
+}
+
+// waitLimiter reserves a token and waits for it on the limiter's clock
+// The reservation is returned when ctx ends first
+func (l *RateLimiter) waitLimiter(ctx context.Context, limiter *rate.Limiter) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	now := l.clock.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if err := clock.Sleep(ctx, l.clock, reservation.DelayFrom(now)); err != nil {
+		reservation.CancelAt(l.clock.Now())
+		return err
+	}
+	return nil
+}
+
+// Wait blocks until a request to the URL's domain is allowed
+// Use Acquire instead when in-flight requests should count against MaxConcurrent
+func (l *RateLimiter) Wait(ctx context.Context, rawURL string) error {
+	release, err := l.Acquire(ctx, rawURL)
+	if err != nil {
+		return err
+	}
+	release()
+	return nil
+}
+
+// Domains returns the number of domains currently tracked
+func (l *RateLimiter) Domains() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.domains)
+}
+
+// domainLimit returns the throttling state for a domain, creating it on first use
+func (l *RateLimiter) domainLimit(domain string) *domainLimit {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d, ok := l.domains[domain]
+	if !ok {
+		d = &domainLimit{limiter: rate.NewLimiter(l.limit, 1)}
+		if l.maxConcurrent > 0 {
+			d.slots = make(chan struct{}, l.maxConcurrent)
+		}
+		l.domains[domain] = d
+	}
+	return d
+}
+
+// urlHost returns the lowercase hostname of a URL, or "" if it cannot be parsed
+func urlHost(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// RateLimitDomain returns the registrable domain (eTLD+1) used as rate limit key
+// Hosts without a public suffix (IPs, localhost) are keyed by their hostname
+func RateLimitDomain(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := strings.ToLower(parsed.Hostname())
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
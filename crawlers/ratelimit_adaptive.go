@@ -0,0 +1,142 @@
+package crawlers
+
+import (
+	"net/http"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Throttle events, the action label of ThrottleMetrics
+const (
+	ThrottleActionSlowed    = "slowed"    // A domain's delay was raised
+	ThrottleActionPaused    = "paused"    // A domain was put on cooldown (DomainCooldown)
+	ThrottleActionRecovered = "recovered" // A domain is back at the configured limit
+)
+
+// ThrottleMetrics records adaptive rate limiter and cooldown events;
+// *libs.Metrics implements it
+type ThrottleMetrics interface {
+	RecordThrottle(action string)
+}
+
+// AdaptiveConfig holds adaptive politeness settings for a RateLimiter
+// The limiter only lowers a throttled domain's rate; holding the domain for
+// its Retry-After window is the job of DomainCooldown
+type AdaptiveConfig struct {
+	Factor       float64         // Delay multiplier per throttled response; defaults to 2
+	MinDelay     time.Duration   // Delay of a domain's first throttle when its limit is faster; defaults to 1s, or MaxDelay if lower
+	MaxDelay     time.Duration   // Upper bound of raised delays; defaults to 1 minute
+	RecoverAfter int             // Successful responses before the delay is divided by Factor; defaults to 10
+	Metrics      ThrottleMetrics // Optional
+}
+
+// withDefaults returns a copy of c with defaults filled in, or nil
+func (c *AdaptiveConfig) withDefaults() *AdaptiveConfig {
+	if c == nil {
+		return nil
+	}
+	config := *c
+	if config.Factor <= 1 {
+		config.Factor = 2
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = time.Minute
+	}
+	if config.MinDelay <= 0 {
+		config.MinDelay = min(time.Second, config.MaxDelay)
+	}
+	if config.MaxDelay < config.MinDelay {
+		config.MaxDelay = config.MinDelay
+	}
+	if config.RecoverAfter <= 0 {
+		config.RecoverAfter = 10
+	}
+	return &config
+}
+
+// IsThrottleResponse reports whether a response asks the client to slow
+// down: a 429 or 503, or another error status with a Retry-After header
+func IsThrottleResponse(statusCode int, header http.Header) bool {
+	return IsThrottleStatus(statusCode) || (statusCode >= 400 && header.Get("Retry-After") != "")
+}
+
+// Observe reports a response from rawURL to an adaptive limiter
+// A throttling response multiplies the domain's delay by Factor, to at
+// least MinDelay and at most MaxDelay; RecoverAfter successful responses in
+// a row step it back down. Engines report responses through observeThrottle,
+// which also puts the domain on cooldown.
+// It returns the domain's delay and whether the response throttled it.
+// Nil limiters and limiters without Adaptive ignore responses
+func (l *RateLimiter) Observe(rawURL string, statusCode int, header http.Header) (time.Duration, bool) {
+	if l == nil || l.adaptive == nil || statusCode == 0 {
+		return 0, false
+	}
+	d := l.domainLimit(RateLimitDomain(rawURL))
+	now := l.clock.Now()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !IsThrottleResponse(statusCode, header) {
+		l.relax(d, now)
+		return d.delay, false
+	}
+
+	delay := time.Duration(float64(max(d.delay, l.minDelay(d))) * l.adaptive.Factor)
+	delay = min(max(delay, l.adaptive.MinDelay), l.adaptive.MaxDelay)
+	d.successes = 0
+	if delay > d.delay {
+		d.delay = delay
+		d.limiter.SetLimitAt(now, l.domainRate(d))
+		d.limiter.AllowN(now, 1) // Spend a token left by a faster limit, so the next request waits
+		l.record(ThrottleActionSlowed)
+	}
+	return d.delay, true
+}
+
+// Delay returns the current gap between requests to the URL's domain: its
+// raised delay, its robots.txt Crawl-delay, or the configured one
+func (l *RateLimiter) Delay(rawURL string) time.Duration {
+	d := l.domainLimit(RateLimitDomain(rawURL))
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return max(d.delay, l.minDelay(d))
+}
+
+// relax counts a successful response and steps a raised delay down once
+// RecoverAfter of them arrived in a row; d.mu must be held
+func (l *RateLimiter) relax(d *domainLimit, now time.Time) {
+	if d.delay == 0 {
+		return
+	}
+	d.successes++
+	if d.successes < l.adaptive.RecoverAfter {
+		return
+	}
+	d.successes = 0
+
+	d.delay = time.Duration(float64(d.delay) / l.adaptive.Factor)
+	if d.delay <= l.minDelay(d) || d.delay < l.adaptive.MinDelay {
+		d.delay = 0
+		d.limiter.SetLimitAt(now, l.domainRate(d))
+		l.record(ThrottleActionRecovered)
+		return
+	}
+	d.limiter.SetLimitAt(now, l.domainRate(d))
+}
+
+// baseDelay is the gap between requests of the configured limit
+func (l *RateLimiter) baseDelay() time.Duration {
+	if l.limit == rate.Inf || l.limit <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / float64(l.limit))
+}
+
+// record reports a throttle event to the configured metrics
+func (l *RateLimiter) record(action string) {
+	if l.adaptive.Metrics != nil {
+		l.adaptive.Metrics.RecordThrottle(action)
+	}
+}
@@ -0,0 +1,79 @@
+package crawlers
+
+import (
+	"net/http"
+	"sync"
+)
+
+// maxRedirects matches net/http's default redirect limit
+const maxRedirects = 10
+
+// Redirect is one hop of a redirect chain
+type Redirect struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	StatusCode int    `json:"status_code"`
+}
+
+// Permanent reports whether the hop is a 301 or 308, which make To the
+// canonical location of From
+func (r Redirect) Permanent() bool {
+	return r.StatusCode == http.StatusMovedPermanently || r.StatusCode == http.StatusPermanentRedirect
+}
+
+// PermanentTarget follows the permanent redirects at the start of a chain
+// from start and returns where they lead; a temporary redirect ends the
+// chain, since its source keeps its own URL
+func PermanentTarget(start string, hops []Redirect) string {
+	target := start
+	for _, hop := range hops {
+		if !hop.Permanent() {
+			break
+		}
+		target = hop.To
+	}
+	return target
+}
+
+// RedirectObserver is implemented by crawler clients that report the
+// redirects they follow
+type RedirectObserver interface {
+	// OnRedirect registers a callback for every redirect followed
+	OnRedirect(handler func(r Redirect))
+}
+
+// redirectHandlers dispatches followed redirects to registered callbacks
+type redirectHandlers struct {
+	mu       sync.RWMutex
+	handlers []func(Redirect)
+}
+
+// add registers a callback
+func (h *redirectHandlers) add(handler func(Redirect)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.handlers = append(h.handlers, handler)
+}
+
+// checkRedirect is an http.Client CheckRedirect function that reports each
+// hop and otherwise behaves like Colly's default: at most 10 redirects, and
+// no Authorization header across hosts
+func (h *redirectHandlers) checkRedirect(req *http.Request, via []*http.Request) error {
+	last := via[len(via)-1]
+	if req.Response != nil {
+		hop := Redirect{From: last.URL.String(), To: req.URL.String(), StatusCode: req.Response.StatusCode}
+		h.mu.RLock()
+		for _, handler := range h.handlers {
+			handler(hop)
+		}
+		h.mu.RUnlock()
+	}
+
+	if len(via) >= maxRedirects {
+		return http.ErrUseLastResponse
+	}
+	if req.URL.Host != last.URL.Host {
+		req.Header.Del("Authorization")
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package crawlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/alonecandies/golwarc/errs"
+	"golang.org/x/net/http/httpguts"
+)
+
+// reservedHeaders cannot be set through RequestOptions; the transport
+// manages them
+var reservedHeaders = map[string]bool{
+	"Host": true, "Content-Length": true, "Transfer-Encoding": true, "Connection": true,
+	"Te": true, "Trailer": true, "Upgrade": true, "Proxy-Connection": true, visitHeader: true,
+}
+
+// RequestOptions are extra headers and query parameters added to every
+// request of one crawl, e.g. a tenant token or an A/B test cookie
+// They travel in the context, so Soup, Spider and Colly's VisitContext apply
+// them to the requests made for that context only
+type RequestOptions struct {
+	Headers map[string]string `json:"headers,omitempty"` // Replace headers of the same name, User-Agent included
+	Query   map[string]string `json:"query,omitempty"`   // Replace query parameters of the same name
+}
+
+// requestOptionsKey carries RequestOptions in a context
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a context whose requests carry opts
+func WithRequestOptions(ctx context.Context, opts RequestOptions) context.Context {
+	if opts.IsZero() {
+		return ctx
+	}
+	return context.WithValue(ctx, requestOptionsKey{}, opts)
+}
+
+// RequestOptionsFrom returns the options stored in ctx, if any
+func RequestOptionsFrom(ctx context.Context) RequestOptions {
+	opts, _ := ctx.Value(requestOptionsKey{}).(RequestOptions)
+	return opts
+}
+
+// IsZero reports whether the options add nothing
+func (o RequestOptions) IsZero() bool {
+	return len(o.Headers) == 0 && len(o.Query) == 0
+}
+
+// Validate rejects header names and values that cannot be sent, and headers
+// the transport manages itself
+func (o RequestOptions) Validate() error {
+	for name, value := range o.Headers {
+		if !httpguts.ValidHeaderFieldName(name) {
+			return errs.Newf(errs.CodeInvalidRequest, "invalid header name %q", name)
+		}
+		if reservedHeaders[http.CanonicalHeaderKey(name)] {
+			return errs.Newf(errs.CodeInvalidRequest, "header %s cannot be set per crawl", http.CanonicalHeaderKey(name))
+		}
+		if !httpguts.ValidHeaderFieldValue(value) {
+			return errs.Newf(errs.CodeInvalidRequest, "invalid value for header %s", name)
+		}
+	}
+	for name := range o.Query {
+		if strings.TrimSpace(name) == "" {
+			return errs.New(errs.CodeInvalidRequest, "query parameter names cannot be empty")
+		}
+	}
+	return nil
+}
+
+// Apply adds the options to req
+func (o RequestOptions) Apply(req *http.Request) {
+	for name, value := range o.Headers {
+		req.Header.Set(name, value)
+	}
+	if len(o.Query) > 0 {
+		query := req.URL.Query()
+		for name, value := range o.Query {
+			query.Set(name, value)
+		}
+		req.URL.RawQuery = query.Encode()
+	}
+}
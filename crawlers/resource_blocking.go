@@ -0,0 +1,96 @@
+package crawlers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// DefaultTrackerPatterns matches common analytics and advertising hosts
+var DefaultTrackerPatterns = []string{
+	"*://*.google-analytics.com/*",
+	"*://*.googletagmanager.com/*",
+	"*://*.googlesyndication.com/*",
+	"*://*.doubleclick.net/*",
+	"*://connect.facebook.net/*",
+	"*://*.hotjar.com/*",
+	"*://*.segment.io/*",
+	"*://*.scorecardresearch.com/*",
+	"*://*.quantserve.com/*",
+	"*://*.adnxs.com/*",
+}
+
+// ResourceBlocker decides which browser requests to abort
+// Requests are blocked by resource type (image, font, stylesheet, media, ...)
+// or by URL wildcard patterns where * matches any run of characters and ?
+// matches one character
+type ResourceBlocker struct {
+	types    map[string]bool
+	patterns []*regexp.Regexp
+	blocked  atomic.Int64
+}
+
+// NewResourceBlocker compiles resource types and URL patterns into a blocker
+func NewResourceBlocker(resourceTypes, urlPatterns []string) (*ResourceBlocker, error) {
+	b := &ResourceBlocker{types: make(map[string]bool, len(resourceTypes))}
+	for _, t := range resourceTypes {
+		b.types[strings.ToLower(t)] = true
+	}
+	for _, pattern := range urlPatterns {
+		re, err := compileWildcard(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid block pattern %q: %w", pattern, err)
+		}
+		b.patterns = append(b.patterns, re)
+	}
+	return b, nil
+}
+
+// compileWildcard turns a * and ? wildcard pattern into an anchored regexp
+func compileWildcard(pattern string) (*regexp.Regexp, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteString("$")
+	return regexp.Compile(sb.String())
+}
+
+// Empty reports whether the blocker never blocks anything
+func (b *ResourceBlocker) Empty() bool {
+	return len(b.types) == 0 && len(b.patterns) == 0
+}
+
+// Blocks reports whether a request should be aborted and counts it if so
+// resourceType is case-insensitive, e.g. "Image" or "image"
+func (b *ResourceBlocker) Blocks(url, resourceType string) bool {
+	if b.types[strings.ToLower(resourceType)] || b.matches(url) {
+		b.blocked.Add(1)
+		return true
+	}
+	return false
+}
+
+// matches reports whether url matches any pattern
+func (b *ResourceBlocker) matches(url string) bool {
+	for _, re := range b.patterns {
+		if re.MatchString(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// Blocked returns the number of requests blocked so far
+func (b *ResourceBlocker) Blocked() int64 {
+	return b.blocked.Load()
+}
@@ -0,0 +1,106 @@
+package crawlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// RobotsDirectives are the indexing directives of a page, from
+// <meta name="robots"> tags and X-Robots-Tag headers
+type RobotsDirectives struct {
+	NoIndex  bool `json:"noindex,omitempty"`  // Do not store the page
+	NoFollow bool `json:"nofollow,omitempty"` // Do not follow the page's links
+}
+
+// robotsValueDirectives are X-Robots-Tag directives written name: value, which
+// must not be mistaken for a user agent prefix
+var robotsValueDirectives = map[string]bool{
+	"unavailable_after": true,
+	"max-snippet":       true,
+	"max-image-preview": true,
+	"max-video-preview": true,
+}
+
+// ParseRobotsDirectives reads the robots directives of a response
+// Rules for all robots apply, as do rules addressed to agent, the crawler's
+// product token such as golwarcbot (see RobotsAgent); rules for other
+// crawlers are ignored. Either header or doc may be nil
+func ParseRobotsDirectives(header http.Header, doc *goquery.Document, agent string) RobotsDirectives {
+	var d RobotsDirectives
+	agent = strings.ToLower(agent)
+
+	for _, value := range header.Values("X-Robots-Tag") {
+		applies := true
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if name, rest, ok := strings.Cut(part, ":"); ok && !robotsValueDirectives[strings.ToLower(strings.TrimSpace(name))] {
+				// A user agent prefix scopes the rest of the header value
+				name = strings.ToLower(strings.TrimSpace(name))
+				applies = name == agent || name == "*"
+				part = rest
+			}
+			if applies {
+				d.apply(part)
+			}
+		}
+	}
+
+	if doc != nil {
+		doc.Find("meta[name]").Each(func(_ int, meta *goquery.Selection) {
+			name := strings.ToLower(strings.TrimSpace(meta.AttrOr("name", "")))
+			if name != "robots" && (agent == "" || name != agent) {
+				return
+			}
+			for _, part := range strings.Split(meta.AttrOr("content", ""), ",") {
+				d.apply(part)
+			}
+		})
+	}
+	return d
+}
+
+// apply records a single directive
+func (d *RobotsDirectives) apply(directive string) {
+	switch strings.ToLower(strings.TrimSpace(directive)) {
+	case "noindex":
+		d.NoIndex = true
+	case "nofollow":
+		d.NoFollow = true
+	case "none":
+		d.NoIndex = true
+		d.NoFollow = true
+	}
+}
+
+// NoFollowLink reports whether a link carries rel="nofollow"
+func NoFollowLink(link *goquery.Selection) bool {
+	return noFollowRel(link.AttrOr("rel", ""))
+}
+
+// noFollowRel reports whether a rel attribute value includes nofollow
+func noFollowRel(rel string) bool {
+	for _, value := range strings.Fields(rel) {
+		if strings.EqualFold(value, "nofollow") {
+			return true
+		}
+	}
+	return false
+}
+
+// RobotsAgent returns the product token of a crawler user agent, e.g.
+// golwarcbot for "Mozilla/5.0 (compatible; GolwarcBot/1.0)", or "" when it
+// names no bot. Info URLs and contact addresses are not product tokens
+func RobotsAgent(userAgent string) string {
+	for _, field := range strings.FieldsFunc(userAgent, func(r rune) bool { return r == ' ' || r == ';' || r == '(' || r == ')' }) {
+		if strings.ContainsAny(field, ":@") {
+			continue
+		}
+		token, _, _ := strings.Cut(strings.ToLower(field), "/")
+		if strings.Contains(token, "bot") || strings.Contains(token, "crawler") || strings.Contains(token, "spider") {
+			return token
+		}
+	}
+	return ""
+}
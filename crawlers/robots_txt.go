@@ -0,0 +1,433 @@
+package crawlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/temoto/robotstxt"
+)
+
+// Robots.txt fetch outcomes, the outcome label of RobotsMetrics
+const (
+	RobotsOutcomeFetched     = "fetched"      // 2xx; the rules apply
+	RobotsOutcomeUnavailable = "unavailable"  // 4xx or too many redirects; everything is allowed
+	RobotsOutcomeServerError = "server_error" // 5xx or 429; everything is disallowed unless a stale copy exists
+	RobotsOutcomeUnreachable = "unreachable"  // Network error; as server_error
+	RobotsOutcomeShared      = "shared"       // A fresh copy another worker fetched was found in the store
+)
+
+// RobotsMetrics records robots.txt fetch outcomes; *libs.Metrics
+// implements it
+type RobotsMetrics interface {
+	RecordRobotsFetch(outcome string)
+}
+
+// RobotsEntry is a robots.txt fetch result as kept in a RobotsStore
+type RobotsEntry struct {
+	Status    int       `json:"status"`               // Status the rules come from; 0 when unreachable
+	Body      string    `json:"body,omitempty"`       // For 2xx
+	FetchedAt time.Time `json:"fetched_at,omitempty"` // Last 2xx or 4xx answer; zero if the host never gave one
+	ExpiresAt time.Time `json:"expires_at"`           // Fetched again after this
+}
+
+// RobotsStore persists robots.txt files by origin (scheme://host:port)
+// Sharing a store (e.g. Redis) lets a cluster fetch each file once
+type RobotsStore interface {
+	// GetRobots returns the entry of origin, or nil if there is none
+	GetRobots(origin string) (*RobotsEntry, error)
+
+	// SetRobots stores the entry of origin for ttl
+	SetRobots(origin string, entry *RobotsEntry, ttl time.Duration) error
+}
+
+// MemoryRobotsStore keeps robots.txt files in process memory
+type MemoryRobotsStore struct {
+	clock   clock.Clock
+	mu      sync.RWMutex
+	entries map[string]memoryRobotsEntry
+}
+
+// memoryRobotsEntry is a MemoryRobotsStore entry and its removal time
+type memoryRobotsEntry struct {
+	entry   RobotsEntry
+	removed time.Time
+}
+
+// NewMemoryRobotsStore creates an in-memory robots.txt store; c defaults to
+// the wall clock
+func NewMemoryRobotsStore(c clock.Clock) *MemoryRobotsStore {
+	return &MemoryRobotsStore{
+		clock:   clock.Or(c),
+		entries: make(map[string]memoryRobotsEntry),
+	}
+}
+
+// GetRobots returns the entry of origin, or nil if there is none
+func (s *MemoryRobotsStore) GetRobots(origin string) (*RobotsEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	e, ok := s.entries[origin]
+	if !ok || !s.clock.Now().Before(e.removed) {
+		return nil, nil
+	}
+	entry := e.entry
+	return &entry, nil
+}
+
+// SetRobots stores the entry of origin for ttl
+func (s *MemoryRobotsStore) SetRobots(origin string, entry *RobotsEntry, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[origin] = memoryRobotsEntry{entry: *entry, removed: s.clock.Now().Add(ttl)}
+	return nil
+}
+
+// CacheRobotsStore persists robots.txt files in a shared cache such as
+// Redis, as JSON. Keys expire on their own
+type CacheRobotsStore struct {
+	client cache.CacheClient
+	prefix string
+}
+
+// NewCacheRobotsStore creates a robots.txt store backed by a cache client
+func NewCacheRobotsStore(client cache.CacheClient) *CacheRobotsStore {
+	return &CacheRobotsStore{
+		client: client,
+		prefix: "robots:",
+	}
+}
+
+// GetRobots returns the entry of origin, or nil if there is none
+func (s *CacheRobotsStore) GetRobots(origin string) (*RobotsEntry, error) {
+	exists, err := s.client.Exists(s.prefix + origin)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	val, err := s.client.Get(s.prefix + origin)
+	if err != nil {
+		return nil, err
+	}
+
+	var entry RobotsEntry
+	if err := json.Unmarshal([]byte(val), &entry); err != nil {
+		return nil, fmt.Errorf("invalid robots.txt entry for %s: %w", origin, err)
+	}
+	return &entry, nil
+}
+
+// SetRobots stores the entry of origin for ttl
+func (s *CacheRobotsStore) SetRobots(origin string, entry *RobotsEntry, ttl time.Duration) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(s.prefix+origin, string(data), ttl)
+}
+
+// RobotsConfig holds robots.txt settings
+type RobotsConfig struct {
+	Store     RobotsStore   // Defaults to an in-memory store
+	TTL       time.Duration // How long a fetched robots.txt is used (default 24h, the RFC 9309 limit)
+	ErrorTTL  time.Duration // How long a 5xx or unreachable robots.txt is trusted before a retry (default 10m)
+	StaleTTL  time.Duration // How long an expired robots.txt stands in while its host fails (default 30 days)
+	Timeout   time.Duration // Fetch timeout (default 10s)
+	MaxSize   int64         // Bytes parsed; the rest is ignored (default 500 KiB, the RFC 9309 minimum)
+	UserAgent string        // Sent when fetching robots.txt
+	Client    *http.Client  // Defaults to a client following at most 5 redirects
+	Metrics   RobotsMetrics // Optional
+	Clock     clock.Clock   // Defaults to the wall clock; shared stores expire keys on wall time
+}
+
+// RobotsTxt decides whether URLs may be crawled by the robots.txt of their
+// host, following RFC 9309:
+//   - a 2xx robots.txt applies to the agent's group, or "*"
+//   - 4xx, or more than 5 redirects, means there is none: everything is allowed
+//   - 5xx, 429 or an unreachable host disallows everything until a retry
+//     ErrorTTL later; an expired copy younger than StaleTTL is used instead
+//
+// Files are kept in the store for other workers and parsed once per process
+type RobotsTxt struct {
+	config RobotsConfig
+
+	mu     sync.Mutex
+	parsed map[string]*parsedRobots // Origin -> rules
+	locks  map[string]*sync.Mutex   // Origin -> fetch lock, so a host is fetched once at a time
+}
+
+// parsedRobots are the parsed rules of an entry
+type parsedRobots struct {
+	data    *robotstxt.RobotsData
+	rates   map[string]time.Duration // Request-rate intervals by lowercased user agent
+	expires time.Time
+}
+
+// robotsAllowAll and robotsDisallowAll are the rules of hosts without a
+// usable robots.txt
+var (
+	robotsAllowAll, _    = robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+	robotsDisallowAll, _ = robotstxt.FromStatusAndBytes(http.StatusServiceUnavailable, nil)
+)
+
+// NewRobotsTxt creates a robots.txt checker
+func NewRobotsTxt(config RobotsConfig) *RobotsTxt {
+	config.Clock = clock.Or(config.Clock)
+	if config.Store == nil {
+		config.Store = NewMemoryRobotsStore(config.Clock)
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.ErrorTTL <= 0 {
+		config.ErrorTTL = 10 * time.Minute
+	}
+	if config.StaleTTL <= 0 {
+		config.StaleTTL = 30 * 24 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxSize <= 0 {
+		config.MaxSize = 500 << 10
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > 5 {
+				return errRobotsRedirects
+			}
+			return nil
+		}}
+	}
+	return &RobotsTxt{
+		config: config,
+		parsed: make(map[string]*parsedRobots),
+		locks:  make(map[string]*sync.Mutex),
+	}
+}
+
+// NewRobotsTxtFromConfig creates a robots.txt checker from application
+// config, keeping files in store (nil for process memory); metrics is optional
+// Returns nil when robots.txt handling is disabled
+func NewRobotsTxtFromConfig(config configs.RobotsConfig, store RobotsStore, userAgent string, metrics RobotsMetrics) *RobotsTxt {
+	if !config.Enabled {
+		return nil
+	}
+	return NewRobotsTxt(RobotsConfig{
+		Store:     store,
+		TTL:       time.Duration(config.TTL) * time.Second,
+		ErrorTTL:  time.Duration(config.ErrorTTL) * time.Second,
+		UserAgent: userAgent,
+		Metrics:   metrics,
+	})
+}
+
+// errRobotsRedirects stops a robots.txt fetch after too many redirects
+var errRobotsRedirects = errors.New("too many robots.txt redirects")
+
+// RobotsDisallowedError is returned for a URL robots.txt disallows
+type RobotsDisallowedError struct {
+	URL string
+}
+
+// Error implements the error interface
+func (e *RobotsDisallowedError) Error() string {
+	return fmt.Sprintf("%s is disallowed by robots.txt", e.URL)
+}
+
+// ErrorCode implements errs.Coder
+func (e *RobotsDisallowedError) ErrorCode() errs.Code {
+	return errs.CodeRobotsDisallowed
+}
+
+// Allowed reports whether agent, a product token such as RobotsAgent
+// returns, may crawl rawURL. Errors are returned only for invalid URLs and
+// when ctx is done; robots.txt itself is always allowed
+func (r *RobotsTxt) Allowed(ctx context.Context, rawURL, agent string) (bool, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, err
+	}
+	if u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return false, fmt.Errorf("cannot check robots.txt of %s", rawURL)
+	}
+	if u.Path == "/robots.txt" {
+		return true, nil
+	}
+
+	parsed, err := r.rules(ctx, strings.ToLower(u.Scheme+"://"+u.Host))
+	if err != nil {
+		return false, err
+	}
+	return parsed.data.TestAgent(u.RequestURI(), agent), nil
+}
+
+// rules returns the parsed rules of origin, fetching robots.txt when
+// neither this process nor the store has a fresh copy
+func (r *RobotsTxt) rules(ctx context.Context, origin string) (*parsedRobots, error) {
+	r.mu.Lock()
+	lock, ok := r.locks[origin]
+	if !ok {
+		lock = &sync.Mutex{}
+		r.locks[origin] = lock
+	}
+	r.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	now := r.config.Clock.Now()
+	r.mu.Lock()
+	parsed, ok := r.parsed[origin]
+	r.mu.Unlock()
+	if ok && now.Before(parsed.expires) {
+		return parsed, nil
+	}
+
+	// A store that cannot be read is treated as empty
+	stored, _ := r.config.Store.GetRobots(origin)
+	entry := stored
+	if stored == nil || !now.Before(stored.ExpiresAt) {
+		var err error
+		if entry, err = r.fetch(ctx, origin, stored); err != nil {
+			return nil, err
+		}
+	} else {
+		r.record(RobotsOutcomeShared)
+	}
+
+	parsed = &parsedRobots{data: parseRobots(entry), rates: parseRequestRates(entry), expires: entry.ExpiresAt}
+	r.mu.Lock()
+	r.parsed[origin] = parsed
+	r.mu.Unlock()
+	return parsed, nil
+}
+
+// fetch downloads the robots.txt of origin and stores the resulting entry;
+// stale is the expired entry of origin, if any
+func (r *RobotsTxt) fetch(ctx context.Context, origin string, stale *RobotsEntry) (*RobotsEntry, error) {
+	status, body, err := r.download(ctx, origin+"/robots.txt")
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	now := r.config.Clock.Now()
+
+	var outcome string
+	switch {
+	case errors.Is(err, errRobotsRedirects):
+		outcome, status = RobotsOutcomeUnavailable, http.StatusNotFound
+	case err != nil:
+		outcome, status = RobotsOutcomeUnreachable, 0
+	case status >= 200 && status < 300:
+		outcome = RobotsOutcomeFetched
+	case status == http.StatusTooManyRequests || status >= 500:
+		outcome = RobotsOutcomeServerError
+	case status >= 400:
+		outcome = RobotsOutcomeUnavailable
+	default: // 1xx, or a 3xx without Location
+		outcome, status = RobotsOutcomeUnavailable, http.StatusNotFound
+	}
+	r.record(outcome)
+
+	var entry *RobotsEntry
+	ttl := r.config.StaleTTL
+	switch {
+	case outcome == RobotsOutcomeFetched || outcome == RobotsOutcomeUnavailable:
+		entry = &RobotsEntry{Status: status, Body: body, FetchedAt: now, ExpiresAt: now.Add(r.config.TTL)}
+	case stale != nil && !stale.FetchedAt.IsZero() && now.Sub(stale.FetchedAt) < r.config.StaleTTL:
+		// Keep the last answer until it is too old to stand in
+		entry = stale
+		entry.ExpiresAt = now.Add(r.config.ErrorTTL)
+		ttl = stale.FetchedAt.Add(r.config.StaleTTL).Sub(now)
+	default:
+		entry = &RobotsEntry{Status: status, ExpiresAt: now.Add(r.config.ErrorTTL)}
+		ttl = r.config.ErrorTTL
+	}
+
+	// A store that cannot be written only costs other workers a fetch
+	_ = r.config.Store.SetRobots(origin, entry, ttl)
+	return entry, nil
+}
+
+// download fetches robotsURL, returning its status and at most MaxSize
+// bytes of its body
+func (r *RobotsTxt) download(ctx context.Context, robotsURL string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return 0, "", err
+	}
+	if r.config.UserAgent != "" {
+		req.Header.Set("User-Agent", r.config.UserAgent)
+	}
+
+	resp, err := r.config.Client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, "", nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, r.config.MaxSize))
+	if err != nil {
+		return 0, "", err
+	}
+	return resp.StatusCode, string(body), nil
+}
+
+// record counts a fetch outcome
+func (r *RobotsTxt) record(outcome string) {
+	if r.config.Metrics != nil {
+		r.config.Metrics.RecordRobotsFetch(outcome)
+	}
+}
+
+// parseRobots returns the rules of an entry; a robots.txt that cannot be
+// parsed has no valid rules and allows everything
+func parseRobots(entry *RobotsEntry) *robotstxt.RobotsData {
+	switch {
+	case entry.Status >= 200 && entry.Status < 300:
+		if data, err := robotstxt.FromString(entry.Body); err == nil {
+			return data
+		}
+		return robotsAllowAll
+	case entry.Status >= 400 && entry.Status < 500 && entry.Status != http.StatusTooManyRequests:
+		return robotsAllowAll
+	default:
+		return robotsDisallowAll
+	}
+}
+
+// RobotsMiddleware fails fetches of URLs robots.txt disallows for agent
+// with a RobotsDisallowedError
+func RobotsMiddleware(robots *RobotsTxt, agent string) Middleware {
+	return func(next Fetcher) Fetcher {
+		return FetcherFunc(func(ctx context.Context, url string) (*Result, error) {
+			allowed, err := robots.Allowed(ctx, url, agent)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				return nil, &RobotsDisallowedError{URL: url}
+			}
+			return next.Fetch(ctx, url)
+		})
+	}
+}
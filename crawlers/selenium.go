@@ -2,6 +2,7 @@ package crawlers
 
 import (
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/tebeka/selenium"
@@ -9,8 +10,10 @@ import (
 
 // SeleniumClient wraps Selenium WebDriver operations
 type SeleniumClient struct {
-	driver  selenium.WebDriver
-	service *selenium.Service
+	driver       selenium.WebDriver
+	service      *selenium.Service
+	proxy        *browserProxy
+	waitInterval time.Duration
 }
 
 // SeleniumConfig holds Selenium configuration
@@ -20,12 +23,22 @@ type SeleniumConfig struct {
 	Port        int
 	Headless    bool
 	RemoteURL   string // Optional: use remote Selenium server
+
+	// Proxy is an optional http, https or socks5 proxy URL, credentials
+	// included. A local browser reaches authenticated proxies through a relay;
+	// a remote one gets the proxy as a capability, which cannot carry HTTP
+	// proxy credentials
+	Proxy string
+
+	// WaitInterval is how often WaitUntil checks its condition (default 100ms)
+	WaitInterval time.Duration
 }
 
 // NewSeleniumClient creates a new Selenium WebDriver client
 func NewSeleniumClient(config SeleniumConfig) (*SeleniumClient, error) {
 	var driver selenium.WebDriver
 	var service *selenium.Service
+	var proxy *browserProxy
 	var err error
 
 	// Use remote URL if provided, otherwise start local service
@@ -37,6 +50,13 @@ func NewSeleniumClient(config SeleniumConfig) (*SeleniumClient, error) {
 			}
 			caps["goog:chromeOptions"] = chromeArgs
 		}
+		if config.Proxy != "" {
+			capability, err := remoteSeleniumProxy(config.Proxy)
+			if err != nil {
+				return nil, err
+			}
+			caps.AddProxy(capability)
+		}
 
 		driver, err = selenium.NewRemote(caps, config.RemoteURL)
 		if err != nil {
@@ -48,9 +68,15 @@ func NewSeleniumClient(config SeleniumConfig) (*SeleniumClient, error) {
 			config.Port = 4444
 		}
 
+		proxy, err = newBrowserProxy(config.Proxy, false)
+		if err != nil {
+			return nil, err
+		}
+
 		opts := []selenium.ServiceOption{}
 		service, err = selenium.NewSeleniumService(config.DriverPath, config.Port, opts...)
 		if err != nil {
+			proxy.close()
 			return nil, fmt.Errorf("failed to start Selenium service: %w", err)
 		}
 
@@ -61,20 +87,54 @@ func NewSeleniumClient(config SeleniumConfig) (*SeleniumClient, error) {
 			}
 			caps["goog:chromeOptions"] = chromeArgs
 		}
+		if proxy != nil {
+			server, _ := url.Parse(proxy.server) // Built by newBrowserProxy, without credentials
+			caps.AddProxy(seleniumProxy(server))
+		}
 
 		driver, err = selenium.NewRemote(caps, fmt.Sprintf("http://localhost:%d/wd/hub", config.Port))
 		if err != nil {
 			_ = service.Stop() // Best effort cleanup
+			proxy.close()
 			return nil, fmt.Errorf("failed to create driver: %w", err)
 		}
 	}
 
 	return &SeleniumClient{
-		driver:  driver,
-		service: service,
+		driver:       driver,
+		service:      service,
+		proxy:        proxy,
+		waitInterval: config.WaitInterval,
 	}, nil
 }
 
+// remoteSeleniumProxy builds the proxy capability for a remote browser,
+// which cannot reach a local relay
+func remoteSeleniumProxy(raw string) (selenium.Proxy, error) {
+	parsed, err := ParseProxyURL(raw)
+	if err != nil {
+		return selenium.Proxy{}, err
+	}
+	if parsed.User != nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		return selenium.Proxy{}, fmt.Errorf("remote Selenium browsers cannot authenticate to HTTP proxies; use a SOCKS5 proxy")
+	}
+	return seleniumProxy(parsed), nil
+}
+
+// seleniumProxy converts a proxy URL to a manual proxy capability
+// SOCKS5 credentials are passed along; Firefox honors them
+func seleniumProxy(proxy *url.URL) selenium.Proxy {
+	if proxy.Scheme == "socks5" || proxy.Scheme == "socks5h" {
+		capability := selenium.Proxy{Type: selenium.Manual, SOCKS: proxy.Host, SOCKSVersion: 5}
+		if proxy.User != nil {
+			capability.SOCKSUsername = proxy.User.Username()
+			capability.SOCKSPassword, _ = proxy.User.Password()
+		}
+		return capability
+	}
+	return selenium.Proxy{Type: selenium.Manual, HTTP: proxy.Host, SSL: proxy.Host}
+}
+
 // Navigate navigates to a URL
 func (s *SeleniumClient) Navigate(url string) error {
 	return s.driver.Get(url)
@@ -205,6 +265,8 @@ func (s *SeleniumClient) MaximizeWindow() error {
 
 // Close closes the current window
 func (s *SeleniumClient) Close() error {
+	defer s.proxy.close()
+
 	if err := s.driver.Quit(); err != nil {
 		return err
 	}
@@ -0,0 +1,200 @@
+package crawlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/tebeka/selenium"
+)
+
+// defaultWaitInterval is how often WaitUntil polls by default
+const defaultWaitInterval = 100 * time.Millisecond
+
+// WaitCondition is a page state SeleniumClient.WaitUntil polls for
+type WaitCondition struct {
+	Name string // Describes the state in timeout errors, e.g. `visible #results`
+
+	// Check reports whether the state is reached; an error ends the wait
+	Check func(driver selenium.WebDriver) (bool, error)
+}
+
+// ElementPresent waits for an element to be in the DOM
+func ElementPresent(by, value string) WaitCondition {
+	return WaitCondition{
+		Name: fmt.Sprintf("present %s", value),
+		Check: func(driver selenium.WebDriver) (bool, error) {
+			_, found, err := findElement(driver, by, value)
+			return found, err
+		},
+	}
+}
+
+// ElementVisible waits for an element to be displayed
+func ElementVisible(by, value string) WaitCondition {
+	return WaitCondition{
+		Name: fmt.Sprintf("visible %s", value),
+		Check: func(driver selenium.WebDriver) (bool, error) {
+			element, found, err := findElement(driver, by, value)
+			if !found || err != nil {
+				return false, err
+			}
+			return elementState(element.IsDisplayed())
+		},
+	}
+}
+
+// ElementClickable waits for an element to be displayed and enabled
+func ElementClickable(by, value string) WaitCondition {
+	return WaitCondition{
+		Name: fmt.Sprintf("clickable %s", value),
+		Check: func(driver selenium.WebDriver) (bool, error) {
+			element, found, err := findElement(driver, by, value)
+			if !found || err != nil {
+				return false, err
+			}
+			if displayed, err := elementState(element.IsDisplayed()); !displayed || err != nil {
+				return false, err
+			}
+			return elementState(element.IsEnabled())
+		},
+	}
+}
+
+// URLMatches waits for the current URL to match pattern, e.g. after a
+// redirect or a single-page app route change
+func URLMatches(pattern *regexp.Regexp) WaitCondition {
+	return WaitCondition{
+		Name: fmt.Sprintf("URL matching %s", pattern),
+		Check: func(driver selenium.WebDriver) (bool, error) {
+			current, err := driver.CurrentURL()
+			if err != nil {
+				return false, err
+			}
+			return pattern.MatchString(current), nil
+		},
+	}
+}
+
+// URLContains waits for the current URL to contain substr
+func URLContains(substr string) WaitCondition {
+	return WaitCondition{
+		Name: fmt.Sprintf("URL containing %q", substr),
+		Check: func(driver selenium.WebDriver) (bool, error) {
+			current, err := driver.CurrentURL()
+			if err != nil {
+				return false, err
+			}
+			return strings.Contains(current, substr), nil
+		},
+	}
+}
+
+// ScriptCondition waits for a JavaScript function body to return a truthy
+// value, e.g. `return window.appReady === true`
+func ScriptCondition(script string, args ...interface{}) WaitCondition {
+	return WaitCondition{
+		Name: fmt.Sprintf("script %q", script),
+		Check: func(driver selenium.WebDriver) (bool, error) {
+			result, err := driver.ExecuteScript(script, args)
+			if err != nil {
+				return false, err
+			}
+			return truthy(result), nil
+		},
+	}
+}
+
+// WaitUntil polls condition every SeleniumConfig.WaitInterval until it holds
+// It fails when ctx is done, when timeout elapses (0 waits for ctx only) or
+// when the condition returns an error
+func (s *SeleniumClient) WaitUntil(ctx context.Context, condition WaitCondition, timeout time.Duration) error {
+	return PollCondition(ctx, s.driver, condition, timeout, s.waitInterval)
+}
+
+// PollCondition polls condition against driver every interval until it
+// holds, like SeleniumClient.WaitUntil
+// A timeout error wraps context.DeadlineExceeded
+func PollCondition(ctx context.Context, driver selenium.WebDriver, condition WaitCondition, timeout, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		ok, err := condition.Check(driver)
+		if err != nil {
+			return fmt.Errorf("failed waiting for %s: %w", condition.Name, err)
+		}
+		if ok {
+			return nil
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for %s: %w", condition.Name, ctx.Err())
+		}
+	}
+}
+
+// findElement looks up an element, reporting a missing or stale one as not
+// found rather than as an error
+func findElement(driver selenium.WebDriver, by, value string) (selenium.WebElement, bool, error) {
+	element, err := driver.FindElement(by, value)
+	if err != nil {
+		if transientElementError(err) {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+	return element, true, nil
+}
+
+// elementState passes on an element's state; a stale or not yet
+// interactable element does not hold it
+func elementState(ok bool, err error) (bool, error) {
+	if err != nil && transientElementError(err) {
+		return false, nil
+	}
+	return ok, err
+}
+
+// transientElementError reports whether err means the element is missing
+// or was replaced, which polling outlasts
+func transientElementError(err error) bool {
+	var webErr *selenium.Error
+	if !errors.As(err, &webErr) {
+		return false
+	}
+	switch webErr.Err {
+	case "no such element", "stale element reference", "element not interactable":
+		return true
+	}
+	// NoSuchElement and StaleElementReference of the legacy wire protocol
+	return webErr.LegacyCode == 7 || webErr.LegacyCode == 10
+}
+
+// truthy applies JavaScript truthiness to a script result
+func truthy(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	case float64:
+		return v != 0 && v == v // NaN is falsy
+	case string:
+		return v != ""
+	default:
+		return true
+	}
+}
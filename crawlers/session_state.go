@@ -0,0 +1,140 @@
+package crawlers
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/playwright-community/playwright-go"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Session state files start with a magic header followed by the scrypt salt,
+// the AES-GCM nonce and the sealed JSON storage state
+const (
+	sessionStateMagic = "GWSTATE1"
+	sessionSaltSize   = 16
+)
+
+// SessionStateKeyEnv is the environment variable the session commands read
+// the session state passphrase from
+const SessionStateKeyEnv = "GOLWARC_SESSION_KEY"
+
+// SaveSessionState encrypts a browser storage state (cookies and
+// localStorage per origin) with passphrase and writes it to path, readable
+// only by the owner
+// Session cookies grant whatever access the logged-in user has, so the file
+// is never written in the clear
+func SaveSessionState(path, passphrase string, state *playwright.StorageState) error {
+	if passphrase == "" {
+		return errs.New(errs.CodeInvalidConfig, "session state passphrase cannot be empty")
+	}
+	plain, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode session state: %w", err)
+	}
+
+	salt := make([]byte, sessionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := sessionCipher(passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(sessionStateMagic)
+	buf.Write(salt)
+	buf.Write(nonce)
+	buf.Write(aead.Seal(nil, nonce, plain, []byte(sessionStateMagic)))
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return fmt.Errorf("failed to create session state directory: %w", err)
+		}
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp) // Best effort cleanup
+		return fmt.Errorf("failed to write session state: %w", err)
+	}
+	return nil
+}
+
+// LoadSessionState decrypts a session state written by SaveSessionState
+// A wrong passphrase or a tampered file returns CodeInvalidConfig
+func LoadSessionState(path, passphrase string) (*playwright.StorageState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session state: %w", err)
+	}
+	header := len(sessionStateMagic) + sessionSaltSize
+	if len(data) < header || string(data[:len(sessionStateMagic)]) != sessionStateMagic {
+		return nil, errs.Newf(errs.CodeInvalidConfig, "%s is not a session state file", path)
+	}
+
+	aead, err := sessionCipher(passphrase, data[len(sessionStateMagic):header])
+	if err != nil {
+		return nil, err
+	}
+	sealed := data[header:]
+	if len(sealed) < aead.NonceSize() {
+		return nil, errs.Newf(errs.CodeInvalidConfig, "%s is truncated", path)
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(sessionStateMagic))
+	if err != nil {
+		return nil, errs.Newf(errs.CodeInvalidConfig, "failed to decrypt %s: wrong passphrase or corrupted file", path)
+	}
+
+	var state playwright.StorageState
+	if err := json.Unmarshal(plain, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode session state: %w", err)
+	}
+	return &state, nil
+}
+
+// sessionCipher derives an AES-256-GCM cipher from a passphrase
+func sessionCipher(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive session key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// ExportSessionState writes the storage state of the client's page, e.g.
+// after logging in, to an encrypted file for SessionOptions.StatePath
+func (p *PlaywrightClient) ExportSessionState(path, passphrase string) error {
+	state, err := p.page.Context().StorageState()
+	if err != nil {
+		return fmt.Errorf("failed to read storage state: %w", err)
+	}
+	return SaveSessionState(path, passphrase, state)
+}
+
+// ExportSessionState writes the session's storage state to an encrypted file
+func (s *PlaywrightSession) ExportSessionState(path, passphrase string) error {
+	state, err := s.context.StorageState()
+	if err != nil {
+		return fmt.Errorf("failed to read storage state: %w", err)
+	}
+	return SaveSessionState(path, passphrase, state)
+}
@@ -0,0 +1,145 @@
+package crawlers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// maxSkipDecisions caps the decisions kept in a SkipSummary
+const maxSkipDecisions = 100
+
+// Skip reasons: why a URL was not crawled
+const (
+	SkipRobots      = "robots"       // Disallowed by robots.txt
+	SkipURLRule     = "url_rule"     // Denied by a urlmatch rule
+	SkipBudget      = "budget"       // The crawl budget ran out
+	SkipDuplicate   = "duplicate"    // Already crawled, cached or stored
+	SkipSSRF        = "ssrf"         // Failed the URL validation policy
+	SkipDepth       = "depth"        // Beyond the maximum depth
+	SkipNoFollow    = "nofollow"     // Found on a nofollow page
+	SkipContentType = "content_type" // Media type rejected by the content type filter
+	SkipThrottled   = "throttled"    // Still answering 429 or 503 after the retries allowed
+)
+
+// SkipDecision records why a URL was not crawled
+type SkipDecision struct {
+	URL    string `json:"url"`
+	Reason string `json:"reason"` // One of the Skip* constants
+
+	// Rule is what made the decision: the matching rule or pattern, the
+	// exhausted budget limit, the rejected media type or the validation error
+	Rule string `json:"rule,omitempty"`
+
+	ParentURL string `json:"parent_url,omitempty"` // Page the URL was found on; empty for start URLs
+}
+
+// String formats the decision for logs, e.g. "url_rule (deny */login*)"
+func (d SkipDecision) String() string {
+	if d.Rule == "" {
+		return d.Reason
+	}
+	return d.Reason + " (" + d.Rule + ")"
+}
+
+// SkippedError is returned for a URL a crawl decided not to fetch
+type SkippedError struct {
+	Decision SkipDecision
+}
+
+// Error implements the error interface
+func (e *SkippedError) Error() string {
+	return fmt.Sprintf("skipped %s: %s", e.Decision.URL, e.Decision)
+}
+
+// ErrorCode implements errs.Coder
+func (e *SkippedError) ErrorCode() errs.Code {
+	if e.Decision.Reason == SkipRobots {
+		return errs.CodeRobotsDisallowed
+	}
+	return errs.CodeURLSkipped
+}
+
+// SkipDecisionOf returns the decision behind err when it reports a skipped
+// URL: a SkippedError, RobotsDisallowedError or SkippedContentError
+func SkipDecisionOf(err error) (SkipDecision, bool) {
+	var skipped *SkippedError
+	var robots *RobotsDisallowedError
+	var content *SkippedContentError
+	switch {
+	case errors.As(err, &skipped):
+		return skipped.Decision, true
+	case errors.As(err, &robots):
+		return SkipDecision{URL: robots.URL, Reason: SkipRobots}, true
+	case errors.As(err, &content):
+		return SkipDecision{URL: content.URL, Reason: SkipContentType, Rule: content.ContentType}, true
+	default:
+		return SkipDecision{}, false
+	}
+}
+
+// SkipSummary aggregates the URLs a crawl skipped
+type SkipSummary struct {
+	Total    int            `json:"total"`
+	ByReason map[string]int `json:"by_reason"`
+	ByRule   map[string]int `json:"by_rule"`         // Keyed by SkipDecision.String, e.g. "url_rule (deny /admin/*)"
+	First    []SkipDecision `json:"first,omitempty"` // The first skipped URLs, at most 100
+}
+
+// skipLog aggregates skip decisions and passes them to handlers
+// The zero value is ready to use
+type skipLog struct {
+	mu       sync.Mutex
+	summary  SkipSummary
+	handlers []func(SkipDecision)
+}
+
+// onSkip registers a handler called for every decision
+func (l *skipLog) onSkip(handler func(SkipDecision)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers = append(l.handlers, handler)
+}
+
+// record counts a decision and calls the handlers outside the lock
+func (l *skipLog) record(d SkipDecision) {
+	l.mu.Lock()
+	if l.summary.ByReason == nil {
+		l.summary.ByReason = make(map[string]int)
+		l.summary.ByRule = make(map[string]int)
+	}
+	l.summary.Total++
+	l.summary.ByReason[d.Reason]++
+	l.summary.ByRule[d.String()]++
+	if len(l.summary.First) < maxSkipDecisions {
+		l.summary.First = append(l.summary.First, d)
+	}
+	handlers := l.handlers
+	l.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(d)
+	}
+}
+
+// snapshot returns a copy of the summary
+func (l *skipLog) snapshot() SkipSummary {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	summary := SkipSummary{
+		Total:    l.summary.Total,
+		ByReason: make(map[string]int, len(l.summary.ByReason)),
+		ByRule:   make(map[string]int, len(l.summary.ByRule)),
+		First:    append([]SkipDecision(nil), l.summary.First...),
+	}
+	for reason, n := range l.summary.ByReason {
+		summary.ByReason[reason] = n
+	}
+	for rule, n := range l.summary.ByRule {
+		summary.ByRule[rule] = n
+	}
+	return summary
+}
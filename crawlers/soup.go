@@ -1,23 +1,96 @@
 package crawlers
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
+	"github.com/alonecandies/golwarc/errs"
 	"github.com/anaskhan96/soup"
+	"golang.org/x/net/html/charset"
 )
 
 // SoupClient wraps soup HTML parsing operations
 type SoupClient struct {
-	userAgent string
-	timeout   time.Duration
+	userAgent  string
+	httpClient *http.Client
+	cooldown   *DomainCooldown
+	limiter    *RateLimiter
+	proxies    *ProxyPool
+	maxBody    int64
+	types      *ContentTypeFilter
+	blocks     *BlockDetector
+	cookies    *CookieJar
+	breaker    *CircuitBreaker
+	cache      *HTTPCache
 }
 
 // SoupConfig holds Soup client configuration
 type SoupConfig struct {
-	UserAgent string
-	Timeout   time.Duration
+	UserAgent   string
+	Timeout     time.Duration
+	Cooldown    *DomainCooldown // Optional per-domain backoff on 429/503
+	RateLimiter *RateLimiter    // Optional limiter shared with other clients
+
+	Proxies       []string // Optional proxy URLs to rotate through
+	ProxyStrategy string   // round_robin (default), random, sticky, or scored
+
+	Transport TransportConfig // Connection pooling, HTTP/2, TLS, proxy and dial settings
+
+	// HTTPClient sends requests instead of a client built from Timeout and
+	// Transport, e.g. one set up for mTLS or instrumentation. It is copied,
+	// so its transport is wrapped for proxies, HSTS and metrics without
+	// changing the caller's client; its Timeout and Jar are used as is
+	// unless Cookies is set
+	HTTPClient *http.Client
+
+	// RoundTripper replaces the transport built from Transport, or that of
+	// HTTPClient. Proxies are only rotated through an *http.Transport
+	RoundTripper http.RoundTripper
+
+	// MaxBodySize fails Get calls whose response body exceeds this many bytes
+	// before the rest is read; 0 means unlimited. GetStream is not limited
+	MaxBodySize int64
+
+	// ContentTypes makes Get fail with a SkippedContentError for responses
+	// whose media type it rejects, without reading them. GetStream is not filtered
+	ContentTypes *ContentTypeFilter
+
+	// HSTS sends requests for hosts known to support https over https and
+	// records the HSTS headers of responses; may be shared with other clients
+	HSTS *HSTS
+
+	// Metrics records every request by status; optional, e.g. *libs.Metrics
+	Metrics Metrics
+
+	// BlockDetector makes Get fail with a BlockedError for CAPTCHA and bot
+	// walls. GetStream is not checked
+	BlockDetector *BlockDetector
+
+	// Cookies stores Set-Cookie responses and sends them back, e.g. a jar
+	// shared with a CollyClient or filled by ImportCookies; nil sends none
+	Cookies *CookieJar
+
+	// Auth logs in with a form before the first request to its hosts and
+	// again when their session expires; may be shared with other clients
+	Auth *AuthFlow
+
+	// CircuitBreaker fails requests to hosts that keep failing with a
+	// CircuitOpenError instead of sending them; may be shared with other
+	// clients
+	CircuitBreaker *CircuitBreaker
+
+	// DNSCache resolves the hostnames of new connections from a cache; may
+	// be shared with other clients. Only an *http.Transport is wrapped
+	DNSCache *DNSCache
+
+	// HTTPCache answers Get calls from stored responses while Cache-Control
+	// and Expires say they are fresh, and revalidates stale ones; may be
+	// shared with other clients. GetStream is not cached
+	HTTPCache *HTTPCache
 }
 
 // NewSoupClient creates a new Soup-based HTML parser
@@ -29,13 +102,48 @@ func NewSoupClient(config SoupConfig) *SoupClient {
 		config.Timeout = 30 * time.Second
 	}
 
-	// Configure soup
-	soup.Header("User-Agent", config.UserAgent)
+	transport := config.RoundTripper
+	if transport == nil && config.HTTPClient == nil {
+		transport = NewTransport(config.Transport)
+	}
+	client := &SoupClient{
+		userAgent:  config.UserAgent,
+		httpClient: clientFrom(config.HTTPClient, transport, config.Timeout),
+		cooldown:   config.Cooldown,
+		limiter:    config.RateLimiter,
+		maxBody:    config.MaxBodySize,
+		types:      config.ContentTypes,
+		blocks:     config.BlockDetector,
+		cookies:    config.Cookies,
+		breaker:    config.CircuitBreaker,
+		cache:      config.HTTPCache,
+	}
+	if config.Cookies != nil {
+		client.httpClient.Jar = config.Cookies
+	}
 
-	return &SoupClient{
-		userAgent: config.UserAgent,
-		timeout:   config.Timeout,
+	client.httpClient.Transport = config.DNSCache.Transport(client.httpClient.Transport)
+
+	if len(config.Proxies) > 0 {
+		pool, err := NewProxyPool(ProxyPoolConfig{
+			Proxies:  config.Proxies,
+			Strategy: config.ProxyStrategy,
+		})
+		if err != nil {
+			// Fetch directly rather than failing client construction
+			fmt.Printf("warning: failed to configure proxies: %v\n", err)
+		} else if proxied, ok := proxiedTransport(pool, client.httpClient.Transport); ok {
+			client.httpClient.Transport = proxied
+			client.proxies = pool
+		} else {
+			fmt.Printf("warning: proxies need an *http.Transport, got %T\n", client.httpClient.Transport)
+		}
 	}
+	client.httpClient.Transport = config.Auth.Transport(client.httpClient.Transport)
+	client.httpClient.Transport = config.HSTS.Transport(client.httpClient.Transport)
+	client.httpClient.Transport = instrumentTransport(client.httpClient.Transport, config.Metrics, CrawlerTypeSoup)
+
+	return client
 }
 
 // NewDefaultSoupClient creates a Soup client with default settings
@@ -46,32 +154,243 @@ func NewDefaultSoupClient() *SoupClient {
 	})
 }
 
+// ProxyPool returns the proxy pool, or nil when no proxies are configured
+func (c *SoupClient) ProxyPool() *ProxyPool {
+	return c.proxies
+}
+
 // Get fetches and parses a URL, returning a soup.Root
 func (c *SoupClient) Get(url string) (soup.Root, error) {
-	resp, err := soup.Get(url)
+	return c.GetWithHeadersContext(context.Background(), url, nil)
+}
+
+// GetContext fetches and parses a URL, aborting when ctx is done
+func (c *SoupClient) GetContext(ctx context.Context, url string) (soup.Root, error) {
+	return c.GetWithHeadersContext(ctx, url, nil)
+}
+
+// GetWithHeaders fetches a URL with custom headers
+func (c *SoupClient) GetWithHeaders(url string, headers map[string]string) (soup.Root, error) {
+	return c.GetWithHeadersContext(context.Background(), url, headers)
+}
+
+// GetWithHeadersContext fetches a URL with custom headers, aborting when ctx is done
+func (c *SoupClient) GetWithHeadersContext(ctx context.Context, url string, headers map[string]string) (soup.Root, error) {
+	_, body, err := c.fetch(ctx, url, headers)
 	if err != nil {
 		return soup.Root{}, fmt.Errorf("failed to fetch URL: %w", err)
 	}
 
-	doc := soup.HTMLParse(resp)
+	doc := soup.HTMLParse(body)
 	return doc, nil
 }
 
-// GetWithHeaders fetches a URL with custom headers
-func (c *SoupClient) GetWithHeaders(url string, headers map[string]string) (soup.Root, error) {
-	// Set custom headers
+// fetch performs a GET request and returns the response, whose body is
+// already closed, with the UTF-8 decoded body
+// Fresh cached responses are returned without a request; stale ones are
+// revalidated, and new responses are stored once read without error
+func (c *SoupClient) fetch(ctx context.Context, rawURL string, headers map[string]string) (*http.Response, string, error) {
+	req, err := c.newRequest(ctx, rawURL, headers)
+	if err != nil {
+		return nil, "", err
+	}
+	cached, fresh := c.cache.lookup(req)
+	if fresh {
+		resp := cached.response(req)
+		body, err := c.read(rawURL, resp)
+		if err != nil {
+			return nil, "", err
+		}
+		return resp, body, nil
+	}
+
+	requestTime := c.cache.now()
+	resp, release, err := c.do(ctx, rawURL, req, c.types)
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	var recorder *httpCacheRecorder
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+		resp = c.cache.revalidate(req, cached, resp, requestTime)
+	} else {
+		recorder = c.cache.record(req, resp)
+	}
+
+	body, err := c.read(rawURL, resp)
+	if !IsCircuitFailure(resp.StatusCode) {
+		var blocked *BlockedError
+		switch {
+		case errors.As(err, &blocked):
+			c.breaker.Report(rawURL, true)
+		case ctx.Err() != nil:
+			c.breaker.Abandon(rawURL)
+		default:
+			c.breaker.Report(rawURL, false)
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	c.cache.commit(req, resp, recorder, requestTime)
+	return resp, body, nil
+}
+
+// read decodes the body of resp, enforcing MaxBodySize, ContentTypes and
+// BlockDetector
+func (c *SoupClient) read(rawURL string, resp *http.Response) (string, error) {
+	if c.maxBody > 0 && resp.ContentLength > c.maxBody {
+		return "", c.bodyTooLarge(rawURL)
+	}
+
+	var body io.Reader = resp.Body
+	if c.maxBody > 0 {
+		// Read one byte past the limit to tell a full body from an oversized one
+		body = io.LimitReader(resp.Body, c.maxBody+1)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if c.types != nil {
+		contentType, body = sniffContentType(resp.Header, body)
+		if !c.types.Allowed(contentType) {
+			return "", &SkippedContentError{URL: rawURL, ContentType: contentType}
+		}
+	}
+
+	reader, err := charset.NewReader(body, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	if c.maxBody > 0 && int64(len(data)) > c.maxBody {
+		return "", c.bodyTooLarge(rawURL)
+	}
+	if c.blocks != nil {
+		if b := c.blocks.Check(rawURL, resp.StatusCode, resp.Header, data); b != nil {
+			return "", &BlockedError{Block: *b}
+		}
+	}
+
+	return string(data), nil
+}
+
+// bodyTooLarge reports a response over MaxBodySize
+func (c *SoupClient) bodyTooLarge(rawURL string) error {
+	return errs.Newf(errs.CodeBodyTooLarge, "response body of %s exceeds %d bytes", rawURL, c.maxBody)
+}
+
+// GetStream performs a GET request and returns the response with its body
+// unread, so large downloads can be copied to disk or discarded without
+// buffering. Cooldown and rate limiting apply as for Get, and the rate
+// limiter slot is held until the body is closed. The caller must close it
+func (c *SoupClient) GetStream(ctx context.Context, url string) (*http.Response, error) {
+	req, err := c.newRequest(ctx, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	resp, release, err := c.do(ctx, url, req, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch URL: %w", err)
+	}
+	if !IsCircuitFailure(resp.StatusCode) {
+		c.breaker.Report(url, false)
+	}
+
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// newRequest builds a GET request with the client's User-Agent, headers and
+// the RequestOptions of ctx
+func (c *SoupClient) newRequest(ctx context.Context, rawURL string, headers map[string]string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", c.userAgent)
 	for key, value := range headers {
-		soup.Header(key, value)
+		req.Header.Set(key, value)
 	}
+	RequestOptionsFrom(ctx).Apply(req)
+	return req, nil
+}
 
-	return c.Get(url)
+// do sends a GET request through the circuit breaker, after waiting out
+// cooldowns and acquiring a rate limiter slot, preceded by a HEAD request
+// when types asks for one. On success the caller owns the response body and
+// must call release once done with it; it reports the outcome of responses
+// that are not circuit failures to the breaker
+func (c *SoupClient) do(ctx context.Context, rawURL string, req *http.Request, types *ContentTypeFilter) (*http.Response, func(), error) {
+	if err := c.breaker.Allow(rawURL); err != nil {
+		return nil, nil, err
+	}
+
+	resp, release, err := c.send(ctx, rawURL, req, types)
+	var skipped *SkippedContentError
+	switch {
+	case err == nil:
+		if IsCircuitFailure(resp.StatusCode) {
+			c.breaker.Report(rawURL, true)
+		}
+	case ctx.Err() != nil:
+		c.breaker.Abandon(rawURL)
+	default:
+		c.breaker.Report(rawURL, !errors.As(err, &skipped))
+	}
+	return resp, release, err
+}
+
+// send performs the request of do
+func (c *SoupClient) send(ctx context.Context, rawURL string, req *http.Request, types *ContentTypeFilter) (*http.Response, func(), error) {
+	if c.cooldown != nil {
+		if err := c.cooldown.Wait(ctx, rawURL); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	release := func() {}
+	if c.limiter != nil {
+		var err error
+		if release, err = c.limiter.Acquire(ctx, rawURL); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if types.HeadRequests() {
+		if contentType, ok := headContentType(c.httpClient, req); ok && !types.Allowed(contentType) {
+			release()
+			return nil, nil, &SkippedContentError{URL: rawURL, ContentType: contentType}
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	if delay, throttled := observeThrottle(c.limiter, c.cooldown, rawURL, resp.StatusCode, resp.Header); throttled {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+		release()
+		return nil, nil, &ThrottledError{URL: rawURL, StatusCode: resp.StatusCode, Delay: delay}
+	}
+
+	return resp, release, nil
 }
 
 // Post sends a POST request and parses the response
 func (c *SoupClient) Post(url string, data map[string]string) (soup.Root, error) {
 	// Note: soup library has limited POST support, using http.Client instead
-	client := &http.Client{Timeout: c.timeout}
-
 	req, err := http.NewRequest("POST", url, nil)
 	if err != nil {
 		return soup.Root{}, err
@@ -86,10 +405,11 @@ func (c *SoupClient) Post(url string, data map[string]string) (soup.Root, error)
 	}
 	req.URL.RawQuery = q.Encode()
 
-	resp, err := client.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return soup.Root{}, err
 	}
+	_ = c.cache.Invalidate(url) // A stale copy would outlive the change; error intentionally ignored
 	defer func() {
 		_ = resp.Body.Close() // Error intentionally ignored on close
 	}()
@@ -101,6 +421,9 @@ func (c *SoupClient) Post(url string, data map[string]string) (soup.Root, error)
 
 // FindAll finds all elements matching the tag and attributes
 func (c *SoupClient) FindAll(doc soup.Root, tag string, attrs map[string]string) []soup.Root {
+	if missing(doc) {
+		return nil
+	}
 	if len(attrs) == 0 {
 		return doc.FindAll(tag)
 	}
@@ -117,6 +440,9 @@ func (c *SoupClient) FindAll(doc soup.Root, tag string, attrs map[string]string)
 
 // Find finds a single element matching the tag and attributes
 func (c *SoupClient) Find(doc soup.Root, tag string, attrs map[string]string) soup.Root {
+	if missing(doc) {
+		return doc
+	}
 	if len(attrs) == 0 {
 		return doc.Find(tag)
 	}
@@ -131,43 +457,65 @@ func (c *SoupClient) Find(doc soup.Root, tag string, attrs map[string]string) so
 
 // FindByClass finds elements by class name
 func (c *SoupClient) FindByClass(doc soup.Root, className string) []soup.Root {
+	if missing(doc) {
+		return nil
+	}
 	return doc.FindAll("class", className)
 }
 
 // FindByID finds an element by ID
 func (c *SoupClient) FindByID(doc soup.Root, id string) soup.Root {
+	if missing(doc) {
+		return doc
+	}
 	return doc.Find("id", id)
 }
 
 // GetText extracts text content from an element
+// Missing elements, as returned by a failed Find, have no text
 func (c *SoupClient) GetText(element soup.Root) string {
+	if missing(element) {
+		return ""
+	}
 	return element.Text()
 }
 
 // GetFullText extracts all text content including nested elements
 func (c *SoupClient) GetFullText(element soup.Root) string {
+	if missing(element) {
+		return ""
+	}
 	return element.FullText()
 }
 
 // GetAttribute gets an attribute value from an element
 func (c *SoupClient) GetAttribute(element soup.Root, attr string) string {
+	if missing(element) {
+		return ""
+	}
 	return element.Attrs()[attr]
 }
 
 // GetAllAttributes gets all attributes from an element
 func (c *SoupClient) GetAllAttributes(element soup.Root) map[string]string {
+	if missing(element) {
+		return nil
+	}
 	return element.Attrs()
 }
 
 // GetHTML gets the HTML content of an element
 func (c *SoupClient) GetHTML(element soup.Root) string {
+	if missing(element) {
+		return ""
+	}
 	return element.HTML()
 }
 
 // FindLinks finds all anchor tags and returns their href attributes
 func (c *SoupClient) FindLinks(doc soup.Root) []string {
 	var links []string
-	anchors := doc.FindAll("a")
+	anchors := c.FindAll(doc, "a", nil)
 
 	for _, anchor := range anchors {
 		href := anchor.Attrs()["href"]
@@ -182,7 +530,7 @@ func (c *SoupClient) FindLinks(doc soup.Root) []string {
 // FindImages finds all image tags and returns their src attributes
 func (c *SoupClient) FindImages(doc soup.Root) []string {
 	var images []string
-	imgs := doc.FindAll("img")
+	imgs := c.FindAll(doc, "img", nil)
 
 	for _, img := range imgs {
 		src := img.Attrs()["src"]
@@ -198,11 +546,14 @@ func (c *SoupClient) FindImages(doc soup.Root) []string {
 func (c *SoupClient) FindBySelector(doc soup.Root, selectorType, value string) []soup.Root {
 	switch selectorType {
 	case "id":
-		return []soup.Root{doc.Find("id", value)}
+		if el := c.FindByID(doc, value); !missing(el) {
+			return []soup.Root{el}
+		}
+		return []soup.Root{}
 	case "class":
-		return doc.FindAll("class", value)
+		return c.FindByClass(doc, value)
 	case "tag":
-		return doc.FindAll(value)
+		return c.FindAll(doc, value, nil)
 	default:
 		return []soup.Root{}
 	}
@@ -213,7 +564,7 @@ func (c *SoupClient) ParseTable(doc soup.Root, tableSelector map[string]string)
 	var data [][]string
 
 	table := c.Find(doc, "table", tableSelector)
-	if table.Error != nil {
+	if missing(table) || table.Error != nil {
 		return data
 	}
 
@@ -236,3 +587,9 @@ func (c *SoupClient) ParseTable(doc soup.Root, tableSelector map[string]string)
 
 	return data
 }
+
+// missing reports whether element is an empty Root, such as a failed Find
+// returns; soup's accessors panic on it
+func missing(element soup.Root) bool {
+	return element.Pointer == nil
+}
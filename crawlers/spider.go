@@ -1,30 +1,132 @@
 package crawlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/andybalholm/cascadia"
+	"golang.org/x/sync/errgroup"
 )
 
 // Spider is a custom web crawler using goquery and cascadia
 type Spider struct {
-	httpClient  *http.Client
-	maxDepth    int
-	concurrency int
-	visited     map[string]bool
-	visitedMu   sync.RWMutex
-	queue       []string
-	queueMu     sync.RWMutex
-	userAgent   string
-	delay       time.Duration
-	onDocument  func(doc *goquery.Document, url string) error
-	running     bool
-	wg          sync.WaitGroup
+	httpClient   *http.Client
+	maxDepth     int
+	concurrency  int
+	visited      map[string]bool         // Keyed by dedup.URL
+	unfinished   map[string]CrawlContext // Visited URLs whose crawl has not completed, by dedup key; guarded by visitedMu
+	visitedMu    sync.RWMutex
+	queue        crawlQueue
+	queueMu      sync.RWMutex
+	priority     PriorityConfig
+	domainCounts map[string]int // URLs queued per host, for PriorityConfig.Domain; guarded by queueMu
+	userAgent    string
+	delay        time.Duration
+	maxRetries   int // Throttled requeues of a URL before it is skipped
+	onDocument   func(doc *goquery.Document, crawl CrawlContext) error
+	onContent    func(resp *http.Response, crawl CrawlContext) error
+	cooldown     *DomainCooldown
+	limiter      *RateLimiter
+	frontier     frontier.Frontier
+	pollEvery    time.Duration
+	rules        *urlmatch.RuleSet
+	budget       *crawlBudget
+	quota        *domainQuota
+	types        *ContentTypeFilter
+	proxies      *ProxyPool
+	hsts         *HSTS
+	canonical    *Canonicalizer
+	dedup        *DedupKey
+	robotsAgent  string // Product token matched against robots meta tags and robots.txt
+	ignoreMeta   bool
+	robots       *RobotsTxt
+	blocks       *BlockDetector
+	policy       *libs.ValidationPolicy
+	skips        skipLog
+
+	checkpointStore SpiderStateStore
+	checkpointEvery time.Duration
+
+	running atomic.Bool
+	stopMu  sync.Mutex
+	stop    context.CancelCauseFunc // Cancels the current run; guarded by stopMu
+	active  atomic.Int64            // Crawls in flight
+	wake    chan struct{}           // Signals the run loop that a URL was queued or a crawl finished
+}
+
+// errStopped is the cancellation cause set by Stop
+var errStopped = errors.New("spider stopped")
+
+// FatalError ends a Spider run: callbacks return one, via Fatal, for
+// failures no other page can succeed past, e.g. a lost database. In-flight
+// crawls are aborted and requeued, and Run returns the error
+type FatalError struct {
+	URL string // Page whose crawl failed; set by the Spider
+	Err error
+}
+
+// Fatal marks err as ending the crawl; nil stays nil
+func Fatal(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &FatalError{Err: err}
+}
+
+// Error implements error
+func (e *FatalError) Error() string {
+	if e.URL == "" {
+		return fmt.Sprintf("fatal crawl error: %v", e.Err)
+	}
+	return fmt.Sprintf("fatal crawl error on %s: %v", e.URL, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *FatalError) Unwrap() error {
+	return e.Err
+}
+
+// fatalError returns the FatalError in err's chain with its URL set, or nil
+func fatalError(err error, url string) error {
+	var fatal *FatalError
+	if !errors.As(err, &fatal) {
+		return nil
+	}
+	if fatal.URL == "" {
+		fatal.URL = url
+	}
+	return fatal
+}
+
+// CrawlContext describes how a Spider reached a URL
+type CrawlContext struct {
+	URL          string    `json:"url"`
+	Depth        int       `json:"depth"`                // 0 for start URLs
+	ParentURL    string    `json:"parent_url,omitempty"` // Page the URL was found on; empty for start URLs
+	DiscoveredAt time.Time `json:"discovered_at"`        // When the URL was queued
+	Retries      int       `json:"retries,omitempty"`    // Earlier attempts: throttled requeues, or frontier retries
+	Priority     float64   `json:"priority,omitempty"`   // Queue order, higher first; see PriorityConfig
+
+	// SitemapPriority and LastModified are the <priority> and <lastmod> of
+	// URLs found in sitemaps; see AddSitemapURL
+	SitemapPriority float64   `json:"sitemap_priority,omitempty"`
+	LastModified    time.Time `json:"last_modified,omitzero"`
+
+	// Robots holds the meta robots and X-Robots-Tag directives of the page
+	// itself; it is set before the document callback runs
+	Robots RobotsDirectives `json:"-"`
 }
 
 // SpiderConfig holds Spider configuration
@@ -34,6 +136,97 @@ type SpiderConfig struct {
 	UserAgent   string
 	Delay       time.Duration
 	Timeout     time.Duration
+	Cooldown    *DomainCooldown   // Optional per-domain backoff on 429/503
+	RateLimiter *RateLimiter      // Optional limiter shared with other clients
+	URLRules    *urlmatch.RuleSet // Optional; URLs the rules deny are never queued
+	MaxRetries  int               // Requeues of a throttled URL before it is skipped (default 5)
+
+	Proxies       []string // Optional http, https or socks5 proxy URLs to rotate through
+	ProxyStrategy string   // round_robin (default), random, sticky, or scored
+
+	// HTTPClient sends requests instead of a client built from Timeout, e.g.
+	// one set up for mTLS or instrumentation. It is copied, so its transport
+	// is wrapped for proxies, HSTS and metrics without changing the caller's
+	// client; its Timeout, Jar and CheckRedirect are used as is
+	HTTPClient *http.Client
+
+	// RoundTripper replaces the default transport, or that of HTTPClient
+	// Proxies are only rotated through an *http.Transport
+	RoundTripper http.RoundTripper
+
+	// ContentTypes skips responses whose media type it rejects instead of
+	// parsing them; OnContent receives them instead when registered
+	ContentTypes *ContentTypeFilter
+
+	// HSTS sends requests for hosts known to support https over https, and
+	// queues discovered http links of those hosts as https so a page is not
+	// crawled under both schemes
+	HSTS *HSTS
+
+	// DNSCache resolves the hostnames of new connections from a cache; may
+	// be shared with other clients. Only an *http.Transport is wrapped
+	DNSCache *DNSCache
+
+	// Auth logs in with a form before the first request to its hosts and
+	// again when their session expires; may be shared with other clients
+	Auth *AuthFlow
+
+	// Canonical folds URL variants (www, trailing slash, index pages) before
+	// they are queued, so each page is crawled once
+	Canonical *Canonicalizer
+
+	// Dedup decides which URLs count as already visited, e.g. URLs that
+	// only differ in query parameter order; defaults to exact URLs. Pass
+	// the same key to the frontier's Key
+	Dedup *DedupKey
+
+	// Priority orders the in-process queue so important URLs are crawled
+	// first; the zero value is first in, first out. A frontier ignores it
+	Priority PriorityConfig
+
+	// IgnoreRobotsMeta follows links on pages marked nofollow, by meta robots
+	// tags or X-Robots-Tag headers, and links marked rel="nofollow", e.g.
+	// for archival crawls that must capture a site as visitors see it
+	IgnoreRobotsMeta bool
+
+	// Robots skips URLs robots.txt disallows for the user agent's product
+	// token; may be shared with other clients
+	Robots *RobotsTxt
+
+	// URLPolicy skips URLs that fail the SSRF checks of ValidateURLWithPolicy
+	// before they are queued, e.g. links to internal addresses; nil checks none
+	URLPolicy *libs.ValidationPolicy
+
+	// BlockDetector fails CAPTCHA and bot-wall pages with a BlockedError
+	// instead of parsing them
+	BlockDetector *BlockDetector
+
+	// Metrics records every request by status; optional, e.g. *libs.Metrics
+	Metrics Metrics
+
+	// Crawl budget; once a limit is hit no new requests start, in-flight
+	// ones finish and Run returns nil. Zero means unlimited
+	MaxPages    int
+	MaxBytes    int64         // Response body bytes
+	MaxDuration time.Duration // Measured from the first request
+
+	// MaxPagesPerDomain caps the pages fetched from each registrable
+	// domain, so a broad crawl from many seeds is not monopolized by one
+	// huge site. Further URLs of a full domain are skipped with rule
+	// BudgetMaxPagesPerDomain while the crawl goes on. With a frontier the
+	// cap is per Spider and URLs over it are acked. Zero means unlimited
+	MaxPagesPerDomain int
+
+	// Frontier replaces the in-process queue so several spiders, possibly in
+	// different processes, can share one crawl
+	Frontier     frontier.Frontier
+	FrontierPoll time.Duration // Wait between claims when the frontier is empty (default 1s)
+
+	// Checkpoint saves the queue and visited set while Run is going and when
+	// it returns, so LoadState can resume an interrupted crawl. Not used with
+	// a frontier
+	Checkpoint      SpiderStateStore
+	CheckpointEvery time.Duration // Interval between saves (default 30s)
 }
 
 // NewSpider creates a new Spider crawler
@@ -50,19 +243,70 @@ func NewSpider(config SpiderConfig) *Spider {
 	if config.Timeout == 0 {
 		config.Timeout = 30 * time.Second
 	}
+	if config.FrontierPoll <= 0 {
+		config.FrontierPoll = time.Second
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 5
+	}
+	if config.CheckpointEvery <= 0 {
+		config.CheckpointEvery = 30 * time.Second
+	}
 
-	return &Spider{
-		httpClient: &http.Client{
-			Timeout: config.Timeout,
-		},
-		maxDepth:    config.MaxDepth,
-		concurrency: config.Concurrency,
-		userAgent:   config.UserAgent,
-		delay:       config.Delay,
-		visited:     make(map[string]bool),
-		queue:       []string{},
-		running:     false,
+	spider := &Spider{
+		httpClient:   clientFrom(config.HTTPClient, config.RoundTripper, config.Timeout),
+		maxDepth:     config.MaxDepth,
+		concurrency:  config.Concurrency,
+		userAgent:    config.UserAgent,
+		delay:        config.Delay,
+		maxRetries:   config.MaxRetries,
+		cooldown:     config.Cooldown,
+		limiter:      config.RateLimiter,
+		frontier:     config.Frontier,
+		pollEvery:    config.FrontierPoll,
+		rules:        config.URLRules,
+		budget:       newCrawlBudget(config.MaxPages, config.MaxBytes, config.MaxDuration),
+		quota:        newDomainQuota(config.MaxPagesPerDomain),
+		types:        config.ContentTypes,
+		hsts:         config.HSTS,
+		canonical:    config.Canonical,
+		dedup:        config.Dedup,
+		robotsAgent:  RobotsAgent(config.UserAgent),
+		ignoreMeta:   config.IgnoreRobotsMeta,
+		robots:       config.Robots,
+		blocks:       config.BlockDetector,
+		policy:       config.URLPolicy,
+		visited:      make(map[string]bool),
+		unfinished:   make(map[string]CrawlContext),
+		priority:     config.Priority,
+		domainCounts: make(map[string]int),
+		wake:         make(chan struct{}, 1),
+
+		checkpointStore: config.Checkpoint,
+		checkpointEvery: config.CheckpointEvery,
+	}
+	spider.httpClient.Transport = config.DNSCache.Transport(spider.httpClient.Transport)
+
+	if len(config.Proxies) > 0 {
+		pool, err := NewProxyPool(ProxyPoolConfig{
+			Proxies:  config.Proxies,
+			Strategy: config.ProxyStrategy,
+		})
+		if err != nil {
+			// Crawl directly rather than failing construction
+			fmt.Printf("warning: failed to configure proxies: %v\n", err)
+		} else if proxied, ok := proxiedTransport(pool, spider.httpClient.Transport); ok {
+			spider.httpClient.Transport = proxied
+			spider.proxies = pool
+		} else {
+			fmt.Printf("warning: proxies need an *http.Transport, got %T\n", spider.httpClient.Transport)
+		}
 	}
+	spider.httpClient.Transport = config.Auth.Transport(spider.httpClient.Transport)
+	spider.httpClient.Transport = config.HSTS.Transport(spider.httpClient.Transport)
+	spider.httpClient.Transport = instrumentTransport(spider.httpClient.Transport, config.Metrics, CrawlerTypeSpider)
+
+	return spider
 }
 
 // NewDefaultSpider creates a Spider with default settings
@@ -76,116 +320,612 @@ func NewDefaultSpider() *Spider {
 	})
 }
 
-// SetMaxDepth sets the maximum crawl depth
+// ProxyPool returns the proxy pool, or nil when no proxies are configured
+func (s *Spider) ProxyPool() *ProxyPool {
+	return s.proxies
+}
+
+// SetMaxDepth sets the maximum crawl depth; call it before Run
 func (s *Spider) SetMaxDepth(depth int) {
 	s.maxDepth = depth
 }
 
-// SetConcurrency sets the number of concurrent requests
+// SetConcurrency sets the number of concurrent requests; call it before Run
 func (s *Spider) SetConcurrency(n int) {
 	s.concurrency = n
 }
 
 // AddStartURL adds a starting URL to the queue
+// With a frontier the URL is pushed to it; URLs already seen are ignored
+// URLs denied by the URL rules or the URL policy are dropped. It is safe to
+// call while the Spider is running
 func (s *Spider) AddStartURL(url string) {
+	url = s.canonicalURL(url)
+	if s.isVisited(url) {
+		s.skip(SkipDecision{URL: url, Reason: SkipDuplicate, Rule: "visited"})
+		return
+	}
+	item := CrawlContext{URL: url, DiscoveredAt: time.Now()}
+	s.prioritize(&item)
+	s.enqueue(item)
+}
+
+// AddSitemapURL adds a starting URL listed in a sitemap with its <priority>
+// (0.0-1.0) and <lastmod>, which PriorityConfig.Sitemap and Freshness
+// weigh; a zero lastModified is unknown. It is otherwise AddStartURL
+func (s *Spider) AddSitemapURL(url string, priority float64, lastModified time.Time) {
+	url = s.canonicalURL(url)
+	if s.isVisited(url) {
+		s.skip(SkipDecision{URL: url, Reason: SkipDuplicate, Rule: "visited"})
+		return
+	}
+	item := CrawlContext{URL: url, DiscoveredAt: time.Now(), SitemapPriority: priority, LastModified: lastModified}
+	s.prioritize(&item)
+	s.enqueue(item)
+}
+
+// AddURL queues a link found on the page described by parent, one level
+// deeper. Links beyond the maximum depth are dropped, as are all links of a nofollow page unless IgnoreRobotsMeta is
+// set; http links of hosts known through HSTS are queued as https
+func (s *Spider) AddURL(url string, parent CrawlContext) {
+	if parent.Robots.NoFollow && !s.ignoreMeta {
+		s.skip(SkipDecision{URL: url, Reason: SkipNoFollow, ParentURL: parent.URL})
+		return
+	}
+	url = s.canonicalURL(s.hsts.upgradeKnown(url))
+	if parent.Depth+1 > s.maxDepth {
+		s.skip(SkipDecision{URL: url, Reason: SkipDepth, Rule: fmt.Sprintf("max depth %d", s.maxDepth), ParentURL: parent.URL})
+		return
+	}
+	if s.isVisited(url) {
+		s.skip(SkipDecision{URL: url, Reason: SkipDuplicate, Rule: "visited", ParentURL: parent.URL})
+		return
+	}
+	item := CrawlContext{
+		URL:          url,
+		Depth:        parent.Depth + 1,
+		ParentURL:    parent.URL,
+		DiscoveredAt: time.Now(),
+	}
+	s.prioritize(&item)
+	s.enqueue(item)
+}
+
+// canonicalURL folds url when a Canonicalizer is configured
+func (s *Spider) canonicalURL(url string) string {
+	if s.canonical == nil {
+		return url
+	}
+	return s.canonical.Canonicalize(url)
+}
+
+// isVisited reports whether url was already crawled by this process
+// Most links on a page point at pages already crawled; dropping them here
+// keeps them out of the queue instead of popping and discarding them later
+func (s *Spider) isVisited(url string) bool {
+	if s.frontier != nil {
+		return false // The frontier tracks seen URLs itself
+	}
+	s.visitedMu.RLock()
+	defer s.visitedMu.RUnlock()
+	return s.visited[s.dedup.URL(url)]
+}
+
+// enqueue adds a URL to the queue or pushes it to the frontier
+func (s *Spider) enqueue(item CrawlContext) {
+	if s.policy != nil {
+		if err := validateURL(item.URL, *s.policy); err != nil {
+			s.skip(SkipDecision{URL: item.URL, Reason: SkipSSRF, Rule: err.Error(), ParentURL: item.ParentURL})
+			return
+		}
+	}
+	if s.rules != nil {
+		if decision := s.rules.Decide(item.URL); !decision.Allowed {
+			rule := "invalid URL"
+			if decision.Rule != nil {
+				rule = decision.Rule.Action.String() + " " + decision.Rule.Pattern
+			}
+			s.skip(SkipDecision{URL: item.URL, Reason: SkipURLRule, Rule: rule, ParentURL: item.ParentURL})
+			return
+		}
+	}
+
+	if s.frontier != nil {
+		if _, err := s.frontier.Push(context.Background(), item.URL); err != nil {
+			fmt.Printf("warning: failed to push %s to frontier: %v\n", item.URL, err)
+		}
+		return
+	}
+	if s.quota.full(item.URL) {
+		s.skip(SkipDecision{URL: item.URL, Reason: SkipBudget, Rule: BudgetMaxPagesPerDomain, ParentURL: item.ParentURL})
+		return
+	}
+
 	s.queueMu.Lock()
-	defer s.queueMu.Unlock()
-	s.queue = append(s.queue, url)
+	s.queue.push(item)
+	s.queueMu.Unlock()
+	s.signal()
+}
+
+// signal wakes a run loop waiting for queued URLs without blocking
+func (s *Spider) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// skip records a URL the spider decided not to crawl
+func (s *Spider) skip(d SkipDecision) {
+	s.skips.record(d)
+}
+
+// OnSkip registers a callback for every URL the spider decides not to crawl,
+// with the reason and rule that decided it. Callbacks may run concurrently
+// and must not block; call it before Run
+func (s *Spider) OnSkip(handler func(SkipDecision)) {
+	s.skips.onSkip(handler)
+}
+
+// SkipSummary counts the URLs the spider skipped by reason and rule
+func (s *Spider) SkipSummary() SkipSummary {
+	return s.skips.snapshot()
 }
 
 // OnDocument registers a callback for processing documents
+// It replaces any callback registered with OnDocumentContext; call it before
+// Run. The callback runs on up to Concurrency goroutines at once. Errors are
+// logged and the crawl goes on, except those wrapped with Fatal, which end it
 func (s *Spider) OnDocument(handler func(doc *goquery.Document, url string) error) {
+	s.onDocument = func(doc *goquery.Document, crawl CrawlContext) error {
+		return handler(doc, crawl.URL)
+	}
+}
+
+// OnDocumentContext registers a callback that also receives the page's depth,
+// parent URL, discovery time and retry count; pass crawl to AddURL to queue
+// links one level deeper. It replaces any callback registered with OnDocument;
+// call it before Run
+//
+// With a frontier only URL and Retries are known; Depth and ParentURL are not
+// shared between workers
+func (s *Spider) OnDocumentContext(handler func(doc *goquery.Document, crawl CrawlContext) error) {
 	s.onDocument = handler
 }
 
+// OnContent registers a callback for responses the ContentTypes filter
+// rejects, e.g. to store PDFs. The body is unread and is closed after the
+// callback returns. Without it rejected responses are discarded; call it
+// before Run. Its errors are handled like those of OnDocument
+func (s *Spider) OnContent(handler func(resp *http.Response, crawl CrawlContext) error) {
+	s.onContent = handler
+}
+
 // Run starts the crawler
 func (s *Spider) Run() error {
-	if s.running {
+	return s.RunContext(context.Background())
+}
+
+// RunContext starts the crawler and stops it when ctx is done or Stop is
+// called. Up to Concurrency URLs are crawled at once. In-flight requests are
+// aborted and requeued and queued URLs are left in the queue; ctx.Err() is
+// returned, or nil after Stop. A callback returning a FatalError stops the
+// crawl the same way, and Run returns it
+func (s *Spider) RunContext(ctx context.Context) error {
+	if !s.running.CompareAndSwap(false, true) {
 		return fmt.Errorf("spider is already running")
 	}
+	defer s.running.Store(false)
 
-	s.running = true
-	defer func() { s.running = false }()
+	ctx, cancel := context.WithCancelCause(ctx)
+	s.stopMu.Lock()
+	s.stop = cancel
+	s.stopMu.Unlock()
+	defer func() {
+		s.stopMu.Lock()
+		s.stop = nil
+		s.stopMu.Unlock()
+		cancel(nil)
+	}()
 
-	sem := make(chan struct{}, s.concurrency)
+	var err error
+	if s.frontier != nil {
+		err = s.runFrontier(ctx)
+	} else {
+		err = s.runQueue(ctx)
+	}
+	if errors.Is(context.Cause(ctx), errStopped) && !errors.As(err, new(*FatalError)) {
+		return nil
+	}
+	return err
+}
 
-	for len(s.queue) > 0 {
+// runQueue crawls the in-process queue until it is empty and no crawl is in
+// flight, or until ctx is done or a crawl fails fatally
+func (s *Spider) runQueue(ctx context.Context) error {
+	if s.checkpointStore != nil {
+		stop, done := make(chan struct{}), make(chan struct{})
+		go s.checkpoint(stop, done)
+		defer func() {
+			close(stop)
+			<-done
+		}()
+	}
+
+	// Crawls run on gctx, which a fatal error also cancels
+	g, gctx := errgroup.WithContext(ctx)
+	slots := make(chan struct{}, s.concurrency)
+
+	for gctx.Err() == nil {
+		// Take a slot before popping so a cancelled run leaves URLs queued
+		select {
+		case slots <- struct{}{}:
+		case <-gctx.Done():
+			continue
+		}
+
+		// Pop and mark under both locks so State never misses a URL
+		s.visitedMu.Lock()
 		s.queueMu.Lock()
-		if len(s.queue) == 0 {
+		current, ok := s.queue.pop()
+		if !ok {
 			s.queueMu.Unlock()
-			break
+			s.visitedMu.Unlock()
+			<-slots
+
+			// In-flight crawls may still queue links or requeue throttled
+			// URLs; they are counted before the queue is checked again
+			if s.active.Load() == 0 {
+				s.queueMu.RLock()
+				empty := s.queue.Len() == 0
+				s.queueMu.RUnlock()
+				if empty {
+					break
+				}
+				continue
+			}
+			select {
+			case <-s.wake:
+			case <-gctx.Done():
+			}
+			continue
 		}
-		currentURL := s.queue[0]
-		s.queue = s.queue[1:]
+		currentURL := current.URL
+		key := s.dedup.URL(currentURL)
 		s.queueMu.Unlock()
 
 		// Check if already visited
-		s.visitedMu.RLock()
-		isVisited := s.visited[currentURL]
-		s.visitedMu.RUnlock()
-
-		if isVisited {
+		if s.visited[key] {
+			s.visitedMu.Unlock()
+			<-slots
+			s.skip(SkipDecision{URL: currentURL, Reason: SkipDuplicate, Rule: "visited", ParentURL: current.ParentURL})
+			continue
+		}
+		if !s.quota.reserve(currentURL) {
+			s.visitedMu.Unlock()
+			<-slots
+			s.skip(SkipDecision{URL: currentURL, Reason: SkipBudget, Rule: BudgetMaxPagesPerDomain, ParentURL: current.ParentURL})
 			continue
 		}
 
-		// Mark as visited
-		s.visitedMu.Lock()
-		s.visited[currentURL] = true
-		s.visitedMu.Unlock()
+		// An exhausted budget ends the crawl; the URL was never fetched, so it
+		// goes back on the queue unvisited and skipQueued records it once
+		if !s.budget.take() {
+			s.quota.release(currentURL)
+			s.queueMu.Lock()
+			s.queue.push(current)
+			s.queueMu.Unlock()
+			s.visitedMu.Unlock()
+			<-slots
+			break
+		}
 
-		sem <- struct{}{}
-		s.wg.Add(1)
+		// Mark as visited; it stays unfinished until its crawl completes
+		s.visited[key] = true
+		s.unfinished[key] = current
+		s.visitedMu.Unlock()
 
-		go func(url string) {
+		s.active.Add(1)
+		g.Go(func() error {
 			defer func() {
-				<-sem
-				s.wg.Done()
+				<-slots
+				s.active.Add(-1)
+				s.signal()
 			}()
+			return s.crawlQueued(gctx, current, key)
+		})
+	}
 
-			if err := s.crawlURL(url); err != nil {
-				fmt.Printf("Error crawling %s: %v\n", url, err)
-			}
+	err := g.Wait()
+	if s.budget != nil {
+		s.skipQueued()
+	}
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
 
-			// Rate limiting
-			if s.delay > 0 {
-				time.Sleep(s.delay)
-			}
-		}(currentURL)
+// crawlQueued crawls a URL popped from the queue and settles its state
+// Throttled and aborted URLs are queued again; only fatal errors are returned
+func (s *Spider) crawlQueued(ctx context.Context, crawl CrawlContext, key string) error {
+	err := s.crawlURL(ctx, crawl)
+	var throttled *ThrottledError
+	requeue := false
+	s.visitedMu.Lock()
+	delete(s.unfinished, key)
+	switch {
+	case errors.As(err, &throttled) && crawl.Retries >= s.maxRetries:
+		// Still throttled; stays visited so the crawl does not loop on it
+		s.quota.release(crawl.URL)
+	case errors.As(err, &throttled):
+		// Requeue so the URL is retried once the cooldown expires
+		delete(s.visited, key)
+		s.quota.release(crawl.URL)
+		crawl.Retries++
+		requeue = true
+	case err != nil && ctx.Err() != nil:
+		// Aborted by cancellation; leave it for a later run
+		delete(s.visited, key)
+		s.quota.release(crawl.URL)
+		requeue = true
+	}
+	s.visitedMu.Unlock()
+
+	switch {
+	case requeue:
+		s.enqueue(crawl)
+	case throttled != nil:
+		s.skip(SkipDecision{
+			URL:       crawl.URL,
+			Reason:    SkipThrottled,
+			Rule:      fmt.Sprintf("status %d after %d retries", throttled.StatusCode, crawl.Retries),
+			ParentURL: crawl.ParentURL,
+		})
+	}
+	if err != nil {
+		fmt.Printf("Error crawling %s: %v\n", crawl.URL, err)
+		if fatal := fatalError(err, crawl.URL); fatal != nil {
+			return fatal
+		}
 	}
 
-	s.wg.Wait()
+	// Rate limiting
+	sleepContext(ctx, s.delay)
 	return nil
 }
 
-// crawlURL fetches and processes a single URL
-func (s *Spider) crawlURL(urlStr string) error {
-	req, err := http.NewRequest("GET", urlStr, nil)
-	if err != nil {
+// skipQueued records URLs left in the queue once the budget is exhausted
+func (s *Spider) skipQueued() {
+	if s.budget.available() {
+		return
+	}
+
+	s.queueMu.Lock()
+	queued := s.queue.list()
+	s.queue.reset()
+	s.queueMu.Unlock()
+
+	s.visitedMu.RLock()
+	var skipped []CrawlContext
+	seen := make(map[string]bool, len(queued))
+	for _, item := range queued {
+		if key := s.dedup.URL(item.URL); !s.visited[key] && !seen[key] {
+			seen[key] = true
+			s.budget.skip(item.URL)
+			skipped = append(skipped, item)
+		}
+	}
+	s.visitedMu.RUnlock()
+
+	limit := s.budget.summary().Exhausted
+	for _, item := range skipped {
+		s.skip(SkipDecision{URL: item.URL, Reason: SkipBudget, Rule: limit, ParentURL: item.ParentURL})
+	}
+}
+
+// BudgetSummary reports what the crawl fetched and, once a MaxPages,
+// MaxBytes or MaxDuration limit stopped it, what it skipped, along with
+// the URLs skipped per domain by MaxPagesPerDomain
+func (s *Spider) BudgetSummary() BudgetSummary {
+	summary := s.budget.summary()
+	summary.DomainSkips = s.quota.skips()
+	return summary
+}
+
+// runFrontier crawls URLs claimed from the frontier until it has no pending
+// or in-flight URLs left, or until ctx is done or a crawl fails fatally
+func (s *Spider) runFrontier(ctx context.Context) error {
+	g, gctx := errgroup.WithContext(ctx)
+	slots := make(chan struct{}, s.concurrency)
+
+	for gctx.Err() == nil {
+		select {
+		case slots <- struct{}{}:
+		case <-gctx.Done():
+			continue
+		}
+
+		// URLs left in the frontier stay there for other workers
+		if !s.budget.available() {
+			<-slots
+			break
+		}
+
+		lease, err := s.frontier.Claim(gctx)
+		if err != nil {
+			<-slots
+			if errors.Is(err, frontier.ErrEmpty) {
+				if stats, err := s.frontier.Stats(gctx); err == nil && stats.Idle() {
+					break
+				}
+			} else if gctx.Err() == nil {
+				fmt.Printf("warning: failed to claim URL from frontier: %v\n", err)
+			}
+			sleepContext(gctx, s.pollEvery)
+			continue
+		}
+
+		if !s.budget.reserve(lease.URL) {
+			<-slots
+			if err := s.frontier.Retry(context.WithoutCancel(gctx), lease); err != nil {
+				fmt.Printf("warning: failed to requeue %s: %v\n", lease.URL, err)
+			}
+			break
+		}
+		if !s.quota.reserve(lease.URL) {
+			<-slots
+			s.skip(SkipDecision{URL: lease.URL, Reason: SkipBudget, Rule: BudgetMaxPagesPerDomain})
+			if err := s.frontier.Ack(context.WithoutCancel(gctx), lease); err != nil {
+				fmt.Printf("warning: failed to ack %s: %v\n", lease.URL, err)
+			}
+			continue
+		}
+
+		g.Go(func() error {
+			defer func() { <-slots }()
+			if err := s.crawlLease(gctx, lease); err != nil {
+				return err
+			}
+			sleepContext(gctx, s.delay)
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
 		return err
 	}
+	return ctx.Err()
+}
 
-	req.Header.Set("User-Agent", s.userAgent)
+// crawlLease crawls a claimed URL and settles its lease
+// Failed URLs are retried until the frontier's retry limit dead-letters them;
+// only fatal errors are returned
+func (s *Spider) crawlLease(ctx context.Context, lease *frontier.Lease) error {
+	keepCtx, stop := context.WithCancel(ctx)
+	go s.keepLease(keepCtx, lease)
+	err := s.crawlURL(ctx, CrawlContext{URL: lease.URL, Retries: lease.Attempts - 1})
+	stop()
 
-	resp, err := s.httpClient.Do(req)
+	// Settle the lease even when ctx was cancelled mid-crawl
+	settleCtx := context.WithoutCancel(ctx)
+	if err == nil {
+		if err := s.frontier.Ack(settleCtx, lease); err != nil {
+			fmt.Printf("warning: failed to ack %s: %v\n", lease.URL, err)
+		}
+		return nil
+	}
+
+	fmt.Printf("Error crawling %s: %v\n", lease.URL, err)
+	if err := s.frontier.Retry(settleCtx, lease); err != nil {
+		fmt.Printf("warning: failed to requeue %s: %v\n", lease.URL, err)
+	}
+	return fatalError(err, lease.URL)
+}
+
+// keepLease extends a lease halfway to each deadline until ctx is done
+func (s *Spider) keepLease(ctx context.Context, lease *frontier.Lease) {
+	for {
+		if !sleepContext(ctx, time.Until(lease.Deadline)/2) {
+			return
+		}
+		if err := s.frontier.Extend(ctx, lease); err != nil {
+			if ctx.Err() == nil {
+				fmt.Printf("warning: failed to extend lease on %s: %v\n", lease.URL, err)
+			}
+			return
+		}
+	}
+}
+
+// sleepContext waits for d and reports whether it elapsed before ctx was done
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// crawlURL fetches and processes a single URL
+func (s *Spider) crawlURL(ctx context.Context, crawl CrawlContext) error {
+	if s.robots != nil {
+		if allowed, err := s.robots.Allowed(ctx, crawl.URL, s.robotsAgent); err != nil || !allowed {
+			if err == nil {
+				s.skip(SkipDecision{URL: crawl.URL, Reason: SkipRobots, Rule: "user-agent " + s.robotsAgent, ParentURL: crawl.ParentURL})
+			}
+			return err
+		}
+	}
+
+	// Skip rejected URLs before downloading them, unless OnContent wants them
+	resp, release, err := s.get(ctx, crawl.URL, s.onContent == nil)
+	var skipped *SkippedContentError
+	if errors.As(err, &skipped) {
+		s.skip(SkipDecision{URL: crawl.URL, Reason: SkipContentType, Rule: skipped.ContentType, ParentURL: crawl.ParentURL})
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	defer release()
 	defer func() {
 		_ = resp.Body.Close() // Error intentionally ignored on close
 	}()
 
+	var body io.Reader = resp.Body
+	if s.blocks != nil {
+		// Challenge pages often come with error statuses, so check first
+		head, err := io.ReadAll(io.LimitReader(resp.Body, blockSniffSize))
+		if err != nil {
+			return err
+		}
+		if b := s.blocks.Check(crawl.URL, resp.StatusCode, resp.Header, head); b != nil {
+			return &BlockedError{Block: *b}
+		}
+		body = io.MultiReader(bytes.NewReader(head), resp.Body)
+	}
+
 	if resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("status code: %d", resp.StatusCode)
 	}
 
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if s.budget != nil {
+		body = &countingReader{Reader: body, budget: s.budget}
+	}
+
+	if s.types != nil {
+		var contentType string
+		contentType, body = sniffContentType(resp.Header, body)
+		if !s.types.Allowed(contentType) {
+			if s.onContent == nil {
+				s.skip(SkipDecision{URL: crawl.URL, Reason: SkipContentType, Rule: contentType, ParentURL: crawl.ParentURL})
+				return nil
+			}
+			content := *resp
+			content.Body = io.NopCloser(body)
+			return s.onContent(&content, crawl)
+		}
+	}
+
+	doc, err := goquery.NewDocumentFromReader(body)
 	if err != nil {
 		return err
 	}
 
+	if !s.ignoreMeta {
+		crawl.Robots = ParseRobotsDirectives(resp.Header, doc, s.robotsAgent)
+	}
+
 	// Call the document handler
 	if s.onDocument != nil {
-		if err := s.onDocument(doc, urlStr); err != nil {
+		if err := s.onDocument(doc, crawl); err != nil {
 			return err
 		}
 	}
@@ -193,11 +933,68 @@ func (s *Spider) crawlURL(urlStr string) error {
 	return nil
 }
 
+// get sends a GET request for urlStr after waiting out cooldowns and
+// acquiring a rate limiter slot. With headCheck, a HEAD request asks for the
+// content type first and URLs the content type filter rejects fail with a
+// SkippedContentError. Otherwise the caller must close the body and call release
+func (s *Spider) get(ctx context.Context, urlStr string, headCheck bool) (*http.Response, func(), error) {
+	if s.cooldown != nil {
+		if err := s.cooldown.Wait(ctx, urlStr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	release := func() {}
+	if s.limiter != nil {
+		var err error
+		if release, err = s.limiter.Acquire(ctx, urlStr); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", urlStr, nil)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	req.Header.Set("User-Agent", s.userAgent)
+	RequestOptionsFrom(ctx).Apply(req)
+
+	if headCheck && s.types.HeadRequests() {
+		if contentType, ok := headContentType(s.httpClient, req); ok && !s.types.Allowed(contentType) {
+			release()
+			return nil, nil, &SkippedContentError{URL: urlStr, ContentType: contentType}
+		}
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		release()
+		return nil, nil, err
+	}
+
+	if delay, throttled := observeThrottle(s.limiter, s.cooldown, urlStr, resp.StatusCode, resp.Header); throttled {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+		release()
+		return nil, nil, &ThrottledError{URL: urlStr, StatusCode: resp.StatusCode, Delay: delay}
+	}
+	return resp, release, nil
+}
+
 // ExtractLinks extracts links from a document using a CSS selector
+// Unless IgnoreRobotsMeta is set, links marked rel="nofollow" are skipped
+// and a page whose meta robots tag says nofollow yields no links
 func (s *Spider) ExtractLinks(doc *goquery.Document, selector string) []string {
 	var links []string
+	if s.metaNoFollow(doc) {
+		return links
+	}
 
 	doc.Find(selector).Each(func(i int, sel *goquery.Selection) {
+		if !s.ignoreMeta && NoFollowLink(sel) {
+			return
+		}
 		href, exists := sel.Attr("href")
 		if exists {
 			links = append(links, href)
@@ -208,31 +1005,44 @@ func (s *Spider) ExtractLinks(doc *goquery.Document, selector string) []string {
 }
 
 // ExtractLinksWithCascadia extracts links using cascadia selector
+// nofollow is honored as in ExtractLinks
 func (s *Spider) ExtractLinksWithCascadia(doc *goquery.Document, selectorStr string) []string {
 	var links []string
 
 	selector, err := cascadia.Parse(selectorStr)
-	if err != nil {
+	if err != nil || s.metaNoFollow(doc) {
 		return links
 	}
 
-	// Use cascadia with goquery - access nodes via Find to avoid embedded field warning
-	doc.Find("*").Each(func(i int, sel *goquery.Selection) {
-		if len(sel.Nodes) > 0 {
-			nodes := cascadia.QueryAll(sel.Nodes[0], selector)
-			for _, node := range nodes {
-				for _, attr := range node.Attr {
-					if attr.Key == "href" {
-						links = append(links, attr.Val)
-					}
+	// Query once from the document root; querying from every element
+	// returned each link once per ancestor, quadratic in the page depth
+	for _, root := range doc.Nodes {
+		for _, node := range cascadia.QueryAll(root, selector) {
+			var href, rel string
+			var hasHref bool
+			for _, attr := range node.Attr {
+				switch attr.Key {
+				case "href":
+					href, hasHref = attr.Val, true
+				case "rel":
+					rel = attr.Val
 				}
 			}
+			if hasHref && (s.ignoreMeta || !noFollowRel(rel)) {
+				links = append(links, href)
+			}
 		}
-	})
+	}
 
 	return links
 }
 
+// metaNoFollow reports whether the document's meta robots tags forbid
+// following its links and IgnoreRobotsMeta is not set
+func (s *Spider) metaNoFollow(doc *goquery.Document) bool {
+	return !s.ignoreMeta && ParseRobotsDirectives(nil, doc, s.robotsAgent).NoFollow
+}
+
 // ResolveURL resolves a relative URL against a base URL
 func (s *Spider) ResolveURL(baseURL, relativeURL string) (string, error) {
 	base, err := url.Parse(baseURL)
@@ -248,14 +1058,19 @@ func (s *Spider) ResolveURL(baseURL, relativeURL string) (string, error) {
 	return base.ResolveReference(relative).String(), nil
 }
 
-// Stop stops the crawler
+// Stop ends the current run as if its context had been cancelled, except
+// that Run returns nil. It does nothing when the spider is not running
 func (s *Spider) Stop() {
-	s.running = false
+	s.stopMu.Lock()
+	defer s.stopMu.Unlock()
+	if s.stop != nil {
+		s.stop(errStopped)
+	}
 }
 
 // IsRunning checks if the spider is currently running
 func (s *Spider) IsRunning() bool {
-	return s.running
+	return s.running.Load()
 }
 
 // ClearVisited clears the visited URLs map
@@ -0,0 +1,150 @@
+package crawlers
+
+import (
+	"container/heap"
+	"math"
+	"time"
+
+	"github.com/alonecandies/golwarc/configs"
+)
+
+// PriorityConfig weighs the signals the Spider orders its queue by; URLs
+// with the highest score are crawled first, so under a page or time budget
+// the important ones are fetched before it runs out
+//
+// A URL scores the Priority of the URL rule it matches, plus the sitemap
+// and freshness terms, minus the depth and domain terms. URLs with equal
+// scores are crawled in the order they were queued, so the zero value
+// keeps the queue first in, first out
+type PriorityConfig struct {
+	Depth             float64       // Score lost per link level below a start URL
+	Domain            float64       // Score lost per URL of the same host queued before
+	Sitemap           float64       // Score per unit of CrawlContext.SitemapPriority (0.0-1.0)
+	Freshness         float64       // Score of a page modified just now; halves every FreshnessHalfLife
+	FreshnessHalfLife time.Duration // Defaults to 7 days
+
+	// Score replaces the weighted sum when set; domainCount is the number
+	// of URLs of the host queued before
+	Score func(item CrawlContext, domainCount int) float64
+}
+
+// NewPriorityConfig converts application config into Spider priority weights
+func NewPriorityConfig(config configs.PriorityConfig) PriorityConfig {
+	return PriorityConfig{
+		Depth:             config.Depth,
+		Domain:            config.Domain,
+		Sitemap:           config.Sitemap,
+		Freshness:         config.Freshness,
+		FreshnessHalfLife: time.Duration(config.FreshnessHalfLife) * time.Hour,
+	}
+}
+
+// score rates item; rulePriority is the Priority of its URL rule
+func (p PriorityConfig) score(item CrawlContext, rulePriority, domainCount int, now time.Time) float64 {
+	if p.Score != nil {
+		return p.Score(item, domainCount)
+	}
+	score := float64(rulePriority) +
+		p.Sitemap*item.SitemapPriority -
+		p.Depth*float64(item.Depth) -
+		p.Domain*float64(domainCount)
+	if p.Freshness != 0 && !item.LastModified.IsZero() {
+		halfLife := p.FreshnessHalfLife
+		if halfLife <= 0 {
+			halfLife = 7 * 24 * time.Hour
+		}
+		age := max(now.Sub(item.LastModified), 0)
+		score += p.Freshness * math.Exp2(-float64(age)/float64(halfLife))
+	}
+	return score
+}
+
+// prioritize scores a newly discovered URL and counts it against its host
+// Requeued URLs keep the score they were first given
+func (s *Spider) prioritize(item *CrawlContext) {
+	if s.frontier != nil {
+		return // The frontier is first in, first out
+	}
+	rulePriority := 0
+	if s.rules != nil {
+		rulePriority = s.rules.Decide(item.URL).Priority
+	}
+	host := urlHost(item.URL)
+
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+	item.Priority = s.priority.score(*item, rulePriority, s.domainCounts[host], time.Now())
+	s.domainCounts[host]++
+}
+
+// crawlQueue is the Spider's in-process queue: a max-heap on
+// CrawlContext.Priority that pops equal priorities in queueing order
+type crawlQueue struct {
+	items []queuedCrawl
+	seq   uint64
+}
+
+// queuedCrawl is a queue entry
+type queuedCrawl struct {
+	crawl CrawlContext
+	seq   uint64 // Queueing order, to break priority ties
+}
+
+// push adds item to the queue
+func (q *crawlQueue) push(item CrawlContext) {
+	q.seq++
+	heap.Push(q, queuedCrawl{crawl: item, seq: q.seq})
+}
+
+// pop removes and returns the entry with the highest priority
+func (q *crawlQueue) pop() (CrawlContext, bool) {
+	if len(q.items) == 0 {
+		return CrawlContext{}, false
+	}
+	return heap.Pop(q).(queuedCrawl).crawl, true
+}
+
+// list returns the queued entries in crawl order without removing them
+func (q *crawlQueue) list() []CrawlContext {
+	sorted := crawlQueue{items: append([]queuedCrawl(nil), q.items...)}
+	crawls := make([]CrawlContext, 0, len(q.items))
+	for {
+		crawl, ok := sorted.pop()
+		if !ok {
+			return crawls
+		}
+		crawls = append(crawls, crawl)
+	}
+}
+
+// reset empties the queue
+func (q *crawlQueue) reset() {
+	q.items = nil
+}
+
+// Len implements heap.Interface
+func (q *crawlQueue) Len() int { return len(q.items) }
+
+// Less implements heap.Interface
+func (q *crawlQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	if a.crawl.Priority != b.crawl.Priority {
+		return a.crawl.Priority > b.crawl.Priority
+	}
+	return a.seq < b.seq
+}
+
+// Swap implements heap.Interface
+func (q *crawlQueue) Swap(i, j int) { q.items[i], q.items[j] = q.items[j], q.items[i] }
+
+// Push implements heap.Interface
+func (q *crawlQueue) Push(x any) { q.items = append(q.items, x.(queuedCrawl)) }
+
+// Pop implements heap.Interface
+func (q *crawlQueue) Pop() any {
+	last := len(q.items) - 1
+	item := q.items[last]
+	q.items[last] = queuedCrawl{} // Release the popped entry's strings
+	q.items = q.items[:last]
+	return item
+}
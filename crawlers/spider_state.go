@@ -0,0 +1,241 @@
+package crawlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+)
+
+// spiderStateVersion is the format version written by SaveState
+const spiderStateVersion = 1
+
+// SpiderState is a snapshot of an in-process crawl
+// URLs whose crawl had started but not finished are back in Queue, so a
+// resumed crawl fetches them again
+type SpiderState struct {
+	Version int            `json:"version"`
+	SavedAt time.Time      `json:"saved_at"`
+	Queue   []CrawlContext `json:"queue"`
+	Visited []string       `json:"visited"` // Dedup keys of crawled URLs; the URLs themselves by default
+}
+
+// SpiderStateStore persists Spider state between runs
+type SpiderStateStore interface {
+	// SaveState stores an encoded state, replacing any earlier one
+	SaveState(data []byte) error
+
+	// LoadState returns the stored state, or nil if there is none
+	LoadState() ([]byte, error)
+}
+
+// FileSpiderStateStore keeps Spider state in a local file
+// Writes go to a temporary file that is renamed into place, so a crash
+// mid-save leaves the previous state intact
+type FileSpiderStateStore struct {
+	path string
+}
+
+// NewFileSpiderStateStore creates a state store writing to path
+func NewFileSpiderStateStore(path string) *FileSpiderStateStore {
+	return &FileSpiderStateStore{path: path}
+}
+
+// SaveState stores an encoded state
+func (s *FileSpiderStateStore) SaveState(data []byte) error {
+	return writeFileAtomic(s.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file and renames it over path
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()           // Best effort cleanup
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to replace file: %w", err)
+	}
+	return nil
+}
+
+// LoadState returns the stored state, or nil if the file does not exist
+func (s *FileSpiderStateStore) LoadState() ([]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	return data, nil
+}
+
+// CacheSpiderStateStore keeps Spider state under one key of a shared cache
+// such as Redis, so a crawl can resume on another host
+type CacheSpiderStateStore struct {
+	client cache.CacheClient
+	key    string
+	ttl    time.Duration
+}
+
+// NewCacheSpiderStateStore creates a state store writing to key
+// A zero ttl keeps the state until it is overwritten
+func NewCacheSpiderStateStore(client cache.CacheClient, key string, ttl time.Duration) *CacheSpiderStateStore {
+	return &CacheSpiderStateStore{client: client, key: key, ttl: ttl}
+}
+
+// SaveState stores an encoded state
+func (s *CacheSpiderStateStore) SaveState(data []byte) error {
+	return s.client.Set(s.key, string(data), s.ttl)
+}
+
+// LoadState returns the stored state, or nil if the key does not exist
+func (s *CacheSpiderStateStore) LoadState() ([]byte, error) {
+	exists, err := s.client.Exists(s.key)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	val, err := s.client.Get(s.key)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil, nil // Expired since the check
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(val), nil
+}
+
+// State returns a snapshot of the queue and visited set
+// It is safe to call while the Spider is running; the locks are only held
+// while copying, and sorting happens after they are released
+func (s *Spider) State() SpiderState {
+	s.visitedMu.RLock()
+	s.queueMu.RLock()
+	unfinished := make([]CrawlContext, 0, len(s.unfinished))
+	for _, crawl := range s.unfinished {
+		unfinished = append(unfinished, crawl)
+	}
+	queue := s.queue.list()
+	visited := make([]string, 0, len(s.visited))
+	for url := range s.visited {
+		if _, ok := s.unfinished[url]; !ok {
+			visited = append(visited, url)
+		}
+	}
+	s.queueMu.RUnlock()
+	s.visitedMu.RUnlock()
+
+	sort.Slice(unfinished, func(i, j int) bool { return unfinished[i].URL < unfinished[j].URL })
+	sort.Strings(visited)
+	return SpiderState{
+		Version: spiderStateVersion,
+		SavedAt: time.Now(),
+		Queue:   append(unfinished, queue...),
+		Visited: visited,
+	}
+}
+
+// RestoreState replaces the queue and visited set with a snapshot
+func (s *Spider) RestoreState(state SpiderState) error {
+	if state.Version != spiderStateVersion {
+		return fmt.Errorf("unsupported spider state version %d", state.Version)
+	}
+	if s.running.Load() {
+		return fmt.Errorf("cannot restore state while the spider is running")
+	}
+
+	s.visitedMu.Lock()
+	defer s.visitedMu.Unlock()
+	s.queueMu.Lock()
+	defer s.queueMu.Unlock()
+
+	s.visited = make(map[string]bool, len(state.Visited))
+	for _, url := range state.Visited {
+		s.visited[url] = true
+	}
+	s.unfinished = make(map[string]CrawlContext)
+	s.queue.reset()
+	s.domainCounts = make(map[string]int)
+	for _, item := range state.Queue {
+		s.queue.push(item)
+		s.domainCounts[urlHost(item.URL)]++
+	}
+	return nil
+}
+
+// SaveState writes a snapshot of the crawl to store
+// With a frontier the queue already lives in the frontier and nothing is saved
+func (s *Spider) SaveState(store SpiderStateStore) error {
+	if s.frontier != nil {
+		return nil
+	}
+	data, err := json.Marshal(s.State())
+	if err != nil {
+		return fmt.Errorf("failed to marshal spider state: %w", err)
+	}
+	if err := store.SaveState(data); err != nil {
+		return fmt.Errorf("failed to save spider state: %w", err)
+	}
+	return nil
+}
+
+// LoadState restores the crawl saved in store and reports whether there was one
+// Call it before Run, instead of or before adding start URLs
+func (s *Spider) LoadState(store SpiderStateStore) (bool, error) {
+	data, err := store.LoadState()
+	if err != nil {
+		return false, fmt.Errorf("failed to load spider state: %w", err)
+	}
+	if data == nil {
+		return false, nil
+	}
+
+	var state SpiderState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false, fmt.Errorf("failed to unmarshal spider state: %w", err)
+	}
+	if err := s.RestoreState(state); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// checkpoint saves state every interval until stop is closed, then once more
+func (s *Spider) checkpoint(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(s.checkpointEvery)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-stop:
+			if err := s.SaveState(s.checkpointStore); err != nil {
+				fmt.Printf("warning: failed to checkpoint spider state: %v\n", err)
+			}
+			return
+		}
+		if err := s.SaveState(s.checkpointStore); err != nil {
+			fmt.Printf("warning: failed to checkpoint spider state: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package crawlers
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TransportConfig tunes the HTTP transport of a client, for example to keep
+// more connections open when scraping many pages from few hosts
+// Zero values keep the defaults of http.DefaultTransport
+type TransportConfig struct {
+	MaxIdleConns        int           // Idle connections kept across all hosts (default 100)
+	MaxIdleConnsPerHost int           // Idle connections kept per host (default 2)
+	MaxConnsPerHost     int           // Connections per host, including active ones; 0 is unlimited
+	IdleConnTimeout     time.Duration // How long an idle connection is kept (default 90s)
+
+	DisableHTTP2 bool        // Use HTTP/1.1 even when a server offers HTTP/2
+	TLSConfig    *tls.Config // Client certificates, custom roots or InsecureSkipVerify
+
+	// Proxy chooses a proxy per request; defaults to the environment
+	// Ignored when the client rotates a proxy pool
+	Proxy func(*http.Request) (*url.URL, error)
+
+	DialTimeout           time.Duration // TCP connect timeout (default 30s)
+	KeepAlive             time.Duration // TCP keep-alive interval (default 30s)
+	TLSHandshakeTimeout   time.Duration // Default 10s
+	ResponseHeaderTimeout time.Duration // Wait for response headers; 0 is bounded only by the client timeout
+}
+
+// NewTransport builds an http.Transport from config
+func NewTransport(config TransportConfig) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if config.MaxIdleConns > 0 {
+		transport.MaxIdleConns = config.MaxIdleConns
+	}
+	if config.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+	}
+	if config.MaxConnsPerHost > 0 {
+		transport.MaxConnsPerHost = config.MaxConnsPerHost
+	}
+	if config.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = config.IdleConnTimeout
+	}
+	if config.TLSHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = config.TLSHandshakeTimeout
+	}
+	if config.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = config.ResponseHeaderTimeout
+	}
+	if config.Proxy != nil {
+		transport.Proxy = config.Proxy
+	}
+	if config.TLSConfig != nil {
+		transport.TLSClientConfig = config.TLSConfig.Clone()
+	}
+
+	if config.DialTimeout > 0 || config.KeepAlive > 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+		if config.DialTimeout > 0 {
+			dialer.Timeout = config.DialTimeout
+		}
+		if config.KeepAlive > 0 {
+			dialer.KeepAlive = config.KeepAlive
+		}
+		transport.DialContext = dialer.DialContext
+	}
+
+	if config.DisableHTTP2 {
+		// A non-nil empty map stops the transport from negotiating h2
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return transport
+}
+
+// clientFrom returns the http.Client a crawler sends requests with: a copy of
+// injected, so wrapping its transport leaves the caller's client untouched,
+// or a new client with timeout. A non-nil transport replaces the client's,
+// and a client without one gets http.DefaultTransport so it can be wrapped
+func clientFrom(injected *http.Client, transport http.RoundTripper, timeout time.Duration) *http.Client {
+	client := &http.Client{Timeout: timeout}
+	if injected != nil {
+		copied := *injected
+		client = &copied
+	}
+	if transport != nil {
+		client.Transport = transport
+	}
+	if client.Transport == nil {
+		client.Transport = http.DefaultTransport
+	}
+	return client
+}
+
+// proxiedTransport routes base through pool. Proxies are chosen by the
+// transport's Proxy func, so round trippers other than *http.Transport cannot
+// be proxied and are returned unchanged with false
+func proxiedTransport(pool *ProxyPool, base http.RoundTripper) (http.RoundTripper, bool) {
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base, false
+	}
+	return pool.TransportFrom(transport.Clone()), true
+}
@@ -0,0 +1,337 @@
+package urlmatch
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Decision is the outcome of matching a URL against a rule set
+type Decision struct {
+	Allowed  bool
+	Priority int
+	Rule     *Rule // Winning rule; nil when no rule matched
+}
+
+// RuleSet is a compiled, read-only set of rules safe for concurrent use
+//
+// When several rules match, the most specific one wins: the one with the
+// most literal (non-wildcard) characters in its pattern. Deny wins ties,
+// then the earlier rule. URLs no rule matches are allowed with priority 0;
+// add "deny *" for a default-deny set
+type RuleSet struct {
+	hosts   *hostNode
+	anyHost *pathNode       // Rules for any host
+	regexps []*compiledRule // "re:" and "glob:" rules, checked for every URL
+	size    int
+}
+
+// compiledRule is a rule placed in the trie
+type compiledRule struct {
+	rule        Rule
+	index       int
+	specificity int
+	exact       bool           // Path must end where the literal prefix ends
+	suffix      *regexp.Regexp // Matches the path after the literal prefix; nil means any
+}
+
+// hostNode is a trie node keyed by reversed host labels
+type hostNode struct {
+	children map[string]*hostNode
+	exact    *pathNode // Rules for exactly this host
+	sub      *pathNode // Rules for subdomains of this host (*.host)
+}
+
+// pathNode is a trie node keyed by path bytes
+type pathNode struct {
+	children map[byte]*pathNode
+	rules    []*compiledRule
+}
+
+// Compile builds a rule set
+func Compile(rules []Rule) (*RuleSet, error) {
+	rs := &RuleSet{hosts: &hostNode{}, anyHost: &pathNode{}}
+	for i, rule := range rules {
+		if err := rs.add(rule, i); err != nil {
+			return nil, err
+		}
+	}
+	rs.size = len(rules)
+	return rs, nil
+}
+
+// MustCompile is Compile that panics on error, for static rule sets
+func MustCompile(rules []Rule) *RuleSet {
+	rs, err := Compile(rules)
+	if err != nil {
+		panic(err)
+	}
+	return rs
+}
+
+// add compiles one rule into the set
+func (rs *RuleSet) add(rule Rule, index int) error {
+	if expr, ok := strings.CutPrefix(rule.Pattern, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid rule pattern %q: %w", rule.Pattern, err)
+		}
+		rs.regexps = append(rs.regexps, &compiledRule{rule: rule, index: index, specificity: len(expr), suffix: re})
+		return nil
+	}
+	if glob, ok := strings.CutPrefix(rule.Pattern, "glob:"); ok {
+		if glob == "" {
+			return fmt.Errorf("invalid rule pattern %q: empty glob", rule.Pattern)
+		}
+		re, literal := compileGlob(glob)
+		rs.regexps = append(rs.regexps, &compiledRule{rule: rule, index: index, specificity: literal, suffix: re})
+		return nil
+	}
+
+	pattern := rule.Pattern
+	if _, rest, ok := strings.Cut(pattern, "://"); ok {
+		pattern = rest // Schemes are not matched
+	}
+	host, path := pattern, ""
+	if i := strings.IndexByte(pattern, '/'); i >= 0 {
+		host, path = pattern[:i], pattern[i:]
+	}
+	host = strings.ToLower(host)
+
+	compiled := &compiledRule{rule: rule, index: index}
+	literal, err := compiled.compilePath(path)
+	if err != nil {
+		return fmt.Errorf("invalid rule pattern %q: %w", rule.Pattern, err)
+	}
+	compiled.specificity = len(strings.TrimPrefix(host, "*")) + len(literal)
+
+	node, err := rs.hostPaths(host)
+	if err != nil {
+		return fmt.Errorf("invalid rule pattern %q: %w", rule.Pattern, err)
+	}
+	for i := 0; i < len(literal); i++ {
+		if node.children == nil {
+			node.children = make(map[byte]*pathNode)
+		}
+		child, ok := node.children[literal[i]]
+		if !ok {
+			child = &pathNode{}
+			node.children[literal[i]] = child
+		}
+		node = child
+	}
+	node.rules = append(node.rules, compiled)
+	return nil
+}
+
+// compilePath splits a robots.txt style path pattern into its literal prefix
+// and sets exact or suffix for the remainder
+func (c *compiledRule) compilePath(path string) (string, error) {
+	star := strings.IndexByte(path, '*')
+	if star < 0 {
+		if trimmed, ok := strings.CutSuffix(path, "$"); ok {
+			c.exact = true
+			return trimmed, nil
+		}
+		return path, nil
+	}
+
+	literal, rest := path[:star], path[star:]
+	if strings.Trim(rest, "*") == "" {
+		return literal, nil // Trailing * is the same as a prefix match
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i, r := range rest {
+		switch {
+		case r == '*':
+			expr.WriteString(".*")
+		case r == '$' && i == len(rest)-1:
+			expr.WriteString("$")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return "", err
+	}
+	c.suffix = re
+	return literal, nil
+}
+
+// compileGlob compiles a whole-URL glob to a regexp and counts its literal characters
+func compileGlob(glob string) (*regexp.Regexp, int) {
+	var expr strings.Builder
+	literal := 0
+	expr.WriteString("^")
+	for _, r := range glob {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			literal++
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String()), literal
+}
+
+// hostPaths returns the path trie root for a host pattern, creating nodes
+func (rs *RuleSet) hostPaths(host string) (*pathNode, error) {
+	if host == "" || host == "*" {
+		return rs.anyHost, nil
+	}
+
+	sub := false
+	if rest, ok := strings.CutPrefix(host, "*."); ok {
+		host, sub = rest, true
+	}
+	if strings.ContainsAny(host, "*?") {
+		return nil, fmt.Errorf("only a leading *. wildcard is allowed in hosts")
+	}
+
+	node := rs.hosts
+	labels := strings.Split(host, ".")
+	for i := len(labels) - 1; i >= 0; i-- {
+		if node.children == nil {
+			node.children = make(map[string]*hostNode)
+		}
+		child, ok := node.children[labels[i]]
+		if !ok {
+			child = &hostNode{}
+			node.children[labels[i]] = child
+		}
+		node = child
+	}
+
+	if sub {
+		if node.sub == nil {
+			node.sub = &pathNode{}
+		}
+		return node.sub, nil
+	}
+	if node.exact == nil {
+		node.exact = &pathNode{}
+	}
+	return node.exact, nil
+}
+
+// Len returns the number of rules
+func (rs *RuleSet) Len() int {
+	return rs.size
+}
+
+// Match returns the winning rule for rawURL, or nil if none matches
+func (rs *RuleSet) Match(rawURL string) *Rule {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	if best := rs.match(u, rawURL); best != nil {
+		return &best.rule
+	}
+	return nil
+}
+
+// Decide matches rawURL and applies the default of allowing unmatched URLs
+// URLs that cannot be parsed are denied
+func (rs *RuleSet) Decide(rawURL string) Decision {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Decision{}
+	}
+	best := rs.match(u, rawURL)
+	if best == nil {
+		return Decision{Allowed: true}
+	}
+	rule := best.rule
+	return Decision{Allowed: rule.Action == Allow, Priority: rule.Priority, Rule: &rule}
+}
+
+// Allowed reports whether rawURL is allowed
+func (rs *RuleSet) Allowed(rawURL string) bool {
+	return rs.Decide(rawURL).Allowed
+}
+
+// match walks the host and path tries and returns the winning rule
+func (rs *RuleSet) match(u *url.URL, rawURL string) *compiledRule {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	var best *compiledRule
+	consider := func(c *compiledRule) {
+		if best == nil || better(c, best) {
+			best = c
+		}
+	}
+
+	matchPath(rs.anyHost, path, consider)
+
+	labels := strings.Split(strings.ToLower(u.Hostname()), ".")
+	node := rs.hosts
+	for i := len(labels) - 1; i >= 0 && node != nil; i-- {
+		node = node.children[labels[i]]
+		if node == nil {
+			break
+		}
+		if i > 0 && node.sub != nil {
+			matchPath(node.sub, path, consider)
+		}
+		if i == 0 && node.exact != nil {
+			matchPath(node.exact, path, consider)
+		}
+	}
+
+	for _, c := range rs.regexps {
+		if c.suffix.MatchString(rawURL) {
+			consider(c)
+		}
+	}
+	return best
+}
+
+// matchPath walks path through a path trie, reporting every matching rule
+func matchPath(node *pathNode, path string, consider func(*compiledRule)) {
+	for pos := 0; node != nil; pos++ {
+		for _, c := range node.rules {
+			switch {
+			case c.exact:
+				if pos == len(path) {
+					consider(c)
+				}
+			case c.suffix != nil:
+				if c.suffix.MatchString(path[pos:]) {
+					consider(c)
+				}
+			default:
+				consider(c)
+			}
+		}
+		if pos == len(path) {
+			return
+		}
+		node = node.children[path[pos]]
+	}
+}
+
+// better reports whether a takes precedence over b
+func better(a, b *compiledRule) bool {
+	if a.specificity != b.specificity {
+		return a.specificity > b.specificity
+	}
+	if a.rule.Action != b.rule.Action {
+		return a.rule.Action == Deny
+	}
+	return a.index < b.index
+}
@@ -0,0 +1,94 @@
+// Package urlmatch evaluates large allow/deny/priority URL rule sets
+//
+// Rules are compiled into a trie keyed by reversed host labels and then by
+// path bytes, so matching a URL walks its host and path once regardless of
+// how many rules there are. Wildcards after a rule's literal path prefix are
+// compiled to a regexp that only runs when the walk reaches that prefix
+package urlmatch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Action is what a rule decides for matching URLs
+type Action int
+
+// Rule actions
+const (
+	Allow Action = iota
+	Deny
+)
+
+// String returns the action name used by ParseRule
+func (a Action) String() string {
+	if a == Deny {
+		return "deny"
+	}
+	return "allow"
+}
+
+// Rule maps URLs matching Pattern to an action and crawl priority
+//
+// Pattern is host[/path]:
+//   - host is a name (example.com), a subdomain wildcard (*.example.com,
+//     which does not match example.com itself) or * for any host
+//   - path follows robots.txt rules: a prefix match, where * matches any
+//     run of characters and a trailing $ anchors the end; the query string
+//     is part of the path
+//
+// A pattern starting with "re:" is a regular expression searched for in the
+// whole URL, and one starting with "glob:" a shell glob that must match the
+// whole URL, where * matches any run of characters and ? a single one. Such
+// rules are checked for every URL, so keep them few
+type Rule struct {
+	Pattern  string
+	Action   Action
+	Priority int // Crawl priority of matching URLs; higher is sooner
+}
+
+// ParseRule parses "<allow|deny> <pattern> [priority]"
+func ParseRule(line string) (Rule, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return Rule{}, fmt.Errorf("invalid rule %q: want \"<allow|deny> <pattern> [priority]\"", line)
+	}
+
+	var rule Rule
+	switch strings.ToLower(fields[0]) {
+	case "allow":
+		rule.Action = Allow
+	case "deny":
+		rule.Action = Deny
+	default:
+		return Rule{}, fmt.Errorf("invalid rule %q: unknown action %q", line, fields[0])
+	}
+	rule.Pattern = fields[1]
+
+	if len(fields) == 3 {
+		priority, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return Rule{}, fmt.Errorf("invalid rule %q: bad priority: %w", line, err)
+		}
+		rule.Priority = priority
+	}
+	return rule, nil
+}
+
+// ParseRules parses one rule per line, skipping blank lines and # comments
+func ParseRules(text string) ([]Rule, error) {
+	var rules []Rule
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := ParseRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
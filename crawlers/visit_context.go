@@ -0,0 +1,152 @@
+package crawlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Keys linking Colly requests to the context passed to VisitContext
+const (
+	visitIDKey  = "golwarc_visit_id"
+	visitHeader = "X-Golwarc-Visit"
+)
+
+// visitRegistry maps VisitContext IDs to their contexts
+// Colly builds HTTP requests from a collector-wide context, so the ID travels
+// in the colly.Context and an internal header until the transport swaps in
+// the visit's context
+type visitRegistry struct {
+	next     atomic.Uint64
+	contexts sync.Map // id -> context.Context
+}
+
+// register stores ctx and returns its ID; the entry is dropped once ctx is done
+func (v *visitRegistry) register(ctx context.Context) string {
+	id := strconv.FormatUint(v.next.Add(1), 10)
+	v.contexts.Store(id, ctx)
+	context.AfterFunc(ctx, func() { v.contexts.Delete(id) })
+	return id
+}
+
+// lookup returns the context registered under id
+func (v *visitRegistry) lookup(id string) (context.Context, bool) {
+	ctx, ok := v.contexts.Load(id)
+	if !ok {
+		return nil, false
+	}
+	return ctx.(context.Context), true
+}
+
+// forget drops a registration early
+func (v *visitRegistry) forget(id string) {
+	v.contexts.Delete(id)
+}
+
+// requestContext returns the VisitContext context of a Colly request
+// Requests started with Visit, and those of finished visits, use context.Background
+func (v *visitRegistry) requestContext(r *colly.Request) context.Context {
+	if id := r.Ctx.Get(visitIDKey); id != "" {
+		if ctx, ok := v.lookup(id); ok {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// abortRequest aborts a Colly request and marks it so later OnRequest
+// callbacks, which Colly still runs, can skip it
+func abortRequest(r *colly.Request) {
+	r.Abort()
+	r.Ctx.Put(abortedKey(r), true)
+}
+
+// isAborted reports whether abortRequest was called for r
+func isAborted(r *colly.Request) bool {
+	aborted, _ := r.Ctx.GetAny(abortedKey(r)).(bool)
+	return aborted
+}
+
+// abortedKey is the colly.Context key marking r as aborted
+func abortedKey(r *colly.Request) string {
+	return "golwarc_aborted_" + strconv.FormatUint(uint64(r.ID), 10)
+}
+
+// registerVisitContext aborts requests whose visit context is done and tags
+// the rest so the transport can bind them to it
+func registerVisitContext(c *colly.Collector, visits *visitRegistry) {
+	c.OnRequest(func(r *colly.Request) {
+		id := r.Ctx.Get(visitIDKey)
+		if id == "" {
+			return
+		}
+		ctx, ok := visits.lookup(id)
+		if !ok || ctx.Err() != nil {
+			abortRequest(r)
+			return
+		}
+		r.Headers.Set(visitHeader, id)
+	})
+}
+
+// visitTransport binds tagged requests to their visit context so cancelling
+// it aborts the in-flight HTTP exchange, and applies the visit's RequestOptions
+type visitTransport struct {
+	base   http.RoundTripper
+	visits *visitRegistry
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *visitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := req.Header.Get(visitHeader)
+	if id == "" {
+		return t.base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Del(visitHeader)
+
+	visitCtx, ok := t.visits.lookup(id)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+	RequestOptionsFrom(visitCtx).Apply(req)
+
+	// Keep the request's values (Colly stores flags there) but cancel with the visit
+	ctx, cancel := context.WithCancel(req.Context())
+	stop := context.AfterFunc(visitCtx, cancel)
+	release := func() {
+		stop()
+		cancel()
+	}
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		release()
+		if visitCtx.Err() != nil {
+			return nil, visitCtx.Err()
+		}
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// releaseOnClose runs release when the body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+// Close implements io.Closer
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -1,6 +1,10 @@
 package database
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // DatabaseClient defines the interface for database operations
 // This enables mocking in tests and provides a consistent API across different database implementations
@@ -39,9 +43,24 @@ type DatabaseClient interface {
 	Transaction(fn func(*gorm.DB) error) error
 }
 
+// Partitioner manages time-based range partitions for a table
+type Partitioner interface {
+	// EnsurePartitions creates the partition containing now and upcoming ones
+	EnsurePartitions(now time.Time) error
+
+	// DropPartitionsBefore drops partitions whose range ends at or before cutoff
+	DropPartitionsBefore(cutoff time.Time) ([]string, error)
+}
+
 // Ensure all database clients implement the interface
 var (
 	_ DatabaseClient = (*MySQLClient)(nil)
 	_ DatabaseClient = (*PostgreSQLClient)(nil)
 	_ DatabaseClient = (*ClickHouseClient)(nil)
 )
+
+// Ensure all partitioners implement the interface
+var (
+	_ Partitioner = (*MySQLPartitioner)(nil)
+	_ Partitioner = (*PostgreSQLPartitioner)(nil)
+)
@@ -0,0 +1,368 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Partition intervals supported by the partitioners
+const (
+	PartitionDaily   = "daily"
+	PartitionMonthly = "monthly"
+)
+
+// PartitionConfig describes time-based range partitioning for one table
+type PartitionConfig struct {
+	Table    string // Table to partition (e.g. "crawl_logs")
+	Column   string // Timestamp column to partition on (default "created_at")
+	Interval string // daily or monthly (default monthly)
+	Ahead    int    // Number of future partitions to keep created (default 3)
+}
+
+// withDefaults fills in unset fields
+func (c PartitionConfig) withDefaults() PartitionConfig {
+	if c.Column == "" {
+		c.Column = "created_at"
+	}
+	if c.Interval == "" {
+		c.Interval = PartitionMonthly
+	}
+	if c.Ahead <= 0 {
+		c.Ahead = 3
+	}
+	return c
+}
+
+// PartitionStart returns the start of the partition containing t
+func PartitionStart(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	if interval == PartitionDaily {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// nextPartitionStart returns the start of the partition after the one starting at start
+func nextPartitionStart(start time.Time, interval string) time.Time {
+	if interval == PartitionDaily {
+		return start.AddDate(0, 0, 1)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// PartitionSuffix returns the partition name suffix for the partition starting at start
+// e.g. p202610 for monthly or p20261016 for daily partitions
+func PartitionSuffix(start time.Time, interval string) string {
+	if interval == PartitionDaily {
+		return "p" + start.Format("20060102")
+	}
+	return "p" + start.Format("200601")
+}
+
+// parsePartitionSuffix parses a suffix produced by PartitionSuffix
+func parsePartitionSuffix(suffix, interval string) (time.Time, bool) {
+	if !strings.HasPrefix(suffix, "p") {
+		return time.Time{}, false
+	}
+	layout := "200601"
+	if interval == PartitionDaily {
+		layout = "20060102"
+	}
+	t, err := time.Parse(layout, suffix[1:])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// RetentionCutoff returns the start of the oldest partition to keep
+// when retaining the current partition and retention-1 before it
+func RetentionCutoff(now time.Time, interval string, retention int) time.Time {
+	start := PartitionStart(now, interval)
+	if interval == PartitionDaily {
+		return start.AddDate(0, 0, -(retention - 1))
+	}
+	return start.AddDate(0, -(retention - 1), 0)
+}
+
+// partitionStarts returns the starts of the current partition and config.Ahead future ones
+func partitionStarts(now time.Time, config PartitionConfig) []time.Time {
+	start := PartitionStart(now, config.Interval)
+	starts := make([]time.Time, 0, config.Ahead+1)
+	for i := 0; i <= config.Ahead; i++ {
+		starts = append(starts, start)
+		start = nextPartitionStart(start, config.Interval)
+	}
+	return starts
+}
+
+// sqlTime formats a time as a SQL datetime literal
+func sqlTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05")
+}
+
+// TimeRange scopes a query to [from, to) on a timestamp column
+// Bounding queries on the partition column lets the database prune partitions
+// Zero times leave that side of the range open
+func TimeRange(column string, from, to time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if !from.IsZero() {
+			db = db.Where(column+" >= ?", from)
+		}
+		if !to.IsZero() {
+			db = db.Where(column+" < ?", to)
+		}
+		return db
+	}
+}
+
+// NewPartitioner creates the partitioner for the driver of db: PostgreSQL
+// native partitions for postgres, RANGE COLUMNS partitions otherwise
+func NewPartitioner(db *gorm.DB, config PartitionConfig) Partitioner {
+	if db.Dialector != nil && db.Dialector.Name() == "postgres" {
+		return NewPostgreSQLPartitioner(db, config)
+	}
+	return NewMySQLPartitioner(db, config)
+}
+
+// =============================================================================
+// MySQL
+// =============================================================================
+
+// MySQLPartitioner manages RANGE COLUMNS partitions on a MySQL table
+// MySQL requires the partition column to be part of every unique key,
+// including the primary key, so the table schema must allow for it
+type MySQLPartitioner struct {
+	db     *gorm.DB
+	config PartitionConfig
+}
+
+// NewMySQLPartitioner creates a partitioner for a MySQL table
+func NewMySQLPartitioner(db *gorm.DB, config PartitionConfig) *MySQLPartitioner {
+	return &MySQLPartitioner{
+		db:     db,
+		config: config.withDefaults(),
+	}
+}
+
+// existingPartitions returns the names of the table's partitions
+func (p *MySQLPartitioner) existingPartitions() ([]string, error) {
+	var names []string
+	err := p.db.Raw(
+		"SELECT PARTITION_NAME FROM information_schema.PARTITIONS "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND PARTITION_NAME IS NOT NULL "+
+			"ORDER BY PARTITION_ORDINAL_POSITION",
+		p.config.Table,
+	).Scan(&names).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", p.config.Table, err)
+	}
+	return names, nil
+}
+
+// partitionDefinition returns the definition of the partition starting at start
+func (p *MySQLPartitioner) partitionDefinition(start time.Time) string {
+	end := nextPartitionStart(start, p.config.Interval)
+	return fmt.Sprintf("PARTITION %s VALUES LESS THAN ('%s')",
+		PartitionSuffix(start, p.config.Interval), sqlTime(end))
+}
+
+// EnsurePartitions creates the current and upcoming partitions
+// An unpartitioned table is converted; otherwise missing partitions are split off pmax
+func (p *MySQLPartitioner) EnsurePartitions(now time.Time) error {
+	existing, err := p.existingPartitions()
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]bool, len(existing))
+	var latest time.Time
+	for _, name := range existing {
+		have[name] = true
+		if start, ok := parsePartitionSuffix(name, p.config.Interval); ok && start.After(latest) {
+			latest = start
+		}
+	}
+
+	var defs []string
+	for _, start := range partitionStarts(now, p.config) {
+		// Ranges must stay increasing, so only partitions after the latest one can be added
+		if have[PartitionSuffix(start, p.config.Interval)] || (len(existing) > 0 && !start.After(latest)) {
+			continue
+		}
+		defs = append(defs, p.partitionDefinition(start))
+	}
+	if len(defs) == 0 {
+		return nil
+	}
+	defs = append(defs, "PARTITION pmax VALUES LESS THAN (MAXVALUE)")
+
+	var stmt string
+	if len(existing) == 0 {
+		stmt = fmt.Sprintf("ALTER TABLE `%s` PARTITION BY RANGE COLUMNS(`%s`) (%s)",
+			p.config.Table, p.config.Column, strings.Join(defs, ", "))
+	} else {
+		stmt = fmt.Sprintf("ALTER TABLE `%s` REORGANIZE PARTITION pmax INTO (%s)",
+			p.config.Table, strings.Join(defs, ", "))
+	}
+
+	if err := p.db.Exec(stmt).Error; err != nil {
+		return fmt.Errorf("failed to create partitions for %s: %w", p.config.Table, err)
+	}
+	return nil
+}
+
+// DropPartitionsBefore drops partitions whose range ends at or before cutoff
+func (p *MySQLPartitioner) DropPartitionsBefore(cutoff time.Time) ([]string, error) {
+	existing, err := p.existingPartitions()
+	if err != nil {
+		return nil, err
+	}
+
+	var drop []string
+	for _, name := range existing {
+		start, ok := parsePartitionSuffix(name, p.config.Interval)
+		if ok && !nextPartitionStart(start, p.config.Interval).After(cutoff) {
+			drop = append(drop, name)
+		}
+	}
+	if len(drop) == 0 {
+		return nil, nil
+	}
+
+	stmt := fmt.Sprintf("ALTER TABLE `%s` DROP PARTITION %s", p.config.Table, strings.Join(drop, ", "))
+	if err := p.db.Exec(stmt).Error; err != nil {
+		return nil, fmt.Errorf("failed to drop partitions of %s: %w", p.config.Table, err)
+	}
+	return drop, nil
+}
+
+// =============================================================================
+// PostgreSQL
+// =============================================================================
+
+// PostgreSQLPartitioner manages native range partitions on a PostgreSQL table
+// A plain table, e.g. one created by AutoMigrate, is converted into a
+// partitioned parent on first use; like on MySQL, its primary key and unique
+// indexes must include the partition column
+type PostgreSQLPartitioner struct {
+	db     *gorm.DB
+	config PartitionConfig
+}
+
+// NewPostgreSQLPartitioner creates a partitioner for a PostgreSQL table
+func NewPostgreSQLPartitioner(db *gorm.DB, config PartitionConfig) *PostgreSQLPartitioner {
+	return &PostgreSQLPartitioner{
+		db:     db,
+		config: config.withDefaults(),
+	}
+}
+
+// partitionTable returns the child table name for the partition starting at start
+func (p *PostgreSQLPartitioner) partitionTable(start time.Time) string {
+	return p.config.Table + "_" + PartitionSuffix(start, p.config.Interval)
+}
+
+// EnsurePartitions creates the current and upcoming partitions if they do not exist
+// An unpartitioned table is converted first
+func (p *PostgreSQLPartitioner) EnsurePartitions(now time.Time) error {
+	var kind string
+	err := p.db.Raw("SELECT relkind FROM pg_class WHERE oid = to_regclass(?)", `"`+p.config.Table+`"`).Scan(&kind).Error
+	if err != nil {
+		return fmt.Errorf("failed to inspect %s: %w", p.config.Table, err)
+	}
+	switch kind {
+	case "p":
+		return p.createPartitions(p.db, now)
+	case "":
+		return fmt.Errorf("table %s does not exist", p.config.Table)
+	default:
+		return p.db.Transaction(func(tx *gorm.DB) error {
+			return p.convert(tx, now)
+		})
+	}
+}
+
+// createPartitions creates the current and upcoming partitions on db
+func (p *PostgreSQLPartitioner) createPartitions(db *gorm.DB, now time.Time) error {
+	for _, start := range partitionStarts(now, p.config) {
+		end := nextPartitionStart(start, p.config.Interval)
+		stmt := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS "%s" PARTITION OF "%s" FOR VALUES FROM ('%s') TO ('%s')`,
+			p.partitionTable(start), p.config.Table, sqlTime(start), sqlTime(end))
+		if err := db.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", p.partitionTable(start), err)
+		}
+	}
+	return nil
+}
+
+// convert replaces a plain table with a partitioned parent of the same
+// columns, defaults and indexes, moving its rows over. Rows before the
+// first range land in a default partition, and sequences of the old table
+// are handed to the new one before it is dropped
+func (p *PostgreSQLPartitioner) convert(tx *gorm.DB, now time.Time) error {
+	table, old := p.config.Table, p.config.Table+"_unpartitioned"
+	steps := []string{
+		fmt.Sprintf(`ALTER TABLE "%s" RENAME TO "%s"`, table, old),
+		fmt.Sprintf(`CREATE TABLE "%s" (LIKE "%s" INCLUDING ALL) PARTITION BY RANGE ("%s")`, table, old, p.config.Column),
+		fmt.Sprintf(`CREATE TABLE "%s_pdefault" PARTITION OF "%s" DEFAULT`, table, table),
+	}
+	for _, stmt := range steps {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to partition %s: %w", table, err)
+		}
+	}
+	if err := p.createPartitions(tx, now); err != nil {
+		return err
+	}
+
+	steps = []string{
+		fmt.Sprintf(`INSERT INTO "%s" SELECT * FROM "%s"`, table, old),
+		fmt.Sprintf(`DO $$ DECLARE r record; BEGIN `+
+			`FOR r IN SELECT d.objid::regclass AS seq, a.attname FROM pg_depend d `+
+			`JOIN pg_attribute a ON a.attrelid = d.refobjid AND a.attnum = d.refobjsubid `+
+			`WHERE d.refobjid = '"%s"'::regclass AND d.classid = 'pg_class'::regclass AND d.deptype = 'a' `+
+			`LOOP EXECUTE format('ALTER SEQUENCE %%s OWNED BY %%I.%%I', r.seq, '%s', r.attname); END LOOP; END $$`, old, table),
+		fmt.Sprintf(`DROP TABLE "%s"`, old),
+	}
+	for _, stmt := range steps {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return fmt.Errorf("failed to partition %s: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// DropPartitionsBefore drops partitions whose range ends at or before cutoff
+func (p *PostgreSQLPartitioner) DropPartitionsBefore(cutoff time.Time) ([]string, error) {
+	var children []string
+	err := p.db.Raw(
+		"SELECT c.relname FROM pg_inherits i "+
+			"JOIN pg_class c ON c.oid = i.inhrelid "+
+			"JOIN pg_class parent ON parent.oid = i.inhparent "+
+			"WHERE parent.relname = ?",
+		p.config.Table,
+	).Scan(&children).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list partitions of %s: %w", p.config.Table, err)
+	}
+	sort.Strings(children)
+
+	prefix := p.config.Table + "_"
+	var dropped []string
+	for _, name := range children {
+		start, ok := parsePartitionSuffix(strings.TrimPrefix(name, prefix), p.config.Interval)
+		if !ok || !strings.HasPrefix(name, prefix) || nextPartitionStart(start, p.config.Interval).After(cutoff) {
+			continue
+		}
+		if err := p.db.Exec(fmt.Sprintf(`DROP TABLE IF EXISTS "%s"`, name)).Error; err != nil {
+			return dropped, fmt.Errorf("failed to drop partition %s: %w", name, err)
+		}
+		dropped = append(dropped, name)
+	}
+	return dropped, nil
+}
@@ -0,0 +1,188 @@
+package errs
+
+// Code is a stable, machine-readable error identity such as GOLWARC-CACHE-001
+// Codes are never reused; retired codes stay reserved
+type Code string
+
+// Kind is the category of a code; it decides the HTTP and gRPC status
+type Kind int
+
+// Error kinds
+const (
+	KindInternal Kind = iota
+	KindInvalidArgument
+	KindNotFound
+	KindAlreadyExists
+	KindFailedPrecondition
+	KindResourceExhausted
+	KindUnavailable
+	KindCanceled
+	KindDeadlineExceeded
+)
+
+// General codes
+const (
+	CodeInternal         Code = "GOLWARC-INTERNAL-001"
+	CodeCanceled         Code = "GOLWARC-INTERNAL-002"
+	CodeDeadlineExceeded Code = "GOLWARC-INTERNAL-003"
+	CodeInvalidConfig    Code = "GOLWARC-CONFIG-001"
+)
+
+// HTTP API codes
+const (
+	CodeInvalidRequest Code = "GOLWARC-API-001"
+	CodeNotFound       Code = "GOLWARC-API-002"
+	CodeUnavailable    Code = "GOLWARC-API-003"
+)
+
+// Cache codes
+const (
+	CodeCacheMiss        Code = "GOLWARC-CACHE-001"
+	CodeCacheConfig      Code = "GOLWARC-CACHE-002"
+	CodeCacheUnavailable Code = "GOLWARC-CACHE-003"
+)
+
+// Database codes
+const (
+	CodeDBUnavailable Code = "GOLWARC-DB-001"
+	CodeDBQuery       Code = "GOLWARC-DB-002"
+)
+
+// Crawler codes
+const (
+	CodeInvalidURL  Code = "GOLWARC-CRAWL-001"
+	CodeFetchFailed Code = "GOLWARC-CRAWL-002"
+	CodeThrottled   Code = "GOLWARC-CRAWL-003"
+	CodeNoProxy     Code = "GOLWARC-CRAWL-004"
+
+	CodeBrowserPoolClosed Code = "GOLWARC-CRAWL-005"
+	CodeBodyTooLarge      Code = "GOLWARC-CRAWL-006"
+	CodeSkippedContent    Code = "GOLWARC-CRAWL-007"
+	CodeRobotsDisallowed  Code = "GOLWARC-CRAWL-008"
+	CodeBlocked           Code = "GOLWARC-CRAWL-009"
+	CodeURLSkipped        Code = "GOLWARC-CRAWL-010"
+	CodeLoginFailed       Code = "GOLWARC-CRAWL-011"
+	CodeCircuitOpen       Code = "GOLWARC-CRAWL-012"
+)
+
+// Extraction codes
+const (
+	CodeExtractRules  Code = "GOLWARC-EXTRACT-001"
+	CodeMissingField  Code = "GOLWARC-EXTRACT-002"
+	CodeExtractFailed Code = "GOLWARC-EXTRACT-003"
+)
+
+// Crawl queue codes (frontier and API crawl jobs)
+const (
+	CodeQueueEmpty Code = "GOLWARC-QUEUE-001"
+	CodeLeaseLost  Code = "GOLWARC-QUEUE-002"
+	CodeQueueFull  Code = "GOLWARC-QUEUE-003"
+)
+
+// Storage codes
+const (
+	CodeStorageNotFound Code = "GOLWARC-STORAGE-001"
+	CodeStorageConfig   Code = "GOLWARC-STORAGE-002"
+	CodeStoreFailed     Code = "GOLWARC-STORAGE-003"
+)
+
+// Message queue codes
+const (
+	CodeMessageTooLarge   Code = "GOLWARC-MQ-001"
+	CodeBadPayload        Code = "GOLWARC-MQ-002"
+	CodeMessageInFlight   Code = "GOLWARC-MQ-003"
+	CodeSchemaTooNew      Code = "GOLWARC-MQ-004"
+	CodeSchemaUnsupported Code = "GOLWARC-MQ-005"
+)
+
+// Control plane codes
+const (
+	CodeUnknownWorker   Code = "GOLWARC-CP-001"
+	CodeUnknownTask     Code = "GOLWARC-CP-002"
+	CodeNoWorkers       Code = "GOLWARC-CP-003"
+	CodeWorkerBackedUp  Code = "GOLWARC-CP-004"
+	CodeWorkerConnected Code = "GOLWARC-CP-005"
+	CodeBadHandshake    Code = "GOLWARC-CP-006"
+)
+
+// Fault injection codes
+const (
+	CodeInjectedFault Code = "GOLWARC-CHAOS-001"
+)
+
+// kinds maps every code to its kind
+var kinds = map[Code]Kind{
+	CodeInternal:         KindInternal,
+	CodeCanceled:         KindCanceled,
+	CodeDeadlineExceeded: KindDeadlineExceeded,
+	CodeInvalidConfig:    KindInvalidArgument,
+
+	CodeInvalidRequest: KindInvalidArgument,
+	CodeNotFound:       KindNotFound,
+	CodeUnavailable:    KindUnavailable,
+
+	CodeCacheMiss:        KindNotFound,
+	CodeCacheConfig:      KindInvalidArgument,
+	CodeCacheUnavailable: KindUnavailable,
+
+	CodeDBUnavailable: KindUnavailable,
+	CodeDBQuery:       KindInternal,
+
+	CodeInvalidURL:  KindInvalidArgument,
+	CodeFetchFailed: KindUnavailable,
+	CodeThrottled:   KindResourceExhausted,
+	CodeNoProxy:     KindUnavailable,
+
+	CodeBrowserPoolClosed: KindUnavailable,
+	CodeBodyTooLarge:      KindResourceExhausted,
+	CodeSkippedContent:    KindFailedPrecondition,
+	CodeRobotsDisallowed:  KindFailedPrecondition,
+	CodeBlocked:           KindUnavailable,
+	CodeURLSkipped:        KindFailedPrecondition,
+	CodeLoginFailed:       KindFailedPrecondition,
+	CodeCircuitOpen:       KindUnavailable,
+
+	CodeExtractRules:  KindInvalidArgument,
+	CodeMissingField:  KindFailedPrecondition,
+	CodeExtractFailed: KindFailedPrecondition,
+
+	CodeQueueEmpty: KindNotFound,
+	CodeLeaseLost:  KindFailedPrecondition,
+	CodeQueueFull:  KindUnavailable,
+
+	CodeStorageNotFound: KindNotFound,
+	CodeStorageConfig:   KindInvalidArgument,
+	CodeStoreFailed:     KindUnavailable,
+
+	CodeMessageTooLarge:   KindResourceExhausted,
+	CodeBadPayload:        KindInvalidArgument,
+	CodeMessageInFlight:   KindUnavailable,
+	CodeSchemaTooNew:      KindUnavailable,
+	CodeSchemaUnsupported: KindFailedPrecondition,
+
+	CodeUnknownWorker:   KindNotFound,
+	CodeUnknownTask:     KindNotFound,
+	CodeNoWorkers:       KindUnavailable,
+	CodeWorkerBackedUp:  KindResourceExhausted,
+	CodeWorkerConnected: KindAlreadyExists,
+	CodeBadHandshake:    KindInvalidArgument,
+
+	CodeInjectedFault: KindUnavailable,
+}
+
+// Kind returns the category of the code; unknown codes are internal
+func (c Code) Kind() Kind {
+	if kind, ok := kinds[c]; ok {
+		return kind
+	}
+	return KindInternal
+}
+
+// Codes returns every registered code
+func Codes() []Code {
+	codes := make([]Code, 0, len(kinds))
+	for code := range kinds {
+		codes = append(codes, code)
+	}
+	return codes
+}
@@ -0,0 +1,115 @@
+// Package errs gives errors a stable code such as GOLWARC-CACHE-001 so API
+// clients, gRPC peers and logs can identify them without matching messages
+//
+// Coded errors keep their plain message as Error(); the code travels
+// separately (CodeOf, the "code" field of API errors, gRPC status details
+// and the error_code log field)
+package errs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Error is an error with a code
+type Error struct {
+	Code    Code
+	Message string
+	Err     error // Optional cause
+}
+
+// New creates a coded error
+// Package-level sentinels created with New still work with errors.Is
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf creates a coded error with a formatted message
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Wrap attaches a code and message to err; it returns nil when err is nil
+// An empty message keeps the cause's message as is
+func Wrap(err error, code Code, message string) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: message, Err: err}
+}
+
+// Wrapf is Wrap with a formatted message
+func Wrapf(err error, code Code, format string, args ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...), Err: err}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Message
+	case e.Message == "":
+		return e.Err.Error()
+	default:
+		return e.Message + ": " + e.Err.Error()
+	}
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is a coded error with the same code
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrorCode implements Coder
+func (e *Error) ErrorCode() Code {
+	return e.Code
+}
+
+// Coder is implemented by error types that carry a code without being *Error
+type Coder interface {
+	ErrorCode() Code
+}
+
+// CodeOf returns the first code in err's chain
+// Context errors map to CodeCanceled and CodeDeadlineExceeded, other uncoded
+// errors to CodeInternal; nil has no code
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ErrorCode()
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeDeadlineExceeded
+	default:
+		return CodeInternal
+	}
+}
+
+// HasCode reports whether err carries code
+func HasCode(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
+
+// Fields returns zap fields for logging err with its code
+func Fields(err error) []zap.Field {
+	return []zap.Field{zap.Error(err), zap.String("error_code", string(CodeOf(err)))}
+}
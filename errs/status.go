@@ -0,0 +1,118 @@
+package errs
+
+import (
+	"errors"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Domain identifies golwarc codes in gRPC ErrorInfo details
+const Domain = "golwarc"
+
+// httpStatuses maps kinds to HTTP status codes
+var httpStatuses = map[Kind]int{
+	KindInternal:           http.StatusInternalServerError,
+	KindInvalidArgument:    http.StatusBadRequest,
+	KindNotFound:           http.StatusNotFound,
+	KindAlreadyExists:      http.StatusConflict,
+	KindFailedPrecondition: http.StatusConflict,
+	KindResourceExhausted:  http.StatusTooManyRequests,
+	KindUnavailable:        http.StatusServiceUnavailable,
+	KindCanceled:           499, // Client closed request
+	KindDeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+// grpcCodes maps kinds to gRPC status codes
+var grpcCodes = map[Kind]codes.Code{
+	KindInternal:           codes.Internal,
+	KindInvalidArgument:    codes.InvalidArgument,
+	KindNotFound:           codes.NotFound,
+	KindAlreadyExists:      codes.AlreadyExists,
+	KindFailedPrecondition: codes.FailedPrecondition,
+	KindResourceExhausted:  codes.ResourceExhausted,
+	KindUnavailable:        codes.Unavailable,
+	KindCanceled:           codes.Canceled,
+	KindDeadlineExceeded:   codes.DeadlineExceeded,
+}
+
+// HTTPStatus returns the HTTP status code for err
+func HTTPStatus(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return httpStatuses[CodeOf(err).Kind()]
+}
+
+// CodeForHTTPStatus returns the generic API code for an HTTP error status
+func CodeForHTTPStatus(statusCode int) Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return CodeInvalidRequest
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusServiceUnavailable:
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
+
+// GRPCStatus implements the interface used by status.FromError
+// The code is attached as an ErrorInfo detail with Domain
+func (e *Error) GRPCStatus() *status.Status {
+	st := status.New(grpcCodes[e.Code.Kind()], e.Error())
+	if detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: string(e.Code), Domain: Domain}); err == nil {
+		return detailed
+	}
+	return st
+}
+
+// ToGRPC converts err to a gRPC status error carrying its code
+func ToGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	var coded *Error
+	if !errors.As(err, &coded) {
+		if _, ok := status.FromError(err); ok {
+			return err // Already a gRPC status
+		}
+	}
+	return (&Error{Code: CodeOf(err), Err: err}).GRPCStatus().Err()
+}
+
+// FromGRPC restores the coded error carried by a gRPC status error
+// Statuses without golwarc details get a code from their gRPC code
+func FromGRPC(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return err
+	}
+
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == Domain {
+			return &Error{Code: Code(info.Reason), Message: st.Message()}
+		}
+	}
+
+	code := CodeInternal
+	switch st.Code() {
+	case codes.InvalidArgument:
+		code = CodeInvalidRequest
+	case codes.NotFound:
+		code = CodeNotFound
+	case codes.Unavailable:
+		code = CodeUnavailable
+	case codes.Canceled:
+		code = CodeCanceled
+	case codes.DeadlineExceeded:
+		code = CodeDeadlineExceeded
+	}
+	return &Error{Code: code, Message: st.Message()}
+}
@@ -0,0 +1,58 @@
+package extractors
+
+import (
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// assetExtensions are the file types a plain link is treated as an asset for
+var assetExtensions = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true,
+	".ppt": true, ".pptx": true, ".odt": true, ".ods": true, ".csv": true,
+	".zip": true, ".gz": true, ".tar": true, ".7z": true,
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".svg": true,
+	".mp3": true, ".mp4": true, ".webm": true,
+}
+
+// DiscoverAssets returns the images and files a page references: image and
+// media sources, and links marked download or ending in a file extension
+// such as .pdf. URLs are resolved against pageURL, deduplicated and in
+// document order; data: and other non-http URLs are skipped
+func DiscoverAssets(doc *goquery.Document, pageURL string) []string {
+	var assets []string
+	seen := make(map[string]bool)
+	add := func(link string) {
+		assetURL := resolve(pageURL, strings.TrimSpace(link))
+		u, err := url.Parse(assetURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		u.Fragment = ""
+		assetURL = u.String()
+		if seen[assetURL] {
+			return
+		}
+		seen[assetURL] = true
+		assets = append(assets, assetURL)
+	}
+
+	doc.Find("img[src], video[src], audio[src], source[src], a[href]").Each(func(_ int, s *goquery.Selection) {
+		if goquery.NodeName(s) != "a" {
+			src, _ := s.Attr("src")
+			add(src)
+			return
+		}
+		href, _ := s.Attr("href")
+		if _, ok := s.Attr("download"); ok {
+			add(href)
+			return
+		}
+		if u, err := url.Parse(strings.TrimSpace(href)); err == nil && assetExtensions[strings.ToLower(path.Ext(u.Path))] {
+			add(href)
+		}
+	})
+	return assets
+}
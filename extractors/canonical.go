@@ -0,0 +1,40 @@
+package extractors
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// CanonicalLink returns the URL a page declares canonical with
+// <link rel="canonical">, resolved against pageURL and without fragment
+// Returns "" when there is none, when it is not an http or https URL, or
+// when the page declares several different ones, which search engines
+// ignore as well
+func CanonicalLink(doc *goquery.Document, pageURL string) string {
+	var canonical string
+	conflict := false
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !hasToken(rel, "canonical") {
+			return
+		}
+		href, _ := s.Attr("href")
+		u, err := url.Parse(resolve(pageURL, strings.TrimSpace(href)))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return
+		}
+		u.Fragment = ""
+		switch link := u.String(); {
+		case canonical == "":
+			canonical = link
+		case canonical != link:
+			conflict = true
+		}
+	})
+	if conflict {
+		return ""
+	}
+	return canonical
+}
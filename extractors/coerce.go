@@ -0,0 +1,213 @@
+package extractors
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// transform post-processes an extracted value; base is the page URL
+type transform func(value string, base *url.URL) string
+
+// compileTransform parses one transform step:
+//   - trim, lower, upper
+//   - squash: collapse runs of whitespace into one space
+//   - regex:<pattern>: the first capture group, or the whole match
+//   - replace:<old>|<new>
+//   - absolute: resolve a relative URL against the page URL
+func compileTransform(step string) (transform, error) {
+	name, arg, _ := strings.Cut(step, ":")
+	switch name {
+	case "trim":
+		return func(v string, _ *url.URL) string { return strings.TrimSpace(v) }, nil
+	case "lower":
+		return func(v string, _ *url.URL) string { return strings.ToLower(v) }, nil
+	case "upper":
+		return func(v string, _ *url.URL) string { return strings.ToUpper(v) }, nil
+	case "squash":
+		return func(v string, _ *url.URL) string { return strings.Join(strings.Fields(v), " ") }, nil
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex transform: %w", err)
+		}
+		return func(v string, _ *url.URL) string {
+			match := re.FindStringSubmatch(v)
+			switch {
+			case match == nil:
+				return ""
+			case len(match) > 1:
+				return match[1]
+			default:
+				return match[0]
+			}
+		}, nil
+	case "replace":
+		old, replacement, ok := strings.Cut(arg, "|")
+		if !ok || old == "" {
+			return nil, fmt.Errorf("replace transform needs <old>|<new>, got %q", arg)
+		}
+		return func(v string, _ *url.URL) string { return strings.ReplaceAll(v, old, replacement) }, nil
+	case "absolute":
+		return func(v string, base *url.URL) string {
+			ref, err := url.Parse(v)
+			if err != nil || base == nil || v == "" {
+				return v
+			}
+			return base.ResolveReference(ref).String()
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown transform %q", step)
+}
+
+// timeLayouts are tried in order for time fields without a layout
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"02 Jan 2006",
+}
+
+// timeType is the reflect type of time.Time
+var timeType = reflect.TypeOf(time.Time{})
+
+// settable reports whether a struct field type can be filled from text
+func settable(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// setField coerces value to the field's type and stores it
+func setField(field reflect.Value, value, layout string) error {
+	if field.Kind() == reflect.Pointer {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setField(ptr.Elem(), value, layout); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	if field.Type() == timeType {
+		t, err := parseTime(value, layout)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		field.SetBool(parseBool(value))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := parseNumber(value)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseNumber(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid unsigned number %q", value)
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, err := parseNumber(value)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(n)
+	default:
+		return errs.Newf(errs.CodeExtractRules, "unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// numberPattern finds the first number, allowing thousands separators
+var numberPattern = regexp.MustCompile(`-?\d[\d.,]*`)
+
+// parseNumber reads the first number in text such as "$1,299.00",
+// "1.299,00 €" or "(42 reviews)"
+// With both separators the last one is the decimal point; a lone comma is a
+// thousands separator only when exactly three digits follow it
+func parseNumber(value string) (float64, error) {
+	raw := strings.TrimRight(numberPattern.FindString(value), ".,")
+	if raw == "" {
+		return 0, fmt.Errorf("no number in %q", value)
+	}
+
+	lastDot, lastComma := strings.LastIndex(raw, "."), strings.LastIndex(raw, ",")
+	switch {
+	case lastDot >= 0 && lastComma >= 0 && lastComma > lastDot:
+		raw = strings.ReplaceAll(raw, ".", "")
+		raw = strings.Replace(raw, ",", ".", 1)
+	case lastDot >= 0 && lastComma >= 0:
+		raw = strings.ReplaceAll(raw, ",", "")
+	case lastComma >= 0 && strings.Count(raw, ",") == 1 && len(raw)-lastComma-1 != 3:
+		raw = strings.Replace(raw, ",", ".", 1)
+	case lastComma >= 0:
+		raw = strings.ReplaceAll(raw, ",", "")
+	case strings.Count(raw, ".") > 1:
+		raw = strings.ReplaceAll(raw, ".", "")
+	}
+
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", value)
+	}
+	return n, nil
+}
+
+// parseBool treats words such as "no", "false", "0" and "out of stock" as
+// false and any other non-empty text as true
+func parseBool(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "0", "false", "no", "n", "off", "out of stock", "sold out", "unavailable":
+		return false
+	}
+	return true
+}
+
+// parseTime parses value with layout, or with common layouts when empty
+func parseTime(value, layout string) (time.Time, error) {
+	if layout != "" {
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: %w", value, err)
+		}
+		return t, nil
+	}
+	for _, l := range timeLayouts {
+		if t, err := time.Parse(l, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", value)
+}
@@ -0,0 +1,280 @@
+package extractors
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/andybalholm/cascadia"
+)
+
+// modelTypes maps model names to the struct an extractor fills
+var modelTypes = map[string]reflect.Type{
+	ModelPage:    reflect.TypeOf(models.Page{}),
+	ModelProduct: reflect.TypeOf(models.Product{}),
+	ModelArticle: reflect.TypeOf(models.Article{}),
+}
+
+// reservedFields are set by the database, the extractor or the crawler
+var reservedFields = map[string]bool{
+	"id": true, "created_at": true, "updated_at": true, "deleted_at": true,
+	"url": true, "source_url": true, "project": true, "domain": true, "status": true,
+	"html": true, "content_hash": true, "body_codec": true, "body_ref": true,
+	"body_size": true, "headers": true, "canonical_url": true,
+}
+
+// Registry holds compiled extractors and picks one per URL
+type Registry struct {
+	extractors []*Extractor
+}
+
+// Extractor extracts one model from the pages of one site
+type Extractor struct {
+	name   string
+	model  string
+	typ    reflect.Type
+	hosts  []string
+	paths  []string
+	fields []compiledField
+}
+
+// compiledField is a FieldRule ready to run
+type compiledField struct {
+	name       string
+	index      []int
+	matcher    goquery.Matcher // Nil for constants
+	rule       FieldRule
+	transforms []transform
+}
+
+// Compile validates a rule set and compiles its extractors
+// Unknown models, fields, transforms and invalid selectors are errors
+func Compile(rules RuleSet) (*Registry, error) {
+	registry := &Registry{}
+	for i, site := range rules.Sites {
+		extractor, err := compileSite(site)
+		if err != nil {
+			name := site.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			return nil, errs.Wrapf(err, errs.CodeExtractRules, "invalid rules for site %s", name)
+		}
+		registry.extractors = append(registry.extractors, extractor)
+	}
+	return registry, nil
+}
+
+// compileSite compiles the rules of one site
+func compileSite(site SiteRules) (*Extractor, error) {
+	typ, ok := modelTypes[site.Model]
+	if !ok {
+		return nil, fmt.Errorf("unknown model %q", site.Model)
+	}
+	if len(site.Hosts) == 0 {
+		return nil, fmt.Errorf("at least one host is required")
+	}
+	if len(site.Fields) == 0 {
+		return nil, fmt.Errorf("at least one field is required")
+	}
+
+	e := &Extractor{name: site.Name, model: site.Model, typ: typ, paths: site.Paths}
+	for _, host := range site.Hosts {
+		e.hosts = append(e.hosts, strings.ToLower(strings.TrimSpace(host)))
+	}
+
+	fields := jsonFields(typ)
+	names := make([]string, 0, len(site.Fields))
+	for name := range site.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names) // Stable order for errors and extraction
+
+	for _, name := range names {
+		rule := site.Fields[name]
+		index, ok := fields[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown %s field %q", site.Model, name)
+		}
+
+		field := compiledField{name: name, index: index, rule: rule}
+		if rule.Selector != "" {
+			matcher, err := cascadia.Compile(rule.Selector)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: invalid selector %q: %w", name, rule.Selector, err)
+			}
+			field.matcher = matcher
+		} else if rule.Default == "" {
+			return nil, fmt.Errorf("field %s: selector or default is required", name)
+		}
+		for _, step := range rule.Transform {
+			t, err := compileTransform(step)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			field.transforms = append(field.transforms, t)
+		}
+		e.fields = append(e.fields, field)
+	}
+	return e, nil
+}
+
+// jsonFields maps the JSON names of a model's settable fields to their index
+func jsonFields(typ reflect.Type) map[string][]int {
+	fields := make(map[string][]int)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" || name == "-" || reservedFields[name] || !settable(f.Type) {
+			continue
+		}
+		fields[name] = f.Index
+	}
+	return fields
+}
+
+// Len returns the number of compiled extractors
+func (r *Registry) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.extractors)
+}
+
+// For returns the first extractor whose hosts and paths match the URL, or
+// nil when none does
+func (r *Registry) For(rawURL string) *Extractor {
+	if r == nil {
+		return nil
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	for _, e := range r.extractors {
+		if e.Matches(parsed) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Name returns the site name of the rules
+func (e *Extractor) Name() string {
+	return e.name
+}
+
+// Model returns the model the extractor produces: page, product or article
+func (e *Extractor) Model() string {
+	return e.model
+}
+
+// Matches reports whether the extractor applies to a URL
+func (e *Extractor) Matches(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	hostOK := false
+	for _, pattern := range e.hosts {
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			hostOK = host == suffix || strings.HasSuffix(host, "."+suffix)
+		} else {
+			hostOK = host == pattern
+		}
+		if hostOK {
+			break
+		}
+	}
+	if !hostOK {
+		return false
+	}
+
+	if len(e.paths) == 0 {
+		return true
+	}
+	for _, prefix := range e.paths {
+		if strings.HasPrefix(u.EscapedPath(), prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract fills a new model from the document
+// The result is a *models.Page, *models.Product or *models.Article with its
+// URL or SourceURL set to pageURL. Values that cannot be coerced leave the
+// field empty; only required fields make extraction fail
+func (e *Extractor) Extract(doc *goquery.Document, pageURL string) (interface{}, error) {
+	base, _ := url.Parse(pageURL) // A bad URL only disables the absolute transform
+	if base != nil && base.Host == "" {
+		base = nil
+	}
+
+	model := reflect.New(e.typ)
+	for _, field := range e.fields {
+		value := field.extract(doc, base)
+		if value == "" {
+			if field.rule.Required {
+				return nil, errs.Newf(errs.CodeMissingField, "%s: required field %s is empty", e.name, field.name)
+			}
+			continue
+		}
+		if err := setField(model.Elem().FieldByIndex(field.index), value, field.rule.Layout); err != nil && field.rule.Required {
+			return nil, errs.Wrapf(err, errs.CodeMissingField, "%s: required field %s", e.name, field.name)
+		}
+	}
+
+	switch m := model.Interface().(type) {
+	case *models.Page:
+		m.URL = pageURL
+		if base != nil {
+			m.Domain = base.Host
+		}
+	case *models.Product:
+		m.SourceURL = pageURL
+	case *models.Article:
+		m.SourceURL = pageURL
+	}
+	return model.Interface(), nil
+}
+
+// extract returns the post-processed text of a field, or its default
+func (f *compiledField) extract(doc *goquery.Document, base *url.URL) string {
+	value := ""
+	if f.matcher != nil {
+		selection := doc.FindMatcher(f.matcher)
+		if !f.rule.All {
+			selection = selection.First()
+		}
+		var values []string
+		selection.Each(func(_ int, s *goquery.Selection) {
+			if v := strings.TrimSpace(f.read(s)); v != "" {
+				values = append(values, v)
+			}
+		})
+		join := f.rule.Join
+		if join == "" {
+			join = ", "
+		}
+		value = strings.Join(values, join)
+	}
+
+	for _, t := range f.transforms {
+		value = t(value, base)
+	}
+	if value == "" {
+		value = f.rule.Default
+	}
+	return value
+}
+
+// read returns the attribute or text of one matched element
+func (f *compiledField) read(s *goquery.Selection) string {
+	if f.rule.Attr != "" {
+		return s.AttrOr(f.rule.Attr, "")
+	}
+	return s.Text()
+}
@@ -0,0 +1,67 @@
+package extractors
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Feed formats found by DiscoverFeeds
+const (
+	FeedRSS  = "rss"
+	FeedAtom = "atom"
+	FeedJSON = "json"
+)
+
+// feedTypes maps alternate link media types to feed formats
+var feedTypes = map[string]string{
+	"application/rss+xml":   FeedRSS,
+	"application/atom+xml":  FeedAtom,
+	"application/feed+json": FeedJSON,
+	"application/json":      FeedJSON, // Only with rel=alternate, as JSON Feed suggests
+}
+
+// FeedLink is a feed a page advertises with <link rel="alternate">
+type FeedLink struct {
+	URL   string `json:"url"`
+	Title string `json:"title,omitempty"`
+	Type  string `json:"type"` // rss, atom or json
+}
+
+// DiscoverFeeds returns the RSS, Atom and JSON feeds a page links to with
+// rel=alternate, resolved against pageURL and in document order
+func DiscoverFeeds(doc *goquery.Document, pageURL string) []FeedLink {
+	var feeds []FeedLink
+	seen := make(map[string]bool)
+	doc.Find("link[rel][type][href]").Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		if !hasToken(rel, "alternate") {
+			return
+		}
+		mediaType, _ := s.Attr("type")
+		mediaType, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(mediaType)), ";")
+		format, ok := feedTypes[strings.TrimSpace(mediaType)]
+		if !ok {
+			return
+		}
+		href, _ := s.Attr("href")
+		feedURL := resolve(pageURL, strings.TrimSpace(href))
+		if feedURL == "" || seen[feedURL] {
+			return
+		}
+		seen[feedURL] = true
+		title, _ := s.Attr("title")
+		feeds = append(feeds, FeedLink{URL: feedURL, Title: strings.TrimSpace(title), Type: format})
+	})
+	return feeds
+}
+
+// hasToken reports whether a space-separated attribute holds token
+func hasToken(attr, token string) bool {
+	for _, t := range strings.Fields(attr) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,98 @@
+// Package extractors compiles declarative scraping rules into extractors
+// for pages, products and articles
+//
+// Rules live in a YAML or JSON file with one entry per site. Each field rule
+// maps a CSS selector to a model field by its JSON name; the extracted text
+// is post-processed by transforms and coerced to the field's type, so a new
+// site only needs a new rules entry:
+//
+//	sites:
+//	  - name: example-shop
+//	    hosts: [shop.example.com]
+//	    model: product
+//	    fields:
+//	      name: {selector: h1.title}
+//	      price: {selector: .price}            # "$1,299.00" -> 1299
+//	      image_url: {selector: img.main, attr: src, transform: [absolute]}
+package extractors
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alonecandies/golwarc/errs"
+	"gopkg.in/yaml.v3"
+)
+
+// Models an extractor can produce
+const (
+	ModelPage    = "page"
+	ModelProduct = "product"
+	ModelArticle = "article"
+)
+
+// RuleSet is the content of a rules file
+type RuleSet struct {
+	Sites []SiteRules `json:"sites" yaml:"sites"`
+}
+
+// SiteRules describes how to extract one model from a site's pages
+type SiteRules struct {
+	Name   string               `json:"name" yaml:"name"`
+	Hosts  []string             `json:"hosts" yaml:"hosts"`   // Host names; *.example.com also matches example.com
+	Paths  []string             `json:"paths" yaml:"paths"`   // Optional path prefixes; empty matches every path
+	Model  string               `json:"model" yaml:"model"`   // page, product or article
+	Fields map[string]FieldRule `json:"fields" yaml:"fields"` // Keyed by the model's JSON field name
+}
+
+// FieldRule extracts one field
+type FieldRule struct {
+	Selector  string   `json:"selector" yaml:"selector"`   // CSS selector; empty uses Default as a constant
+	Attr      string   `json:"attr" yaml:"attr"`           // Attribute to read instead of the element text
+	All       bool     `json:"all" yaml:"all"`             // Join every match instead of taking the first
+	Join      string   `json:"join" yaml:"join"`           // Separator for All (default ", ")
+	Default   string   `json:"default" yaml:"default"`     // Used when nothing matches
+	Transform []string `json:"transform" yaml:"transform"` // Post-processing steps applied in order
+	Layout    string   `json:"layout" yaml:"layout"`       // time.Parse layout for time fields
+	Required  bool     `json:"required" yaml:"required"`   // Fail extraction when the field ends up empty or invalid
+}
+
+// ParseRules decodes a rule set; format is "json" or "yaml"
+func ParseRules(data []byte, format string) (RuleSet, error) {
+	var rules RuleSet
+	var err error
+	switch strings.ToLower(format) {
+	case "json":
+		err = json.Unmarshal(data, &rules)
+	case "yaml", "yml":
+		err = yaml.Unmarshal(data, &rules)
+	default:
+		return rules, errs.Newf(errs.CodeExtractRules, "unsupported rules format %q", format)
+	}
+	if err != nil {
+		return rules, errs.Wrap(err, errs.CodeExtractRules, "failed to parse rules")
+	}
+	return rules, nil
+}
+
+// LoadRules reads and compiles a rules file
+// Files ending in .json are JSON; anything else is read as YAML
+func LoadRules(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	format := "yaml"
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = "json"
+	}
+	rules, err := ParseRules(data, format)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(rules)
+}
@@ -0,0 +1,180 @@
+package extractors
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Issues reported by AuditSecurityHeaders
+const (
+	IssueNotHTTPS            = "not-https"
+	IssueMissingCSP          = "missing-csp"
+	IssueCSPUnsafeInline     = "csp-unsafe-inline"
+	IssueCSPUnsafeEval       = "csp-unsafe-eval"
+	IssueCSPWildcard         = "csp-wildcard-source"
+	IssueMissingHSTS         = "missing-hsts"
+	IssueHSTSShortMaxAge     = "hsts-short-max-age"
+	IssueMissingFrameOptions = "missing-frame-options"
+	IssueMissingNoSniff      = "missing-nosniff"
+	IssueMissingReferrer     = "missing-referrer-policy"
+	IssueUnsafeReferrer      = "unsafe-referrer-policy"
+	IssueMissingPermissions  = "missing-permissions-policy"
+)
+
+// hstsRecommendedMaxAge is the shortest HSTS max-age given full marks
+const hstsRecommendedMaxAge = 180 * 24 * 3600
+
+// SecurityHeaders is the security header audit of one response
+// Score runs from 0 to 100: CSP 30, HSTS 25, framing protection 20,
+// nosniff 10, Referrer-Policy 10 and Permissions-Policy 5
+type SecurityHeaders struct {
+	CSP                 string   `json:"csp,omitempty"`
+	HSTS                string   `json:"hsts,omitempty"`
+	XFrameOptions       string   `json:"x_frame_options,omitempty"`
+	XContentTypeOptions string   `json:"x_content_type_options,omitempty"`
+	ReferrerPolicy      string   `json:"referrer_policy,omitempty"`
+	PermissionsPolicy   string   `json:"permissions_policy,omitempty"`
+	Score               int      `json:"score"`
+	Issues              []string `json:"issues,omitempty"`
+}
+
+// AuditSecurityHeaders records and scores the security headers of a
+// response for pageURL
+// Report-only CSP headers are not enforced and do not count
+func AuditSecurityHeaders(pageURL string, header http.Header) SecurityHeaders {
+	audit := SecurityHeaders{
+		CSP:                 strings.Join(header.Values("Content-Security-Policy"), ", "),
+		HSTS:                header.Get("Strict-Transport-Security"),
+		XFrameOptions:       header.Get("X-Frame-Options"),
+		XContentTypeOptions: header.Get("X-Content-Type-Options"),
+		ReferrerPolicy:      header.Get("Referrer-Policy"),
+		PermissionsPolicy:   header.Get("Permissions-Policy"),
+	}
+	issue := func(name string) {
+		audit.Issues = append(audit.Issues, name)
+	}
+
+	csp := parseCSP(audit.CSP)
+	if audit.CSP == "" {
+		issue(IssueMissingCSP)
+	} else {
+		score := 30
+		scripts, ok := csp["script-src"]
+		if !ok {
+			scripts = csp["default-src"]
+		}
+		if containsFold(scripts, "'unsafe-inline'") && !hasNonceOrHash(scripts) {
+			issue(IssueCSPUnsafeInline)
+			score -= 10
+		}
+		if containsFold(scripts, "'unsafe-eval'") {
+			issue(IssueCSPUnsafeEval)
+			score -= 10
+		}
+		if len(scripts) == 0 || containsFold(scripts, "*") || containsFold(scripts, "https:") || containsFold(scripts, "http:") {
+			issue(IssueCSPWildcard)
+			score -= 10
+		}
+		audit.Score += score
+	}
+
+	// Browsers ignore HSTS received over plain http
+	if u, err := url.Parse(pageURL); err != nil || u.Scheme != "https" {
+		issue(IssueNotHTTPS)
+	} else if maxAge := hstsMaxAge(audit.HSTS); maxAge <= 0 {
+		issue(IssueMissingHSTS)
+	} else if maxAge < hstsRecommendedMaxAge {
+		issue(IssueHSTSShortMaxAge)
+		audit.Score += 15
+	} else {
+		audit.Score += 25
+	}
+
+	frameOptions := strings.ToUpper(strings.TrimSpace(audit.XFrameOptions))
+	if _, ok := csp["frame-ancestors"]; ok || frameOptions == "DENY" || frameOptions == "SAMEORIGIN" {
+		audit.Score += 20
+	} else {
+		issue(IssueMissingFrameOptions)
+	}
+
+	if strings.EqualFold(strings.TrimSpace(audit.XContentTypeOptions), "nosniff") {
+		audit.Score += 10
+	} else {
+		issue(IssueMissingNoSniff)
+	}
+
+	// The last policy a browser understands wins
+	policies := strings.Split(audit.ReferrerPolicy, ",")
+	switch policy := strings.ToLower(strings.TrimSpace(policies[len(policies)-1])); policy {
+	case "":
+		issue(IssueMissingReferrer)
+	case "unsafe-url", "no-referrer-when-downgrade":
+		issue(IssueUnsafeReferrer)
+	default:
+		audit.Score += 10
+	}
+
+	if audit.PermissionsPolicy != "" {
+		audit.Score += 5
+	} else {
+		issue(IssueMissingPermissions)
+	}
+	return audit
+}
+
+// parseCSP splits a policy into its directives and their sources
+// With several policies the first occurrence of a directive is kept
+func parseCSP(policy string) map[string][]string {
+	directives := make(map[string][]string)
+	for _, part := range strings.FieldsFunc(policy, func(r rune) bool { return r == ';' || r == ',' }) {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if _, seen := directives[name]; !seen {
+			directives[name] = fields[1:]
+		}
+	}
+	return directives
+}
+
+// hasNonceOrHash reports whether sources allow scripts by nonce or hash,
+// which makes browsers ignore 'unsafe-inline'
+func hasNonceOrHash(sources []string) bool {
+	for _, s := range sources {
+		s = strings.ToLower(s)
+		if strings.HasPrefix(s, "'nonce-") || strings.HasPrefix(s, "'sha256-") || strings.HasPrefix(s, "'sha384-") || strings.HasPrefix(s, "'sha512-") {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold reports whether values holds v, ignoring case
+func containsFold(values []string, v string) bool {
+	for _, value := range values {
+		if strings.EqualFold(value, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// hstsMaxAge returns the max-age of a Strict-Transport-Security header in
+// seconds, or 0 when absent or invalid
+func hstsMaxAge(value string) int64 {
+	for _, directive := range strings.Split(value, ";") {
+		name, v, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(strings.TrimSpace(name), "max-age") {
+			seconds, err := strconv.ParseInt(strings.Trim(strings.TrimSpace(v), `"`), 10, 64)
+			if err != nil {
+				return 0
+			}
+			return seconds
+		}
+	}
+	return 0
+}
@@ -0,0 +1,144 @@
+package extractors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// anyIconSize ranks sizes="any" icons, usually SVG, above every fixed size
+const anyIconSize = 1 << 16
+
+// SiteMetadata is what a page says about the site it belongs to
+type SiteMetadata struct {
+	SiteName    string `json:"site_name,omitempty"`    // og:site_name, application-name or apple-mobile-web-app-title
+	FaviconURL  string `json:"favicon_url,omitempty"`  // Largest declared icon, or /favicon.ico
+	ManifestURL string `json:"manifest_url,omitempty"` // <link rel="manifest">
+	ThemeColor  string `json:"theme_color,omitempty"`  // <meta name="theme-color">
+}
+
+// ExtractSiteMetadata reads the site name, favicon, web app manifest link and
+// theme color of a page, with links resolved against pageURL
+// Without a declared icon the favicon is /favicon.ico of the page's origin,
+// where browsers look for one
+func ExtractSiteMetadata(doc *goquery.Document, pageURL string) SiteMetadata {
+	var meta SiteMetadata
+	for _, selector := range []string{
+		`meta[property="og:site_name"]`,
+		`meta[name="application-name"]`,
+		`meta[name="apple-mobile-web-app-title"]`,
+	} {
+		if name := strings.TrimSpace(doc.Find(selector).First().AttrOr("content", "")); name != "" {
+			meta.SiteName = name
+			break
+		}
+	}
+	meta.ThemeColor = strings.TrimSpace(doc.Find(`meta[name="theme-color"]`).First().AttrOr("content", ""))
+
+	var touchIcon string
+	iconSize, touchSize := -1, -1
+	doc.Find("link[rel][href]").Each(func(_ int, s *goquery.Selection) {
+		rel, _ := s.Attr("rel")
+		href := resolve(pageURL, strings.TrimSpace(s.AttrOr("href", "")))
+		if href == "" {
+			return
+		}
+		size := largestIconSize(s.AttrOr("sizes", ""))
+		switch {
+		case hasToken(rel, "manifest"):
+			if meta.ManifestURL == "" {
+				meta.ManifestURL = href
+			}
+		case hasToken(rel, "icon"):
+			if size > iconSize {
+				meta.FaviconURL, iconSize = href, size
+			}
+		case hasToken(rel, "apple-touch-icon"), hasToken(rel, "apple-touch-icon-precomposed"):
+			if size > touchSize {
+				touchIcon, touchSize = href, size
+			}
+		}
+	})
+	if meta.FaviconURL == "" {
+		meta.FaviconURL = touchIcon
+	}
+	if meta.FaviconURL == "" {
+		if u, err := url.Parse(pageURL); err == nil && u.Host != "" {
+			meta.FaviconURL = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/favicon.ico"}).String()
+		}
+	}
+	return meta
+}
+
+// WebManifest is the part of a web app manifest that identifies a site
+type WebManifest struct {
+	Name            string         `json:"name,omitempty"`
+	ShortName       string         `json:"short_name,omitempty"`
+	ThemeColor      string         `json:"theme_color,omitempty"`
+	BackgroundColor string         `json:"background_color,omitempty"`
+	Icons           []ManifestIcon `json:"icons,omitempty"`
+}
+
+// ManifestIcon is an icon listed in a web app manifest
+type ManifestIcon struct {
+	Src     string `json:"src"`
+	Sizes   string `json:"sizes,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Purpose string `json:"purpose,omitempty"`
+}
+
+// ParseWebManifest parses a web app manifest, resolving icon sources
+// against manifestURL as browsers do
+func ParseWebManifest(data []byte, manifestURL string) (*WebManifest, error) {
+	var manifest WebManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("invalid web app manifest: %w", err)
+	}
+	manifest.Name = strings.TrimSpace(manifest.Name)
+	manifest.ShortName = strings.TrimSpace(manifest.ShortName)
+
+	icons := manifest.Icons[:0]
+	for _, icon := range manifest.Icons {
+		if icon.Src = resolve(manifestURL, strings.TrimSpace(icon.Src)); icon.Src != "" {
+			icons = append(icons, icon)
+		}
+	}
+	manifest.Icons = icons
+	return &manifest, nil
+}
+
+// BestIcon returns the source of the largest icon meant for general display,
+// or "" when there is none. Monochrome-only icons are skipped, as they
+// render as a silhouette
+func (m *WebManifest) BestIcon() string {
+	best, bestSize := "", -1
+	for _, icon := range m.Icons {
+		if icon.Purpose != "" && !hasToken(icon.Purpose, "any") && !hasToken(icon.Purpose, "maskable") {
+			continue
+		}
+		if size := largestIconSize(icon.Sizes); size > bestSize {
+			best, bestSize = icon.Src, size
+		}
+	}
+	return best
+}
+
+// largestIconSize returns the largest width in a sizes attribute such as
+// "16x16 32x32", anyIconSize for "any", or 0 when it lists none
+func largestIconSize(sizes string) int {
+	largest := 0
+	for _, size := range strings.Fields(strings.ToLower(sizes)) {
+		if size == "any" {
+			return anyIconSize
+		}
+		width, _, _ := strings.Cut(size, "x")
+		if n, err := strconv.Atoi(width); err == nil && n > largest {
+			largest = n
+		}
+	}
+	return largest
+}
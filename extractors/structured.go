@@ -0,0 +1,374 @@
+package extractors
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/models"
+)
+
+// articleTypes are the schema.org types read as articles
+var articleTypes = map[string]bool{
+	"Article": true, "NewsArticle": true, "BlogPosting": true, "TechArticle": true,
+	"Report": true, "ScholarlyArticle": true, "SocialMediaPosting": true, "LiveBlogPosting": true,
+}
+
+// StructuredData is the metadata a page publishes about itself
+type StructuredData struct {
+	Items     []map[string]interface{} // schema.org JSON-LD and microdata items
+	OpenGraph map[string][]string      // og:*, product:* and article:* properties
+	Twitter   map[string]string        // twitter:* card properties
+}
+
+// ParseStructuredData reads the schema.org JSON-LD scripts, microdata items,
+// OpenGraph and Twitter Card meta tags of a document
+// Invalid JSON-LD scripts are skipped
+func ParseStructuredData(doc *goquery.Document) *StructuredData {
+	data := &StructuredData{
+		OpenGraph: make(map[string][]string),
+		Twitter:   make(map[string]string),
+	}
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(s.Text()), &parsed); err != nil {
+			return
+		}
+		data.Items = append(data.Items, jsonLDItems(parsed)...)
+	})
+
+	doc.Find("[itemscope]").Not("[itemprop]").Each(func(_ int, s *goquery.Selection) {
+		data.Items = append(data.Items, microdataItem(s))
+	})
+
+	doc.Find("meta").Each(func(_ int, s *goquery.Selection) {
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if content == "" {
+			return
+		}
+		// OpenGraph uses property, Twitter Cards name, but sites mix them up
+		for _, key := range []string{s.AttrOr("property", ""), s.AttrOr("name", "")} {
+			key = strings.ToLower(strings.TrimSpace(key))
+			switch {
+			case strings.HasPrefix(key, "og:"), strings.HasPrefix(key, "product:"), strings.HasPrefix(key, "article:"):
+				data.OpenGraph[key] = append(data.OpenGraph[key], content)
+			case strings.HasPrefix(key, "twitter:"):
+				if _, ok := data.Twitter[key]; !ok {
+					data.Twitter[key] = content
+				}
+			default:
+				continue
+			}
+			break
+		}
+	})
+	return data
+}
+
+// jsonLDItems flattens a JSON-LD document: a single item, an array of
+// items, or an @graph
+func jsonLDItems(value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		var items []map[string]interface{}
+		for _, item := range v {
+			items = append(items, jsonLDItems(item)...)
+		}
+		return items
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return jsonLDItems(graph)
+		}
+		return []map[string]interface{}{v}
+	}
+	return nil
+}
+
+// microdataItem converts an itemscope element to a JSON-LD style item
+func microdataItem(s *goquery.Selection) map[string]interface{} {
+	item := make(map[string]interface{})
+	if itemType := s.AttrOr("itemtype", ""); itemType != "" {
+		item["@type"] = itemType[strings.LastIndex(itemType, "/")+1:]
+	}
+
+	var walk func(*goquery.Selection)
+	walk = func(parent *goquery.Selection) {
+		parent.Children().Each(func(_ int, child *goquery.Selection) {
+			name, isProp := child.Attr("itemprop")
+			_, isScope := child.Attr("itemscope")
+			if isProp {
+				var value interface{} = microdataValue(child)
+				if isScope {
+					value = microdataItem(child)
+				}
+				for _, prop := range strings.Fields(name) {
+					if _, ok := item[prop]; !ok {
+						item[prop] = value
+					}
+				}
+			}
+			if !isScope {
+				walk(child)
+			}
+		})
+	}
+	walk(s)
+	return item
+}
+
+// microdataValue returns the value of an itemprop element
+func microdataValue(s *goquery.Selection) string {
+	for _, attr := range []string{"content", "datetime"} {
+		if v, ok := s.Attr(attr); ok {
+			return strings.TrimSpace(v)
+		}
+	}
+	switch goquery.NodeName(s) {
+	case "a", "link", "area":
+		return s.AttrOr("href", "")
+	case "img", "audio", "video", "source", "iframe", "embed":
+		return s.AttrOr("src", "")
+	case "meta":
+		return ""
+	}
+	return strings.Join(strings.Fields(s.Text()), " ")
+}
+
+// find returns the first item of one of the given types
+func (d *StructuredData) find(types func(string) bool) map[string]interface{} {
+	for _, item := range d.Items {
+		for _, t := range stringsOf(item["@type"]) {
+			if types(t[strings.LastIndex(t, ":")+1:]) { // Accept schema:Product
+				return item
+			}
+		}
+	}
+	return nil
+}
+
+// og returns the first value of an OpenGraph property
+func (d *StructuredData) og(key string) string {
+	if values := d.OpenGraph[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Product maps a schema.org Product, or an OpenGraph page of type product,
+// to a product; nil when the page describes none
+// OpenGraph and Twitter Card tags fill fields the item leaves empty
+func (d *StructuredData) Product(pageURL string) *models.Product {
+	item := d.find(func(t string) bool { return t == "Product" || t == "ProductGroup" })
+	if item == nil && !strings.EqualFold(d.og("og:type"), "product") && d.og("product:price:amount") == "" {
+		return nil
+	}
+
+	p := &models.Product{
+		SourceURL:   pageURL,
+		Name:        firstNonEmpty(text(item, "name"), d.og("og:title"), d.Twitter["twitter:title"]),
+		Description: firstNonEmpty(text(item, "description"), d.og("og:description"), d.Twitter["twitter:description"]),
+		ImageURL:    firstNonEmpty(link(item, "image"), d.og("og:image"), d.Twitter["twitter:image"]),
+		Brand:       firstNonEmpty(text(item, "brand"), d.og("product:brand")),
+		Category:    firstNonEmpty(text(item, "category"), d.og("product:category")),
+		SKU:         firstNonEmpty(text(item, "sku"), text(item, "mpn"), d.og("product:retailer_item_id")),
+		InStock:     true,
+	}
+
+	offer := firstItem(item["offers"])
+	price := firstNonEmpty(text(offer, "price"), text(offer, "lowPrice"), d.og("product:price:amount"), d.og("og:price:amount"))
+	if n, err := parseNumber(price); err == nil {
+		p.Price = n
+	}
+	p.Currency = firstNonEmpty(text(offer, "priceCurrency"), d.og("product:price:currency"), d.og("og:price:currency"), "USD")
+	if availability := firstNonEmpty(text(offer, "availability"), d.og("product:availability"), d.og("og:availability")); availability != "" {
+		availability = strings.ToLower(availability[strings.LastIndex(availability, "/")+1:])
+		p.InStock = !strings.Contains(availability, "outofstock") && !strings.Contains(availability, "out of stock") &&
+			!strings.Contains(availability, "soldout") && !strings.Contains(availability, "discontinued")
+	}
+
+	rating := firstItem(item["aggregateRating"])
+	if n, err := parseNumber(text(rating, "ratingValue")); err == nil {
+		p.Rating = float32(n)
+	}
+	if n, err := parseNumber(firstNonEmpty(text(rating, "reviewCount"), text(rating, "ratingCount"))); err == nil {
+		p.ReviewCount = int(n)
+	}
+
+	p.ImageURL = resolve(pageURL, p.ImageURL)
+	if p.Name == "" {
+		return nil // Required by the products table
+	}
+	return p
+}
+
+// Article maps a schema.org Article (or a subtype), or an OpenGraph page of
+// type article, to an article; nil when the page describes none
+// OpenGraph and Twitter Card tags fill fields the item leaves empty
+func (d *StructuredData) Article(pageURL string) *models.Article {
+	item := d.find(func(t string) bool { return articleTypes[t] })
+	if item == nil && !strings.EqualFold(d.og("og:type"), "article") {
+		return nil
+	}
+
+	a := &models.Article{
+		SourceURL:  pageURL,
+		Title:      firstNonEmpty(text(item, "headline"), text(item, "name"), d.og("og:title"), d.Twitter["twitter:title"]),
+		Author:     strings.Join(names(item["author"]), ", "),
+		Content:    text(item, "articleBody"),
+		Summary:    firstNonEmpty(text(item, "description"), d.og("og:description"), d.Twitter["twitter:description"]),
+		SourceName: firstNonEmpty(text(item, "publisher"), d.og("og:site_name")),
+		Category:   firstNonEmpty(text(item, "articleSection"), d.og("article:section")),
+		ImageURL:   firstNonEmpty(link(item, "image"), d.og("og:image"), d.Twitter["twitter:image"]),
+		Language:   firstNonEmpty(text(item, "inLanguage"), d.og("og:locale")),
+	}
+	if a.Author == "" {
+		a.Author = firstNonEmpty(d.og("article:author"), d.Twitter["twitter:creator"])
+	}
+
+	tags := stringsOf(item["keywords"])
+	if len(tags) == 0 {
+		tags = d.OpenGraph["article:tag"]
+	}
+	a.Tags = strings.Join(tags, ", ")
+
+	if published := firstNonEmpty(text(item, "datePublished"), d.og("article:published_time")); published != "" {
+		if t, err := parseTime(published, ""); err == nil {
+			a.PublishedAt = &t
+		}
+	}
+	if n, err := parseNumber(text(item, "wordCount")); err == nil {
+		a.WordCount = int(n)
+	} else if a.Content != "" {
+		a.WordCount = len(strings.Fields(a.Content))
+	}
+	if len(a.Language) > 10 {
+		a.Language = "" // en_US fits; anything longer is not a language code
+	}
+	a.Language = strings.ReplaceAll(a.Language, "_", "-")
+
+	a.ImageURL = resolve(pageURL, a.ImageURL)
+	if a.Title == "" {
+		return nil // Required by the articles table
+	}
+	return a
+}
+
+// ExtractStructured returns the product or article a document describes in
+// its structured data, or nil when it describes neither
+// Products win over articles when both are present
+func ExtractStructured(doc *goquery.Document, pageURL string) interface{} {
+	data := ParseStructuredData(doc)
+	if p := data.Product(pageURL); p != nil {
+		return p
+	}
+	if a := data.Article(pageURL); a != nil {
+		return a
+	}
+	return nil
+}
+
+// text returns a property as text; objects give their name, or their url for
+// images and links, and arrays their first value
+func text(item map[string]interface{}, key string) string {
+	if item == nil {
+		return ""
+	}
+	values := stringsOf(item[key])
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// link returns a URL property; objects such as an ImageObject give their url
+func link(item map[string]interface{}, key string) string {
+	if object := firstItem(item[key]); object != nil {
+		return firstNonEmpty(text(object, "url"), text(object, "contentUrl"), text(object, "@id"))
+	}
+	return text(item, key)
+}
+
+// names returns the names of one or more people or organizations
+func names(value interface{}) []string {
+	if list, ok := value.([]interface{}); ok {
+		var result []string
+		for _, v := range list {
+			result = append(result, names(v)...)
+		}
+		return result
+	}
+	return stringsOf(value)
+}
+
+// stringsOf converts a JSON-LD value to strings; comma-separated strings
+// are not split
+func stringsOf(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if v = strings.TrimSpace(v); v != "" {
+			return []string{v}
+		}
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		return []string{strconv.FormatBool(v)}
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			result = append(result, stringsOf(item)...)
+		}
+		return result
+	case map[string]interface{}:
+		for _, key := range []string{"name", "url", "@value", "@id"} {
+			if s := stringsOf(v[key]); len(s) > 0 {
+				return s[:1]
+			}
+		}
+	}
+	return nil
+}
+
+// firstItem returns the first object of a value that is an object or an
+// array of them
+func firstItem(value interface{}) map[string]interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v
+	case []interface{}:
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				return m
+			}
+		}
+	}
+	return nil
+}
+
+// firstNonEmpty returns the first non-empty value
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// resolve makes a possibly relative link absolute against pageURL
+func resolve(pageURL, link string) string {
+	if link == "" {
+		return ""
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil || base.Host == "" {
+		return link
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return link
+	}
+	return base.ResolveReference(ref).String()
+}
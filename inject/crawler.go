@@ -0,0 +1,172 @@
+package inject
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// NewCrawler builds a Crawler on engine, one of the CrawlerType constants;
+// empty means crawler.engine from the configuration, else colly. Every
+// engine shares the container's rate limiter, robots.txt rules and bot-wall
+// detector and, where it supports them, its domain cooldowns, content type
+// filter, HSTS tracking, DNS cache and proxies. Close the result when done; browser
+// engines start a browser
+func (c *Container) NewCrawler(engine string) (crawlers.Crawler, error) {
+	crawler, err := c.newCrawler(engine)
+	if err != nil {
+		return nil, err
+	}
+	if c.Robots != nil {
+		crawler.Use(crawlers.RobotsMiddleware(c.Robots, c.RobotsAgent()))
+	}
+	if c.Blocks != nil {
+		crawler.Use(crawlers.BlockMiddleware(c.Blocks, nil))
+	}
+	return crawler, nil
+}
+
+// RobotsAgent returns the name robots.txt rules and robots meta tags address
+// the crawler by: the identity's name when configured, else the product
+// token of crawler.user_agent
+func (c *Container) RobotsAgent() string {
+	if c.Identity != nil {
+		return c.Identity.Agent()
+	}
+	return crawlers.RobotsAgent(c.Config.Crawler.UserAgent)
+}
+
+// newCrawler builds the engine of NewCrawler
+func (c *Container) newCrawler(engine string) (crawlers.Crawler, error) {
+	config := c.Config.Crawler
+	if engine == "" {
+		engine = config.Engine
+	}
+	timeout := time.Duration(config.RequestTimeout) * time.Second
+	var proxy string // Browsers take a single proxy
+	if len(config.Proxies) > 0 {
+		proxy = config.Proxies[0]
+	}
+
+	switch engine {
+	case "", crawlers.CrawlerTypeColly:
+		return crawlers.NewCollyCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{
+			UserAgent:     config.UserAgent,
+			MaxDepth:      config.MaxDepth,
+			Cooldown:      c.Cooldown,
+			RateLimiter:   c.RateLimiter,
+			Proxies:       config.Proxies,
+			ProxyStrategy: config.ProxyStrategy,
+			ContentTypes:  c.ContentTypes,
+			HSTS:          c.HSTS,
+			DNSCache:      c.DNS,
+			Metrics:       c.Metrics,
+		})), nil
+	case crawlers.CrawlerTypeSoup:
+		return crawlers.NewSoupCrawler(crawlers.NewSoupClient(crawlers.SoupConfig{
+			UserAgent:      config.UserAgent,
+			Timeout:        timeout,
+			Cooldown:       c.Cooldown,
+			RateLimiter:    c.RateLimiter,
+			Proxies:        config.Proxies,
+			ProxyStrategy:  config.ProxyStrategy,
+			ContentTypes:   c.ContentTypes,
+			HSTS:           c.HSTS,
+			CircuitBreaker: c.Breaker,
+			DNSCache:       c.DNS,
+			HTTPCache:      c.HTTPCache,
+			Metrics:        c.Metrics,
+		})), nil
+	case crawlers.CrawlerTypeSpider:
+		return crawlers.NewSpiderCrawler(crawlers.NewSpider(crawlers.SpiderConfig{
+			MaxDepth:      config.MaxDepth,
+			Concurrency:   config.Concurrency,
+			UserAgent:     config.UserAgent,
+			Timeout:       timeout,
+			Cooldown:      c.Cooldown,
+			MaxRetries:    config.Cooldown.MaxRetries,
+			RateLimiter:   c.RateLimiter,
+			Proxies:       config.Proxies,
+			ProxyStrategy: config.ProxyStrategy,
+			ContentTypes:  c.ContentTypes,
+			HSTS:          c.HSTS,
+			DNSCache:      c.DNS,
+			Canonical:     c.Canonical,
+			Dedup:         c.Dedup,
+			Priority:      crawlers.NewPriorityConfig(config.Priority),
+			Metrics:       c.Metrics,
+		})), nil
+	case crawlers.CrawlerTypePlaywright:
+		client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{
+			BrowserType: config.PlaywrightBrowser,
+			Headless:    true,
+			Timeout:     timeout,
+			RateLimiter: c.RateLimiter,
+			Proxy:       proxy,
+			Stealth:     newStealthConfig(config.PlaywrightStealth),
+			Emulation:   newEmulationConfig(config.Emulation),
+			Metrics:     c.Metrics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return crawlers.NewPlaywrightCrawler(client), nil
+	case crawlers.CrawlerTypePuppeteer:
+		client, err := crawlers.NewPuppeteerClient(crawlers.PuppeteerConfig{
+			Headless:  true,
+			Timeout:   timeout,
+			Tabs:      config.Concurrency,
+			Proxy:     proxy,
+			Emulation: newEmulationConfig(config.Emulation),
+			Metrics:   c.Metrics,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c.limited(crawlers.NewPuppeteerCrawler(client)), nil
+	case crawlers.CrawlerTypeSelenium:
+		client, err := crawlers.NewSeleniumClient(crawlers.SeleniumConfig{
+			BrowserName: "chrome",
+			Headless:    true,
+			RemoteURL:   config.SeleniumURL,
+			Proxy:       proxy,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return c.limited(crawlers.NewSeleniumCrawler(client)), nil
+	default:
+		return nil, fmt.Errorf("unknown crawler engine: %s", engine)
+	}
+}
+
+// limited rate limits a crawler whose client does not consult the shared
+// limiter and domain cooldowns itself
+func (c *Container) limited(crawler crawlers.Crawler) crawlers.Crawler {
+	if c.RateLimiter != nil || c.Cooldown != nil {
+		crawler.Use(crawlers.RateLimitMiddleware(c.RateLimiter, c.Cooldown))
+	}
+	return crawler
+}
+
+// newStealthConfig converts the configured Playwright fingerprint overrides
+func newStealthConfig(config configs.StealthConfig) crawlers.StealthConfig {
+	return crawlers.StealthConfig{
+		Enabled:        config.Enabled,
+		UserAgent:      config.UserAgent,
+		ViewportWidth:  config.ViewportWidth,
+		ViewportHeight: config.ViewportHeight,
+		Locale:         config.Locale,
+		TimezoneID:     config.Timezone,
+		Platform:       config.Platform,
+		WebGLVendor:    config.WebGLVendor,
+		WebGLRenderer:  config.WebGLRenderer,
+	}
+}
+
+// newEmulationConfig converts the configured device and region presets
+func newEmulationConfig(config configs.EmulationConfig) crawlers.EmulationConfig {
+	return crawlers.EmulationConfig{Device: config.Device, Region: config.Region}
+}
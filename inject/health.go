@@ -0,0 +1,105 @@
+package inject
+
+import (
+	"context"
+	"time"
+
+	"github.com/alonecandies/golwarc/libs"
+)
+
+// RequiredServices returns the services the configuration expects to be up
+// Services left unconfigured are optional and never block readiness
+func (c *Container) RequiredServices() []string {
+	required := []string{"logger", "config"}
+	if c.Config == nil {
+		return required
+	}
+
+	if c.Config.Cache.LRU.Size > 0 {
+		required = append(required, "lru_cache")
+	}
+	if c.Config.Cache.Redis.Addr != "" {
+		required = append(required, "redis")
+	}
+	if c.Config.Database.MySQL.Host != "" {
+		required = append(required, "mysql")
+	}
+	if c.Config.Database.PostgreSQL.Host != "" {
+		required = append(required, "postgresql")
+	}
+	if c.Config.Database.ClickHouse.Host != "" {
+		required = append(required, "clickhouse")
+	}
+	if len(c.Config.MessageQueue.Kafka.Brokers) > 0 {
+		required = append(required, "kafka")
+	}
+	if c.Config.MessageQueue.RabbitMQ.URL != "" {
+		required = append(required, "rabbitmq")
+	}
+	return required
+}
+
+// Readiness reports whether every required service is healthy along with
+// the status of each required service
+// It uses the latest MonitorHealth snapshot and only checks services
+// directly when no monitor is running
+// Its signature matches libs.ReadinessCheck
+func (c *Container) Readiness() (bool, map[string]bool) {
+	c.healthMu.RLock()
+	health := c.lastHealth
+	c.healthMu.RUnlock()
+	if health == nil {
+		health = c.Health()
+	}
+
+	ready := true
+	services := make(map[string]bool)
+	for _, name := range c.RequiredServices() {
+		services[name] = health[name]
+		if !health[name] {
+			ready = false
+		}
+	}
+	return ready, services
+}
+
+// MonitorHealth checks all services on every interval, records the results
+// for Readiness and feeds the health gauges of metrics (if not nil) until
+// ctx is done
+// A non-positive interval uses app.health_interval (default 15s)
+func (c *Container) MonitorHealth(ctx context.Context, metrics *libs.Metrics, interval time.Duration) {
+	if interval <= 0 && c.Config != nil {
+		interval = time.Duration(c.Config.App.HealthInterval) * time.Second
+	}
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		c.refreshHealth(metrics)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// refreshHealth runs all checks once and publishes the results
+func (c *Container) refreshHealth(metrics *libs.Metrics) {
+	health := c.Health()
+
+	c.healthMu.Lock()
+	c.lastHealth = health
+	c.healthMu.Unlock()
+
+	if metrics != nil {
+		for service, healthy := range health {
+			metrics.SetHealthStatus(service, healthy)
+		}
+	}
+}
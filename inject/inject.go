@@ -2,26 +2,67 @@ package inject
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/chaos"
 	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
 	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/extractors"
 	"github.com/alonecandies/golwarc/libs"
 	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"github.com/alonecandies/golwarc/storage"
+	"github.com/alonecandies/golwarc/warc"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // Container holds all injected dependencies
 type Container struct {
 	Logger       *zap.Logger
 	Config       *configs.Config
-	LRUCache     *cache.LRUCache
+	Metrics      *libs.Metrics    // Prometheus metrics of crawlers and health checks, exposed by the metrics server
+	LRUCache     cache.LocalCache // *cache.LRUCache, or *cache.ShardedLRUCache when shards > 1
 	RedisClient  *cache.RedisClient
 	MySQLClient  *database.MySQLClient
 	PGClient     *database.PostgreSQLClient
 	CHClient     *database.ClickHouseClient
 	KafkaClient  *messagequeue.KafkaProducer
 	RabbitClient *messagequeue.RabbitMQClient
+	BodyStore    *storage.BodyStore
+	WARCWriter   *warc.Writer                // Writer of the warc large backend; nil when another backend is used
+	WARCDigests  *warc.MemoryDigestStore     // Payload digests of WARC dedup, kept current by compaction; nil when disabled
+	RateLimiter  *crawlers.RateLimiter       // Shared by all crawler clients; nil when disabled
+	Cooldown     *crawlers.DomainCooldown    // Per-domain 429/503 backoff, shared through Redis when configured; nil when disabled
+	Frontier     *frontier.RedisFrontier     // Shared crawl queue; nil when disabled
+	ContentTypes *crawlers.ContentTypeFilter // Skips non-HTML responses; nil when disabled
+	Extractors   *extractors.Registry        // Declarative extraction rules; nil when disabled
+	HSTS         *crawlers.HSTS              // Known https hosts shared by all crawler clients; nil when disabled
+	Canonical    *crawlers.Canonicalizer     // URL canonical folding; nil when disabled
+	Dedup        *crawlers.DedupKey          // Page identity of the project; nil means exact URLs
+	Robots       *crawlers.RobotsTxt         // robots.txt rules, shared through Redis when configured; nil when disabled
+	Blocks       *crawlers.BlockDetector     // CAPTCHA and bot-wall detection; nil when disabled
+	Breaker      *crawlers.CircuitBreaker    // Per-host circuit breaker; nil when disabled
+	DNS          *crawlers.DNSCache          // Hostname cache shared by the HTTP crawler clients; nil when disabled
+	HTTPCache    *crawlers.HTTPCache         // Response cache of the Soup client; nil when disabled
+	Identity     *crawlers.BotIdentity       // Crawler name and contact details; nil keeps crawler.user_agent
+	Chaos        map[string]*chaos.Injector  // Fault injectors by dependency (cache, database, queue); nil when disabled
+
+	healthMu   sync.RWMutex
+	lastHealth map[string]bool // Latest MonitorHealth snapshot
+}
+
+// newLocalCache creates a sharded LRU cache when more than one shard is configured
+func newLocalCache(config configs.LRUConfig) (cache.LocalCache, error) {
+	if config.Shards > 1 {
+		return cache.NewShardedLRUCache(config.Size, config.Shards)
+	}
+	return cache.NewLRUCache(config.Size)
 }
 
 // NewContainer creates and initializes all dependencies based on configuration
@@ -34,6 +75,7 @@ func NewContainer(configPath string) (*Container, error) {
 	}
 	container.Logger = libs.GetLogger()
 	container.Logger.Info("Logger initialized")
+	container.Metrics = libs.DefaultMetrics()
 
 	// Load configuration
 	config, err := configs.LoadConfig(configPath)
@@ -44,28 +86,63 @@ func NewContainer(configPath string) (*Container, error) {
 	container.Config = config
 	container.Logger.Info("Configuration loaded")
 
+	// Initialize fault injection before the clients it hooks into
+	injectors, err := newChaosInjectors(config.Chaos)
+	if err != nil {
+		container.Logger.Warn("Failed to configure fault injection", zap.Error(err))
+	} else if injectors != nil {
+		container.Chaos = injectors
+		for name, injector := range injectors {
+			fault := injector.Fault()
+			container.Logger.Warn("Fault injection enabled",
+				zap.String("dependency", name),
+				zap.Duration("latency", fault.Latency),
+				zap.Duration("jitter", fault.Jitter),
+				zap.Float64("error_rate", fault.ErrorRate))
+		}
+	}
+
 	// Initialize LRU Cache if configured
 	if config.Cache.LRU.Size > 0 {
-		lruCache, err := cache.NewLRUCache(config.Cache.LRU.Size)
+		lruCache, err := newLocalCache(config.Cache.LRU)
 		if err != nil {
 			container.Logger.Warn("Failed to initialize LRU cache", zap.Error(err))
 		} else {
 			container.LRUCache = lruCache
-			container.Logger.Info("LRU cache initialized", zap.Int("size", config.Cache.LRU.Size))
+			container.Logger.Info("LRU cache initialized",
+				zap.Int("size", config.Cache.LRU.Size),
+				zap.Int("shards", config.Cache.LRU.Shards))
 		}
 	}
 
 	// Initialize Redis if configured
 	if config.Cache.Redis.Addr != "" {
+		serializer, err := cache.SerializerByName(config.Cache.Redis.Serializer)
+		if err != nil {
+			return nil, err
+		}
+		var clientCache *cache.ClientCacheConfig
+		if cc := config.Cache.Redis.ClientCache; cc.Enabled {
+			clientCache = &cache.ClientCacheConfig{
+				MaxKeys:  cc.MaxKeys,
+				TTL:      time.Duration(cc.TTL) * time.Second,
+				Prefixes: cc.Prefixes,
+			}
+		}
 		redisClient, err := cache.NewRedisClient(cache.RedisConfig{
-			Addr:     config.Cache.Redis.Addr,
-			Password: config.Cache.Redis.Password,
-			DB:       config.Cache.Redis.DB,
+			Addr:        config.Cache.Redis.Addr,
+			Password:    config.Cache.Redis.Password,
+			DB:          config.Cache.Redis.DB,
+			Serializer:  serializer,
+			ClientCache: clientCache,
 		})
 		if err != nil {
 			container.Logger.Warn("Failed to initialize Redis", zap.Error(err))
 		} else {
 			container.RedisClient = redisClient
+			if injector := container.Chaos[chaos.DependencyCache]; injector != nil {
+				redisClient.GetClient().AddHook(chaos.RedisHook(injector))
+			}
 			container.Logger.Info("Redis client initialized", zap.String("addr", config.Cache.Redis.Addr))
 		}
 	}
@@ -83,6 +160,7 @@ func NewContainer(configPath string) (*Container, error) {
 			container.Logger.Warn("Failed to initialize MySQL", zap.Error(err))
 		} else {
 			container.MySQLClient = mysqlClient
+			container.useDatabaseFaults(mysqlClient.GetDB())
 			container.Logger.Info("MySQL client initialized", zap.String("host", config.Database.MySQL.Host))
 		}
 	}
@@ -100,6 +178,7 @@ func NewContainer(configPath string) (*Container, error) {
 			container.Logger.Warn("Failed to initialize PostgreSQL", zap.Error(err))
 		} else {
 			container.PGClient = pgClient
+			container.useDatabaseFaults(pgClient.GetDB())
 			container.Logger.Info("PostgreSQL client initialized", zap.String("host", config.Database.PostgreSQL.Host))
 		}
 	}
@@ -121,6 +200,12 @@ func NewContainer(configPath string) (*Container, error) {
 		}
 	}
 
+	// Initialize message payload handling
+	payload, err := newPayloadConfig(config.MessageQueue.Payload)
+	if err != nil {
+		container.Logger.Warn("Failed to initialize message payload offloading", zap.Error(err))
+	}
+
 	// Initialize Kafka if configured
 	if len(config.MessageQueue.Kafka.Brokers) > 0 {
 		topic := config.MessageQueue.Kafka.Topic
@@ -128,8 +213,11 @@ func NewContainer(configPath string) (*Container, error) {
 			topic = "golwarc-events" // Default topic
 		}
 		kafkaClient := messagequeue.NewKafkaProducer(messagequeue.KafkaProducerConfig{
-			Brokers: config.MessageQueue.Kafka.Brokers,
-			Topic:   topic,
+			Brokers:     config.MessageQueue.Kafka.Brokers,
+			Topic:       topic,
+			Payload:     payload,
+			PartitionBy: config.MessageQueue.Kafka.PartitionBy,
+			Faults:      container.queueFaults(),
 		})
 		container.KafkaClient = kafkaClient
 		container.Logger.Info("Kafka producer initialized", zap.Strings("brokers", config.MessageQueue.Kafka.Brokers), zap.String("topic", topic))
@@ -138,13 +226,214 @@ func NewContainer(configPath string) (*Container, error) {
 	// Initialize RabbitMQ if configured
 	if config.MessageQueue.RabbitMQ.URL != "" {
 		rabbitClient, err := messagequeue.NewRabbitMQClient(messagequeue.RabbitMQConfig{
-			URL: config.MessageQueue.RabbitMQ.URL,
+			URL:     config.MessageQueue.RabbitMQ.URL,
+			Payload: payload,
+			Faults:  container.queueFaults(),
 		})
 		if err != nil {
 			container.Logger.Warn("Failed to initialize RabbitMQ", zap.Error(err))
 		} else {
 			container.RabbitClient = rabbitClient
 			container.Logger.Info("RabbitMQ client initialized")
+
+			if topology := newRabbitMQTopology(config.MessageQueue.RabbitMQ.Topology); !topology.IsEmpty() {
+				if err := rabbitClient.DeclareTopology(topology); err != nil {
+					container.Logger.Warn("Failed to declare RabbitMQ topology", zap.Error(err))
+				} else {
+					container.Logger.Info("RabbitMQ topology declared",
+						zap.Int("exchanges", len(topology.Exchanges)),
+						zap.Int("queues", len(topology.Queues)),
+						zap.Int("bindings", len(topology.Bindings)))
+				}
+			}
+		}
+	}
+
+	// Initialize page body storage
+	if config.Storage.LargeBackend == storage.CodecWARC && config.Storage.WARCDedup {
+		container.WARCDigests = seedDigests(config.Storage.WARCPath)
+	}
+	bodyStore, writer, err := newBodyStore(config.Storage, container.WARCDigests)
+	if err != nil {
+		container.Logger.Warn("Failed to initialize body storage, using defaults", zap.Error(err))
+		bodyStore = storage.NewBodyStore(storage.BodyStoreConfig{})
+	}
+	container.BodyStore = bodyStore
+	container.WARCWriter = writer
+	container.Logger.Info("Body storage initialized", zap.String("large_backend", config.Storage.LargeBackend))
+
+	// Build the user agent from the crawler's identity
+	identity, err := crawlers.NewBotIdentity(config.Crawler.Identity)
+	if err != nil {
+		container.Logger.Warn("Invalid crawler identity, using crawler.user_agent", zap.Error(err))
+	} else if identity != nil {
+		container.Identity = identity
+		config.Crawler.UserAgent = identity.UserAgent()
+		container.Logger.Info("Crawler identity initialized", zap.String("user_agent", config.Crawler.UserAgent))
+	}
+
+	// Initialize robots.txt handling
+	var robotsStore crawlers.RobotsStore
+	if container.RedisClient != nil {
+		robotsStore = crawlers.NewCacheRobotsStore(container.RedisClient)
+	}
+	if robots := crawlers.NewRobotsTxtFromConfig(config.Crawler.Robots, robotsStore, config.Crawler.UserAgent, container.Metrics); robots != nil {
+		container.Robots = robots
+		container.Logger.Info("robots.txt handling initialized",
+			zap.Bool("shared", robotsStore != nil))
+	}
+
+	// Initialize the shared per-domain rate limiter; robots.txt delays need
+	// one even when rate limiting itself is disabled
+	limiter := crawlers.NewRateLimiterFromConfig(config.Crawler.RateLimit, container.Metrics)
+	if container.Robots != nil && config.Crawler.Robots.CrawlDelay {
+		if limiter == nil {
+			limiter = crawlers.NewRateLimiter(crawlers.RateLimiterConfig{})
+		}
+		limiter.HonorCrawlDelay(container.Robots, container.RobotsAgent(),
+			time.Duration(config.Crawler.Robots.MaxCrawlDelay)*time.Second)
+	}
+	if limiter != nil {
+		container.RateLimiter = limiter
+		container.Logger.Info("Per-domain rate limiter initialized",
+			zap.Int("requests_per_sec", config.Crawler.RateLimit.RequestsPerSec),
+			zap.Int("max_concurrent", config.Crawler.RateLimit.MaxConcurrent),
+			zap.Bool("crawl_delay", container.Robots != nil && config.Crawler.Robots.CrawlDelay))
+	}
+
+	// Initialize per-domain cooldowns; through Redis every worker respects
+	// the backoff another one was told to take
+	var cooldownStore crawlers.CooldownStore
+	if container.RedisClient != nil {
+		cooldownStore = crawlers.NewCacheCooldownStore(container.RedisClient)
+	}
+	if cooldown := crawlers.NewDomainCooldownFromConfig(config.Crawler.Cooldown, cooldownStore, container.Metrics); cooldown != nil {
+		container.Cooldown = cooldown
+		container.Logger.Info("Domain cooldown initialized",
+			zap.Int("max_delay", config.Crawler.Cooldown.MaxDelay),
+			zap.Bool("shared", cooldownStore != nil))
+	}
+
+	// Initialize content type filtering
+	if filter := crawlers.NewContentTypeFilterFromConfig(config.Crawler.ContentTypes); filter != nil {
+		container.ContentTypes = filter
+		container.Logger.Info("Content type filter initialized",
+			zap.Strings("allow", config.Crawler.ContentTypes.Allow),
+			zap.Bool("head_requests", config.Crawler.ContentTypes.HeadRequests))
+	}
+
+	// Initialize HSTS tracking
+	if hsts := crawlers.NewHSTSFromConfig(config.Crawler.HSTS); hsts != nil {
+		container.HSTS = hsts
+		container.Logger.Info("HSTS tracking initialized",
+			zap.Bool("probe_https", config.Crawler.HSTS.ProbeHTTPS))
+	}
+
+	// Initialize bot-wall detection
+	if config.Crawler.BlockDetection {
+		container.Blocks = crawlers.NewBlockDetector(container.Metrics)
+		container.Logger.Info("Bot-wall detection initialized")
+	}
+
+	// Initialize the per-host circuit breaker
+	if breaker := crawlers.NewCircuitBreakerFromConfig(config.Crawler.CircuitBreaker, container.Metrics); breaker != nil {
+		container.Breaker = breaker
+		container.Logger.Info("Circuit breaker initialized",
+			zap.Int("failure_threshold", config.Crawler.CircuitBreaker.FailureThreshold),
+			zap.Int("open_timeout", config.Crawler.CircuitBreaker.OpenTimeout))
+	}
+
+	// Initialize the shared DNS cache
+	if dns := crawlers.NewDNSCacheFromConfig(config.Crawler.DNSCache); dns != nil {
+		container.DNS = dns
+		container.Logger.Info("DNS cache initialized",
+			zap.Strings("servers", config.Crawler.DNSCache.Servers),
+			zap.Int("max_entries", config.Crawler.DNSCache.MaxEntries))
+	}
+
+	// Initialize the Soup response cache; the redis backend falls back to
+	// memory without a Redis client
+	var httpCacheStore crawlers.HTTPCacheStore
+	switch config.Crawler.HTTPCache.Backend {
+	case "redis":
+		if container.RedisClient != nil {
+			httpCacheStore = crawlers.NewCacheHTTPCacheStore(container.RedisClient)
+		} else {
+			container.Logger.Warn("HTTP cache backend redis needs cache.redis, using memory")
+		}
+	case "file":
+		dir := config.Crawler.HTTPCache.Dir
+		if dir == "" {
+			dir = "./data/http-cache"
+		}
+		httpCacheStore = crawlers.NewFileHTTPCacheStore(dir)
+	}
+	if httpCache := crawlers.NewHTTPCacheFromConfig(config.Crawler.HTTPCache, httpCacheStore); httpCache != nil {
+		container.HTTPCache = httpCache
+		container.Logger.Info("HTTP cache initialized",
+			zap.String("backend", config.Crawler.HTTPCache.Backend),
+			zap.Bool("persistent", httpCacheStore != nil))
+	}
+
+	// Initialize URL canonical folding; query learning feeds its whitelists
+	// into the Canonicalizer, so it needs one even without folding rules
+	canonical, err := crawlers.NewCanonicalizerFromConfig(config.Crawler.Canonical)
+	if err == nil && canonical == nil && config.Crawler.QueryLearning.Enabled {
+		canonical, err = crawlers.NewCanonicalizer(crawlers.CanonicalConfig{})
+	}
+	if err != nil {
+		container.Logger.Warn("Failed to configure canonical folding", zap.Error(err))
+	} else if canonical != nil {
+		container.Canonical = canonical
+		container.Logger.Info("Canonical folding initialized",
+			zap.String("www", config.Crawler.Canonical.WWW),
+			zap.String("trailing_slash", config.Crawler.Canonical.TrailingSlash),
+			zap.Strings("index_pages", config.Crawler.Canonical.IndexPages),
+			zap.Int("query_param_hosts", len(config.Crawler.Canonical.QueryParams)))
+	}
+
+	// Initialize the project's dedup strategy
+	if config.Crawler.Dedup != "" {
+		dedup, err := crawlers.NewDedupKey(config.Crawler.Dedup)
+		if err != nil {
+			container.Logger.Warn("Failed to configure dedup strategy", zap.Error(err))
+		} else {
+			container.Dedup = dedup
+			container.Logger.Info("Dedup strategy initialized", zap.String("strategy", dedup.Strategy()))
+		}
+	}
+
+	// Load declarative extraction rules
+	if config.Crawler.Extractors != "" {
+		registry, err := extractors.LoadRules(config.Crawler.Extractors)
+		if err != nil {
+			container.Logger.Warn("Failed to load extraction rules", zap.String("path", config.Crawler.Extractors), zap.Error(err))
+		} else {
+			container.Extractors = registry
+			container.Logger.Info("Extraction rules loaded",
+				zap.String("path", config.Crawler.Extractors),
+				zap.Int("sites", registry.Len()))
+		}
+	}
+
+	// Initialize the shared crawl frontier
+	if config.Crawler.Frontier.Enabled {
+		if container.RedisClient == nil {
+			container.Logger.Warn("Crawl frontier requires Redis; using the in-process queue")
+		} else {
+			f, err := frontier.NewRedisFrontier(frontier.RedisConfig{
+				Client:            container.RedisClient.GetClient(),
+				Name:              config.Crawler.Frontier.Name,
+				VisibilityTimeout: time.Duration(config.Crawler.Frontier.VisibilityTimeout) * time.Second,
+				MaxRetries:        config.Crawler.Frontier.MaxRetries,
+				Key:               container.Dedup.URL,
+			})
+			if err != nil {
+				container.Logger.Warn("Failed to initialize crawl frontier", zap.Error(err))
+			} else {
+				container.Frontier = f
+				container.Logger.Info("Redis crawl frontier initialized", zap.String("name", config.Crawler.Frontier.Name))
+			}
 		}
 	}
 
@@ -152,6 +441,196 @@ func NewContainer(configPath string) (*Container, error) {
 	return container, nil
 }
 
+// newChaosInjectors creates an injector per dependency when fault injection
+// is enabled in config or GOLWARC_CHAOS is set; the variable's faults
+// replace the configured ones
+func newChaosInjectors(config configs.ChaosConfig) (map[string]*chaos.Injector, error) {
+	faults := map[string]chaos.Fault{
+		chaos.DependencyCache:    newFault(config.Cache),
+		chaos.DependencyDatabase: newFault(config.Database),
+		chaos.DependencyQueue:    newFault(config.Queue),
+	}
+	if spec := os.Getenv(chaos.EnvVar); spec != "" {
+		parsed, err := chaos.ParseSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		for name := range parsed {
+			if _, known := faults[name]; !known {
+				return nil, fmt.Errorf("%s: unknown dependency %q", chaos.EnvVar, name)
+			}
+		}
+		for name := range faults {
+			faults[name] = parsed[name]
+		}
+	} else if !config.Enabled {
+		return nil, nil
+	}
+
+	injectors := make(map[string]*chaos.Injector, len(faults))
+	for name, fault := range faults {
+		injectors[name] = chaos.NewInjector(chaos.InjectorConfig{
+			Name:  name,
+			Fault: fault,
+			Seed:  config.Seed,
+		})
+	}
+	return injectors, nil
+}
+
+// newFault converts configured faults
+func newFault(config configs.FaultConfig) chaos.Fault {
+	return chaos.Fault{
+		Latency:   time.Duration(config.Latency) * time.Millisecond,
+		Jitter:    time.Duration(config.Jitter) * time.Millisecond,
+		ErrorRate: config.ErrorRate,
+	}
+}
+
+// useDatabaseFaults registers the database fault injector on db
+func (c *Container) useDatabaseFaults(db *gorm.DB) {
+	injector := c.Chaos[chaos.DependencyDatabase]
+	if injector == nil || db == nil {
+		return
+	}
+	if err := db.Use(chaos.GormPlugin(injector)); err != nil {
+		c.Logger.Warn("Failed to enable database fault injection", zap.Error(err))
+	}
+}
+
+// queueFaults returns the queue fault injector as a message-queue
+// FaultInjector; nil when fault injection is disabled
+func (c *Container) queueFaults() messagequeue.FaultInjector {
+	if injector := c.Chaos[chaos.DependencyQueue]; injector != nil {
+		return injector
+	}
+	return nil
+}
+
+// Database returns the MySQL client as a DatabaseClient; nil, not a typed
+// nil, when MySQL is not configured, so services can check it against nil
+func (c *Container) Database() database.DatabaseClient {
+	if c.MySQLClient != nil {
+		return c.MySQLClient
+	}
+	return nil
+}
+
+// newPayloadConfig builds message compression and offloading from configuration
+// If the offload store cannot be opened, oversized messages are rejected
+func newPayloadConfig(config configs.MessagePayloadConfig) (messagequeue.PayloadConfig, error) {
+	payload := messagequeue.PayloadConfig{
+		CompressAbove:  config.CompressAbove,
+		MaxMessageSize: config.MaxMessageSize,
+	}
+	if config.OffloadDir == "" {
+		return payload, nil
+	}
+	store, err := storage.NewFileObjectStore(config.OffloadDir)
+	if err != nil {
+		return payload, err
+	}
+	payload.Offload = store
+	return payload, nil
+}
+
+// newRabbitMQTopology converts the configured topology
+func newRabbitMQTopology(config configs.RabbitMQTopologyConfig) messagequeue.RabbitMQTopology {
+	var topology messagequeue.RabbitMQTopology
+	for _, exchange := range config.Exchanges {
+		topology.Exchanges = append(topology.Exchanges, messagequeue.ExchangeSpec{
+			Name:    exchange.Name,
+			Kind:    exchange.Kind,
+			Durable: exchange.Durable,
+		})
+	}
+	for _, queue := range config.Queues {
+		topology.Queues = append(topology.Queues, messagequeue.QueueSpec{
+			Name:                 queue.Name,
+			Durable:              queue.Durable,
+			MessageTTL:           time.Duration(queue.MessageTTL) * time.Second,
+			MaxLength:            queue.MaxLength,
+			DeadLetterQueue:      queue.DeadLetterQueue,
+			DeadLetterExchange:   queue.DeadLetterExchange,
+			DeadLetterRoutingKey: queue.DeadLetterRoutingKey,
+		})
+	}
+	for _, binding := range config.Bindings {
+		topology.Bindings = append(topology.Bindings, messagequeue.BindingSpec{
+			Queue:      binding.Queue,
+			Exchange:   binding.Exchange,
+			RoutingKey: binding.RoutingKey,
+		})
+	}
+	return topology
+}
+
+// newBodyStore builds a size-tiered body store from configuration, and returns
+// the WARC writer when bodies go to WARC files
+// digests deduplicates WARC payloads; nil writes every payload
+func newBodyStore(config configs.StorageConfig, digests *warc.MemoryDigestStore) (*storage.BodyStore, *warc.Writer, error) {
+	var large storage.BodyCodec
+	var writer *warc.Writer
+
+	switch config.LargeBackend {
+	case "", storage.CodecGzip:
+		large = storage.GzipCodec{}
+	case storage.CodecObject:
+		store, err := storage.NewFileObjectStore(config.ObjectDir)
+		if err != nil {
+			return nil, nil, err
+		}
+		large = storage.NewObjectCodec(store)
+	case storage.CodecWARC:
+		var err error
+		writer, err = warc.NewWriter(config.WARCPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := writer.EnableCDX(); err != nil {
+			_ = writer.Close() // Best effort cleanup
+			return nil, nil, err
+		}
+		writer.SetRotation(warc.RotationConfig{
+			MaxSize: config.WARCMaxSize,
+			MaxAge:  time.Duration(config.WARCMaxAge) * time.Minute,
+		})
+		if digests != nil {
+			large = storage.NewDedupWARCCodec(writer, digests)
+		} else {
+			large = storage.NewWARCCodec(writer)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown storage backend: %s", config.LargeBackend)
+	}
+
+	return storage.NewBodyStore(storage.BodyStoreConfig{
+		InlineMaxSize:     config.InlineMaxSize,
+		CompressedMaxSize: config.CompressedMaxSize,
+		Large:             large,
+	}), writer, nil
+}
+
+// seedDigests loads payload digests from the CDX indexes of the WARC files
+// next to warcPath, which include its rotated and compacted files
+func seedDigests(warcPath string) *warc.MemoryDigestStore {
+	store := warc.NewMemoryDigestStore()
+
+	dir := filepath.Dir(warcPath)
+	paths, _ := filepath.Glob(filepath.Join(dir, warc.CDXPath("*.warc.gz"))) // Pattern is valid
+	for _, path := range paths {
+		file, err := os.Open(path)
+		if err != nil {
+			continue // Removed by compaction since the listing
+		}
+		if entries, err := warc.ReadCDXJ(file); err == nil {
+			store.Seed(dir, entries)
+		}
+		_ = file.Close() // Error intentionally ignored on close
+	}
+	return store
+}
+
 // Close closes all open connections
 func (c *Container) Close() error {
 	c.Logger.Info("Closing all connections...")
@@ -199,6 +678,13 @@ func (c *Container) Close() error {
 		c.Logger.Info("RabbitMQ connection closed")
 	}
 
+	if c.BodyStore != nil {
+		if err := c.BodyStore.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("body store close: %w", err))
+		}
+		c.Logger.Info("Body storage closed")
+	}
+
 	libs.Sync()
 
 	if len(errs) > 0 {
@@ -0,0 +1,58 @@
+package libs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// CrawlIDField is the log field holding the crawl ID
+	CrawlIDField = "crawl_id"
+	// CrawlIDHeader carries the crawl ID over HTTP and message queue headers
+	CrawlIDHeader = "X-Crawl-ID"
+)
+
+// crawlIDKey is the context key of the crawl ID
+type crawlIDKey struct{}
+
+// NewCrawlID returns a random 16-character hex crawl ID
+func NewCrawlID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:]) // Never fails on supported platforms
+	return hex.EncodeToString(b[:])
+}
+
+// WithCrawlID returns a copy of ctx carrying the crawl ID
+func WithCrawlID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, crawlIDKey{}, id)
+}
+
+// CrawlIDFrom returns the crawl ID carried by ctx, or "" if none
+func CrawlIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(crawlIDKey{}).(string)
+	return id
+}
+
+// EnsureCrawlID returns ctx and its crawl ID, attaching a new ID if ctx has none
+func EnsureCrawlID(ctx context.Context) (context.Context, string) {
+	if id := CrawlIDFrom(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewCrawlID()
+	return WithCrawlID(ctx, id), id
+}
+
+// LoggerFrom returns logger with the crawl ID of ctx attached as a field
+// A nil logger falls back to the global logger
+func LoggerFrom(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if logger == nil {
+		logger = GetLogger()
+	}
+	if id := CrawlIDFrom(ctx); id != "" {
+		return logger.With(zap.String(CrawlIDField, id))
+	}
+	return logger
+}
@@ -1,8 +1,11 @@
 package libs
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -16,6 +19,10 @@ type Metrics struct {
 	CrawlerRequestsTotal *prometheus.CounterVec
 	CrawlerDuration      *prometheus.HistogramVec
 	CrawlerErrorsTotal   *prometheus.CounterVec
+	RobotsFetchesTotal   *prometheus.CounterVec
+	CrawlerBlockedTotal  *prometheus.CounterVec
+	CrawlerThrottleTotal *prometheus.CounterVec
+	CrawlerCircuitTotal  *prometheus.CounterVec
 
 	// Cache metrics
 	CacheOperationsTotal *prometheus.CounterVec
@@ -29,10 +36,33 @@ type Metrics struct {
 	// System metrics
 	ActiveConnections prometheus.Gauge
 	HealthStatus      *prometheus.GaugeVec
+
+	// SLO metrics derived from recent crawls
+	SLO *SLOTracker
+}
+
+var (
+	defaultMetrics     *Metrics
+	defaultMetricsOnce sync.Once
+)
+
+// DefaultMetrics returns the process-wide metrics, creating them on first use
+// Metrics register with the default Prometheus registry, so components that
+// share one process must share these rather than call NewMetrics again
+func DefaultMetrics() *Metrics {
+	defaultMetricsOnce.Do(func() {
+		defaultMetrics = NewMetrics()
+	})
+	return defaultMetrics
 }
 
-// NewMetrics creates and registers all Prometheus metrics
+// NewMetrics creates and registers all Prometheus metrics with default SLO thresholds
 func NewMetrics() *Metrics {
+	return NewMetricsWithSLO(SLOConfig{})
+}
+
+// NewMetricsWithSLO creates and registers all Prometheus metrics
+func NewMetricsWithSLO(sloConfig SLOConfig) *Metrics {
 	metrics := &Metrics{
 		// Crawler metrics
 		CrawlerRequestsTotal: promauto.NewCounterVec(
@@ -57,6 +87,34 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"crawler_type", "error_type"},
 		),
+		RobotsFetchesTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "golwarc_robots_fetches_total",
+				Help: "Total number of robots.txt fetches by outcome",
+			},
+			[]string{"outcome"},
+		),
+		CrawlerBlockedTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "golwarc_crawler_blocked_total",
+				Help: "Total number of responses that were CAPTCHA or bot walls",
+			},
+			[]string{"kind"},
+		),
+		CrawlerThrottleTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "golwarc_crawler_throttle_events_total",
+				Help: "Total number of adaptive rate limiter events by action (slowed, paused, recovered)",
+			},
+			[]string{"action"},
+		),
+		CrawlerCircuitTotal: promauto.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "golwarc_crawler_circuit_transitions_total",
+				Help: "Total number of per-host circuit breaker state changes by new state (open, half_open, closed)",
+			},
+			[]string{"state"},
+		),
 
 		// Cache metrics
 		CacheOperationsTotal: promauto.NewCounterVec(
@@ -113,42 +171,88 @@ func NewMetrics() *Metrics {
 			},
 			[]string{"service"},
 		),
+
+		SLO: NewSLOTracker(sloConfig),
 	}
+	prometheus.MustRegister(metrics.SLO)
 
 	return metrics
 }
 
-// MetricsServer holds the HTTP server for metrics
+// ReadinessCheck reports whether the application can serve traffic along
+// with the status of each service it depends on
+type ReadinessCheck func() (ready bool, services map[string]bool)
+
+// MetricsServer holds the HTTP server for metrics and health probes
 type MetricsServer struct {
-	server  *http.Server
-	Metrics *Metrics
+	server    *http.Server
+	Metrics   *Metrics
+	readiness atomic.Pointer[ReadinessCheck]
 }
 
-// NewMetricsServer creates a new metrics server
+// NewMetricsServer creates a new metrics server exposing DefaultMetrics
+// Routes: /metrics, /livez (process is up), /readyz (see SetReadinessCheck)
+// and /health (alias of /livez)
 func NewMetricsServer(port int) *MetricsServer {
-	metrics := NewMetrics()
+	ms := &MetricsServer{
+		Metrics: DefaultMetrics(),
+	}
 
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write([]byte("OK")); err != nil {
-			// Log error but don't fail on write error for health check
-			return
-		}
-	})
+	mux.HandleFunc("/livez", ms.livez)
+	mux.HandleFunc("/health", ms.livez)
+	mux.HandleFunc("/readyz", ms.readyz)
 
-	server := &http.Server{
+	ms.server = &http.Server{
 		Addr:         fmt.Sprintf(":%d", port),
 		Handler:      mux,
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 	}
+	return ms
+}
+
+// SetReadinessCheck sets the check behind /readyz
+// Without a check the server is ready as soon as it is live
+func (ms *MetricsServer) SetReadinessCheck(check ReadinessCheck) {
+	ms.readiness.Store(&check)
+}
+
+// Handler returns the root HTTP handler (useful for tests)
+func (ms *MetricsServer) Handler() http.Handler {
+	return ms.server.Handler
+}
+
+// livez answers liveness probes; it only fails when the process cannot serve HTTP
+func (ms *MetricsServer) livez(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("OK")) // Error intentionally ignored; client may disconnect
+}
+
+// readinessResponse is the JSON body of /readyz
+type readinessResponse struct {
+	Status   string          `json:"status"`
+	Services map[string]bool `json:"services,omitempty"`
+}
 
-	return &MetricsServer{
-		server:  server,
-		Metrics: metrics,
+// readyz answers readiness probes with 503 until the readiness check passes
+func (ms *MetricsServer) readyz(w http.ResponseWriter, _ *http.Request) {
+	ready, services := true, map[string]bool(nil)
+	if check := ms.readiness.Load(); check != nil {
+		ready, services = (*check)()
 	}
+
+	body := readinessResponse{Status: "ready", Services: services}
+	status := http.StatusOK
+	if !ready {
+		body.Status = "not_ready"
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body) // Error intentionally ignored; headers are already sent
 }
 
 // Start starts the metrics server
@@ -176,6 +280,37 @@ func (m *Metrics) RecordCrawlerError(crawlerType, errorType string) {
 	m.CrawlerErrorsTotal.WithLabelValues(crawlerType, errorType).Inc()
 }
 
+// RecordRobotsFetch records a robots.txt fetch outcome
+func (m *Metrics) RecordRobotsFetch(outcome string) {
+	m.RobotsFetchesTotal.WithLabelValues(outcome).Inc()
+}
+
+// RecordBlocked records a CAPTCHA or bot wall response
+func (m *Metrics) RecordBlocked(kind string) {
+	m.CrawlerBlockedTotal.WithLabelValues(kind).Inc()
+}
+
+// RecordThrottle records an adaptive rate limiter event
+func (m *Metrics) RecordThrottle(action string) {
+	m.CrawlerThrottleTotal.WithLabelValues(action).Inc()
+}
+
+// RecordCircuitState records a per-host circuit breaker state change
+func (m *Metrics) RecordCircuitState(state string) {
+	m.CrawlerCircuitTotal.WithLabelValues(state).Inc()
+}
+
+// RecordFetch records a completed crawl in the request, duration and SLO metrics
+func (m *Metrics) RecordFetch(crawlerType string, duration time.Duration, err error) {
+	status := "success"
+	if err != nil {
+		status = "error"
+	}
+	m.RecordCrawlerRequest(crawlerType, status)
+	m.RecordCrawlerDuration(crawlerType, duration)
+	m.SLO.RecordFetch(duration, err == nil)
+}
+
 // RecordCacheOperation records a cache operation
 func (m *Metrics) RecordCacheOperation(cacheType, operation, status string) {
 	m.CacheOperationsTotal.WithLabelValues(cacheType, operation, status).Inc()
@@ -0,0 +1,276 @@
+package libs
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+)
+
+// SLOConfig holds SLO targets and alert thresholds
+type SLOConfig struct {
+	Window             time.Duration // Sliding window for derived metrics (default 5m)
+	MaxSamples         int           // Fetch samples kept in the window (default 10000)
+	SuccessRatioTarget float64       // Crawl success ratio objective (default 0.99)
+	FetchLatencyP95    time.Duration // p95 fetch latency threshold (default 5s)
+	QueueAge           time.Duration // Oldest queued URL threshold (default 10m)
+	BurnRateAlert      float64       // Error budget burn rate that pages (default 14.4)
+	AlertFor           time.Duration // How long a threshold must be breached (default 5m)
+	Clock              clock.Clock   // Sample times; defaults to the wall clock
+}
+
+// SLOSnapshot holds the derived SLO values for the current window
+type SLOSnapshot struct {
+	Requests        int
+	SuccessRatio    float64 // 1 when there were no requests
+	FetchLatencyP95 time.Duration
+	QueueAge        time.Duration
+	BurnRate        float64 // Error rate relative to the error budget
+}
+
+// fetchSample is one crawl outcome
+type fetchSample struct {
+	at       time.Time
+	duration time.Duration
+	ok       bool
+}
+
+// SLOTracker derives SLO metrics from recent crawl outcomes
+// It implements prometheus.Collector; values are computed at scrape time
+type SLOTracker struct {
+	config SLOConfig
+
+	mu          sync.Mutex
+	samples     []fetchSample
+	queueOldest time.Time
+
+	successRatio *prometheus.Desc
+	latencyP95   *prometheus.Desc
+	queueAge     *prometheus.Desc
+	burnRate     *prometheus.Desc
+	requests     *prometheus.Desc
+	threshold    *prometheus.Desc
+}
+
+// NewSLOTracker creates a new SLO tracker
+func NewSLOTracker(config SLOConfig) *SLOTracker {
+	if config.Window <= 0 {
+		config.Window = 5 * time.Minute
+	}
+	if config.MaxSamples <= 0 {
+		config.MaxSamples = 10000
+	}
+	if config.SuccessRatioTarget <= 0 || config.SuccessRatioTarget >= 1 {
+		config.SuccessRatioTarget = 0.99
+	}
+	if config.FetchLatencyP95 <= 0 {
+		config.FetchLatencyP95 = 5 * time.Second
+	}
+	if config.QueueAge <= 0 {
+		config.QueueAge = 10 * time.Minute
+	}
+	if config.BurnRateAlert <= 0 {
+		config.BurnRateAlert = 14.4
+	}
+	if config.AlertFor <= 0 {
+		config.AlertFor = 5 * time.Minute
+	}
+	config.Clock = clock.Or(config.Clock)
+
+	return &SLOTracker{
+		config: config,
+		successRatio: prometheus.NewDesc("golwarc_slo_crawl_success_ratio",
+			"Share of successful crawls in the SLO window", nil, nil),
+		latencyP95: prometheus.NewDesc("golwarc_slo_fetch_latency_p95_seconds",
+			"95th percentile fetch latency in the SLO window", nil, nil),
+		queueAge: prometheus.NewDesc("golwarc_slo_queue_age_seconds",
+			"Age of the oldest queued URL", nil, nil),
+		burnRate: prometheus.NewDesc("golwarc_slo_error_budget_burn_rate",
+			"Crawl error rate divided by the error budget (1 - success target)", nil, nil),
+		requests: prometheus.NewDesc("golwarc_slo_window_requests",
+			"Crawls observed in the SLO window", nil, nil),
+		threshold: prometheus.NewDesc("golwarc_slo_threshold",
+			"Configured SLO alert thresholds", []string{"slo"}, nil),
+	}
+}
+
+// Config returns the tracker configuration with defaults applied
+func (t *SLOTracker) Config() SLOConfig {
+	return t.config
+}
+
+// RecordFetch records the outcome of a single crawl
+func (t *SLOTracker) RecordFetch(duration time.Duration, success bool) {
+	now := t.config.Clock.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.samples = append(t.samples, fetchSample{at: now, duration: duration, ok: success})
+	t.pruneLocked(now)
+}
+
+// SetQueueOldest records when the oldest queued URL was enqueued
+// A zero time means the queue is empty
+func (t *SLOTracker) SetQueueOldest(enqueuedAt time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.queueOldest = enqueuedAt
+}
+
+// pruneLocked drops samples outside the window or beyond MaxSamples
+func (t *SLOTracker) pruneLocked(now time.Time) {
+	cutoff := now.Add(-t.config.Window)
+	drop := sort.Search(len(t.samples), func(i int) bool { return t.samples[i].at.After(cutoff) })
+	if excess := len(t.samples) - drop - t.config.MaxSamples; excess > 0 {
+		drop += excess
+	}
+	if drop > 0 {
+		t.samples = append(t.samples[:0], t.samples[drop:]...)
+	}
+}
+
+// Snapshot computes the SLO values for the current window
+func (t *SLOTracker) Snapshot() SLOSnapshot {
+	now := t.config.Clock.Now()
+
+	t.mu.Lock()
+	t.pruneLocked(now)
+	durations := make([]time.Duration, len(t.samples))
+	failures := 0
+	for i, s := range t.samples {
+		durations[i] = s.duration
+		if !s.ok {
+			failures++
+		}
+	}
+	oldest := t.queueOldest
+	t.mu.Unlock()
+
+	snap := SLOSnapshot{Requests: len(durations), SuccessRatio: 1}
+	if !oldest.IsZero() {
+		snap.QueueAge = now.Sub(oldest)
+	}
+	if len(durations) == 0 {
+		return snap
+	}
+
+	snap.SuccessRatio = float64(len(durations)-failures) / float64(len(durations))
+	snap.BurnRate = (1 - snap.SuccessRatio) / (1 - t.config.SuccessRatioTarget)
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	rank := int(math.Ceil(0.95*float64(len(durations)))) - 1
+	snap.FetchLatencyP95 = durations[rank]
+	return snap
+}
+
+// Describe implements prometheus.Collector
+func (t *SLOTracker) Describe(ch chan<- *prometheus.Desc) {
+	ch <- t.successRatio
+	ch <- t.latencyP95
+	ch <- t.queueAge
+	ch <- t.burnRate
+	ch <- t.requests
+	ch <- t.threshold
+}
+
+// Collect implements prometheus.Collector
+func (t *SLOTracker) Collect(ch chan<- prometheus.Metric) {
+	snap := t.Snapshot()
+
+	ch <- prometheus.MustNewConstMetric(t.successRatio, prometheus.GaugeValue, snap.SuccessRatio)
+	ch <- prometheus.MustNewConstMetric(t.latencyP95, prometheus.GaugeValue, snap.FetchLatencyP95.Seconds())
+	ch <- prometheus.MustNewConstMetric(t.queueAge, prometheus.GaugeValue, snap.QueueAge.Seconds())
+	ch <- prometheus.MustNewConstMetric(t.burnRate, prometheus.GaugeValue, snap.BurnRate)
+	ch <- prometheus.MustNewConstMetric(t.requests, prometheus.GaugeValue, float64(snap.Requests))
+
+	ch <- prometheus.MustNewConstMetric(t.threshold, prometheus.GaugeValue, t.config.SuccessRatioTarget, "success_ratio")
+	ch <- prometheus.MustNewConstMetric(t.threshold, prometheus.GaugeValue, t.config.FetchLatencyP95.Seconds(), "fetch_latency_p95_seconds")
+	ch <- prometheus.MustNewConstMetric(t.threshold, prometheus.GaugeValue, t.config.QueueAge.Seconds(), "queue_age_seconds")
+	ch <- prometheus.MustNewConstMetric(t.threshold, prometheus.GaugeValue, t.config.BurnRateAlert, "burn_rate")
+}
+
+// AlertRule is a Prometheus alerting rule
+type AlertRule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty"`
+}
+
+// AlertRuleGroup is a named group of alerting rules
+type AlertRuleGroup struct {
+	Name  string      `yaml:"name"`
+	Rules []AlertRule `yaml:"rules"`
+}
+
+// AlertRules returns example alerts for the tracker's thresholds
+func (t *SLOTracker) AlertRules() AlertRuleGroup {
+	c := t.config
+	forDuration := prometheusDuration(c.AlertFor)
+
+	return AlertRuleGroup{
+		Name: "golwarc-slo",
+		Rules: []AlertRule{
+			{
+				Alert:       "GolwarcCrawlSuccessRatioLow",
+				Expr:        fmt.Sprintf("golwarc_slo_crawl_success_ratio < %g and golwarc_slo_window_requests > 0", c.SuccessRatioTarget),
+				For:         forDuration,
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": fmt.Sprintf("Crawl success ratio is below %g", c.SuccessRatioTarget)},
+			},
+			{
+				Alert:       "GolwarcErrorBudgetBurn",
+				Expr:        fmt.Sprintf("golwarc_slo_error_budget_burn_rate > %g", c.BurnRateAlert),
+				For:         forDuration,
+				Labels:      map[string]string{"severity": "critical"},
+				Annotations: map[string]string{"summary": fmt.Sprintf("Crawl error budget is burning more than %gx too fast", c.BurnRateAlert)},
+			},
+			{
+				Alert:       "GolwarcFetchLatencyHigh",
+				Expr:        fmt.Sprintf("golwarc_slo_fetch_latency_p95_seconds > %g", c.FetchLatencyP95.Seconds()),
+				For:         forDuration,
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": fmt.Sprintf("p95 fetch latency is above %s", c.FetchLatencyP95)},
+			},
+			{
+				Alert:       "GolwarcQueueStale",
+				Expr:        fmt.Sprintf("golwarc_slo_queue_age_seconds > %g", c.QueueAge.Seconds()),
+				For:         forDuration,
+				Labels:      map[string]string{"severity": "warning"},
+				Annotations: map[string]string{"summary": fmt.Sprintf("Oldest queued URL has waited more than %s", c.QueueAge)},
+			},
+		},
+	}
+}
+
+// WriteAlertRules writes groups as a Prometheus rules file
+func WriteAlertRules(w io.Writer, groups ...AlertRuleGroup) error {
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(struct {
+		Groups []AlertRuleGroup `yaml:"groups"`
+	}{groups}); err != nil {
+		return fmt.Errorf("failed to encode alert rules: %w", err)
+	}
+	return enc.Close()
+}
+
+// prometheusDuration formats d in Prometheus duration syntax (e.g. 5m, 90s)
+func prometheusDuration(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	default:
+		return fmt.Sprintf("%ds", int64(math.Ceil(d.Seconds())))
+	}
+}
@@ -1,16 +1,17 @@
 package libs
 
 import (
-	"errors"
 	"fmt"
 	"net"
 	"net/url"
 	"strings"
+
+	"github.com/alonecandies/golwarc/errs"
 )
 
 // Validator provides input validation functionality
 type Validator struct {
-	// Additional validator configuration can be added here
+	policy ValidationPolicy
 }
 
 // NewValidator creates a new validator instance
@@ -18,6 +19,95 @@ func NewValidator() *Validator {
 	return &Validator{}
 }
 
+// NewValidatorWithPolicy creates a validator whose URL checks follow policy
+func NewValidatorWithPolicy(policy ValidationPolicy) (*Validator, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return &Validator{policy: policy}, nil
+}
+
+// ValidationPolicy relaxes or tightens the SSRF checks of URL validation, for
+// example to crawl an intranet. The zero value blocks localhost, private,
+// link-local and multicast addresses
+type ValidationPolicy struct {
+	// AllowPrivate allows localhost, loopback, private and link-local addresses
+	AllowPrivate bool
+
+	// AllowedHosts are trusted whatever they resolve to and are not resolved
+	// Entries are hostnames, "*.example.com" wildcards for subdomains, IPs or CIDRs
+	AllowedHosts []string
+
+	// BlockedCIDRs are blocked in addition to the default ranges, even when
+	// AllowPrivate is set; entries are CIDRs or single IPs
+	BlockedCIDRs []string
+}
+
+// Validate checks that the IP and CIDR entries of the policy parse
+func (p ValidationPolicy) Validate() error {
+	for _, entry := range p.AllowedHosts {
+		if strings.Contains(entry, "/") && parseNetwork(entry) == nil {
+			return errs.Newf(errs.CodeInvalidConfig, "invalid allowed host CIDR: %s", entry)
+		}
+	}
+	for _, entry := range p.BlockedCIDRs {
+		if parseNetwork(entry) == nil {
+			return errs.Newf(errs.CodeInvalidConfig, "invalid blocked CIDR: %s", entry)
+		}
+	}
+	return nil
+}
+
+// AllowsHost reports whether host is on the allowlist
+func (p ValidationPolicy) AllowsHost(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	ip := net.ParseIP(host)
+
+	for _, entry := range p.AllowedHosts {
+		entry = strings.TrimSuffix(strings.ToLower(entry), ".")
+		switch {
+		case strings.HasPrefix(entry, "*."):
+			if strings.HasSuffix(host, entry[1:]) {
+				return true
+			}
+		case ip != nil && parseNetwork(entry) != nil:
+			if parseNetwork(entry).Contains(ip) {
+				return true
+			}
+		case host == entry:
+			return true
+		}
+	}
+	return false
+}
+
+// Blocks reports whether ip is in one of the blocked CIDRs
+func (p ValidationPolicy) Blocks(ip net.IP) bool {
+	for _, entry := range p.BlockedCIDRs {
+		if network := parseNetwork(entry); network != nil && network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork parses a CIDR or a single IP, returning nil if it is neither
+func parseNetwork(entry string) *net.IPNet {
+	if _, network, err := net.ParseCIDR(entry); err == nil {
+		return network
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip = ip.To4()
+		bits = 8 * net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+}
+
 // ValidateURL validates a URL and checks for SSRF vulnerabilities.
 // It blocks:
 // - file:// and javascript:// schemes
@@ -25,16 +115,17 @@ func NewValidator() *Validator {
 // - private IP ranges (10.x.x.x, 192.168.x.x, 172.16-31.x.x)
 // - link-local addresses (169.254.x.x)
 //
+// The validator's ValidationPolicy can allow some of these or block more.
 // Returns an error if the URL is invalid or potentially dangerous.
 func (v *Validator) ValidateURL(rawURL string) error {
 	if rawURL == "" {
-		return errors.New("URL cannot be empty")
+		return errs.New(errs.CodeInvalidURL, "URL cannot be empty")
 	}
 
 	// Parse URL
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return fmt.Errorf("invalid URL format: %w", err)
+		return errs.Wrap(err, errs.CodeInvalidURL, "invalid URL format")
 	}
 
 	// Check scheme
@@ -60,7 +151,7 @@ func (v *Validator) validateScheme(scheme string) error {
 	}
 
 	if !allowedSchemes[scheme] {
-		return fmt.Errorf("disallowed URL scheme: %s (only http/https allowed)", scheme)
+		return errs.Newf(errs.CodeInvalidURL, "disallowed URL scheme: %s (only http/https allowed)", scheme)
 	}
 
 	return nil
@@ -69,24 +160,32 @@ func (v *Validator) validateScheme(scheme string) error {
 // validateHostname checks for SSRF vulnerabilities in the hostname
 func (v *Validator) validateHostname(hostname string) error {
 	if hostname == "" {
-		return errors.New("hostname cannot be empty")
+		return errs.New(errs.CodeInvalidURL, "hostname cannot be empty")
+	}
+
+	// Trusted hosts skip the address checks
+	if v.policy.AllowsHost(hostname) {
+		return nil
 	}
 
 	// Block localhost variations
-	if isLocalhost(hostname) {
-		return errors.New("localhost URLs are not allowed for security reasons")
+	if isLocalhost(hostname) && !v.policy.AllowPrivate {
+		return errs.New(errs.CodeInvalidURL, "localhost URLs are not allowed for security reasons")
 	}
 
 	// Resolve IP address
 	ips, err := net.LookupIP(hostname)
 	if err != nil {
 		// If DNS lookup fails, block the URL to be safe
-		return fmt.Errorf("cannot resolve hostname: %w", err)
+		return errs.Wrap(err, errs.CodeInvalidURL, "cannot resolve hostname")
 	}
 
 	// Check if any resolved IP is private or loopback
 	for _, ip := range ips {
-		if err := validateIP(ip); err != nil {
+		if v.policy.Blocks(ip) {
+			return errs.Newf(errs.CodeInvalidURL, "hostname resolves to blocked IP %s", ip)
+		}
+		if err := validateIP(ip, v.policy.AllowPrivate); err != nil {
 			return fmt.Errorf("hostname resolves to blocked IP %s: %w", ip, err)
 		}
 	}
@@ -115,25 +214,28 @@ func isLocalhost(hostname string) bool {
 }
 
 // validateIP checks if an IP address is safe (not private/loopback)
-func validateIP(ip net.IP) error {
-	// Check for loopback
-	if ip.IsLoopback() {
-		return errors.New("loopback addresses are not allowed")
-	}
+// allowPrivate skips the loopback, private and link-local checks
+func validateIP(ip net.IP, allowPrivate bool) error {
+	if !allowPrivate {
+		// Check for loopback
+		if ip.IsLoopback() {
+			return errs.New(errs.CodeInvalidURL, "loopback addresses are not allowed")
+		}
 
-	// Check for private IP ranges
-	if ip.IsPrivate() {
-		return errors.New("private IP addresses are not allowed")
-	}
+		// Check for private IP ranges
+		if ip.IsPrivate() {
+			return errs.New(errs.CodeInvalidURL, "private IP addresses are not allowed")
+		}
 
-	// Check for link-local addresses (169.254.x.x for IPv4, fe80::/10 for IPv6)
-	if ip.IsLinkLocalUnicast() {
-		return errors.New("link-local addresses are not allowed")
+		// Check for link-local addresses (169.254.x.x for IPv4, fe80::/10 for IPv6)
+		if ip.IsLinkLocalUnicast() {
+			return errs.New(errs.CodeInvalidURL, "link-local addresses are not allowed")
+		}
 	}
 
 	// Check for multicast
 	if ip.IsMulticast() {
-		return errors.New("multicast addresses are not allowed")
+		return errs.New(errs.CodeInvalidURL, "multicast addresses are not allowed")
 	}
 
 	return nil
@@ -141,60 +243,33 @@ func validateIP(ip net.IP) error {
 
 // ValidateCrawlerConfig validates crawler configuration parameters
 func (v *Validator) ValidateCrawlerConfig(userAgent string, maxDepth, concurrency int) error {
-	if userAgent == "" {
-		return errors.New("user agent cannot be empty")
-	}
-
-	if maxDepth < 1 || maxDepth > 10 {
-		return errors.New("max depth must be between 1 and 10")
-	}
-
-	if concurrency < 1 || concurrency > 100 {
-		return errors.New("concurrency must be between 1 and 100")
-	}
-
-	return nil
+	return v.ValidateStruct(struct {
+		UserAgent   string `mapstructure:"user_agent" validate:"required"`
+		MaxDepth    int    `mapstructure:"max_depth" validate:"min=1,max=10"`
+		Concurrency int    `mapstructure:"concurrency" validate:"min=1,max=100"`
+	}{userAgent, maxDepth, concurrency})
 }
 
 // ValidateTimeout validates timeout values (in seconds)
 func (v *Validator) ValidateTimeout(timeout int) error {
-	if timeout < 1 {
-		return errors.New("timeout must be at least 1 second")
-	}
-
-	if timeout > 300 {
-		return errors.New("timeout cannot exceed 300 seconds (5 minutes)")
-	}
-
-	return nil
+	return v.ValidateStruct(struct {
+		Timeout int `mapstructure:"timeout" validate:"min=1,max=300"`
+	}{timeout})
 }
 
 // ValidateDatabaseConfig validates database configuration
 func (v *Validator) ValidateDatabaseConfig(host string, port int, database string) error {
-	if host == "" {
-		return errors.New("database host cannot be empty")
-	}
-
-	if port < 1 || port > 65535 {
-		return errors.New("database port must be between 1 and 65535")
-	}
-
-	if database == "" {
-		return errors.New("database name cannot be empty")
-	}
-
-	return nil
+	return v.ValidateStruct(struct {
+		Host     string `mapstructure:"host" validate:"required"`
+		Port     int    `mapstructure:"port" validate:"min=1,max=65535"`
+		Database string `mapstructure:"database" validate:"required"`
+	}{host, port, database})
 }
 
 // ValidateCacheConfig validates cache configuration
 func (v *Validator) ValidateCacheConfig(addr string, db int) error {
-	if addr == "" {
-		return errors.New("cache address cannot be empty")
-	}
-
-	if db < 0 || db > 15 {
-		return errors.New("cache database must be between 0 and 15")
-	}
-
-	return nil
+	return v.ValidateStruct(struct {
+		Addr string `mapstructure:"addr" validate:"required"`
+		DB   int    `mapstructure:"db" validate:"min=0,max=15"`
+	}{addr, db})
 }
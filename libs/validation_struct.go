@@ -0,0 +1,205 @@
+package libs
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// FieldError is a struct field that failed a validation rule
+type FieldError struct {
+	Field   string // Path such as crawler.rate_limit.delay
+	Rule    string // Failed rule: required, min, max or oneof
+	Param   string // Rule parameter, e.g. "10" for max=10
+	Message string
+}
+
+// Error implements the error interface
+func (e *FieldError) Error() string {
+	return e.Field + " " + e.Message
+}
+
+// ValidationErrors lists every field that failed validation
+type ValidationErrors []*FieldError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ErrorCode implements errs.Coder
+func (e ValidationErrors) ErrorCode() errs.Code {
+	return errs.CodeInvalidConfig
+}
+
+// ValidateStruct validates a struct (or pointer to one) by its validate tags
+// and returns ValidationErrors listing every failing field
+//
+// Rules are comma separated:
+//   - required: the field must not be the zero value
+//   - min=N, max=N: bounds for numbers, lengths for strings, slices and maps
+//   - oneof=a b c: the value must be one of the space separated options
+//   - omitempty: skip the remaining rules when the field is the zero value
+//
+// Nested structs and slices of structs are validated recursively. Field
+// paths use mapstructure or json tag names when present
+func (v *Validator) ValidateStruct(s interface{}) error {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return errs.New(errs.CodeInvalidConfig, "cannot validate a nil pointer")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errs.Newf(errs.CodeInvalidConfig, "cannot validate %s, want a struct", rv.Kind())
+	}
+
+	var failures ValidationErrors
+	validateFields(rv, "", &failures)
+	if len(failures) > 0 {
+		return failures
+	}
+	return nil
+}
+
+// validateFields checks the tagged fields of a struct value
+func validateFields(rv reflect.Value, prefix string, failures *ValidationErrors) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+
+		path := fieldName(field)
+		if prefix != "" {
+			path = prefix + "." + path
+		}
+		value := rv.Field(i)
+
+		if tag != "" {
+			if fieldErr := checkRules(value, path, tag); fieldErr != nil {
+				*failures = append(*failures, fieldErr)
+				continue
+			}
+		}
+		validateNested(value, path, failures)
+	}
+}
+
+// validateNested descends into struct, pointer and slice values
+func validateNested(value reflect.Value, path string, failures *ValidationErrors) {
+	switch value.Kind() {
+	case reflect.Struct:
+		validateFields(value, path, failures)
+	case reflect.Pointer:
+		if !value.IsNil() && value.Elem().Kind() == reflect.Struct {
+			validateFields(value.Elem(), path, failures)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			validateNested(value.Index(i), fmt.Sprintf("%s[%d]", path, i), failures)
+		}
+	}
+}
+
+// fieldName returns the config name of a field
+func fieldName(field reflect.StructField) string {
+	for _, key := range []string{"mapstructure", "json", "yaml"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(key), ","); name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// checkRules applies a validate tag to value and returns the first failure
+func checkRules(value reflect.Value, path, tag string) *FieldError {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		fail := func(format string, args ...interface{}) *FieldError {
+			return &FieldError{Field: path, Rule: name, Param: param, Message: fmt.Sprintf(format, args...)}
+		}
+
+		switch name {
+		case "":
+		case "omitempty":
+			if value.IsZero() {
+				return nil
+			}
+		case "required":
+			if value.IsZero() {
+				return fail("is required")
+			}
+		case "min", "max":
+			bound, err := strconv.ParseFloat(param, 64)
+			if err != nil {
+				return fail("has an invalid %s rule %q", name, param)
+			}
+			size, isLength, ok := measure(value)
+			if !ok {
+				return fail("does not support the %s rule", name)
+			}
+			if name == "min" && size < bound {
+				if isLength {
+					return fail("must have a length of at least %s", param)
+				}
+				return fail("must be at least %s", param)
+			}
+			if name == "max" && size > bound {
+				if isLength {
+					return fail("must have a length of at most %s", param)
+				}
+				return fail("must be at most %s", param)
+			}
+		case "oneof":
+			options := strings.Fields(param)
+			actual := fmt.Sprint(value.Interface())
+			found := false
+			for _, option := range options {
+				if actual == option {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fail("must be one of [%s], got %q", strings.Join(options, ", "), actual)
+			}
+		default:
+			return fail("has unknown validation rule %q", name)
+		}
+	}
+	return nil
+}
+
+// measure returns the number compared by min and max: the value of numbers
+// and the length of strings, slices and maps
+func measure(value reflect.Value) (size float64, isLength bool, ok bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), true, true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(value.Len()), true, true
+	default:
+		return 0, false, false
+	}
+}
@@ -1,27 +1,80 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	stdlog "log"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
 
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/database"
 	"github.com/alonecandies/golwarc/inject"
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/alonecandies/golwarc/services"
+	"github.com/alonecandies/golwarc/storage"
+	"github.com/alonecandies/golwarc/warc"
 	"go.uber.org/zap"
 )
 
+// apiShutdownTimeout bounds how long in-flight API requests may finish
+const apiShutdownTimeout = 10 * time.Second
+
 func main() {
 	// Initialize dependency injection container
 	container, err := inject.NewContainer("config.yaml")
 	if err != nil {
 		stdlog.Fatalf("Failed to initialize container: %v", err)
 	}
+
+	// golwarc queue ... inspects and repairs the shared crawl frontier
+	if len(os.Args) > 1 && os.Args[1] == "queue" {
+		os.Exit(runQueue(container, os.Args[2:]))
+	}
+
+	// golwarc session ... saves a manual login for headless crawls
+	if len(os.Args) > 1 && os.Args[1] == "session" {
+		os.Exit(runSession(container, os.Args[2:]))
+	}
+
+	// golwarc plan ... estimates the cost of a crawl without sending requests
+	if len(os.Args) > 1 && os.Args[1] == "plan" {
+		os.Exit(runPlan(container, os.Args[2:]))
+	}
+
 	defer func() {
 		if err := container.Close(); err != nil {
 			stdlog.Printf("Warning: error closing container: %v", err)
 		}
 	}()
 
+	// Serve metrics and health probes while the crawler runs
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	stopMetrics := startMetricsServer(ctx, container)
+	defer stopMetrics()
+
+	// Run archive maintenance every storage.maintenance_interval minutes
+	stopMaintenance := startArchiveMaintenance(ctx, container)
+	defer stopMaintenance()
+
 	log := container.Logger
+
+	// The crawler service needs Redis and MySQL; the API serves crawls with it
+	var crawlerService *services.CrawlerService
+	if container.RedisClient != nil && container.MySQLClient != nil {
+		crawlerService = newCrawlerService(container)
+	}
+
+	// Serve the API, web UI and archive on api.port
+	stopAPI := startAPIServer(ctx, container, crawlerService)
+	defer stopAPI()
+
 	log.Info("==============================================")
 	log.Info("Golwarc Crawler Master - Dependency Injection Demo")
 	log.Info("==============================================")
@@ -51,8 +104,8 @@ func main() {
 	}
 
 	// Demonstrate service usage
-	if container.RedisClient != nil && container.MySQLClient != nil {
-		runCrawlerDemo(container)
+	if crawlerService != nil {
+		runCrawlerDemo(container, crawlerService)
 	} else {
 		log.Warn("Crawler demo requires Redis and MySQL to be configured")
 		log.Info("Please configure database.mysql and cache.redis in config.yaml")
@@ -61,26 +114,207 @@ func main() {
 	log.Info("==============================================")
 	log.Info("Demo completed successfully!")
 	log.Info("==============================================")
+
+	if container.Config.API.Port > 0 {
+		log.Info("Serving the API until interrupted", zap.Int("port", container.Config.API.Port))
+		<-ctx.Done()
+	}
 }
 
-func runCrawlerDemo(container *inject.Container) {
-	log := container.Logger
-	log.Info("")
-	log.Info("--- Crawler Service Demo ---")
-	log.Info("")
+// startMetricsServer serves /metrics, /livez and /readyz on app.metrics_port
+// and refreshes service health every app.health_interval until ctx is done
+// The returned function stops the server
+func startMetricsServer(ctx context.Context, container *inject.Container) func() {
+	port := container.Config.App.MetricsPort
+	if port <= 0 {
+		return func() {}
+	}
 
-	// Create crawler service with injected dependencies
+	server := libs.NewMetricsServer(port)
+	server.SetReadinessCheck(container.Readiness)
+	go container.MonitorHealth(ctx, server.Metrics, 0)
+	go func() {
+		if err := server.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			container.Logger.Error("Metrics server failed", zap.Error(err))
+		}
+	}()
+	container.Logger.Info("Metrics server started", zap.Int("port", port))
+
+	return func() {
+		if err := server.Stop(); err != nil {
+			container.Logger.Warn("Failed to stop metrics server", zap.Error(err))
+		}
+	}
+}
+
+// newCrawlerService creates the crawler service with injected dependencies
+// and migrates the database
+func newCrawlerService(container *inject.Container) *services.CrawlerService {
+	log := container.Logger
 	crawlerService := services.NewCrawlerService(
 		container.Logger,
 		container.RedisClient,
 		container.MySQLClient,
 	)
+	crawlerService.SetProject(container.Config.Crawler.Project, container.Config.Crawler.SharedCorpus)
+	crawlerService.SetBodyStore(container.BodyStore)
+	crawlerService.SetExtractors(container.Extractors)
+	crawlerService.SetStructuredData(container.Config.Crawler.StructuredData)
+	if container.Config.Crawler.FeedDiscovery {
+		crawlerService.SetFeedRegistry(services.NewDBFeedRegistry(container.MySQLClient))
+	}
+	crawlerService.SetSecurityAudit(container.Config.Crawler.SecurityAudit)
+	crawlerService.SetIgnoreRobotsMeta(container.Config.Crawler.IgnoreRobotsMeta)
+	if cfg := container.Config.Crawler.Assets; cfg.Enabled {
+		dir := cfg.Dir
+		if dir == "" {
+			dir = container.Config.Storage.ObjectDir
+		}
+		if objects, err := storage.NewFileObjectStore(dir); err != nil {
+			log.Warn("Asset downloads disabled: failed to open object store", zap.Error(err))
+		} else {
+			crawlerService.SetAssetDownloader(services.NewAssetDownloader(container.Logger, container.MySQLClient, objects, services.AssetDownloaderConfig{
+				MaxSize:    cfg.MaxSize,
+				Types:      cfg.Types,
+				MaxPerPage: cfg.MaxPerPage,
+			}))
+		}
+	}
+	if cfg := container.Config.Crawler.Certificates; cfg.Enabled {
+		crawlerService.SetCertificateMonitor(services.NewCertificateMonitor(container.Logger, container.MySQLClient, services.CertificateMonitorConfig{
+			ExpiryWarning: time.Duration(cfg.AlertDays) * 24 * time.Hour,
+			CheckEvery:    time.Duration(cfg.CheckEvery) * time.Hour,
+		}))
+	}
+	if cfg := container.Config.Crawler.SiteMetadata; cfg.Enabled {
+		crawlerService.SetSiteMetadata(services.NewSiteMetadataService(container.Logger, container.MySQLClient, services.SiteMetadataConfig{
+			RefreshEvery: time.Duration(cfg.RefreshEvery) * time.Hour,
+		}))
+	}
+	crawlerService.SetHSTS(container.HSTS)
+	crawlerService.SetCanonicalizer(container.Canonical)
+	crawlerService.SetCanonicalDedupe(container.Config.Crawler.Canonical.Dedupe)
+	crawlerService.SetDedupKey(container.Dedup)
+	if policy, err := services.NewTTLPolicyFromConfig(container.RedisClient, container.Config.Crawler.CacheTTL); err != nil {
+		container.Logger.Warn("Invalid cache TTL policy; caching pages for 24h", zap.Error(err))
+	} else {
+		crawlerService.SetTTLPolicy(policy)
+	}
+	switch container.Config.Crawler.Conditional {
+	case "cache":
+		// Pages keep their validators too, so a cache flush only costs a query
+		crawlerService.SetValidatorStore(services.NewTieredValidatorStore(
+			services.NewCacheValidatorStore(container.RedisClient, 30*24*time.Hour),
+			services.NewPageValidatorStore(container.MySQLClient),
+		))
+	case "database":
+		crawlerService.SetValidatorStore(services.NewPageValidatorStore(container.MySQLClient))
+	}
 
 	// Initialize service (migrate database)
 	log.Info("Initializing crawler service...")
 	if err := crawlerService.Initialize(); err != nil {
 		log.Fatal("Failed to initialize crawler service", zap.Error(err))
 	}
+	return crawlerService
+}
+
+// startAPIServer serves the HTTP API and web UI on api.port until ctx is done
+// Crawl submissions and the UI need crawlerService, exports need MySQL and
+// archive lookups need storage.warc_path; the other routes are always served.
+// The returned function shuts the server down gracefully
+func startAPIServer(ctx context.Context, container *inject.Container, crawlerService *services.CrawlerService) func() {
+	log := container.Logger
+	port := container.Config.API.Port
+	if port <= 0 {
+		return func() {}
+	}
+
+	server := api.NewServer(api.ServerConfig{Port: port})
+	if crawlerService != nil {
+		crawls := api.NewCrawlHandler(api.CrawlHandlerConfig{
+			Crawler: crawlerService,
+			DB:      container.Database(),
+		})
+		server.Register(crawls, api.NewUIHandler())
+		go crawls.Start(ctx)
+	}
+	if db := container.Database(); db != nil {
+		server.Register(api.NewExportHandler(db))
+	}
+	if path := container.Config.Storage.WARCPath; path != "" {
+		index := warc.NewCDXIndex(filepath.Dir(path))
+		if err := index.Load(); err != nil {
+			log.Warn("Failed to load CDX index", zap.Error(err))
+		}
+		server.Register(api.NewArchiveHandler(index))
+		go reloadCDXIndex(ctx, container, index)
+	}
+	if container.Identity != nil {
+		server.Register(api.NewBotInfoHandler(container.Identity))
+	}
+
+	go func() {
+		if err := server.Start(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("API server failed", zap.Error(err))
+		}
+	}()
+	log.Info("API server started", zap.Int("port", port))
+
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), apiShutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Warn("Failed to stop API server", zap.Error(err))
+		}
+	}
+}
+
+// reloadCDXIndex reloads the archive index every minute so lookups see new
+// captures and files moved by compaction, until ctx is done
+func reloadCDXIndex(ctx context.Context, container *inject.Container, index *warc.CDXIndex) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := index.Load(); err != nil {
+				container.Logger.Warn("Failed to reload CDX index", zap.Error(err))
+			}
+		}
+	}
+}
+
+func runCrawlerDemo(container *inject.Container, crawlerService *services.CrawlerService) {
+	log := container.Logger
+	log.Info("")
+	log.Info("--- Crawler Service Demo ---")
+	log.Info("")
+
+	// Maintain time-based partitions of crawl_logs if enabled, in the MySQL
+	// database the crawler service writes to; pages stay unpartitioned since
+	// their (project, url) key cannot include created_at
+	if cfg := container.Config.Database.Partitioning; cfg.Enabled {
+		const table = "crawl_logs"
+		partitioner := database.NewPartitioner(container.MySQLClient.GetDB(), database.PartitionConfig{
+			Table:    table,
+			Interval: cfg.Interval,
+			Ahead:    cfg.Ahead,
+		})
+		if err := partitioner.EnsurePartitions(time.Now()); err != nil {
+			log.Warn("Failed to ensure partitions", zap.String("table", table), zap.Error(err))
+		} else if cfg.Retention > 0 {
+			cutoff := database.RetentionCutoff(time.Now(), cfg.Interval, cfg.Retention)
+			if dropped, err := partitioner.DropPartitionsBefore(cutoff); err != nil {
+				log.Warn("Failed to drop old partitions", zap.String("table", table), zap.Error(err))
+			} else if len(dropped) > 0 {
+				log.Info("Dropped old partitions", zap.String("table", table), zap.Strings("partitions", dropped))
+			}
+		}
+	}
 
 	// Crawl example URLs
 	urls := []string{
@@ -88,10 +322,10 @@ func runCrawlerDemo(container *inject.Container) {
 		"https://example.org",
 	}
 
-	for _, url := range urls {
-		log.Info("Crawling URL...", zap.String("url", url))
-		if err := crawlerService.CrawlAndStore(url); err != nil {
-			log.Error("Failed to crawl URL", zap.String("url", url), zap.Error(err))
+	log.Info("Crawling URLs...", zap.Strings("urls", urls))
+	for _, result := range crawlerService.CrawlAndStoreBatch(context.Background(), urls) {
+		if result.Err != nil {
+			log.Error("Failed to crawl URL", zap.String("url", result.URL), zap.String("stage", result.Stage()), zap.Error(result.Err))
 		}
 	}
 
@@ -118,6 +352,79 @@ func runCrawlerDemo(container *inject.Container) {
 		}
 	}
 
+	// Learn which query parameters change content if configured
+	if container.Config.Crawler.QueryLearning.Enabled {
+		runQueryLearning(container)
+	}
+
 	log.Info("")
 	log.Info("--- Crawler Service Demo Complete ---")
 }
+
+func runQueryLearning(container *inject.Container) {
+	log := container.Logger
+	cfg := container.Config.Crawler.QueryLearning
+
+	learner := services.NewQueryLearningService(container.Logger, container.MySQLClient, services.QueryLearningConfig{
+		SamplesPerParam: cfg.SamplesPerParam,
+		MinSamples:      cfg.MinSamples,
+		MaxURLs:         cfg.MaxURLs,
+		Canonical:       container.Canonical,
+	})
+	reports, err := learner.Run(context.Background(), container.Config.Crawler.Project)
+	if err != nil {
+		log.Error("Query parameter learning failed", zap.Error(err))
+		return
+	}
+	log.Info(fmt.Sprintf("Learned query parameters for %d hosts", len(reports)))
+}
+
+// startArchiveMaintenance runs a maintenance pass now and then every
+// storage.maintenance_interval minutes until ctx is done
+// The returned function stops the schedule and waits for a running pass
+func startArchiveMaintenance(ctx context.Context, container *inject.Container) func() {
+	log := container.Logger
+	cfg := container.Config.Storage
+	if cfg.MaintenanceInterval <= 0 {
+		return func() {}
+	}
+
+	lifecycle := storage.LifecycleConfig{
+		CompactBelow: cfg.CompactBelow,
+		ColdAfter:    time.Duration(cfg.ColdAfterDays) * 24 * time.Hour,
+	}
+	if cfg.WARCPath != "" {
+		lifecycle.WARCDir = filepath.Dir(cfg.WARCPath)
+	}
+	if container.WARCWriter != nil {
+		// Files the writer has rotated away from are closed and can be compacted
+		lifecycle.Writers = []*warc.Writer{container.WARCWriter}
+	}
+	if cfg.ObjectDir != "" {
+		objects, err := storage.NewFileObjectStore(cfg.ObjectDir)
+		if err != nil {
+			log.Warn("Failed to open object store", zap.Error(err))
+		} else {
+			lifecycle.Objects = objects
+		}
+	}
+
+	archiveService := services.NewArchiveService(container.Logger, container.Database(), lifecycle)
+	archiveService.SetDigestStore(container.WARCDigests)
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := archiveService.RunMaintenance(); err != nil {
+			log.Error("Archive maintenance failed", zap.Error(err))
+		}
+		archiveService.Start(ctx, time.Duration(cfg.MaintenanceInterval)*time.Minute)
+	}()
+	log.Info("Archive maintenance scheduled", zap.Int("interval_minutes", cfg.MaintenanceInterval))
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
@@ -0,0 +1,148 @@
+package messagequeue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BackPressure reports whether a downstream sink wants consumption paused
+type BackPressure interface {
+	Overloaded() bool
+}
+
+// BackPressureFunc adapts a function to BackPressure
+type BackPressureFunc func() bool
+
+// Overloaded calls f
+func (f BackPressureFunc) Overloaded() bool {
+	return f()
+}
+
+// PressureGauge counts work a sink has accepted but not finished
+// It reports overload once pending work reaches the high watermark and keeps
+// doing so until it drains to the low watermark, so consumption does not
+// flap between paused and running
+type PressureGauge struct {
+	mu         sync.Mutex
+	pending    int
+	high       int
+	low        int
+	overloaded bool
+}
+
+// NewPressureGauge creates a gauge with the given watermarks
+// A low watermark outside [0, high) defaults to half of high
+func NewPressureGauge(high, low int) *PressureGauge {
+	if high <= 0 {
+		high = 1
+	}
+	if low < 0 || low >= high {
+		low = high / 2
+	}
+	return &PressureGauge{high: high, low: low}
+}
+
+// Add records n items of accepted work
+func (g *PressureGauge) Add(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pending += n
+	if g.pending >= g.high {
+		g.overloaded = true
+	}
+}
+
+// Done records n items of finished work
+func (g *PressureGauge) Done(n int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.pending -= n
+	if g.pending < 0 {
+		g.pending = 0
+	}
+	if g.pending <= g.low {
+		g.overloaded = false
+	}
+}
+
+// Pending returns the amount of unfinished work
+func (g *PressureGauge) Pending() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.pending
+}
+
+// Overloaded implements BackPressure
+func (g *PressureGauge) Overloaded() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.overloaded
+}
+
+// Pause stops Consume from fetching further messages until Resume is called
+// The message being handled finishes; group membership is kept, so partitions
+// are not rebalanced away while paused
+func (c *KafkaConsumer) Pause() {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+	if !c.paused {
+		c.paused = true
+		c.resumed = make(chan struct{})
+	}
+}
+
+// Resume lets a paused Consume fetch again
+func (c *KafkaConsumer) Resume() {
+	c.pauseMu.Lock()
+	defer c.pauseMu.Unlock()
+	if c.paused {
+		c.paused = false
+		close(c.resumed)
+	}
+}
+
+// Paused reports whether Consume is held back, by Pause or by back-pressure
+func (c *KafkaConsumer) Paused() bool {
+	c.pauseMu.Lock()
+	paused := c.paused
+	c.pauseMu.Unlock()
+	return paused || (c.backPressure != nil && c.backPressure.Overloaded())
+}
+
+// waitWhilePaused blocks while the consumer is paused or its sink is overloaded
+// An overloaded sink is polled every pressure interval
+func (c *KafkaConsumer) waitWhilePaused(ctx context.Context) error {
+	for {
+		c.pauseMu.Lock()
+		paused, resumed := c.paused, c.resumed
+		c.pauseMu.Unlock()
+		overloaded := c.backPressure != nil && c.backPressure.Overloaded()
+		if !paused && !overloaded {
+			return nil
+		}
+
+		var recheck <-chan time.Time
+		var timer *time.Timer
+		if overloaded {
+			timer = time.NewTimer(c.pressureInterval)
+			recheck = timer.C
+		}
+		if !paused {
+			resumed = nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return ctx.Err()
+		case <-resumed:
+		case <-recheck:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package messagequeue
+
+import "context"
+
+// FaultInjector delays or fails calls to the broker for resilience testing;
+// *chaos.Injector implements it. Inject runs before each publish and each
+// consumed message and must return nil to let the call through
+type FaultInjector interface {
+	Inject(ctx context.Context) error
+}
+
+// injectFault calls f, if set
+func injectFault(ctx context.Context, f FaultInjector) error {
+	if f == nil {
+		return nil
+	}
+	return f.Inject(ctx)
+}
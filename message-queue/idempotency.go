@@ -0,0 +1,150 @@
+package messagequeue
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/segmentio/kafka-go"
+)
+
+// HeaderMessageID carries a producer-assigned message ID used for deduplication
+const HeaderMessageID = "X-Message-ID"
+
+// Dedup markers stored under a message ID
+const (
+	dedupProcessing = "processing"
+	dedupDone       = "done"
+)
+
+// DedupStore records processed message IDs; *cache.RedisClient implements it
+type DedupStore interface {
+	Get(key string) (string, error)
+	Set(key string, value interface{}, ttl time.Duration) error
+	SetNX(key string, value interface{}, ttl time.Duration) (bool, error)
+	Delete(key string) error
+}
+
+// IdempotencyConfig holds consumer deduplication settings
+type IdempotencyConfig struct {
+	Store   DedupStore
+	Prefix  string        // Key prefix of message IDs (default: "mq:dedup:")
+	TTL     time.Duration // How long processed IDs are remembered (default: 24 hours)
+	LockTTL time.Duration // How long a claim lasts while a handler runs (default: 5 minutes)
+}
+
+// IdempotencyStats reports deduplication activity
+type IdempotencyStats struct {
+	Processed  int64
+	Duplicates int64 // Messages skipped because their ID was already processed
+}
+
+// Idempotency wraps queue handlers so that a message delivered more than
+// once is only handled once
+//
+// A handler run claims the message ID; the ID is marked done when the
+// handler succeeds and released when it fails, so a redelivery retries it.
+// A message whose ID is claimed but not done is rejected with
+// errs.CodeMessageInFlight rather than skipped, since the other run may
+// still fail
+type Idempotency struct {
+	config     IdempotencyConfig
+	processed  atomic.Int64
+	duplicates atomic.Int64
+}
+
+// NewIdempotency creates a deduplicating handler wrapper
+func NewIdempotency(config IdempotencyConfig) *Idempotency {
+	if config.Prefix == "" {
+		config.Prefix = "mq:dedup:"
+	}
+	if config.TTL <= 0 {
+		config.TTL = 24 * time.Hour
+	}
+	if config.LockTTL <= 0 {
+		config.LockTTL = 5 * time.Minute
+	}
+	return &Idempotency{config: config}
+}
+
+// Do runs fn unless the message ID was already processed
+// An empty ID cannot be deduplicated, so fn always runs
+func (i *Idempotency) Do(id string, fn func() error) error {
+	if id == "" {
+		return fn()
+	}
+	key := i.config.Prefix + id
+
+	claimed, err := i.config.Store.SetNX(key, dedupProcessing, i.config.LockTTL)
+	if err != nil {
+		return fmt.Errorf("failed to claim message %s: %w", id, err)
+	}
+	if !claimed {
+		if state, err := i.config.Store.Get(key); err == nil && state == dedupDone {
+			i.duplicates.Add(1)
+			return nil
+		}
+		return errs.Newf(errs.CodeMessageInFlight, "message %s is being processed elsewhere", id)
+	}
+
+	if err := fn(); err != nil {
+		_ = i.config.Store.Delete(key) // Best effort cleanup; the claim expires anyway
+		return err
+	}
+	i.processed.Add(1)
+
+	if err := i.config.Store.Set(key, dedupDone, i.config.TTL); err != nil {
+		return fmt.Errorf("failed to mark message %s processed: %w", id, err)
+	}
+	return nil
+}
+
+// KafkaHandler wraps a KafkaConsumer.Consume handler; nil id uses KafkaMessageID
+func (i *Idempotency) KafkaHandler(id func(msg kafka.Message) string, handler func(msg kafka.Message) error) func(msg kafka.Message) error {
+	if id == nil {
+		id = KafkaMessageID
+	}
+	return func(msg kafka.Message) error {
+		return i.Do(id(msg), func() error { return handler(msg) })
+	}
+}
+
+// Handler wraps a RabbitMQClient.Consume handler; nil id uses ContentMessageID
+func (i *Idempotency) Handler(id func(body []byte) string, handler func(body []byte) error) func(body []byte) error {
+	if id == nil {
+		id = ContentMessageID
+	}
+	return func(body []byte) error {
+		return i.Do(id(body), func() error { return handler(body) })
+	}
+}
+
+// Stats returns a snapshot of deduplication activity
+func (i *Idempotency) Stats() IdempotencyStats {
+	return IdempotencyStats{
+		Processed:  i.processed.Load(),
+		Duplicates: i.duplicates.Load(),
+	}
+}
+
+// KafkaMessageID returns the message's X-Message-ID header, or its topic,
+// partition and offset, which identify redeliveries of the same record
+func KafkaMessageID(msg kafka.Message) string {
+	for _, header := range msg.Headers {
+		if header.Key == HeaderMessageID && len(header.Value) > 0 {
+			return string(header.Value)
+		}
+	}
+	return msg.Topic + "/" + strconv.Itoa(msg.Partition) + "/" + strconv.FormatInt(msg.Offset, 10)
+}
+
+// ContentMessageID returns a SHA-256 digest of body, so identical bodies
+// count as the same message
+func ContentMessageID(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
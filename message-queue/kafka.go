@@ -3,25 +3,45 @@ package messagequeue
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
+	"github.com/alonecandies/golwarc/storage"
 	"github.com/segmentio/kafka-go"
 )
 
 // KafkaProducer wraps Kafka producer operations
 type KafkaProducer struct {
-	writer *kafka.Writer
+	writer  *kafka.Writer
+	payload PayloadConfig
+	faults  FaultInjector
 }
 
 // KafkaConsumer wraps Kafka consumer operations
 type KafkaConsumer struct {
-	reader *kafka.Reader
+	reader  *kafka.Reader
+	offload storage.ObjectStore
+	faults  FaultInjector
+
+	backPressure     BackPressure
+	pressureInterval time.Duration
+
+	pauseMu sync.Mutex
+	paused  bool
+	resumed chan struct{} // Closed by Resume
 }
 
 // KafkaProducerConfig holds Kafka producer configuration
 type KafkaProducerConfig struct {
 	Brokers []string
 	Topic   string
+	Payload PayloadConfig // Compression and size guard for message values
+
+	// Partitioning; events sharing a partition key stay in order
+	PartitionBy  string           // domain (default), key or least_bytes
+	PartitionKey PartitionKeyFunc // Custom partition key; overrides PartitionBy
+
+	Faults FaultInjector // Injects latency and errors into produce calls for resilience testing
 }
 
 // KafkaConsumerConfig holds Kafka consumer configuration
@@ -29,6 +49,14 @@ type KafkaConsumerConfig struct {
 	Brokers []string
 	Topic   string
 	GroupID string
+	Offload storage.ObjectStore // Resolves values offloaded by producers
+
+	// Consume stops fetching while BackPressure reports overload, so a slow
+	// sink holds messages in Kafka instead of in memory
+	BackPressure     BackPressure
+	PressureInterval time.Duration // How often an overloaded sink is polled (default 1s)
+
+	Faults FaultInjector // Injects latency and errors into fetches for resilience testing
 }
 
 // NewKafkaProducer creates a new Kafka producer
@@ -36,13 +64,15 @@ func NewKafkaProducer(config KafkaProducerConfig) *KafkaProducer {
 	writer := &kafka.Writer{
 		Addr:         kafka.TCP(config.Brokers...),
 		Topic:        config.Topic,
-		Balancer:     &kafka.LeastBytes{},
+		Balancer:     newBalancer(config),
 		WriteTimeout: 10 * time.Second,
 		ReadTimeout:  10 * time.Second,
 	}
 
 	return &KafkaProducer{
-		writer: writer,
+		writer:  writer,
+		payload: config.Payload,
+		faults:  config.Faults,
 	}
 }
 
@@ -57,8 +87,16 @@ func NewKafkaConsumer(config KafkaConsumerConfig) *KafkaConsumer {
 		CommitInterval: time.Second,
 	})
 
+	if config.PressureInterval <= 0 {
+		config.PressureInterval = time.Second
+	}
+
 	return &KafkaConsumer{
-		reader: reader,
+		reader:           reader,
+		offload:          config.Offload,
+		faults:           config.Faults,
+		backPressure:     config.BackPressure,
+		pressureInterval: config.PressureInterval,
 	}
 }
 
@@ -69,6 +107,12 @@ func (p *KafkaProducer) Produce(ctx context.Context, key, value []byte) error {
 		Value: value,
 		Time:  time.Now(),
 	}
+	if err := p.encodeMessage(&msg); err != nil {
+		return err
+	}
+	if err := injectFault(ctx, p.faults); err != nil {
+		return err
+	}
 
 	return p.writer.WriteMessages(ctx, msg)
 }
@@ -89,13 +133,46 @@ func (p *KafkaProducer) ProduceWithHeaders(ctx context.Context, key, value []byt
 		Headers: kafkaHeaders,
 		Time:    time.Now(),
 	}
+	if err := p.encodeMessage(&msg); err != nil {
+		return err
+	}
+	if err := injectFault(ctx, p.faults); err != nil {
+		return err
+	}
 
 	return p.writer.WriteMessages(ctx, msg)
 }
 
 // ProduceBatch sends multiple messages to Kafka
 func (p *KafkaProducer) ProduceBatch(ctx context.Context, messages []kafka.Message) error {
-	return p.writer.WriteMessages(ctx, messages...)
+	encoded := make([]kafka.Message, len(messages))
+	for i, msg := range messages {
+		msg.Headers = append([]kafka.Header(nil), msg.Headers...)
+		if err := p.encodeMessage(&msg); err != nil {
+			return fmt.Errorf("message %d: %w", i, err)
+		}
+		encoded[i] = msg
+	}
+	if err := injectFault(ctx, p.faults); err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, encoded...)
+}
+
+// encodeMessage compresses or offloads the message value and records how in its headers
+func (p *KafkaProducer) encodeMessage(msg *kafka.Message) error {
+	payload, err := p.payload.Encode(msg.Value)
+	if err != nil {
+		return err
+	}
+	msg.Value = payload.Body
+	if payload.Encoding != "" {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: HeaderContentEncoding, Value: []byte(payload.Encoding)})
+	}
+	if payload.Ref != "" {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: HeaderPayloadRef, Value: []byte(payload.Ref)})
+	}
+	return nil
 }
 
 // Close closes the Kafka producer
@@ -104,13 +181,18 @@ func (p *KafkaProducer) Close() error {
 }
 
 // Consume reads messages from Kafka and processes them with the handler
+// It waits before each fetch while the consumer is paused or overloaded
 func (c *KafkaConsumer) Consume(ctx context.Context, handler func(msg kafka.Message) error) error {
 	for {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		default:
-			msg, err := c.reader.FetchMessage(ctx)
+			if err := c.waitWhilePaused(ctx); err != nil {
+				return err
+			}
+
+			msg, err := c.FetchMessage(ctx)
 			if err != nil {
 				return fmt.Errorf("failed to fetch message: %w", err)
 			}
@@ -129,12 +211,55 @@ func (c *KafkaConsumer) Consume(ctx context.Context, handler func(msg kafka.Mess
 
 // ReadMessage reads a single message from Kafka
 func (c *KafkaConsumer) ReadMessage(ctx context.Context) (kafka.Message, error) {
-	return c.reader.ReadMessage(ctx)
+	if err := injectFault(ctx, c.faults); err != nil {
+		return kafka.Message{}, err
+	}
+	msg, err := c.reader.ReadMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	return msg, c.decodeMessage(&msg)
 }
 
 // FetchMessage fetches a message without committing
 func (c *KafkaConsumer) FetchMessage(ctx context.Context) (kafka.Message, error) {
-	return c.reader.FetchMessage(ctx)
+	if err := injectFault(ctx, c.faults); err != nil {
+		return kafka.Message{}, err
+	}
+	msg, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return msg, err
+	}
+	return msg, c.decodeMessage(&msg)
+}
+
+// decodeMessage restores a value encoded by a producer's PayloadConfig and
+// drops the encoding headers. On error the message is returned as read so it
+// can still be committed or inspected
+func (c *KafkaConsumer) decodeMessage(msg *kafka.Message) error {
+	var encoding, ref string
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for _, header := range msg.Headers {
+		switch header.Key {
+		case HeaderContentEncoding:
+			encoding = string(header.Value)
+		case HeaderPayloadRef:
+			ref = string(header.Value)
+		default:
+			headers = append(headers, header)
+		}
+	}
+	if encoding == "" && ref == "" {
+		return nil
+	}
+
+	value, err := DecodePayload(msg.Value, encoding, ref, c.offload)
+	if err != nil {
+		return err
+	}
+	msg.Value = value
+	msg.Headers = headers
+	return nil
 }
 
 // CommitMessages commits messages
@@ -0,0 +1,73 @@
+package messagequeue
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Partitioning strategies for KafkaProducerConfig.PartitionBy
+const (
+	PartitionByDomain   = "domain"      // Hash the host of the URL in the message key
+	PartitionByKey      = "key"         // Hash the whole message key
+	PartitionLeastBytes = "least_bytes" // Ignore keys and balance by load; no ordering
+)
+
+// PartitionKeyFunc returns the bytes hashed to choose a message's partition
+// Messages with the same partition key keep their order; nil spreads the
+// message round robin
+type PartitionKeyFunc func(msg kafka.Message) []byte
+
+// DomainPartitionKey returns the lowercased host of a URL message key, so
+// every event for a domain lands on one partition. A key that is not a URL
+// is used as the domain itself
+func DomainPartitionKey(msg kafka.Message) []byte {
+	if len(msg.Key) == 0 {
+		return nil
+	}
+	key := string(msg.Key)
+	if u, err := url.Parse(key); err == nil && u.Host != "" {
+		key = u.Hostname()
+	}
+	return []byte(strings.ToLower(key))
+}
+
+// MessagePartitionKey returns the message key unchanged
+func MessagePartitionKey(msg kafka.Message) []byte {
+	return msg.Key
+}
+
+// KeyBalancer hashes a derived partition key, leaving the message key as is
+type KeyBalancer struct {
+	Key  PartitionKeyFunc
+	hash kafka.Hash
+}
+
+// Balance implements kafka.Balancer
+func (b *KeyBalancer) Balance(msg kafka.Message, partitions ...int) int {
+	msg.Key = b.Key(msg)
+	return b.hash.Balance(msg, partitions...)
+}
+
+// newBalancer picks the writer balancer for a producer configuration
+func newBalancer(config KafkaProducerConfig) kafka.Balancer {
+	if config.PartitionKey != nil {
+		return &KeyBalancer{Key: config.PartitionKey}
+	}
+	switch config.PartitionBy {
+	case "", PartitionByDomain:
+		return &KeyBalancer{Key: DomainPartitionKey}
+	case PartitionByKey:
+		return &KeyBalancer{Key: MessagePartitionKey}
+	case PartitionLeastBytes:
+		return &kafka.LeastBytes{}
+	default:
+		fmt.Printf("warning: unknown Kafka partition strategy %q, partitioning by domain\n", config.PartitionBy)
+		return &KeyBalancer{Key: DomainPartitionKey}
+	}
+}
+
+// Ensure KeyBalancer implements the kafka.Balancer interface
+var _ kafka.Balancer = (*KeyBalancer)(nil)
@@ -0,0 +1,145 @@
+package messagequeue
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/storage"
+)
+
+// Message metadata describing how a payload was encoded
+// RabbitMQ carries the encoding in the ContentEncoding property instead of a header
+const (
+	HeaderContentEncoding = "Content-Encoding"
+	HeaderPayloadRef      = "X-Payload-Ref"
+)
+
+// EncodingGzip marks gzip-compressed payloads
+const EncodingGzip = "gzip"
+
+// PayloadConfig controls compression and offloading of large payloads
+type PayloadConfig struct {
+	CompressAbove  int                 // Gzip payloads larger than this many bytes; 0 disables compression
+	MaxMessageSize int                 // Payloads still larger are offloaded; 0 disables the guard
+	Offload        storage.ObjectStore // Receives oversized payloads; without it they are rejected
+	OffloadPrefix  string              // Key prefix of offloaded payloads (default: "mq-payloads/")
+}
+
+// PayloadRef is the message body published in place of an offloaded payload
+type PayloadRef struct {
+	Ref  string `json:"payload_ref"`
+	Size int    `json:"size"`
+}
+
+// EncodedPayload is a payload ready to publish with its metadata
+type EncodedPayload struct {
+	Body     []byte
+	Encoding string // EncodingGzip or empty
+	Ref      string // Object key when the payload was offloaded
+}
+
+// Encode compresses value when it is large and offloads it when it is still
+// larger than the maximum message size
+func (c PayloadConfig) Encode(value []byte) (EncodedPayload, error) {
+	payload := EncodedPayload{Body: value}
+
+	if c.CompressAbove > 0 && len(value) > c.CompressAbove {
+		compressed, err := gzipBytes(value)
+		if err != nil {
+			return EncodedPayload{}, fmt.Errorf("failed to compress payload: %w", err)
+		}
+		if len(compressed) < len(value) {
+			payload.Body = compressed
+			payload.Encoding = EncodingGzip
+		}
+	}
+
+	if c.MaxMessageSize <= 0 || len(payload.Body) <= c.MaxMessageSize {
+		return payload, nil
+	}
+	if c.Offload == nil {
+		return EncodedPayload{}, errs.Newf(errs.CodeMessageTooLarge,
+			"payload of %d bytes exceeds the %d byte message limit", len(payload.Body), c.MaxMessageSize)
+	}
+
+	prefix := c.OffloadPrefix
+	if prefix == "" {
+		prefix = "mq-payloads/"
+	}
+	sum := sha256.Sum256(payload.Body)
+	key := prefix + hex.EncodeToString(sum[:])
+	if err := c.Offload.Put(key, payload.Body); err != nil {
+		return EncodedPayload{}, fmt.Errorf("failed to offload payload: %w", err)
+	}
+
+	ref, err := json.Marshal(PayloadRef{Ref: key, Size: len(payload.Body)})
+	if err != nil {
+		return EncodedPayload{}, fmt.Errorf("failed to marshal payload reference: %w", err)
+	}
+	payload.Body = ref
+	payload.Ref = key
+	return payload, nil
+}
+
+// DecodePayload restores a payload published with a PayloadConfig
+// encoding and ref come from the message's Content-Encoding and X-Payload-Ref
+// metadata; store is needed only for offloaded payloads
+func DecodePayload(body []byte, encoding, ref string, store storage.ObjectStore) ([]byte, error) {
+	if ref != "" {
+		if store == nil {
+			return nil, errs.Newf(errs.CodeBadPayload, "payload offloaded to %s but no object store is configured", ref)
+		}
+		data, err := store.Get(ref)
+		if errs.CodeOf(err) == errs.CodeStorageNotFound {
+			return nil, errs.Wrapf(err, errs.CodeBadPayload, "offloaded payload %s not found", ref)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load offloaded payload %s: %w", ref, err)
+		}
+		body = data
+	}
+
+	switch encoding {
+	case "":
+		return body, nil
+	case EncodingGzip:
+		data, err := gunzipBytes(body)
+		if err != nil {
+			return nil, errs.Wrap(err, errs.CodeBadPayload, "failed to decompress payload")
+		}
+		return data, nil
+	default:
+		return nil, errs.Newf(errs.CodeBadPayload, "unsupported payload encoding %q", encoding)
+	}
+}
+
+// gzipBytes compresses data
+func gzipBytes(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gunzipBytes decompresses data
+func gunzipBytes(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = r.Close() // Error intentionally ignored on close
+	}()
+	return io.ReadAll(r)
+}
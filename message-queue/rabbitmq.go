@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/alonecandies/golwarc/errs"
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
@@ -13,11 +14,15 @@ type RabbitMQClient struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
 	url     string
+	payload PayloadConfig
+	faults  FaultInjector
 }
 
 // RabbitMQConfig holds RabbitMQ connection configuration
 type RabbitMQConfig struct {
-	URL string
+	URL     string
+	Payload PayloadConfig // Compression and size guard for message bodies; Payload.Offload also resolves consumed references
+	Faults  FaultInjector // Injects latency and errors into publishes and deliveries for resilience testing
 }
 
 // NewRabbitMQClient creates a new RabbitMQ client
@@ -37,6 +42,8 @@ func NewRabbitMQClient(config RabbitMQConfig) (*RabbitMQClient, error) {
 		conn:    conn,
 		channel: channel,
 		url:     config.URL,
+		payload: config.Payload,
+		faults:  config.Faults,
 	}, nil
 }
 
@@ -90,53 +97,90 @@ func (r *RabbitMQClient) BindQueue(queueName, exchangeName, routingKey string) e
 
 // Publish publishes a message to a queue
 func (r *RabbitMQClient) Publish(ctx context.Context, queue string, message []byte) error {
+	publishing, err := r.publishing(message, nil)
+	if err != nil {
+		return err
+	}
+	if err := injectFault(ctx, r.faults); err != nil {
+		return err
+	}
 	return r.channel.PublishWithContext(
 		ctx,
 		"",    // exchange
 		queue, // routing key
 		false, // mandatory
 		false, // immediate
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        message,
-			Timestamp:   time.Now(),
-		},
+		publishing,
 	)
 }
 
 // PublishToExchange publishes a message to an exchange with a routing key
 func (r *RabbitMQClient) PublishToExchange(ctx context.Context, exchange, routingKey string, message []byte) error {
+	publishing, err := r.publishing(message, nil)
+	if err != nil {
+		return err
+	}
+	if err := injectFault(ctx, r.faults); err != nil {
+		return err
+	}
 	return r.channel.PublishWithContext(
 		ctx,
 		exchange,   // exchange
 		routingKey, // routing key
 		false,      // mandatory
 		false,      // immediate
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        message,
-			Timestamp:   time.Now(),
-		},
+		publishing,
 	)
 }
 
 // PublishWithHeaders publishes a message with custom headers
 func (r *RabbitMQClient) PublishWithHeaders(ctx context.Context, queue string, message []byte, headers amqp.Table) error {
+	publishing, err := r.publishing(message, headers)
+	if err != nil {
+		return err
+	}
+	if err := injectFault(ctx, r.faults); err != nil {
+		return err
+	}
 	return r.channel.PublishWithContext(
 		ctx,
 		"",    // exchange
 		queue, // routing key
 		false, // mandatory
 		false, // immediate
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        message,
-			Headers:     headers,
-			Timestamp:   time.Now(),
-		},
+		publishing,
 	)
 }
 
+// publishing builds a message, compressing or offloading its body as configured
+func (r *RabbitMQClient) publishing(message []byte, headers amqp.Table) (amqp.Publishing, error) {
+	payload, err := r.payload.Encode(message)
+	if err != nil {
+		return amqp.Publishing{}, err
+	}
+	if payload.Ref != "" {
+		table := amqp.Table{HeaderPayloadRef: payload.Ref}
+		for k, v := range headers {
+			table[k] = v
+		}
+		headers = table
+	}
+	return amqp.Publishing{
+		ContentType:     "text/plain",
+		ContentEncoding: payload.Encoding,
+		Body:            payload.Body,
+		Headers:         headers,
+		Timestamp:       time.Now(),
+	}, nil
+}
+
+// DecodeDelivery returns the original body of a delivery published with a PayloadConfig
+// Use it with ConsumeWithManualAck; Consume decodes bodies itself
+func (r *RabbitMQClient) DecodeDelivery(msg amqp.Delivery) ([]byte, error) {
+	ref, _ := msg.Headers[HeaderPayloadRef].(string)
+	return DecodePayload(msg.Body, msg.ContentEncoding, ref, r.payload.Offload)
+}
+
 // Consume consumes messages from a queue
 func (r *RabbitMQClient) Consume(ctx context.Context, queue string, handler func([]byte) error) error {
 	msgs, err := r.channel.Consume(
@@ -161,7 +205,21 @@ func (r *RabbitMQClient) Consume(ctx context.Context, queue string, handler func
 				return fmt.Errorf("channel closed")
 			}
 
-			if err := handler(msg.Body); err != nil {
+			if err := injectFault(ctx, r.faults); err != nil {
+				_ = msg.Nack(false, true) // Error intentionally ignored
+				return fmt.Errorf("failed to receive message: %w", err)
+			}
+
+			body, err := r.DecodeDelivery(msg)
+			if err != nil {
+				// A corrupt body will never decode; requeue only when the
+				// offload store may recover
+				requeue := errs.CodeOf(err) != errs.CodeBadPayload
+				_ = msg.Nack(false, requeue) // Error intentionally ignored
+				return fmt.Errorf("failed to decode message: %w", err)
+			}
+
+			if err := handler(body); err != nil {
 				// Negative acknowledgment - requeue the message
 				_ = msg.Nack(false, true) // Error intentionally ignored
 				return fmt.Errorf("handler error: %w", err)
@@ -0,0 +1,195 @@
+package messagequeue
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// HeaderSchemaVersion carries the schema version of a message body, so
+// consumers can route or reject a message without decoding it
+const HeaderSchemaVersion = "X-Schema-Version"
+
+// schemaVersionField is the body field holding the schema version; bodies
+// without it are version 1
+const schemaVersionField = "schema_version"
+
+// VersionRange is the schema versions a producer or consumer understands
+type VersionRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// Contains reports whether version is within the range
+func (r VersionRange) Contains(version int) bool {
+	return version >= r.Min && version <= r.Max
+}
+
+// Migration converts a decoded message between a version and the next
+// Up runs on messages of the older version, Down on those of the newer;
+// nil means no change. Down fails when the message uses something the
+// older version cannot express, e.g. a field older consumers would ignore
+type Migration struct {
+	Up   func(doc map[string]any) error
+	Down func(doc map[string]any) error
+}
+
+// Schema versions the JSON body of one message type so that producers and
+// consumers of different releases can share a queue during rolling deploys.
+// Messages are written at a negotiated version and upgraded to the current
+// one when read
+type Schema struct {
+	name       string
+	current    int
+	migrations map[int]Migration // From version to version+1
+}
+
+// NewSchema creates a schema at version current; migrations are keyed by
+// the older of the two versions they convert between
+func NewSchema(name string, current int, migrations map[int]Migration) *Schema {
+	if current < 1 {
+		current = 1
+	}
+	return &Schema{name: name, current: current, migrations: migrations}
+}
+
+// Name returns the message type the schema describes
+func (s *Schema) Name() string {
+	return s.name
+}
+
+// Current returns the version this release writes by default
+func (s *Schema) Current() int {
+	return s.current
+}
+
+// Range returns the versions this release reads: the current one and every
+// older one a chain of migrations upgrades from
+func (s *Schema) Range() VersionRange {
+	lowest := s.current
+	for lowest > 1 {
+		if _, ok := s.migrations[lowest-1]; !ok {
+			break
+		}
+		lowest--
+	}
+	return VersionRange{Min: lowest, Max: s.current}
+}
+
+// Negotiate returns the newest version both this release and a peer
+// reading peer can handle. A zero peer range is a release that predates
+// versioning and reads version 1 only
+func (s *Schema) Negotiate(peer VersionRange) (int, error) {
+	if peer == (VersionRange{}) {
+		peer = VersionRange{Min: 1, Max: 1}
+	}
+	own := s.Range()
+	version := min(own.Max, peer.Max)
+	if version < own.Min || version < peer.Min {
+		return 0, errs.Newf(errs.CodeSchemaUnsupported,
+			"no common %s schema version: this release reads %d-%d, the peer %d-%d",
+			s.name, own.Min, own.Max, peer.Min, peer.Max)
+	}
+	return version, nil
+}
+
+// Marshal encodes v, a message of the current version, as version
+func (s *Schema) Marshal(v any, version int) ([]byte, error) {
+	if !s.Range().Contains(version) {
+		return nil, errs.Newf(errs.CodeSchemaUnsupported, "cannot write %s schema version %d", s.name, version)
+	}
+
+	doc, err := toDocument(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", s.name, err)
+	}
+	for from := s.current - 1; from >= version; from-- {
+		if down := s.migrations[from].Down; down != nil {
+			if err := down(doc); err != nil {
+				return nil, errs.Wrapf(err, errs.CodeSchemaUnsupported,
+					"cannot downgrade %s to schema version %d", s.name, from)
+			}
+		}
+	}
+	doc[schemaVersionField] = version
+	return json.Marshal(doc)
+}
+
+// Unmarshal decodes a message of any version this release reads into v,
+// upgrading it to the current version, and returns the version it had.
+// Messages newer than this release fail with errs.CodeSchemaTooNew; requeue
+// them for an upgraded consumer rather than dropping them
+func (s *Schema) Unmarshal(data []byte, v any) (int, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return 0, errs.Wrapf(err, errs.CodeBadPayload, "malformed %s message", s.name)
+	}
+
+	version, err := documentVersion(doc)
+	if err != nil {
+		return 0, errs.Wrapf(err, errs.CodeBadPayload, "malformed %s message", s.name)
+	}
+	if version > s.current {
+		return version, errs.Newf(errs.CodeSchemaTooNew,
+			"%s schema version %d is newer than this release (%d)", s.name, version, s.current)
+	}
+	if version < s.Range().Min {
+		return version, errs.Newf(errs.CodeSchemaUnsupported,
+			"%s schema version %d is no longer supported", s.name, version)
+	}
+
+	for from := version; from < s.current; from++ {
+		if up := s.migrations[from].Up; up != nil {
+			if err := up(doc); err != nil {
+				return version, errs.Wrapf(err, errs.CodeBadPayload,
+					"cannot upgrade %s from schema version %d", s.name, from)
+			}
+		}
+	}
+	delete(doc, schemaVersionField)
+
+	upgraded, err := json.Marshal(doc)
+	if err != nil {
+		return version, fmt.Errorf("failed to decode %s: %w", s.name, err)
+	}
+	if err := json.Unmarshal(upgraded, v); err != nil {
+		return version, errs.Wrapf(err, errs.CodeBadPayload, "malformed %s message", s.name)
+	}
+	return version, nil
+}
+
+// Headers returns the message headers announcing version
+func (s *Schema) Headers(version int) map[string]string {
+	return map[string]string{HeaderSchemaVersion: strconv.Itoa(version)}
+}
+
+// toDocument converts a struct to its generic JSON form
+func toDocument(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("message is not a JSON object")
+	}
+	return doc, nil
+}
+
+// documentVersion reads the schema version of a decoded message
+func documentVersion(doc map[string]any) (int, error) {
+	raw, ok := doc[schemaVersionField]
+	if !ok {
+		return 1, nil
+	}
+	number, ok := raw.(float64)
+	if !ok || number < 1 || number != float64(int(number)) {
+		return 0, fmt.Errorf("invalid schema version %v", raw)
+	}
+	return int(number), nil
+}
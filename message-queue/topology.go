@@ -0,0 +1,124 @@
+package messagequeue
+
+import (
+	"fmt"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// RabbitMQTopology declares exchanges, queues and bindings in one call
+// Declarations are idempotent, so every service can apply the same topology
+// at startup. A declaration that conflicts with an existing one fails and,
+// as AMQP requires, closes the channel; call Reconnect before reusing it
+type RabbitMQTopology struct {
+	Exchanges []ExchangeSpec
+	Queues    []QueueSpec
+	Bindings  []BindingSpec
+}
+
+// ExchangeSpec describes an exchange
+type ExchangeSpec struct {
+	Name    string
+	Kind    string // direct (default), fanout, topic or headers
+	Durable bool
+}
+
+// QueueSpec describes a queue and its TTL and dead-letter policies
+type QueueSpec struct {
+	Name       string
+	Durable    bool
+	MessageTTL time.Duration // Messages older than this are dropped or dead-lettered; 0 keeps them
+	MaxLength  int           // Oldest messages beyond this count are dropped or dead-lettered; 0 is unlimited
+
+	// DeadLetterQueue declares a durable queue that receives this queue's
+	// rejected, expired and overflowing messages through the default exchange
+	DeadLetterQueue string
+
+	// DeadLetterExchange routes dead letters to an exchange instead; it is
+	// ignored when DeadLetterQueue is set
+	DeadLetterExchange   string
+	DeadLetterRoutingKey string
+
+	Args amqp.Table // Extra x-arguments
+}
+
+// BindingSpec binds a queue to an exchange
+type BindingSpec struct {
+	Queue      string
+	Exchange   string
+	RoutingKey string
+}
+
+// IsEmpty reports whether the topology declares nothing
+func (t RabbitMQTopology) IsEmpty() bool {
+	return len(t.Exchanges) == 0 && len(t.Queues) == 0 && len(t.Bindings) == 0
+}
+
+// Arguments returns the x-arguments the queue is declared with
+func (q QueueSpec) Arguments() amqp.Table {
+	args := amqp.Table{}
+	for k, v := range q.Args {
+		args[k] = v
+	}
+	if q.MessageTTL > 0 {
+		args["x-message-ttl"] = q.MessageTTL.Milliseconds()
+	}
+	if q.MaxLength > 0 {
+		args["x-max-length"] = int64(q.MaxLength)
+	}
+	switch {
+	case q.DeadLetterQueue != "":
+		args["x-dead-letter-exchange"] = ""
+		args["x-dead-letter-routing-key"] = q.DeadLetterQueue
+	case q.DeadLetterExchange != "":
+		args["x-dead-letter-exchange"] = q.DeadLetterExchange
+		if q.DeadLetterRoutingKey != "" {
+			args["x-dead-letter-routing-key"] = q.DeadLetterRoutingKey
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+// DeclareTopology declares exchanges, then queues with their dead-letter
+// queues, then bindings
+func (r *RabbitMQClient) DeclareTopology(topology RabbitMQTopology) error {
+	for _, exchange := range topology.Exchanges {
+		kind := exchange.Kind
+		if kind == "" {
+			kind = amqp.ExchangeDirect
+		}
+		if err := r.channel.ExchangeDeclare(
+			exchange.Name,    // name
+			kind,             // type
+			exchange.Durable, // durable
+			false,            // auto-deleted
+			false,            // internal
+			false,            // no-wait
+			nil,              // arguments
+		); err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", exchange.Name, err)
+		}
+	}
+
+	for _, queue := range topology.Queues {
+		if queue.DeadLetterQueue != "" {
+			if _, err := r.DeclareQueue(queue.DeadLetterQueue, true); err != nil {
+				return fmt.Errorf("failed to declare dead-letter queue %s: %w", queue.DeadLetterQueue, err)
+			}
+		}
+		if _, err := r.DeclareQueueWithArgs(queue.Name, queue.Durable, queue.Arguments()); err != nil {
+			return fmt.Errorf("failed to declare queue %s: %w", queue.Name, err)
+		}
+	}
+
+	for _, binding := range topology.Bindings {
+		if err := r.BindQueue(binding.Queue, binding.Exchange, binding.RoutingKey); err != nil {
+			return fmt.Errorf("failed to bind queue %s to %s: %w", binding.Queue, binding.Exchange, err)
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"time"
 
 	"github.com/gocolly/colly/v2"
@@ -218,6 +219,7 @@ func (m *MockCacheClient) SetJSON(key string, value interface{}, ttl time.Durati
 type MockCrawlerClient struct {
 	VisitedURLs       []string
 	VisitFunc         func(url string) error
+	VisitContextFunc  func(ctx context.Context, url string) error
 	VisitMultipleFunc func(urls []string) error
 	WaitFunc          func()
 	OnHTMLFunc        func(selector string, handler func(e *colly.HTMLElement))
@@ -238,6 +240,15 @@ func (m *MockCrawlerClient) Visit(url string) error {
 	return nil
 }
 
+// VisitContext starts crawling from the given URL until ctx is done
+func (m *MockCrawlerClient) VisitContext(ctx context.Context, url string) error {
+	m.VisitedURLs = append(m.VisitedURLs, url)
+	if m.VisitContextFunc != nil {
+		return m.VisitContextFunc(ctx, url)
+	}
+	return ctx.Err()
+}
+
 // VisitMultiple visits multiple URLs
 func (m *MockCrawlerClient) VisitMultiple(urls []string) error {
 	m.VisitedURLs = append(m.VisitedURLs, urls...)
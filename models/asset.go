@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Asset is an image or file referenced by a crawled page
+// The content lives in blob storage under StorageKey; identical files share
+// one object, keyed by their SHA-256
+type Asset struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	PageID      uint      `gorm:"index;not null" json:"page_id"` // Page the asset was referenced by
+	URL         string    `gorm:"not null;size:2048" json:"url"`
+	ContentType string    `gorm:"size:255" json:"content_type"`
+	Size        int64     `gorm:"default:0" json:"size"`
+	SHA256      string    `gorm:"index;size:64" json:"sha256"`
+	StorageKey  string    `gorm:"size:255" json:"storage_key"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for Asset model
+func (Asset) TableName() string {
+	return "assets"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// CrawlLog records the outcome of a single fetch
+// CreatedAt is part of the primary key so the table can be range-partitioned by time
+// URLs a crawl decided not to fetch are logged with a SkipReason and no status
+type CrawlLog struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	CreatedAt   time.Time `gorm:"primaryKey;index" json:"created_at"`
+	Project     string    `gorm:"index;size:255" json:"project,omitempty"`
+	CrawlID     string    `gorm:"index;size:64" json:"crawl_id,omitempty"` // Matches the crawl_id log field
+	URL         string    `gorm:"not null;size:2048" json:"url"`
+	Domain      string    `gorm:"index;size:255" json:"domain"`
+	Status      int       `json:"status"`
+	DurationMs  int64     `json:"duration_ms"`
+	Bytes       int64     `json:"bytes,omitempty"`                       // Size of the fetched body
+	ContentHash string    `gorm:"size:64" json:"content_hash,omitempty"` // SHA-256 of the fetched body, for comparing crawls
+	Error       string    `gorm:"type:text" json:"error,omitempty"`
+	SkipReason  string    `gorm:"index;size:32" json:"skip_reason,omitempty"` // e.g. robots, url_rule, budget, duplicate, ssrf
+	SkipRule    string    `gorm:"size:512" json:"skip_rule,omitempty"`        // What decided the skip, e.g. "deny /admin/*"
+}
+
+// TableName specifies the table name for CrawlLog model
+func (CrawlLog) TableName() string {
+	return "crawl_logs"
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Domain describes a crawled site so dashboards can render a recognizable
+// entry for it: its name, icon and colors
+// One row per host, refreshed from its crawled pages and web app manifest
+type Domain struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Host            string    `gorm:"uniqueIndex;not null;size:255" json:"host"` // As stored in pages.domain
+	SiteName        string    `gorm:"size:255" json:"site_name,omitempty"`       // From the manifest, else the page's meta tags
+	ShortName       string    `gorm:"size:255" json:"short_name,omitempty"`      // Manifest short_name
+	FaviconURL      string    `gorm:"size:2048" json:"favicon_url,omitempty"`
+	IconURL         string    `gorm:"size:2048" json:"icon_url,omitempty"` // Largest manifest icon, else the favicon
+	ManifestURL     string    `gorm:"size:2048" json:"manifest_url,omitempty"`
+	ThemeColor      string    `gorm:"size:64" json:"theme_color,omitempty"`
+	BackgroundColor string    `gorm:"size:64" json:"background_color,omitempty"`
+	CheckedAt       time.Time `json:"checked_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Domain model
+func (Domain) TableName() string {
+	return "domains"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// DomainCertificate is the TLS certificate last presented by an https host
+// One row per host, updated on every check
+type DomainCertificate struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Host         string    `gorm:"uniqueIndex;not null;size:255" json:"host"` // Host name, with the port when not 443
+	Subject      string    `gorm:"size:512" json:"subject"`
+	Issuer       string    `gorm:"size:512" json:"issuer"`
+	DNSNames     string    `gorm:"type:text" json:"dns_names"` // Comma-separated subject alternative names
+	SerialNumber string    `gorm:"size:128" json:"serial_number"`
+	Fingerprint  string    `gorm:"size:64" json:"fingerprint"` // SHA-256 of the DER certificate, hex
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `gorm:"index" json:"not_after"`
+	Verified     bool      `json:"verified"`                                // Chain and host name verified against system roots
+	VerifyError  string    `gorm:"type:text" json:"verify_error,omitempty"` // Why verification failed
+	CheckedAt    time.Time `json:"checked_at"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for DomainCertificate model
+func (DomainCertificate) TableName() string {
+	return "domain_certificates"
+}
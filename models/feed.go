@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Feed is an RSS, Atom or JSON feed to monitor
+// Feeds are registered when a crawled page advertises them
+type Feed struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Project   string    `gorm:"uniqueIndex:idx_feeds_project_url;size:255" json:"project,omitempty"`
+	URL       string    `gorm:"uniqueIndex:idx_feeds_project_url;not null;size:2048" json:"url"`
+	Title     string    `gorm:"size:512" json:"title,omitempty"`
+	Type      string    `gorm:"size:16" json:"type"`         // rss, atom or json
+	SourceURL string    `gorm:"size:2048" json:"source_url"` // Page the feed was discovered on
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for Feed model
+func (Feed) TableName() string {
+	return "feeds"
+}
@@ -8,17 +8,26 @@ import (
 
 // Page represents a crawled web page
 type Page struct {
-	ID        uint           `gorm:"primaryKey" json:"id"`
-	URL       string         `gorm:"uniqueIndex;not null;size:2048" json:"url"`
-	Title     string         `gorm:"size:512" json:"title"`
-	Content   string         `gorm:"type:longtext" json:"content"`
-	Status    int            `gorm:"default:200" json:"status"`
-	Domain    string         `gorm:"index;size:255" json:"domain"`
-	HTML      string         `gorm:"type:longtext" json:"html,omitempty"`
-	Headers   string         `gorm:"type:text" json:"headers,omitempty"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
+	ID           uint           `gorm:"primaryKey" json:"id"`
+	Project      string         `gorm:"uniqueIndex:idx_pages_project_url;size:255" json:"project,omitempty"`
+	URL          string         `gorm:"uniqueIndex:idx_pages_project_url;not null;size:2048" json:"url"`
+	Title        string         `gorm:"size:512" json:"title"`
+	CanonicalURL string         `gorm:"size:2048" json:"canonical_url,omitempty"` // From <link rel="canonical"> or permanent redirects
+	Content      string         `gorm:"type:longtext" json:"content"`
+	Status       int            `gorm:"default:200" json:"status"`
+	Domain       string         `gorm:"index;size:255" json:"domain"`
+	HTML         string         `gorm:"type:longtext" json:"html,omitempty"`
+	ContentHash  string         `gorm:"index;size:64" json:"content_hash,omitempty"` // References PageContent when HTML lives in the shared corpus
+	BodyCodec    string         `gorm:"size:32" json:"body_codec,omitempty"`         // How the body is stored: inline, gzip, object, warc
+	BodyRef      string         `gorm:"size:2048" json:"body_ref,omitempty"`         // Object key or WARC reference for externally stored bodies
+	BodyData     []byte         `json:"-"`                                           // Compressed body for the gzip codec
+	BodySize     int64          `gorm:"default:0" json:"body_size"`
+	Headers      string         `gorm:"type:text" json:"headers,omitempty"`
+	ETag         string         `gorm:"column:etag;size:512;not null;default:''" json:"etag,omitempty"` // Validators sent back on conditional re-crawls
+	LastModified string         `gorm:"size:64;not null;default:''" json:"last_modified,omitempty"`
+	CreatedAt    time.Time      `gorm:"index" json:"created_at"` // Serves GetPagesBetween; pages is not partitioned
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at,omitempty"`
 }
 
 // TableName specifies the table name for Page model
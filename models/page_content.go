@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// PageContent stores a page body once per content hash
+// Pages in projects that opt into the shared corpus reference it by hash
+type PageContent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Hash      string    `gorm:"uniqueIndex;not null;size:64" json:"hash"`
+	Body      string    `gorm:"type:longtext" json:"body"`
+	Size      int64     `gorm:"default:0" json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for PageContent model
+func (PageContent) TableName() string {
+	return "page_contents"
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// SchemaMigration records a one-time data migration that has been applied
+// Migrations with a row here are never run again
+type SchemaMigration struct {
+	Name      string    `gorm:"primaryKey;size:255" json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// TableName specifies the table name for SchemaMigration model
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
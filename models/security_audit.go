@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// SecurityAudit records the security headers of a crawled page and their
+// score, see extractors.AuditSecurityHeaders
+type SecurityAudit struct {
+	ID                  uint      `gorm:"primaryKey" json:"id"`
+	PageID              uint      `gorm:"index" json:"page_id"`
+	Project             string    `gorm:"index:idx_security_audits_project_domain;size:255" json:"project,omitempty"`
+	Domain              string    `gorm:"index:idx_security_audits_project_domain;size:255" json:"domain"`
+	URL                 string    `gorm:"not null;size:2048" json:"url"`
+	CSP                 string    `gorm:"type:text" json:"csp,omitempty"`
+	HSTS                string    `gorm:"size:512" json:"hsts,omitempty"`
+	XFrameOptions       string    `gorm:"size:255" json:"x_frame_options,omitempty"`
+	XContentTypeOptions string    `gorm:"size:255" json:"x_content_type_options,omitempty"`
+	ReferrerPolicy      string    `gorm:"size:255" json:"referrer_policy,omitempty"`
+	PermissionsPolicy   string    `gorm:"type:text" json:"permissions_policy,omitempty"`
+	Score               int       `json:"score"`                   // 0 to 100
+	Issues              string    `gorm:"type:text" json:"issues"` // Comma-separated issue names
+	CreatedAt           time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for SecurityAudit model
+func (SecurityAudit) TableName() string {
+	return "security_audits"
+}
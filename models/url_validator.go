@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// URLValidator stores the HTTP cache validators last seen for a URL
+// Re-crawls send them as If-None-Match and If-Modified-Since
+type URLValidator struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Project      string    `gorm:"uniqueIndex:idx_url_validators_project_url;size:255" json:"project,omitempty"`
+	URL          string    `gorm:"uniqueIndex:idx_url_validators_project_url;not null;size:2048" json:"url"`
+	ETag         string    `gorm:"column:etag;size:512" json:"etag,omitempty"`
+	LastModified string    `gorm:"size:64" json:"last_modified,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for URLValidator model
+func (URLValidator) TableName() string {
+	return "url_validators"
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+	"github.com/alonecandies/golwarc/inject"
+)
+
+// planUsage documents the plan command
+const planUsage = `usage: golwarc plan [flags] <urls.txt|->
+
+Replays a URL list, one URL per line, through the URL rules, scheduler and
+politeness settings of config.yaml without sending a request, and estimates
+the requests, duration and bandwidth of the crawl. Lists of -concurrency and
+-rps values print one estimate per combination.
+
+flags:
+  -concurrency 5,10   crawl workers (default crawler.concurrency)
+  -rps 1,2            requests per second per domain (default crawler.rate_limit)
+  -latency 500ms      assumed response time
+  -size 102400        assumed response body size in bytes
+  -max-pages, -max-bytes, -max-duration   crawl budget
+  -allow, -deny       URL rule patterns; repeatable. With -allow, URLs
+                      no allow pattern matches are denied
+  -domains            also list the estimate of every domain
+`
+
+// planRules compiles -allow and -deny patterns into URL rules; with allow
+// patterns, a closing "deny *" denies URLs none of them match
+func planRules(allow, deny []string) (*urlmatch.RuleSet, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	var rules []urlmatch.Rule
+	for _, pattern := range allow {
+		rules = append(rules, urlmatch.Rule{Pattern: pattern, Action: urlmatch.Allow})
+	}
+	for _, pattern := range deny {
+		rules = append(rules, urlmatch.Rule{Pattern: pattern, Action: urlmatch.Deny})
+	}
+	if len(allow) > 0 {
+		rules = append(rules, urlmatch.Rule{Pattern: "*", Action: urlmatch.Deny})
+	}
+	return urlmatch.Compile(rules)
+}
+
+// listFlag collects repeated string flags
+type listFlag []string
+
+func (l *listFlag) String() string { return strings.Join(*l, ",") }
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// runPlan runs the plan command and returns the process exit code
+func runPlan(container *inject.Container, args []string) int {
+	defer func() {
+		_ = container.Close() // Error intentionally ignored on close
+	}()
+
+	config := container.Config.Crawler
+	if err := planCommand(config, container.Canonical, args, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "plan: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// planCommand parses the plan flags, runs a dry run per configuration and
+// prints the estimates
+func planCommand(config configs.CrawlerConfig, canonical *crawlers.Canonicalizer, args []string, in io.Reader, w io.Writer) error {
+	flags := flag.NewFlagSet("plan", flag.ContinueOnError)
+	flags.Usage = func() { _, _ = io.WriteString(os.Stderr, planUsage) }
+	concurrency := flags.String("concurrency", "", "Comma-separated crawl worker counts")
+	rps := flags.String("rps", "", "Comma-separated requests per second per domain")
+	latency := flags.Duration("latency", 500*time.Millisecond, "Assumed response time")
+	size := flags.Int64("size", 100<<10, "Assumed response body size in bytes")
+	maxPages := flags.Int("max-pages", 0, "Crawl budget in pages")
+	maxBytes := flags.Int64("max-bytes", 0, "Crawl budget in bytes")
+	maxDuration := flags.Duration("max-duration", 0, "Crawl budget in time")
+	domains := flags.Bool("domains", false, "List the estimate of every domain")
+	var allow, deny listFlag
+	flags.Var(&allow, "allow", "URL rule allow pattern")
+	flags.Var(&deny, "deny", "URL rule deny pattern")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("plan takes exactly one URL list")
+	}
+
+	rules, err := planRules(allow, deny)
+	if err != nil {
+		return err
+	}
+	urls, err := readURLList(flags.Arg(0), in)
+	if err != nil {
+		return err
+	}
+
+	workers := []float64{float64(max(config.Concurrency, 1))}
+	if *concurrency != "" {
+		if workers, err = parseNumberList(*concurrency); err != nil {
+			return fmt.Errorf("invalid -concurrency: %w", err)
+		}
+	}
+	limit := crawlers.RateLimiterConfig{}
+	if config.RateLimit.Enabled {
+		limit = crawlers.RateLimiterConfig{
+			RequestsPerSecond: float64(config.RateLimit.RequestsPerSec),
+			Delay:             time.Duration(config.RateLimit.Delay) * time.Millisecond,
+			RandomDelay:       time.Duration(config.RateLimit.RandomDelay) * time.Millisecond,
+			MaxConcurrent:     config.RateLimit.MaxConcurrent,
+		}
+	}
+	rates := []float64{limit.RequestsPerSecond}
+	if *rps != "" {
+		if rates, err = parseNumberList(*rps); err != nil {
+			return fmt.Errorf("invalid -rps: %w", err)
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CONCURRENCY\tRPS\tURLS\tREQUESTS\tSKIPPED\tBYTES\tDURATION\tEXHAUSTED")
+	var estimates []crawlers.DryRunEstimate
+	for _, n := range workers {
+		for _, rate := range rates {
+			limit.RequestsPerSecond = rate
+			estimate := crawlers.DryRun(urls, crawlers.DryRunConfig{
+				RateLimit:   limit,
+				Concurrency: int(n),
+				URLRules:    rules,
+				Canonical:   canonical,
+				MaxPages:    *maxPages,
+				MaxBytes:    *maxBytes,
+				MaxDuration: *maxDuration,
+				Latency:     *latency,
+				PageSize:    *size,
+			})
+			estimates = append(estimates, estimate)
+
+			rateLabel := "unlimited"
+			if rate > 0 {
+				rateLabel = strconv.FormatFloat(rate, 'f', -1, 64)
+			}
+			exhausted := estimate.Exhausted
+			if exhausted == "" {
+				exhausted = "-"
+			}
+			fmt.Fprintf(tw, "%d\t%s\t%d\t%d\t%d\t%d\t%s\t%s\n", int(n), rateLabel, estimate.URLs,
+				estimate.Requests, estimate.Skips.Total, estimate.Bytes, estimate.Duration.Round(time.Second), exhausted)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(estimates) > 0 && estimates[0].Skips.Total > 0 {
+		rules := make([]string, 0, len(estimates[0].Skips.ByRule))
+		for rule := range estimates[0].Skips.ByRule {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		fmt.Fprintln(w)
+		for _, rule := range rules {
+			fmt.Fprintf(w, "skipped %d: %s\n", estimates[0].Skips.ByRule[rule], rule)
+		}
+	}
+	if *domains && len(estimates) > 0 {
+		fmt.Fprintln(w)
+		printDomainEstimates(w, estimates[0])
+	}
+	return nil
+}
+
+// printDomainEstimates lists the domains of an estimate, slowest first
+func printDomainEstimates(w io.Writer, estimate crawlers.DryRunEstimate) {
+	names := make([]string, 0, len(estimate.Domains))
+	for name := range estimate.Domains {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := estimate.Domains[names[i]], estimate.Domains[names[j]]
+		if a.Duration != b.Duration {
+			return a.Duration > b.Duration
+		}
+		return names[i] < names[j]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "DOMAIN\tREQUESTS\tBYTES\tDURATION")
+	for _, name := range names {
+		d := estimate.Domains[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%s\n", name, d.Requests, d.Bytes, d.Duration.Round(time.Second))
+	}
+	_ = tw.Flush() // Error intentionally ignored; the summary was already written
+}
+
+// readURLList reads one URL per line from path, or from in when path is
+// "-"; blank lines and # comments are skipped
+func readURLList(path string, in io.Reader) ([]string, error) {
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = f.Close() // Error intentionally ignored on close
+		}()
+		in = f
+	}
+
+	var urls []string
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		urls = append(urls, line)
+	}
+	return urls, scanner.Err()
+}
+
+// parseNumberList parses comma-separated non-negative numbers
+func parseNumberList(value string) ([]float64, error) {
+	var numbers []float64
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("negative value %v", n)
+		}
+		numbers = append(numbers, n)
+	}
+	return numbers, nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+	"github.com/alonecandies/golwarc/inject"
+)
+
+// queueUsage documents the queue subcommands
+const queueUsage = `usage: golwarc queue <command> [arguments]
+
+commands:
+  ls                                   show the size of every frontier queue
+  peek [-n 20] [pending|inflight|dead]  list URLs at the head of a queue (default pending)
+  requeue [url ...]                    move dead-lettered URLs back to pending (all when none given)
+  purge -yes <pending|inflight|dead>   drop every URL in a queue
+`
+
+// queueFrontier is what the queue commands need from a frontier
+type queueFrontier interface {
+	frontier.Frontier
+	frontier.Inspector
+}
+
+// runQueue runs a queue subcommand against the configured frontier and
+// returns the process exit code
+func runQueue(container *inject.Container, args []string) int {
+	defer func() {
+		_ = container.Close() // Error intentionally ignored on close
+	}()
+
+	if container.Frontier == nil {
+		fmt.Fprintln(os.Stderr, "queue: crawl frontier is not enabled; set crawler.frontier.enabled and configure cache.redis")
+		return 1
+	}
+	if err := queueCommand(context.Background(), container.Frontier, args, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "queue: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// queueCommand dispatches a queue subcommand
+func queueCommand(ctx context.Context, f queueFrontier, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		_, _ = io.WriteString(os.Stderr, queueUsage)
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "ls":
+		stats, err := f.Stats(ctx)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "QUEUE\tURLS")
+		fmt.Fprintf(tw, "pending\t%d\n", stats.Pending)
+		fmt.Fprintf(tw, "inflight\t%d\n", stats.InFlight)
+		fmt.Fprintf(tw, "dead\t%d\n", stats.Dead)
+		fmt.Fprintf(tw, "seen\t%d\n", stats.Seen)
+		return tw.Flush()
+
+	case "peek":
+		flags := flag.NewFlagSet("peek", flag.ContinueOnError)
+		limit := flags.Int("n", 20, "Number of URLs to show; 0 shows all")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		queue := frontier.QueuePending
+		if flags.NArg() > 0 {
+			var err error
+			if queue, err = frontier.ParseQueue(flags.Arg(0)); err != nil {
+				return err
+			}
+		}
+		entries, err := f.Peek(ctx, queue, *limit)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "URL\tATTEMPTS\tLEASE DEADLINE")
+		for _, e := range entries {
+			deadline := "-"
+			if !e.Deadline.IsZero() {
+				deadline = e.Deadline.Format(time.RFC3339)
+				if time.Until(e.Deadline) < 0 {
+					deadline += " (expired)"
+				}
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%s\n", e.URL, e.Attempts, deadline)
+		}
+		return tw.Flush()
+
+	case "requeue":
+		moved, err := f.Requeue(ctx, args[1:]...)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "requeued %d URLs\n", moved)
+		return nil
+
+	case "purge":
+		flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+		yes := flags.Bool("yes", false, "Confirm that the queue should be emptied")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("purge takes exactly one queue: pending, inflight or dead")
+		}
+		queue, err := frontier.ParseQueue(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		if !*yes {
+			return fmt.Errorf("refusing to purge the %s queue without -yes", queue)
+		}
+		purged, err := f.Purge(ctx, queue)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "purged %d URLs from %s\n", purged, queue)
+		return nil
+	}
+
+	_, _ = io.WriteString(os.Stderr, queueUsage)
+	return fmt.Errorf("unknown command %q", args[0])
+}
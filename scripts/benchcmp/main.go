@@ -0,0 +1,173 @@
+// Command benchcmp records `go test -bench` results as JSON and compares them
+// with a baseline, exiting non-zero when a metric regresses
+// Usage:
+//
+//	go test -run '^$' -bench . -benchmem ./tests/benchmarks > bench.out
+//	go run ./scripts/benchcmp -write tests/benchmarks/baseline.json < bench.out
+//	go run ./scripts/benchcmp -baseline tests/benchmarks/baseline.json < bench.out
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// higherIsBetter lists the compared metrics and their direction
+var higherIsBetter = map[string]bool{
+	"pages/s":   true,
+	"p95-ms":    false,
+	"allocs/op": false,
+	"B/op":      false,
+}
+
+// procsSuffix is the -GOMAXPROCS suffix go test appends to benchmark names
+var procsSuffix = regexp.MustCompile(`-\d+$`)
+
+// Results maps benchmark names to their metrics
+type Results struct {
+	Benchmarks map[string]map[string]float64 `json:"benchmarks"`
+}
+
+func main() {
+	baselinePath := flag.String("baseline", "", "Compare against this baseline JSON")
+	writePath := flag.String("write", "", "Write the results to this JSON file")
+	tolerance := flag.Float64("tolerance", 0.25, "Allowed relative regression per metric")
+	flag.Parse()
+
+	current, err := parse(os.Stdin)
+	if err != nil {
+		fail("failed to parse benchmark output: %v", err)
+	}
+	if len(current.Benchmarks) == 0 {
+		fail("no benchmark results on stdin")
+	}
+
+	if *writePath != "" {
+		data, err := json.MarshalIndent(current, "", "  ")
+		if err != nil {
+			fail("failed to marshal results: %v", err)
+		}
+		if err := os.WriteFile(*writePath, append(data, '\n'), 0o644); err != nil {
+			fail("failed to write results: %v", err)
+		}
+		fmt.Printf("wrote %d benchmarks to %s\n", len(current.Benchmarks), *writePath)
+	}
+
+	if *baselinePath != "" {
+		data, err := os.ReadFile(*baselinePath)
+		if err != nil {
+			fail("failed to read baseline: %v", err)
+		}
+		var baseline Results
+		if err := json.Unmarshal(data, &baseline); err != nil {
+			fail("failed to parse baseline: %v", err)
+		}
+		if regressions := compare(os.Stdout, baseline, current, *tolerance); regressions > 0 {
+			fail("%d metrics regressed by more than %.0f%%", regressions, *tolerance*100)
+		}
+	}
+}
+
+// parse reads benchmark lines, averaging repeated runs of a benchmark
+func parse(r io.Reader) (Results, error) {
+	sums := make(map[string]map[string]float64)
+	runs := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		name := procsSuffix.ReplaceAllString(fields[0], "")
+		if sums[name] == nil {
+			sums[name] = make(map[string]float64)
+		}
+		runs[name]++
+
+		// Fields after the iteration count are value/unit pairs
+		for i := 2; i+1 < len(fields); i += 2 {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return Results{}, fmt.Errorf("%s: bad value %q", name, fields[i])
+			}
+			if _, ok := higherIsBetter[fields[i+1]]; ok {
+				sums[name][fields[i+1]] += value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Results{}, err
+	}
+
+	results := Results{Benchmarks: make(map[string]map[string]float64, len(sums))}
+	for name, metrics := range sums {
+		results.Benchmarks[name] = make(map[string]float64, len(metrics))
+		for unit, sum := range metrics {
+			results.Benchmarks[name][unit] = math.Round(sum/float64(runs[name])*1000) / 1000
+		}
+	}
+	return results, nil
+}
+
+// compare prints every metric against the baseline and returns the number of
+// regressions beyond tolerance
+func compare(w io.Writer, baseline, current Results, tolerance float64) int {
+	names := make([]string, 0, len(baseline.Benchmarks))
+	for name := range baseline.Benchmarks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	regressions := 0
+	for _, name := range names {
+		got, ok := current.Benchmarks[name]
+		if !ok {
+			fmt.Fprintf(w, "%-45s missing from results\n", name)
+			continue
+		}
+
+		units := make([]string, 0, len(baseline.Benchmarks[name]))
+		for unit := range baseline.Benchmarks[name] {
+			units = append(units, unit)
+		}
+		sort.Strings(units)
+
+		for _, unit := range units {
+			base := baseline.Benchmarks[name][unit]
+			value, ok := got[unit]
+			if !ok || base == 0 {
+				continue
+			}
+			change := (value - base) / base
+			worse := change > tolerance
+			if higherIsBetter[unit] {
+				worse = change < -tolerance
+			}
+
+			status := "ok"
+			if worse {
+				status = "REGRESSION"
+				regressions++
+			}
+			fmt.Fprintf(w, "%-45s %-10s %12.3f %12.3f %+7.1f%%  %s\n",
+				name, unit, base, value, math.Round(change*1000)/10, status)
+		}
+	}
+	return regressions
+}
+
+// fail prints an error and exits
+func fail(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "benchcmp: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,501 @@
+// Package clientgen generates the operation methods of api/client from the
+// OpenAPI document of the golwarc HTTP API
+// Model schemas are mapped back to their Go types through x-go-type, so the
+// generated methods share types with the server
+package clientgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/alonecandies/golwarc/api"
+)
+
+// Content types with dedicated handling; anything else is read as bytes
+const (
+	contentJSON   = "application/json"
+	contentNDJSON = "application/x-ndjson"
+)
+
+// Header is the first line of generated files
+const Header = "// Code generated by scripts/openapi from docs/openapi.json. DO NOT EDIT."
+
+// initialisms are written in upper case in Go names
+var initialisms = map[string]bool{"API": true, "HTTP": true, "ID": true, "JSON": true, "URL": true}
+
+// reserved names are used by generated method bodies
+var reserved = map[string]bool{"c": true, "ctx": true, "params": true, "body": true, "query": true, "out": true, "resp": true, "content": true, "err": true, "url": true, "http": true}
+
+// generator accumulates the source of one file
+type generator struct {
+	doc     *api.OpenAPIDocument
+	imports map[string]string // Import path -> package name
+	buf     bytes.Buffer
+}
+
+// Generate returns the gofmt-ed source of the client methods of every
+// operation of doc, in package pkg
+// The package must provide the runtime the methods call: Client with doJSON,
+// readAll and openStream
+func Generate(doc *api.OpenAPIDocument, pkg string) ([]byte, error) {
+	g := &generator{
+		doc:     doc,
+		imports: map[string]string{"context": "context", "net/http": "http"},
+	}
+
+	for _, op := range operations(doc) {
+		if err := g.operation(op); err != nil {
+			return nil, fmt.Errorf("operation %s: %w", op.OperationID, err)
+		}
+	}
+
+	var file bytes.Buffer
+	fmt.Fprintf(&file, "%s\n\npackage %s\n\nimport (\n", Header, pkg)
+	var std, other []string
+	for importPath := range g.imports {
+		if strings.Contains(strings.Split(importPath, "/")[0], ".") {
+			other = append(other, importPath)
+		} else {
+			std = append(std, importPath)
+		}
+	}
+	for i, group := range [][]string{std, other} {
+		if i > 0 && len(group) > 0 {
+			file.WriteString("\n")
+		}
+		sort.Strings(group)
+		for _, importPath := range group {
+			fmt.Fprintf(&file, "\t%q\n", importPath)
+		}
+	}
+	file.WriteString(")\n")
+	file.Write(g.buf.Bytes())
+
+	src, err := format.Source(file.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated client: %w", err)
+	}
+	return src, nil
+}
+
+// endpoint is an operation with its route
+type endpoint struct {
+	*api.Operation
+	Method string
+	Path   string
+}
+
+// operations returns the endpoints of doc sorted by operation ID
+func operations(doc *api.OpenAPIDocument) []endpoint {
+	var eps []endpoint
+	for route, methods := range doc.Paths {
+		for method, op := range methods {
+			eps = append(eps, endpoint{Operation: op, Method: strings.ToUpper(method), Path: route})
+		}
+	}
+	sort.Slice(eps, func(i, j int) bool { return eps[i].OperationID < eps[j].OperationID })
+	return eps
+}
+
+// operation writes the params type, response type and method of op
+func (g *generator) operation(op endpoint) error {
+	name := exported(op.OperationID)
+
+	var pathParams, queryParams []api.Parameter
+	for _, param := range op.Parameters {
+		switch param.In {
+		case "path":
+			pathParams = append(pathParams, param)
+		case "query":
+			queryParams = append(queryParams, param)
+		default:
+			return fmt.Errorf("unsupported %s parameter %s", param.In, param.Name)
+		}
+	}
+
+	// Arguments
+	args := []string{"ctx context.Context"}
+	for _, param := range pathParams {
+		args = append(args, local(param.Name)+" string")
+	}
+	if len(queryParams) > 0 {
+		if err := g.paramsType(name, queryParams); err != nil {
+			return err
+		}
+		args = append(args, "params "+name+"Params")
+	}
+	bodyArg := "nil"
+	if op.RequestBody != nil {
+		media := op.RequestBody.Content[contentJSON]
+		if media == nil {
+			return fmt.Errorf("request body must be %s", contentJSON)
+		}
+		bodyType, err := g.goType(media.Schema)
+		if err != nil {
+			return err
+		}
+		args = append(args, "body "+bodyType)
+		bodyArg = "body"
+	}
+
+	// Result
+	resp := successResponse(op.Responses)
+	if resp == nil {
+		return fmt.Errorf("no 2xx response")
+	}
+	kind, schema := responseKind(resp)
+	var result, zero string
+	switch kind {
+	case contentJSON:
+		t, err := g.goType(schema)
+		if err != nil {
+			return err
+		}
+		result, zero = t, "nil"
+		if !strings.HasPrefix(t, "[]") && !strings.HasPrefix(t, "map[") {
+			result = "*" + t
+		}
+	case contentNDJSON:
+		t, err := g.goType(schema)
+		if err != nil {
+			return err
+		}
+		result, zero = "*ExportStream["+t+"]", "nil"
+	case "":
+		result = ""
+	default:
+		result, zero = "[]byte", "nil"
+		if len(resp.Headers) > 0 {
+			g.responseType(name, resp.Headers)
+			result = "*" + name + "Response"
+		}
+	}
+
+	// Method
+	fmt.Fprintf(&g.buf, "\n// %s calls %s %s\n", name, op.Method, op.Path)
+	if op.Summary != "" {
+		fmt.Fprintf(&g.buf, "// %s\n", op.Summary)
+	}
+	returns := "error"
+	if result != "" {
+		returns = "(" + result + ", error)"
+	}
+	fmt.Fprintf(&g.buf, "func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), returns)
+
+	queryArg := "nil"
+	if len(queryParams) > 0 {
+		g.queryValues(queryParams)
+		g.buf.WriteString("\n")
+		queryArg = "query"
+	}
+	method := "http.Method" + exported(strings.ToLower(op.Method))
+	route := routeExpr(op.Path, pathParams)
+	fail := "return err"
+	if result != "" {
+		fail = "return " + zero + ", err"
+	}
+
+	switch kind {
+	case contentJSON:
+		fmt.Fprintf(&g.buf, "\tvar out %s\n", strings.TrimPrefix(result, "*"))
+		fmt.Fprintf(&g.buf, "\tif err := c.doJSON(ctx, %s, %s, %s, %s, &out); err != nil {\n\t\t%s\n\t}\n", method, route, queryArg, bodyArg, fail)
+		if strings.HasPrefix(result, "*") {
+			g.buf.WriteString("\treturn &out, nil\n")
+		} else {
+			g.buf.WriteString("\treturn out, nil\n")
+		}
+	case contentNDJSON:
+		if op.RequestBody != nil {
+			return fmt.Errorf("streamed operations cannot take a request body")
+		}
+		fmt.Fprintf(&g.buf, "\treturn openStream[%s](ctx, c, %s, %s, %s)\n", strings.TrimSuffix(strings.TrimPrefix(result, "*ExportStream["), "]"), method, route, queryArg)
+	case "":
+		fmt.Fprintf(&g.buf, "\treturn c.doJSON(ctx, %s, %s, %s, %s, nil)\n", method, route, queryArg, bodyArg)
+	default:
+		if op.RequestBody != nil {
+			return fmt.Errorf("binary operations cannot take a request body")
+		}
+		respVar := "_"
+		if len(resp.Headers) > 0 {
+			respVar = "resp"
+		}
+		fmt.Fprintf(&g.buf, "\t%s, content, err := c.readAll(ctx, %s, %s, %s)\n\tif err != nil {\n\t\t%s\n\t}\n", respVar, method, route, queryArg, fail)
+		if len(resp.Headers) == 0 {
+			g.buf.WriteString("\treturn content, nil\n")
+			break
+		}
+		fmt.Fprintf(&g.buf, "\treturn &%sResponse{\n\t\tContentType: resp.Header.Get(\"Content-Type\"),\n", name)
+		for _, header := range sortedKeys(resp.Headers) {
+			fmt.Fprintf(&g.buf, "\t\t%s: resp.Header.Get(%q),\n", headerField(header), header)
+		}
+		g.buf.WriteString("\t\tContent: content,\n\t}, nil\n")
+	}
+	g.buf.WriteString("}\n")
+	return nil
+}
+
+// paramsType writes the struct holding the query parameters of an operation
+func (g *generator) paramsType(name string, params []api.Parameter) error {
+	fmt.Fprintf(&g.buf, "\n// %sParams holds the query parameters of %s\ntype %sParams struct {\n", name, name, name)
+	for _, param := range params {
+		t, err := g.paramType(param.Schema)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", param.Name, err)
+		}
+		comment := param.Description
+		if param.Required {
+			comment = strings.TrimSpace(comment + " (required)")
+		}
+		fmt.Fprintf(&g.buf, "\t%s %s", exported(param.Name), t)
+		if comment != "" {
+			fmt.Fprintf(&g.buf, " // %s", comment)
+		}
+		g.buf.WriteString("\n")
+	}
+	g.buf.WriteString("}\n")
+	return nil
+}
+
+// queryValues writes the statements encoding params into query
+// Optional parameters are only sent when set
+func (g *generator) queryValues(params []api.Parameter) {
+	g.imports["net/url"] = "url"
+	g.buf.WriteString("\tquery := url.Values{}\n")
+	for _, param := range params {
+		field := "params." + exported(param.Name)
+		var value, isSet string
+		switch {
+		case param.Schema.Type == "integer":
+			g.imports["strconv"] = "strconv"
+			value, isSet = "strconv.Itoa("+field+")", field+" != 0"
+		case param.Schema.Type == "boolean":
+			g.imports["strconv"] = "strconv"
+			value, isSet = "strconv.FormatBool("+field+")", field
+		case param.Schema.Format == "date-time":
+			value, isSet = field+".Format(time.RFC3339)", "!"+field+".IsZero()"
+		default:
+			value, isSet = field, field+` != ""`
+		}
+		if param.Required {
+			fmt.Fprintf(&g.buf, "\tquery.Set(%q, %s)\n", param.Name, value)
+		} else {
+			fmt.Fprintf(&g.buf, "\tif %s {\n\t\tquery.Set(%q, %s)\n\t}\n", isSet, param.Name, value)
+		}
+	}
+}
+
+// responseType writes the struct of a binary response with documented headers
+func (g *generator) responseType(name string, headers map[string]*api.Header) {
+	fmt.Fprintf(&g.buf, "\n// %sResponse is the response of %s\ntype %sResponse struct {\n\tContentType string\n", name, name, name)
+	for _, header := range sortedKeys(headers) {
+		fmt.Fprintf(&g.buf, "\t%s string", headerField(header))
+		if desc := headers[header].Description; desc != "" {
+			fmt.Fprintf(&g.buf, " // %s (%s header)", desc, header)
+		}
+		g.buf.WriteString("\n")
+	}
+	g.buf.WriteString("\tContent []byte\n}\n")
+}
+
+// paramType returns the Go type of a query parameter
+func (g *generator) paramType(schema *api.Schema) (string, error) {
+	switch schema.Type {
+	case "string":
+		if schema.Format == "date-time" {
+			g.imports["time"] = "time"
+			return "time.Time", nil
+		}
+		return "string", nil
+	case "integer":
+		return "int", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "", fmt.Errorf("unsupported parameter type %q", schema.Type)
+}
+
+// goType returns the Go type of a body schema, importing its package
+func (g *generator) goType(schema *api.Schema) (string, error) {
+	if schema == nil {
+		return "", fmt.Errorf("missing schema")
+	}
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		component := g.doc.Components.Schemas[name]
+		if component == nil {
+			return "", fmt.Errorf("unknown schema %s", schema.Ref)
+		}
+		return g.namedType(component)
+	}
+	if schema.GoType != "" {
+		return g.namedType(schema)
+	}
+
+	switch schema.Type {
+	case "array":
+		items, err := g.goType(schema.Items)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + items, nil
+	case "object":
+		return "map[string]any", nil
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["time"] = "time"
+			return "time.Time", nil
+		case "byte", "binary":
+			return "[]byte", nil
+		}
+		return "string", nil
+	case "integer":
+		if schema.Format == "int64" {
+			return "int64", nil
+		}
+		return "int", nil
+	case "number":
+		return "float64", nil
+	case "boolean":
+		return "bool", nil
+	}
+	return "", fmt.Errorf("unsupported schema type %q", schema.Type)
+}
+
+// namedType returns the package qualified name of a schema's x-go-type
+func (g *generator) namedType(schema *api.Schema) (string, error) {
+	dot := strings.LastIndex(schema.GoType, ".")
+	if dot <= 0 {
+		return "", fmt.Errorf("schema has no x-go-type")
+	}
+	importPath, typeName := schema.GoType[:dot], schema.GoType[dot+1:]
+	pkg := path.Base(importPath)
+	for other, name := range g.imports {
+		if name == pkg && other != importPath {
+			return "", fmt.Errorf("packages %s and %s are both named %s", other, importPath, pkg)
+		}
+	}
+	g.imports[importPath] = pkg
+	return pkg + "." + typeName, nil
+}
+
+// successResponse returns the lowest 2xx response
+func successResponse(responses map[string]*api.Response) *api.Response {
+	for _, code := range sortedKeys(responses) {
+		if strings.HasPrefix(code, "2") {
+			return responses[code]
+		}
+	}
+	return nil
+}
+
+// responseKind returns how a response body is read and its schema:
+// JSON, NDJSON, empty ("") or any other content type, read as bytes
+func responseKind(resp *api.Response) (string, *api.Schema) {
+	if len(resp.Content) == 0 {
+		return "", nil
+	}
+	for _, kind := range []string{contentJSON, contentNDJSON} {
+		if media := resp.Content[kind]; media != nil {
+			return kind, media.Schema
+		}
+	}
+	return "binary", nil
+}
+
+// routeExpr returns the Go expression of a route with path parameters filled in
+func routeExpr(route string, params []api.Parameter) string {
+	parts := []string{}
+	rest := route
+	for _, param := range params {
+		placeholder := "{" + param.Name + "}"
+		before, after, found := strings.Cut(rest, placeholder)
+		if !found {
+			continue
+		}
+		if before != "" {
+			parts = append(parts, fmt.Sprintf("%q", before))
+		}
+		parts = append(parts, local(param.Name))
+		rest = after
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("%q", rest))
+	}
+	return strings.Join(parts, "+")
+}
+
+// headerField returns the response field of a header, dropping an X- prefix
+func headerField(header string) string {
+	return exported(strings.TrimPrefix(header, "X-"))
+}
+
+// exported converts a name such as limit, operationId or Memento-Datetime
+// to an exported Go name
+func exported(name string) string {
+	var b strings.Builder
+	for _, word := range words(name) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		runes := []rune(word)
+		b.WriteRune(unicode.ToUpper(runes[0]))
+		b.WriteString(string(runes[1:]))
+	}
+	return b.String()
+}
+
+// local converts a parameter name to an unexported Go name
+func local(name string) string {
+	ws := words(name)
+	if len(ws) == 0 {
+		return "param"
+	}
+	name = strings.ToLower(ws[0]) + exported(strings.Join(ws[1:], "-"))
+	if reserved[name] {
+		name += "Param"
+	}
+	return name
+}
+
+// words splits a name at separators and lower-to-upper case changes
+func words(name string) []string {
+	var out []string
+	var current []rune
+	flush := func() {
+		if len(current) > 0 {
+			out = append(out, string(current))
+			current = nil
+		}
+	}
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush()
+			current = append(current, r)
+		default:
+			current = append(current, r)
+		}
+	}
+	flush()
+	return out
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
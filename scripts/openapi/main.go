@@ -0,0 +1,65 @@
+// Command openapi writes the OpenAPI document of the golwarc HTTP API, or
+// generates the API client from a written document
+// Usage:
+//
+//	go run ./scripts/openapi > docs/openapi.json
+//	go run ./scripts/openapi -client docs/openapi.json -out api/client/client_gen.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/scripts/openapi/clientgen"
+)
+
+func main() {
+	spec := flag.String("client", "", "Generate the api/client methods from this OpenAPI document")
+	out := flag.String("out", "", "File the generated client is written to (default stdout)")
+	flag.Parse()
+
+	if *spec != "" {
+		if err := generateClient(*spec, *out); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to generate client: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(
+		api.NewArchiveHandler(nil),
+		api.NewExportHandler(nil),
+		api.NewCrawlHandler(api.CrawlHandlerConfig{}),
+	)
+
+	if err := server.WriteOpenAPI(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write OpenAPI document: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generateClient writes the client methods of the document at spec to out
+func generateClient(spec, out string) error {
+	data, err := os.ReadFile(spec)
+	if err != nil {
+		return err
+	}
+	var doc api.OpenAPIDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+
+	src, err := clientgen.Generate(&doc, "client")
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/storage"
+	"github.com/alonecandies/golwarc/warc"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ArchiveService runs WARC and object storage maintenance
+// Page references and dedup digests of compacted WARC records are rewritten
+// before the old files are removed
+type ArchiveService struct {
+	logger    *zap.Logger
+	db        database.DatabaseClient
+	digests   *warc.MemoryDigestStore
+	lifecycle *storage.Lifecycle
+}
+
+// NewArchiveService creates a new archive maintenance service
+func NewArchiveService(logger *zap.Logger, dbClient database.DatabaseClient, config storage.LifecycleConfig) *ArchiveService {
+	s := &ArchiveService{
+		logger: logger,
+		db:     dbClient,
+	}
+	if config.OnRelocate == nil {
+		config.OnRelocate = s.relocate
+	}
+	s.lifecycle = storage.NewLifecycle(config)
+	return s
+}
+
+// SetDigestStore sets the dedup digest store kept in step with compaction
+func (s *ArchiveService) SetDigestStore(store *warc.MemoryDigestStore) {
+	s.digests = store
+}
+
+// RunMaintenance performs a single compaction, reindex and tiering pass
+func (s *ArchiveService) RunMaintenance() (storage.LifecycleReport, error) {
+	report, err := s.lifecycle.RunOnce()
+	if err != nil {
+		return report, fmt.Errorf("archive maintenance failed: %w", err)
+	}
+
+	s.logger.Info("Archive maintenance completed",
+		zap.Int("compacted_files", len(report.Compacted)),
+		zap.Int("relocated_records", report.Relocated),
+		zap.Int("reindexed_files", len(report.Reindexed)),
+		zap.Int("cold_objects", len(report.Transitioned)))
+	return report, nil
+}
+
+// Start runs maintenance every interval until the context is cancelled
+func (s *ArchiveService) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RunMaintenance(); err != nil {
+				s.logger.Error("Archive maintenance failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// relocate rewrites page body references and dedup digests to their compacted locations
+func (s *ArchiveService) relocate(relocations []warc.Relocation) error {
+	if s.db != nil {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			for _, r := range relocations {
+				err := tx.Model(&models.Page{}).
+					Where("body_codec = ? AND body_ref = ?", storage.CodecWARC, storage.FormatWARCRef(r.OldFile, r.OldOffset)).
+					Update("body_ref", storage.FormatWARCRef(r.NewFile, r.NewOffset)).Error
+				if err != nil {
+					return fmt.Errorf("failed to relocate %s#%d: %w", r.OldFile, r.OldOffset, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// New revisits must refer to the compacted copies
+	if s.digests != nil {
+		s.digests.Relocate(relocations)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/storage"
+	"go.uber.org/zap"
+)
+
+// AssetDownloaderConfig holds asset download settings
+type AssetDownloaderConfig struct {
+	MaxSize    int64         // Largest asset in bytes (default 10MB)
+	Types      []string      // Allowed media types or type/* wildcards (default image/* and application/pdf)
+	MaxPerPage int           // Assets fetched per page (default 50)
+	Timeout    time.Duration // Per-asset download timeout (default 30s)
+	Client     *http.Client  // Defaults to http.DefaultClient
+}
+
+// AssetDownloader fetches the images and files crawled pages reference and
+// keeps them in blob storage, recording each as an Asset of its page
+// Objects are keyed by SHA-256, so a file referenced by many pages is
+// stored once
+type AssetDownloader struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+	store  storage.ObjectStore
+	types  *crawlers.ContentTypeFilter
+	config AssetDownloaderConfig
+}
+
+// NewAssetDownloader creates an asset downloader writing to store
+// CrawlerService.Initialize migrates its table
+func NewAssetDownloader(logger *zap.Logger, dbClient database.DatabaseClient, store storage.ObjectStore, config AssetDownloaderConfig) *AssetDownloader {
+	if config.MaxSize <= 0 {
+		config.MaxSize = 10 << 20
+	}
+	if len(config.Types) == 0 {
+		config.Types = []string{"image/*", "application/pdf"}
+	}
+	if config.MaxPerPage <= 0 {
+		config.MaxPerPage = 50
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 30 * time.Second
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	return &AssetDownloader{
+		logger: logger,
+		db:     dbClient,
+		store:  store,
+		types:  crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{Allow: config.Types}),
+		config: config,
+	}
+}
+
+// Download fetches the assets at urls and links them to page, which must
+// already be saved. Assets that fail, are too large or have a type that is
+// not allowed are skipped and logged; the stored assets are returned
+func (d *AssetDownloader) Download(ctx context.Context, page *models.Page, urls []string) ([]models.Asset, error) {
+	if page == nil || page.ID == 0 {
+		return nil, errs.New(errs.CodeInvalidRequest, "assets can only be linked to a saved page")
+	}
+	if len(urls) > d.config.MaxPerPage {
+		d.logger.Debug("Too many assets, keeping the first ones",
+			zap.String("page", page.URL),
+			zap.Int("found", len(urls)),
+			zap.Int("max", d.config.MaxPerPage))
+		urls = urls[:d.config.MaxPerPage]
+	}
+
+	var assets []models.Asset
+	for _, u := range urls {
+		if ctx.Err() != nil {
+			return assets, ctx.Err()
+		}
+		asset, err := d.fetch(ctx, u)
+		if err != nil {
+			if errs.HasCode(err, errs.CodeSkippedContent) || errs.HasCode(err, errs.CodeBodyTooLarge) {
+				d.logger.Debug("Asset skipped", append(errs.Fields(err), zap.String("asset", u))...)
+			} else {
+				d.logger.Warn("Failed to download asset", append(errs.Fields(err), zap.String("asset", u))...)
+			}
+			continue
+		}
+		asset.PageID = page.ID
+		assets = append(assets, *asset)
+	}
+	if len(assets) == 0 {
+		return nil, nil
+	}
+
+	if err := d.db.Create(&assets); err != nil {
+		return nil, fmt.Errorf("failed to save assets: %w", err)
+	}
+	return assets, nil
+}
+
+// fetch downloads one asset, checks its limits and stores its content
+func (d *AssetDownloader) fetch(ctx context.Context, rawURL string) (*models.Asset, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, errs.Newf(errs.CodeInvalidURL, "invalid asset URL %s: %v", rawURL, err)
+	}
+	resp, err := d.config.Client.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err, errs.CodeFetchFailed, "failed to fetch asset")
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+	if resp.StatusCode >= 400 {
+		return nil, errs.Newf(errs.CodeFetchFailed, "asset %s returned status %d", rawURL, resp.StatusCode)
+	}
+	if resp.ContentLength > d.config.MaxSize {
+		return nil, errs.Newf(errs.CodeBodyTooLarge, "asset %s exceeds %d bytes", rawURL, d.config.MaxSize)
+	}
+
+	// Sniff the type of responses without a Content-Type header
+	body := bufio.NewReader(resp.Body)
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		head, _ := body.Peek(512) // Short reads are fine for sniffing
+		contentType = http.DetectContentType(head)
+	}
+	if !d.types.Allowed(contentType) {
+		return nil, &crawlers.SkippedContentError{URL: rawURL, ContentType: contentType}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, d.config.MaxSize+1))
+	if err != nil {
+		return nil, errs.Wrap(err, errs.CodeFetchFailed, "failed to read asset")
+	}
+	if int64(len(data)) > d.config.MaxSize {
+		return nil, errs.Newf(errs.CodeBodyTooLarge, "asset %s exceeds %d bytes", rawURL, d.config.MaxSize)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	key := "assets/" + hash[:2] + "/" + hash
+	exists, err := d.store.Exists(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check object %s: %w", key, err)
+	}
+	if !exists {
+		if err := d.store.Put(key, data); err != nil {
+			return nil, fmt.Errorf("failed to store object %s: %w", key, err)
+		}
+	}
+
+	return &models.Asset{
+		URL:         rawURL,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hash,
+		StorageKey:  key,
+	}, nil
+}
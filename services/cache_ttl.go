@@ -0,0 +1,196 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+	"github.com/alonecandies/golwarc/models"
+)
+
+// Page kinds TTLRule.Kind matches, from the record extracted with a page
+const (
+	PageKindPage    = "page"
+	PageKindArticle = "article"
+	PageKindProduct = "product"
+)
+
+// TTLRule sets the cache TTL of matching pages; empty fields match any page
+type TTLRule struct {
+	ContentType string        // Media type such as text/html, or a type/* wildcard
+	Kind        string        // page, article or product
+	Pattern     string        // urlmatch pattern, e.g. news.example.com or */docs/
+	TTL         time.Duration // Required
+}
+
+// TTLPolicyConfig holds page cache TTL settings
+type TTLPolicyConfig struct {
+	Default time.Duration // TTL of pages no rule matches (default 24h)
+	Rules   []TTLRule     // The first matching rule wins
+
+	// Adaptive caches a page for ChangeFactor of the interval it was seen
+	// to change at, clamped to MinTTL and MaxTTL, once it changed at least
+	// once; until then the rules apply
+	Adaptive     bool
+	ChangeFactor float64       // Default 0.5
+	MinTTL       time.Duration // Default 5m
+	MaxTTL       time.Duration // Default 30 days
+	Clock        clock.Clock   // Defaults to the wall clock
+}
+
+// TTLPolicy picks how long CrawlerService caches a page: by rules on its
+// content type, kind and URL (news pages 1h, static docs 7d), and
+// optionally by how often the page was seen to change
+//
+// Change observations are kept in the cache next to the pages, so workers
+// sharing a Redis cache learn together
+type TTLPolicy struct {
+	rules  []ttlRule
+	config TTLPolicyConfig
+	cache  cache.JSONCacheClient
+	clock  clock.Clock
+}
+
+// ttlRule is a TTLRule with its matchers compiled
+type ttlRule struct {
+	TTLRule
+	types   *crawlers.ContentTypeFilter // nil matches any type
+	pattern *urlmatch.RuleSet           // nil matches any URL
+}
+
+// pageChanges is the change history of a page, stored under its change key
+type pageChanges struct {
+	Hash      string        `json:"hash"`
+	ChangedAt time.Time     `json:"changed_at"`         // When Hash was first seen
+	Interval  time.Duration `json:"interval,omitempty"` // Smoothed interval between changes; zero until a change is seen
+}
+
+// NewTTLPolicy compiles the rules; observations are kept in cacheClient
+// when Adaptive is set
+func NewTTLPolicy(cacheClient cache.JSONCacheClient, config TTLPolicyConfig) (*TTLPolicy, error) {
+	if config.Default <= 0 {
+		config.Default = 24 * time.Hour
+	}
+	if config.ChangeFactor <= 0 {
+		config.ChangeFactor = 0.5
+	}
+	if config.MinTTL <= 0 {
+		config.MinTTL = 5 * time.Minute
+	}
+	if config.MaxTTL <= 0 {
+		config.MaxTTL = 30 * 24 * time.Hour
+	}
+
+	p := &TTLPolicy{config: config, cache: cacheClient, clock: clock.Or(config.Clock)}
+	for i, rule := range config.Rules {
+		if rule.TTL <= 0 {
+			return nil, fmt.Errorf("TTL rule %d: ttl must be positive", i+1)
+		}
+		compiled := ttlRule{TTLRule: rule}
+		if rule.ContentType != "" {
+			compiled.types = crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{Allow: []string{rule.ContentType}})
+		}
+		if rule.Pattern != "" {
+			rs, err := urlmatch.Compile([]urlmatch.Rule{{Pattern: rule.Pattern, Action: urlmatch.Allow}})
+			if err != nil {
+				return nil, fmt.Errorf("TTL rule %d: %w", i+1, err)
+			}
+			compiled.pattern = rs
+		}
+		p.rules = append(p.rules, compiled)
+	}
+	return p, nil
+}
+
+// NewTTLPolicyFromConfig creates a TTL policy from application config
+func NewTTLPolicyFromConfig(cacheClient cache.JSONCacheClient, config configs.CacheTTLConfig) (*TTLPolicy, error) {
+	rules := make([]TTLRule, len(config.Rules))
+	for i, rule := range config.Rules {
+		rules[i] = TTLRule{
+			ContentType: rule.ContentType,
+			Kind:        rule.Kind,
+			Pattern:     rule.Pattern,
+			TTL:         time.Duration(rule.TTL) * time.Second,
+		}
+	}
+	return NewTTLPolicy(cacheClient, TTLPolicyConfig{
+		Default:  time.Duration(config.Default) * time.Second,
+		Rules:    rules,
+		Adaptive: config.Adaptive,
+		MinTTL:   time.Duration(config.MinTTL) * time.Second,
+		MaxTTL:   time.Duration(config.MaxTTL) * time.Second,
+	})
+}
+
+// TTL returns how long to cache page, fetched with the given Content-Type
+// header and kind, and records its body for change tracking. A nil policy
+// returns 24h
+func (p *TTLPolicy) TTL(page *models.Page, contentType, kind string) time.Duration {
+	if p == nil {
+		return 24 * time.Hour
+	}
+	if p.config.Adaptive && p.cache != nil {
+		if interval := p.observe(page.URL, ContentHash(page.HTML)); interval > 0 {
+			ttl := time.Duration(float64(interval) * p.config.ChangeFactor)
+			return min(max(ttl, p.config.MinTTL), p.config.MaxTTL)
+		}
+	}
+	return p.ruleTTL(page.URL, contentType, kind)
+}
+
+// ruleTTL returns the TTL of the first matching rule, or the default
+func (p *TTLPolicy) ruleTTL(url, contentType, kind string) time.Duration {
+	for _, rule := range p.rules {
+		if rule.Kind != "" && rule.Kind != kind {
+			continue
+		}
+		if rule.types != nil && (contentType == "" || !rule.types.Allowed(contentType)) {
+			continue
+		}
+		if rule.pattern != nil && rule.pattern.Match(url) == nil {
+			continue
+		}
+		return rule.TTL
+	}
+	return p.config.Default
+}
+
+// observe records hash as the current body of url and returns the interval
+// the page changes at, or zero while unknown. Cache failures only lose the
+// observation
+func (p *TTLPolicy) observe(url, hash string) time.Duration {
+	key := "page-changes:" + url
+	now := p.clock.Now()
+
+	var changes pageChanges
+	if err := p.cache.GetJSON(key, &changes); err != nil || changes.Hash == "" {
+		changes = pageChanges{Hash: hash, ChangedAt: now}
+	} else if changes.Hash != hash {
+		interval := now.Sub(changes.ChangedAt)
+		if changes.Interval > 0 {
+			interval = (changes.Interval + interval) / 2
+		}
+		changes = pageChanges{Hash: hash, ChangedAt: now, Interval: interval}
+	} else if changes.Interval > 0 {
+		// Unchanged for longer than expected: the page changes less often
+		changes.Interval = max(changes.Interval, now.Sub(changes.ChangedAt))
+	}
+
+	_ = p.cache.SetJSON(key, changes, 4*p.config.MaxTTL) // Error intentionally ignored; the TTL falls back to the rules
+	return changes.Interval
+}
+
+// pageKind names the kind of record extracted with a page
+func pageKind(extracted interface{}) string {
+	switch extracted.(type) {
+	case *models.Article:
+		return PageKindArticle
+	case *models.Product:
+		return PageKindProduct
+	}
+	return PageKindPage
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// CertificateMonitorConfig holds TLS certificate monitoring settings
+type CertificateMonitorConfig struct {
+	ExpiryWarning time.Duration  // Certificates expiring within this are alerted on (default 14 days)
+	CheckEvery    time.Duration  // Least time between two checks of a host (default 24h)
+	Timeout       time.Duration  // TLS handshake timeout (default 10s)
+	RootCAs       *x509.CertPool // Roots certificates are verified against; defaults to the system pool
+
+	// OnExpiring is called for every checked certificate that expires within
+	// ExpiryWarning or has expired, e.g. to page someone. Such certificates
+	// are logged as warnings either way
+	OnExpiring func(cert models.DomainCertificate)
+
+	Clock clock.Clock // Defaults to the wall clock
+}
+
+// CertificateMonitor records the TLS certificates of crawled https hosts in
+// the domain_certificates table and alerts on ones about to expire
+type CertificateMonitor struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+	config CertificateMonitorConfig
+
+	mu      sync.Mutex
+	checked map[string]time.Time // Host -> last check
+}
+
+// NewCertificateMonitor creates a certificate monitor
+// CrawlerService.Initialize migrates its table
+func NewCertificateMonitor(logger *zap.Logger, dbClient database.DatabaseClient, config CertificateMonitorConfig) *CertificateMonitor {
+	if config.ExpiryWarning <= 0 {
+		config.ExpiryWarning = 14 * 24 * time.Hour
+	}
+	if config.CheckEvery <= 0 {
+		config.CheckEvery = 24 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	config.Clock = clock.Or(config.Clock)
+	return &CertificateMonitor{
+		logger:  logger,
+		db:      dbClient,
+		config:  config,
+		checked: make(map[string]time.Time),
+	}
+}
+
+// Check records the certificate of the host of an https URL
+// Hosts checked less than CheckEvery ago and http URLs are skipped and
+// return nil. Certificates are recorded even when they do not verify
+func (m *CertificateMonitor) Check(ctx context.Context, rawURL string) (*models.DomainCertificate, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return nil, nil
+	}
+	host := strings.ToLower(u.Host)
+	addr := host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(host, "443")
+	} else if u.Port() == "443" {
+		host = strings.ToLower(u.Hostname())
+	}
+
+	now := m.config.Clock.Now()
+	m.mu.Lock()
+	if last, ok := m.checked[host]; ok && now.Sub(last) < m.config.CheckEvery {
+		m.mu.Unlock()
+		return nil, nil
+	}
+	m.checked[host] = now
+	m.mu.Unlock()
+
+	cert, err := m.fetch(ctx, addr, u.Hostname())
+	if err != nil {
+		// Allow another attempt on the next crawl
+		m.mu.Lock()
+		delete(m.checked, host)
+		m.mu.Unlock()
+		return nil, err
+	}
+	cert.Host = host
+	cert.CheckedAt = now
+
+	err = m.db.GetDB().WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "host"}},
+			DoUpdates: clause.AssignmentColumns([]string{"subject", "issuer", "dns_names", "serial_number", "fingerprint", "not_before", "not_after", "verified", "verify_error", "checked_at", "updated_at"}),
+		}).
+		Create(cert).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to save certificate of %s: %w", host, err)
+	}
+
+	if remaining := cert.NotAfter.Sub(now); remaining < m.config.ExpiryWarning {
+		m.logger.Warn("TLS certificate expiring",
+			zap.String("host", host),
+			zap.Time("not_after", cert.NotAfter),
+			zap.Duration("remaining", remaining),
+			zap.String("issuer", cert.Issuer))
+		if m.config.OnExpiring != nil {
+			m.config.OnExpiring(*cert)
+		}
+	}
+	return cert, nil
+}
+
+// fetch runs a TLS handshake with addr and describes the leaf certificate
+func (m *CertificateMonitor) fetch(ctx context.Context, addr, serverName string) (*models.DomainCertificate, error) {
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: m.config.Timeout},
+		// Verified below, so invalid certificates are still recorded
+		Config: &tls.Config{ServerName: serverName, InsecureSkipVerify: true},
+	}
+	ctx, cancel := context.WithTimeout(ctx, m.config.Timeout)
+	defer cancel()
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("TLS handshake with %s failed: %w", addr, err)
+	}
+	defer func() {
+		_ = conn.Close() // Error intentionally ignored on close
+	}()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	if len(state.PeerCertificates) == 0 {
+		return nil, fmt.Errorf("%s presented no certificate", addr)
+	}
+	leaf := state.PeerCertificates[0]
+	fingerprint := sha256.Sum256(leaf.Raw)
+	cert := &models.DomainCertificate{
+		Subject:      leaf.Subject.String(),
+		Issuer:       leaf.Issuer.String(),
+		DNSNames:     strings.Join(leaf.DNSNames, ","),
+		SerialNumber: leaf.SerialNumber.Text(16),
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+		NotBefore:    leaf.NotBefore,
+		NotAfter:     leaf.NotAfter,
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range state.PeerCertificates[1:] {
+		intermediates.AddCert(c)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         m.config.RootCAs,
+		Intermediates: intermediates,
+		CurrentTime:   m.config.Clock.Now(),
+	})
+	cert.Verified = err == nil
+	if err != nil {
+		cert.VerifyError = err.Error()
+	}
+	return cert, nil
+}
+
+// Expiring lists recorded certificates that expire within the given time
+// or have expired, soonest first
+func (m *CertificateMonitor) Expiring(ctx context.Context, within time.Duration) ([]models.DomainCertificate, error) {
+	var certs []models.DomainCertificate
+	err := m.db.GetDB().WithContext(ctx).
+		Where("not_after < ?", m.config.Clock.Now().Add(within)).
+		Order("not_after").
+		Find(&certs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load expiring certificates: %w", err)
+	}
+	return certs, nil
+}
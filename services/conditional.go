@@ -0,0 +1,261 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"gorm.io/gorm"
+)
+
+// Validators are the HTTP cache validators of a fetched URL
+type Validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ValidatorsFrom reads the ETag and Last-Modified response headers
+func ValidatorsFrom(header http.Header) Validators {
+	return Validators{
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+}
+
+// IsZero reports whether there is nothing to revalidate with
+func (v Validators) IsZero() bool {
+	return v.ETag == "" && v.LastModified == ""
+}
+
+// Apply sets If-None-Match and If-Modified-Since on a request header
+func (v Validators) Apply(header http.Header) {
+	if v.ETag != "" {
+		header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// normalizeURL lowercases the host and adds the root path the way Colly
+// normalizes request URLs, so a request can be matched to the crawled URL
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Host = strings.ToLower(u.Host)
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	return u.String()
+}
+
+// ValidatorStore persists validators per project and URL
+type ValidatorStore interface {
+	// LoadValidators returns the stored validators, or zero Validators if there are none
+	LoadValidators(project, url string) (Validators, error)
+
+	// SaveValidators stores validators, replacing any earlier ones
+	SaveValidators(project, url string, validators Validators) error
+}
+
+// CacheValidatorStore keeps validators in a shared cache such as Redis
+type CacheValidatorStore struct {
+	client cache.JSONCacheClient
+	ttl    time.Duration
+}
+
+// NewCacheValidatorStore creates a validator store whose entries expire after ttl
+// A zero ttl keeps them until they are overwritten
+func NewCacheValidatorStore(client cache.JSONCacheClient, ttl time.Duration) *CacheValidatorStore {
+	return &CacheValidatorStore{client: client, ttl: ttl}
+}
+
+// validatorKey returns the cache key of a URL's validators
+func validatorKey(project, url string) string {
+	return fmt.Sprintf("validators:%s:%s", project, url)
+}
+
+// LoadValidators returns the stored validators, or zero Validators if the key does not exist
+func (s *CacheValidatorStore) LoadValidators(project, url string) (Validators, error) {
+	var validators Validators
+	err := s.client.GetJSON(validatorKey(project, url), &validators)
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return Validators{}, nil
+	}
+	if err != nil {
+		return Validators{}, fmt.Errorf("failed to load validators: %w", err)
+	}
+	return validators, nil
+}
+
+// SaveValidators stores validators
+func (s *CacheValidatorStore) SaveValidators(project, url string, validators Validators) error {
+	if err := s.client.SetJSON(validatorKey(project, url), validators, s.ttl); err != nil {
+		return fmt.Errorf("failed to save validators: %w", err)
+	}
+	return nil
+}
+
+// DBValidatorStore keeps validators in the url_validators table
+//
+// Deprecated: validators are stored on pages; use PageValidatorStore.
+// The first CrawlerService.Initialize copies existing rows onto their pages
+type DBValidatorStore struct {
+	db database.DatabaseClient
+}
+
+// NewDBValidatorStore creates a validator store backed by the database
+// CrawlerService.Initialize migrates its table
+func NewDBValidatorStore(dbClient database.DatabaseClient) *DBValidatorStore {
+	return &DBValidatorStore{db: dbClient}
+}
+
+// LoadValidators returns the stored validators, or zero Validators if there is no row
+func (s *DBValidatorStore) LoadValidators(project, url string) (Validators, error) {
+	var row models.URLValidator
+	err := s.db.GetDB().
+		Where("project = ? AND url = ?", project, url).
+		First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Validators{}, nil
+	}
+	if err != nil {
+		return Validators{}, fmt.Errorf("failed to load validators: %w", err)
+	}
+	return Validators{ETag: row.ETag, LastModified: row.LastModified}, nil
+}
+
+// SaveValidators inserts or updates the row for project and url
+func (s *DBValidatorStore) SaveValidators(project, url string, validators Validators) error {
+	var row models.URLValidator
+	err := s.db.GetDB().
+		Where(models.URLValidator{Project: project, URL: url}).
+		Assign(map[string]interface{}{"etag": validators.ETag, "last_modified": validators.LastModified}).
+		FirstOrCreate(&row).Error
+	if err != nil {
+		return fmt.Errorf("failed to save validators: %w", err)
+	}
+	return nil
+}
+
+// PageValidatorStore keeps validators in the etag and last_modified columns
+// of the stored page, so they last as long as the page does
+type PageValidatorStore struct {
+	db database.DatabaseClient
+}
+
+// NewPageValidatorStore creates a validator store backed by the pages table
+func NewPageValidatorStore(dbClient database.DatabaseClient) *PageValidatorStore {
+	return &PageValidatorStore{db: dbClient}
+}
+
+// LoadValidators returns the validators of the stored page, or zero
+// Validators if the page was never stored
+func (s *PageValidatorStore) LoadValidators(project, url string) (Validators, error) {
+	var page models.Page
+	err := s.db.GetDB().
+		Select("etag", "last_modified").
+		Where("project = ? AND url = ?", project, url).
+		First(&page).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Validators{}, nil
+	}
+	if err != nil {
+		return Validators{}, fmt.Errorf("failed to load validators: %w", err)
+	}
+	return Validators{ETag: page.ETag, LastModified: page.LastModified}, nil
+}
+
+// SaveValidators updates the stored page; without one there is nothing to
+// revalidate and the validators are dropped
+func (s *PageValidatorStore) SaveValidators(project, url string, validators Validators) error {
+	err := s.db.GetDB().
+		Model(&models.Page{}).
+		Where("project = ? AND url = ?", project, url).
+		Updates(map[string]interface{}{"etag": validators.ETag, "last_modified": validators.LastModified}).Error
+	if err != nil {
+		return fmt.Errorf("failed to save validators: %w", err)
+	}
+	return nil
+}
+
+// TieredValidatorStore reads validators from a cache and falls back to a
+// durable store after the cache is flushed or its entries expire
+type TieredValidatorStore struct {
+	cache   ValidatorStore
+	durable ValidatorStore
+}
+
+// NewTieredValidatorStore creates a store that saves to both cache and
+// durable, e.g. a CacheValidatorStore and a PageValidatorStore
+func NewTieredValidatorStore(cache, durable ValidatorStore) *TieredValidatorStore {
+	return &TieredValidatorStore{cache: cache, durable: durable}
+}
+
+// LoadValidators returns the cached validators, else the durable ones,
+// which are cached again. An unavailable cache is skipped
+func (s *TieredValidatorStore) LoadValidators(project, url string) (Validators, error) {
+	validators, err := s.cache.LoadValidators(project, url)
+	if err == nil && !validators.IsZero() {
+		return validators, nil
+	}
+
+	validators, err = s.durable.LoadValidators(project, url)
+	if err != nil || validators.IsZero() {
+		return validators, err
+	}
+	_ = s.cache.SaveValidators(project, url, validators) // Best effort; the durable copy stays
+	return validators, nil
+}
+
+// SaveValidators stores validators in the durable store, then the cache
+func (s *TieredValidatorStore) SaveValidators(project, url string, validators Validators) error {
+	if err := s.durable.SaveValidators(project, url, validators); err != nil {
+		return err
+	}
+	return s.cache.SaveValidators(project, url, validators)
+}
+
+// backfillPageValidatorsSQL copies url_validators rows onto pages that have
+// no validators of their own
+const backfillPageValidatorsSQL = `UPDATE pages SET
+	etag = (SELECT v.etag FROM url_validators v WHERE v.project = pages.project AND v.url = pages.url),
+	last_modified = (SELECT v.last_modified FROM url_validators v WHERE v.project = pages.project AND v.url = pages.url)
+WHERE etag = '' AND last_modified = ''
+	AND EXISTS (SELECT 1 FROM url_validators v WHERE v.project = pages.project AND v.url = pages.url)`
+
+// backfillPageValidatorsMigration names the backfill in schema_migrations
+const backfillPageValidatorsMigration = "backfill_page_validators"
+
+// BackfillPageValidators copies the validators of the url_validators table
+// onto pages stored before pages kept them, returning the pages updated.
+// It runs once per database: later calls find its schema_migrations row and
+// return 0 without touching pages
+func BackfillPageValidators(dbClient database.DatabaseClient) (int64, error) {
+	var updated int64
+	err := runMigrationOnce(dbClient, backfillPageValidatorsMigration, func(tx *gorm.DB) error {
+		result := tx.Exec(backfillPageValidatorsSQL)
+		updated = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to backfill page validators: %w", err)
+	}
+	return updated, nil
+}
+
+// Ensure the stores implement the ValidatorStore interface
+var (
+	_ ValidatorStore = (*CacheValidatorStore)(nil)
+	_ ValidatorStore = (*DBValidatorStore)(nil)
+	_ ValidatorStore = (*PageValidatorStore)(nil)
+	_ ValidatorStore = (*TieredValidatorStore)(nil)
+)
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/libs"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// CorpusService stores page bodies in a corpus shared across projects
+// Identical bodies are stored once and referenced by their SHA-256 hash
+type CorpusService struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+}
+
+// NewCorpusService creates a new shared corpus service
+func NewCorpusService(logger *zap.Logger, dbClient database.DatabaseClient) *CorpusService {
+	return &CorpusService{
+		logger: logger,
+		db:     dbClient,
+	}
+}
+
+// ContentHash returns the hex-encoded SHA-256 hash of a page body
+func ContentHash(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store moves the page HTML into the shared corpus and sets page.ContentHash
+// The body is only inserted when no other page already references the same hash
+func (s *CorpusService) Store(page *models.Page) error {
+	return s.StoreContext(context.Background(), page)
+}
+
+// StoreContext is Store with log lines tagged by the crawl ID of ctx
+func (s *CorpusService) StoreContext(ctx context.Context, page *models.Page) error {
+	if page.HTML == "" {
+		return nil
+	}
+
+	hash := ContentHash(page.HTML)
+	content := models.PageContent{
+		Hash: hash,
+		Body: page.HTML,
+		Size: int64(len(page.HTML)),
+	}
+
+	// Insert and ignore a duplicate hash in one statement, so concurrent
+	// stores of the same body cannot race between a lookup and the insert
+	result := s.db.GetDB().WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&content)
+	if result.Error != nil {
+		return fmt.Errorf("failed to store content %s: %w", hash, result.Error)
+	}
+
+	if result.RowsAffected == 0 {
+		libs.LoggerFrom(ctx, s.logger).Debug("Content already in shared corpus",
+			zap.String("url", page.URL),
+			zap.String("hash", hash))
+	}
+
+	page.ContentHash = hash
+	page.HTML = ""
+	return nil
+}
+
+// Load restores the page HTML from the shared corpus
+func (s *CorpusService) Load(page *models.Page) error {
+	if page.ContentHash == "" || page.HTML != "" {
+		return nil
+	}
+
+	var content models.PageContent
+	if err := s.db.First(&content, "hash = ?", page.ContentHash); err != nil {
+		return fmt.Errorf("failed to load content %s: %w", page.ContentHash, err)
+	}
+
+	page.HTML = content.Body
+	return nil
+}
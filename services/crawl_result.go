@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/models"
+)
+
+// Crawl stages a CrawlError can report
+const (
+	StageFetch   = "fetch"   // The request failed or the URL was skipped
+	StageExtract = "extract" // The response held no page, or extraction rules failed
+	StageCache   = "cache"   // Reading or writing the page cache failed; never fatal
+	StageStore   = "store"   // Saving the body or the page failed
+)
+
+// CrawlError is a crawl failure tagged with the stage it happened in
+type CrawlError struct {
+	URL   string
+	Stage string
+	Err   error
+}
+
+// Error implements the error interface
+func (e *CrawlError) Error() string {
+	return fmt.Sprintf("crawl of %s failed at %s: %v", e.URL, e.Stage, e.Err)
+}
+
+// Unwrap returns the cause
+func (e *CrawlError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode implements errs.Coder
+// The cause's code wins, so skipped URLs keep GOLWARC-CRAWL-008/010; an
+// uncoded cause gets the code of its stage
+func (e *CrawlError) ErrorCode() errs.Code {
+	if code := errs.CodeOf(e.Err); code != errs.CodeInternal {
+		return code
+	}
+	switch e.Stage {
+	case StageFetch:
+		return errs.CodeFetchFailed
+	case StageExtract:
+		return errs.CodeExtractFailed
+	case StageCache:
+		return errs.CodeCacheUnavailable
+	case StageStore:
+		return errs.CodeStoreFailed
+	default:
+		return errs.CodeInternal
+	}
+}
+
+// StageOf returns the stage err failed at, or "" when it is not a CrawlError
+func StageOf(err error) string {
+	var crawlErr *CrawlError
+	if errors.As(err, &crawlErr) {
+		return crawlErr.Stage
+	}
+	return ""
+}
+
+// CrawlResult is the outcome of crawling one URL
+type CrawlResult struct {
+	URL      string
+	Page     *models.Page // Set once the page was fetched, even if storing it failed
+	Stored   bool         // The page was saved; false for cache hits, 304s, noindex and canonical duplicates
+	Err      error        // A *CrawlError, or nil
+	Warnings []error      // Non-fatal *CrawlErrors: cache failures and failed extraction rules
+
+	pending *fetchedPage // Kept so Retry can resume at the failed stage
+}
+
+// Stage returns the stage a retry has to start at: the stage of Err,
+// StageCache when only the cache write failed, or "" when nothing is left
+func (r CrawlResult) Stage() string {
+	if r.Err != nil {
+		return StageOf(r.Err)
+	}
+	if r.pending != nil && !r.pending.cached {
+		for _, warning := range r.Warnings {
+			if StageOf(warning) == StageCache {
+				return StageCache
+			}
+		}
+	}
+	return ""
+}
+
+// fetchedPage is what the fetch and extract stages produced for a URL, and
+// how far storing it got
+type fetchedPage struct {
+	url       string
+	cacheKey  string
+	page      *models.Page
+	extracted interface{} // *models.Product or *models.Article
+	feeds     []extractors.FeedLink
+	assetURLs []string
+	audit     *models.SecurityAudit
+	site      extractors.SiteMetadata
+	fresh     Validators
+	mediaType string        // Content-Type header of the response
+	ttl       time.Duration // How long the page is cached
+
+	bodyStored bool
+	saved      bool
+	cached     bool
+}
@@ -0,0 +1,234 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+)
+
+// defaultCrawlStatsWindow is how far back crawl statistics look by default
+const defaultCrawlStatsWindow = 24 * time.Hour
+
+// CrawlStatsQuery selects the crawl log entries statistics are computed from
+type CrawlStatsQuery struct {
+	Project string
+	Domain  string    // Only this domain; empty for all
+	Since   time.Time // Defaults to 24 hours ago
+}
+
+// DomainCrawlStats summarizes the crawls of one domain
+// Latency percentiles are nearest-rank over every crawl in the window
+type DomainCrawlStats struct {
+	Domain       string     `json:"domain"`
+	Crawls       int        `json:"crawls"`
+	Skipped      int        `json:"skipped"`    // URLs not fetched; see SkipStats for why
+	Errors       int        `json:"errors"`     // Failed fetches and 4xx/5xx responses
+	ErrorRate    float64    `json:"error_rate"` // 0 to 1
+	LatencyP50Ms int64      `json:"latency_p50_ms"`
+	LatencyP90Ms int64      `json:"latency_p90_ms"`
+	LatencyP95Ms int64      `json:"latency_p95_ms"`
+	LatencyP99Ms int64      `json:"latency_p99_ms"`
+	AvgPageBytes int64      `json:"avg_page_bytes"` // Over successful crawls that returned a body
+	LastCrawlAt  time.Time  `json:"last_crawl_at"`
+	LastSuccess  *time.Time `json:"last_success_at,omitempty"` // Latest successful crawl, also before the window
+}
+
+// CrawlStatsService computes per-domain statistics from the crawl_logs
+// table written by CrawlerService. Only plain SQL is used, so the log may
+// live in MySQL, PostgreSQL or ClickHouse
+type CrawlStatsService struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+}
+
+// NewCrawlStatsService creates a new crawl statistics service
+func NewCrawlStatsService(logger *zap.Logger, dbClient database.DatabaseClient) *CrawlStatsService {
+	return &CrawlStatsService{
+		logger: logger,
+		db:     dbClient,
+	}
+}
+
+// DomainStats returns statistics per crawled domain, busiest first
+func (s *CrawlStatsService) DomainStats(ctx context.Context, q CrawlStatsQuery) ([]DomainCrawlStats, error) {
+	if q.Since.IsZero() {
+		q.Since = time.Now().Add(-defaultCrawlStatsWindow)
+	}
+
+	query := s.db.GetDB().WithContext(ctx).
+		Model(&models.CrawlLog{}).
+		Select("domain", "status", "duration_ms", "bytes", "error", "skip_reason", "created_at").
+		Where("project = ? AND created_at >= ?", q.Project, q.Since)
+	if q.Domain != "" {
+		query = query.Where("domain = ?", strings.ToLower(q.Domain))
+	}
+
+	var logs []models.CrawlLog
+	if err := query.Find(&logs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load crawl logs: %w", err)
+	}
+
+	type accumulator struct {
+		stats     DomainCrawlStats
+		latencies []int64
+		bytes     int64
+		bodies    int
+	}
+	byDomain := make(map[string]*accumulator)
+	for _, l := range logs {
+		acc, ok := byDomain[l.Domain]
+		if !ok {
+			acc = &accumulator{stats: DomainCrawlStats{Domain: l.Domain}}
+			byDomain[l.Domain] = acc
+		}
+
+		if l.SkipReason != "" {
+			acc.stats.Skipped++
+			continue
+		}
+		acc.stats.Crawls++
+		acc.latencies = append(acc.latencies, l.DurationMs)
+		if l.CreatedAt.After(acc.stats.LastCrawlAt) {
+			acc.stats.LastCrawlAt = l.CreatedAt
+		}
+		if failedCrawl(l) {
+			acc.stats.Errors++
+		} else if l.Bytes > 0 {
+			acc.bytes += l.Bytes
+			acc.bodies++
+		}
+	}
+
+	lastSuccess, err := s.lastSuccess(ctx, q.Project, q.Domain)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]DomainCrawlStats, 0, len(byDomain))
+	for domain, acc := range byDomain {
+		st := acc.stats
+		if st.Crawls > 0 {
+			st.ErrorRate = float64(st.Errors) / float64(st.Crawls)
+		}
+		sort.Slice(acc.latencies, func(i, j int) bool { return acc.latencies[i] < acc.latencies[j] })
+		st.LatencyP50Ms = percentile(acc.latencies, 0.50)
+		st.LatencyP90Ms = percentile(acc.latencies, 0.90)
+		st.LatencyP95Ms = percentile(acc.latencies, 0.95)
+		st.LatencyP99Ms = percentile(acc.latencies, 0.99)
+		if acc.bodies > 0 {
+			st.AvgPageBytes = acc.bytes / int64(acc.bodies)
+		}
+		if at, ok := lastSuccess[domain]; ok {
+			st.LastSuccess = &at
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Crawls != stats[j].Crawls {
+			return stats[i].Crawls > stats[j].Crawls
+		}
+		return stats[i].Domain < stats[j].Domain
+	})
+
+	s.logger.Info("Crawl statistics computed",
+		zap.String("project", q.Project),
+		zap.Time("since", q.Since),
+		zap.Int("domains", len(stats)),
+		zap.Int("crawls", len(logs)))
+	return stats, nil
+}
+
+// SkipStats counts the URLs skipped for one reason and rule
+type SkipStats struct {
+	Reason        string    `json:"reason"`         // e.g. robots, url_rule, budget, duplicate, ssrf
+	Rule          string    `json:"rule,omitempty"` // What decided the skip, e.g. "deny /admin/*"
+	Count         int64     `json:"count"`
+	Domains       int64     `json:"domains"`    // Distinct domains with skipped URLs
+	SampleURL     string    `json:"sample_url"` // One of the skipped URLs
+	LastSkippedAt time.Time `json:"last_skipped_at"`
+}
+
+// SkipStats answers "why wasn't this page crawled?" in aggregate: the
+// skipped URLs in the window grouped by reason and rule, most frequent first
+func (s *CrawlStatsService) SkipStats(ctx context.Context, q CrawlStatsQuery) ([]SkipStats, error) {
+	if q.Since.IsZero() {
+		q.Since = time.Now().Add(-defaultCrawlStatsWindow)
+	}
+
+	query := s.db.GetDB().WithContext(ctx).
+		Model(&models.CrawlLog{}).
+		Select("skip_reason AS reason, skip_rule AS rule, COUNT(*) AS count, COUNT(DISTINCT domain) AS domains, "+
+			"MIN(url) AS sample_url, MAX(created_at) AS last_skipped_at").
+		Where("project = ? AND created_at >= ? AND skip_reason <> ?", q.Project, q.Since, "")
+	if q.Domain != "" {
+		query = query.Where("domain = ?", strings.ToLower(q.Domain))
+	}
+
+	var stats []SkipStats
+	if err := query.Group("skip_reason, skip_rule").Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to load skipped URLs: %w", err)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Count != stats[j].Count {
+			return stats[i].Count > stats[j].Count
+		}
+		if stats[i].Reason != stats[j].Reason {
+			return stats[i].Reason < stats[j].Reason
+		}
+		return stats[i].Rule < stats[j].Rule
+	})
+
+	s.logger.Info("Skip statistics computed",
+		zap.String("project", q.Project),
+		zap.Time("since", q.Since),
+		zap.Int("groups", len(stats)))
+	return stats, nil
+}
+
+// lastSuccess returns the time of the latest successful crawl per domain
+func (s *CrawlStatsService) lastSuccess(ctx context.Context, project, domain string) (map[string]time.Time, error) {
+	query := s.db.GetDB().WithContext(ctx).
+		Model(&models.CrawlLog{}).
+		Select("domain, MAX(created_at) AS last_success_at").
+		Where("project = ? AND error = ? AND status > 0 AND status < ?", project, "", http.StatusBadRequest)
+	if domain != "" {
+		query = query.Where("domain = ?", strings.ToLower(domain))
+	}
+
+	var rows []struct {
+		Domain        string
+		LastSuccessAt time.Time
+	}
+	if err := query.Group("domain").Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load last successful crawls: %w", err)
+	}
+
+	last := make(map[string]time.Time, len(rows))
+	for _, r := range rows {
+		last[r.Domain] = r.LastSuccessAt
+	}
+	return last, nil
+}
+
+// failedCrawl reports whether a crawl log entry is an error: the fetch
+// failed, the response was 4xx/5xx, or nothing was fetched
+func failedCrawl(l models.CrawlLog) bool {
+	return l.Error != "" || l.Status == 0 || l.Status >= http.StatusBadRequest
+}
+
+// percentile returns the nearest-rank percentile p (0 to 1) of sorted values
+func percentile(sorted []int64, p float64) int64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+	return sorted[max(rank, 0)]
+}
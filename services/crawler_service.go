@@ -1,23 +1,64 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	neturl "net/url"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/alonecandies/golwarc/cache"
 	"github.com/alonecandies/golwarc/crawlers"
 	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/storage"
 	"github.com/gocolly/colly/v2"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 // CrawlerService handles web crawling with caching and persistence
 type CrawlerService struct {
-	logger  *zap.Logger
-	cache   cache.JSONCacheClient
-	db      database.DatabaseClient
-	crawler crawlers.CrawlerClient
+	logger     *zap.Logger
+	cache      cache.JSONCacheClient
+	db         database.DatabaseClient
+	crawler    crawlers.CrawlerClient
+	project    string
+	corpus     *CorpusService
+	bodies     *storage.BodyStore
+	slo        *libs.SLOTracker
+	publisher  PagePublisher
+	validators ValidatorStore
+	extractors *extractors.Registry
+	hsts       *crawlers.HSTS
+	structured bool
+	canonical  *crawlers.Canonicalizer
+	feeds      FeedRegistry
+	assets     *AssetDownloader
+	security   bool
+	ignoreMeta bool
+	dedupe     bool
+	dedupKey   *crawlers.DedupKey
+	ttl        *TTLPolicy
+	certs      *CertificateMonitor
+	sites      *SiteMetadataService
+
+	hooked       crawlers.CrawlerClient // Crawler the shared request and response hooks are registered on
+	revalidateMu sync.Mutex
+	revalidate   *revalidation // Validators sent with the current crawl's request; nil when none
+}
+
+// revalidation holds the stored validators of the URL being crawled
+type revalidation struct {
+	target     string // Normalized URL the validators belong to
+	validators Validators
 }
 
 // NewCrawlerService creates a new crawler service with injected dependencies
@@ -34,107 +75,767 @@ func NewCrawlerService(
 	}
 }
 
+// SetProject tags stored pages with a project name
+// When sharedCorpus is true, page bodies are deduplicated across projects by content hash
+func (s *CrawlerService) SetProject(project string, sharedCorpus bool) {
+	s.project = project
+	if sharedCorpus {
+		s.corpus = NewCorpusService(s.logger, s.db)
+	} else {
+		s.corpus = nil
+	}
+}
+
+// SetCrawler replaces the default Colly client, for example to change its
+// politeness delay; the client must not be shared with another service
+func (s *CrawlerService) SetCrawler(crawler crawlers.CrawlerClient) {
+	s.crawler = crawler
+}
+
+// SetBodyStore stores page bodies through size-tiered codecs
+// The shared corpus, when enabled, takes precedence
+func (s *CrawlerService) SetBodyStore(store *storage.BodyStore) {
+	s.bodies = store
+}
+
+// SetSLOTracker feeds crawl outcomes into SLO metrics
+func (s *CrawlerService) SetSLOTracker(tracker *libs.SLOTracker) {
+	s.slo = tracker
+}
+
+// SetPublisher announces every stored page, e.g. on a Kafka topic
+func (s *CrawlerService) SetPublisher(publisher PagePublisher) {
+	s.publisher = publisher
+}
+
+// SetValidatorStore enables conditional re-crawls: ETag and Last-Modified
+// validators are stored per URL and sent back as If-None-Match and
+// If-Modified-Since, and a 304 response skips parsing and storage
+func (s *CrawlerService) SetValidatorStore(store ValidatorStore) {
+	s.validators = store
+}
+
+// SetExtractors applies declarative extraction rules to crawled pages
+// Page rules fill the stored page's fields; product and article rules store
+// a record alongside it
+func (s *CrawlerService) SetExtractors(registry *extractors.Registry) {
+	s.extractors = registry
+}
+
+// SetStructuredData stores a product or article for pages that describe one
+// in schema.org JSON-LD or microdata, OpenGraph or Twitter Card tags
+// Extraction rules take precedence for the URLs they match
+func (s *CrawlerService) SetStructuredData(enabled bool) {
+	s.structured = enabled
+}
+
+// SetFeedRegistry registers the RSS, Atom and JSON feeds crawled pages link
+// to with rel=alternate, so newly found blogs can be monitored
+func (s *CrawlerService) SetFeedRegistry(registry FeedRegistry) {
+	s.feeds = registry
+}
+
+// SetAssetDownloader downloads the images and files crawled pages reference
+// and links them to the stored page
+func (s *CrawlerService) SetAssetDownloader(downloader *AssetDownloader) {
+	s.assets = downloader
+}
+
+// SetSecurityAudit records the security headers of every stored page (CSP,
+// HSTS, X-Frame-Options, ...) with a score, for SecurityReportService
+func (s *CrawlerService) SetSecurityAudit(enabled bool) {
+	s.security = enabled
+}
+
+// SetCertificateMonitor records the TLS certificate of every crawled https
+// host and alerts on ones about to expire
+func (s *CrawlerService) SetCertificateMonitor(monitor *CertificateMonitor) {
+	s.certs = monitor
+}
+
+// SetSiteMetadata records the name, favicon and web app manifest of every
+// crawled site in the domains table
+func (s *CrawlerService) SetSiteMetadata(service *SiteMetadataService) {
+	s.sites = service
+}
+
+// SetIgnoreRobotsMeta stores pages marked noindex, and discovers feeds and
+// assets on pages marked nofollow, by meta robots tags or X-Robots-Tag
+// headers; for archival crawls. By default noindex pages are not stored
+func (s *CrawlerService) SetIgnoreRobotsMeta(ignore bool) {
+	s.ignoreMeta = ignore
+}
+
+// SetCanonicalizer folds URL variants (www, trailing slash, index pages)
+// before crawling, so each page is fetched, cached and stored under one URL
+func (s *CrawlerService) SetCanonicalizer(canonical *crawlers.Canonicalizer) {
+	s.canonical = canonical
+}
+
+// SetCanonicalDedupe stores pages under their canonical URL, from
+// <link rel="canonical"> or 301/308 redirects, instead of the fetched URL,
+// and skips pages already stored under it. The canonical URL is recorded on
+// every page either way
+func (s *CrawlerService) SetCanonicalDedupe(enabled bool) {
+	s.dedupe = enabled
+}
+
+// SetDedupKey picks what makes two pages of the project the same page. URLs
+// are fetched, cached and stored under key.URL; the canonical strategy
+// turns on canonical dedupe and the content strategy skips pages whose
+// stored body is unchanged instead of the page cache. With a key, pages are
+// upserted on project and URL, so a recrawl replaces the stored row
+func (s *CrawlerService) SetDedupKey(key *crawlers.DedupKey) {
+	s.dedupKey = key
+}
+
+// SetTTLPolicy picks the cache TTL of each page by its content type, kind,
+// URL and change history instead of a fixed 24h
+func (s *CrawlerService) SetTTLPolicy(policy *TTLPolicy) {
+	s.ttl = policy
+}
+
+// SetHSTS crawls http URLs over https when their host is known to support
+// it, so a site is not stored under both schemes, and records the HSTS
+// headers of crawled pages
+func (s *CrawlerService) SetHSTS(hsts *crawlers.HSTS) {
+	s.hsts = hsts
+}
+
+// LoadBody returns the stored body of a page regardless of how it was stored
+func (s *CrawlerService) LoadBody(page *models.Page) ([]byte, error) {
+	if s.corpus != nil && page.ContentHash != "" {
+		if err := s.corpus.Load(page); err != nil {
+			return nil, err
+		}
+		return []byte(page.HTML), nil
+	}
+	if s.bodies != nil {
+		return s.bodies.Load(page)
+	}
+	return []byte(page.HTML), nil
+}
+
 // Initialize sets up the database schema
 func (s *CrawlerService) Initialize() error {
 	s.logger.Info("Initializing crawler service database schema")
 
 	// Auto-migrate models
-	if err := s.db.Migrate(&models.Page{}, &models.Product{}, &models.Article{}); err != nil {
+	if err := s.db.Migrate(&models.Page{}, &models.Product{}, &models.Article{}, &models.PageContent{}, &models.CrawlLog{}, &models.URLValidator{}, &models.Feed{}, &models.Asset{}, &models.SecurityAudit{}, &models.DomainCertificate{}, &models.Domain{}, &models.SchemaMigration{}); err != nil {
 		return fmt.Errorf("failed to migrate models: %w", err)
 	}
 
+	// Let the same URL be stored under several projects
+	if dropped, err := DropLegacyPageURLIndex(s.db); err != nil {
+		return err
+	} else if dropped {
+		s.logger.Info("Dropped legacy page URL index", zap.String("index", legacyPageURLIndex))
+	}
+
+	// Copy validators saved before pages kept them; runs once per database
+	if updated, err := BackfillPageValidators(s.db); err != nil {
+		return err
+	} else if updated > 0 {
+		s.logger.Info("Backfilled page validators", zap.Int64("pages", updated))
+	}
+
 	s.logger.Info("Database schema initialized successfully")
 	return nil
 }
 
+// legacyPageURLIndex is the URL-only unique index pages had before they were keyed by project and URL
+const legacyPageURLIndex = "idx_pages_url"
+
+// DropLegacyPageURLIndex drops the URL-only unique index of pages if it exists
+// AutoMigrate never drops indexes, so databases created before pages were
+// keyed by project and URL would otherwise reject a URL stored under a
+// second project
+func DropLegacyPageURLIndex(dbClient database.DatabaseClient) (bool, error) {
+	var dropped bool
+	err := dbClient.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		if !migrator.HasIndex(&models.Page{}, legacyPageURLIndex) {
+			return nil
+		}
+		dropped = true
+		return migrator.DropIndex(&models.Page{}, legacyPageURLIndex)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to drop legacy page URL index: %w", err)
+	}
+	return dropped, nil
+}
+
 // CrawlAndStore crawls a URL, caches the result, and stores in database
 func (s *CrawlerService) CrawlAndStore(url string) error {
-	s.logger.Info("Starting crawl", zap.String("url", url))
+	return s.CrawlAndStoreContext(context.Background(), url)
+}
+
+// CrawlAndStoreContext is CrawlAndStore bounded by ctx
+// Every log line carries the crawl ID of ctx (a new one is generated if
+// absent) and the stage: fetch, extract, store or publish
+// Failures are returned as a *CrawlError naming the stage; cache failures
+// are only logged
+func (s *CrawlerService) CrawlAndStoreContext(ctx context.Context, url string) error {
+	return s.crawlAndStore(ctx, url).Err
+}
+
+// CrawlAndStoreBatch crawls urls one after another and returns a result for
+// each, in order; a failed URL does not stop the batch. URLs left when ctx
+// is done fail at the fetch stage with the context's error
+func (s *CrawlerService) CrawlAndStoreBatch(ctx context.Context, urls []string) []CrawlResult {
+	results := make([]CrawlResult, len(urls))
+	for i, url := range urls {
+		if err := ctx.Err(); err != nil {
+			results[i] = CrawlResult{URL: url, Err: &CrawlError{URL: url, Stage: StageFetch, Err: err}}
+			continue
+		}
+		results[i] = s.crawlAndStore(ctx, url)
+	}
+	return results
+}
+
+// Retry resumes a failed crawl at the stage it failed: a fetch or extract
+// failure crawls the URL again, a store failure saves the page that was
+// already fetched, and a failed cache write only writes the cache. The
+// returned result reports the retried stages; results with nothing left to
+// do are returned as is. Crawling again needs a crawler that revisits URLs,
+// e.g. a CollyClient with AllowURLRevisit
+func (s *CrawlerService) Retry(ctx context.Context, result CrawlResult) CrawlResult {
+	switch result.Stage() {
+	case "":
+		return result
+	case StageStore, StageCache:
+		if result.pending != nil {
+			ctx, _ = libs.EnsureCrawlID(ctx)
+			retried := CrawlResult{URL: result.URL, Page: result.pending.page, pending: result.pending}
+			s.finish(ctx, libs.LoggerFrom(ctx, s.logger).With(zap.String("url", result.pending.url)), &retried)
+			return retried
+		}
+	}
+	return s.crawlAndStore(ctx, result.URL)
+}
+
+// registerHooks registers the request and response hooks shared by every
+// crawl on the current crawler, once per crawler; they read the state of the
+// running crawl from the service, so crawls do not leave callbacks behind
+func (s *CrawlerService) registerHooks() {
+	if s.hooked == s.crawler {
+		return
+	}
+	s.hooked = s.crawler
+
+	s.crawler.OnRequest(func(r *colly.Request) {
+		s.revalidateMu.Lock()
+		current := s.revalidate
+		s.revalidateMu.Unlock()
+		if current != nil && normalizeURL(r.URL.String()) == current.target {
+			current.validators.Apply(*r.Headers)
+		}
+	})
+	s.crawler.OnResponse(func(r *colly.Response) {
+		if s.hsts != nil && r.Headers != nil {
+			s.hsts.Observe(r.Request.URL, *r.Headers)
+		}
+	})
+}
+
+// setRevalidation sets the validators sent with the running crawl's request
+func (s *CrawlerService) setRevalidation(r *revalidation) {
+	s.revalidateMu.Lock()
+	s.revalidate = r
+	s.revalidateMu.Unlock()
+}
+
+// crawlAndStore runs every stage for url
+func (s *CrawlerService) crawlAndStore(ctx context.Context, url string) CrawlResult {
+	ctx, _ = libs.EnsureCrawlID(ctx)
+	if s.canonical != nil {
+		url = s.canonical.Canonicalize(url)
+	}
+	if upgraded, ok := s.hsts.Upgrade(ctx, url); ok {
+		libs.LoggerFrom(ctx, s.logger).Debug("Upgraded URL to https", zap.String("from", url), zap.String("url", upgraded))
+		url = upgraded
+	}
+	url = s.dedupKey.URL(url)
+	result := CrawlResult{URL: url}
+	log := libs.LoggerFrom(ctx, s.logger).With(zap.String("url", url))
+	fetchLog := log.With(zap.String("stage", "fetch"))
+	storeLog := log.With(zap.String("stage", "store"))
+
+	fetchLog.Info("Starting crawl")
+	s.registerHooks()
 
-	// Check cache first
+	// Check cache first; a cache that cannot be read does not stop the crawl
+	// Deduplicating by content needs the body, so the cache cannot decide
 	cacheKey := fmt.Sprintf("page:%s", url)
-	if s.cache != nil {
+	if s.cache != nil && s.dedupKey.Strategy() != crawlers.DedupContent {
 		cached, err := s.cache.Exists(cacheKey)
-		if err == nil && cached {
-			s.logger.Info("Page found in cache, skipping crawl", zap.String("url", url))
-			return nil
+		if err != nil {
+			fetchLog.Warn("Failed to check page cache", errs.Fields(err)...)
+			result.Warnings = append(result.Warnings, &CrawlError{URL: url, Stage: StageCache, Err: err})
+		} else if cached {
+			fetchLog.Info("Page found in cache, skipping crawl")
+			s.recordSkip(ctx, fetchLog, crawlers.SkipDecision{URL: url, Reason: crawlers.SkipDuplicate, Rule: "cached"})
+			return result
+		}
+	}
+
+	fetched := &fetchedPage{url: url, cacheKey: cacheKey}
+	var robots crawlers.RobotsDirectives
+	var canonicalLink string
+	var redirects []crawlers.Redirect
+	var crawlErr, extractErr error
+	var notModified *models.Page
+	started := time.Now()
+
+	// Callbacks stay registered on the crawler after this call returns;
+	// later calls must not write into this one's state
+	var done bool
+	defer func() { done = true }()
+
+	// Revalidate instead of refetching when the URL was seen before
+	if s.validators != nil {
+		previous, err := s.validators.LoadValidators(s.project, url)
+		if err != nil {
+			fetchLog.Warn("Failed to load validators", errs.Fields(err)...)
+		} else if !previous.IsZero() {
+			s.setRevalidation(&revalidation{target: normalizeURL(url), validators: previous})
+			defer s.setRevalidation(nil)
 		}
 	}
 
-	var crawledPage *models.Page
-	var crawlErr error
+	if observer, ok := s.crawler.(crawlers.RedirectObserver); ok {
+		observer.OnRedirect(func(r crawlers.Redirect) {
+			if done {
+				return
+			}
+			redirects = append(redirects, r)
+		})
+	}
 
 	// Set up crawler callbacks
 	s.crawler.OnHTML("html", func(e *colly.HTMLElement) {
+		if done {
+			return
+		}
 		title := e.ChildText("title")
 		if title == "" {
 			title = "No title"
 		}
 
-		s.logger.Info("Page scraped",
-			zap.String("url", url),
+		log.Info("Page scraped",
+			zap.String("stage", "extract"),
 			zap.String("title", title))
 
 		// Create page model
-		crawledPage = &models.Page{
-			URL:    url,
-			Title:  title,
-			Domain: e.Request.URL.Host,
-			Status: 200,
-			HTML:   string(e.Response.Body),
+		crawledPage := &models.Page{
+			Project: s.project,
+			URL:     url,
+			Title:   title,
+			Domain:  e.Request.URL.Host,
+			Status:  200,
+			HTML:    string(e.Response.Body),
+		}
+		fetched.page = crawledPage
+		if e.Response.Headers != nil {
+			fetched.mediaType = e.Response.Headers.Get("Content-Type")
+		}
+		if !s.ignoreMeta {
+			var header http.Header
+			var agent string
+			if e.Response.Headers != nil {
+				header = *e.Response.Headers
+			}
+			if e.Request.Headers != nil {
+				agent = crawlers.RobotsAgent(e.Request.Headers.Get("User-Agent"))
+			}
+			robots = crawlers.ParseRobotsDirectives(header, goquery.NewDocumentFromNode(e.DOM.Get(0)), agent)
+		}
+		canonicalLink = extractors.CanonicalLink(goquery.NewDocumentFromNode(e.DOM.Get(0)), e.Request.URL.String())
+		if s.sites != nil {
+			fetched.site = extractors.ExtractSiteMetadata(goquery.NewDocumentFromNode(e.DOM.Get(0)), e.Request.URL.String())
+		}
+		if e.Response.Headers != nil {
+			fetched.fresh = ValidatorsFrom(*e.Response.Headers)
+			crawledPage.ETag, crawledPage.LastModified = fetched.fresh.ETag, fetched.fresh.LastModified
+			if s.security {
+				fetched.audit = newSecurityAudit(s.project, crawledPage, *e.Response.Headers)
+			}
+		}
+
+		if extractor := s.extractors.For(url); extractor != nil {
+			fetched.extracted, extractErr = s.extract(log, extractor, e, crawledPage)
+		}
+		if s.feeds != nil && !robots.NoFollow {
+			fetched.feeds = extractors.DiscoverFeeds(goquery.NewDocumentFromNode(e.DOM.Get(0)), url)
+		}
+		if s.assets != nil && !robots.NoFollow {
+			fetched.assetURLs = extractors.DiscoverAssets(goquery.NewDocumentFromNode(e.DOM.Get(0)), url)
+		}
+		if fetched.extracted == nil && s.structured {
+			fetched.extracted = extractors.ExtractStructured(goquery.NewDocumentFromNode(e.DOM.Get(0)), url)
+			if fetched.extracted != nil {
+				log.Info("Record extracted from structured data", zap.String("stage", "extract"))
+			}
 		}
 	})
 
 	s.crawler.OnError(func(r *colly.Response, err error) {
+		if done {
+			return
+		}
+		if s.validators != nil && r != nil && r.StatusCode == http.StatusNotModified {
+			notModified = &models.Page{URL: url, Domain: r.Request.URL.Host, Status: http.StatusNotModified}
+			return
+		}
 		crawlErr = err
-		s.logger.Error("Crawl failed",
-			zap.String("url", url),
-			zap.Error(err))
+		fetchLog.Error("Crawl failed", errs.Fields(err)...)
 	})
 
 	// Visit the URL
-	if err := s.crawler.Visit(url); err != nil {
-		return fmt.Errorf("failed to visit URL: %w", err)
+	if err := s.crawler.VisitContext(ctx, url); err != nil && notModified == nil {
+		if decision, skipped := crawlers.SkipDecisionOf(err); skipped {
+			decision.URL = url
+			s.recordSkip(ctx, fetchLog, decision)
+		}
+		result.Err = &CrawlError{URL: url, Stage: StageFetch, Err: fmt.Errorf("failed to visit URL: %w", err)}
+		return result
 	}
 
 	s.crawler.Wait()
 
+	if notModified != nil {
+		fetchLog.Info("Page not modified, skipping")
+		s.recordCrawl(ctx, storeLog, url, notModified, nil, time.Since(started))
+		return result
+	}
+
+	s.recordCrawl(ctx, storeLog, url, fetched.page, crawlErr, time.Since(started))
+
 	if crawlErr != nil {
-		return crawlErr
+		result.Err = &CrawlError{URL: url, Stage: StageFetch, Err: crawlErr}
+		return result
+	}
+
+	if fetched.page == nil {
+		result.Err = &CrawlError{URL: url, Stage: StageExtract, Err: errors.New("no data extracted from URL")}
+		return result
+	}
+	result.Page = fetched.page
+	if extractErr != nil {
+		result.Warnings = append(result.Warnings, &CrawlError{URL: url, Stage: StageExtract, Err: extractErr})
 	}
 
-	if crawledPage == nil {
-		return fmt.Errorf("no data extracted from URL")
+	if robots.NoIndex {
+		storeLog.Info("Page is marked noindex, not storing")
+		return result
+	}
+
+	crawledPage := fetched.page
+	crawledPage.CanonicalURL = s.canonicalURL(url, canonicalLink, redirects)
+	canonicalDedupe := s.dedupe || s.dedupKey.Strategy() == crawlers.DedupCanonical
+	if canonicalDedupe && crawledPage.CanonicalURL != crawledPage.URL {
+		var stored []models.Page
+		if err := s.db.Find(&stored, "project = ? AND url = ?", s.project, crawledPage.CanonicalURL); err != nil {
+			storeLog.Warn("Failed to look up canonical URL", errs.Fields(err)...)
+		} else if len(stored) > 0 {
+			storeLog.Info("Page already stored under its canonical URL, skipping",
+				zap.String("canonical_url", crawledPage.CanonicalURL),
+				zap.Uint("page_id", stored[0].ID))
+			return result
+		}
+		crawledPage.URL = crawledPage.CanonicalURL
+	}
+	if s.dedupKey.Strategy() == crawlers.DedupContent {
+		if stored, err := s.storedDuplicate(crawledPage); err != nil {
+			storeLog.Warn("Failed to compare stored content", errs.Fields(err)...)
+		} else if stored != nil {
+			storeLog.Info("Page content unchanged, skipping", zap.Uint("page_id", stored.ID))
+			s.recordSkip(ctx, storeLog, crawlers.SkipDecision{URL: url, Reason: crawlers.SkipDuplicate, Rule: "content"})
+			return result
+		}
+	}
+
+	if s.cache != nil {
+		fetched.ttl = s.ttl.TTL(crawledPage, fetched.mediaType, pageKind(fetched.extracted))
+	}
+	result.pending = fetched
+	s.finish(ctx, log, &result)
+	return result
+}
+
+// finish runs the stages after extraction on result.pending, skipping the
+// ones a previous attempt completed: store, cache and publish
+func (s *CrawlerService) finish(ctx context.Context, log *zap.Logger, result *CrawlResult) {
+	fetched := result.pending
+	if !fetched.saved {
+		if err := s.store(ctx, log, fetched); err != nil {
+			result.Err = &CrawlError{URL: fetched.url, Stage: StageStore, Err: err}
+			return
+		}
+	}
+	result.Stored = true
+
+	// Cache the result; the page is saved, so failures are only reported
+	if s.cache != nil && !fetched.cached {
+		storeLog := log.With(zap.String("stage", "store"))
+		if err := s.cache.SetJSON(fetched.cacheKey, fetched.page, fetched.ttl); err != nil {
+			storeLog.Warn("Failed to cache page", errs.Fields(err)...)
+			result.Warnings = append(result.Warnings, &CrawlError{URL: fetched.url, Stage: StageCache, Err: err})
+			return
+		}
+		fetched.cached = true
+		storeLog.Info("Page cached", zap.Duration("ttl", fetched.ttl))
+	}
+}
+
+// store saves the body and the page, then everything recorded alongside it
+// Only body and page failures are returned; the rest is logged
+func (s *CrawlerService) store(ctx context.Context, log *zap.Logger, fetched *fetchedPage) error {
+	crawledPage, url := fetched.page, fetched.url
+	storeLog := log.With(zap.String("stage", "store"))
+
+	// Move the body into the shared corpus if enabled
+	if !fetched.bodyStored {
+		if s.corpus != nil {
+			if err := s.corpus.StoreContext(ctx, crawledPage); err != nil {
+				storeLog.Error("Failed to store page content in shared corpus", errs.Fields(err)...)
+				return fmt.Errorf("failed to store content: %w", err)
+			}
+		} else if s.bodies != nil {
+			if err := s.bodies.Save(crawledPage, []byte(crawledPage.HTML)); err != nil {
+				storeLog.Error("Failed to store page body", errs.Fields(err)...)
+				return fmt.Errorf("failed to store body: %w", err)
+			}
+		}
+		fetched.bodyStored = true
 	}
 
 	// Save to database
-	if err := s.db.Create(crawledPage); err != nil {
-		s.logger.Error("Failed to save page to database",
-			zap.String("url", url),
-			zap.Error(err))
+	if err := s.save(crawledPage); err != nil {
+		storeLog.Error("Failed to save page to database", errs.Fields(err)...)
 		return fmt.Errorf("failed to save to database: %w", err)
 	}
+	fetched.saved = true
 
-	s.logger.Info("Page saved to database",
-		zap.String("url", url),
-		zap.Uint("page_id", crawledPage.ID))
+	storeLog.Info("Page saved to database", zap.Uint("page_id", crawledPage.ID))
 
-	// Cache the result
-	if s.cache != nil {
-		if err := s.cache.SetJSON(cacheKey, crawledPage, 24*time.Hour); err != nil {
-			s.logger.Warn("Failed to cache page",
-				zap.String("url", url),
-				zap.Error(err))
+	// Store the extracted record; the page is already saved, so failures are only logged
+	if fetched.extracted != nil {
+		if err := s.db.Create(fetched.extracted); err != nil {
+			storeLog.Warn("Failed to save extracted record", errs.Fields(err)...)
+		} else {
+			storeLog.Info("Extracted record saved")
+		}
+	}
+
+	// Register advertised feeds; failures are only logged
+	if len(fetched.feeds) > 0 {
+		if added, err := s.feeds.RegisterFeeds(ctx, s.project, url, fetched.feeds); err != nil {
+			storeLog.Warn("Failed to register feeds", errs.Fields(err)...)
+		} else if added > 0 {
+			storeLog.Info("Feeds discovered", zap.Int("new", added), zap.Int("found", len(fetched.feeds)))
+		}
+	}
+
+	// Record the security header audit; failures are only logged
+	if fetched.audit != nil {
+		fetched.audit.PageID = crawledPage.ID
+		if err := s.db.Create(fetched.audit); err != nil {
+			storeLog.Warn("Failed to save security audit", errs.Fields(err)...)
+		}
+	}
+
+	// Record the host's TLS certificate; failures are only logged
+	if s.certs != nil {
+		if _, err := s.certs.Check(ctx, url); err != nil {
+			storeLog.Warn("Failed to record TLS certificate", errs.Fields(err)...)
+		}
+	}
+
+	// Record the site's name and icons; failures are only logged
+	if s.sites != nil {
+		if _, err := s.sites.Record(ctx, url, fetched.site); err != nil {
+			storeLog.Warn("Failed to record site metadata", errs.Fields(err)...)
+		}
+	}
+
+	// Download referenced assets; failures are only logged
+	if len(fetched.assetURLs) > 0 {
+		if assets, err := s.assets.Download(ctx, crawledPage, fetched.assetURLs); err != nil {
+			storeLog.Warn("Failed to store assets", errs.Fields(err)...)
+		} else {
+			storeLog.Info("Assets stored", zap.Int("stored", len(assets)), zap.Int("found", len(fetched.assetURLs)))
+		}
+	}
+
+	// Remember the validators for the next crawl
+	if s.validators != nil && !fetched.fresh.IsZero() {
+		if err := s.validators.SaveValidators(s.project, url, fetched.fresh); err != nil {
+			storeLog.Warn("Failed to save validators", errs.Fields(err)...)
+		}
+	}
+
+	// Announce the page; the crawl already succeeded, so failures are only logged
+	if s.publisher != nil {
+		publishLog := log.With(zap.String("stage", "publish"))
+		if err := s.publisher.PublishPage(ctx, crawledPage); err != nil {
+			publishLog.Warn("Failed to publish page", errs.Fields(err)...)
 		} else {
-			s.logger.Info("Page cached",
-				zap.String("url", url),
-				zap.Duration("ttl", 24*time.Hour))
+			publishLog.Info("Page published", zap.Uint("page_id", crawledPage.ID))
 		}
 	}
 
 	return nil
 }
 
+// save inserts page or, with a dedup key, updates the row stored under
+// its project and URL
+func (s *CrawlerService) save(page *models.Page) error {
+	if s.dedupKey == nil {
+		return s.db.Create(page)
+	}
+	var stored []models.Page
+	if err := s.db.Find(&stored, "project = ? AND url = ?", page.Project, page.URL); err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		return s.db.Create(page)
+	}
+	page.ID, page.CreatedAt = stored[0].ID, stored[0].CreatedAt
+	return s.db.Updates(page, page)
+}
+
+// storedDuplicate returns the page stored under page's project and URL when
+// it has the same dedup key, i.e. the same body for the content strategy
+func (s *CrawlerService) storedDuplicate(page *models.Page) (*models.Page, error) {
+	var stored []models.Page
+	if err := s.db.Find(&stored, "project = ? AND url = ?", s.project, page.URL); err != nil || len(stored) == 0 {
+		return nil, err
+	}
+	body, err := s.LoadBody(&stored[0])
+	if err != nil {
+		return nil, err
+	}
+	key := s.dedupKey.Page(page.URL, page.CanonicalURL, ContentHash(page.HTML))
+	if s.dedupKey.Page(stored[0].URL, stored[0].CanonicalURL, ContentHash(string(body))) != key {
+		return nil, nil
+	}
+	return &stored[0], nil
+}
+
+// canonicalURL returns the canonical URL of a fetched page: the one it
+// declares with <link rel="canonical">, else where permanent redirects from
+// url lead, folded by the Canonicalizer when one is set
+func (s *CrawlerService) canonicalURL(url, link string, redirects []crawlers.Redirect) string {
+	canonical := link
+	if canonical == "" {
+		canonical = crawlers.PermanentTarget(url, redirects)
+	}
+	if s.canonical != nil {
+		canonical = s.canonical.Canonicalize(canonical)
+	}
+	return canonical
+}
+
+// extract runs extraction rules on a scraped page
+// Page rules update page in place; product and article records are returned
+// A failure is logged and returned, and the page is stored without a record
+func (s *CrawlerService) extract(log *zap.Logger, extractor *extractors.Extractor, e *colly.HTMLElement, page *models.Page) (interface{}, error) {
+	log = log.With(zap.String("stage", "extract"), zap.String("extractor", extractor.Name()))
+
+	result, err := extractor.Extract(goquery.NewDocumentFromNode(e.DOM.Get(0)), page.URL)
+	if err != nil {
+		log.Warn("Extraction rules failed", errs.Fields(err)...)
+		return nil, err
+	}
+
+	if extractedPage, ok := result.(*models.Page); ok {
+		if extractedPage.Title != "" {
+			page.Title = extractedPage.Title
+		}
+		if extractedPage.Content != "" {
+			page.Content = extractedPage.Content
+		}
+		return nil, nil
+	}
+	log.Info("Record extracted", zap.String("model", extractor.Model()))
+	return result, nil
+}
+
+// newSecurityAudit scores the security headers of a scraped page
+func newSecurityAudit(project string, page *models.Page, header http.Header) *models.SecurityAudit {
+	result := extractors.AuditSecurityHeaders(page.URL, header)
+	return &models.SecurityAudit{
+		Project:             project,
+		Domain:              page.Domain,
+		URL:                 page.URL,
+		CSP:                 result.CSP,
+		HSTS:                result.HSTS,
+		XFrameOptions:       result.XFrameOptions,
+		XContentTypeOptions: result.XContentTypeOptions,
+		ReferrerPolicy:      result.ReferrerPolicy,
+		PermissionsPolicy:   result.PermissionsPolicy,
+		Score:               result.Score,
+		Issues:              strings.Join(result.Issues, ","),
+	}
+}
+
+// recordCrawl appends a crawl log entry; failures are logged but not returned
+func (s *CrawlerService) recordCrawl(ctx context.Context, log *zap.Logger, url string, page *models.Page, crawlErr error, duration time.Duration) {
+	if s.slo != nil {
+		s.slo.RecordFetch(duration, crawlErr == nil)
+	}
+
+	entry := &models.CrawlLog{
+		Project:    s.project,
+		CrawlID:    libs.CrawlIDFrom(ctx),
+		URL:        url,
+		DurationMs: duration.Milliseconds(),
+	}
+	if page != nil {
+		entry.Domain = page.Domain
+		entry.Status = page.Status
+		entry.Bytes = int64(len(page.HTML))
+		if page.HTML != "" {
+			entry.ContentHash = ContentHash(page.HTML)
+		}
+	} else if u, err := neturl.Parse(url); err == nil {
+		// Failed fetches still count toward their domain's statistics
+		entry.Domain = u.Host
+	}
+	if crawlErr != nil {
+		entry.Error = crawlErr.Error()
+	}
+
+	if err := s.db.Create(entry); err != nil {
+		log.Warn("Failed to record crawl log", errs.Fields(err)...)
+	}
+}
+
+// recordSkip logs why a URL was not fetched and appends a crawl log entry
+// carrying the reason; failures are logged but not returned
+func (s *CrawlerService) recordSkip(ctx context.Context, log *zap.Logger, decision crawlers.SkipDecision) {
+	log.Info("URL skipped",
+		zap.String("skip_reason", decision.Reason),
+		zap.String("skip_rule", decision.Rule))
+
+	entry := &models.CrawlLog{
+		Project:    s.project,
+		CrawlID:    libs.CrawlIDFrom(ctx),
+		URL:        decision.URL,
+		SkipReason: decision.Reason,
+		SkipRule:   decision.Rule,
+	}
+	if u, err := neturl.Parse(decision.URL); err == nil {
+		entry.Domain = u.Host
+	}
+	if err := s.db.Create(entry); err != nil {
+		log.Warn("Failed to record crawl log", errs.Fields(err)...)
+	}
+}
+
 // GetStats returns crawler statistics
 func (s *CrawlerService) GetStats() (map[string]interface{}, error) {
 	s.logger.Info("Fetching crawler statistics")
@@ -170,3 +871,39 @@ func (s *CrawlerService) GetRecentPages(limit int) ([]models.Page, error) {
 	s.logger.Info("Retrieved recent pages", zap.Int("count", len(pages)))
 	return pages, nil
 }
+
+// GetPagesBetween retrieves pages crawled in [from, to), newest first
+// pages is not partitioned, since its (project, url) unique key cannot
+// include created_at; the range is served by the created_at index instead
+func (s *CrawlerService) GetPagesBetween(from, to time.Time, limit int) ([]models.Page, error) {
+	var pages []models.Page
+
+	err := s.db.GetDB().
+		Scopes(database.TimeRange("created_at", from, to)).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&pages).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pages: %w", err)
+	}
+
+	return pages, nil
+}
+
+// GetCrawlLogs retrieves crawl log entries in [from, to), newest first
+func (s *CrawlerService) GetCrawlLogs(from, to time.Time, limit int) ([]models.CrawlLog, error) {
+	var logs []models.CrawlLog
+
+	err := s.db.GetDB().
+		Scopes(database.TimeRange("created_at", from, to)).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&logs).Error
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch crawl logs: %w", err)
+	}
+
+	return logs, nil
+}
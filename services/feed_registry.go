@@ -0,0 +1,52 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/models"
+	"gorm.io/gorm/clause"
+)
+
+// FeedRegistry records feeds discovered during crawls so they can be
+// monitored
+type FeedRegistry interface {
+	// RegisterFeeds stores feeds found on sourceURL and returns how many
+	// were new; feeds already registered for the project are left alone
+	RegisterFeeds(ctx context.Context, project, sourceURL string, feeds []extractors.FeedLink) (int, error)
+}
+
+// DBFeedRegistry keeps discovered feeds in the feeds table
+type DBFeedRegistry struct {
+	db database.DatabaseClient
+}
+
+// NewDBFeedRegistry creates a feed registry backed by the database
+// CrawlerService.Initialize migrates its table
+func NewDBFeedRegistry(dbClient database.DatabaseClient) *DBFeedRegistry {
+	return &DBFeedRegistry{db: dbClient}
+}
+
+// RegisterFeeds implements FeedRegistry
+func (r *DBFeedRegistry) RegisterFeeds(ctx context.Context, project, sourceURL string, feeds []extractors.FeedLink) (int, error) {
+	if len(feeds) == 0 {
+		return 0, nil
+	}
+
+	rows := make([]models.Feed, 0, len(feeds))
+	for _, f := range feeds {
+		rows = append(rows, models.Feed{Project: project, URL: f.URL, Title: f.Title, Type: f.Type, SourceURL: sourceURL})
+	}
+	result := r.db.GetDB().WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&rows)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to register feeds: %w", result.Error)
+	}
+	return int(result.RowsAffected), nil
+}
+
+// Ensure the registry implements the FeedRegistry interface
+var _ FeedRegistry = (*DBFeedRegistry)(nil)
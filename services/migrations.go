@@ -0,0 +1,32 @@
+package services
+
+import (
+	"time"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// runMigrationOnce runs a one-time data migration in a transaction and
+// records it in schema_migrations; it does nothing once the record exists
+// Instances initializing at the same time may both run it, so migrate must
+// be idempotent
+func runMigrationOnce(dbClient database.DatabaseClient, name string, migrate func(tx *gorm.DB) error) error {
+	return dbClient.Transaction(func(tx *gorm.DB) error {
+		var applied int64
+		if err := tx.Model(&models.SchemaMigration{}).Where("name = ?", name).Count(&applied).Error; err != nil {
+			return err
+		}
+		if applied > 0 {
+			return nil
+		}
+
+		if err := migrate(tx); err != nil {
+			return err
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.SchemaMigration{Name: name, AppliedAt: time.Now()}).Error
+	})
+}
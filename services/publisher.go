@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"github.com/alonecandies/golwarc/models"
+)
+
+// PagePublisher announces stored pages to downstream consumers
+type PagePublisher interface {
+	PublishPage(ctx context.Context, page *models.Page) error
+}
+
+// PageEventSchema versions page events; version 1 is the first versioned
+// release and matches the unversioned events published before it
+var PageEventSchema = messagequeue.NewSchema("page_event", 1, nil)
+
+// DecodePageEvent reads a page event of any version this release reads
+func DecodePageEvent(data []byte) (PageEvent, error) {
+	var event PageEvent
+	if _, err := PageEventSchema.Unmarshal(data, &event); err != nil {
+		return PageEvent{}, err
+	}
+	return event, nil
+}
+
+// PageEvent is the message published for a stored page
+// Bodies are not included; consumers load them by page ID
+type PageEvent struct {
+	CrawlID   string    `json:"crawl_id"`
+	PageID    uint      `json:"page_id"`
+	Project   string    `json:"project,omitempty"`
+	URL       string    `json:"url"`
+	Title     string    `json:"title"`
+	Domain    string    `json:"domain"`
+	Status    int       `json:"status"`
+	CrawledAt time.Time `json:"crawled_at"`
+}
+
+// NewPageEvent builds the event for a page crawled under the crawl ID of ctx
+func NewPageEvent(ctx context.Context, page *models.Page) PageEvent {
+	return PageEvent{
+		CrawlID:   libs.CrawlIDFrom(ctx),
+		PageID:    page.ID,
+		Project:   page.Project,
+		URL:       page.URL,
+		Title:     page.Title,
+		Domain:    page.Domain,
+		Status:    page.Status,
+		CrawledAt: page.CreatedAt,
+	}
+}
+
+// KafkaPagePublisher publishes page events to a Kafka topic
+// Messages are keyed by URL, carry the crawl ID in the X-Crawl-ID header, a
+// page-<id> X-Message-ID for consumer deduplication and their schema version
+// in X-Schema-Version. With the producer's default domain partitioning a
+// domain's events stay in order
+type KafkaPagePublisher struct {
+	producer *messagequeue.KafkaProducer
+	version  int
+}
+
+// NewKafkaPagePublisher creates a page publisher on top of a Kafka producer
+func NewKafkaPagePublisher(producer *messagequeue.KafkaProducer) *KafkaPagePublisher {
+	return &KafkaPagePublisher{producer: producer, version: PageEventSchema.Current()}
+}
+
+// SetSchemaVersion publishes events at an older schema version, e.g. the
+// one PageEventSchema.Negotiate picks for consumers not yet upgraded during
+// a rolling deploy
+func (p *KafkaPagePublisher) SetSchemaVersion(version int) error {
+	if !PageEventSchema.Range().Contains(version) {
+		return errs.Newf(errs.CodeSchemaUnsupported, "cannot publish page events at schema version %d", version)
+	}
+	p.version = version
+	return nil
+}
+
+// PublishPage implements PagePublisher
+func (p *KafkaPagePublisher) PublishPage(ctx context.Context, page *models.Page) error {
+	event := NewPageEvent(ctx, page)
+	value, err := PageEventSchema.Marshal(event, p.version)
+	if err != nil {
+		return fmt.Errorf("failed to marshal page event: %w", err)
+	}
+
+	headers := PageEventSchema.Headers(p.version)
+	headers[libs.CrawlIDHeader] = event.CrawlID
+	headers[messagequeue.HeaderMessageID] = fmt.Sprintf("page-%d", event.PageID)
+	if err := p.producer.ProduceWithHeaders(ctx, []byte(page.URL), value, headers); err != nil {
+		return fmt.Errorf("failed to publish page event: %w", err)
+	}
+	return nil
+}
+
+// Ensure KafkaPagePublisher implements the PagePublisher interface
+var _ PagePublisher = (*KafkaPagePublisher)(nil)
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+)
+
+// QueryLearningConfig holds query parameter learning settings
+type QueryLearningConfig struct {
+	SamplesPerParam int // URLs tested per parameter (default 5)
+	MinSamples      int // Conclusive samples needed to decide (default 2)
+	MaxURLs         int // Stored URLs sampled per run (default 500)
+
+	// Fetch returns a page body; defaults to a GET with a 30s timeout and a
+	// 10MB limit
+	Fetch func(ctx context.Context, url string) ([]byte, error)
+
+	// Canonical receives a query parameter whitelist per host after each run
+	Canonical *crawlers.Canonicalizer
+}
+
+// QueryParamReport is what a learning run found out about one host
+type QueryParamReport struct {
+	Host        string   `json:"host"`
+	Significant []string `json:"significant"` // Removing them changed the content
+	Ignored     []string `json:"ignored"`     // Removing them never changed the content
+	Undecided   []string `json:"undecided"`   // Too few conclusive samples
+}
+
+// Whitelist returns the parameters to keep: significant and undecided ones
+func (r QueryParamReport) Whitelist() []string {
+	params := append(append([]string{}, r.Significant...), r.Undecided...)
+	sort.Strings(params)
+	return params
+}
+
+// QueryLearningService learns which query parameters change page content
+//
+// For every parameter seen on a host it fetches sample URLs with and without
+// the parameter and compares content hashes. Parameters whose removal never
+// changes the content (session IDs, tracking tags) are left out of the
+// host's whitelist, so the Canonicalizer folds URLs that differ only in them
+// Pages whose content changes between two identical requests are not
+// conclusive and are skipped
+type QueryLearningService struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+	config QueryLearningConfig
+}
+
+// NewQueryLearningService creates a new query parameter learning service
+func NewQueryLearningService(logger *zap.Logger, dbClient database.DatabaseClient, config QueryLearningConfig) *QueryLearningService {
+	if config.SamplesPerParam <= 0 {
+		config.SamplesPerParam = 5
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = 2
+	}
+	if config.MinSamples > config.SamplesPerParam {
+		config.MinSamples = config.SamplesPerParam
+	}
+	if config.MaxURLs <= 0 {
+		config.MaxURLs = 500
+	}
+	if config.Fetch == nil {
+		config.Fetch = fetchBody
+	}
+	return &QueryLearningService{
+		logger: logger,
+		db:     dbClient,
+		config: config,
+	}
+}
+
+// fetchBody is the default fetcher
+func fetchBody(ctx context.Context, rawURL string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// Run samples stored URLs with a query string from a project and learns
+// from them
+func (s *QueryLearningService) Run(ctx context.Context, project string) ([]QueryParamReport, error) {
+	var urls []string
+	err := s.db.GetDB().WithContext(ctx).
+		Model(&models.Page{}).
+		Where("project = ? AND url LIKE ?", project, "%?%").
+		Order("id DESC").
+		Limit(s.config.MaxURLs).
+		Pluck("url", &urls).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample URLs: %w", err)
+	}
+	return s.Learn(ctx, urls)
+}
+
+// Start runs learning every interval until the context is cancelled
+func (s *QueryLearningService) Start(ctx context.Context, project string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Run(ctx, project); err != nil {
+				s.logger.Error("Query parameter learning failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Learn tests the query parameters of urls and returns one report per host,
+// sorted by host. Whitelists are handed to the configured Canonicalizer
+func (s *QueryLearningService) Learn(ctx context.Context, urls []string) ([]QueryParamReport, error) {
+	samples := s.samples(urls)
+	hosts := make([]string, 0, len(samples))
+	for host := range samples {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+
+	hashes := &hashCache{fetch: s.config.Fetch, hashes: make(map[string]string), stable: make(map[string]bool)}
+	reports := make([]QueryParamReport, 0, len(hosts))
+	for _, host := range hosts {
+		report := QueryParamReport{Host: host}
+		params := make([]string, 0, len(samples[host]))
+		for param := range samples[host] {
+			params = append(params, param)
+		}
+		sort.Strings(params)
+
+		for _, param := range params {
+			conclusive, changed := 0, 0
+			for _, u := range samples[host][param] {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+				differs, ok := hashes.differs(ctx, u, withoutParam(u, param))
+				if !ok {
+					continue
+				}
+				conclusive++
+				if differs {
+					changed++
+				}
+			}
+
+			switch {
+			case conclusive < s.config.MinSamples:
+				report.Undecided = append(report.Undecided, param)
+			case changed > 0:
+				report.Significant = append(report.Significant, param)
+			default:
+				report.Ignored = append(report.Ignored, param)
+			}
+		}
+
+		if s.config.Canonical != nil {
+			s.config.Canonical.SetQueryParams(host, report.Whitelist())
+		}
+		s.logger.Info("Query parameters learned",
+			zap.String("host", host),
+			zap.Strings("significant", report.Significant),
+			zap.Strings("ignored", report.Ignored),
+			zap.Strings("undecided", report.Undecided))
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// samples groups up to SamplesPerParam URLs per host and query parameter
+func (s *QueryLearningService) samples(urls []string) map[string]map[string][]string {
+	samples := make(map[string]map[string][]string)
+	seen := make(map[string]bool)
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.RawQuery == "" || seen[raw] {
+			continue
+		}
+		seen[raw] = true
+		query, err := url.ParseQuery(u.RawQuery)
+		if err != nil {
+			continue
+		}
+
+		host := strings.ToLower(u.Hostname())
+		if samples[host] == nil {
+			samples[host] = make(map[string][]string)
+		}
+		for param := range query {
+			if len(samples[host][param]) < s.config.SamplesPerParam {
+				samples[host][param] = append(samples[host][param], raw)
+			}
+		}
+	}
+	return samples
+}
+
+// withoutParam removes one query parameter from a URL
+func withoutParam(rawURL, param string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Del(param)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// hashCache fetches each URL once, and sample URLs twice to check that
+// their content is stable
+type hashCache struct {
+	fetch  func(ctx context.Context, url string) ([]byte, error)
+	hashes map[string]string // Empty for failed fetches
+	stable map[string]bool
+}
+
+// hash returns the content hash of a URL, ignoring whitespace differences
+func (c *hashCache) hash(ctx context.Context, rawURL string) string {
+	if h, ok := c.hashes[rawURL]; ok {
+		return h
+	}
+	h := c.fetchHash(ctx, rawURL)
+	c.hashes[rawURL] = h
+	return h
+}
+
+// fetchHash fetches a URL and hashes its whitespace-collapsed body; empty
+// when the fetch fails
+func (c *hashCache) fetchHash(ctx context.Context, rawURL string) string {
+	body, err := c.fetch(ctx, rawURL)
+	if err != nil {
+		return ""
+	}
+	return ContentHash(strings.Join(strings.Fields(string(body)), " "))
+}
+
+// differs reports whether variant has other content than sample; ok is false
+// when either fetch failed or the sample's content is not stable
+func (c *hashCache) differs(ctx context.Context, sample, variant string) (differs, ok bool) {
+	h := c.hash(ctx, sample)
+	if h == "" {
+		return false, false
+	}
+	stable, checked := c.stable[sample]
+	if !checked {
+		stable = c.fetchHash(ctx, sample) == h
+		c.stable[sample] = stable
+	}
+	if !stable {
+		return false, false
+	}
+
+	v := c.hash(ctx, variant)
+	if v == "" {
+		return false, false
+	}
+	return v != h, true
+}
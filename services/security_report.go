@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+)
+
+// securityReportWorstPages is how many lowest-scoring pages a domain report lists
+const securityReportWorstPages = 10
+
+// DomainSecurityReport summarizes the security header audits of one domain
+// Only the latest audit of each URL counts
+type DomainSecurityReport struct {
+	Domain       string         `json:"domain"`
+	Pages        int            `json:"pages"`
+	AverageScore float64        `json:"average_score"` // 0 to 100
+	MinScore     int            `json:"min_score"`
+	Grade        string         `json:"grade"`    // A to F from the average score
+	Coverage     map[string]int `json:"coverage"` // Header name to pages sending it
+	Issues       map[string]int `json:"issues"`   // Issue name to pages reporting it
+	WorstPages   []PageSecurity `json:"worst_pages"`
+}
+
+// PageSecurity is the latest audit score of one page
+type PageSecurity struct {
+	URL    string   `json:"url"`
+	Score  int      `json:"score"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+// SecurityReportService builds per-domain reports from the security audits
+// recorded by CrawlerService.SetSecurityAudit
+type SecurityReportService struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+}
+
+// NewSecurityReportService creates a new security report service
+func NewSecurityReportService(logger *zap.Logger, dbClient database.DatabaseClient) *SecurityReportService {
+	return &SecurityReportService{
+		logger: logger,
+		db:     dbClient,
+	}
+}
+
+// Report returns one report per audited domain of a project, worst average
+// score first. With a domain only that domain is reported
+func (s *SecurityReportService) Report(ctx context.Context, project, domain string) ([]DomainSecurityReport, error) {
+	query := s.db.GetDB().WithContext(ctx).
+		Model(&models.SecurityAudit{}).
+		Select("url", "domain", "csp", "hsts", "x_frame_options", "x_content_type_options", "referrer_policy", "permissions_policy", "score", "issues").
+		Where("project = ?", project)
+	if domain != "" {
+		query = query.Where("domain = ?", strings.ToLower(domain))
+	}
+
+	var audits []models.SecurityAudit
+	if err := query.Order("id").Find(&audits).Error; err != nil {
+		return nil, fmt.Errorf("failed to load security audits: %w", err)
+	}
+
+	// The last audit of a URL wins
+	latest := make(map[string]models.SecurityAudit, len(audits))
+	for _, a := range audits {
+		latest[a.URL] = a
+	}
+
+	byDomain := make(map[string]*DomainSecurityReport)
+	totals := make(map[string]int)
+	for _, a := range latest {
+		report, ok := byDomain[a.Domain]
+		if !ok {
+			report = &DomainSecurityReport{
+				Domain:   a.Domain,
+				MinScore: a.Score,
+				Coverage: make(map[string]int),
+				Issues:   make(map[string]int),
+			}
+			byDomain[a.Domain] = report
+		}
+
+		report.Pages++
+		totals[a.Domain] += a.Score
+		report.MinScore = min(report.MinScore, a.Score)
+		for header, value := range map[string]string{
+			"Content-Security-Policy":   a.CSP,
+			"Strict-Transport-Security": a.HSTS,
+			"X-Frame-Options":           a.XFrameOptions,
+			"X-Content-Type-Options":    a.XContentTypeOptions,
+			"Referrer-Policy":           a.ReferrerPolicy,
+			"Permissions-Policy":        a.PermissionsPolicy,
+		} {
+			if value != "" {
+				report.Coverage[header]++
+			}
+		}
+
+		page := PageSecurity{URL: a.URL, Score: a.Score}
+		if a.Issues != "" {
+			page.Issues = strings.Split(a.Issues, ",")
+		}
+		for _, issue := range page.Issues {
+			report.Issues[issue]++
+		}
+		report.WorstPages = append(report.WorstPages, page)
+	}
+
+	reports := make([]DomainSecurityReport, 0, len(byDomain))
+	for d, report := range byDomain {
+		report.AverageScore = float64(totals[d]) / float64(report.Pages)
+		report.Grade = securityGrade(report.AverageScore)
+		sort.Slice(report.WorstPages, func(i, j int) bool {
+			a, b := report.WorstPages[i], report.WorstPages[j]
+			if a.Score != b.Score {
+				return a.Score < b.Score
+			}
+			return a.URL < b.URL
+		})
+		if len(report.WorstPages) > securityReportWorstPages {
+			report.WorstPages = report.WorstPages[:securityReportWorstPages]
+		}
+		reports = append(reports, *report)
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].AverageScore != reports[j].AverageScore {
+			return reports[i].AverageScore < reports[j].AverageScore
+		}
+		return reports[i].Domain < reports[j].Domain
+	})
+
+	s.logger.Info("Security report built",
+		zap.String("project", project),
+		zap.Int("domains", len(reports)),
+		zap.Int("pages", len(latest)))
+	return reports, nil
+}
+
+// securityGrade maps an average score to a letter grade
+func securityGrade(score float64) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
+)
+
+// SiteMetadataConfig holds site metadata collection settings
+type SiteMetadataConfig struct {
+	RefreshEvery    time.Duration // Least time between two updates of a host (default 24h)
+	Timeout         time.Duration // Manifest download timeout (default 10s)
+	MaxManifestSize int64         // Larger manifests are ignored (default 256KB)
+	Client          *http.Client  // Defaults to http.DefaultClient
+	Clock           clock.Clock   // Defaults to the wall clock
+}
+
+// SiteMetadataService records the name, favicon and web app manifest of
+// crawled sites in the domains table
+type SiteMetadataService struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+	config SiteMetadataConfig
+
+	mu      sync.Mutex
+	checked map[string]time.Time // Host -> last update
+}
+
+// NewSiteMetadataService creates a site metadata service
+// CrawlerService.Initialize migrates its table
+func NewSiteMetadataService(logger *zap.Logger, dbClient database.DatabaseClient, config SiteMetadataConfig) *SiteMetadataService {
+	if config.RefreshEvery <= 0 {
+		config.RefreshEvery = 24 * time.Hour
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxManifestSize <= 0 {
+		config.MaxManifestSize = 256 << 10
+	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	config.Clock = clock.Or(config.Clock)
+	return &SiteMetadataService{
+		logger:  logger,
+		db:      dbClient,
+		config:  config,
+		checked: make(map[string]time.Time),
+	}
+}
+
+// Record saves the metadata of the site pageURL belongs to, fetching its web
+// app manifest when the page links one. Hosts updated less than
+// RefreshEvery ago are skipped and return nil. A manifest that cannot be
+// fetched is logged and the page's own metadata is saved
+func (s *SiteMetadataService) Record(ctx context.Context, pageURL string, meta extractors.SiteMetadata) (*models.Domain, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return nil, nil
+	}
+	host := strings.ToLower(u.Host)
+
+	now := s.config.Clock.Now()
+	s.mu.Lock()
+	if last, ok := s.checked[host]; ok && now.Sub(last) < s.config.RefreshEvery {
+		s.mu.Unlock()
+		return nil, nil
+	}
+	s.checked[host] = now
+	s.mu.Unlock()
+
+	domain := &models.Domain{
+		Host:        host,
+		SiteName:    meta.SiteName,
+		FaviconURL:  meta.FaviconURL,
+		IconURL:     meta.FaviconURL,
+		ManifestURL: meta.ManifestURL,
+		ThemeColor:  meta.ThemeColor,
+		CheckedAt:   now,
+	}
+	if meta.ManifestURL != "" {
+		if manifest, err := s.fetchManifest(ctx, meta.ManifestURL); err != nil {
+			s.logger.Warn("Failed to fetch web app manifest",
+				zap.String("host", host),
+				zap.String("manifest_url", meta.ManifestURL),
+				zap.Error(err))
+		} else {
+			applyManifest(domain, manifest)
+		}
+	}
+
+	err = s.db.GetDB().WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "host"}},
+			DoUpdates: clause.AssignmentColumns([]string{"site_name", "short_name", "favicon_url", "icon_url", "manifest_url", "theme_color", "background_color", "checked_at", "updated_at"}),
+		}).
+		Create(domain).Error
+	if err != nil {
+		// Allow another attempt on the next crawl
+		s.mu.Lock()
+		delete(s.checked, host)
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to save metadata of %s: %w", host, err)
+	}
+	return domain, nil
+}
+
+// applyManifest fills domain from a web app manifest, which names a site
+// more deliberately than its pages' meta tags
+func applyManifest(domain *models.Domain, manifest *extractors.WebManifest) {
+	if manifest.Name != "" {
+		domain.SiteName = manifest.Name
+	} else if domain.SiteName == "" {
+		domain.SiteName = manifest.ShortName
+	}
+	domain.ShortName = manifest.ShortName
+	if icon := manifest.BestIcon(); icon != "" {
+		domain.IconURL = icon
+	}
+	if manifest.ThemeColor != "" {
+		domain.ThemeColor = manifest.ThemeColor
+	}
+	domain.BackgroundColor = manifest.BackgroundColor
+}
+
+// fetchManifest downloads and parses a web app manifest
+func (s *SiteMetadataService) fetchManifest(ctx context.Context, manifestURL string) (*extractors.WebManifest, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/manifest+json, application/json")
+
+	resp, err := s.config.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("status code: %d", resp.StatusCode)
+	}
+
+	// Read one byte past the limit to tell a full manifest from an oversized one
+	data, err := io.ReadAll(io.LimitReader(resp.Body, s.config.MaxManifestSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.config.MaxManifestSize {
+		return nil, fmt.Errorf("manifest exceeds %d bytes", s.config.MaxManifestSize)
+	}
+	return extractors.ParseWebManifest(data, manifestURL)
+}
+
+// List returns the recorded domains ordered by host; with host only that
+// one is returned
+func (s *SiteMetadataService) List(ctx context.Context, host string) ([]models.Domain, error) {
+	query := s.db.GetDB().WithContext(ctx)
+	if host != "" {
+		query = query.Where("host = ?", strings.ToLower(host))
+	}
+
+	var domains []models.Domain
+	if err := query.Order("host").Find(&domains).Error; err != nil {
+		return nil, fmt.Errorf("failed to load domains: %w", err)
+	}
+	return domains, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/alonecandies/golwarc/database"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/models"
+	"go.uber.org/zap"
+)
+
+// SnapshotDiff compares what two crawls fetched, e.g. before and after a
+// deploy. URLs are listed in order; a URL whose status changed is only
+// listed under StatusChanged
+type SnapshotDiff struct {
+	Base          string    `json:"base"`           // Crawl ID of the earlier crawl
+	Target        string    `json:"target"`         // Crawl ID of the later crawl
+	Domains       []string  `json:"domains"`        // Domains compared
+	Added         []URLDiff `json:"added"`          // Fetched by the target crawl only
+	Removed       []URLDiff `json:"removed"`        // Fetched by the base crawl only
+	Changed       []URLDiff `json:"changed"`        // Same status, different content
+	StatusChanged []URLDiff `json:"status_changed"` // Different status or error
+	Unchanged     int       `json:"unchanged"`
+}
+
+// URLDiff is one URL of a SnapshotDiff
+type URLDiff struct {
+	URL          string `json:"url"`
+	BaseStatus   int    `json:"base_status,omitempty"`
+	TargetStatus int    `json:"target_status,omitempty"`
+	BaseError    string `json:"base_error,omitempty"`
+	TargetError  string `json:"target_error,omitempty"`
+}
+
+// SnapshotService compares crawls using their crawl logs
+type SnapshotService struct {
+	logger *zap.Logger
+	db     database.DatabaseClient
+}
+
+// NewSnapshotService creates a new crawl comparison service
+func NewSnapshotService(logger *zap.Logger, dbClient database.DatabaseClient) *SnapshotService {
+	return &SnapshotService{
+		logger: logger,
+		db:     dbClient,
+	}
+}
+
+// Compare diffs the URLs fetched by two crawls, identified by crawl ID
+// With a domain only that domain is compared; otherwise every domain both
+// crawls fetched. Content changes are detected from the body hash recorded
+// in the crawl log, so they are only reported when both fetches succeeded
+func (s *SnapshotService) Compare(ctx context.Context, base, target, domain string) (*SnapshotDiff, error) {
+	if base == "" || target == "" {
+		return nil, errs.New(errs.CodeInvalidRequest, "base and target crawl IDs are required")
+	}
+	if base == target {
+		return nil, errs.New(errs.CodeInvalidRequest, "base and target must be different crawls")
+	}
+
+	var logs []models.CrawlLog
+	err := s.db.GetDB().WithContext(ctx).
+		Model(&models.CrawlLog{}).
+		Select("crawl_id", "url", "domain", "status", "error", "content_hash", "skip_reason").
+		Where("crawl_id IN ?", []string{base, target}).
+		Order("created_at, id").
+		Find(&logs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load crawl logs: %w", err)
+	}
+
+	// The last fetch of a URL in a crawl wins
+	fetches := map[string]map[string]models.CrawlLog{base: {}, target: {}}
+	domains := map[string]map[string]bool{base: {}, target: {}}
+	for _, l := range logs {
+		if l.SkipReason != "" {
+			continue // Not fetched
+		}
+		l.Domain = logDomain(l)
+		if domain != "" && l.Domain != strings.ToLower(domain) {
+			continue
+		}
+		fetches[l.CrawlID][l.URL] = l
+		domains[l.CrawlID][l.Domain] = true
+	}
+	for _, id := range []string{base, target} {
+		if len(fetches[id]) == 0 {
+			return nil, errs.Newf(errs.CodeNotFound, "crawl %s has no logged fetches", id)
+		}
+	}
+
+	diff := &SnapshotDiff{Base: base, Target: target}
+	for d := range domains[base] {
+		if domains[target][d] {
+			diff.Domains = append(diff.Domains, d)
+		}
+	}
+	if len(diff.Domains) == 0 {
+		return nil, errs.Newf(errs.CodeInvalidRequest, "crawls %s and %s share no domain", base, target)
+	}
+	sort.Strings(diff.Domains)
+	shared := make(map[string]bool, len(diff.Domains))
+	for _, d := range diff.Domains {
+		shared[d] = true
+	}
+
+	urls := make(map[string]bool)
+	for _, id := range []string{base, target} {
+		for u, l := range fetches[id] {
+			if shared[l.Domain] {
+				urls[u] = true
+			}
+		}
+	}
+	sorted := make([]string, 0, len(urls))
+	for u := range urls {
+		sorted = append(sorted, u)
+	}
+	sort.Strings(sorted)
+
+	for _, u := range sorted {
+		before, inBase := fetches[base][u]
+		after, inTarget := fetches[target][u]
+		entry := URLDiff{
+			URL:          u,
+			BaseStatus:   before.Status,
+			TargetStatus: after.Status,
+			BaseError:    before.Error,
+			TargetError:  after.Error,
+		}
+		switch {
+		case !inBase:
+			diff.Added = append(diff.Added, entry)
+		case !inTarget:
+			diff.Removed = append(diff.Removed, entry)
+		case before.Status != after.Status || (before.Error == "") != (after.Error == ""):
+			diff.StatusChanged = append(diff.StatusChanged, entry)
+		case before.ContentHash != "" && after.ContentHash != "" && before.ContentHash != after.ContentHash:
+			diff.Changed = append(diff.Changed, entry)
+		default:
+			diff.Unchanged++
+		}
+	}
+
+	s.logger.Info("Crawls compared",
+		zap.String("base", base),
+		zap.String("target", target),
+		zap.Int("added", len(diff.Added)),
+		zap.Int("removed", len(diff.Removed)),
+		zap.Int("changed", len(diff.Changed)),
+		zap.Int("status_changed", len(diff.StatusChanged)))
+	return diff, nil
+}
+
+// logDomain returns the domain of a crawl log entry; failed fetches are
+// logged without one, so it falls back to the URL host
+func logDomain(l models.CrawlLog) string {
+	if l.Domain != "" {
+		return strings.ToLower(l.Domain)
+	}
+	if u, err := url.Parse(l.URL); err == nil {
+		return strings.ToLower(u.Host)
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/inject"
+)
+
+// sessionUsage documents the session subcommands
+const sessionUsage = `usage: golwarc session <command> [arguments]
+
+commands:
+  login [-out session.state] <url>  open a browser at url, log in by hand and press Enter to save the session
+  show <file>                       list the cookies and localStorage origins of a saved session
+
+Session files are encrypted with the passphrase in $` + crawlers.SessionStateKeyEnv + `
+`
+
+// runSession runs a session subcommand and returns the process exit code
+func runSession(container *inject.Container, args []string) int {
+	defer func() {
+		_ = container.Close() // Error intentionally ignored on close
+	}()
+
+	passphrase := os.Getenv(crawlers.SessionStateKeyEnv)
+	if passphrase == "" {
+		fmt.Fprintf(os.Stderr, "session: set %s to the session file passphrase\n", crawlers.SessionStateKeyEnv)
+		return 1
+	}
+	if err := sessionCommand(container.Config.Crawler.PlaywrightBrowser, passphrase, args, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "session: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// sessionCommand dispatches a session subcommand
+func sessionCommand(browser, passphrase string, args []string, in io.Reader, w io.Writer) error {
+	if len(args) == 0 {
+		_, _ = io.WriteString(os.Stderr, sessionUsage)
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "login":
+		flags := flag.NewFlagSet("login", flag.ContinueOnError)
+		out := flags.String("out", "session.state", "Where to write the encrypted session")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+		if flags.NArg() != 1 {
+			return fmt.Errorf("login takes exactly one URL")
+		}
+
+		client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{BrowserType: browser, Timeout: time.Minute})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = client.Close() // Error intentionally ignored on close
+		}()
+		if err := client.Navigate(flags.Arg(0)); err != nil {
+			return err
+		}
+		fmt.Fprintln(w, "log in in the browser window, then press Enter here to save the session")
+		if _, err := bufio.NewReader(in).ReadString('\n'); err != nil && err != io.EOF {
+			return err
+		}
+		if err := client.ExportSessionState(*out, passphrase); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "session saved to %s\n", *out)
+		return nil
+
+	case "show":
+		if len(args) != 2 {
+			return fmt.Errorf("show takes exactly one file")
+		}
+		state, err := crawlers.LoadSessionState(args[1], passphrase)
+		if err != nil {
+			return err
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "COOKIE\tDOMAIN\tPATH\tEXPIRES")
+		for _, c := range state.Cookies {
+			expires := "session"
+			if c.Expires > 0 {
+				expires = time.Unix(int64(c.Expires), 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Name, c.Domain, c.Path, expires)
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+		for _, o := range state.Origins {
+			fmt.Fprintf(w, "localStorage %s: %d keys\n", o.Origin, len(o.LocalStorage))
+		}
+		return nil
+	}
+
+	_, _ = io.WriteString(os.Stderr, sessionUsage)
+	return fmt.Errorf("unknown command %q", args[0])
+}
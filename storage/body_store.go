@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/alonecandies/golwarc/models"
+)
+
+// BodyStoreConfig holds size thresholds for choosing a body codec
+type BodyStoreConfig struct {
+	InlineMaxSize     int       // Bodies up to this size stay inline and queryable (default 64KB)
+	CompressedMaxSize int       // Bodies up to this size are gzip-compressed in the database (default 1MB)
+	Large             BodyCodec // Codec for bodies above CompressedMaxSize (defaults to gzip)
+}
+
+// BodyStore chooses a codec per page by body size and decodes pages
+// by the codec recorded on them
+type BodyStore struct {
+	inlineMax     int
+	compressedMax int
+	inline        BodyCodec
+	compressed    BodyCodec
+	large         BodyCodec
+	codecs        map[string]BodyCodec
+}
+
+// NewBodyStore creates a size-tiered body store
+func NewBodyStore(config BodyStoreConfig) *BodyStore {
+	if config.InlineMaxSize <= 0 {
+		config.InlineMaxSize = 64 * 1024
+	}
+	if config.CompressedMaxSize <= 0 {
+		config.CompressedMaxSize = 1024 * 1024
+	}
+
+	store := &BodyStore{
+		inlineMax:     config.InlineMaxSize,
+		compressedMax: config.CompressedMaxSize,
+		inline:        InlineCodec{},
+		compressed:    GzipCodec{},
+		codecs:        make(map[string]BodyCodec),
+	}
+	store.large = store.compressed
+	if config.Large != nil {
+		store.large = config.Large
+	}
+
+	store.Register(store.inline)
+	store.Register(store.compressed)
+	store.Register(store.large)
+	return store
+}
+
+// Register makes a codec available for decoding pages stored with it
+func (s *BodyStore) Register(codec BodyCodec) {
+	s.codecs[codec.Name()] = codec
+}
+
+// CodecFor returns the codec that would be used for a body of the given size
+func (s *BodyStore) CodecFor(size int) BodyCodec {
+	switch {
+	case size <= s.inlineMax:
+		return s.inline
+	case size <= s.compressedMax:
+		return s.compressed
+	default:
+		return s.large
+	}
+}
+
+// Save encodes the body onto the page using the size-appropriate codec
+func (s *BodyStore) Save(page *models.Page, body []byte) error {
+	codec := s.CodecFor(len(body))
+	if err := codec.Encode(page, body); err != nil {
+		return fmt.Errorf("failed to encode body with %s codec: %w", codec.Name(), err)
+	}
+	return nil
+}
+
+// Load returns the page body, decoding it with the codec recorded on the page
+func (s *BodyStore) Load(page *models.Page) ([]byte, error) {
+	name := page.BodyCodec
+	if name == "" {
+		name = CodecInline // Pages stored before codecs existed
+	}
+
+	codec, ok := s.codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown body codec: %s", name)
+	}
+	return codec.Decode(page)
+}
+
+// Close closes codecs that hold resources (e.g. open WARC files)
+func (s *BodyStore) Close() error {
+	for _, codec := range s.codecs {
+		if closer, ok := codec.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
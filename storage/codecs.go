@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/warc"
+)
+
+// InlineCodec keeps the body in the pages table so it stays queryable
+type InlineCodec struct{}
+
+// Name returns the codec name
+func (InlineCodec) Name() string {
+	return CodecInline
+}
+
+// Encode stores the body in page.HTML
+func (InlineCodec) Encode(page *models.Page, body []byte) error {
+	page.HTML = string(body)
+	page.BodyData = nil
+	page.BodyRef = ""
+	page.BodyCodec = CodecInline
+	page.BodySize = int64(len(body))
+	return nil
+}
+
+// Decode returns page.HTML
+func (InlineCodec) Decode(page *models.Page) ([]byte, error) {
+	return []byte(page.HTML), nil
+}
+
+// GzipCodec stores a gzip-compressed body in the database
+type GzipCodec struct{}
+
+// Name returns the codec name
+func (GzipCodec) Name() string {
+	return CodecGzip
+}
+
+// Encode compresses the body into page.BodyData
+func (GzipCodec) Encode(page *models.Page, body []byte) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(body); err != nil {
+		return fmt.Errorf("failed to compress body: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("failed to compress body: %w", err)
+	}
+
+	page.HTML = ""
+	page.BodyData = buf.Bytes()
+	page.BodyRef = ""
+	page.BodyCodec = CodecGzip
+	page.BodySize = int64(len(body))
+	return nil
+}
+
+// Decode decompresses page.BodyData
+func (GzipCodec) Decode(page *models.Page) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(page.BodyData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress body: %w", err)
+	}
+	defer func() {
+		_ = gz.Close() // Error intentionally ignored on close
+	}()
+	return io.ReadAll(gz)
+}
+
+// ObjectCodec stores bodies in an ObjectStore keyed by content hash
+type ObjectCodec struct {
+	store  ObjectStore
+	prefix string
+}
+
+// NewObjectCodec creates a codec that writes bodies to blob storage
+func NewObjectCodec(store ObjectStore) *ObjectCodec {
+	return &ObjectCodec{
+		store:  store,
+		prefix: "pages/",
+	}
+}
+
+// Name returns the codec name
+func (c *ObjectCodec) Name() string {
+	return CodecObject
+}
+
+// Encode writes the body to the object store and records its key
+func (c *ObjectCodec) Encode(page *models.Page, body []byte) error {
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+	key := c.prefix + hash[:2] + "/" + hash
+
+	exists, err := c.store.Exists(key)
+	if err != nil {
+		return fmt.Errorf("failed to check object %s: %w", key, err)
+	}
+	if !exists {
+		if err := c.store.Put(key, body); err != nil {
+			return fmt.Errorf("failed to store object %s: %w", key, err)
+		}
+	}
+
+	page.HTML = ""
+	page.BodyData = nil
+	page.BodyRef = key
+	page.BodyCodec = CodecObject
+	page.BodySize = int64(len(body))
+	return nil
+}
+
+// Decode reads the body from the object store
+func (c *ObjectCodec) Decode(page *models.Page) ([]byte, error) {
+	return c.store.Get(page.BodyRef)
+}
+
+// WARCCodec appends bodies to a WARC file and references them by offset
+type WARCCodec struct {
+	writer *warc.Writer
+	dedup  *warc.DedupWriter
+}
+
+// NewWARCCodec creates a codec writing resource records to the given WARC writer
+func NewWARCCodec(writer *warc.Writer) *WARCCodec {
+	return &WARCCodec{writer: writer}
+}
+
+// NewDedupWARCCodec creates a codec that writes revisit records for payloads
+// already archived; pages then reference the original capture
+func NewDedupWARCCodec(writer *warc.Writer, store warc.DigestStore) *WARCCodec {
+	return &WARCCodec{
+		writer: writer,
+		dedup:  warc.NewDedupWriter(writer, store),
+	}
+}
+
+// Name returns the codec name
+func (c *WARCCodec) Name() string {
+	return CodecWARC
+}
+
+// Encode writes a resource record and stores "path#offset" as the reference
+func (c *WARCCodec) Encode(page *models.Page, body []byte) error {
+	rec := warc.NewRecord(warc.TypeResource, page.URL)
+	rec.Header.Set(warc.HeaderContentType, "text/html")
+	rec.Content = body
+
+	ref, err := c.write(rec)
+	if err != nil {
+		return err
+	}
+
+	page.HTML = ""
+	page.BodyData = nil
+	page.BodyRef = ref
+	page.BodyCodec = CodecWARC
+	page.BodySize = int64(len(body))
+	return nil
+}
+
+// write writes the record and returns the reference to the full payload
+func (c *WARCCodec) write(rec *warc.Record) (string, error) {
+	if c.dedup != nil {
+		_, capture, _, err := c.dedup.WriteCapture(rec)
+		if err != nil {
+			return "", err
+		}
+		return FormatWARCRef(capture.File, capture.Offset), nil
+	}
+
+	path, offset, err := c.writer.AppendRecord(rec)
+	if err != nil {
+		return "", err
+	}
+	return FormatWARCRef(path, offset), nil
+}
+
+// Decode reads the referenced record from the WARC file
+func (c *WARCCodec) Decode(page *models.Page) ([]byte, error) {
+	path, offset, err := ParseWARCRef(page.BodyRef)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := warc.ReadRecordAt(path, offset)
+	if err != nil {
+		return nil, err
+	}
+	return rec.Content, nil
+}
+
+// Close closes the underlying WARC writer
+func (c *WARCCodec) Close() error {
+	return c.writer.Close()
+}
+
+// FormatWARCRef builds a "path#offset" WARC record reference
+func FormatWARCRef(path string, offset int64) string {
+	return path + "#" + strconv.FormatInt(offset, 10)
+}
+
+// ParseWARCRef splits a "path#offset" WARC record reference
+func ParseWARCRef(ref string) (string, int64, error) {
+	idx := strings.LastIndex(ref, "#")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("invalid WARC reference: %q", ref)
+	}
+	offset, err := strconv.ParseInt(ref[idx+1:], 10, 64)
+	if err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("invalid WARC reference offset: %q", ref)
+	}
+	return ref[:idx], offset, nil
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/alonecandies/golwarc/models"
+)
+
+// Codec names recorded in models.Page.BodyCodec
+const (
+	CodecInline = "inline"
+	CodecGzip   = "gzip"
+	CodecObject = "object"
+	CodecWARC   = "warc"
+)
+
+// BodyCodec defines how a page body is persisted
+// Implementations fill the page's body fields on Encode and read them back on Decode
+type BodyCodec interface {
+	// Name returns the codec name stored on the page
+	Name() string
+
+	// Encode stores the body and records where it lives on the page
+	Encode(page *models.Page, body []byte) error
+
+	// Decode returns the body of a page previously encoded with this codec
+	Decode(page *models.Page) ([]byte, error)
+}
+
+// ObjectStore defines the interface for blob storage backends
+// A local filesystem implementation is provided; cloud object stores can implement the same interface
+type ObjectStore interface {
+	// Put stores data under the given key
+	Put(key string, data []byte) error
+
+	// Get retrieves the data stored under the given key
+	Get(key string) ([]byte, error)
+
+	// Delete removes the data stored under the given key
+	Delete(key string) error
+
+	// Exists checks if a key exists
+	Exists(key string) (bool, error)
+}
+
+// Storage classes for archived objects
+const (
+	StorageClassStandard = "standard"
+	StorageClassCold     = "cold"
+)
+
+// ObjectInfo describes a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ModTime      time.Time
+	StorageClass string
+}
+
+// TieredObjectStore is an ObjectStore whose objects can move between storage classes
+type TieredObjectStore interface {
+	ObjectStore
+
+	// List returns the objects whose keys start with prefix
+	List(prefix string) ([]ObjectInfo, error)
+
+	// SetStorageClass moves an object to the given storage class
+	SetStorageClass(key, class string) error
+}
+
+// Ensure implementations satisfy the interfaces
+var (
+	_ BodyCodec   = (*InlineCodec)(nil)
+	_ BodyCodec   = (*GzipCodec)(nil)
+	_ BodyCodec   = (*ObjectCodec)(nil)
+	_ BodyCodec   = (*WARCCodec)(nil)
+	_ ObjectStore = (*FileObjectStore)(nil)
+
+	_ TieredObjectStore = (*FileObjectStore)(nil)
+)
@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/alonecandies/golwarc/warc"
+)
+
+// LifecycleConfig holds archive maintenance settings
+type LifecycleConfig struct {
+	WARCDir      string                        // Directory holding .warc.gz files; empty skips WARC maintenance
+	ActiveFiles  []string                      // Files still being written, never compacted
+	Writers      []*warc.Writer                // Open writers; the file each is writing is never compacted
+	CompactBelow int64                         // Files smaller than this are compacted together (default 64MB)
+	Objects      TieredObjectStore             // Object store to tier; nil skips object transitions
+	ColdAfter    time.Duration                 // Objects older than this move to cold storage; 0 disables
+	OnRelocate   func([]warc.Relocation) error // Called before compacted sources are removed
+	Now          func() time.Time              // Clock override for tests
+}
+
+// LifecycleReport summarizes one maintenance run
+type LifecycleReport struct {
+	Compacted     []string // Source files merged and removed
+	CompactedInto string   // File the sources were merged into
+	Relocated     int      // Number of records moved
+	Reindexed     []string // WARC files whose offset index was rebuilt
+	Transitioned  []string // Object keys moved to cold storage
+}
+
+// Lifecycle compacts small WARC files, keeps their offset indexes current,
+// and moves old objects to cold storage
+type Lifecycle struct {
+	config LifecycleConfig
+	active map[string]bool
+}
+
+// NewLifecycle creates an archive maintenance job
+func NewLifecycle(config LifecycleConfig) *Lifecycle {
+	if config.CompactBelow <= 0 {
+		config.CompactBelow = 64 * 1024 * 1024
+	}
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+
+	active := make(map[string]bool, len(config.ActiveFiles))
+	for _, path := range config.ActiveFiles {
+		active[filepath.Clean(path)] = true
+	}
+
+	return &Lifecycle{
+		config: config,
+		active: active,
+	}
+}
+
+// RunOnce performs a single maintenance pass
+func (l *Lifecycle) RunOnce() (LifecycleReport, error) {
+	var report LifecycleReport
+
+	if l.config.WARCDir != "" {
+		if err := l.compact(&report); err != nil {
+			return report, err
+		}
+		if err := l.reindex(&report); err != nil {
+			return report, err
+		}
+	}
+
+	if l.config.Objects != nil && l.config.ColdAfter > 0 {
+		if err := l.transition(&report); err != nil {
+			return report, err
+		}
+	}
+
+	return report, nil
+}
+
+// warcFiles lists the WARC files in the configured directory, oldest name first
+func (l *Lifecycle) warcFiles() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(l.config.WARCDir, "*.warc.gz"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list WARC files: %w", err)
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// activeFiles returns the configured active files and the files the writers
+// are writing now; a rotated writer has moved on, so its old files are not
+func (l *Lifecycle) activeFiles() map[string]bool {
+	active := make(map[string]bool, len(l.active)+len(l.config.Writers))
+	for path := range l.active {
+		active[path] = true
+	}
+	for _, w := range l.config.Writers {
+		active[filepath.Clean(w.Path())] = true
+	}
+	return active
+}
+
+// compact merges small, inactive WARC files into a single new file
+func (l *Lifecycle) compact(report *LifecycleReport) error {
+	files, err := l.warcFiles()
+	if err != nil {
+		return err
+	}
+
+	active := l.activeFiles()
+	var small []string
+	for _, path := range files {
+		if active[filepath.Clean(path)] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		if info.Size() < l.config.CompactBelow {
+			small = append(small, path)
+		}
+	}
+	if len(small) < 2 {
+		return nil
+	}
+
+	dest := filepath.Join(l.config.WARCDir,
+		fmt.Sprintf("compacted-%s.warc.gz", l.config.Now().UTC().Format("20060102150405")))
+	relocations, err := warc.Compact(small, dest)
+	if err != nil {
+		removeWARC(dest) // Best effort cleanup of the partial file
+		return err
+	}
+
+	if l.config.OnRelocate != nil {
+		if err := l.config.OnRelocate(relocations); err != nil {
+			// References were not updated, so the sources must stay
+			removeWARC(dest)
+			return fmt.Errorf("failed to update relocated references: %w", err)
+		}
+	}
+
+	for _, path := range small {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove compacted file %s: %w", path, err)
+		}
+		for _, sidecar := range []string{warc.IndexPath(path), warc.CDXPath(path)} {
+			if err := os.Remove(sidecar); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to remove index of %s: %w", path, err)
+			}
+		}
+	}
+
+	report.Compacted = small
+	report.CompactedInto = dest
+	report.Relocated = len(relocations)
+	return nil
+}
+
+// reindex rebuilds offset indexes that are missing or older than their WARC file
+func (l *Lifecycle) reindex(report *LifecycleReport) error {
+	files, err := l.warcFiles()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range files {
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", path, err)
+		}
+		idx, err := os.Stat(warc.IndexPath(path))
+		if err == nil && !idx.ModTime().Before(info.ModTime()) {
+			continue
+		}
+		if _, err := warc.RebuildIndex(path); err != nil {
+			return fmt.Errorf("failed to rebuild index of %s: %w", path, err)
+		}
+		report.Reindexed = append(report.Reindexed, path)
+	}
+	return nil
+}
+
+// transition moves objects older than ColdAfter to the cold storage class
+func (l *Lifecycle) transition(report *LifecycleReport) error {
+	objects, err := l.config.Objects.List("")
+	if err != nil {
+		return err
+	}
+
+	cutoff := l.config.Now().Add(-l.config.ColdAfter)
+	for _, obj := range objects {
+		if obj.StorageClass != StorageClassStandard || !obj.ModTime.Before(cutoff) {
+			continue
+		}
+		if err := l.config.Objects.SetStorageClass(obj.Key, StorageClassCold); err != nil {
+			return err
+		}
+		report.Transitioned = append(report.Transitioned, obj.Key)
+	}
+	return nil
+}
+
+// removeWARC removes a WARC file and its index sidecars, ignoring errors
+func removeWARC(path string) {
+	_ = os.Remove(path)
+	_ = os.Remove(warc.IndexPath(path))
+	_ = os.Remove(warc.CDXPath(path))
+}
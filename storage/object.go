@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// coldDir is the reserved subdirectory holding objects in the cold storage class
+const coldDir = ".cold"
+
+// FileObjectStore stores objects as files below a root directory
+// Objects in the cold storage class are kept under a reserved subdirectory
+type FileObjectStore struct {
+	root string
+}
+
+// NewFileObjectStore creates a filesystem-backed object store
+func NewFileObjectStore(root string) (*FileObjectStore, error) {
+	if root == "" {
+		return nil, errs.New(errs.CodeStorageConfig, "object store root cannot be empty")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create object store root: %w", err)
+	}
+	return &FileObjectStore{root: root}, nil
+}
+
+// Put stores data under the given key in the standard storage class
+func (s *FileObjectStore) Put(key string, data []byte) error {
+	path, err := s.path(key, StorageClassStandard)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+
+	// Drop any stale cold copy so the new data wins
+	if cold, err := s.path(key, StorageClassCold); err == nil {
+		_ = os.Remove(cold) // Error intentionally ignored; the copy may not exist
+	}
+	return nil
+}
+
+// Get retrieves the data stored under the given key from any storage class
+func (s *FileObjectStore) Get(key string) ([]byte, error) {
+	path, _, err := s.locate(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Delete removes the data stored under the given key
+func (s *FileObjectStore) Delete(key string) error {
+	for _, class := range []string{StorageClassStandard, StorageClassCold} {
+		path, err := s.path(key, class)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Exists checks if a key exists in any storage class
+func (s *FileObjectStore) Exists(key string) (bool, error) {
+	_, _, err := s.locate(key)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// List returns the objects whose keys start with prefix
+func (s *FileObjectStore) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+
+	for _, class := range []string{StorageClassStandard, StorageClassCold} {
+		base := s.root
+		if class == StorageClassCold {
+			base = filepath.Join(s.root, coldDir)
+		}
+
+		err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				if class == StorageClassStandard && path == filepath.Join(s.root, coldDir) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(path, ".tmp") {
+				return nil
+			}
+
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			key := filepath.ToSlash(rel)
+			if !strings.HasPrefix(key, prefix) {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			objects = append(objects, ObjectInfo{
+				Key:          key,
+				Size:         info.Size(),
+				ModTime:      info.ModTime(),
+				StorageClass: class,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", err)
+		}
+	}
+
+	return objects, nil
+}
+
+// SetStorageClass moves an object to the given storage class
+func (s *FileObjectStore) SetStorageClass(key, class string) error {
+	if class != StorageClassStandard && class != StorageClassCold {
+		return fmt.Errorf("unknown storage class: %s", class)
+	}
+
+	current, currentClass, err := s.locate(key)
+	if err != nil {
+		return err
+	}
+	if currentClass == class {
+		return nil
+	}
+
+	target, err := s.path(key, class)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	if err := os.Rename(current, target); err != nil {
+		return fmt.Errorf("failed to move object %s to %s: %w", key, class, err)
+	}
+	return nil
+}
+
+// Root returns the root directory of the store
+func (s *FileObjectStore) Root() string {
+	return s.root
+}
+
+// locate finds the file holding key and its storage class
+func (s *FileObjectStore) locate(key string) (string, string, error) {
+	for _, class := range []string{StorageClassStandard, StorageClassCold} {
+		path, err := s.path(key, class)
+		if err != nil {
+			return "", "", err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, class, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", "", err
+		}
+	}
+	return "", "", errs.Wrapf(os.ErrNotExist, errs.CodeStorageNotFound, "object %s", key)
+}
+
+// path maps a key to a file path, rejecting keys that escape the root
+func (s *FileObjectStore) path(key, class string) (string, error) {
+	if key == "" {
+		return "", errs.New(errs.CodeStorageConfig, "object key cannot be empty")
+	}
+	clean := filepath.Clean(filepath.FromSlash(key))
+	if clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) || filepath.IsAbs(clean) ||
+		clean == coldDir || strings.HasPrefix(clean, coldDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid object key: %s", key)
+	}
+
+	if class == StorageClassCold {
+		return filepath.Join(s.root, coldDir, clean), nil
+	}
+	return filepath.Join(s.root, clean), nil
+}
+
+// writeFileAtomic writes to a temp file first so readers never see partial objects
+// Each write gets its own temp file, so concurrent puts of one key cannot clobber each other
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create object: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()           // Best effort cleanup
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()           // Best effort cleanup
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name()) // Best effort cleanup
+		return fmt.Errorf("failed to replace object: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/warc"
+)
+
+// =============================================================================
+// ArchiveHandler Tests
+// =============================================================================
+
+func newArchiveServer(t *testing.T) *api.Server {
+	t.Helper()
+
+	dir := t.TempDir()
+	w, err := warc.NewWriter(filepath.Join(dir, "test.warc.gz"))
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.EnableCDX(); err != nil {
+		t.Fatalf("EnableCDX() error = %v", err)
+	}
+	rec := warc.NewRecord(warc.TypeResource, "https://example.com/")
+	rec.Header.Set(warc.HeaderContentType, "text/html")
+	rec.Content = []byte("<html>archived</html>")
+	if _, _, err := w.WriteRecord(rec); err != nil {
+		t.Fatalf("WriteRecord() error = %v", err)
+	}
+	_ = w.Close()
+
+	index := warc.NewCDXIndex(dir)
+	if err := index.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(api.NewArchiveHandler(index))
+	return server
+}
+
+func TestArchiveHandler_Lookup(t *testing.T) {
+	server := newArchiveServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cdx?url=https://example.com/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	var captures []map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &captures); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(captures) != 1 || captures[0]["url"] != "https://example.com/" {
+		t.Errorf("Unexpected captures: %v", captures)
+	}
+}
+
+func TestArchiveHandler_Lookup_MissingURL(t *testing.T) {
+	server := newArchiveServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/cdx", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, want 400", rec.Code)
+	}
+}
+
+func TestArchiveHandler_Replay(t *testing.T) {
+	server := newArchiveServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/replay?url=https://example.com/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	if rec.Body.String() != "<html>archived</html>" {
+		t.Errorf("Body = %q", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/html" {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+	if rec.Header().Get("Memento-Datetime") == "" {
+		t.Error("Expected Memento-Datetime header")
+	}
+}
+
+func TestArchiveHandler_Replay_NotFound(t *testing.T) {
+	server := newArchiveServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/replay?url=https://missing.example/", nil))
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Status = %d, want 404", rec.Code)
+	}
+}
+
+func TestArchiveHandler_Replay_BadTimestamp(t *testing.T) {
+	server := newArchiveServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/replay?url=https://example.com/&timestamp=abc", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, want 400", rec.Code)
+	}
+}
+
+func TestArchiveHandler_Replay_Revisit(t *testing.T) {
+	dir := t.TempDir()
+	w, err := warc.NewWriter(filepath.Join(dir, "test.warc.gz"))
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.EnableCDX(); err != nil {
+		t.Fatalf("EnableCDX() error = %v", err)
+	}
+	dedup := warc.NewDedupWriter(w, nil)
+	for _, target := range []string{"https://example.com/a", "https://example.com/b"} {
+		rec := warc.NewRecord(warc.TypeResource, target)
+		rec.Header.Set(warc.HeaderContentType, "text/html")
+		rec.Content = []byte("<html>shared</html>")
+		if _, _, _, err := dedup.WriteCapture(rec); err != nil {
+			t.Fatalf("WriteCapture() error = %v", err)
+		}
+	}
+	_ = w.Close()
+
+	index := warc.NewCDXIndex(dir)
+	if err := index.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(api.NewArchiveHandler(index))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/replay?url=https://example.com/b", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	if rec.Body.String() != "<html>shared</html>" {
+		t.Errorf("Body = %q", rec.Body.String())
+	}
+	if rec.Header().Get("Content-Type") != "text/html" {
+		t.Errorf("Content-Type = %q", rec.Header().Get("Content-Type"))
+	}
+}
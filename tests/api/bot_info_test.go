@@ -0,0 +1,78 @@
+package api_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Bot Info Tests
+// =============================================================================
+
+func newBotInfoServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	identity := &crawlers.BotIdentity{
+		Name:        "AcmeBot",
+		Version:     "1.0",
+		InfoURL:     "https://acme.example/bot",
+		Email:       "bot@acme.example",
+		Operator:    "Acme <Research>",
+		Description: "Archives public product pages.",
+	}
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(api.NewBotInfoHandler(identity))
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return httpServer
+}
+
+func TestBotInfo_JSON(t *testing.T) {
+	server := newBotInfoServer(t)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+api.BotInfoPath, nil)
+	req.Header.Set("Accept", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var info api.BotInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if info.UserAgent != "Mozilla/5.0 (compatible; AcmeBot/1.0; +https://acme.example/bot; bot@acme.example)" {
+		t.Errorf("Unexpected user agent %q", info.UserAgent)
+	}
+	if info.RobotsAgent != "acmebot" || info.Email != "bot@acme.example" || info.Operator != "Acme <Research>" {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+}
+
+func TestBotInfo_HTML(t *testing.T) {
+	server := newBotInfoServer(t)
+
+	resp, err := http.Get(server.URL + api.BotInfoPath)
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	page := string(body)
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") {
+		t.Errorf("Expected HTML, got %s", resp.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{"User-agent: acmebot", "mailto:bot@acme.example", "Acme &lt;Research&gt;", "Archives public product pages."} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Page is missing %q", want)
+		}
+	}
+}
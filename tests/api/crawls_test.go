@@ -0,0 +1,569 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/api/client"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+	"github.com/alonecandies/golwarc/mocks"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Crawl Job Tests
+// =============================================================================
+
+// fakeCrawler records crawled URLs and fails those containing "fail"
+type fakeCrawler struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (f *fakeCrawler) CrawlAndStore(url string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.urls = append(f.urls, url)
+	if strings.Contains(url, "fail") {
+		return errors.New("crawl failed")
+	}
+	return nil
+}
+
+// fakeScreenshotter writes a placeholder PNG
+type fakeScreenshotter struct{}
+
+func (fakeScreenshotter) CaptureScreenshot(_ context.Context, _, path string) error {
+	return os.WriteFile(path, []byte("\x89PNG"), 0o644)
+}
+
+func allowAll(string) error { return nil }
+
+func newCrawlServer(t *testing.T, config api.CrawlHandlerConfig) (*httptest.Server, *api.CrawlHandler) {
+	t.Helper()
+
+	if config.Crawler == nil {
+		config.Crawler = &fakeCrawler{}
+	}
+	if config.ValidateURL == nil {
+		config.ValidateURL = allowAll
+	}
+	handler := api.NewCrawlHandler(config)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	go handler.Start(ctx)
+
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(handler, api.NewUIHandler())
+	httpServer := httptest.NewServer(server.Handler())
+	t.Cleanup(httpServer.Close)
+	return httpServer, handler
+}
+
+func newCrawlClient(t *testing.T, baseURL string) *client.Client {
+	t.Helper()
+	c, err := client.NewClient(client.Config{BaseURL: baseURL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return c
+}
+
+// waitForJob polls until the job leaves the queue
+func waitForJob(t *testing.T, c *client.Client, id string) *api.CrawlJob {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := c.GetCrawl(context.Background(), id)
+		if err != nil {
+			t.Fatalf("GetCrawl() error = %v", err)
+		}
+		if job.Status == api.JobDone || job.Status == api.JobFailed {
+			return job
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Job %s did not finish", id)
+	return nil
+}
+
+func TestCrawlHandler_SubmitAndComplete(t *testing.T) {
+	dir := t.TempDir()
+	crawler := &fakeCrawler{}
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{
+		Crawler:       crawler,
+		Screenshotter: fakeScreenshotter{},
+		ScreenshotDir: dir,
+	})
+	c := newCrawlClient(t, httpServer.URL)
+	ctx := context.Background()
+
+	job, err := c.SubmitCrawl(ctx, api.CrawlRequest{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("SubmitCrawl() error = %v", err)
+	}
+	if job.ID == "" || job.URL != "https://example.com/" {
+		t.Fatalf("Unexpected job: %+v", job)
+	}
+
+	done := waitForJob(t, c, job.ID)
+	if done.Status != api.JobDone || done.FinishedAt == nil {
+		t.Errorf("Expected completed job, got %+v", done)
+	}
+	if done.Screenshot == "" {
+		t.Fatal("Expected a screenshot")
+	}
+
+	shot, err := c.GetScreenshot(ctx, done.Screenshot)
+	if err != nil {
+		t.Fatalf("GetScreenshot() error = %v", err)
+	}
+	if string(shot) != "\x89PNG" {
+		t.Errorf("Screenshot = %q", shot)
+	}
+}
+
+func TestCrawlHandler_FailedJob(t *testing.T) {
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{})
+	c := newCrawlClient(t, httpServer.URL)
+
+	job, err := c.SubmitCrawl(context.Background(), api.CrawlRequest{URL: "https://example.com/fail"})
+	if err != nil {
+		t.Fatalf("SubmitCrawl() error = %v", err)
+	}
+
+	done := waitForJob(t, c, job.ID)
+	if done.Status != api.JobFailed || done.Error != "crawl failed" {
+		t.Errorf("Expected failed job, got %+v", done)
+	}
+}
+
+func TestCrawlHandler_ListNewestFirst(t *testing.T) {
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{})
+	c := newCrawlClient(t, httpServer.URL)
+	ctx := context.Background()
+
+	for _, u := range []string{"https://a.example/", "https://b.example/"} {
+		if _, err := c.SubmitCrawl(ctx, api.CrawlRequest{URL: u}); err != nil {
+			t.Fatalf("SubmitCrawl() error = %v", err)
+		}
+	}
+
+	jobs, err := c.ListCrawls(ctx)
+	if err != nil {
+		t.Fatalf("ListCrawls() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].URL != "https://b.example/" {
+		t.Errorf("Expected newest job first, got %+v", jobs)
+	}
+}
+
+func TestCrawlHandler_Validation(t *testing.T) {
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{
+		ValidateURL: func(string) error { return errors.New("private address") },
+	})
+	c := newCrawlClient(t, httpServer.URL)
+	ctx := context.Background()
+
+	var apiErr *client.APIError
+	_, err := c.SubmitCrawl(ctx, api.CrawlRequest{URL: ""})
+	if !errors.As(err, &apiErr) || apiErr.Message != "url is required" {
+		t.Fatalf("SubmitCrawl(\"\") error = %v", err)
+	}
+	if apiErr.Code != errs.CodeInvalidRequest {
+		t.Errorf("Code = %q, want %q", apiErr.Code, errs.CodeInvalidRequest)
+	}
+	_, err = c.SubmitCrawl(ctx, api.CrawlRequest{URL: "http://10.0.0.1/"})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("SubmitCrawl() error = %v, want 400", err)
+	}
+	if !errs.HasCode(err, errs.CodeInvalidURL) {
+		t.Errorf("CodeOf() = %q, want %q", errs.CodeOf(err), errs.CodeInvalidURL)
+	}
+	if _, err := c.GetCrawl(ctx, "999"); !client.IsNotFound(err) || errs.CodeOf(err) != errs.CodeNotFound {
+		t.Errorf("GetCrawl() error = %v, want 404 %s", err, errs.CodeNotFound)
+	}
+}
+
+// contextCrawler records the crawl ID it was called with
+type contextCrawler struct {
+	fakeCrawler
+	crawlIDs chan string
+}
+
+func (c *contextCrawler) CrawlAndStoreContext(ctx context.Context, url string) error {
+	c.crawlIDs <- libs.CrawlIDFrom(ctx)
+	return c.CrawlAndStore(url)
+}
+
+func TestCrawlHandler_CrawlID(t *testing.T) {
+	crawler := &contextCrawler{crawlIDs: make(chan string, 2)}
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{Crawler: crawler})
+
+	req, _ := http.NewRequest(http.MethodPost, httpServer.URL+"/api/v1/crawls", strings.NewReader(`{"url":"https://example.com/"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(libs.CrawlIDHeader, "trace-1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if got := resp.Header.Get(libs.CrawlIDHeader); got != "trace-1" {
+		t.Errorf("%s = %q, want trace-1", libs.CrawlIDHeader, got)
+	}
+	if got := <-crawler.crawlIDs; got != "trace-1" {
+		t.Errorf("Crawler saw crawl ID %q, want trace-1", got)
+	}
+
+	// Without the header an ID is generated and reported on the job
+	job, err := newCrawlClient(t, httpServer.URL).SubmitCrawl(context.Background(), api.CrawlRequest{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("SubmitCrawl() error = %v", err)
+	}
+	if got := <-crawler.crawlIDs; job.CrawlID == "" || got != job.CrawlID {
+		t.Errorf("Crawler saw crawl ID %q, job has %q", got, job.CrawlID)
+	}
+}
+
+// optionsCrawler records the request options it was called with
+type optionsCrawler struct {
+	fakeCrawler
+	options chan crawlers.RequestOptions
+}
+
+func (c *optionsCrawler) CrawlAndStoreContext(ctx context.Context, url string) error {
+	c.options <- crawlers.RequestOptionsFrom(ctx)
+	return c.CrawlAndStore(url)
+}
+
+func TestCrawlHandler_RequestOptions(t *testing.T) {
+	crawler := &optionsCrawler{options: make(chan crawlers.RequestOptions, 1)}
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{Crawler: crawler})
+	c := newCrawlClient(t, httpServer.URL)
+	ctx := context.Background()
+
+	want := crawlers.RequestOptions{
+		Headers: map[string]string{"Authorization": "Bearer tenant-token"},
+		Query:   map[string]string{"variant": "b"},
+	}
+	job, err := c.SubmitCrawl(ctx, api.CrawlRequest{URL: "https://example.com/", RequestOptions: want})
+	if err != nil {
+		t.Fatalf("SubmitCrawl() error = %v", err)
+	}
+	got := <-crawler.options
+	if got.Headers["Authorization"] != "Bearer tenant-token" || got.Query["variant"] != "b" {
+		t.Errorf("Crawler saw options %+v, want %+v", got, want)
+	}
+
+	// Header values may be credentials, so jobs never echo them
+	resp, err := http.Get(httpServer.URL + "/api/v1/crawls/" + job.ID)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if strings.Contains(string(body), "tenant-token") {
+		t.Errorf("Job JSON leaks a header value: %s", body)
+	}
+
+	var apiErr *client.APIError
+	_, err = c.SubmitCrawl(ctx, api.CrawlRequest{
+		URL:            "https://example.com/",
+		RequestOptions: crawlers.RequestOptions{Headers: map[string]string{"Host": "other.example"}},
+	})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != errs.CodeInvalidRequest {
+		t.Errorf("SubmitCrawl(Host header) error = %v, want 400 %s", err, errs.CodeInvalidRequest)
+	}
+}
+
+func TestCrawlHandler_FormSubmit(t *testing.T) {
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{})
+
+	resp, err := http.PostForm(httpServer.URL+"/api/v1/crawls", map[string][]string{"url": {"https://example.com/"}})
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Status = %d, want 202", resp.StatusCode)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Location"), "/api/v1/crawls/") {
+		t.Errorf("Location = %q", resp.Header.Get("Location"))
+	}
+}
+
+func TestCrawlHandler_QueueFull(t *testing.T) {
+	// Not started, so the single queue slot stays occupied
+	handler := api.NewCrawlHandler(api.CrawlHandlerConfig{Crawler: &fakeCrawler{}, QueueSize: 1, ValidateURL: allowAll})
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(handler)
+
+	codes := make([]int, 2)
+	for i := range codes {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/crawls", strings.NewReader(`{"url":"https://example.com/"}`))
+		req.Header.Set("Content-Type", "application/json")
+		server.Handler().ServeHTTP(rec, req)
+		codes[i] = rec.Code
+	}
+	if codes[0] != http.StatusAccepted || codes[1] != http.StatusServiceUnavailable {
+		t.Errorf("Status codes = %v, want [202 503]", codes)
+	}
+}
+
+func TestCrawlHandler_ScreenshotPathTraversal(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "secret.png"), []byte("secret"), 0o644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{ScreenshotDir: dir})
+	c := newCrawlClient(t, httpServer.URL)
+
+	for _, name := range []string{"../secret.png", "..%2Fsecret.png", "missing.png"} {
+		if _, err := c.GetScreenshot(context.Background(), name); err == nil {
+			t.Errorf("GetScreenshot(%q) succeeded, want error", name)
+		}
+	}
+}
+
+func TestCrawlHandler_RecentPages(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT `id`,`url`,`title`,`domain`,`status`,`created_at` FROM `pages`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "title", "domain", "status"}).
+			AddRow(2, "https://example.com/b", "B", "example.com", 200).
+			AddRow(1, "https://example.com/a", "A", "example.com", 200))
+
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{DB: &mocks.MockDatabaseClient{DB: gormDB}})
+	pages, err := newCrawlClient(t, httpServer.URL).ListRecentPages(context.Background(), client.ListRecentPagesParams{Limit: 5})
+	if err != nil {
+		t.Fatalf("ListRecentPages() error = %v", err)
+	}
+	if len(pages) != 2 || pages[0].ID != 2 || pages[0].Title != "B" {
+		t.Errorf("Unexpected pages: %+v", pages)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestCrawlHandler_RecentPagesWithoutDB(t *testing.T) {
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{})
+
+	var apiErr *client.APIError
+	_, err := newCrawlClient(t, httpServer.URL).ListRecentPages(context.Background(), client.ListRecentPagesParams{})
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("ListRecentPages() error = %v, want 503", err)
+	}
+}
+
+func TestCrawlHandler_CompareCrawls(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{DB: &mocks.MockDatabaseClient{DB: gormDB}})
+	c := newCrawlClient(t, httpServer.URL)
+	ctx := context.Background()
+
+	job, err := c.SubmitCrawl(ctx, api.CrawlRequest{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("SubmitCrawl() error = %v", err)
+	}
+	waitForJob(t, c, job.ID)
+
+	// Job IDs resolve to their crawl IDs
+	mock.ExpectQuery("FROM `crawl_logs` WHERE crawl_id IN").
+		WithArgs("deploy-1", job.CrawlID).
+		WillReturnRows(sqlmock.NewRows([]string{"crawl_id", "url", "domain", "status", "error", "content_hash"}).
+			AddRow("deploy-1", "https://example.com/", "example.com", 200, "", "a").
+			AddRow(job.CrawlID, "https://example.com/", "example.com", 500, "", ""))
+
+	diff, err := c.CompareCrawls(ctx, client.CompareCrawlsParams{Base: "deploy-1", Target: job.ID})
+	if err != nil {
+		t.Fatalf("CompareCrawls() error = %v", err)
+	}
+	if len(diff.StatusChanged) != 1 || diff.StatusChanged[0].TargetStatus != 500 || diff.Target != job.CrawlID {
+		t.Errorf("Unexpected diff: %+v", diff)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+
+	if _, err := c.CompareCrawls(ctx, client.CompareCrawlsParams{Base: "deploy-1"}); !errs.HasCode(err, errs.CodeInvalidRequest) {
+		t.Errorf("CompareCrawls() without target error = %v, want %s", err, errs.CodeInvalidRequest)
+	}
+}
+
+func TestCrawlHandler_SecurityReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	mock.ExpectQuery("FROM `security_audits` WHERE project = \\? AND domain = \\?").
+		WithArgs("estate", "example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"url", "domain", "score", "issues"}).
+			AddRow("https://example.com/", "example.com", 90, "missing-permissions-policy"))
+
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{DB: &mocks.MockDatabaseClient{DB: gormDB}})
+	reports, err := newCrawlClient(t, httpServer.URL).GetSecurityReport(context.Background(), client.GetSecurityReportParams{Project: "estate", Domain: "example.com"})
+	if err != nil {
+		t.Fatalf("GetSecurityReport() error = %v", err)
+	}
+	if len(reports) != 1 || reports[0].Grade != "A" || reports[0].Issues["missing-permissions-policy"] != 1 {
+		t.Errorf("Unexpected reports: %+v", reports)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestCrawlHandler_ListDomains(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	mock.ExpectQuery("FROM `domains` WHERE host = \\? ORDER BY host").
+		WithArgs("example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "host", "site_name", "favicon_url"}).
+			AddRow(1, "example.com", "Example", "https://example.com/favicon.ico"))
+
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{DB: &mocks.MockDatabaseClient{DB: gormDB}})
+	domains, err := newCrawlClient(t, httpServer.URL).ListDomains(context.Background(), client.ListDomainsParams{Domain: "Example.com"})
+	if err != nil {
+		t.Fatalf("ListDomains() error = %v", err)
+	}
+	if len(domains) != 1 || domains[0].SiteName != "Example" || domains[0].FaviconURL != "https://example.com/favicon.ico" {
+		t.Errorf("Unexpected domains: %+v", domains)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestCrawlHandler_DomainStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer func() { _ = db.Close() }()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{Conn: db, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery("FROM `crawl_logs` WHERE \\(project = \\? AND created_at >= \\?\\) AND domain = \\?").
+		WithArgs("shop", since, "example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"domain", "status", "duration_ms", "bytes", "error", "created_at"}).
+			AddRow("example.com", 200, 120, 2048, "", since.Add(time.Hour)))
+	mock.ExpectQuery("MAX\\(created_at\\) AS last_success_at FROM `crawl_logs`").
+		WillReturnRows(sqlmock.NewRows([]string{"domain", "last_success_at"}).AddRow("example.com", since.Add(time.Hour)))
+
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{DB: &mocks.MockDatabaseClient{DB: gormDB}})
+	c := newCrawlClient(t, httpServer.URL)
+	stats, err := c.GetDomainStats(context.Background(), client.GetDomainStatsParams{Project: "shop", Domain: "example.com", Since: since})
+	if err != nil {
+		t.Fatalf("GetDomainStats() error = %v", err)
+	}
+	if len(stats) != 1 || stats[0].Crawls != 1 || stats[0].LatencyP50Ms != 120 || stats[0].AvgPageBytes != 2048 || stats[0].LastSuccess == nil {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+
+	resp, err := http.Get(httpServer.URL + "/api/v1/stats/domains?since=yesterday")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Invalid since status = %d, want 400", resp.StatusCode)
+	}
+}
+
+// =============================================================================
+// Web UI Tests
+// =============================================================================
+
+func TestUIHandler_ServesAssets(t *testing.T) {
+	httpServer, _ := newCrawlServer(t, api.CrawlHandlerConfig{})
+
+	for path, want := range map[string]string{
+		"/ui/":          "<title>golwarc</title>",
+		"/ui/app.js":    "/api/v1/crawls",
+		"/ui/style.css": "font-family",
+	} {
+		resp, err := http.Get(httpServer.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("GET %s status = %d", path, resp.StatusCode)
+		}
+		if !strings.Contains(string(body), want) {
+			t.Errorf("GET %s missing %q", path, want)
+		}
+	}
+}
+
+func TestUIHandler_RootRedirect(t *testing.T) {
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(api.NewUIHandler())
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/ui/" {
+		t.Errorf("GET / = %d %q, want redirect to /ui/", rec.Code, rec.Header().Get("Location"))
+	}
+}
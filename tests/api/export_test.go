@@ -0,0 +1,164 @@
+package api_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/mocks"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// ExportHandler Tests
+// =============================================================================
+
+func newExportServer(t *testing.T) (*api.Server, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(api.NewExportHandler(&mocks.MockDatabaseClient{DB: gormDB}))
+	return server, mock
+}
+
+func pageRows(ids ...int) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"id", "url", "title"})
+	for _, id := range ids {
+		rows.AddRow(id, "https://example.com/"+string(rune('a'+id)), "Page")
+	}
+	return rows
+}
+
+func readLines(t *testing.T, body string) []map[string]interface{} {
+	t.Helper()
+
+	var lines []map[string]interface{}
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var row map[string]interface{}
+		if err := json.Unmarshal(scanner.Bytes(), &row); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, row)
+	}
+	return lines
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	id, err := api.DecodeCursor(api.EncodeCursor(42))
+	if err != nil || id != 42 {
+		t.Errorf("DecodeCursor(EncodeCursor(42)) = %d, %v", id, err)
+	}
+	if _, err := api.DecodeCursor("garbage!"); err == nil {
+		t.Error("Expected error for invalid cursor")
+	}
+}
+
+func TestExportHandler_StreamsAllBatches(t *testing.T) {
+	server, mock := newExportServer(t)
+
+	mock.ExpectQuery("SELECT \\* FROM `pages` WHERE id > \\? AND `pages`.`deleted_at` IS NULL ORDER BY id LIMIT \\?").
+		WithArgs(0, 2).WillReturnRows(pageRows(1, 2))
+	mock.ExpectQuery("SELECT \\* FROM `pages` WHERE id > \\?").
+		WithArgs(2, 2).WillReturnRows(pageRows(3))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/pages?batch=2", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if lines := readLines(t, rec.Body.String()); len(lines) != 3 {
+		t.Errorf("Expected 3 rows, got %d", len(lines))
+	}
+	if cursor := rec.Result().Trailer.Get("X-Next-Cursor"); cursor != "" {
+		t.Errorf("Expected no resume cursor for a complete export, got %q", cursor)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestExportHandler_LimitAndResume(t *testing.T) {
+	server, mock := newExportServer(t)
+
+	mock.ExpectQuery("SELECT \\* FROM `pages`").WithArgs(0, 2).WillReturnRows(pageRows(1, 2))
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/pages?limit=2", nil))
+
+	cursor := rec.Result().Trailer.Get("X-Next-Cursor")
+	if cursor != api.EncodeCursor(2) {
+		t.Fatalf("X-Next-Cursor = %q, want cursor after id 2", cursor)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `pages`").WithArgs(2, 500).WillReturnRows(pageRows(3))
+
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/pages?cursor="+cursor, nil))
+	if lines := readLines(t, rec.Body.String()); len(lines) != 1 || lines[0]["id"] != float64(3) {
+		t.Errorf("Unexpected resumed rows: %v", lines)
+	}
+}
+
+func TestExportHandler_Gzip(t *testing.T) {
+	server, mock := newExportServer(t)
+
+	mock.ExpectQuery("SELECT \\* FROM `products`").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Widget"))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export/products", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip content encoding")
+	}
+	gz, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatalf("gzip.NewReader() error = %v", err)
+	}
+	var sb strings.Builder
+	if _, err := bufio.NewReader(gz).WriteTo(&sb); err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if lines := readLines(t, sb.String()); len(lines) != 1 || lines[0]["name"] != "Widget" {
+		t.Errorf("Unexpected rows: %v", lines)
+	}
+}
+
+func TestExportHandler_InvalidCursor(t *testing.T) {
+	server, _ := newExportServer(t)
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/export/pages?cursor=@@", nil))
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Status = %d, want 400", rec.Code)
+	}
+}
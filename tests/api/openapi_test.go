@@ -0,0 +1,255 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alonecandies/golwarc/api"
+	"github.com/alonecandies/golwarc/api/client"
+	"github.com/alonecandies/golwarc/scripts/openapi/clientgen"
+)
+
+// =============================================================================
+// OpenAPI Tests
+// =============================================================================
+
+func newDocumentedServer() *api.Server {
+	server := api.NewServer(api.ServerConfig{})
+	server.Register(api.NewArchiveHandler(nil), api.NewExportHandler(nil), api.NewCrawlHandler(api.CrawlHandlerConfig{}))
+	return server
+}
+
+func TestOpenAPI_Paths(t *testing.T) {
+	doc := newDocumentedServer().OpenAPI()
+
+	if doc.OpenAPI != api.OpenAPIVersion {
+		t.Errorf("openapi = %s, want %s", doc.OpenAPI, api.OpenAPIVersion)
+	}
+	for _, path := range []string{"/api/v1/cdx", "/api/v1/replay", "/api/v1/export/pages", "/api/v1/export/products", "/api/v1/openapi.json"} {
+		if doc.Paths[path]["get"] == nil {
+			t.Errorf("Missing GET %s", path)
+		}
+	}
+}
+
+func TestOpenAPI_Schemas(t *testing.T) {
+	doc := newDocumentedServer().OpenAPI()
+
+	page := doc.Components.Schemas["Page"]
+	if page == nil {
+		t.Fatal("Expected Page schema")
+	}
+	if page.Properties["url"] == nil || page.Properties["url"].Type != "string" {
+		t.Error("Expected string url property")
+	}
+	if _, ok := page.Properties["BodyData"]; ok {
+		t.Error("Fields tagged json:\"-\" must be omitted")
+	}
+	if created := page.Properties["created_at"]; created == nil || created.Format != "date-time" {
+		t.Error("Expected date-time created_at property")
+	}
+	if id := page.Properties["id"]; id == nil || id.Type != "integer" {
+		t.Error("Expected integer id property")
+	}
+
+	captures := doc.Paths["/api/v1/cdx"]["get"].Responses["200"].Content["application/json"].Schema
+	if captures.Type != "array" || captures.Items.Ref != "#/components/schemas/Capture" {
+		t.Errorf("Unexpected capture list schema: %+v", captures)
+	}
+}
+
+func TestOpenAPI_ServedDocument(t *testing.T) {
+	server := newDocumentedServer()
+
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Status = %d, want 200", rec.Code)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if doc["openapi"] != api.OpenAPIVersion {
+		t.Errorf("openapi = %v", doc["openapi"])
+	}
+}
+
+func TestOpenAPI_CheckedInDocumentIsCurrent(t *testing.T) {
+	want, err := os.ReadFile("../../docs/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to read docs/openapi.json: %v", err)
+	}
+
+	var got bytes.Buffer
+	if err := newDocumentedServer().WriteOpenAPI(&got); err != nil {
+		t.Fatalf("WriteOpenAPI() error = %v", err)
+	}
+	if !bytes.Equal(got.Bytes(), want) {
+		t.Error("docs/openapi.json is out of date; run make openapi")
+	}
+}
+
+func TestClient_GeneratedCodeIsCurrent(t *testing.T) {
+	spec, err := os.ReadFile("../../docs/openapi.json")
+	if err != nil {
+		t.Fatalf("Failed to read docs/openapi.json: %v", err)
+	}
+	var doc api.OpenAPIDocument
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatalf("Invalid docs/openapi.json: %v", err)
+	}
+
+	want, err := os.ReadFile("../../api/client/client_gen.go")
+	if err != nil {
+		t.Fatalf("Failed to read api/client/client_gen.go: %v", err)
+	}
+	got, err := clientgen.Generate(&doc, "client")
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("api/client/client_gen.go is out of date; run make openapi")
+	}
+}
+
+func TestClient_CoversEveryOperation(t *testing.T) {
+	clientType := reflect.TypeOf(&client.Client{})
+
+	for _, op := range newDocumentedServer().OpenAPI().Operations() {
+		method := strings.ToUpper(op.OperationID[:1]) + op.OperationID[1:]
+		if _, ok := clientType.MethodByName(method); !ok {
+			t.Errorf("client.Client has no method for operation %s", op.OperationID)
+		}
+	}
+}
+
+// =============================================================================
+// Client Tests
+// =============================================================================
+
+func TestNewClient_Validation(t *testing.T) {
+	if _, err := client.NewClient(client.Config{}); err == nil {
+		t.Error("Expected error for empty base URL")
+	}
+}
+
+func TestClient_ListCapturesAndReplay(t *testing.T) {
+	httpServer := httptest.NewServer(newArchiveServer(t).Handler())
+	defer httpServer.Close()
+
+	c, err := client.NewClient(client.Config{BaseURL: httpServer.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ctx := context.Background()
+
+	captures, err := c.ListCaptures(ctx, client.ListCapturesParams{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("ListCaptures() error = %v", err)
+	}
+	if len(captures) != 1 || captures[0].URL != "https://example.com/" {
+		t.Fatalf("Unexpected captures: %+v", captures)
+	}
+
+	replay, err := c.ReplayCapture(ctx, client.ReplayCaptureParams{URL: "https://example.com/"})
+	if err != nil {
+		t.Fatalf("ReplayCapture() error = %v", err)
+	}
+	if string(replay.Content) != "<html>archived</html>" {
+		t.Errorf("Content = %q", replay.Content)
+	}
+	if replay.MementoDatetime == "" || replay.ArchiveRecordID == "" {
+		t.Errorf("Expected capture metadata, got %+v", replay)
+	}
+
+	_, err = c.ReplayCapture(ctx, client.ReplayCaptureParams{URL: "https://missing.example/"})
+	if !client.IsNotFound(err) {
+		t.Errorf("ReplayCapture() error = %v, want 404", err)
+	}
+
+	var apiErr *client.APIError
+	if _, err := c.ListCaptures(ctx, client.ListCapturesParams{}); !errors.As(err, &apiErr) || apiErr.Message != "url parameter is required" {
+		t.Errorf("ListCaptures() error = %v, want API error message", err)
+	}
+}
+
+func TestClient_ExportPages(t *testing.T) {
+	server, mock := newExportServer(t)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM `pages`").WillReturnRows(pageRows(1, 2))
+
+	c, err := client.NewClient(client.Config{BaseURL: httpServer.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	for _, compressed := range []bool{false, true} {
+		if compressed {
+			mock.ExpectQuery("SELECT \\* FROM `pages`").WillReturnRows(pageRows(1, 2))
+		}
+
+		stream, err := c.ExportPages(context.Background(), client.ExportPagesParams{Batch: 10, Gzip: compressed})
+		if err != nil {
+			t.Fatalf("ExportPages() error = %v", err)
+		}
+
+		var ids []uint
+		for stream.Next() {
+			ids = append(ids, stream.Row().ID)
+		}
+		if err := stream.Err(); err != nil {
+			t.Fatalf("stream error = %v", err)
+		}
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+			t.Errorf("gzip=%v: ids = %v, want [1 2]", compressed, ids)
+		}
+		if cursor := stream.NextCursor(); cursor != "" {
+			t.Errorf("Expected no resume cursor for a complete export, got %q", cursor)
+		}
+		_ = stream.Close()
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestClient_ExportResumeCursor(t *testing.T) {
+	server, mock := newExportServer(t)
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	mock.ExpectQuery("SELECT \\* FROM `pages`").WillReturnRows(pageRows(1))
+
+	c, err := client.NewClient(client.Config{BaseURL: httpServer.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+
+	stream, err := c.ExportPages(context.Background(), client.ExportPagesParams{Project: "p", Limit: 1})
+	if err != nil {
+		t.Fatalf("ExportPages() error = %v", err)
+	}
+	defer func() { _ = stream.Close() }()
+
+	for stream.Next() {
+	}
+	if stream.NextCursor() != api.EncodeCursor(1) {
+		t.Errorf("NextCursor() = %q, want cursor after id 1", stream.NextCursor())
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package benchmarks
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -149,3 +150,116 @@ func BenchmarkRedisPipeline(b *testing.B) {
 		redisClient.MGet("key1", "key2", "key3")
 	}
 }
+
+// benchmarkPage is a typical cached crawl result
+var benchmarkPage = map[string]interface{}{
+	"url":         "https://example.com/products/42",
+	"title":       "Example Product",
+	"status_code": 200,
+	"links":       []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"},
+	"headers":     map[string]string{"Content-Type": "text/html", "Cache-Control": "max-age=60"},
+}
+
+// benchmarkMarshal benchmarks encoding with a cache serializer
+func benchmarkMarshal(b *testing.B, serializer cache.Serializer) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := serializer.Marshal(benchmarkPage); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkUnmarshal benchmarks decoding with a cache serializer
+func benchmarkUnmarshal(b *testing.B, serializer cache.Serializer) {
+	data, err := serializer.Marshal(benchmarkPage)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportMetric(float64(len(data)), "bytes/value")
+	b.ReportAllocs()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var result map[string]interface{}
+		if err := serializer.Unmarshal(data, &result); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkSerializerJSONMarshal benchmarks JSON encoding of cached values
+func BenchmarkSerializerJSONMarshal(b *testing.B) {
+	benchmarkMarshal(b, cache.JSONSerializer{})
+}
+
+// BenchmarkSerializerMsgpackMarshal benchmarks msgpack encoding of cached values
+func BenchmarkSerializerMsgpackMarshal(b *testing.B) {
+	benchmarkMarshal(b, cache.MsgpackSerializer{})
+}
+
+// BenchmarkSerializerJSONUnmarshal benchmarks JSON decoding of cached values
+func BenchmarkSerializerJSONUnmarshal(b *testing.B) {
+	benchmarkUnmarshal(b, cache.JSONSerializer{})
+}
+
+// BenchmarkSerializerMsgpackUnmarshal benchmarks msgpack decoding of cached values
+func BenchmarkSerializerMsgpackUnmarshal(b *testing.B) {
+	benchmarkUnmarshal(b, cache.MsgpackSerializer{})
+}
+
+// BenchmarkRedisSetMsgpack benchmarks Redis set operations with msgpack
+func BenchmarkRedisSetMsgpack(b *testing.B) {
+	redisClient, err := cache.NewRedisClient(cache.RedisConfig{
+		Addr:       "localhost:6379",
+		Serializer: cache.MsgpackSerializer{},
+	})
+	if err != nil {
+		b.Skip("Redis not available:", err)
+	}
+	defer redisClient.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		redisClient.SetJSON("bench_msgpack", benchmarkPage, 10*time.Minute)
+	}
+}
+
+// benchmarkLocalCacheParallel benchmarks a dedup-style mix of lookups and inserts
+// from all CPUs
+func benchmarkLocalCacheParallel(b *testing.B, c cache.LocalCache) {
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("https://example.com/page/%d", i)
+	}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			key := keys[i%len(keys)]
+			if _, ok := c.Get(key); !ok {
+				c.Set(key, true)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkLRUParallel benchmarks the single-lock LRU cache under contention
+func BenchmarkLRUParallel(b *testing.B) {
+	lru, err := cache.NewLRUCache(2048)
+	if err != nil {
+		b.Fatalf("Failed to create LRU cache: %v", err)
+	}
+	benchmarkLocalCacheParallel(b, lru)
+}
+
+// BenchmarkShardedLRUParallel benchmarks the sharded LRU cache under contention
+func BenchmarkShardedLRUParallel(b *testing.B) {
+	lru, err := cache.NewShardedLRUCache(2048, 0)
+	if err != nil {
+		b.Fatalf("Failed to create sharded LRU cache: %v", err)
+	}
+	benchmarkLocalCacheParallel(b, lru)
+}
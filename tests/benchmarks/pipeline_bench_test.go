@@ -0,0 +1,258 @@
+package benchmarks
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"github.com/alonecandies/golwarc/storage"
+	"go.uber.org/zap"
+)
+
+// Pipeline benchmarks crawl a synthetic site into in-memory backends. Each
+// benchmark op is one page, so allocs/op is allocations per page. They also
+// report pages/s and p95-ms; compare runs with `make bench`
+
+// =============================================================================
+// Synthetic Site
+// =============================================================================
+
+// syntheticSite serves an unbounded binary tree of pages: /page/N links to
+// /page/2N+1 and /page/2N+2. It records when each page was requested so
+// crawlers without request hooks can measure per-page latency
+type syntheticSite struct {
+	server    *httptest.Server
+	requested sync.Map // path -> time.Time
+}
+
+// newSyntheticSite starts a site whose pages carry about size bytes of text
+func newSyntheticSite(b *testing.B, size int) *syntheticSite {
+	b.Helper()
+	filler := strings.Repeat("<p>Lorem ipsum dolor sit amet, consectetur adipiscing elit.</p>\n", size/64+1)
+
+	site := &syntheticSite{}
+	site.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		site.requested.Store(r.URL.Path, time.Now())
+
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/page/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, `<html><head><title>Page %d</title></head><body>
+<nav><a href="/page/0">Home</a></nav>
+<h1>Page %d</h1>
+%s
+<ul><li><a href="/page/%d">Left</a></li><li><a href="/page/%d">Right</a></li></ul>
+</body></html>`, n, n, filler, 2*n+1, 2*n+2)
+	}))
+	b.Cleanup(site.server.Close)
+	return site
+}
+
+// pageURL returns the URL of page n
+func (s *syntheticSite) pageURL(n int) string {
+	return fmt.Sprintf("%s/page/%d", s.server.URL, n)
+}
+
+// sinceRequested returns the time since the page at rawURL was requested
+func (s *syntheticSite) sinceRequested(rawURL string) time.Duration {
+	start, ok := s.requested.Load(strings.TrimPrefix(rawURL, s.server.URL))
+	if !ok {
+		return 0
+	}
+	return time.Since(start.(time.Time))
+}
+
+// =============================================================================
+// Harness
+// =============================================================================
+
+// latencies collects per-page durations from concurrent workers
+type latencies struct {
+	mu        sync.Mutex
+	durations []time.Duration
+}
+
+// add records one page
+func (l *latencies) add(d time.Duration) {
+	l.mu.Lock()
+	l.durations = append(l.durations, d)
+	l.mu.Unlock()
+}
+
+// report adds pages/s and p95-ms to the benchmark result
+func (l *latencies) report(b *testing.B, pages int) {
+	b.Helper()
+	if elapsed := b.Elapsed(); elapsed > 0 {
+		b.ReportMetric(float64(pages)/elapsed.Seconds(), "pages/s")
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.durations) == 0 {
+		return
+	}
+	sort.Slice(l.durations, func(i, j int) bool { return l.durations[i] < l.durations[j] })
+	p95 := l.durations[(len(l.durations)*95-1)/100]
+	b.ReportMetric(float64(p95.Microseconds())/1000, "p95-ms")
+}
+
+// runPages sends b.N pages through process with the given concurrency
+func runPages(b *testing.B, site *syntheticSite, concurrency int, process func(url string) error) {
+	b.Helper()
+	var next atomic.Int64
+	var lat latencies
+	var wg sync.WaitGroup
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				n := int(next.Add(1)) - 1
+				if n >= b.N {
+					return
+				}
+				started := time.Now()
+				if err := process(site.pageURL(n)); err != nil {
+					b.Errorf("page %d: %v", n, err)
+					return
+				}
+				lat.add(time.Since(started))
+			}
+		}()
+	}
+	wg.Wait()
+	b.StopTimer()
+	lat.report(b, b.N)
+}
+
+// memoryDB is an in-memory database backend that counts stored pages
+func memoryDB(stored *atomic.Int64) *mocks.MockDatabaseClient {
+	return &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			if _, ok := value.(*models.Page); ok {
+				stored.Add(1)
+			}
+			return nil
+		},
+	}
+}
+
+// =============================================================================
+// Pipeline Benchmarks
+// =============================================================================
+
+// BenchmarkPipeline measures the main crawl pipelines end to end
+func BenchmarkPipeline(b *testing.B) {
+	b.Run("spider", benchmarkSpider)
+	b.Run("soup", benchmarkSoup)
+	b.Run("colly_service", func(b *testing.B) { benchmarkCrawlerService(b, nil) })
+	b.Run("colly_service_gzip", func(b *testing.B) {
+		benchmarkCrawlerService(b, storage.NewBodyStore(storage.BodyStoreConfig{InlineMaxSize: 1024}))
+	})
+}
+
+// benchmarkSpider crawls the site with a Spider that follows every link
+// Latency runs from the site receiving the request to the page being stored
+func benchmarkSpider(b *testing.B) {
+	site := newSyntheticSite(b, 8<<10)
+	var stored atomic.Int64
+	db := memoryDB(&stored)
+	var lat latencies
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:    64,
+		Concurrency: 4,
+		MaxPages:    b.N,
+	})
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		page := &models.Page{URL: crawl.URL, Title: doc.Find("title").Text(), Status: 200}
+		if err := db.Create(page); err != nil {
+			return err
+		}
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		lat.add(site.sinceRequested(crawl.URL))
+		return nil
+	})
+	spider.AddStartURL(site.pageURL(0))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	if err := spider.Run(); err != nil {
+		b.Fatalf("Run() error = %v", err)
+	}
+	b.StopTimer()
+
+	if int(stored.Load()) != b.N {
+		b.Fatalf("stored %d pages, want %d", stored.Load(), b.N)
+	}
+	lat.report(b, b.N)
+}
+
+// benchmarkSoup fetches and parses pages with a shared SoupClient
+func benchmarkSoup(b *testing.B) {
+	site := newSyntheticSite(b, 8<<10)
+	client := crawlers.NewDefaultSoupClient()
+	var stored atomic.Int64
+	db := memoryDB(&stored)
+
+	runPages(b, site, 4, func(url string) error {
+		doc, err := client.GetContext(context.Background(), url)
+		if err != nil {
+			return err
+		}
+		page := &models.Page{
+			URL:    url,
+			Title:  client.GetText(client.Find(doc, "title", nil)),
+			Status: 200,
+			HTML:   client.GetHTML(doc),
+		}
+		_ = client.FindLinks(doc)
+		return db.Create(page)
+	})
+}
+
+// benchmarkCrawlerService runs CrawlerService.CrawlAndStore, one service per
+// page, optionally with a body store. The Colly client has no politeness
+// delay, which would otherwise dominate
+func benchmarkCrawlerService(b *testing.B, bodies *storage.BodyStore) {
+	site := newSyntheticSite(b, 8<<10)
+	logger := zap.NewNop()
+	var stored atomic.Int64
+	db := memoryDB(&stored)
+
+	runPages(b, site, 4, func(url string) error {
+		service := services.NewCrawlerService(logger, nil, db)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		if bodies != nil {
+			service.SetBodyStore(bodies)
+		}
+		return service.CrawlAndStore(url)
+	})
+
+	if int(stored.Load()) != b.N {
+		b.Fatalf("stored %d pages, want %d", stored.Load(), b.N)
+	}
+}
@@ -0,0 +1,25 @@
+package benchmarks
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+)
+
+// BenchmarkRuleSetDecide benchmarks URL rule evaluation with 20,000 rules
+func BenchmarkRuleSetDecide(b *testing.B) {
+	rules := make([]urlmatch.Rule, 0, 20000)
+	for i := 0; i < 10000; i++ {
+		rules = append(rules,
+			urlmatch.Rule{Pattern: fmt.Sprintf("site%d.example.com/", i), Action: urlmatch.Allow, Priority: i},
+			urlmatch.Rule{Pattern: fmt.Sprintf("site%d.example.com/*/private/*.html$", i), Action: urlmatch.Deny},
+		)
+	}
+	rs := urlmatch.MustCompile(rules)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = rs.Decide("https://site5000.example.com/docs/private/page.html")
+	}
+}
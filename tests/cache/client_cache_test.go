@@ -0,0 +1,158 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/clock"
+)
+
+// setupClientCacheTest returns a caching client and a plain client writing
+// to the same Redis, or skips when Redis is not available
+func setupClientCacheTest(t *testing.T, config cache.ClientCacheConfig) (cached, writer *cache.RedisClient) {
+	t.Helper()
+	writer, err := cache.NewRedisClient(cache.RedisConfig{Addr: "localhost:6379"})
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	t.Cleanup(func() { writer.Close() })
+
+	cached, err = cache.NewRedisClient(cache.RedisConfig{Addr: "localhost:6379", ClientCache: &config})
+	if err != nil {
+		t.Fatalf("NewRedisClient() with client cache error = %v", err)
+	}
+	t.Cleanup(func() { cached.Close() })
+	return cached, writer
+}
+
+// waitFor polls cond until it holds or a second passes
+func waitFor(t *testing.T, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRedisClient_ClientCacheHits(t *testing.T) {
+	cached, writer := setupClientCacheTest(t, cache.ClientCacheConfig{})
+	defer writer.Delete("csc-hot")
+
+	if err := writer.Set("csc-hot", "v1", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if val, err := cached.Get("csc-hot"); err != nil || val != "v1" {
+			t.Fatalf("Get() = %q, %v; want v1", val, err)
+		}
+	}
+
+	stats := cached.ClientCacheStats()
+	if stats.Misses != 1 || stats.Hits != 2 {
+		t.Errorf("ClientCacheStats() = %+v, want 1 miss and 2 hits", stats)
+	}
+}
+
+func TestRedisClient_ClientCacheTTL(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	cached, writer := setupClientCacheTest(t, cache.ClientCacheConfig{TTL: time.Minute, Clock: fake})
+	defer writer.Delete("csc-ttl")
+
+	if err := writer.Set("csc-ttl", "v1", time.Hour); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	_, _ = cached.Get("csc-ttl")
+	_, _ = cached.Get("csc-ttl")
+
+	// Local entries expire after TTL even without an invalidation
+	fake.Advance(time.Minute)
+	if val, err := cached.Get("csc-ttl"); err != nil || val != "v1" {
+		t.Fatalf("Get() = %q, %v; want v1", val, err)
+	}
+	if stats := cached.ClientCacheStats(); stats.Misses != 2 || stats.Hits != 1 {
+		t.Errorf("ClientCacheStats() = %+v, want 2 misses and 1 hit", stats)
+	}
+}
+
+func TestRedisClient_ClientCacheInvalidation(t *testing.T) {
+	cached, writer := setupClientCacheTest(t, cache.ClientCacheConfig{})
+	defer writer.Delete("csc-key")
+
+	if err := writer.Set("csc-key", "old", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if val, _ := cached.Get("csc-key"); val != "old" {
+		t.Fatalf("Get() = %q, want old", val)
+	}
+
+	// Written by another client, so only the invalidation message can tell
+	if err := writer.Set("csc-key", "new", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !waitFor(t, func() bool { val, _ := cached.Get("csc-key"); return val == "new" }) {
+		t.Error("Get() still returns the old value after another client wrote the key")
+	}
+	if cached.ClientCacheStats().Invalidations == 0 {
+		t.Error("Invalidations = 0, want the write to be reported")
+	}
+}
+
+func TestRedisClient_ClientCacheOwnWrites(t *testing.T) {
+	cached, _ := setupClientCacheTest(t, cache.ClientCacheConfig{})
+	defer cached.Delete("csc-own")
+
+	if err := cached.Set("csc-own", "1", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if val, _ := cached.Get("csc-own"); val != "1" {
+		t.Fatalf("Get() = %q, want 1", val)
+	}
+	if _, err := cached.Increment("csc-own"); err != nil {
+		t.Fatalf("Increment() error = %v", err)
+	}
+	if val, _ := cached.Get("csc-own"); val != "2" {
+		t.Errorf("Get() after own write = %q, want 2", val)
+	}
+}
+
+func TestRedisClient_ClientCachePrefixes(t *testing.T) {
+	cached, writer := setupClientCacheTest(t, cache.ClientCacheConfig{Prefixes: []string{"csc-robots:"}})
+	defer writer.DeleteMany("csc-robots:example.com", "csc-other")
+
+	if err := writer.MSet(map[string]interface{}{"csc-robots:example.com": "Disallow: /", "csc-other": "x"}); err != nil {
+		t.Fatalf("MSet() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		_, _ = cached.Get("csc-robots:example.com")
+		_, _ = cached.Get("csc-other")
+	}
+
+	stats := cached.ClientCacheStats()
+	if stats.Keys != 1 || stats.Hits != 1 {
+		t.Errorf("ClientCacheStats() = %+v, want only the prefixed key cached", stats)
+	}
+
+	if err := writer.Set("csc-robots:example.com", "Allow: /", time.Minute); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if !waitFor(t, func() bool { val, _ := cached.Get("csc-robots:example.com"); return val == "Allow: /" }) {
+		t.Error("Broadcast invalidation did not reach the cache")
+	}
+}
+
+func TestRedisClient_ClientCacheDisabled(t *testing.T) {
+	client, err := cache.NewRedisClient(cache.RedisConfig{Addr: "localhost:6379"})
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer client.Close()
+
+	if stats := client.ClientCacheStats(); stats != (cache.ClientCacheStats{}) {
+		t.Errorf("ClientCacheStats() = %+v, want zero when disabled", stats)
+	}
+}
@@ -0,0 +1,138 @@
+package cache_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+type cachedPage struct {
+	URL       string            `json:"url"`
+	Title     string            `json:"title,omitempty"`
+	Status    int               `json:"status"`
+	Links     []string          `json:"links"`
+	Headers   map[string]string `json:"headers"`
+	CrawledAt time.Time         `json:"crawled_at"`
+}
+
+// TestSerializers_RoundTrip tests that every serializer decodes what it encodes
+func TestSerializers_RoundTrip(t *testing.T) {
+	original := cachedPage{
+		URL:       "https://example.com/",
+		Title:     "Example",
+		Status:    200,
+		Links:     []string{"https://example.com/a", "https://example.com/b"},
+		Headers:   map[string]string{"Content-Type": "text/html"},
+		CrawledAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	for _, serializer := range []cache.Serializer{cache.JSONSerializer{}, cache.MsgpackSerializer{}} {
+		t.Run(serializer.Name(), func(t *testing.T) {
+			data, err := serializer.Marshal(original)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+
+			var decoded cachedPage
+			if err := serializer.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			decoded.CrawledAt = decoded.CrawledAt.UTC()
+			if !reflect.DeepEqual(decoded, original) {
+				t.Errorf("Unmarshal() = %+v, want %+v", decoded, original)
+			}
+		})
+	}
+}
+
+// TestMsgpackSerializer_JSONTags tests that msgpack uses json tag names and is smaller
+func TestMsgpackSerializer_JSONTags(t *testing.T) {
+	value := cachedPage{URL: "https://example.com/", Status: 200}
+
+	packed, err := cache.MsgpackSerializer{}.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+
+	var fields map[string]interface{}
+	if err := (cache.MsgpackSerializer{}).Unmarshal(packed, &fields); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if fields["url"] != "https://example.com/" {
+		t.Errorf("fields[url] = %v, want json tag names: %v", fields["url"], fields)
+	}
+	if _, ok := fields["title"]; ok {
+		t.Error("omitempty field should be omitted")
+	}
+
+	encoded, err := cache.JSONSerializer{}.Marshal(value)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if len(packed) >= len(encoded) {
+		t.Errorf("msgpack size = %d, want smaller than JSON size %d", len(packed), len(encoded))
+	}
+}
+
+// TestSerializerByName tests serializer lookup by config name
+func TestSerializerByName(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    string
+		wantErr bool
+	}{
+		{"", cache.SerializerJSON, false},
+		{"json", cache.SerializerJSON, false},
+		{"msgpack", cache.SerializerMsgpack, false},
+		{"gob", "", true},
+	}
+
+	for _, tt := range tests {
+		serializer, err := cache.SerializerByName(tt.name)
+		if tt.wantErr {
+			if errs.CodeOf(err) != errs.CodeCacheConfig {
+				t.Errorf("SerializerByName(%q) error code = %v, want %v", tt.name, errs.CodeOf(err), errs.CodeCacheConfig)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("SerializerByName(%q) error = %v", tt.name, err)
+		}
+		if serializer.Name() != tt.want {
+			t.Errorf("SerializerByName(%q) = %q, want %q", tt.name, serializer.Name(), tt.want)
+		}
+	}
+}
+
+// TestRedisClient_MsgpackSerializer tests SetJSON/GetJSON with msgpack
+func TestRedisClient_MsgpackSerializer(t *testing.T) {
+	client, err := cache.NewRedisClient(cache.RedisConfig{
+		Addr:       "localhost:6379",
+		Serializer: cache.MsgpackSerializer{},
+	})
+	if err != nil {
+		t.Skipf("Redis not available: %v", err)
+	}
+	defer client.Close()
+
+	if client.Serializer().Name() != cache.SerializerMsgpack {
+		t.Errorf("Serializer() = %q, want %q", client.Serializer().Name(), cache.SerializerMsgpack)
+	}
+
+	original := cachedPage{URL: "https://example.com/", Status: 200, Links: []string{"a"}}
+	if err := client.SetJSON("msgpack-key", original, time.Minute); err != nil {
+		t.Fatalf("SetJSON() error = %v", err)
+	}
+	defer client.Delete("msgpack-key")
+
+	var decoded cachedPage
+	if err := client.GetJSON("msgpack-key", &decoded); err != nil {
+		t.Fatalf("GetJSON() error = %v", err)
+	}
+	if decoded.URL != original.URL || decoded.Status != original.Status {
+		t.Errorf("GetJSON() = %+v, want %+v", decoded, original)
+	}
+}
@@ -0,0 +1,135 @@
+package cache_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/alonecandies/golwarc/cache"
+)
+
+func TestNewShardedLRUCache(t *testing.T) {
+	tests := []struct {
+		name       string
+		size       int
+		shards     int
+		wantShards int
+		wantErr    bool
+	}{
+		{"power of two", 1000, 8, 8, false},
+		{"rounded up", 1000, 6, 8, false},
+		{"one per CPU", 1000, 0, 0, false},
+		{"capped by size", 2, 16, 2, false},
+		{"zero size", 0, 4, 0, true},
+		{"negative shards", 100, -1, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := cache.NewShardedLRUCache(tt.size, tt.shards)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewShardedLRUCache() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantShards > 0 && c.Shards() != tt.wantShards {
+				t.Errorf("Shards() = %d, want %d", c.Shards(), tt.wantShards)
+			}
+			if c.Shards() < 1 {
+				t.Errorf("Shards() = %d, want at least 1", c.Shards())
+			}
+		})
+	}
+}
+
+func TestShardedLRUCache_Operations(t *testing.T) {
+	c, err := cache.NewShardedLRUCache(100, 4)
+	if err != nil {
+		t.Fatalf("NewShardedLRUCache() error = %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		c.Set(fmt.Sprintf("key%d", i), i)
+	}
+	if c.Len() != 50 {
+		t.Errorf("Len() = %d, want 50", c.Len())
+	}
+	if len(c.Keys()) != 50 {
+		t.Errorf("len(Keys()) = %d, want 50", len(c.Keys()))
+	}
+
+	if value, ok := c.Get("key7"); !ok || value != 7 {
+		t.Errorf("Get(key7) = %v, %v; want 7, true", value, ok)
+	}
+	if value, ok := c.Peek("key8"); !ok || value != 8 {
+		t.Errorf("Peek(key8) = %v, %v; want 8, true", value, ok)
+	}
+	if !c.Contains("key9") {
+		t.Error("Contains(key9) = false, want true")
+	}
+
+	if !c.Delete("key9") || c.Contains("key9") {
+		t.Error("Delete(key9) did not remove the key")
+	}
+
+	c.Clear()
+	if c.Len() != 0 {
+		t.Errorf("Len() after Clear() = %d, want 0", c.Len())
+	}
+}
+
+func TestShardedLRUCache_Eviction(t *testing.T) {
+	c, err := cache.NewShardedLRUCache(64, 4)
+	if err != nil {
+		t.Fatalf("NewShardedLRUCache() error = %v", err)
+	}
+
+	for i := 0; i < 1000; i++ {
+		c.Set(fmt.Sprintf("key%d", i), i)
+	}
+	if c.Len() > 64 {
+		t.Errorf("Len() = %d, want at most 64", c.Len())
+	}
+	if !c.Contains("key999") {
+		t.Error("Most recent key was evicted")
+	}
+
+	if _, err := c.Resize(16); err != nil {
+		t.Fatalf("Resize() error = %v", err)
+	}
+	if c.Len() > 16 {
+		t.Errorf("Len() after Resize(16) = %d, want at most 16", c.Len())
+	}
+	if _, err := c.Resize(0); err == nil {
+		t.Error("Resize(0) should return an error")
+	}
+}
+
+func TestShardedLRUCache_Concurrent(t *testing.T) {
+	c, err := cache.NewShardedLRUCache(10000, 0)
+	if err != nil {
+		t.Fatalf("NewShardedLRUCache() error = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := fmt.Sprintf("g%d-%d", g, i)
+				c.Set(key, i)
+				if _, ok := c.Get(key); !ok {
+					t.Errorf("Get(%s) missed right after Set", key)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if c.Len() != 8000 {
+		t.Errorf("Len() = %d, want 8000", c.Len())
+	}
+}
@@ -0,0 +1,291 @@
+package chaos_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/chaos"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/redis/go-redis/v9"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// record is a row written through gorm in the tests
+type record struct {
+	ID  uint
+	URL string
+}
+
+// newMockDB opens gorm on sqlmock with the database fault plugin registered
+func newMockDB(t *testing.T, injector *chaos.Injector) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+	if err := gormDB.Use(chaos.GormPlugin(injector)); err != nil {
+		t.Fatalf("Failed to register the fault plugin: %v", err)
+	}
+	return gormDB, mock
+}
+
+// =============================================================================
+// Injector Tests
+// =============================================================================
+
+func TestInjector_ErrorRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		rate     float64
+		min, max int64 // Expected failures of 1000 calls
+	}{
+		{name: "never", rate: 0, min: 0, max: 0},
+		{name: "always", rate: 1, min: 1000, max: 1000},
+		{name: "sometimes", rate: 0.3, min: 230, max: 370},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			injector := chaos.NewInjector(chaos.InjectorConfig{
+				Name:  chaos.DependencyCache,
+				Fault: chaos.Fault{ErrorRate: tt.rate},
+				Seed:  42,
+			})
+
+			var failures int64
+			for i := 0; i < 1000; i++ {
+				if err := injector.Inject(context.Background()); err != nil {
+					if !errors.Is(err, chaos.ErrInjected) || errs.CodeOf(err) != errs.CodeInjectedFault {
+						t.Fatalf("Expected an injected fault, got %v", err)
+					}
+					failures++
+				}
+			}
+
+			if failures < tt.min || failures > tt.max {
+				t.Errorf("Expected %d to %d failures, got %d", tt.min, tt.max, failures)
+			}
+			if stats := injector.Stats(); stats.Calls != 1000 || stats.Failures != failures {
+				t.Errorf("Unexpected stats: %+v", stats)
+			}
+		})
+	}
+}
+
+func TestInjector_Seed(t *testing.T) {
+	outcomes := func() []bool {
+		injector := chaos.NewInjector(chaos.InjectorConfig{Fault: chaos.Fault{ErrorRate: 0.5}, Seed: 7})
+		results := make([]bool, 50)
+		for i := range results {
+			results[i] = injector.Inject(context.Background()) != nil
+		}
+		return results
+	}
+
+	first, second := outcomes(), outcomes()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("Expected the same seed to fail the same calls, call %d differs", i)
+		}
+	}
+}
+
+func TestInjector_CustomError(t *testing.T) {
+	injector := chaos.NewInjector(chaos.InjectorConfig{
+		Name:  chaos.DependencyCache,
+		Fault: chaos.Fault{ErrorRate: 1, Err: redis.Nil},
+	})
+
+	err := injector.Inject(context.Background())
+	if !errors.Is(err, redis.Nil) {
+		t.Errorf("Expected the configured error, got %v", err)
+	}
+}
+
+func TestInjector_Latency(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	injector := chaos.NewInjector(chaos.InjectorConfig{
+		Fault: chaos.Fault{Latency: time.Second},
+		Clock: fake,
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- injector.Inject(context.Background())
+	}()
+
+	fake.BlockUntil(1)
+	select {
+	case <-done:
+		t.Fatal("Expected the call to wait for the latency")
+	default:
+	}
+
+	fake.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Expected the call to succeed after the latency, got %v", err)
+	}
+	if stats := injector.Stats(); stats.Delay != time.Second {
+		t.Errorf("Expected 1s of added latency, got %v", stats.Delay)
+	}
+}
+
+func TestInjector_LatencyTimesOut(t *testing.T) {
+	injector := chaos.NewInjector(chaos.InjectorConfig{Fault: chaos.Fault{Latency: time.Minute}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := injector.Inject(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the slow call to time out, got %v", err)
+	}
+}
+
+func TestInjector_SetFault(t *testing.T) {
+	injector := chaos.NewInjector(chaos.InjectorConfig{Fault: chaos.Fault{ErrorRate: 1}})
+	if err := injector.Inject(context.Background()); err == nil {
+		t.Fatal("Expected the call to fail")
+	}
+
+	injector.SetFault(chaos.Fault{})
+	if err := injector.Inject(context.Background()); err != nil {
+		t.Errorf("Expected the zero fault to let calls through, got %v", err)
+	}
+}
+
+func TestInjector_Nil(t *testing.T) {
+	var injector *chaos.Injector
+	if err := injector.Inject(context.Background()); err != nil {
+		t.Errorf("Expected a nil injector to inject nothing, got %v", err)
+	}
+	if stats := injector.Stats(); stats != (chaos.Stats{}) {
+		t.Errorf("Expected empty stats, got %+v", stats)
+	}
+}
+
+// =============================================================================
+// Spec Tests
+// =============================================================================
+
+func TestParseSpec(t *testing.T) {
+	faults, err := chaos.ParseSpec("cache:latency=50ms,jitter=10ms,error_rate=0.2; database:error_rate=1;queue:")
+	if err != nil {
+		t.Fatalf("ParseSpec failed: %v", err)
+	}
+
+	want := map[string]chaos.Fault{
+		chaos.DependencyCache:    {Latency: 50 * time.Millisecond, Jitter: 10 * time.Millisecond, ErrorRate: 0.2},
+		chaos.DependencyDatabase: {ErrorRate: 1},
+		chaos.DependencyQueue:    {},
+	}
+	if len(faults) != len(want) {
+		t.Fatalf("Expected %d dependencies, got %v", len(want), faults)
+	}
+	for name, fault := range want {
+		if faults[name] != fault {
+			t.Errorf("%s: expected %+v, got %+v", name, fault, faults[name])
+		}
+	}
+}
+
+func TestParseSpec_Invalid(t *testing.T) {
+	specs := []string{
+		"cache",
+		"cache:latency",
+		"cache:latency=fast",
+		"cache:latency=-1s",
+		"cache:error_rate=1.5",
+		"cache:timeout=1s",
+		"cache:error_rate=0.1;cache:latency=1s",
+	}
+
+	for _, spec := range specs {
+		if _, err := chaos.ParseSpec(spec); errs.CodeOf(err) != errs.CodeInvalidConfig {
+			t.Errorf("ParseSpec(%q): expected an invalid config error, got %v", spec, err)
+		}
+	}
+}
+
+// =============================================================================
+// Hook Tests
+// =============================================================================
+
+func TestRedisHook(t *testing.T) {
+	injector := chaos.NewInjector(chaos.InjectorConfig{
+		Name:  chaos.DependencyCache,
+		Fault: chaos.Fault{ErrorRate: 1},
+	})
+
+	// Nothing listens on the address; failed commands must not try to dial
+	client := redis.NewClient(&redis.Options{Addr: "127.0.0.1:1", MaxRetries: -1})
+	defer func() { _ = client.Close() }()
+	client.AddHook(chaos.RedisHook(injector))
+
+	ctx := context.Background()
+	if err := client.Get(ctx, "key").Err(); !errors.Is(err, chaos.ErrInjected) {
+		t.Errorf("Expected the command to fail with an injected fault, got %v", err)
+	}
+
+	pipe := client.Pipeline()
+	set := pipe.Set(ctx, "key", "value", 0)
+	get := pipe.Get(ctx, "key")
+	if _, err := pipe.Exec(ctx); !errors.Is(err, chaos.ErrInjected) {
+		t.Errorf("Expected the pipeline to fail with an injected fault, got %v", err)
+	}
+	if !errors.Is(set.Err(), chaos.ErrInjected) || !errors.Is(get.Err(), chaos.ErrInjected) {
+		t.Errorf("Expected every pipelined command to fail, got %v and %v", set.Err(), get.Err())
+	}
+
+	injector.SetFault(chaos.Fault{})
+	if err := client.Get(ctx, "key").Err(); err == nil || errors.Is(err, chaos.ErrInjected) {
+		t.Errorf("Expected the command to reach the (missing) server, got %v", err)
+	}
+	if stats := injector.Stats(); stats.Calls != 3 || stats.Failures != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestGormPlugin(t *testing.T) {
+	injector := chaos.NewInjector(chaos.InjectorConfig{
+		Name:  chaos.DependencyDatabase,
+		Fault: chaos.Fault{ErrorRate: 1},
+	})
+	db, mock := newMockDB(t, injector)
+
+	// No SQL may reach the database while the fault is active
+	if err := db.Create(&record{URL: "https://example.com/"}).Error; !errors.Is(err, chaos.ErrInjected) {
+		t.Errorf("Expected Create to fail with an injected fault, got %v", err)
+	}
+	var found []record
+	if err := db.Find(&found).Error; !errors.Is(err, chaos.ErrInjected) {
+		t.Errorf("Expected Find to fail with an injected fault, got %v", err)
+	}
+	if err := db.Exec("DELETE FROM records").Error; !errors.Is(err, chaos.ErrInjected) {
+		t.Errorf("Expected Exec to fail with an injected fault, got %v", err)
+	}
+
+	injector.SetFault(chaos.Fault{})
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `records`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := db.Create(&record{URL: "https://example.com/"}).Error; err != nil {
+		t.Errorf("Expected Create to succeed without a fault, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected SQL: %v", err)
+	}
+}
@@ -0,0 +1,169 @@
+package chaos_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/chaos"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+)
+
+// memoryDedupStore is an in-memory messagequeue.DedupStore
+type memoryDedupStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (s *memoryDedupStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	val, ok := s.values[key]
+	if !ok {
+		return "", cache.ErrCacheMiss
+	}
+	return val, nil
+}
+
+func (s *memoryDedupStore) Set(key string, value interface{}, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value.(string)
+	return nil
+}
+
+func (s *memoryDedupStore) SetNX(key string, value interface{}, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.values[key] = value.(string)
+	return true, nil
+}
+
+func (s *memoryDedupStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// =============================================================================
+// Resilience Tests
+// =============================================================================
+
+func TestResilience_IdempotencyRetriesAfterDatabaseFault(t *testing.T) {
+	injector := chaos.NewInjector(chaos.InjectorConfig{
+		Name:  chaos.DependencyDatabase,
+		Fault: chaos.Fault{ErrorRate: 1},
+	})
+	db, mock := newMockDB(t, injector)
+
+	dedup := messagequeue.NewIdempotency(messagequeue.IdempotencyConfig{
+		Store: &memoryDedupStore{values: make(map[string]string)},
+	})
+	handler := dedup.Handler(nil, func(body []byte) error {
+		return db.Create(&record{URL: string(body)}).Error
+	})
+	body := []byte("https://example.com/")
+
+	// The failed delivery must release its claim so the redelivery runs
+	if err := handler(body); !errors.Is(err, chaos.ErrInjected) {
+		t.Fatalf("Expected the first delivery to fail with an injected fault, got %v", err)
+	}
+
+	injector.SetFault(chaos.Fault{})
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `records`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	if err := handler(body); err != nil {
+		t.Fatalf("Expected the redelivery to succeed, got %v", err)
+	}
+
+	// Once stored, further redeliveries are duplicates
+	if err := handler(body); err != nil {
+		t.Fatalf("Expected the duplicate to be skipped, got %v", err)
+	}
+	if stats := dedup.Stats(); stats.Processed != 1 || stats.Duplicates != 1 {
+		t.Errorf("Unexpected idempotency stats: %+v", stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected SQL: %v", err)
+	}
+}
+
+func TestResilience_FrontierRetriesDatabaseFaults(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>` + r.URL.Path + `</body></html>`))
+	}))
+	defer server.Close()
+
+	injector := chaos.NewInjector(chaos.InjectorConfig{
+		Name:  chaos.DependencyDatabase,
+		Fault: chaos.Fault{ErrorRate: 1},
+	})
+	db, mock := newMockDB(t, injector)
+	mock.MatchExpectationsInOrder(false)
+
+	queue := frontier.NewMemoryFrontier(frontier.MemoryConfig{MaxRetries: 3})
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:     1,
+		Frontier:     queue,
+		FrontierPoll: 10 * time.Millisecond,
+	})
+
+	// /recovers fails twice and is stored on its last attempt; /down never
+	// gets through and is dead-lettered
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	spider.OnDocument(func(doc *goquery.Document, pageURL string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[pageURL]++
+		if pageURL == server.URL+"/recovers" && attempts[pageURL] == 3 {
+			injector.SetFault(chaos.Fault{})
+			defer injector.SetFault(chaos.Fault{ErrorRate: 1})
+		}
+		return db.Create(&record{URL: pageURL}).Error
+	})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `records`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	spider.AddStartURL(server.URL + "/recovers")
+	spider.AddStartURL(server.URL + "/down")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := spider.RunContext(ctx); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if attempts[server.URL+"/recovers"] != 3 || attempts[server.URL+"/down"] != 3 {
+		t.Errorf("Expected three attempts per URL, got %v", attempts)
+	}
+	dead, err := queue.Dead(context.Background())
+	if err != nil {
+		t.Fatalf("Dead failed: %v", err)
+	}
+	if len(dead) != 1 || dead[0] != server.URL+"/down" {
+		t.Errorf("Expected only /down to be dead-lettered, got %v", dead)
+	}
+	if stats := injector.Stats(); stats.Calls != 6 || stats.Failures != 5 {
+		t.Errorf("Unexpected injector stats: %+v", stats)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unexpected SQL: %v", err)
+	}
+}
@@ -0,0 +1,170 @@
+package clock_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+)
+
+// =============================================================================
+// Fake Clock Tests
+// =============================================================================
+
+func TestFake_NowAndAdvance(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+
+	if !fake.Now().Equal(start) {
+		t.Errorf("Now() = %v, want %v", fake.Now(), start)
+	}
+	fake.Advance(90 * time.Second)
+	if got := fake.Since(start); got != 90*time.Second {
+		t.Errorf("Since(start) = %v, want 90s", got)
+	}
+	if got := fake.Until(start.Add(time.Hour)); got != time.Hour-90*time.Second {
+		t.Errorf("Until() = %v, want 58m30s", got)
+	}
+
+	if clock.NewFake(time.Time{}).Now().IsZero() {
+		t.Error("Expected a zero start to use a fixed date")
+	}
+}
+
+func TestFake_TimersFireInOrder(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	start := fake.Now()
+
+	var fired []time.Duration
+	fake.AfterFunc(3*time.Second, func() { fired = append(fired, fake.Since(start)) })
+	fake.AfterFunc(time.Second, func() { fired = append(fired, fake.Since(start)) })
+	timer := fake.NewTimer(2 * time.Second)
+
+	fake.Advance(1500 * time.Millisecond)
+	if len(fired) != 1 || fired[0] != time.Second {
+		t.Fatalf("Fired %v after 1.5s, want [1s]", fired)
+	}
+	select {
+	case <-timer.C():
+		t.Fatal("Timer fired early")
+	default:
+	}
+
+	fake.Advance(2 * time.Second)
+	select {
+	case at := <-timer.C():
+		if got := at.Sub(start); got != 2*time.Second {
+			t.Errorf("Timer fired at %v, want 2s", got)
+		}
+	default:
+		t.Fatal("Expected timer to fire")
+	}
+	if len(fired) != 2 || fired[1] != 3*time.Second {
+		t.Errorf("Fired %v, want callbacks to see their due time", fired)
+	}
+	if fake.Waiters() != 0 {
+		t.Errorf("Waiters() = %d, want 0", fake.Waiters())
+	}
+}
+
+func TestFake_StopAndReset(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+
+	called := false
+	timer := fake.AfterFunc(time.Second, func() { called = true })
+	if !timer.Stop() {
+		t.Error("Stop() = false for a pending timer")
+	}
+	fake.Advance(time.Minute)
+	if called {
+		t.Error("Stopped timer fired")
+	}
+	if timer.Stop() {
+		t.Error("Stop() = true for a stopped timer")
+	}
+
+	if timer.Reset(time.Second) {
+		t.Error("Reset() = true for a stopped timer")
+	}
+	fake.Advance(time.Second)
+	if !called {
+		t.Error("Expected reset timer to fire")
+	}
+}
+
+func TestFake_Ticker(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	ticker := fake.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 0; i < 3; i++ {
+		fake.Advance(time.Second)
+		select {
+		case <-ticker.C():
+		default:
+			t.Fatalf("Expected tick %d", i+1)
+		}
+	}
+
+	// Ticks missed within one move are dropped
+	fake.Advance(5 * time.Second)
+	<-ticker.C()
+	select {
+	case <-ticker.C():
+		t.Error("Expected a single tick for one Advance")
+	default:
+	}
+
+	ticker.Reset(time.Minute)
+	fake.Advance(time.Second)
+	select {
+	case <-ticker.C():
+		t.Error("Expected Reset to change the period")
+	default:
+	}
+}
+
+func TestFake_BlockUntil(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+
+	done := make(chan error, 1)
+	go func() { done <- clock.Sleep(context.Background(), fake, time.Hour) }()
+
+	fake.BlockUntil(1)
+	fake.Advance(time.Hour)
+	if err := <-done; err != nil {
+		t.Errorf("Sleep() error = %v", err)
+	}
+}
+
+// =============================================================================
+// Sleep Tests
+// =============================================================================
+
+func TestSleep_ContextDone(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error, 1)
+	go func() { done <- clock.Sleep(ctx, fake, time.Hour) }()
+
+	fake.BlockUntil(1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Sleep() error = %v, want context.Canceled", err)
+	}
+	if fake.Waiters() != 0 {
+		t.Errorf("Waiters() = %d, want the timer stopped", fake.Waiters())
+	}
+}
+
+func TestSleep_Real(t *testing.T) {
+	if err := clock.Sleep(context.Background(), clock.Real, time.Millisecond); err != nil {
+		t.Errorf("Sleep() error = %v", err)
+	}
+	if err := clock.Sleep(context.Background(), clock.Or(nil), 0); err != nil {
+		t.Errorf("Sleep(0) error = %v", err)
+	}
+}
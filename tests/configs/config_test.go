@@ -2,6 +2,8 @@ package configs_test
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/alonecandies/golwarc/configs"
@@ -23,6 +25,9 @@ func TestLoadConfig(t *testing.T) {
 	if cfg.App.Name == "" {
 		t.Error("Expected app name to be set")
 	}
+	if cfg.API.Port != 8080 {
+		t.Errorf("API port = %d, want 8080", cfg.API.Port)
+	}
 }
 
 // TestGetDefaultConfig tests the default configuration
@@ -366,3 +371,69 @@ func TestLoadConfigWithActualFile(t *testing.T) {
 		t.Error("Expected app name to be set in example config")
 	}
 }
+
+// TestConfigValidate tests struct-tag validation of config sections
+func TestConfigValidate(t *testing.T) {
+	if err := configs.GetDefaultConfig().Validate(); err != nil {
+		t.Errorf("Default config should be valid: %v", err)
+	}
+
+	cfg := configs.GetDefaultConfig()
+	cfg.Crawler.MaxDepth = 20
+	cfg.Crawler.ProxyStrategy = "fastest"
+	cfg.Crawler.Conditional = "disk"
+	cfg.Database.PostgreSQL.Port = 70000
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, field := range []string{"crawler.max_depth", "crawler.proxy_strategy", "crawler.conditional", "database.postgresql.port"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Error %q does not name %s", err.Error(), field)
+		}
+	}
+}
+
+// TestLoadConfigRejectsInvalidValues tests that LoadConfig validates the file
+func TestLoadConfigRejectsInvalidValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("logger:\n  level: verbose\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := configs.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "logger.level") {
+		t.Errorf("LoadConfig() error = %v, want logger.level error", err)
+	}
+}
+
+// TestRabbitMQTopologyConfigValidate tests validation of declared topology
+func TestRabbitMQTopologyConfigValidate(t *testing.T) {
+	cfg := configs.GetDefaultConfig()
+	cfg.MessageQueue.RabbitMQ.Topology = configs.RabbitMQTopologyConfig{
+		Exchanges: []configs.RabbitMQExchangeConfig{{Name: "crawl", Kind: "topic", Durable: true}},
+		Queues: []configs.RabbitMQQueueConfig{{
+			Name:            "crawl-tasks",
+			Durable:         true,
+			MessageTTL:      3600,
+			DeadLetterQueue: "crawl-tasks.dlq",
+		}},
+		Bindings: []configs.RabbitMQBindingConfig{{Queue: "crawl-tasks", Exchange: "crawl", RoutingKey: "task.#"}},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Valid topology rejected: %v", err)
+	}
+
+	cfg.MessageQueue.RabbitMQ.Topology.Exchanges[0].Kind = "broadcast"
+	cfg.MessageQueue.RabbitMQ.Topology.Queues[0].MessageTTL = -1
+	cfg.MessageQueue.RabbitMQ.Topology.Bindings[0].Exchange = ""
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Expected validation error")
+	}
+	for _, field := range []string{"kind", "message_ttl", "exchange"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Errorf("Error %q does not name %s", err.Error(), field)
+		}
+	}
+}
@@ -0,0 +1,465 @@
+package controlplane_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/controlplane"
+	"github.com/alonecandies/golwarc/errs"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// =============================================================================
+// Control Plane Tests
+// =============================================================================
+
+// startCoordinator serves a coordinator over an in-memory listener
+func startCoordinator(t *testing.T, config controlplane.CoordinatorConfig) (*controlplane.Coordinator, []grpc.DialOption) {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	coordinator := controlplane.NewCoordinator(config)
+	server := coordinator.NewServer()
+	go func() {
+		_ = server.Serve(listener) // Returns when the server is stopped
+	}()
+	t.Cleanup(server.Stop)
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	return coordinator, dialOptions
+}
+
+// startWorker runs a worker until the test ends
+func startWorker(t *testing.T, config controlplane.WorkerConfig) *controlplane.Worker {
+	t.Helper()
+
+	worker, err := controlplane.NewWorker(config)
+	if err != nil {
+		t.Fatalf("NewWorker() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = worker.Run(ctx) // Returns context.Canceled on cleanup
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return worker
+}
+
+// waitFor polls cond until it holds or the timeout expires
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Timed out waiting for %s", what)
+}
+
+func TestNewWorker_Validation(t *testing.T) {
+	handler := func(context.Context, controlplane.Task) error { return nil }
+
+	tests := []struct {
+		name   string
+		config controlplane.WorkerConfig
+	}{
+		{name: "missing ID", config: controlplane.WorkerConfig{Address: "localhost:1", OnTask: handler}},
+		{name: "missing address", config: controlplane.WorkerConfig{ID: "w1", OnTask: handler}},
+		{name: "missing handler", config: controlplane.WorkerConfig{ID: "w1", Address: "localhost:1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := controlplane.NewWorker(tt.config); err == nil {
+				t.Error("Expected validation error")
+			}
+		})
+	}
+}
+
+func TestCoordinator_AssignAndResult(t *testing.T) {
+	results := make(chan controlplane.TaskResult, 1)
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{
+		OnResult: func(workerID string, result controlplane.TaskResult) {
+			if workerID != "w1" {
+				t.Errorf("Result from %s, want w1", workerID)
+			}
+			results <- result
+		},
+	})
+
+	startWorker(t, controlplane.WorkerConfig{
+		ID:          "w1",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask: func(_ context.Context, task controlplane.Task) error {
+			if task.URL != "https://example.com" {
+				return errors.New("unexpected URL")
+			}
+			return nil
+		},
+	})
+	waitFor(t, "worker to connect", func() bool { return len(coordinator.Workers()) == 1 })
+
+	workerID, err := coordinator.AssignAny(controlplane.Task{ID: "t1", URL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("AssignAny() error = %v", err)
+	}
+	if workerID != "w1" {
+		t.Errorf("AssignAny() worker = %s, want w1", workerID)
+	}
+
+	select {
+	case result := <-results:
+		if result.TaskID != "t1" || result.Status != controlplane.StatusDone {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for task result")
+	}
+
+	if tasks := coordinator.Workers()[0].Tasks; len(tasks) != 0 {
+		t.Errorf("Expected finished task to be cleared, got %v", tasks)
+	}
+}
+
+func TestCoordinator_Cancel(t *testing.T) {
+	results := make(chan controlplane.TaskResult, 1)
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{
+		OnResult: func(_ string, result controlplane.TaskResult) { results <- result },
+	})
+
+	started := make(chan struct{})
+	startWorker(t, controlplane.WorkerConfig{
+		ID:          "w1",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask: func(ctx context.Context, _ controlplane.Task) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	})
+	waitFor(t, "worker to connect", func() bool { return len(coordinator.Workers()) == 1 })
+
+	if err := coordinator.Assign("w1", controlplane.Task{ID: "slow", URL: "https://example.com"}); err != nil {
+		t.Fatalf("Assign() error = %v", err)
+	}
+	<-started
+
+	if err := coordinator.Cancel("slow"); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+
+	select {
+	case result := <-results:
+		if result.Status != controlplane.StatusCanceled {
+			t.Errorf("Status = %s, want %s", result.Status, controlplane.StatusCanceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for cancellation")
+	}
+
+	if err := coordinator.Cancel("slow"); !errors.Is(err, controlplane.ErrUnknownTask) {
+		t.Errorf("Cancel() of finished task error = %v, want ErrUnknownTask", err)
+	}
+}
+
+func TestCoordinator_PushConfig(t *testing.T) {
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{})
+
+	var mu sync.Mutex
+	var pushed map[string]string
+	worker := startWorker(t, controlplane.WorkerConfig{
+		ID:          "w1",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask:      func(context.Context, controlplane.Task) error { return nil },
+		OnConfig: func(config map[string]string) {
+			mu.Lock()
+			pushed = config
+			mu.Unlock()
+		},
+	})
+	waitFor(t, "worker to connect", func() bool { return len(coordinator.Workers()) == 1 })
+
+	coordinator.PushConfig(map[string]string{"delay": "500"})
+	waitFor(t, "config push", func() bool { return worker.Config()["delay"] == "500" })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if pushed["delay"] != "500" {
+		t.Errorf("OnConfig received %v", pushed)
+	}
+}
+
+func TestCoordinator_ConfigSentOnConnect(t *testing.T) {
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{})
+	coordinator.PushConfig(map[string]string{"user_agent": "GolwarcBot"})
+
+	worker := startWorker(t, controlplane.WorkerConfig{
+		ID:          "late",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask:      func(context.Context, controlplane.Task) error { return nil },
+	})
+	waitFor(t, "initial config", func() bool { return worker.Config()["user_agent"] == "GolwarcBot" })
+}
+
+func TestCoordinator_Heartbeat(t *testing.T) {
+	coordinatorClock := clock.NewFake(time.Time{})
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{
+		HeartbeatTimeout: time.Minute,
+		Clock:            coordinatorClock,
+	})
+
+	workerClock := clock.NewFake(time.Time{})
+	startWorker(t, controlplane.WorkerConfig{
+		ID:                "w1",
+		Address:           "passthrough:///bufnet",
+		HeartbeatInterval: 10 * time.Second,
+		DialOptions:       dialOptions,
+		OnTask:            func(context.Context, controlplane.Task) error { return nil },
+		Clock:             workerClock,
+	})
+	waitFor(t, "worker", func() bool { return len(coordinator.Workers()) == 1 })
+
+	// A worker silent for longer than the timeout is not assigned tasks
+	coordinatorClock.Advance(time.Minute + time.Second)
+	if coordinator.Workers()[0].Healthy {
+		t.Error("Expected silent worker to be unhealthy")
+	}
+	if _, err := coordinator.AssignAny(controlplane.Task{ID: "t1"}); !errors.Is(err, controlplane.ErrNoWorkers) {
+		t.Errorf("AssignAny() error = %v, want ErrNoWorkers", err)
+	}
+
+	workerClock.BlockUntil(1)
+	workerClock.Advance(10 * time.Second)
+	waitFor(t, "heartbeat", func() bool {
+		workers := coordinator.Workers()
+		return len(workers) == 1 && workers[0].Health.Status == "ok"
+	})
+	if !coordinator.Workers()[0].Healthy {
+		t.Error("Expected worker sending heartbeats to be healthy")
+	}
+}
+
+func TestCoordinator_NoWorkers(t *testing.T) {
+	coordinator := controlplane.NewCoordinator(controlplane.CoordinatorConfig{})
+
+	if _, err := coordinator.AssignAny(controlplane.Task{ID: "t1"}); !errors.Is(err, controlplane.ErrNoWorkers) {
+		t.Errorf("AssignAny() error = %v, want ErrNoWorkers", err)
+	}
+	if err := coordinator.Assign("missing", controlplane.Task{ID: "t1"}); !errors.Is(err, controlplane.ErrUnknownWorker) {
+		t.Errorf("Assign() error = %v, want ErrUnknownWorker", err)
+	}
+}
+
+func TestCoordinator_WorkerDisconnect(t *testing.T) {
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{})
+
+	worker, err := controlplane.NewWorker(controlplane.WorkerConfig{
+		ID:          "w1",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask:      func(context.Context, controlplane.Task) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("NewWorker() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- worker.Run(ctx) }()
+	waitFor(t, "worker to connect", func() bool { return len(coordinator.Workers()) == 1 })
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Run() error = %v, want context.Canceled", err)
+	}
+	waitFor(t, "worker removal", func() bool { return len(coordinator.Workers()) == 0 })
+}
+
+func TestCoordinator_WorkerDisconnect_RequeuesTasks(t *testing.T) {
+	type outcome struct {
+		workerID string
+		result   controlplane.TaskResult
+	}
+	results := make(chan outcome, 1)
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{
+		OnResult: func(workerID string, result controlplane.TaskResult) {
+			results <- outcome{workerID, result}
+		},
+	})
+
+	gone := connectLegacyWorker(t, "w1", dialOptions)
+	waitFor(t, "worker to connect", func() bool { return len(coordinator.Workers()) == 1 })
+	if err := coordinator.Assign("w1", controlplane.Task{ID: "t1", URL: "https://example.com"}); err != nil {
+		t.Fatalf("Assign() error = %v", err)
+	}
+	var msg map[string]any
+	if err := gone.RecvMsg(&msg); err != nil {
+		t.Fatalf("RecvMsg() error = %v", err)
+	}
+
+	startWorker(t, controlplane.WorkerConfig{
+		ID:          "w2",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask:      func(context.Context, controlplane.Task) error { return nil },
+	})
+	waitFor(t, "second worker to connect", func() bool { return len(coordinator.Workers()) == 2 })
+
+	// w1 drops the stream without reporting its task
+	if err := gone.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error = %v", err)
+	}
+
+	select {
+	case got := <-results:
+		if got.workerID != "w2" || got.result.TaskID != "t1" || got.result.Status != controlplane.StatusDone {
+			t.Errorf("Got %+v from %s, want t1 done by w2", got.result, got.workerID)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the requeued task")
+	}
+}
+
+func TestCoordinator_WorkerDisconnect_ReportsLostTasks(t *testing.T) {
+	results := make(chan controlplane.TaskResult, 1)
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{
+		OnResult: func(workerID string, result controlplane.TaskResult) {
+			if workerID != "w1" {
+				t.Errorf("Result from %s, want w1", workerID)
+			}
+			results <- result
+		},
+	})
+
+	gone := connectLegacyWorker(t, "w1", dialOptions)
+	waitFor(t, "worker to connect", func() bool { return len(coordinator.Workers()) == 1 })
+	if err := coordinator.Assign("w1", controlplane.Task{ID: "t1", URL: "https://example.com"}); err != nil {
+		t.Fatalf("Assign() error = %v", err)
+	}
+	var msg map[string]any
+	if err := gone.RecvMsg(&msg); err != nil {
+		t.Fatalf("RecvMsg() error = %v", err)
+	}
+	if err := gone.CloseSend(); err != nil {
+		t.Fatalf("CloseSend() error = %v", err)
+	}
+
+	// No worker is left to take the task, so it is reported as failed
+	select {
+	case result := <-results:
+		if result.TaskID != "t1" || result.Status != controlplane.StatusFailed || result.Error == "" {
+			t.Errorf("Unexpected result: %+v", result)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the lost task to be reported")
+	}
+}
+
+// legacyCodec encodes messages as JSON the way the control plane does
+type legacyCodec struct{}
+
+func (legacyCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (legacyCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (legacyCodec) Name() string                       { return "json" }
+
+// connectLegacyWorker opens a control stream the way workers released
+// before task versioning did, without announcing a schema range
+func connectLegacyWorker(t *testing.T, id string, dialOptions []grpc.DialOption) grpc.ClientStream {
+	t.Helper()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet", append(dialOptions, grpc.WithDefaultCallOptions(grpc.ForceCodec(legacyCodec{})))...)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	stream, err := conn.NewStream(ctx, &grpc.StreamDesc{ServerStreams: true, ClientStreams: true},
+		"/golwarc.controlplane.v1.ControlPlane/Connect")
+	if err != nil {
+		t.Fatalf("NewStream() error = %v", err)
+	}
+	if err := stream.SendMsg(map[string]any{"type": "hello", "worker_id": id}); err != nil {
+		t.Fatalf("SendMsg(hello) error = %v", err)
+	}
+	return stream
+}
+
+func TestCoordinator_TaskSchemaNegotiation(t *testing.T) {
+	coordinator, dialOptions := startCoordinator(t, controlplane.CoordinatorConfig{})
+
+	tasks := make(chan controlplane.Task, 1)
+	startWorker(t, controlplane.WorkerConfig{
+		ID:          "new",
+		Address:     "passthrough:///bufnet",
+		DialOptions: dialOptions,
+		OnTask: func(_ context.Context, task controlplane.Task) error {
+			tasks <- task
+			return nil
+		},
+	})
+	legacy := connectLegacyWorker(t, "old", dialOptions)
+	waitFor(t, "workers to connect", func() bool { return len(coordinator.Workers()) == 2 })
+
+	for _, status := range coordinator.Workers() {
+		want := map[string]int{"new": controlplane.TaskSchema.Current(), "old": 1}[status.ID]
+		if status.TaskSchema != want {
+			t.Errorf("Worker %s negotiated task schema %d, want %d", status.ID, status.TaskSchema, want)
+		}
+	}
+
+	// Version 1 workers cannot honor an engine, so only the new worker may take it
+	if err := coordinator.Assign("old", controlplane.Task{ID: "t1", URL: "https://example.com", Engine: "playwright"}); errs.CodeOf(err) != errs.CodeSchemaUnsupported {
+		t.Errorf("Assign() to a version 1 worker error = %v, want %s", err, errs.CodeSchemaUnsupported)
+	}
+	if err := coordinator.Assign("old", controlplane.Task{ID: "t2", URL: "https://example.com"}); err != nil {
+		t.Errorf("Assign() of a version 1 task error = %v", err)
+	}
+	var msg map[string]any
+	if err := legacy.RecvMsg(&msg); err != nil {
+		t.Fatalf("RecvMsg() error = %v", err)
+	}
+	if task, _ := msg["task"].(map[string]any); task["id"] != "t2" || task["engine"] != nil {
+		t.Errorf("Legacy worker received %v, want task t2 without an engine", msg)
+	}
+
+	workerID, err := coordinator.AssignAny(controlplane.Task{ID: "t3", URL: "https://example.com", Engine: "playwright"})
+	if err != nil || workerID != "new" {
+		t.Fatalf("AssignAny() = %s, %v; want the new worker", workerID, err)
+	}
+	select {
+	case task := <-tasks:
+		if task.Engine != "playwright" {
+			t.Errorf("New worker received %+v, want the engine kept", task)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the task")
+	}
+}
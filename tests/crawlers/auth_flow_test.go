@@ -0,0 +1,273 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Auth Flow Tests
+// =============================================================================
+
+// loginSite is a site behind a form login with a CSRF token, an optional
+// second factor and sessions the test can expire
+type loginSite struct {
+	*httptest.Server
+	twoFactor bool
+	logins    atomic.Int32 // Successful logins
+	attempts  atomic.Int32 // Login form submissions
+
+	mu       sync.Mutex
+	csrf     string
+	sessions map[string]bool
+}
+
+func newLoginSite(t *testing.T, twoFactor bool) *loginSite {
+	t.Helper()
+	s := &loginSite{twoFactor: twoFactor, sessions: make(map[string]bool)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", s.login)
+	mux.HandleFunc("/2fa", s.secondFactor)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if !s.valid(r) {
+			http.Redirect(w, r, "/login", http.StatusFound)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<html><body><a class="logout" href="/logout">Log out</a>
+			<a href="%s/1">1</a><a href="%s/2">2</a><a href="%s/3">3</a></body></html>`, r.URL.Path, r.URL.Path, r.URL.Path)
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *loginSite) login(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		s.mu.Lock()
+		s.csrf = fmt.Sprintf("csrf-%d", s.attempts.Load())
+		token := s.csrf
+		s.mu.Unlock()
+		_, _ = fmt.Fprintf(w, `<html><head><meta name="csrf-token" content="%s"></head><body>
+			<form id="search" action="/search"><input name="q"></form>
+			<form method="post" action="/login">
+				<input type="hidden" name="_csrf" value="%s">
+				<input name="username"><input type="password" name="password">
+				<input type="checkbox" name="remember" value="1" checked>
+				<input type="submit" name="go" value="Log in">
+			</form></body></html>`, token, token)
+		return
+	}
+
+	s.attempts.Add(1)
+	s.mu.Lock()
+	csrf := s.csrf
+	s.mu.Unlock()
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("_csrf") != csrf || r.Header.Get("X-CSRF-Token") != csrf ||
+		r.PostForm.Get("username") != "ada" || r.PostForm.Get("password") != "s3cret" || r.PostForm.Get("remember") != "1" || r.PostForm.Has("go") {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`<html><body><form method="post"><input type="password" name="password"></form></body></html>`))
+		return
+	}
+	if s.twoFactor {
+		_, _ = w.Write([]byte(`<html><body><form id="otp" method="post" action="/2fa">
+			<input type="hidden" name="ticket" value="t-1"><input name="code"></form></body></html>`))
+		return
+	}
+	s.startSession(w, r)
+}
+
+func (s *loginSite) secondFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("ticket") != "t-1" || r.PostForm.Get("code") != "123456" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	s.startSession(w, r)
+}
+
+func (s *loginSite) startSession(w http.ResponseWriter, r *http.Request) {
+	id := fmt.Sprintf("session-%d", s.logins.Add(1))
+	s.mu.Lock()
+	s.sessions[id] = true
+	s.mu.Unlock()
+	http.SetCookie(w, &http.Cookie{Name: "sid", Value: id, Path: "/"})
+	http.Redirect(w, r, "/account", http.StatusSeeOther)
+}
+
+func (s *loginSite) valid(r *http.Request) bool {
+	c, err := r.Cookie("sid")
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessions[c.Value]
+}
+
+func (s *loginSite) expire() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions = make(map[string]bool)
+}
+
+func newTestAuthFlow(t *testing.T, config crawlers.AuthConfig) *crawlers.AuthFlow {
+	t.Helper()
+	if config.Fields == nil {
+		config.Fields = map[string]string{"username": "ada", "password": "s3cret"}
+	}
+	config.CSRFSelector = `meta[name="csrf-token"]`
+	config.CSRFField = "_csrf"
+	config.CSRFHeader = "X-CSRF-Token"
+	flow, err := crawlers.NewAuthFlow(config)
+	if err != nil {
+		t.Fatalf("NewAuthFlow() error = %v", err)
+	}
+	return flow
+}
+
+func TestAuthFlow_SoupRefreshesExpiredSession(t *testing.T) {
+	site := newLoginSite(t, false)
+	flow := newTestAuthFlow(t, crawlers.AuthConfig{LoginURL: site.URL + "/login", LoggedInSelector: "a.logout"})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Auth: flow})
+
+	for i := 0; i < 2; i++ {
+		doc, err := client.Get(site.URL + "/account")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if doc.Find("a", "class", "logout").Error != nil {
+			t.Fatalf("Expected the account page, got %s", doc.HTML())
+		}
+	}
+	if n := site.logins.Load(); n != 1 {
+		t.Errorf("Expected one login, got %d", n)
+	}
+
+	// An expired session redirects to the login page; the flow logs in again
+	site.expire()
+	doc, err := client.Get(site.URL + "/account")
+	if err != nil {
+		t.Fatalf("Get() after expiry error = %v", err)
+	}
+	if doc.Find("a", "class", "logout").Error != nil {
+		t.Errorf("Expected the account page after expiry, got %s", doc.HTML())
+	}
+	if n := site.logins.Load(); n != 2 {
+		t.Errorf("Expected a second login after expiry, got %d", n)
+	}
+
+	cookies, err := flow.ExportCookies()
+	if err != nil || len(cookies) != 1 || cookies[0].Value != "session-2" {
+		t.Errorf("ExportCookies() = %+v, %v; want the refreshed session", cookies, err)
+	}
+}
+
+func TestAuthFlow_SpiderLogsInOncePerHost(t *testing.T) {
+	site := newLoginSite(t, false)
+	flow := newTestAuthFlow(t, crawlers.AuthConfig{LoginURL: "/login"})
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Concurrency: 4, Auth: flow})
+
+	var pages atomic.Int32
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		if doc.Find("a.logout").Length() > 0 {
+			pages.Add(1)
+		}
+		for _, link := range spider.ExtractLinks(doc, "a") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil && !strings.HasSuffix(resolved, "/logout") {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(site.URL + "/account")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if n := pages.Load(); n != 4 {
+		t.Errorf("Expected 4 logged-in pages, got %d", n)
+	}
+	if n := site.logins.Load(); n != 1 {
+		t.Errorf("Expected one login, got %d", n)
+	}
+}
+
+func TestAuthFlow_CollyTwoFactor(t *testing.T) {
+	site := newLoginSite(t, true)
+	var challenges []string
+	flow := newTestAuthFlow(t, crawlers.AuthConfig{
+		LoginURL:          site.URL + "/login",
+		TwoFactorSelector: "form#otp",
+		TwoFactor: func(ctx context.Context, challenge crawlers.TwoFactorChallenge) (map[string]string, error) {
+			challenges = append(challenges, challenge.URL)
+			return map[string]string{"code": "123456"}, nil
+		},
+	})
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{Auth: flow})
+
+	var loggedIn bool
+	client.OnHTML("a.logout", func(e *colly.HTMLElement) {
+		loggedIn = true
+	})
+	if err := client.Visit(site.URL + "/account"); err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+	if !loggedIn || site.logins.Load() != 1 {
+		t.Errorf("Expected one login through the second factor, got %d", site.logins.Load())
+	}
+	if len(challenges) != 1 || challenges[0] != site.URL+"/login" {
+		t.Errorf("Expected one challenge on the login page, got %v", challenges)
+	}
+}
+
+func TestAuthFlow_WrongPassword(t *testing.T) {
+	site := newLoginSite(t, false)
+	flow := newTestAuthFlow(t, crawlers.AuthConfig{
+		LoginURL: site.URL + "/login",
+		Fields:   map[string]string{"username": "ada", "password": "wrong"},
+	})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Auth: flow})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.Get(site.URL + "/account")
+		var loginErr *crawlers.LoginError
+		if !errors.As(err, &loginErr) || errs.CodeOf(err) != errs.CodeLoginFailed {
+			t.Fatalf("Expected a LoginError, got %v", err)
+		}
+	}
+	if n := site.attempts.Load(); n != 1 {
+		t.Errorf("Expected a failed login not to be retried, got %d attempts", n)
+	}
+
+	// Other hosts are not covered by an absolute login URL
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>public</body></html>"))
+	}))
+	defer other.Close()
+	if _, err := client.Get(strings.Replace(other.URL, "127.0.0.1", "localhost", 1)); err != nil {
+		t.Errorf("Get() of another host error = %v", err)
+	}
+}
+
+func TestNewAuthFlow_InvalidConfig(t *testing.T) {
+	for _, config := range []crawlers.AuthConfig{
+		{},
+		{LoginURL: "://bad"},
+		{LoginURL: "/login", TwoFactorSelector: "form#otp"},
+	} {
+		if _, err := crawlers.NewAuthFlow(config); errs.CodeOf(err) != errs.CodeInvalidConfig {
+			t.Errorf("NewAuthFlow(%+v) error = %v, want %s", config, err, errs.CodeInvalidConfig)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package crawlers_test
+
+import (
+	"testing"
+
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Bot Identity Tests
+// =============================================================================
+
+func TestBotIdentity_UserAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		identity crawlers.BotIdentity
+		want     string
+	}{
+		{"name only", crawlers.BotIdentity{Name: "AcmeBot"}, "Mozilla/5.0 (compatible; AcmeBot)"},
+		{"version", crawlers.BotIdentity{Name: "AcmeBot", Version: "1.0"}, "Mozilla/5.0 (compatible; AcmeBot/1.0)"},
+		{
+			"info URL and email",
+			crawlers.BotIdentity{Name: "AcmeBot", Version: "2.1", InfoURL: "https://acme.example/bot", Email: "bot@acme.example"},
+			"Mozilla/5.0 (compatible; AcmeBot/2.1; +https://acme.example/bot; bot@acme.example)",
+		},
+		{"email only", crawlers.BotIdentity{Name: "AcmeBot", Email: "bot@acme.example"}, "Mozilla/5.0 (compatible; AcmeBot; bot@acme.example)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.identity.UserAgent(); got != tt.want {
+				t.Errorf("UserAgent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBotIdentity_RobotsAgentOfUserAgent(t *testing.T) {
+	identity := crawlers.BotIdentity{Name: "Acme-Crawler", Version: "1.0", InfoURL: "https://bot.example/info", Email: "robots@bot.example"}
+
+	if got := identity.Agent(); got != "acme-crawler" {
+		t.Errorf("Agent() = %q, want acme-crawler", got)
+	}
+	if got := crawlers.RobotsAgent(identity.UserAgent()); got != "acme-crawler" {
+		t.Errorf("RobotsAgent(%q) = %q, want acme-crawler", identity.UserAgent(), got)
+	}
+}
+
+func TestNewBotIdentity(t *testing.T) {
+	identity, err := crawlers.NewBotIdentity(configs.BotIdentityConfig{})
+	if err != nil || identity != nil {
+		t.Fatalf("NewBotIdentity() without a name = %v, %v, want nil, nil", identity, err)
+	}
+
+	identity, err = crawlers.NewBotIdentity(configs.BotIdentityConfig{Name: "AcmeBot", Version: "1.0", Operator: "Acme Inc."})
+	if err != nil {
+		t.Fatalf("NewBotIdentity() failed: %v", err)
+	}
+	if identity.Token() != "AcmeBot/1.0" || identity.Operator != "Acme Inc." {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+
+	invalid := []configs.BotIdentityConfig{
+		{Name: "Acme Bot"},
+		{Name: "AcmeBot2"},
+		{Name: "AcmeBot", Version: "1.0 beta"},
+		{Name: "AcmeBot", InfoURL: "acme.example/bot"},
+		{Name: "AcmeBot", InfoURL: "ftp://acme.example/bot"},
+		{Name: "AcmeBot", Email: "acme.example"},
+		{Name: "AcmeBot", Email: "bot@acme.example; evil"},
+	}
+	for _, config := range invalid {
+		if _, err := crawlers.NewBotIdentity(config); errs.CodeOf(err) != errs.CodeInvalidConfig {
+			t.Errorf("NewBotIdentity(%+v) error = %v, want %s", config, err, errs.CodeInvalidConfig)
+		}
+	}
+}
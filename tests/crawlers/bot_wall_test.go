@@ -0,0 +1,282 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Bot Wall Tests
+// =============================================================================
+
+const (
+	cloudflarePage = `<html><head><title>Just a moment...</title></head>` +
+		`<body><script src="/cdn-cgi/challenge-platform/h/b/orchestrate/chl_page/v1"></script></body></html>`
+	recaptchaPage = `<html><head><title>Sign in</title>` +
+		`<script src="https://www.google.com/recaptcha/api.js"></script></head>` +
+		`<body><form><div class="g-recaptcha"></div></form></body></html>`
+	articlePage = `<html><head><title>Article</title></head><body><p>Content</p></body></html>`
+)
+
+// blockMetrics counts blocked responses by kind
+type blockMetrics struct {
+	mu    sync.Mutex
+	kinds map[string]int
+}
+
+func (m *blockMetrics) RecordBlocked(kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.kinds == nil {
+		m.kinds = make(map[string]int)
+	}
+	m.kinds[kind]++
+}
+
+// newBotWallServer serves a Cloudflare challenge at /blocked and an
+// ordinary page everywhere else
+func newBotWallServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/blocked" {
+			w.Header().Set("Server", "cloudflare")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(cloudflarePage))
+			return
+		}
+		_, _ = w.Write([]byte(articlePage))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDetectBlock(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		header http.Header
+		body   string
+		want   string // Kind; empty means not blocked
+	}{
+		{
+			name:   "cf-mitigated header",
+			status: http.StatusForbidden,
+			header: http.Header{"Cf-Mitigated": {"challenge"}},
+			want:   crawlers.BlockKindCloudflare,
+		},
+		{
+			name:   "cloudflare challenge script",
+			status: http.StatusForbidden,
+			body:   cloudflarePage,
+			want:   crawlers.BlockKindCloudflare,
+		},
+		{
+			name:   "cloudflare interstitial title",
+			status: http.StatusServiceUnavailable,
+			header: http.Header{"Server": {"cloudflare"}},
+			body:   `<html><head><title>Just a moment...</title></head></html>`,
+			want:   crawlers.BlockKindCloudflare,
+		},
+		{
+			name:   "recaptcha on 403",
+			status: http.StatusForbidden,
+			body:   recaptchaPage,
+			want:   crawlers.BlockKindRecaptcha,
+		},
+		{
+			name:   "recaptcha on an ordinary login page",
+			status: http.StatusOK,
+			body:   recaptchaPage,
+		},
+		{
+			name:   "hcaptcha asking for a human",
+			status: http.StatusOK,
+			body:   `<html><head><title>Verify you are human</title></head><body><div class="h-captcha"></div></body></html>`,
+			want:   crawlers.BlockKindHCaptcha,
+		},
+		{
+			name:   "datadome",
+			status: http.StatusForbidden,
+			header: http.Header{"X-Datadome": {"protected"}},
+			want:   crawlers.BlockKindDataDome,
+		},
+		{
+			name:   "akamai access denied",
+			status: http.StatusForbidden,
+			header: http.Header{"Server": {"AkamaiGHost"}},
+			body:   `<html><head><title>Access Denied</title></head></html>`,
+			want:   crawlers.BlockKindAkamai,
+		},
+		{
+			name:   "generic challenge on 429",
+			status: http.StatusTooManyRequests,
+			body:   `<html><body>We have detected unusual traffic from your network</body></html>`,
+			want:   crawlers.BlockKindChallenge,
+		},
+		{
+			name:   "ordinary 404",
+			status: http.StatusNotFound,
+			body:   `<html><head><title>Not Found</title></head></html>`,
+		},
+		{
+			name:   "ordinary page",
+			status: http.StatusOK,
+			body:   articlePage,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			block := crawlers.DetectBlock(tt.status, tt.header, []byte(tt.body))
+			switch {
+			case tt.want == "" && block != nil:
+				t.Errorf("Expected no block, got %+v", block)
+			case tt.want != "" && block == nil:
+				t.Errorf("Expected a %s block, got none", tt.want)
+			case tt.want != "" && block.Kind != tt.want:
+				t.Errorf("Expected a %s block, got %+v", tt.want, block)
+			}
+		})
+	}
+}
+
+func TestBlockDetector_Callbacks(t *testing.T) {
+	metrics := &blockMetrics{}
+	detector := crawlers.NewBlockDetector(metrics)
+
+	var blocked []*crawlers.Block
+	detector.OnBlocked(func(b *crawlers.Block) {
+		blocked = append(blocked, b)
+	})
+
+	if b := detector.Check("https://example.com/", http.StatusOK, nil, []byte(articlePage)); b != nil {
+		t.Errorf("Expected an ordinary page to pass, got %+v", b)
+	}
+	b := detector.Check("https://example.com/shop", http.StatusForbidden, nil, []byte(cloudflarePage))
+	if b == nil || b.URL != "https://example.com/shop" || b.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected a block for the challenge page, got %+v", b)
+	}
+
+	if len(blocked) != 1 || blocked[0].Kind != crawlers.BlockKindCloudflare {
+		t.Errorf("Expected OnBlocked to run once for cloudflare, got %v", blocked)
+	}
+	if counts := detector.Counts(); counts[crawlers.BlockKindCloudflare] != 1 || len(counts) != 1 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+	if metrics.kinds[crawlers.BlockKindCloudflare] != 1 {
+		t.Errorf("Expected the block to be recorded, got %v", metrics.kinds)
+	}
+}
+
+func TestBlockMiddleware(t *testing.T) {
+	server := newBotWallServer(t)
+	detector := crawlers.NewBlockDetector(nil)
+
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	c.Use(crawlers.BlockMiddleware(detector, nil))
+
+	if _, err := c.Fetch(context.Background(), server.URL+"/page"); err != nil {
+		t.Fatalf("Fetch of an ordinary page failed: %v", err)
+	}
+	_, err := c.Fetch(context.Background(), server.URL+"/blocked")
+	var blocked *crawlers.BlockedError
+	if !errors.As(err, &blocked) || errs.CodeOf(err) != errs.CodeBlocked {
+		t.Fatalf("Expected a BlockedError, got %v", err)
+	}
+	if blocked.Block.Kind != crawlers.BlockKindCloudflare {
+		t.Errorf("Expected a cloudflare block, got %+v", blocked.Block)
+	}
+}
+
+func TestBlockMiddleware_Fallback(t *testing.T) {
+	server := newBotWallServer(t)
+
+	var fallbackURL string
+	fallback := crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+		fallbackURL = url
+		return &crawlers.Result{URL: url, StatusCode: http.StatusOK, Body: []byte(articlePage), Engine: crawlers.CrawlerTypePlaywright}, nil
+	})
+
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	c.Use(crawlers.BlockMiddleware(crawlers.NewBlockDetector(nil), fallback))
+
+	result, err := c.Fetch(context.Background(), server.URL+"/blocked")
+	if err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if fallbackURL != server.URL+"/blocked" || result.StatusCode != http.StatusOK {
+		t.Errorf("Expected the fallback to fetch the blocked URL, got %q and status %d", fallbackURL, result.StatusCode)
+	}
+}
+
+func TestSoupClient_BlockDetector(t *testing.T) {
+	server := newBotWallServer(t)
+	detector := crawlers.NewBlockDetector(nil)
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{BlockDetector: detector})
+
+	if _, err := client.Get(server.URL + "/page"); err != nil {
+		t.Fatalf("Get of an ordinary page failed: %v", err)
+	}
+	_, err := client.Get(server.URL + "/blocked")
+	if errs.CodeOf(err) != errs.CodeBlocked {
+		t.Errorf("Expected a blocked error, got %v", err)
+	}
+	if detector.Counts()[crawlers.BlockKindCloudflare] != 1 {
+		t.Errorf("Expected one cloudflare block, got %v", detector.Counts())
+	}
+}
+
+func TestCollyClient_BlockDetector(t *testing.T) {
+	server := newBotWallServer(t)
+	detector := crawlers.NewBlockDetector(nil)
+
+	var blocked []string
+	detector.OnBlocked(func(b *crawlers.Block) {
+		blocked = append(blocked, b.URL)
+	})
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{BlockDetector: detector})
+
+	_ = client.Visit(server.URL + "/page")
+	_ = client.Visit(server.URL + "/blocked")
+	client.Wait()
+
+	if len(blocked) != 1 || blocked[0] != server.URL+"/blocked" {
+		t.Errorf("Expected only /blocked to be reported, got %v", blocked)
+	}
+}
+
+func TestSpider_BlockDetector(t *testing.T) {
+	server := newBotWallServer(t)
+	detector := crawlers.NewBlockDetector(nil)
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, BlockDetector: detector})
+
+	var mu sync.Mutex
+	var crawled []string
+	spider.OnDocument(func(doc *goquery.Document, url string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		crawled = append(crawled, url)
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/page")
+	spider.AddStartURL(server.URL + "/blocked")
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(crawled) != 1 || crawled[0] != server.URL+"/page" {
+		t.Errorf("Expected only /page to be crawled, got %v", crawled)
+	}
+	if detector.Counts()[crawlers.BlockKindCloudflare] != 1 {
+		t.Errorf("Expected one cloudflare block, got %v", detector.Counts())
+	}
+}
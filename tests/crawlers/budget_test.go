@@ -0,0 +1,220 @@
+package crawlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Crawl Budget Tests
+// =============================================================================
+
+// linkServer serves pages /0 through /n-1, each linking to every other page
+func linkServer(t *testing.T, n int, hits *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		var body strings.Builder
+		body.WriteString("<html><body>")
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(&body, `<a href="/%d">page %d</a>`, i, i)
+		}
+		body.WriteString("</body></html>")
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// followLinks queues every link of a spider's documents
+func followLinks(spider *crawlers.Spider, base string) func(*goquery.Document, string) error {
+	return func(doc *goquery.Document, _ string) error {
+		for _, link := range spider.ExtractLinks(doc, "a") {
+			spider.AddStartURL(base + link)
+		}
+		return nil
+	}
+}
+
+func TestSpider_MaxPages(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, 10, &hits)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1, MaxPages: 3})
+	spider.OnDocument(followLinks(spider, server.URL))
+	spider.AddStartURL(server.URL + "/0")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	summary := spider.BudgetSummary()
+	if hits.Load() != 3 || summary.Pages != 3 {
+		t.Errorf("Fetched %d pages (summary %d), want 3", hits.Load(), summary.Pages)
+	}
+	if summary.Exhausted != crawlers.BudgetMaxPages {
+		t.Errorf("Exhausted = %q, want %q", summary.Exhausted, crawlers.BudgetMaxPages)
+	}
+	if summary.Skipped != 7 || len(summary.SkippedURLs) != 7 {
+		t.Errorf("Skipped = %d (%d URLs), want 7", summary.Skipped, len(summary.SkippedURLs))
+	}
+	if summary.Bytes == 0 {
+		t.Error("Bytes = 0, want response bytes counted")
+	}
+}
+
+func TestSpider_MaxPages_LeavesSkippedURLsUnvisited(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, 10, &hits)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1, MaxPages: 3})
+	spider.OnDocument(followLinks(spider, server.URL))
+	spider.AddStartURL(server.URL + "/0")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The URL that hit the budget was never fetched, so it must not be
+	// saved as visited or as an unfinished crawl to resume
+	state := spider.State()
+	if len(state.Visited) != 3 {
+		t.Errorf("Visited = %v, want the 3 fetched pages", state.Visited)
+	}
+	if len(state.Queue) != 0 {
+		t.Errorf("Queue = %v, want empty", state.Queue)
+	}
+}
+
+func TestSpider_MaxBytes(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, 10, &hits)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1, MaxBytes: 1})
+	spider.OnDocument(followLinks(spider, server.URL))
+	spider.AddStartURL(server.URL + "/0")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	summary := spider.BudgetSummary()
+	if hits.Load() != 1 {
+		t.Errorf("Fetched %d pages, want 1", hits.Load())
+	}
+	if summary.Exhausted != crawlers.BudgetMaxBytes {
+		t.Errorf("Exhausted = %q, want %q", summary.Exhausted, crawlers.BudgetMaxBytes)
+	}
+}
+
+func TestSpider_MaxDuration(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, 10, &hits)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		Concurrency: 1,
+		Delay:       30 * time.Millisecond,
+		MaxDuration: 50 * time.Millisecond,
+	})
+	spider.OnDocument(followLinks(spider, server.URL))
+	spider.AddStartURL(server.URL + "/0")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	summary := spider.BudgetSummary()
+	if summary.Exhausted != crawlers.BudgetMaxDuration {
+		t.Errorf("Exhausted = %q, want %q", summary.Exhausted, crawlers.BudgetMaxDuration)
+	}
+	if hits.Load() >= 10 || summary.Skipped == 0 {
+		t.Errorf("Fetched %d pages and skipped %d, want the crawl cut short", hits.Load(), summary.Skipped)
+	}
+}
+
+func TestSpider_NoBudget(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, 5, &hits)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 2})
+	spider.OnDocument(followLinks(spider, server.URL))
+	spider.AddStartURL(server.URL + "/0")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if hits.Load() != 5 {
+		t.Errorf("Fetched %d pages, want 5", hits.Load())
+	}
+	if summary := spider.BudgetSummary(); summary.Exhausted != "" || summary.Pages != 0 {
+		t.Errorf("BudgetSummary() = %+v, want zero without limits", summary)
+	}
+}
+
+func TestCollyClient_MaxPages(t *testing.T) {
+	var hits atomic.Int32
+	server := linkServer(t, 10, &hits)
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{UserAgent: "test", MaxPages: 4})
+	client.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		_ = e.Request.Visit(e.Attr("href")) // Already visited URLs return an error
+	})
+
+	if err := client.Visit(server.URL + "/0"); err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+	client.Wait()
+
+	summary := client.BudgetSummary()
+	if hits.Load() != 4 || summary.Pages != 4 {
+		t.Errorf("Fetched %d pages (summary %d), want 4", hits.Load(), summary.Pages)
+	}
+	if summary.Exhausted != crawlers.BudgetMaxPages || summary.Skipped == 0 {
+		t.Errorf("BudgetSummary() = %+v, want max_pages with skipped URLs", summary)
+	}
+}
+
+func TestSpider_MaxPagesPerDomain(t *testing.T) {
+	var bigHits, smallHits atomic.Int32
+	big := linkServer(t, 10, &bigHits)
+	small := linkServer(t, 3, &smallHits)
+	smallURL := strings.Replace(small.URL, "127.0.0.1", "localhost", 1)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1, MaxPagesPerDomain: 4})
+	spider.OnDocument(func(doc *goquery.Document, url string) error {
+		base := big.URL
+		if strings.HasPrefix(url, smallURL) {
+			base = smallURL
+		}
+		return followLinks(spider, base)(doc, url)
+	})
+	spider.AddStartURL(big.URL + "/0")
+	spider.AddStartURL(smallURL + "/0")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if bigHits.Load() != 4 {
+		t.Errorf("Fetched %d pages of the big site, want 4", bigHits.Load())
+	}
+	if smallHits.Load() != 3 {
+		t.Errorf("Fetched %d pages of the small site, want all 3", smallHits.Load())
+	}
+	summary := spider.BudgetSummary()
+	if summary.Exhausted != "" {
+		t.Errorf("Exhausted = %q, want the crawl to go on", summary.Exhausted)
+	}
+	if len(summary.DomainSkips) != 1 || summary.DomainSkips["127.0.0.1"] == 0 {
+		t.Errorf("DomainSkips = %v, want skips of the big site only", summary.DomainSkips)
+	}
+}
@@ -0,0 +1,144 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Canonical Folding Tests
+// =============================================================================
+
+func TestCanonicalizer_Normalize(t *testing.T) {
+	var c *crawlers.Canonicalizer // Normalization only
+
+	tests := map[string]string{
+		"HTTP://Example.COM":                 "http://example.com/",
+		"http://example.com:80/a#top":        "http://example.com/a",
+		"https://example.com:443/a?b=1":      "https://example.com/a?b=1",
+		"https://example.com:8443/a/":        "https://example.com:8443/a/",
+		"http://[::1]:80/a":                  "http://[::1]/a",
+		"mailto:someone@example.com":         "mailto:someone@example.com",
+		"/relative/path":                     "/relative/path",
+		"https://www.example.com/index.html": "https://www.example.com/index.html",
+	}
+	for in, want := range tests {
+		if got := c.Canonicalize(in); got != want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCanonicalizer_Fold(t *testing.T) {
+	strip, err := crawlers.NewCanonicalizer(crawlers.CanonicalConfig{
+		WWW:           crawlers.FoldStrip,
+		TrailingSlash: crawlers.FoldStrip,
+		IndexPages:    []string{"index.html", "Default.aspx"},
+	})
+	if err != nil {
+		t.Fatalf("NewCanonicalizer() error = %v", err)
+	}
+	add, err := crawlers.NewCanonicalizer(crawlers.CanonicalConfig{
+		WWW:           crawlers.FoldAdd,
+		TrailingSlash: crawlers.FoldAdd,
+		IndexPages:    []string{"index.html"},
+	})
+	if err != nil {
+		t.Fatalf("NewCanonicalizer() error = %v", err)
+	}
+
+	tests := []struct {
+		in, stripped, added string
+	}{
+		{"https://www.example.com/docs/", "https://example.com/docs", "https://www.example.com/docs/"},
+		{"https://example.com/docs", "https://example.com/docs", "https://www.example.com/docs/"},
+		{"https://example.com/docs/index.html", "https://example.com/docs", "https://www.example.com/docs/"},
+		{"https://example.com/docs/DEFAULT.ASPX?x=1", "https://example.com/docs?x=1", "https://www.example.com/docs/DEFAULT.ASPX?x=1"},
+		{"https://example.com/a/page.html", "https://example.com/a/page.html", "https://www.example.com/a/page.html"},
+		{"https://www.example.com/", "https://example.com/", "https://www.example.com/"},
+		{"https://www.co/", "https://www.co/", "https://www.co/"}, // No apex left to fold to
+		{"http://localhost:8080/a/", "http://localhost:8080/a", "http://localhost:8080/a/"},
+		{"http://127.0.0.1/a", "http://127.0.0.1/a", "http://127.0.0.1/a/"},
+	}
+	for _, tt := range tests {
+		if got := strip.Canonicalize(tt.in); got != tt.stripped {
+			t.Errorf("strip: Canonicalize(%q) = %q, want %q", tt.in, got, tt.stripped)
+		}
+		if got := add.Canonicalize(tt.in); got != tt.added {
+			t.Errorf("add: Canonicalize(%q) = %q, want %q", tt.in, got, tt.added)
+		}
+	}
+}
+
+func TestCanonicalizer_Config(t *testing.T) {
+	if _, err := crawlers.NewCanonicalizer(crawlers.CanonicalConfig{WWW: "apex"}); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("NewCanonicalizer() error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+
+	c, err := crawlers.NewCanonicalizerFromConfig(configs.CanonicalConfig{})
+	if err != nil || c != nil {
+		t.Errorf("NewCanonicalizerFromConfig(empty) = %v, %v; want nil, nil", c, err)
+	}
+	c, err = crawlers.NewCanonicalizerFromConfig(configs.CanonicalConfig{TrailingSlash: "strip"})
+	if err != nil || c == nil {
+		t.Errorf("NewCanonicalizerFromConfig() = %v, %v; want a Canonicalizer", c, err)
+	}
+}
+
+func TestCanonicalizer_SpiderDedup(t *testing.T) {
+	var mu sync.Mutex
+	var fetched []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		fetched = append(fetched, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body>
+			<a href="/docs">docs</a><a href="/docs/">docs</a><a href="/docs/index.html">docs</a>
+			<a href="/docs/#intro">docs</a><a href="/about/">about</a></body></html>`))
+	}))
+	defer server.Close()
+
+	canonical, err := crawlers.NewCanonicalizer(crawlers.CanonicalConfig{
+		TrailingSlash: crawlers.FoldStrip,
+		IndexPages:    []string{"index.html"},
+	})
+	if err != nil {
+		t.Fatalf("NewCanonicalizer() error = %v", err)
+	}
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 2, Concurrency: 1, Canonical: canonical})
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		for _, link := range spider.ExtractLinks(doc, "a") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(server.URL)
+	spider.AddStartURL(server.URL + "/#top")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	sort.Strings(fetched)
+	want := []string{"/", "/about", "/docs"}
+	if len(fetched) != len(want) {
+		t.Fatalf("Fetched %v, want %v", fetched, want)
+	}
+	for i := range want {
+		if fetched[i] != want[i] {
+			t.Errorf("Fetched %v, want %v", fetched, want)
+			break
+		}
+	}
+}
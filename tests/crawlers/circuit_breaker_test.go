@@ -0,0 +1,138 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Circuit Breaker Tests
+// =============================================================================
+
+// circuitMetrics counts circuit state changes
+type circuitMetrics struct {
+	mu     sync.Mutex
+	states map[string]int
+}
+
+func (m *circuitMetrics) RecordCircuitState(state string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.states == nil {
+		m.states = make(map[string]int)
+	}
+	m.states[state]++
+}
+
+func TestCircuitBreaker_OpensAndProbes(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	metrics := &circuitMetrics{}
+	breaker := crawlers.NewCircuitBreaker(crawlers.CircuitBreakerConfig{
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		Metrics:          metrics,
+		Clock:            fake,
+	})
+	const url = "https://dead.example.com/page"
+
+	for i := 0; i < 3; i++ {
+		if err := breaker.Allow(url); err != nil {
+			t.Fatalf("Allow() before the threshold failed: %v", err)
+		}
+		breaker.Report(url, true)
+	}
+	if state := breaker.State(url); state != crawlers.CircuitOpen {
+		t.Fatalf("Expected an open circuit after 3 failures, got %s", state)
+	}
+
+	err := breaker.Allow(url)
+	var open *crawlers.CircuitOpenError
+	if !errors.As(err, &open) || open.RetryIn != time.Minute || errs.CodeOf(err) != errs.CodeCircuitOpen {
+		t.Fatalf("Expected a CircuitOpenError retrying in 1m, got %v", err)
+	}
+	if err := breaker.Allow("https://alive.example.com/"); err != nil {
+		t.Errorf("Expected other hosts to stay closed, got %v", err)
+	}
+
+	// Half-open: one probe at a time, and its failure reopens the circuit
+	fake.Advance(time.Minute)
+	if err := breaker.Allow(url); err != nil {
+		t.Fatalf("Expected a probe after OpenTimeout, got %v", err)
+	}
+	if err := breaker.Allow(url); err == nil {
+		t.Fatal("Expected a second request to wait for the probe")
+	}
+	breaker.Report(url, true)
+	if state := breaker.State(url); state != crawlers.CircuitOpen {
+		t.Fatalf("Expected a failed probe to reopen the circuit, got %s", state)
+	}
+
+	// An abandoned probe lets the next one through; its success closes the circuit
+	fake.Advance(time.Minute)
+	if err := breaker.Allow(url); err != nil {
+		t.Fatalf("Expected a probe, got %v", err)
+	}
+	breaker.Abandon(url)
+	if err := breaker.Allow(url); err != nil {
+		t.Fatalf("Expected a new probe after an abandoned one, got %v", err)
+	}
+	breaker.Report(url, false)
+	if state := breaker.State(url); state != crawlers.CircuitClosed {
+		t.Fatalf("Expected a successful probe to close the circuit, got %s", state)
+	}
+
+	want := map[string]int{crawlers.CircuitOpen: 2, crawlers.CircuitHalfOpen: 2, crawlers.CircuitClosed: 1}
+	for state, n := range want {
+		if metrics.states[state] != n {
+			t.Errorf("Expected %d %s transitions, got %v", n, state, metrics.states)
+		}
+	}
+}
+
+func TestCircuitBreaker_SuccessResetsFailures(t *testing.T) {
+	breaker := crawlers.NewCircuitBreaker(crawlers.CircuitBreakerConfig{FailureThreshold: 2})
+	const url = "https://flaky.example.com/"
+
+	for i := 0; i < 5; i++ {
+		breaker.Report(url, true)
+		breaker.Report(url, false)
+	}
+	if err := breaker.Allow(url); err != nil {
+		t.Errorf("Expected alternating failures to keep the circuit closed, got %v", err)
+	}
+}
+
+func TestSoupClient_CircuitBreaker(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{
+		CircuitBreaker: crawlers.NewCircuitBreaker(crawlers.CircuitBreakerConfig{FailureThreshold: 2}),
+	})
+	for i := 0; i < 5; i++ {
+		_, _ = client.GetContext(context.Background(), server.URL+"/")
+	}
+
+	if n := requests.Load(); n != 2 {
+		t.Errorf("Expected the circuit to stop requests after 2 failures, server saw %d", n)
+	}
+	_, err := client.GetContext(context.Background(), server.URL+"/")
+	var open *crawlers.CircuitOpenError
+	if !errors.As(err, &open) {
+		t.Errorf("Expected a CircuitOpenError, got %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/gocolly/colly/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Colly Queue Tests
+// =============================================================================
+
+// concurrencyServer serves /n/<i> pages after delay and records the peak
+// number of requests served at once
+func concurrencyServer(t *testing.T, delay time.Duration) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var current, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := current.Add(1)
+		defer current.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, `<html><body><a href="/child%s">child</a></body></html>`, r.URL.Path)
+	}))
+	t.Cleanup(server.Close)
+	return server, &peak
+}
+
+func TestCollyQueue_BoundedConcurrency(t *testing.T) {
+	server, peak := concurrencyServer(t, 20*time.Millisecond)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{})
+
+	q, err := client.NewQueue(crawlers.CollyQueueConfig{Threads: 3})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+	for i := 0; i < 12; i++ {
+		if err := q.AddURL(fmt.Sprintf("%s/n/%d", server.URL, i)); err != nil {
+			t.Fatalf("AddURL() error = %v", err)
+		}
+	}
+	if stats := q.Stats(); stats.Pending != 12 {
+		t.Errorf("Stats().Pending = %d, want 12", stats.Pending)
+	}
+
+	if err := q.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if p := peak.Load(); p > 3 || p < 2 {
+		t.Errorf("Peak concurrency = %d, want 2-3", p)
+	}
+	if stats := q.Stats(); stats.Done != 12 || stats.Pending != 0 || stats.Active != 0 {
+		t.Errorf("Stats() = %+v, want 12 done", stats)
+	}
+}
+
+func TestCollyQueue_CallbacksQueueLinks(t *testing.T) {
+	server, _ := concurrencyServer(t, 0)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 2})
+	q, err := client.NewQueue(crawlers.CollyQueueConfig{Threads: 2})
+	if err != nil {
+		t.Fatalf("NewQueue() error = %v", err)
+	}
+
+	var mu sync.Mutex
+	var visited []string
+	client.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		r, err := e.Request.New(http.MethodGet, e.Request.AbsoluteURL(e.Attr("href")), nil)
+		if err != nil {
+			t.Errorf("New() error = %v", err)
+			return
+		}
+		r.Depth = e.Request.Depth + 1
+		_ = q.AddRequest(r) // Past MaxDepth the request fails
+	})
+	client.OnResponse(func(r *colly.Response) {
+		mu.Lock()
+		defer mu.Unlock()
+		visited = append(visited, r.Request.URL.Path)
+	})
+
+	_ = q.AddURL(server.URL + "/a")
+	_ = q.AddURL(server.URL + "/b")
+	if err := q.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// Depth 1 pages and their children; grandchildren exceed MaxDepth
+	if len(visited) != 4 {
+		t.Errorf("Visited %v, want /a, /b and their children", visited)
+	}
+	if stats := q.Stats(); stats.Failed != 2 {
+		t.Errorf("Stats().Failed = %d, want the 2 requests past MaxDepth", stats.Failed)
+	}
+}
+
+func TestCollyQueue_Cancel(t *testing.T) {
+	server, _ := concurrencyServer(t, 5*time.Second)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{})
+	q, _ := client.NewQueue(crawlers.CollyQueueConfig{Threads: 2})
+	for i := 0; i < 5; i++ {
+		_ = q.AddURL(fmt.Sprintf("%s/n/%d", server.URL, i))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := q.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v after cancellation", elapsed)
+	}
+	if stats := q.Stats(); stats.Pending != 3 || stats.Failed != 2 {
+		t.Errorf("Stats() = %+v, want 3 pending and 2 aborted", stats)
+	}
+}
+
+func TestCollyQueue_Stop(t *testing.T) {
+	server, _ := concurrencyServer(t, 50*time.Millisecond)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{})
+	q, _ := client.NewQueue(crawlers.CollyQueueConfig{Threads: 1})
+	for i := 0; i < 5; i++ {
+		_ = q.AddURL(fmt.Sprintf("%s/n/%d", server.URL, i))
+	}
+	client.OnResponse(func(*colly.Response) { q.Stop() })
+
+	if err := q.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stats := q.Stats(); stats.Done != 1 || stats.Pending != 4 {
+		t.Errorf("Stats() = %+v, want 1 done and 4 pending", stats)
+	}
+}
+
+func TestCollyQueue_Errors(t *testing.T) {
+	async := crawlers.NewCollyClient(crawlers.CollyConfig{Async: true})
+	if _, err := async.NewQueue(crawlers.CollyQueueConfig{}); errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("NewQueue() on an async client error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+
+	q, _ := crawlers.NewCollyClient(crawlers.CollyConfig{}).NewQueue(crawlers.CollyQueueConfig{MaxSize: 1})
+	if err := q.AddURL("not a url"); errs.CodeOf(err) != errs.CodeInvalidURL {
+		t.Errorf("AddURL() error = %v, want %s", err, errs.CodeInvalidURL)
+	}
+	_ = q.AddURL("https://example.com/1")
+	if err := q.AddURL("https://example.com/2"); errs.CodeOf(err) != errs.CodeQueueFull {
+		t.Errorf("AddURL() on a full queue error = %v, want %s", err, errs.CodeQueueFull)
+	}
+}
+
+func TestRedisQueueStorage(t *testing.T) {
+	if err := pingRedis(); err != nil {
+		t.Skipf("Skipping Redis queue storage tests: %v", err)
+	}
+	redisClient := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+	defer redisClient.Close()
+
+	storage, err := crawlers.NewRedisQueueStorage(crawlers.RedisQueueStorageConfig{Client: redisClient, Name: t.Name()})
+	if err != nil {
+		t.Fatalf("NewRedisQueueStorage() error = %v", err)
+	}
+	_ = storage.Reset(context.Background())
+	defer storage.Reset(context.Background())
+
+	server, _ := concurrencyServer(t, 0)
+	first, _ := crawlers.NewCollyClient(crawlers.CollyConfig{}).NewQueue(crawlers.CollyQueueConfig{Storage: storage})
+	for i := 0; i < 3; i++ {
+		_ = first.AddURL(fmt.Sprintf("%s/n/%d", server.URL, i))
+	}
+
+	// Another process picks up the backlog
+	second, _ := crawlers.NewCollyClient(crawlers.CollyConfig{}).NewQueue(crawlers.CollyQueueConfig{Storage: storage})
+	if err := second.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if stats := second.Stats(); stats.Done != 3 || stats.Pending != 0 {
+		t.Errorf("Stats() = %+v, want 3 done", stats)
+	}
+}
+
+func TestNewRedisQueueStorage_RequiresClient(t *testing.T) {
+	if _, err := crawlers.NewRedisQueueStorage(crawlers.RedisQueueStorageConfig{}); errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("NewRedisQueueStorage() error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+}
@@ -0,0 +1,272 @@
+package crawlers_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Content Type Filter Tests
+// =============================================================================
+
+func TestContentTypeFilter_Allowed(t *testing.T) {
+	filter := crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{})
+
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"text/html", true},
+		{"text/html; charset=utf-8", true},
+		{"TEXT/HTML", true},
+		{"application/xhtml+xml", true},
+		{"", true}, // Sniffed by the caller
+		{"application/pdf", false},
+		{"image/png", false},
+		{"application/octet-stream", false},
+		{"text/plain", false},
+	}
+	for _, tt := range tests {
+		if got := filter.Allowed(tt.contentType); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.contentType, got, tt.want)
+		}
+	}
+
+	var disabled *crawlers.ContentTypeFilter
+	if !disabled.Allowed("application/pdf") || disabled.HeadRequests() {
+		t.Error("Expected a nil filter to allow everything without HEAD requests")
+	}
+}
+
+func TestContentTypeFilter_Wildcards(t *testing.T) {
+	filter := crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{
+		Allow: []string{"text/*", "application/json"},
+	})
+
+	for contentType, want := range map[string]bool{
+		"text/html":        true,
+		"text/plain":       true,
+		"application/json": true,
+		"application/pdf":  false,
+		"textual/html":     false,
+	} {
+		if got := filter.Allowed(contentType); got != want {
+			t.Errorf("Allowed(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestNewContentTypeFilterFromConfig(t *testing.T) {
+	if crawlers.NewContentTypeFilterFromConfig(configs.ContentTypeConfig{}) != nil {
+		t.Error("Expected no filter when disabled")
+	}
+
+	filter := crawlers.NewContentTypeFilterFromConfig(configs.ContentTypeConfig{
+		Enabled:      true,
+		Allow:        []string{"image/*"},
+		HeadRequests: true,
+	})
+	if filter == nil || !filter.Allowed("image/png") || filter.Allowed("text/html") || !filter.HeadRequests() {
+		t.Error("Expected the filter to follow the config")
+	}
+}
+
+// =============================================================================
+// Crawler Integration Tests
+// =============================================================================
+
+// pdfBody is a minimal binary body served without and with a Content-Type
+var pdfBody = []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n1 0 obj <<>> endobj\ntrailer <<>>\n%%EOF")
+
+// newMixedSite serves an HTML page linking to a PDF, a PDF without a
+// Content-Type header, and an image. It counts GET and HEAD requests per path
+func newMixedSite(t *testing.T) (*httptest.Server, *sync.Map) {
+	t.Helper()
+	var requests sync.Map // "METHOD path" -> *atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counter, _ := requests.LoadOrStore(r.Method+" "+r.URL.Path, new(atomic.Int32))
+		counter.(*atomic.Int32).Add(1)
+
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = io.WriteString(w, `<html><head><title>Home</title></head><body>
+<a href="/report.pdf">PDF</a><a href="/untyped">Untyped</a><a href="/logo.png">Logo</a>
+</body></html>`)
+		case "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write(pdfBody)
+		case "/untyped":
+			w.Header()["Content-Type"] = nil // Suppress Go's own sniffing
+			_, _ = w.Write(pdfBody)
+		case "/logo.png":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+// requestCount returns how often method was used on path
+func requestCount(requests *sync.Map, method, path string) int32 {
+	counter, ok := requests.Load(method + " " + path)
+	if !ok {
+		return 0
+	}
+	return counter.(*atomic.Int32).Load()
+}
+
+func TestSpider_ContentTypes_SkipsBinary(t *testing.T) {
+	server, _ := newMixedSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:     1,
+		ContentTypes: crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{}),
+	})
+	var mu sync.Mutex
+	var parsed []string
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		mu.Lock()
+		parsed = append(parsed, crawl.URL)
+		mu.Unlock()
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(parsed) != 1 || parsed[0] != server.URL+"/" {
+		t.Errorf("Parsed %v, want only the HTML page", parsed)
+	}
+	if got := spider.GetVisitedCount(); got != 4 {
+		t.Errorf("GetVisitedCount() = %d, want skipped URLs to count as visited", got)
+	}
+}
+
+func TestSpider_ContentTypes_OnContent(t *testing.T) {
+	server, _ := newMixedSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		ContentTypes: crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{}),
+	})
+	spider.OnDocument(func(*goquery.Document, string) error { return nil })
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	spider.OnContent(func(resp *http.Response, crawl crawlers.CrawlContext) error {
+		data, err := io.ReadAll(resp.Body)
+		mu.Lock()
+		bodies[crawl.URL] = string(data)
+		mu.Unlock()
+		return err
+	})
+	spider.AddStartURL(server.URL + "/report.pdf")
+	spider.AddStartURL(server.URL + "/untyped")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	for _, path := range []string{"/report.pdf", "/untyped"} {
+		if bodies[server.URL+path] != string(pdfBody) {
+			t.Errorf("OnContent(%s) body = %q, want the full PDF", path, bodies[server.URL+path])
+		}
+	}
+}
+
+func TestSpider_ContentTypes_HeadRequests(t *testing.T) {
+	server, requests := newMixedSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		ContentTypes: crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{HeadRequests: true}),
+	})
+	var parsed atomic.Int32
+	spider.OnDocument(func(*goquery.Document, string) error {
+		parsed.Add(1)
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/")
+	spider.AddStartURL(server.URL + "/report.pdf")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if parsed.Load() != 1 {
+		t.Errorf("Parsed %d pages, want 1", parsed.Load())
+	}
+	if got := requestCount(requests, http.MethodGet, "/report.pdf"); got != 0 {
+		t.Errorf("PDF downloaded %d times, want a HEAD request only", got)
+	}
+	if got := requestCount(requests, http.MethodHead, "/report.pdf"); got != 1 {
+		t.Errorf("HEAD /report.pdf sent %d times, want 1", got)
+	}
+}
+
+func TestSoupClient_ContentTypes(t *testing.T) {
+	server, requests := newMixedSite(t)
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{
+		ContentTypes: crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{}),
+	})
+
+	if _, err := client.Get(server.URL + "/"); err != nil {
+		t.Fatalf("Get(/) error = %v", err)
+	}
+	for _, path := range []string{"/report.pdf", "/untyped", "/logo.png"} {
+		_, err := client.Get(server.URL + path)
+		var skipped *crawlers.SkippedContentError
+		if !errors.As(err, &skipped) || errs.CodeOf(err) != errs.CodeSkippedContent {
+			t.Errorf("Get(%s) error = %v, want SkippedContentError", path, err)
+		}
+	}
+
+	head := crawlers.NewSoupClient(crawlers.SoupConfig{
+		ContentTypes: crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{HeadRequests: true}),
+	})
+	if _, err := head.Get(server.URL + "/logo.png"); errs.CodeOf(err) != errs.CodeSkippedContent {
+		t.Errorf("Get(/logo.png) error = %v, want %s", err, errs.CodeSkippedContent)
+	}
+	if got := requestCount(requests, http.MethodGet, "/logo.png"); got != 1 {
+		t.Errorf("GET /logo.png sent %d times, want only the unfiltered client's", got)
+	}
+}
+
+func TestCollyClient_ContentTypes(t *testing.T) {
+	server, _ := newMixedSite(t)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{
+		ContentTypes: crawlers.NewContentTypeFilter(crawlers.ContentTypeFilterConfig{}),
+	})
+
+	var responses atomic.Int32
+	client.OnResponse(func(*colly.Response) { responses.Add(1) })
+
+	err := client.VisitContext(t.Context(), server.URL+"/report.pdf")
+	var skipped *crawlers.SkippedContentError
+	if !errors.As(err, &skipped) || skipped.ContentType != "application/pdf" {
+		t.Errorf("VisitContext(/report.pdf) error = %v, want SkippedContentError", err)
+	}
+	if err := client.VisitContext(t.Context(), server.URL+"/"); err != nil {
+		t.Errorf("VisitContext(/) error = %v", err)
+	}
+	if responses.Load() != 1 {
+		t.Errorf("Got %d responses, want only the HTML page", responses.Load())
+	}
+}
@@ -0,0 +1,203 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+)
+
+// =============================================================================
+// Cookie Jar Tests
+// =============================================================================
+
+// sessionServer sets a session cookie on /login, clears it on /logout and
+// records the session cookie sent to other paths
+func sessionServer(t *testing.T) (*httptest.Server, func() string) {
+	t.Helper()
+	var mu sync.Mutex
+	var lastSession string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cret", Path: "/", MaxAge: 3600})
+		case "/logout":
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "", Path: "/", MaxAge: -1})
+		default:
+			mu.Lock()
+			lastSession = ""
+			if c, err := r.Cookie("session"); err == nil {
+				lastSession = c.Value
+			}
+			mu.Unlock()
+		}
+		_, _ = w.Write([]byte(`<html></html>`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastSession
+	}
+}
+
+func TestCollyClient_KeepsSessionCookies(t *testing.T) {
+	server, session := sessionServer(t)
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{})
+	if err := client.Visit(server.URL + "/login"); err != nil {
+		t.Fatalf("Visit(/login) error = %v", err)
+	}
+	if err := client.Visit(server.URL + "/account"); err != nil {
+		t.Fatalf("Visit(/account) error = %v", err)
+	}
+	if got := session(); got != "s3cret" {
+		t.Errorf("session cookie = %q, want s3cret", got)
+	}
+
+	// Clones share the jar
+	clone := client.Clone()
+	if err := clone.Visit(server.URL + "/orders"); err != nil {
+		t.Fatalf("Visit(/orders) error = %v", err)
+	}
+	if got := session(); got != "s3cret" {
+		t.Errorf("session cookie from clone = %q, want s3cret", got)
+	}
+}
+
+func TestCookieJar_PersistsAcrossClients(t *testing.T) {
+	server, session := sessionServer(t)
+	store := crawlers.NewFileCookieStore(filepath.Join(t.TempDir(), "cookies.json"))
+
+	jar, err := crawlers.NewCookieJar(store)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	first := crawlers.NewCollyClient(crawlers.CollyConfig{Cookies: jar})
+	if err := first.Visit(server.URL + "/login"); err != nil {
+		t.Fatalf("Visit(/login) error = %v", err)
+	}
+
+	// A new process loads the saved session
+	reloaded, err := crawlers.NewCookieJar(store)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	if reloaded.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", reloaded.Len())
+	}
+	second := crawlers.NewCollyClient(crawlers.CollyConfig{Cookies: reloaded})
+	if err := second.Visit(server.URL + "/account"); err != nil {
+		t.Fatalf("Visit(/account) error = %v", err)
+	}
+	if got := session(); got != "s3cret" {
+		t.Errorf("session cookie = %q, want s3cret", got)
+	}
+
+	// Deleting the cookie is persisted too
+	if err := second.Visit(server.URL + "/logout"); err != nil {
+		t.Fatalf("Visit(/logout) error = %v", err)
+	}
+	afterLogout, err := crawlers.NewCookieJar(store)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	if afterLogout.Len() != 0 {
+		t.Errorf("Len() after logout = %d, want 0", afterLogout.Len())
+	}
+}
+
+func TestCookieJar_DomainAndPathMatching(t *testing.T) {
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+
+	u, _ := url.Parse("https://shop.example.com/account/login")
+	jar.SetCookies(u, []*http.Cookie{
+		{Name: "wide", Value: "1", Domain: "example.com", Path: "/"},
+		{Name: "account", Value: "2"}, // host-only, default path /account
+	})
+
+	tests := []struct {
+		url  string
+		want []string
+	}{
+		{"https://shop.example.com/account/orders", []string{"account", "wide"}},
+		{"https://shop.example.com/cart", []string{"wide"}},
+		{"https://www.example.com/", []string{"wide"}},
+		{"https://example.org/", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			target, _ := url.Parse(tt.url)
+			got := map[string]bool{}
+			for _, c := range jar.Cookies(target) {
+				got[c.Name] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Cookies(%s) = %v, want %v", tt.url, got, tt.want)
+			}
+			for _, name := range tt.want {
+				if !got[name] {
+					t.Errorf("Cookies(%s) missing %s", tt.url, name)
+				}
+			}
+		})
+	}
+}
+
+func TestCookieJar_DefaultCookies(t *testing.T) {
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	jar.SetDefaultCookies([]*http.Cookie{{Name: "consent", Value: "yes"}, {Name: "session", Value: "default"}})
+
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "session", Value: "site"}})
+
+	got := map[string]string{}
+	for _, c := range jar.Cookies(u) {
+		got[c.Name] = c.Value
+	}
+	if got["consent"] != "yes" || got["session"] != "site" {
+		t.Errorf("Cookies() = %v, want consent=yes and the site's session", got)
+	}
+	if jar.Len() != 1 {
+		t.Errorf("Len() = %d, want 1; defaults are not stored", jar.Len())
+	}
+}
+
+func TestCacheCookieStore(t *testing.T) {
+	mockCache := &mocks.MockCacheClient{}
+	store := crawlers.NewCacheCookieStore(mockCache, "cookies:shop", time.Hour)
+
+	jar, err := crawlers.NewCookieJar(store)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	if jar.Len() != 0 {
+		t.Fatalf("Len() of empty store = %d, want 0", jar.Len())
+	}
+
+	u, _ := url.Parse("https://example.com/")
+	jar.SetCookies(u, []*http.Cookie{{Name: "token", Value: "abc", Expires: time.Now().Add(time.Hour)}})
+
+	reloaded, err := crawlers.NewCookieJar(store)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	cookies := reloaded.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Value != "abc" {
+		t.Errorf("Cookies() = %v, want token=abc", cookies)
+	}
+}
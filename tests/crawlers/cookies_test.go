@@ -0,0 +1,213 @@
+package crawlers_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Cookie Transfer Tests
+// =============================================================================
+
+func TestCookieJar_ExportImport(t *testing.T) {
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	expires := time.Now().Add(time.Hour).Truncate(time.Second)
+	jar.SetCookies(mustParseURL(t, "https://shop.example.com/account/orders"), []*http.Cookie{
+		{Name: "session", Value: "s3cret", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode},
+		{Name: "region", Value: "eu", Domain: "example.com", Path: "/", Expires: expires},
+	})
+
+	cookies, err := jar.ExportCookies()
+	if err != nil {
+		t.Fatalf("ExportCookies() error = %v", err)
+	}
+	byName := make(map[string]crawlers.Cookie)
+	for _, c := range cookies {
+		byName[c.Name] = c
+	}
+	session, region := byName["session"], byName["region"]
+	if len(cookies) != 2 || session.Domain != "shop.example.com" || region.Domain != ".example.com" {
+		t.Fatalf("Unexpected exported cookies: %+v", cookies)
+	}
+	if session.Path != "/account" || !session.Secure || !session.HttpOnly || session.SameSite != "Lax" || !session.Expires.IsZero() {
+		t.Errorf("Unexpected session cookie: %+v", session)
+	}
+	if !region.Expires.Equal(expires) {
+		t.Errorf("Expected region to expire at %v, got %v", expires, region.Expires)
+	}
+
+	imported, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	if err := imported.ImportCookies(cookies); err != nil {
+		t.Fatalf("ImportCookies() error = %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want map[string]string
+	}{
+		{"https://shop.example.com/account/orders", map[string]string{"session": "s3cret", "region": "eu"}},
+		{"http://shop.example.com/account/orders", map[string]string{"region": "eu"}}, // Secure
+		{"https://shop.example.com/", map[string]string{"region": "eu"}},              // Path
+		{"https://blog.example.com/account/", map[string]string{"region": "eu"}},      // Host-only
+	}
+	for _, tt := range tests {
+		got := make(map[string]string)
+		for _, c := range imported.Cookies(mustParseURL(t, tt.url)) {
+			got[c.Name] = c.Value
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Cookies(%s) = %v, want %v", tt.url, got, tt.want)
+			continue
+		}
+		for name, value := range tt.want {
+			if got[name] != value {
+				t.Errorf("Cookies(%s) = %v, want %v", tt.url, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestCookieJar_ImportSkipsExpired(t *testing.T) {
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	err = jar.ImportCookies([]crawlers.Cookie{
+		{Name: "old", Value: "1", Domain: "example.com", Expires: time.Now().Add(-time.Minute)},
+		{Name: "new", Value: "2", Domain: "example.com"},
+	})
+	if err != nil {
+		t.Fatalf("ImportCookies() error = %v", err)
+	}
+	if cookies := jar.Cookies(mustParseURL(t, "http://example.com/")); len(cookies) != 1 || cookies[0].Name != "new" {
+		t.Errorf("Expected only the unexpired cookie, got %v", cookies)
+	}
+
+	if err := jar.ImportCookies([]crawlers.Cookie{{Name: "bad"}}); err == nil {
+		t.Error("Expected an error for a cookie without a domain")
+	}
+}
+
+func TestCopyCookies_CollyToSoup(t *testing.T) {
+	server, session := sessionServer(t)
+
+	collyClient := crawlers.NewCollyClient(crawlers.CollyConfig{})
+	if err := collyClient.Visit(server.URL + "/login"); err != nil {
+		t.Fatalf("Visit(/login) error = %v", err)
+	}
+
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	soup := crawlers.NewSoupClient(crawlers.SoupConfig{Cookies: jar})
+	if err := crawlers.CopyCookies(soup, collyClient); err != nil {
+		t.Fatalf("CopyCookies() error = %v", err)
+	}
+
+	if _, err := soup.Get(server.URL + "/account"); err != nil {
+		t.Fatalf("Get(/account) error = %v", err)
+	}
+	if got := session(); got != "s3cret" {
+		t.Errorf("Expected the Colly session to be sent by Soup, got %q", got)
+	}
+}
+
+func TestSoupClient_KeepsSessionCookies(t *testing.T) {
+	server, session := sessionServer(t)
+
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	soup := crawlers.NewSoupClient(crawlers.SoupConfig{Cookies: jar})
+	if _, err := soup.Get(server.URL + "/login"); err != nil {
+		t.Fatalf("Get(/login) error = %v", err)
+	}
+	if _, err := soup.Get(server.URL + "/account"); err != nil {
+		t.Fatalf("Get(/account) error = %v", err)
+	}
+	if got := session(); got != "s3cret" {
+		t.Errorf("Expected the session cookie to be sent back, got %q", got)
+	}
+
+	cookies, err := soup.ExportCookies()
+	if err != nil {
+		t.Fatalf("ExportCookies() error = %v", err)
+	}
+	if len(cookies) != 1 || cookies[0].Value != "s3cret" {
+		t.Errorf("Unexpected exported cookies: %+v", cookies)
+	}
+}
+
+func TestSoupClient_NoCookieJar(t *testing.T) {
+	soup := crawlers.NewDefaultSoupClient()
+	if _, err := soup.ExportCookies(); !errors.Is(err, colly.ErrNoCookieJar) {
+		t.Errorf("Expected ErrNoCookieJar, got %v", err)
+	}
+	if err := soup.ImportCookies(nil); !errors.Is(err, colly.ErrNoCookieJar) {
+		t.Errorf("Expected ErrNoCookieJar, got %v", err)
+	}
+}
+
+func TestPlaywrightClient_ExportCookies(t *testing.T) {
+	server, session := sessionServer(t)
+
+	browser, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Skipf("Skipping Playwright cookie tests: browser not available (%v)", err)
+	}
+	defer browser.Close()
+
+	// Log in with the browser, then reuse the session over plain HTTP
+	if err := browser.Navigate(server.URL + "/login"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	jar, err := crawlers.NewCookieJar(nil)
+	if err != nil {
+		t.Fatalf("NewCookieJar() error = %v", err)
+	}
+	soup := crawlers.NewSoupClient(crawlers.SoupConfig{Cookies: jar})
+	if err := crawlers.CopyCookies(soup, browser); err != nil {
+		t.Fatalf("CopyCookies() error = %v", err)
+	}
+	if _, err := soup.Get(server.URL + "/account"); err != nil {
+		t.Fatalf("Get(/account) error = %v", err)
+	}
+	if got := session(); got != "s3cret" {
+		t.Errorf("Expected the browser session to be sent by Soup, got %q", got)
+	}
+
+	// Imported cookies are exported along with the browser's own
+	if err := browser.ImportCookies([]crawlers.Cookie{{Name: "theme", Value: "dark", Domain: mustParseURL(t, server.URL).Hostname()}}); err != nil {
+		t.Fatalf("ImportCookies() error = %v", err)
+	}
+	cookies, err := browser.ExportCookies()
+	if err != nil {
+		t.Fatalf("ExportCookies() error = %v", err)
+	}
+	if len(cookies) != 2 {
+		t.Errorf("Expected the session and theme cookies, got %+v", cookies)
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("url.Parse(%q) error = %v", raw, err)
+	}
+	return u
+}
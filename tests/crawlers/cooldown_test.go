@@ -0,0 +1,304 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+)
+
+// =============================================================================
+// Domain Cooldown Tests
+// =============================================================================
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{name: "empty", value: "", wantOK: false},
+		{name: "seconds", value: "120", want: 2 * time.Minute, wantOK: true},
+		{name: "negative seconds", value: "-5", wantOK: false},
+		{name: "http date", value: "Wed, 01 Jan 2025 12:00:30 GMT", want: 30 * time.Second, wantOK: true},
+		{name: "http date in past", value: "Wed, 01 Jan 2025 11:00:00 GMT", want: 0, wantOK: true},
+		{name: "garbage", value: "soon", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := crawlers.ParseRetryAfter(tt.value, now)
+			if ok != tt.wantOK {
+				t.Fatalf("ParseRetryAfter(%q) ok = %v, want %v", tt.value, ok, tt.wantOK)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDomainCooldown_HandleResponse(t *testing.T) {
+	cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{
+		BaseDelay: time.Second,
+		MaxDelay:  3 * time.Second,
+	})
+
+	if _, throttled := cooldown.HandleResponse("https://example.com/a", http.StatusOK, http.Header{}); throttled {
+		t.Error("200 response should not throttle")
+	}
+
+	// Exponential backoff without Retry-After, capped at MaxDelay
+	wantDelays := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}
+	for i, want := range wantDelays {
+		delay, throttled := cooldown.HandleResponse("https://example.com/a", http.StatusTooManyRequests, http.Header{})
+		if !throttled {
+			t.Fatalf("strike %d: expected throttling", i+1)
+		}
+		if delay != want {
+			t.Errorf("strike %d: delay = %v, want %v", i+1, delay, want)
+		}
+	}
+
+	if cooldown.Remaining("https://example.com/other") <= 0 {
+		t.Error("cooldown should apply to the whole domain")
+	}
+	if cooldown.Remaining("https://example.org/") != 0 {
+		t.Error("cooldown should not leak to other domains")
+	}
+}
+
+func TestDomainCooldown_RetryAfterHeader(t *testing.T) {
+	cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{MaxDelay: time.Hour})
+
+	header := http.Header{}
+	header.Set("Retry-After", "42")
+
+	delay, throttled := cooldown.HandleResponse("https://example.com/", http.StatusServiceUnavailable, header)
+	if !throttled {
+		t.Fatal("503 response should throttle")
+	}
+	if delay != 42*time.Second {
+		t.Errorf("delay = %v, want 42s", delay)
+	}
+}
+
+func TestDomainCooldown_WaitHonorsContext(t *testing.T) {
+	cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{BaseDelay: time.Minute})
+	cooldown.HandleResponse("https://example.com/", http.StatusTooManyRequests, http.Header{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := cooldown.Wait(ctx, "https://example.com/"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestDomainCooldown_Clock(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{BaseDelay: time.Minute, Clock: fake})
+	cooldown.HandleResponse("https://example.com/", http.StatusServiceUnavailable, http.Header{})
+
+	done := make(chan error, 1)
+	go func() { done <- cooldown.Wait(context.Background(), "https://example.com/a") }()
+
+	fake.BlockUntil(1)
+	fake.Advance(30 * time.Second)
+	if got := cooldown.Remaining("https://example.com/"); got != 30*time.Second {
+		t.Errorf("Remaining() = %v, want 30s", got)
+	}
+	select {
+	case <-done:
+		t.Fatal("Wait() returned during the cooldown")
+	default:
+	}
+
+	fake.Advance(30 * time.Second)
+	if err := <-done; err != nil {
+		t.Errorf("Wait() error = %v", err)
+	}
+	if got := cooldown.Remaining("https://example.com/"); got != 0 {
+		t.Errorf("Remaining() = %v after cooldown, want 0", got)
+	}
+}
+
+func TestCacheCooldownStore(t *testing.T) {
+	mockCache := &mocks.MockCacheClient{}
+	store := crawlers.NewCacheCooldownStore(mockCache)
+
+	until := time.Now().Add(time.Minute).Truncate(time.Millisecond)
+	if err := store.SetCooldown("example.com", until); err != nil {
+		t.Fatalf("SetCooldown() error = %v", err)
+	}
+
+	got, err := store.GetCooldown("example.com")
+	if err != nil {
+		t.Fatalf("GetCooldown() error = %v", err)
+	}
+	if !got.Equal(until) {
+		t.Errorf("GetCooldown() = %v, want %v", got, until)
+	}
+
+	missing, err := store.GetCooldown("example.org")
+	if err != nil || !missing.IsZero() {
+		t.Errorf("GetCooldown() for unknown domain = %v, %v; want zero time", missing, err)
+	}
+}
+
+func TestSpider_Run_RequeuesThrottledURL(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		Concurrency: 1,
+		Cooldown:    crawlers.NewDomainCooldown(crawlers.CooldownConfig{}),
+	})
+
+	var documents, retries int32
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		atomic.AddInt32(&documents, 1)
+		atomic.StoreInt32(&retries, int32(crawl.Retries))
+		return nil
+	})
+	spider.AddStartURL(server.URL)
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if atomic.LoadInt32(&documents) != 1 {
+		t.Errorf("expected throttled URL to be retried once, got %d documents", documents)
+	}
+	if atomic.LoadInt32(&retries) != 1 {
+		t.Errorf("CrawlContext.Retries = %d, want 1", retries)
+	}
+}
+
+func TestSpider_Run_SkipsURLThrottledPastMaxRetries(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		Concurrency: 1,
+		MaxRetries:  2,
+		Cooldown:    crawlers.NewDomainCooldown(crawlers.CooldownConfig{}),
+	})
+	spider.AddStartURL(server.URL)
+
+	done := make(chan error, 1)
+	go func() { done <- spider.Run() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected Run to give up on a URL that is always throttled")
+	}
+
+	if got := atomic.LoadInt32(&hits); got != 3 {
+		t.Errorf("Server got %d requests, want the first one and 2 retries", got)
+	}
+	summary := spider.SkipSummary()
+	if summary.ByReason[crawlers.SkipThrottled] != 1 {
+		t.Errorf("Expected a throttled skip, got %+v", summary)
+	}
+}
+
+func TestNewDomainCooldownFromConfig(t *testing.T) {
+	if cooldown := crawlers.NewDomainCooldownFromConfig(configs.CooldownConfig{}, nil, nil); cooldown != nil {
+		t.Error("Expected nil when disabled")
+	}
+
+	store := crawlers.NewMemoryCooldownStore()
+	metrics := &throttleMetrics{}
+	cooldown := crawlers.NewDomainCooldownFromConfig(configs.CooldownConfig{Enabled: true, BaseDelay: 7}, store, metrics)
+	delay, throttled := cooldown.HandleResponse("https://example.com/", http.StatusTooManyRequests, http.Header{})
+	if !throttled || delay != 7*time.Second {
+		t.Fatalf("HandleResponse() = %s, %v; want the configured base delay", delay, throttled)
+	}
+	if until, _ := store.GetCooldown("example.com"); until.IsZero() {
+		t.Error("Expected the cooldown to be written to the given store")
+	}
+	if metrics.actions[crawlers.ThrottleActionPaused] != 1 {
+		t.Errorf("Expected the cooldown to be recorded to the given metrics, got %v", metrics.actions)
+	}
+}
+
+func TestDomainCooldown_KeysByRegistrableDomain(t *testing.T) {
+	metrics := &throttleMetrics{}
+	cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{BaseDelay: time.Minute, Metrics: metrics})
+
+	cooldown.HandleResponse("https://www.example.com/a", http.StatusTooManyRequests, http.Header{})
+
+	// Same key as the rate limiter: every host of the registrable domain cools down
+	if cooldown.Remaining("https://api.example.com/") <= 0 {
+		t.Error("Expected sibling hosts of the domain to cool down")
+	}
+	if cooldown.Remaining("https://example.org/") != 0 {
+		t.Error("Expected other domains not to cool down")
+	}
+	if metrics.actions[crawlers.ThrottleActionPaused] != 1 {
+		t.Errorf("Expected one pause event, got %v", metrics.actions)
+	}
+
+	var none *crawlers.DomainCooldown
+	if _, throttled := none.HandleResponse("https://example.com/", http.StatusTooManyRequests, http.Header{}); throttled {
+		t.Error("Expected a nil cooldown to ignore responses")
+	}
+}
+
+func TestSoupClient_ThrottleBacksOffOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{
+		Adaptive: &crawlers.AdaptiveConfig{MinDelay: time.Second, MaxDelay: time.Minute},
+	})
+	cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{Clock: clock.NewFake(time.Now())})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{RateLimiter: limiter, Cooldown: cooldown})
+
+	_, err := client.Get(server.URL)
+	var throttled *crawlers.ThrottledError
+	if !errors.As(err, &throttled) {
+		t.Fatalf("Expected a ThrottledError, got %v", err)
+	}
+	// The limiter slows the domain to 1s while the cooldown holds it for Retry-After
+	if throttled.Delay != 3*time.Second {
+		t.Errorf("Delay = %s, want the 3s Retry-After window", throttled.Delay)
+	}
+	if got := limiter.Delay(server.URL); got != time.Second {
+		t.Errorf("Limiter delay = %s, want 1s", got)
+	}
+	if got := cooldown.Remaining(server.URL); got != 3*time.Second {
+		t.Errorf("Remaining() = %s, want 3s", got)
+	}
+}
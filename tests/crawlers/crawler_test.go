@@ -0,0 +1,152 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Crawler Interface Tests
+// =============================================================================
+
+// newCrawlerServer serves a page at /, a redirect to it at /old and a 404
+// at /missing
+func newCrawlerServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("X-Page", "home")
+		_, _ = w.Write([]byte(`<html><head><title>Home</title></head><body><a href="/next">next</a></body></html>`))
+	})
+	mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusNotFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// httpCrawlers returns the adapters of the engines that need no browser
+func httpCrawlers() []crawlers.Crawler {
+	return []crawlers.Crawler{
+		crawlers.NewCollyCrawler(crawlers.NewDefaultCollyClient()),
+		crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient()),
+		crawlers.NewSpiderCrawler(crawlers.NewDefaultSpider()),
+	}
+}
+
+func TestCrawler_Fetch(t *testing.T) {
+	server := newCrawlerServer(t)
+
+	for _, c := range httpCrawlers() {
+		t.Run(c.Engine(), func(t *testing.T) {
+			defer c.Close()
+
+			var results []*crawlers.Result
+			c.OnResult(func(r *crawlers.Result) {
+				results = append(results, r)
+			})
+
+			result, err := c.Fetch(context.Background(), server.URL+"/old")
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if result.URL != server.URL+"/" {
+				t.Errorf("Expected final URL %s/, got %s", server.URL, result.URL)
+			}
+			if result.StatusCode != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", result.StatusCode)
+			}
+			if result.Header.Get("X-Page") != "home" {
+				t.Errorf("Expected X-Page header, got %v", result.Header)
+			}
+			if result.Engine != c.Engine() {
+				t.Errorf("Expected engine %s, got %s", c.Engine(), result.Engine)
+			}
+			doc, err := result.Document()
+			if err != nil {
+				t.Fatalf("Document failed: %v", err)
+			}
+			if title := doc.Find("title").Text(); title != "Home" {
+				t.Errorf("Expected title Home, got %q", title)
+			}
+			if len(results) != 1 || results[0] != result {
+				t.Errorf("Expected OnResult to get the result once, got %d calls", len(results))
+			}
+
+			// The same URL can be fetched again
+			if _, err := c.Fetch(context.Background(), server.URL+"/"); err != nil {
+				t.Fatalf("Second fetch failed: %v", err)
+			}
+			if len(results) != 2 {
+				t.Errorf("Expected 2 results, got %d", len(results))
+			}
+		})
+	}
+}
+
+func TestCrawler_ErrorStatusIsResult(t *testing.T) {
+	server := newCrawlerServer(t)
+
+	for _, c := range httpCrawlers() {
+		t.Run(c.Engine(), func(t *testing.T) {
+			result, err := c.Fetch(context.Background(), server.URL+"/missing")
+			if err != nil {
+				t.Fatalf("Expected a result for a 404, got %v", err)
+			}
+			if result.StatusCode != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", result.StatusCode)
+			}
+		})
+	}
+}
+
+func TestCrawler_FetchError(t *testing.T) {
+	server := newCrawlerServer(t)
+	url := server.URL
+	server.Close()
+
+	for _, c := range httpCrawlers() {
+		t.Run(c.Engine(), func(t *testing.T) {
+			var failed []string
+			c.OnFetchError(func(u string, err error) {
+				failed = append(failed, u)
+			})
+
+			result, err := c.Fetch(context.Background(), url)
+			if err == nil {
+				t.Fatalf("Expected an error, got status %d", result.StatusCode)
+			}
+			if !strings.Contains(err.Error(), c.Engine()) {
+				t.Errorf("Expected the engine in the error, got %v", err)
+			}
+			if len(failed) != 1 || failed[0] != url {
+				t.Errorf("Expected OnFetchError for %s, got %v", url, failed)
+			}
+		})
+	}
+}
+
+func TestCrawler_CanceledContext(t *testing.T) {
+	server := newCrawlerServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, c := range httpCrawlers() {
+		t.Run(c.Engine(), func(t *testing.T) {
+			if _, err := c.Fetch(ctx, server.URL); !errors.Is(err, context.Canceled) {
+				t.Errorf("Expected context.Canceled, got %v", err)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/gocolly/colly/v2"
 )
 
@@ -65,6 +66,39 @@ func TestValidateURL(t *testing.T) {
 	}
 }
 
+func TestValidateURLWithPolicy(t *testing.T) {
+	policy := libs.ValidationPolicy{
+		AllowPrivate: true,
+		AllowedHosts: []string{"*.corp.example.com"},
+		BlockedCIDRs: []string{"10.99.0.0/16"},
+	}
+
+	tests := []struct {
+		name    string
+		policy  libs.ValidationPolicy
+		url     string
+		wantErr bool
+	}{
+		{"private IP allowed", policy, "http://10.0.0.1/internal", false},
+		{"localhost allowed", policy, "http://localhost:8080/", false},
+		{"blocked CIDR wins", policy, "http://10.99.0.5/", true},
+		{"scheme still checked", policy, "ftp://10.0.0.1/", true},
+		{"allowlisted host only", libs.ValidationPolicy{AllowedHosts: []string{"192.168.1.10"}}, "http://192.168.1.10/", false},
+		{"other private IP blocked", libs.ValidationPolicy{AllowedHosts: []string{"192.168.1.10"}}, "http://192.168.1.11/", true},
+		{"zero policy matches ValidateURL", libs.ValidationPolicy{}, "http://10.0.0.1/", true},
+		{"blocked public IP", libs.ValidationPolicy{BlockedCIDRs: []string{"203.0.113.0/24"}}, "http://203.0.113.9/", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := crawlers.ValidateURLWithPolicy(tt.url, tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateURLWithPolicy(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // CollyClient Constructor Tests
 // =============================================================================
@@ -0,0 +1,125 @@
+package crawlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Dedup Key Tests
+// =============================================================================
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"HTTPS://Example.COM:443/Docs?b=2&a=1#top", "https://example.com/Docs?a=1&b=2"},
+		{"http://example.com:80", "http://example.com/"},
+		{"http://example.com:8080/a/", "http://example.com:8080/a/"},
+		{"https://www.example.com/index.html", "https://www.example.com/index.html"},
+		{"mailto:someone@example.com", "mailto:someone@example.com"},
+	}
+	for _, tt := range tests {
+		if got := crawlers.NormalizeURL(tt.in); got != tt.want {
+			t.Errorf("NormalizeURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDedupKey(t *testing.T) {
+	const (
+		fetched   = "https://Example.com/p?b=2&a=1"
+		canonical = "https://example.com/product"
+	)
+	tests := []struct {
+		strategy string
+		url      string
+		page     string
+	}{
+		{crawlers.DedupExact, fetched, fetched},
+		{crawlers.DedupNormalized, "https://example.com/p?a=1&b=2", "https://example.com/p?a=1&b=2"},
+		{crawlers.DedupContent, "https://example.com/p?a=1&b=2", "https://example.com/p?a=1&b=2 abc123"},
+		{crawlers.DedupCanonical, "https://example.com/p?a=1&b=2", canonical},
+	}
+	for _, tt := range tests {
+		key, err := crawlers.NewDedupKey(tt.strategy)
+		if err != nil {
+			t.Fatalf("NewDedupKey(%q) error = %v", tt.strategy, err)
+		}
+		if got := key.URL(fetched); got != tt.url {
+			t.Errorf("%s: URL() = %q, want %q", tt.strategy, got, tt.url)
+		}
+		if got := key.Page(fetched, canonical, "abc123"); got != tt.page {
+			t.Errorf("%s: Page() = %q, want %q", tt.strategy, got, tt.page)
+		}
+	}
+
+	var nilKey *crawlers.DedupKey
+	if nilKey.Strategy() != crawlers.DedupExact || nilKey.URL(fetched) != fetched {
+		t.Error("Expected a nil key to use exact URLs")
+	}
+	if _, err := crawlers.NewDedupKey("fuzzy"); errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("Expected an invalid config error for an unknown strategy, got %v", err)
+	}
+}
+
+func TestMemoryFrontier_DedupKey(t *testing.T) {
+	key, _ := crawlers.NewDedupKey(crawlers.DedupNormalized)
+	f := frontier.NewMemoryFrontier(frontier.MemoryConfig{Key: key.URL})
+
+	added, err := f.Push(context.Background(),
+		"https://example.com/a?x=1&y=2",
+		"HTTPS://EXAMPLE.COM/a?y=2&x=1#frag",
+		"https://example.com/b",
+	)
+	if err != nil || added != 2 {
+		t.Fatalf("Push() = %d, %v; want 2 URLs added", added, err)
+	}
+	lease, err := f.Claim(context.Background())
+	if err != nil || lease.URL != "https://example.com/a?x=1&y=2" {
+		t.Errorf("Expected the first URL to be queued as pushed, got %+v, %v", lease, err)
+	}
+}
+
+func TestSpider_DedupKey(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = fmt.Fprint(w, `<html><body><a href="/item?a=1&b=2">1</a><a href="/item?b=2&a=1">2</a></body></html>`)
+	}))
+	defer server.Close()
+
+	key, _ := crawlers.NewDedupKey(crawlers.DedupNormalized)
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Dedup: key})
+	spider.OnDocument(func(doc *goquery.Document, pageURL string) error {
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if abs, err := spider.ResolveURL(pageURL, link); err == nil {
+				spider.AddStartURL(abs)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/")
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if requests["/item"] != 1 {
+		t.Errorf("Expected /item to be crawled once across query orders, got %d", requests["/item"])
+	}
+}
@@ -0,0 +1,272 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// =============================================================================
+// Fake Nameserver
+// =============================================================================
+
+// fakeNameserver answers A queries over UDP from a fixed zone
+type fakeNameserver struct {
+	addr    string
+	queries atomic.Int32 // A queries received
+	hosts   map[string]net.IP
+	ttl     uint32
+	soaTTL  uint32 // Negative TTL of the zone; 0 sends no SOA record
+	rcode   dnsmessage.RCode
+	delay   time.Duration
+}
+
+// serveFakeNameserver serves ns, whose hosts are keyed by fully qualified
+// name, until the test ends
+func serveFakeNameserver(t *testing.T, ns *fakeNameserver) *fakeNameserver {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ns.addr = conn.LocalAddr().String()
+	if ns.ttl == 0 {
+		ns.ttl = 60
+	}
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, from, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var query dnsmessage.Message
+			if err := query.Unpack(buf[:n]); err != nil || len(query.Questions) != 1 {
+				continue
+			}
+			go func() {
+				resp := ns.answer(query)
+				if packed, err := resp.Pack(); err == nil {
+					_, _ = conn.WriteTo(packed, from)
+				}
+			}()
+		}
+	}()
+	return ns
+}
+
+// answer builds the response to a query
+func (ns *fakeNameserver) answer(query dnsmessage.Message) dnsmessage.Message {
+	question := query.Questions[0]
+	if question.Type == dnsmessage.TypeA {
+		ns.queries.Add(1)
+	}
+	time.Sleep(ns.delay)
+
+	resp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RCode: ns.rcode},
+		Questions: query.Questions,
+	}
+	if ns.rcode != dnsmessage.RCodeSuccess {
+		return resp
+	}
+	ip, ok := ns.hosts[question.Name.String()]
+	if !ok {
+		resp.RCode = dnsmessage.RCodeNameError
+	}
+	if ok && question.Type == dnsmessage.TypeA {
+		resp.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: question.Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: ns.ttl},
+			Body:   &dnsmessage.AResource{A: [4]byte(ip.To4())},
+		}}
+	}
+	if len(resp.Answers) == 0 && ns.soaTTL > 0 {
+		zone := dnsmessage.MustNewName("test.")
+		resp.Authorities = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: zone, Type: dnsmessage.TypeSOA, Class: dnsmessage.ClassINET, TTL: 3600},
+			Body: &dnsmessage.SOAResource{
+				NS:     dnsmessage.MustNewName("ns.test."),
+				MBox:   dnsmessage.MustNewName("admin.test."),
+				MinTTL: ns.soaTTL,
+			},
+		}}
+	}
+	return resp
+}
+
+// =============================================================================
+// DNS Cache Tests
+// =============================================================================
+
+func TestDNSCache_HonorsRecordTTL(t *testing.T) {
+	ns := serveFakeNameserver(t, &fakeNameserver{hosts: map[string]net.IP{"crawl.test.": net.ParseIP("192.0.2.10")}})
+	fake := clock.NewFake(time.Now())
+	cache := crawlers.NewDNSCache(crawlers.DNSCacheConfig{Servers: []string{ns.addr}, Clock: fake})
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		ips, err := cache.LookupIP(ctx, "Crawl.Test")
+		if err != nil {
+			t.Fatalf("LookupIP() error = %v", err)
+		}
+		if len(ips) != 1 || !ips[0].Equal(net.ParseIP("192.0.2.10")) {
+			t.Fatalf("LookupIP() = %v, want [192.0.2.10]", ips)
+		}
+	}
+	if got := ns.queries.Load(); got != 1 {
+		t.Errorf("Nameserver got %d queries, want 1 within the TTL", got)
+	}
+
+	fake.Advance(61 * time.Second)
+	if _, err := cache.LookupIP(ctx, "crawl.test"); err != nil {
+		t.Fatalf("LookupIP() error = %v", err)
+	}
+	if got := ns.queries.Load(); got != 2 {
+		t.Errorf("Nameserver got %d queries, want 2 after the TTL", got)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 2 hits, 2 misses, 1 entry", stats)
+	}
+}
+
+func TestDNSCache_NegativeCaching(t *testing.T) {
+	ns := serveFakeNameserver(t, &fakeNameserver{soaTTL: 20})
+	fake := clock.NewFake(time.Now())
+	cache := crawlers.NewDNSCache(crawlers.DNSCacheConfig{Servers: []string{ns.addr}, Clock: fake})
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.LookupIP(ctx, "missing.test")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || !dnsErr.IsNotFound {
+			t.Fatalf("LookupIP() error = %v, want a not found DNSError", err)
+		}
+	}
+	if got := ns.queries.Load(); got != 1 {
+		t.Errorf("Nameserver got %d queries, want 1", got)
+	}
+	if stats := cache.Stats(); stats.NegativeHits != 1 {
+		t.Errorf("NegativeHits = %d, want 1", stats.NegativeHits)
+	}
+
+	// The zone's SOA minimum bounds the negative TTL
+	fake.Advance(21 * time.Second)
+	_, _ = cache.LookupIP(ctx, "missing.test")
+	if got := ns.queries.Load(); got != 2 {
+		t.Errorf("Nameserver got %d queries, want 2 after the negative TTL", got)
+	}
+}
+
+func TestDNSCache_ServerFailureNotCached(t *testing.T) {
+	ns := serveFakeNameserver(t, &fakeNameserver{rcode: dnsmessage.RCodeServerFailure})
+	cache := crawlers.NewDNSCache(crawlers.DNSCacheConfig{Servers: []string{ns.addr}})
+
+	for i := 0; i < 2; i++ {
+		_, err := cache.LookupIP(context.Background(), "flaky.test")
+		var dnsErr *net.DNSError
+		if !errors.As(err, &dnsErr) || dnsErr.IsNotFound {
+			t.Fatalf("LookupIP() error = %v, want a temporary DNSError", err)
+		}
+	}
+	if got := ns.queries.Load(); got != 2 {
+		t.Errorf("Nameserver got %d queries, want 2", got)
+	}
+}
+
+func TestDNSCache_SharesConcurrentLookups(t *testing.T) {
+	ns := serveFakeNameserver(t, &fakeNameserver{
+		hosts: map[string]net.IP{"busy.test.": net.ParseIP("192.0.2.20")},
+		delay: 50 * time.Millisecond,
+	})
+	cache := crawlers.NewDNSCache(crawlers.DNSCacheConfig{Servers: []string{ns.addr}})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.LookupIP(context.Background(), "busy.test"); err != nil {
+				t.Errorf("LookupIP() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := ns.queries.Load(); got != 1 {
+		t.Errorf("Nameserver got %d queries, want 1", got)
+	}
+}
+
+func TestDNSCache_IPLiteral(t *testing.T) {
+	cache := crawlers.NewDNSCache(crawlers.DNSCacheConfig{Servers: []string{"127.0.0.1:1"}})
+	ips, err := cache.LookupIP(context.Background(), "2001:db8::1")
+	if err != nil || len(ips) != 1 || !ips[0].Equal(net.ParseIP("2001:db8::1")) {
+		t.Errorf("LookupIP() = %v, %v; want the literal", ips, err)
+	}
+	if stats := cache.Stats(); stats.Misses != 0 {
+		t.Errorf("Expected IP literals to skip the cache, got %+v", stats)
+	}
+}
+
+func TestDNSCache_NilTransport(t *testing.T) {
+	var cache *crawlers.DNSCache
+	if got := cache.Transport(http.DefaultTransport); got != http.DefaultTransport {
+		t.Error("Expected a nil DNSCache to leave the transport unchanged")
+	}
+}
+
+func TestDNSCache_Clients(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><h1>%s</h1></body></html>", r.Host)
+	}))
+	defer server.Close()
+	serverURL, _ := url.Parse(server.URL)
+
+	// crawl.test only resolves through the fake nameserver
+	ns := serveFakeNameserver(t, &fakeNameserver{hosts: map[string]net.IP{"crawl.test.": net.ParseIP("127.0.0.1")}})
+	cache := crawlers.NewDNSCache(crawlers.DNSCacheConfig{Servers: []string{ns.addr}})
+	target := "http://crawl.test:" + serverURL.Port() + "/"
+
+	soupClient := crawlers.NewSoupClient(crawlers.SoupConfig{DNSCache: cache})
+	doc, err := soupClient.Get(target)
+	if err != nil {
+		t.Fatalf("SoupClient.Get() error = %v", err)
+	}
+	if h1 := doc.Find("h1"); h1.Error != nil || !strings.HasPrefix(h1.Text(), "crawl.test") {
+		t.Errorf("Expected the page of crawl.test, got %q", h1.Text())
+	}
+
+	collyClient := crawlers.NewCollyClient(crawlers.CollyConfig{DNSCache: cache})
+	var visited atomic.Bool
+	collyClient.OnHTML("h1", func(e *colly.HTMLElement) { visited.Store(true) })
+	if err := collyClient.Visit(target); err != nil {
+		t.Fatalf("CollyClient.Visit() error = %v", err)
+	}
+	if !visited.Load() {
+		t.Error("Expected Colly to fetch the page")
+	}
+
+	// Both clients resolved crawl.test through the shared cache once
+	if got := ns.queries.Load(); got != 1 {
+		t.Errorf("Nameserver got %d queries, want 1", got)
+	}
+}
@@ -0,0 +1,115 @@
+package crawlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+)
+
+// =============================================================================
+// Dry Run Tests
+// =============================================================================
+
+func TestDryRunPoliteness(t *testing.T) {
+	urls := []string{
+		"https://a.example.com/1",
+		"https://a.example.com/2",
+		"https://a.example.com/3",
+		"https://b.example.org/1",
+	}
+
+	estimate := crawlers.DryRun(urls, crawlers.DryRunConfig{
+		RateLimit:   crawlers.RateLimiterConfig{Delay: 2 * time.Second},
+		Concurrency: 2,
+		Latency:     time.Second,
+		PageSize:    1000,
+	})
+
+	if estimate.URLs != 4 || estimate.Requests != 4 || estimate.Bytes != 4000 {
+		t.Errorf("URLs = %d, Requests = %d, Bytes = %d, want 4, 4, 4000", estimate.URLs, estimate.Requests, estimate.Bytes)
+	}
+	// a.example.com starts at 0s, 2s and 4s; its last response arrives at 5s
+	if estimate.Duration != 5*time.Second {
+		t.Errorf("Duration = %v, want 5s", estimate.Duration)
+	}
+	a := estimate.Domains["example.com"]
+	if a == nil || a.Requests != 3 || a.Duration != 5*time.Second {
+		t.Errorf("example.com estimate = %+v, want 3 requests over 5s", a)
+	}
+	if b := estimate.Domains["example.org"]; b == nil || b.Requests != 1 {
+		t.Errorf("example.org estimate = %+v, want 1 request", b)
+	}
+}
+
+func TestDryRunConcurrency(t *testing.T) {
+	urls := []string{
+		"https://a.example/1",
+		"https://b.example/1",
+		"https://c.example/1",
+		"https://d.example/1",
+	}
+
+	serial := crawlers.DryRun(urls, crawlers.DryRunConfig{Latency: time.Second})
+	parallel := crawlers.DryRun(urls, crawlers.DryRunConfig{Latency: time.Second, Concurrency: 4})
+
+	if serial.Duration != 4*time.Second {
+		t.Errorf("serial Duration = %v, want 4s", serial.Duration)
+	}
+	if parallel.Duration != time.Second {
+		t.Errorf("parallel Duration = %v, want 1s", parallel.Duration)
+	}
+}
+
+func TestDryRunMaxConcurrent(t *testing.T) {
+	urls := []string{"https://a.example/1", "https://a.example/2", "https://a.example/3"}
+
+	estimate := crawlers.DryRun(urls, crawlers.DryRunConfig{
+		RateLimit:   crawlers.RateLimiterConfig{MaxConcurrent: 1},
+		Concurrency: 3,
+		Latency:     time.Second,
+	})
+
+	if estimate.Duration != 3*time.Second {
+		t.Errorf("Duration = %v, want 3s with one slot per domain", estimate.Duration)
+	}
+}
+
+func TestDryRunSkips(t *testing.T) {
+	urls := []string{
+		"https://a.example/1",
+		"https://a.example/1",
+		"https://a.example/login",
+		"https://a.example/2",
+		"https://a.example/3",
+	}
+
+	estimate := crawlers.DryRun(urls, crawlers.DryRunConfig{
+		URLRules: urlmatch.MustCompile([]urlmatch.Rule{{Pattern: "/login*", Action: urlmatch.Deny}}),
+		MaxPages: 2,
+	})
+
+	if estimate.Requests != 2 || estimate.Exhausted != crawlers.BudgetMaxPages {
+		t.Errorf("Requests = %d, Exhausted = %q, want 2, %q", estimate.Requests, estimate.Exhausted, crawlers.BudgetMaxPages)
+	}
+	want := map[string]int{crawlers.SkipDuplicate: 1, crawlers.SkipURLRule: 1, crawlers.SkipBudget: 1}
+	for reason, n := range want {
+		if estimate.Skips.ByReason[reason] != n {
+			t.Errorf("Skips.ByReason[%q] = %d, want %d", reason, estimate.Skips.ByReason[reason], n)
+		}
+	}
+}
+
+func TestDryRunMaxDuration(t *testing.T) {
+	urls := []string{"https://a.example/1", "https://a.example/2", "https://a.example/3"}
+
+	estimate := crawlers.DryRun(urls, crawlers.DryRunConfig{
+		RateLimit:   crawlers.RateLimiterConfig{Delay: time.Minute},
+		MaxDuration: 90 * time.Second,
+	})
+
+	if estimate.Requests != 2 || estimate.Exhausted != crawlers.BudgetMaxDuration {
+		t.Errorf("Requests = %d, Exhausted = %q, want 2, %q", estimate.Requests, estimate.Exhausted, crawlers.BudgetMaxDuration)
+	}
+}
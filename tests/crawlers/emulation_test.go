@@ -0,0 +1,107 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Device and Region Emulation Tests
+// =============================================================================
+
+func TestEmulationPresets(t *testing.T) {
+	for name, device := range crawlers.Devices {
+		if device.UserAgent == "" || device.ViewportWidth <= 0 || device.ViewportHeight <= 0 || device.DeviceScaleFactor <= 0 || device.Platform == "" {
+			t.Errorf("Device %s is incomplete: %+v", name, device)
+		}
+		if device.Mobile != device.Touch {
+			t.Errorf("Device %s: mobile and touch should go together: %+v", name, device)
+		}
+	}
+	for name, region := range crawlers.Regions {
+		if region.Locale != name {
+			t.Errorf("Region %s has locale %s", name, region.Locale)
+		}
+		if _, err := time.LoadLocation(region.TimezoneID); err != nil {
+			t.Errorf("Region %s: %v", name, err)
+		}
+		if lat, lon := region.Geolocation.Latitude, region.Geolocation.Longitude; lat < -90 || lat > 90 || lon < -180 || lon > 180 || (lat == 0 && lon == 0) {
+			t.Errorf("Region %s has an invalid position: %+v", name, region.Geolocation)
+		}
+	}
+}
+
+func TestEmulation_UnknownPreset(t *testing.T) {
+	// Presets are checked before a browser is started
+	_, err := crawlers.NewPuppeteerClient(crawlers.PuppeteerConfig{Emulation: crawlers.EmulationConfig{Device: "nokia-3310"}})
+	if errs.CodeOf(err) != errs.CodeInvalidConfig || !strings.Contains(err.Error(), "iphone-15") {
+		t.Errorf("Expected an invalid config error listing the presets, got %v", err)
+	}
+	_, err = crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Emulation: crawlers.EmulationConfig{Region: "xx-XX"}})
+	if errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("Expected an invalid config error, got %v", err)
+	}
+	_, err = crawlers.NewPlaywrightPool(crawlers.PlaywrightPoolConfig{Emulation: crawlers.EmulationConfig{Device: "nokia-3310"}})
+	if errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("Expected an invalid config error, got %v", err)
+	}
+}
+
+func TestPlaywrightClient_Emulation(t *testing.T) {
+	var userAgent, acceptLanguage string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, acceptLanguage = r.UserAgent(), r.Header.Get("Accept-Language")
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><meta name="viewport" content="width=device-width"></head><body>mobile</body></html>`))
+	}))
+	defer server.Close()
+
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{
+		Headless:  true,
+		Timeout:   10 * time.Second,
+		Emulation: crawlers.EmulationConfig{Device: "iphone-15", Region: "de-DE"},
+	})
+	if err != nil {
+		t.Skipf("Skipping Playwright emulation tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	if err := client.Navigate(server.URL + "/"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	result, err := client.Evaluate(`async () => {
+		const position = await new Promise((resolve, reject) => navigator.geolocation.getCurrentPosition(resolve, reject));
+		return {
+			width: window.innerWidth,
+			ratio: window.devicePixelRatio,
+			touch: navigator.maxTouchPoints > 0,
+			platform: navigator.platform,
+			language: navigator.language,
+			timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+			latitude: position.coords.latitude,
+		};
+	}`)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	page, ok := result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Unexpected result %T", result)
+	}
+
+	if !strings.Contains(userAgent, "iPhone") || !strings.HasPrefix(acceptLanguage, "de-DE") {
+		t.Errorf("Expected an iPhone user agent and German languages, got %q and %q", userAgent, acceptLanguage)
+	}
+	if page["width"] != 393 || page["ratio"] != 3 || page["touch"] != true || page["platform"] != "iPhone" {
+		t.Errorf("Expected the iPhone 15 screen and input, got %v", page)
+	}
+	if page["language"] != "de-DE" || page["timezone"] != "Europe/Berlin" || page["latitude"] != 52.52 {
+		t.Errorf("Expected Berlin, got %v", page)
+	}
+}
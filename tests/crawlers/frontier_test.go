@@ -0,0 +1,405 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/redis/go-redis/v9"
+)
+
+// =============================================================================
+// Frontier Tests
+// =============================================================================
+
+// frontierFactory creates an empty frontier with the given settings and a
+// function that lets lease time pass
+type frontierFactory func(t *testing.T, visibility time.Duration, maxRetries int) (frontier.Frontier, func(time.Duration))
+
+func memoryFrontier(_ *testing.T, visibility time.Duration, maxRetries int) (frontier.Frontier, func(time.Duration)) {
+	fake := clock.NewFake(time.Time{})
+	f := frontier.NewMemoryFrontier(frontier.MemoryConfig{VisibilityTimeout: visibility, MaxRetries: maxRetries, Clock: fake})
+	return f, fake.Advance
+}
+
+// pingRedis pings the local Redis server once
+var pingRedis = sync.OnceValue(func() error {
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379", MaxRetries: -1})
+	defer func() { _ = client.Close() }()
+	return client.Ping(context.Background()).Err()
+})
+
+// redisFrontier uses a local Redis server and skips when none is available
+// Leases expire on the server's clock, so time passes by sleeping
+func redisFrontier(t *testing.T, visibility time.Duration, maxRetries int) (frontier.Frontier, func(time.Duration)) {
+	t.Helper()
+
+	if err := pingRedis(); err != nil {
+		t.Skipf("Skipping Redis frontier tests: %v", err)
+	}
+	client := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+
+	f, err := frontier.NewRedisFrontier(frontier.RedisConfig{
+		Client:            client,
+		Name:              t.Name(),
+		VisibilityTimeout: visibility,
+		MaxRetries:        maxRetries,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisFrontier() error = %v", err)
+	}
+	_ = f.Reset(context.Background())
+	t.Cleanup(func() {
+		_ = f.Reset(context.Background()) // Best effort cleanup
+		_ = client.Close()
+	})
+	return f, time.Sleep
+}
+
+func TestNewRedisFrontier_RequiresClient(t *testing.T) {
+	if _, err := frontier.NewRedisFrontier(frontier.RedisConfig{}); err == nil {
+		t.Error("Expected error without a Redis client")
+	}
+}
+
+func TestFrontier(t *testing.T) {
+	for name, factory := range map[string]frontierFactory{
+		"memory": memoryFrontier,
+		"redis":  redisFrontier,
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("PushDeduplicates", func(t *testing.T) { testFrontierPush(t, factory) })
+			t.Run("ClaimAck", func(t *testing.T) { testFrontierClaimAck(t, factory) })
+			t.Run("RetryAndDeadLetter", func(t *testing.T) { testFrontierRetry(t, factory) })
+			t.Run("VisibilityTimeout", func(t *testing.T) { testFrontierVisibility(t, factory) })
+			t.Run("ConcurrentClaims", func(t *testing.T) { testFrontierConcurrentClaims(t, factory) })
+			t.Run("Inspect", func(t *testing.T) { testFrontierInspect(t, factory) })
+		})
+	}
+}
+
+func testFrontierPush(t *testing.T, factory frontierFactory) {
+	f, _ := factory(t, time.Minute, 3)
+	ctx := context.Background()
+
+	added, err := f.Push(ctx, "https://a.example/", "https://b.example/", "https://a.example/")
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if added != 2 {
+		t.Errorf("Push() added = %d, want 2", added)
+	}
+	if added, _ := f.Push(ctx, "https://b.example/"); added != 0 {
+		t.Errorf("Push() of seen URL added = %d, want 0", added)
+	}
+
+	stats, err := f.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Pending != 2 || stats.Seen != 2 {
+		t.Errorf("Stats() = %+v, want 2 pending and 2 seen", stats)
+	}
+}
+
+func testFrontierClaimAck(t *testing.T, factory frontierFactory) {
+	f, _ := factory(t, time.Minute, 3)
+	ctx := context.Background()
+
+	if _, err := f.Claim(ctx); !errors.Is(err, frontier.ErrEmpty) {
+		t.Errorf("Claim() on empty frontier error = %v, want ErrEmpty", err)
+	}
+
+	_, _ = f.Push(ctx, "https://a.example/")
+	lease, err := f.Claim(ctx)
+	if err != nil {
+		t.Fatalf("Claim() error = %v", err)
+	}
+	if lease.URL != "https://a.example/" || lease.Attempts != 1 || lease.Token == "" {
+		t.Errorf("Unexpected lease: %+v", lease)
+	}
+
+	if stats, _ := f.Stats(ctx); stats.InFlight != 1 || stats.Pending != 0 {
+		t.Errorf("Stats() = %+v, want 1 in flight", stats)
+	}
+	if err := f.Extend(ctx, lease); err != nil {
+		t.Errorf("Extend() error = %v", err)
+	}
+	if err := f.Ack(ctx, lease); err != nil {
+		t.Fatalf("Ack() error = %v", err)
+	}
+	if err := f.Ack(ctx, lease); !errors.Is(err, frontier.ErrLeaseLost) {
+		t.Errorf("Second Ack() error = %v, want ErrLeaseLost", err)
+	}
+
+	stats, _ := f.Stats(ctx)
+	if !stats.Idle() {
+		t.Errorf("Expected idle frontier after ack, got %+v", stats)
+	}
+}
+
+func testFrontierRetry(t *testing.T, factory frontierFactory) {
+	f, _ := factory(t, time.Minute, 2)
+	ctx := context.Background()
+	_, _ = f.Push(ctx, "https://a.example/")
+
+	first, _ := f.Claim(ctx)
+	if err := f.Retry(ctx, first); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+
+	second, err := f.Claim(ctx)
+	if err != nil {
+		t.Fatalf("Claim() after retry error = %v", err)
+	}
+	if second.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", second.Attempts)
+	}
+	if err := f.Retry(ctx, second); err != nil {
+		t.Fatalf("Retry() error = %v", err)
+	}
+
+	stats, _ := f.Stats(ctx)
+	if stats.Dead != 1 || !stats.Idle() {
+		t.Errorf("Expected URL dead-lettered after max retries, got %+v", stats)
+	}
+}
+
+func testFrontierVisibility(t *testing.T, factory frontierFactory) {
+	f, advance := factory(t, 50*time.Millisecond, 3)
+	ctx := context.Background()
+	_, _ = f.Push(ctx, "https://a.example/")
+
+	stale, _ := f.Claim(ctx)
+	if _, err := f.Claim(ctx); !errors.Is(err, frontier.ErrEmpty) {
+		t.Errorf("Claim() while leased error = %v, want ErrEmpty", err)
+	}
+
+	advance(100 * time.Millisecond)
+	fresh, err := f.Claim(ctx)
+	if err != nil {
+		t.Fatalf("Claim() after visibility timeout error = %v", err)
+	}
+	if fresh.URL != stale.URL || fresh.Attempts != 2 {
+		t.Errorf("Unexpected reclaimed lease: %+v", fresh)
+	}
+
+	if err := f.Ack(ctx, stale); !errors.Is(err, frontier.ErrLeaseLost) {
+		t.Errorf("Ack() with stale lease error = %v, want ErrLeaseLost", err)
+	}
+	if err := f.Ack(ctx, fresh); err != nil {
+		t.Errorf("Ack() with current lease error = %v", err)
+	}
+}
+
+func testFrontierConcurrentClaims(t *testing.T, factory frontierFactory) {
+	f, _ := factory(t, time.Minute, 3)
+	ctx := context.Background()
+
+	var urls []string
+	for i := 0; i < 50; i++ {
+		urls = append(urls, fmt.Sprintf("https://example.com/%d", i))
+	}
+	_, _ = f.Push(ctx, urls...)
+
+	var (
+		mu      sync.Mutex
+		claimed = make(map[string]int)
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < 5; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				lease, err := f.Claim(ctx)
+				if err != nil {
+					return
+				}
+				mu.Lock()
+				claimed[lease.URL]++
+				mu.Unlock()
+				_ = f.Ack(ctx, lease)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claimed) != len(urls) {
+		t.Errorf("Claimed %d distinct URLs, want %d", len(claimed), len(urls))
+	}
+	for u, n := range claimed {
+		if n != 1 {
+			t.Errorf("%s claimed %d times", u, n)
+		}
+	}
+}
+
+// =============================================================================
+// Spider Frontier Tests
+// =============================================================================
+
+func testFrontierInspect(t *testing.T, factory frontierFactory) {
+	f, _ := factory(t, time.Minute, 1)
+	inspector, ok := f.(frontier.Inspector)
+	if !ok {
+		t.Fatalf("%T does not implement frontier.Inspector", f)
+	}
+	ctx := context.Background()
+
+	_, _ = f.Push(ctx, "https://a.example/", "https://b.example/", "https://c.example/", "https://d.example/")
+	for i := 0; i < 3; i++ {
+		lease, _ := f.Claim(ctx)
+		if i < 2 {
+			_ = f.Retry(ctx, lease) // One retry allowed, so both are dead-lettered
+		}
+	}
+
+	pending, err := inspector.Peek(ctx, frontier.QueuePending, 0)
+	if err != nil {
+		t.Fatalf("Peek() error = %v", err)
+	}
+	if len(pending) != 1 || pending[0].URL != "https://d.example/" {
+		t.Errorf("Peek(pending) = %+v", pending)
+	}
+	inFlight, _ := inspector.Peek(ctx, frontier.QueueInFlight, 0)
+	if len(inFlight) != 1 || inFlight[0].URL != "https://c.example/" || inFlight[0].Attempts != 1 || inFlight[0].Deadline.IsZero() {
+		t.Errorf("Peek(inflight) = %+v", inFlight)
+	}
+	dead, _ := inspector.Peek(ctx, frontier.QueueDead, 1)
+	if len(dead) != 1 || dead[0].URL != "https://a.example/" {
+		t.Errorf("Peek(dead, 1) = %+v", dead)
+	}
+	if _, err := inspector.Peek(ctx, frontier.Queue("stuck"), 0); !errs.HasCode(err, errs.CodeInvalidRequest) {
+		t.Errorf("Peek(unknown) error = %v, want %s", err, errs.CodeInvalidRequest)
+	}
+
+	moved, err := inspector.Requeue(ctx, "https://b.example/", "https://z.example/")
+	if err != nil || moved != 1 {
+		t.Fatalf("Requeue() = %d, %v; want 1 moved", moved, err)
+	}
+	if moved, _ := inspector.Requeue(ctx); moved != 1 {
+		t.Errorf("Requeue(all) moved %d, want 1", moved)
+	}
+	stats, _ := f.Stats(ctx)
+	if stats.Pending != 3 || stats.Dead != 0 {
+		t.Errorf("Stats() after requeue = %+v, want 3 pending", stats)
+	}
+	pending, _ = inspector.Peek(ctx, frontier.QueuePending, 0)
+	for _, e := range pending {
+		if e.Attempts != 0 {
+			t.Errorf("Requeued %s has %d attempts, want a fresh budget", e.URL, e.Attempts)
+		}
+	}
+
+	if purged, err := inspector.Purge(ctx, frontier.QueueInFlight); err != nil || purged != 1 {
+		t.Errorf("Purge(inflight) = %d, %v; want 1", purged, err)
+	}
+	if purged, err := inspector.Purge(ctx, frontier.QueuePending); err != nil || purged != 3 {
+		t.Errorf("Purge(pending) = %d, %v; want 3", purged, err)
+	}
+	if added, _ := f.Push(ctx, "https://a.example/"); added != 0 {
+		t.Errorf("Push() of purged URL added %d, want 0", added)
+	}
+	if stats, _ := f.Stats(ctx); !stats.Idle() || stats.Dead != 0 || stats.Seen != 4 {
+		t.Errorf("Stats() after purge = %+v", stats)
+	}
+}
+
+func TestParseQueue(t *testing.T) {
+	if q, err := frontier.ParseQueue("dead"); err != nil || q != frontier.QueueDead {
+		t.Errorf("ParseQueue(dead) = %q, %v", q, err)
+	}
+	if _, err := frontier.ParseQueue("failed"); !errs.HasCode(err, errs.CodeInvalidRequest) {
+		t.Errorf("ParseQueue(failed) error = %v, want %s", err, errs.CodeInvalidRequest)
+	}
+}
+
+func TestSpider_SharedFrontier(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		hits = make(map[string]int)
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		mu.Unlock()
+		_, _ = w.Write([]byte(`<html><body><a href="/a">a</a><a href="/b">b</a><a href="/c">c</a></body></html>`))
+	}))
+	defer server.Close()
+
+	shared := frontier.NewMemoryFrontier(frontier.MemoryConfig{})
+
+	newSpider := func() *crawlers.Spider {
+		spider := crawlers.NewSpider(crawlers.SpiderConfig{
+			Concurrency:  2,
+			Frontier:     shared,
+			FrontierPoll: 10 * time.Millisecond,
+		})
+		spider.OnDocument(func(doc *goquery.Document, pageURL string) error {
+			for _, link := range spider.ExtractLinks(doc, "a[href]") {
+				if abs, err := spider.ResolveURL(pageURL, link); err == nil {
+					spider.AddStartURL(abs)
+				}
+			}
+			return nil
+		})
+		return spider
+	}
+
+	first, second := newSpider(), newSpider()
+	first.AddStartURL(server.URL + "/")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, spider := range []*crawlers.Spider{first, second} {
+		wg.Add(1)
+		go func(s *crawlers.Spider) {
+			defer wg.Done()
+			if err := s.RunContext(ctx); err != nil {
+				t.Errorf("RunContext() error = %v", err)
+			}
+		}(spider)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range []string{"/", "/a", "/b", "/c"} {
+		if hits[path] != 1 {
+			t.Errorf("%s fetched %d times, want 1", path, hits[path])
+		}
+	}
+}
+
+func TestSpider_FrontierRetriesFailures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	shared := frontier.NewMemoryFrontier(frontier.MemoryConfig{MaxRetries: 2})
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Frontier: shared, FrontierPoll: 10 * time.Millisecond})
+	spider.AddStartURL(server.URL + "/broken")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	dead, _ := shared.Dead(context.Background())
+	if len(dead) != 1 || dead[0] != server.URL+"/broken" {
+		t.Errorf("Dead = %v, want the failing URL", dead)
+	}
+}
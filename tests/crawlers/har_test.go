@@ -0,0 +1,123 @@
+package crawlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// HAR Capture Tests
+// =============================================================================
+
+func TestHARRecorder_Empty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.har")
+	if err := crawlers.NewHARRecorder().WriteFile(path); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read HAR: %v", err)
+	}
+	var har map[string]map[string]interface{}
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("Invalid HAR JSON: %v", err)
+	}
+	if har["log"]["version"] != "1.2" {
+		t.Errorf("version = %v, want 1.2", har["log"]["version"])
+	}
+	if entries, ok := har["log"]["entries"].([]interface{}); !ok || len(entries) != 0 {
+		t.Errorf("entries = %v, want an empty array", har["log"]["entries"])
+	}
+}
+
+func TestPlaywrightClient_HAR(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><div id="out"></div><script>
+				fetch('/api/items?page=2&sort=new', {method: 'POST', headers: {'Content-Type': 'application/json'}, body: '{"q":"x"}'})
+					.then(r => r.text()).then(t => document.getElementById('out').textContent = t)
+			</script></body></html>`))
+		case "/api/items":
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("X-Served-By", "test")
+			_, _ = w.Write([]byte(`["done"]`))
+		}
+	}))
+	defer server.Close()
+
+	harPath := filepath.Join(t.TempDir(), "crawl.har")
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true, HARPath: harPath})
+	if err != nil {
+		t.Skipf("Skipping Playwright HAR tests: browser not available (%v)", err)
+	}
+
+	if err := client.Navigate(server.URL + "/"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	if err := client.WaitForSelector("#out:has-text('done')"); err != nil {
+		t.Fatalf("API response not rendered: %v", err)
+	}
+
+	har := client.HAR()
+	var api *crawlers.HAREntry
+	for i := range har.Log.Entries {
+		if strings.Contains(har.Log.Entries[i].Request.URL, "/api/items") {
+			api = &har.Log.Entries[i]
+		}
+	}
+	if api == nil {
+		t.Fatalf("HAR has no entry for the API request: %+v", har.Log.Entries)
+	}
+	if api.Request.Method != http.MethodPost || api.Request.PostData == nil || api.Request.PostData.Text != `{"q":"x"}` {
+		t.Errorf("Unexpected request: %+v", api.Request)
+	}
+	if len(api.Request.QueryString) != 2 || api.Request.QueryString[0].Name != "page" {
+		t.Errorf("QueryString = %+v", api.Request.QueryString)
+	}
+	if api.Response.Status != http.StatusOK || api.Response.Content.MimeType != "application/json" {
+		t.Errorf("Unexpected response: %+v", api.Response)
+	}
+	found := false
+	for _, h := range api.Response.Headers {
+		found = found || (strings.EqualFold(h.Name, "X-Served-By") && h.Value == "test")
+	}
+	if !found {
+		t.Errorf("Response headers missing X-Served-By: %+v", api.Response.Headers)
+	}
+	if api.StartedDateTime.IsZero() || api.Time < 0 {
+		t.Errorf("Missing timings: %+v", api)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if data, err := os.ReadFile(harPath); err != nil || !strings.Contains(string(data), "/api/items") {
+		t.Errorf("HAR file not written on Close: %v", err)
+	}
+}
+
+func TestPlaywrightClient_ExportHARDisabled(t *testing.T) {
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true})
+	if err != nil {
+		t.Skipf("Skipping Playwright HAR tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	if client.HAR() != nil {
+		t.Error("HAR() should be nil without RecordHAR")
+	}
+	if err := client.ExportHAR(filepath.Join(t.TempDir(), "x.har")); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("ExportHAR() error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+}
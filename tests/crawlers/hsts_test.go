@@ -0,0 +1,188 @@
+package crawlers_test
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// HSTS Tests
+// =============================================================================
+
+// observe records an HSTS header sent by rawURL
+func observe(hsts *crawlers.HSTS, rawURL, value string) {
+	u, _ := url.Parse(rawURL)
+	hsts.Observe(u, http.Header{"Strict-Transport-Security": {value}})
+}
+
+func TestHSTS_Observe(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	hsts := crawlers.NewHSTS(crawlers.HSTSConfig{Clock: fake})
+
+	observe(hsts, "https://secure.example.com/", `max-age="3600"; includeSubDomains`)
+	observe(hsts, "http://plain.example.com/", "max-age=3600")        // Ignored over http
+	observe(hsts, "https://10.0.0.1/", "max-age=3600")                // Ignored for IPs
+	observe(hsts, "https://broken.example.com/", "includeSubDomains") // No max-age
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"secure.example.com", true},
+		{"SECURE.example.com", true},
+		{"api.secure.example.com", true},
+		{"example.com", false},
+		{"plain.example.com", false},
+		{"10.0.0.1", false},
+		{"broken.example.com", false},
+	}
+	for _, tt := range tests {
+		if got := hsts.Known(tt.host); got != tt.want {
+			t.Errorf("Known(%q) = %v, want %v", tt.host, got, tt.want)
+		}
+	}
+
+	fake.Advance(time.Hour)
+	if hsts.Known("secure.example.com") {
+		t.Error("Expected the policy to expire after max-age")
+	}
+}
+
+func TestHSTS_SubdomainsAndRemoval(t *testing.T) {
+	hsts := crawlers.NewHSTS(crawlers.HSTSConfig{})
+
+	observe(hsts, "https://example.com/", "max-age=3600")
+	if !hsts.Known("example.com") || hsts.Known("www.example.com") {
+		t.Error("Expected the policy to cover the host only without includeSubDomains")
+	}
+
+	observe(hsts, "https://example.com/", "max-age=0")
+	if hsts.Known("example.com") {
+		t.Error("Expected max-age=0 to forget the host")
+	}
+}
+
+func TestHSTS_Upgrade(t *testing.T) {
+	hsts := crawlers.NewHSTS(crawlers.HSTSConfig{})
+	observe(hsts, "https://example.com/", "max-age=3600")
+
+	tests := []struct {
+		in, want string
+		upgraded bool
+	}{
+		{"http://example.com/a?b=1#c", "https://example.com/a?b=1#c", true},
+		{"http://example.com:80/a", "https://example.com/a", true},
+		{"http://example.com:8080/a", "https://example.com:8080/a", true},
+		{"https://example.com/a", "https://example.com/a", false},
+		{"http://other.example/a", "http://other.example/a", false},
+		{"ftp://example.com/a", "ftp://example.com/a", false},
+	}
+	for _, tt := range tests {
+		got, upgraded := hsts.Upgrade(context.Background(), tt.in)
+		if got != tt.want || upgraded != tt.upgraded {
+			t.Errorf("Upgrade(%q) = %q, %v; want %q, %v", tt.in, got, upgraded, tt.want, tt.upgraded)
+		}
+	}
+
+	var nilHSTS *crawlers.HSTS
+	if got, upgraded := nilHSTS.Upgrade(context.Background(), "http://example.com/"); upgraded || got != "http://example.com/" {
+		t.Error("Expected a nil HSTS to upgrade nothing")
+	}
+}
+
+// probeTransport answers https probes for the hosts in secure
+type probeTransport struct {
+	secure map[string]bool
+	calls  atomic.Int32
+}
+
+func (p *probeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.calls.Add(1)
+	if req.Method != http.MethodHead || req.URL.Scheme != "https" || !p.secure[req.URL.Hostname()] {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{
+		StatusCode: http.StatusMovedPermanently,
+		Header:     http.Header{"Location": {"https://www." + req.URL.Host + "/"}},
+		Body:       http.NoBody,
+		Request:    req,
+	}, nil
+}
+
+func TestHSTS_Probe(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	transport := &probeTransport{secure: map[string]bool{"secure.example": true}}
+	hsts := crawlers.NewHSTS(crawlers.HSTSConfig{
+		ProbeHTTPS: true,
+		ProbeTTL:   time.Hour,
+		Transport:  transport,
+		Clock:      fake,
+	})
+	ctx := context.Background()
+
+	if got, ok := hsts.Upgrade(ctx, "http://secure.example/page"); !ok || got != "https://secure.example/page" {
+		t.Errorf("Upgrade() = %q, %v; want the https URL", got, ok)
+	}
+	if got, ok := hsts.Upgrade(ctx, "http://plain.example/page"); ok || got != "http://plain.example/page" {
+		t.Errorf("Upgrade() = %q, %v; want the http URL", got, ok)
+	}
+	if _, ok := hsts.Upgrade(ctx, "http://secure.example:8080/page"); ok {
+		t.Error("Expected hosts on other ports not to be probed")
+	}
+
+	hsts.Upgrade(ctx, "http://secure.example/other")
+	hsts.Upgrade(ctx, "http://plain.example/other")
+	if calls := transport.calls.Load(); calls != 2 {
+		t.Errorf("Probes = %d, want 2 cached per host", calls)
+	}
+
+	fake.Advance(time.Hour)
+	hsts.Upgrade(ctx, "http://plain.example/page")
+	if calls := transport.calls.Load(); calls != 3 {
+		t.Errorf("Probes = %d, want a new probe after ProbeTTL", calls)
+	}
+}
+
+func TestHSTS_SoupUpgrade(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", "max-age=600")
+		_, _ = w.Write([]byte(`<html><body><p>secure</p></body></html>`))
+	}))
+	defer server.Close()
+	base := strings.Replace(server.URL, "127.0.0.1", "localhost", 1) // HSTS ignores IP hosts
+
+	hsts := crawlers.NewHSTS(crawlers.HSTSConfig{})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{
+		HSTS: hsts,
+		Transport: crawlers.TransportConfig{
+			TLSConfig: &tls.Config{InsecureSkipVerify: true}, //nolint:gosec // Test server certificate
+		},
+	})
+
+	if _, err := client.Get(base + "/"); err != nil {
+		t.Fatalf("Get(https) error = %v", err)
+	}
+	if !hsts.Known("localhost") {
+		t.Fatal("Expected the HSTS header to be recorded")
+	}
+
+	// The TLS server rejects plain http, so this only succeeds once upgraded
+	doc, err := client.Get(strings.Replace(base, "https://", "http://", 1) + "/page")
+	if err != nil {
+		t.Fatalf("Get(http) error = %v, want the request sent over https", err)
+	}
+	if p := doc.Find("p"); p.Error != nil || p.Text() != "secure" {
+		t.Error("Unexpected body for the upgraded request")
+	}
+}
@@ -0,0 +1,309 @@
+package crawlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+)
+
+// =============================================================================
+// HTTP Cache Tests
+// =============================================================================
+
+// newCachingServer serves a page numbered by request, with the headers set
+// by handle, and counts requests
+func newCachingServer(t *testing.T, hits *int32, handle func(w http.ResponseWriter, r *http.Request) bool) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(hits, 1)
+		w.Header().Set("Content-Type", "text/html")
+		if handle != nil && !handle(w, r) {
+			return
+		}
+		fmt.Fprintf(w, "<html><body><h1>page %d</h1></body></html>", n)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// getHeading fetches url and returns its h1
+func getHeading(t *testing.T, client *crawlers.SoupClient, url string, headers map[string]string) string {
+	t.Helper()
+
+	doc, err := client.GetWithHeaders(url, headers)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	return doc.Find("h1").Text()
+}
+
+func TestHTTPCache_MaxAge(t *testing.T) {
+	var hits int32
+	fake := clock.NewFake(time.Now())
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Cache-Control", "public, max-age=60")
+		w.Header().Set("Date", fake.Now().UTC().Format(http.TimeFormat)) // A real Date would lag the advanced fake clock and age responses
+		return true
+	})
+	cache := crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{Clock: fake})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPCache: cache})
+
+	for i := 0; i < 3; i++ {
+		if got := getHeading(t, client, server.URL, nil); got != "page 1" {
+			t.Fatalf("Get() = %q, want the cached page 1", got)
+		}
+	}
+	if hits != 1 {
+		t.Errorf("Server got %d requests, want 1 within max-age", hits)
+	}
+
+	fake.Advance(61 * time.Second)
+	if got := getHeading(t, client, server.URL, nil); got != "page 2" {
+		t.Errorf("Get() = %q, want page 2 once stale", got)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.Stored != 2 {
+		t.Errorf("Stats() = %+v, want 2 hits, 2 misses, 2 stored", stats)
+	}
+}
+
+func TestHTTPCache_Expires(t *testing.T) {
+	var hits int32
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		now := time.Now().UTC()
+		w.Header().Set("Date", now.Format(http.TimeFormat))
+		w.Header().Set("Expires", now.Add(time.Hour).Format(http.TimeFormat))
+		return true
+	})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPCache: crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{})})
+
+	getHeading(t, client, server.URL, nil)
+	getHeading(t, client, server.URL, nil)
+	if hits != 1 {
+		t.Errorf("Server got %d requests, want 1 before Expires", hits)
+	}
+}
+
+func TestHTTPCache_NotStored(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		request map[string]string
+	}{
+		{name: "no-store", header: "Cache-Control", value: "no-store, max-age=60"},
+		{name: "vary star", header: "Vary", value: "*"},
+		{name: "no freshness", header: "Cache-Control", value: "public"},
+		{name: "authorization", header: "Cache-Control", value: "max-age=60", request: map[string]string{"Authorization": "Bearer token"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int32
+			server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+				if tt.header != "Cache-Control" {
+					w.Header().Set("Cache-Control", "max-age=60")
+				}
+				w.Header().Set(tt.header, tt.value)
+				return true
+			})
+			cache := crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{})
+			client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPCache: cache})
+
+			getHeading(t, client, server.URL, tt.request)
+			getHeading(t, client, server.URL, tt.request)
+			if hits != 2 {
+				t.Errorf("Server got %d requests, want 2", hits)
+			}
+			if stats := cache.Stats(); stats.Stored != 0 {
+				t.Errorf("Stored = %d, want 0", stats.Stored)
+			}
+		})
+	}
+}
+
+func TestHTTPCache_Revalidates(t *testing.T) {
+	var hits, notModified int32
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return false
+		}
+		return true
+	})
+	cache := crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPCache: cache})
+
+	for i := 0; i < 3; i++ {
+		if got := getHeading(t, client, server.URL, nil); got != "page 1" {
+			t.Fatalf("Get() = %q, want page 1 confirmed by a 304", got)
+		}
+	}
+	if hits != 3 || notModified != 2 {
+		t.Errorf("Server got %d requests and sent %d 304s, want 3 and 2", hits, notModified)
+	}
+	if stats := cache.Stats(); stats.Revalidated != 2 {
+		t.Errorf("Revalidated = %d, want 2", stats.Revalidated)
+	}
+}
+
+func TestHTTPCache_Vary(t *testing.T) {
+	var hits int32
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Header().Set("Vary", "Accept-Language")
+		return true
+	})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPCache: crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{})})
+
+	english := map[string]string{"Accept-Language": "en"}
+	getHeading(t, client, server.URL, english)
+	getHeading(t, client, server.URL, english)
+	if hits != 1 {
+		t.Fatalf("Server got %d requests, want 1 for the same language", hits)
+	}
+	getHeading(t, client, server.URL, map[string]string{"Accept-Language": "fr"})
+	if hits != 2 {
+		t.Errorf("Server got %d requests, want 2 for another language", hits)
+	}
+}
+
+func TestHTTPCache_Heuristic(t *testing.T) {
+	var hits int32
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Last-Modified", time.Now().Add(-100*time.Hour).UTC().Format(http.TimeFormat))
+		return true
+	})
+	fake := clock.NewFake(time.Now())
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{
+		HTTPCache: crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{HeuristicTTL: time.Hour, Clock: fake}),
+	})
+
+	getHeading(t, client, server.URL, nil)
+	fake.Advance(30 * time.Minute)
+	getHeading(t, client, server.URL, nil)
+	if hits != 1 {
+		t.Fatalf("Server got %d requests, want 1 within the heuristic freshness", hits)
+	}
+
+	// 10% of 100 hours is capped at HeuristicTTL
+	fake.Advance(31 * time.Minute)
+	getHeading(t, client, server.URL, nil)
+	if hits != 2 {
+		t.Errorf("Server got %d requests, want 2 past HeuristicTTL", hits)
+	}
+}
+
+func TestHTTPCache_PostInvalidates(t *testing.T) {
+	var hits int32
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Cache-Control", "max-age=60")
+		return true
+	})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPCache: crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{})})
+
+	getHeading(t, client, server.URL, nil)
+	if _, err := client.Post(server.URL, map[string]string{"q": "1"}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if got := getHeading(t, client, server.URL, nil); got != "page 3" {
+		t.Errorf("Get() = %q, want a fresh page 3 after the POST", got)
+	}
+}
+
+func TestHTTPCache_BlockedNotStored(t *testing.T) {
+	var hits int32
+	server := newCachingServer(t, &hits, func(w http.ResponseWriter, r *http.Request) bool {
+		w.Header().Set("Cache-Control", "max-age=60")
+		_, _ = w.Write([]byte(`<html><body><script>window._cf_chl_opt = {}</script></body></html>`))
+		return false
+	})
+	cache := crawlers.NewHTTPCache(crawlers.HTTPCacheConfig{})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{
+		HTTPCache:     cache,
+		BlockDetector: crawlers.NewBlockDetector(nil),
+	})
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Get(server.URL); err == nil {
+			t.Fatal("Expected the bot wall to fail Get")
+		}
+	}
+	if hits != 2 || cache.Stats().Stored != 0 {
+		t.Errorf("Expected bot walls not to be cached, got %d requests and %+v", hits, cache.Stats())
+	}
+}
+
+func TestHTTPCache_Stores(t *testing.T) {
+	resp := &crawlers.CachedResponse{
+		URL:        "https://example.com/page",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}},
+		Body:       []byte("<html></html>"),
+	}
+	stores := map[string]crawlers.HTTPCacheStore{
+		"memory": crawlers.NewMemoryHTTPCacheStore(10),
+		"cache":  crawlers.NewCacheHTTPCacheStore(&mocks.MockCacheClient{}),
+		"file":   crawlers.NewFileHTTPCacheStore(filepath.Join(t.TempDir(), "http-cache")),
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			if got, err := store.GetResponse(resp.URL); err != nil || got != nil {
+				t.Fatalf("GetResponse() = %v, %v; want nil before SetResponse", got, err)
+			}
+			if err := store.SetResponse(resp.URL, resp, time.Minute); err != nil {
+				t.Fatalf("SetResponse() error = %v", err)
+			}
+			got, err := store.GetResponse(resp.URL)
+			if err != nil || got == nil || string(got.Body) != string(resp.Body) || got.Header.Get("Content-Type") != "text/html" {
+				t.Fatalf("GetResponse() = %+v, %v; want the stored response", got, err)
+			}
+			if err := store.DeleteResponse(resp.URL); err != nil {
+				t.Fatalf("DeleteResponse() error = %v", err)
+			}
+			if got, _ := store.GetResponse(resp.URL); got != nil {
+				t.Error("Expected no response after DeleteResponse")
+			}
+		})
+	}
+}
+
+func TestHTTPCache_FileStoreExpires(t *testing.T) {
+	store := crawlers.NewFileHTTPCacheStore(t.TempDir())
+	resp := &crawlers.CachedResponse{URL: "https://example.com/", StatusCode: http.StatusOK}
+	if err := store.SetResponse(resp.URL, resp, -time.Second); err != nil {
+		t.Fatalf("SetResponse() error = %v", err)
+	}
+	if got, err := store.GetResponse(resp.URL); err != nil || got != nil {
+		t.Errorf("GetResponse() = %v, %v; want nil once expired", got, err)
+	}
+}
+
+func TestNewHTTPCacheFromConfig(t *testing.T) {
+	if cache := crawlers.NewHTTPCacheFromConfig(configs.HTTPCacheConfig{}, nil); cache != nil {
+		t.Error("Expected nil when disabled")
+	}
+	if cache := crawlers.NewHTTPCacheFromConfig(configs.HTTPCacheConfig{Enabled: true}, nil); cache == nil {
+		t.Error("Expected an in-memory cache when enabled without a store")
+	}
+
+	var nilCache *crawlers.HTTPCache
+	if err := nilCache.Invalidate("https://example.com/"); err != nil {
+		t.Errorf("Invalidate() on nil error = %v", err)
+	}
+}
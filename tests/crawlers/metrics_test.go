@@ -0,0 +1,141 @@
+package crawlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Crawler Metrics Tests
+// =============================================================================
+
+// recordingMetrics counts crawler metric observations by label
+type recordingMetrics struct {
+	mu        sync.Mutex
+	requests  map[string]int // crawler_type/status
+	errors    map[string]int // crawler_type/error_type
+	durations int
+}
+
+func newRecordingMetrics() *recordingMetrics {
+	return &recordingMetrics{requests: make(map[string]int), errors: make(map[string]int)}
+}
+
+func (m *recordingMetrics) RecordCrawlerRequest(crawlerType, status string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[crawlerType+"/"+status]++
+}
+
+func (m *recordingMetrics) RecordCrawlerDuration(string, time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.durations++
+}
+
+func (m *recordingMetrics) RecordCrawlerError(crawlerType, errorType string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.errors[crawlerType+"/"+errorType]++
+}
+
+// newMetricsServer serves a page at / and a 503 at /down
+func newMetricsServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body><a href="/down">down</a></body></html>`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSoupClient_Metrics(t *testing.T) {
+	server := newMetricsServer(t)
+	metrics := newRecordingMetrics()
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Metrics: metrics})
+
+	if _, err := client.Get(server.URL + "/"); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	_, _ = client.Get(server.URL + "/down")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetContext(ctx, server.URL+"/slow"); err == nil {
+		t.Fatal("Expected the slow request to time out")
+	}
+
+	if metrics.requests["soup/2xx"] != 1 || metrics.requests["soup/5xx"] != 1 || metrics.requests["soup/error"] != 1 {
+		t.Errorf("Requests = %v", metrics.requests)
+	}
+	if metrics.errors["soup/http_5xx"] != 1 || metrics.errors["soup/timeout"] != 1 {
+		t.Errorf("Errors = %v", metrics.errors)
+	}
+	if metrics.durations != 3 {
+		t.Errorf("Durations = %d, want 3", metrics.durations)
+	}
+}
+
+func TestCollyClient_Metrics(t *testing.T) {
+	server := newMetricsServer(t)
+	metrics := newRecordingMetrics()
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 2, Metrics: metrics})
+	client.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		_ = e.Request.Visit(e.Attr("href"))
+	})
+
+	if err := client.Visit(server.URL + "/"); err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+	client.Wait()
+
+	if metrics.requests["colly/2xx"] != 1 || metrics.requests["colly/5xx"] != 1 {
+		t.Errorf("Requests = %v", metrics.requests)
+	}
+	if metrics.errors["colly/http_5xx"] != 1 {
+		t.Errorf("Errors = %v", metrics.errors)
+	}
+}
+
+func TestSpider_Metrics(t *testing.T) {
+	server := newMetricsServer(t)
+	metrics := newRecordingMetrics()
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 2, Concurrency: 1, Metrics: metrics})
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if metrics.requests["spider/2xx"] != 1 || metrics.requests["spider/5xx"] != 1 {
+		t.Errorf("Requests = %v", metrics.requests)
+	}
+	if metrics.errors["spider/http_5xx"] != 1 {
+		t.Errorf("Errors = %v", metrics.errors)
+	}
+}
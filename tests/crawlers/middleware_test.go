@@ -0,0 +1,235 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/crawlers"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// =============================================================================
+// Crawler Middleware Tests
+// =============================================================================
+
+// tracing returns middleware appending name to trace before and after the
+// fetch it wraps
+func tracing(name string, trace *[]string) crawlers.Middleware {
+	return func(next crawlers.Fetcher) crawlers.Fetcher {
+		return crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+			*trace = append(*trace, name+">")
+			result, err := next.Fetch(ctx, url)
+			*trace = append(*trace, "<"+name)
+			return result, err
+		})
+	}
+}
+
+func TestChain_Order(t *testing.T) {
+	var trace []string
+	f := crawlers.Chain(crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+		trace = append(trace, "fetch")
+		return &crawlers.Result{URL: url}, nil
+	}), tracing("a", &trace), tracing("b", &trace))
+
+	if _, err := f.Fetch(context.Background(), "https://example.com"); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	want := []string{"a>", "b>", "fetch", "<b", "<a"}
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("Expected %v, got %v", want, trace)
+	}
+}
+
+func TestCrawler_Use(t *testing.T) {
+	server := newCrawlerServer(t)
+
+	for _, c := range httpCrawlers() {
+		t.Run(c.Engine(), func(t *testing.T) {
+			var seen *crawlers.Result
+			c.Use(func(next crawlers.Fetcher) crawlers.Fetcher {
+				return crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+					result, err := next.Fetch(ctx, url)
+					seen = result
+					return result, err
+				})
+			})
+
+			result, err := c.Fetch(context.Background(), server.URL)
+			if err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if seen != result {
+				t.Fatal("Expected the middleware to see the result")
+			}
+			if seen.Engine != c.Engine() || seen.Duration <= 0 {
+				t.Errorf("Expected a stamped result, got engine %q and duration %v", seen.Engine, seen.Duration)
+			}
+		})
+	}
+}
+
+func TestCrawler_MiddlewareShortCircuit(t *testing.T) {
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	denied := errors.New("denied")
+	c.Use(func(next crawlers.Fetcher) crawlers.Fetcher {
+		return crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+			return nil, denied
+		})
+	})
+
+	var failed int
+	c.OnFetchError(func(string, error) { failed++ })
+	if _, err := c.Fetch(context.Background(), "http://127.0.0.1:1"); !errors.Is(err, denied) {
+		t.Errorf("Expected the middleware's error, got %v", err)
+	}
+	if failed != 1 {
+		t.Errorf("Expected OnFetchError once, got %d", failed)
+	}
+}
+
+func TestHeadersMiddleware(t *testing.T) {
+	var auth, tenant atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Authorization"))
+		tenant.Store(r.Header.Get("X-Tenant"))
+		_, _ = w.Write([]byte("<html></html>"))
+	}))
+	defer server.Close()
+
+	for _, c := range httpCrawlers() {
+		t.Run(c.Engine(), func(t *testing.T) {
+			c.Use(crawlers.HeadersMiddleware(map[string]string{"Authorization": "Bearer token", "X-Tenant": "default"}))
+
+			ctx := crawlers.WithRequestOptions(context.Background(), crawlers.RequestOptions{
+				Headers: map[string]string{"X-Tenant": "acme"},
+			})
+			if _, err := c.Fetch(ctx, server.URL); err != nil {
+				t.Fatalf("Fetch failed: %v", err)
+			}
+			if got := auth.Load(); got != "Bearer token" {
+				t.Errorf("Expected the Authorization header, got %v", got)
+			}
+			if got := tenant.Load(); got != "acme" {
+				t.Errorf("Expected the context's X-Tenant to win, got %v", got)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	server := newCrawlerServer(t)
+	core, logs := observer.New(zap.DebugLevel)
+
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	c.Use(crawlers.LoggingMiddleware(zap.New(core)))
+
+	if _, err := c.Fetch(context.Background(), server.URL); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if _, err := c.Fetch(context.Background(), "http://127.0.0.1:1"); err == nil {
+		t.Fatal("Expected an error")
+	}
+
+	if n := logs.FilterMessage("Fetched page").FilterField(zap.String("engine", "soup")).Len(); n != 1 {
+		t.Errorf("Expected one success entry, got %d", n)
+	}
+	if n := logs.FilterMessage("Fetch failed").Len(); n != 1 {
+		t.Errorf("Expected one failure entry, got %d", n)
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	server := newCrawlerServer(t)
+	metrics := newRecordingMetrics()
+
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	c.Use(crawlers.MetricsMiddleware(metrics, crawlers.CrawlerTypeSoup))
+
+	_, _ = c.Fetch(context.Background(), server.URL)
+	_, _ = c.Fetch(context.Background(), server.URL+"/missing")
+
+	if metrics.requests["soup/2xx"] != 1 || metrics.requests["soup/4xx"] != 1 {
+		t.Errorf("Expected one 2xx and one 4xx, got %v", metrics.requests)
+	}
+	if metrics.durations != 2 {
+		t.Errorf("Expected 2 durations, got %d", metrics.durations)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{MaxConcurrent: 1})
+	var inFlight, peak int32
+	f := crawlers.Chain(crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return &crawlers.Result{URL: url}, nil
+	}), crawlers.RateLimitMiddleware(limiter, nil))
+
+	done := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, _ = f.Fetch(context.Background(), "https://example.com/")
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		<-done
+	}
+	if p := atomic.LoadInt32(&peak); p != 1 {
+		t.Errorf("Expected one fetch at a time, got %d", p)
+	}
+}
+
+func TestCacheMiddleware(t *testing.T) {
+	server := newCrawlerServer(t)
+	lru, err := cache.NewLRUCache(10)
+	if err != nil {
+		t.Fatalf("NewLRUCache failed: %v", err)
+	}
+
+	var fetched int
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	c.Use(crawlers.CacheMiddleware(lru, time.Minute), func(next crawlers.Fetcher) crawlers.Fetcher {
+		return crawlers.FetcherFunc(func(ctx context.Context, url string) (*crawlers.Result, error) {
+			fetched++
+			return next.Fetch(ctx, url)
+		})
+	})
+
+	for i := 0; i < 2; i++ {
+		result, err := c.Fetch(context.Background(), server.URL)
+		if err != nil {
+			t.Fatalf("Fetch failed: %v", err)
+		}
+		if len(result.Body) == 0 {
+			t.Error("Expected a body")
+		}
+	}
+	if fetched != 1 {
+		t.Errorf("Expected the second fetch from the cache, got %d fetches", fetched)
+	}
+
+	// Error statuses are not cached
+	for i := 0; i < 2; i++ {
+		_, _ = c.Fetch(context.Background(), server.URL+"/missing")
+	}
+	if fetched != 3 {
+		t.Errorf("Expected 404s to be fetched every time, got %d fetches", fetched)
+	}
+}
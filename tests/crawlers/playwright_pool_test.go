@@ -0,0 +1,72 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Playwright Pool Tests
+// =============================================================================
+
+func TestNewPlaywrightPool_UnsupportedBrowser(t *testing.T) {
+	_, err := crawlers.NewPlaywrightPool(crawlers.PlaywrightPoolConfig{BrowserType: "netscape"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported browser type") {
+		t.Errorf("NewPlaywrightPool() error = %v, want unsupported browser type", err)
+	}
+}
+
+func TestPlaywrightPool_CheckoutCheckin(t *testing.T) {
+	pool, err := crawlers.NewPlaywrightPool(crawlers.PlaywrightPoolConfig{Headless: true, Size: 2})
+	if err != nil {
+		t.Skipf("Skipping Playwright pool tests: browser not available (%v)", err)
+	}
+	defer pool.Close()
+
+	ctx := context.Background()
+	first, err := pool.Checkout(ctx)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	second, err := pool.Checkout(ctx)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if stats := pool.Stats(); stats.InUse != 2 || stats.Idle != 0 {
+		t.Errorf("Stats() = %+v, want 2 in use", stats)
+	}
+
+	// An exhausted pool waits until ctx is done
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Checkout(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Checkout() on exhausted pool error = %v, want deadline exceeded", err)
+	}
+
+	second.Discard()
+	pool.Checkin(first)
+	pool.Checkin(second)
+	pool.Checkin(second) // Double checkin is ignored
+	if stats := pool.Stats(); stats.Idle != 2 {
+		t.Errorf("Stats() = %+v, want 2 idle", stats)
+	}
+
+	// Discarded pages are replaced on checkout
+	for i := 0; i < 2; i++ {
+		page, err := pool.Checkout(ctx)
+		if err != nil {
+			t.Fatalf("Checkout() after discard error = %v", err)
+		}
+		defer pool.Checkin(page)
+	}
+
+	_ = pool.Close()
+	if _, err := pool.Checkout(ctx); !errors.Is(err, crawlers.ErrPoolClosed) {
+		t.Errorf("Checkout() after Close error = %v, want ErrPoolClosed", err)
+	}
+}
@@ -0,0 +1,73 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Playwright Routing Tests
+// =============================================================================
+
+func TestPlaywrightClient_Routing(t *testing.T) {
+	var trackerHits atomic.Int32
+	var gotHeader atomic.Value
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tracker.js":
+			trackerHits.Add(1)
+		case "/":
+			gotHeader.Store(r.Header.Get("X-Golwarc"))
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><body>
+				<script src="/tracker.js"></script>
+				<div id="out"></div>
+				<script>fetch('/api/data').then(r => r.text()).then(t => document.getElementById('out').textContent = t)</script>
+			</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true})
+	if err != nil {
+		t.Skipf("Skipping Playwright routing tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	if err := client.BlockRequests("**/tracker.js"); err != nil {
+		t.Fatalf("BlockRequests() error = %v", err)
+	}
+	if err := client.MockRequests("**/api/data", crawlers.MockResponse{ContentType: "text/plain", Body: "stubbed"}); err != nil {
+		t.Fatalf("MockRequests() error = %v", err)
+	}
+	if err := client.SetRequestHeaders("**/*", map[string]string{"X-Golwarc": "yes"}); err != nil {
+		t.Fatalf("SetRequestHeaders() error = %v", err)
+	}
+
+	if err := client.Navigate(server.URL + "/"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	if err := client.WaitForSelector("#out:has-text('stubbed')"); err != nil {
+		t.Fatalf("Mocked API response not rendered: %v", err)
+	}
+
+	if trackerHits.Load() != 0 {
+		t.Errorf("Blocked tracker was requested %d times", trackerHits.Load())
+	}
+	if header, _ := gotHeader.Load().(string); header != "yes" {
+		t.Errorf("X-Golwarc header = %q, want yes", header)
+	}
+
+	if err := client.UnrouteAll(); err != nil {
+		t.Errorf("UnrouteAll() error = %v", err)
+	}
+	content, _ := client.GetContent()
+	if !strings.Contains(content, "stubbed") {
+		t.Errorf("GetContent() = %q, want mocked body", content)
+	}
+}
@@ -0,0 +1,86 @@
+package crawlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Playwright Infinite Scroll Tests
+// =============================================================================
+
+// infiniteScrollPage loads one more screen of items from /items whenever the
+// bottom is reached, until the server has no more
+const infiniteScrollPage = `<html><body><div id="list"></div><script>
+let page = 0, loading = false;
+async function more() {
+	if (loading) return;
+	loading = true;
+	const html = await (await fetch('/items?page=' + page)).text();
+	if (html) {
+		document.getElementById('list').insertAdjacentHTML('beforeend', html);
+		page++;
+	}
+	loading = false;
+}
+window.addEventListener('scroll', () => {
+	if (window.innerHeight + window.scrollY >= document.body.scrollHeight - 10) more();
+});
+more();
+</script></body></html>`
+
+func TestPlaywrightClient_ScrollToBottomUntilStable(t *testing.T) {
+	const pages = 4
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/items" {
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(infiniteScrollPage))
+			return
+		}
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page >= pages {
+			return
+		}
+		time.Sleep(100 * time.Millisecond) // Slower than the scroll wait, so only network idle catches it
+		for i := 0; i < 10; i++ {
+			_, _ = fmt.Fprintf(w, `<div class="item" style="height:400px">item %d</div>`, page*10+i)
+		}
+	}))
+	defer server.Close()
+
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Skipf("Skipping Playwright scroll tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	if err := client.Navigate(server.URL + "/"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.WaitForNetworkIdle(ctx, 0); err != nil {
+		t.Fatalf("WaitForNetworkIdle() error = %v", err)
+	}
+
+	scrolls, err := client.ScrollToBottomUntilStable(20, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("ScrollToBottomUntilStable() error = %v", err)
+	}
+	if scrolls >= 20 {
+		t.Errorf("ScrollToBottomUntilStable() made %d scrolls, want it to stop once stable", scrolls)
+	}
+
+	content, _ := client.GetContent()
+	if n := strings.Count(content, `class="item"`); n != pages*10 {
+		t.Errorf("Rendered %d items, want %d", n, pages*10)
+	}
+}
@@ -0,0 +1,52 @@
+package crawlers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Playwright Session Tests
+// =============================================================================
+
+func TestPlaywrightClient_SessionsAreIsolated(t *testing.T) {
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true})
+	if err != nil {
+		t.Skipf("Skipping Playwright session tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	first, err := client.NewSession(crawlers.SessionOptions{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	defer first.Close()
+	second, err := client.NewSession(crawlers.SessionOptions{UserAgent: "golwarc-test"})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	defer second.Close()
+
+	if err := first.AddCookie("session", "one", "example.com"); err != nil {
+		t.Fatalf("AddCookie() error = %v", err)
+	}
+
+	cookies, err := second.GetCookies()
+	if err != nil {
+		t.Fatalf("GetCookies() error = %v", err)
+	}
+	if len(cookies) != 0 {
+		t.Errorf("Second session sees %d cookies, want 0", len(cookies))
+	}
+
+	cookies, err = first.GetCookies()
+	if err != nil || len(cookies) != 1 {
+		t.Errorf("First session cookies = %v, %v; want 1", cookies, err)
+	}
+
+	if agent, err := second.Evaluate("navigator.userAgent"); err != nil || agent != "golwarc-test" {
+		t.Errorf("User agent = %v, %v; want golwarc-test", agent, err)
+	}
+}
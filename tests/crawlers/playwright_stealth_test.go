@@ -0,0 +1,115 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Playwright Stealth Tests
+// =============================================================================
+
+// fingerprintScript collects what bot walls commonly read from a page
+const fingerprintScript = `() => {
+	const canvas = document.createElement('canvas');
+	const gl = canvas.getContext('webgl');
+	let vendor = '', renderer = '';
+	if (gl) {
+		vendor = gl.getParameter(37445);
+		renderer = gl.getParameter(37446);
+	}
+	return {
+		webdriver: navigator.webdriver,
+		userAgent: navigator.userAgent,
+		languages: navigator.languages.join(','),
+		platform: navigator.platform,
+		timezone: Intl.DateTimeFormat().resolvedOptions().timeZone,
+		width: window.innerWidth,
+		height: window.innerHeight,
+		chrome: typeof window.chrome,
+		webgl: !!gl,
+		vendor: String(vendor),
+		renderer: String(renderer),
+	};
+}`
+
+func TestPlaywrightClient_Stealth(t *testing.T) {
+	var acceptLanguage string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptLanguage = r.Header.Get("Accept-Language")
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><body>fingerprint</body></html>"))
+	}))
+	defer server.Close()
+
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{
+		Headless: true,
+		Timeout:  10 * time.Second,
+		Stealth: crawlers.StealthConfig{
+			Enabled:        true,
+			ViewportWidth:  1440,
+			ViewportHeight: 900,
+			Locale:         "de-DE",
+			TimezoneID:     "Europe/Berlin",
+			Platform:       "Win32",
+			WebGLVendor:    "Intel Inc.",
+			WebGLRenderer:  "Intel Iris OpenGL Engine",
+		},
+	})
+	if err != nil {
+		t.Skipf("Skipping Playwright stealth tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	if err := client.Navigate(server.URL + "/"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	result, err := client.Evaluate(fingerprintScript)
+	if err != nil {
+		t.Fatalf("Evaluate() error = %v", err)
+	}
+	fp, ok := result.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Unexpected fingerprint %T", result)
+	}
+
+	if fp["webdriver"] != false {
+		t.Errorf("Expected navigator.webdriver to be false, got %v", fp["webdriver"])
+	}
+	if ua, _ := fp["userAgent"].(string); strings.Contains(ua, "Headless") {
+		t.Errorf("Expected the user agent to hide headless mode, got %q", ua)
+	}
+	if fp["languages"] != "de-DE,de" || !strings.HasPrefix(acceptLanguage, "de-DE") {
+		t.Errorf("Expected German languages, got %v and Accept-Language %q", fp["languages"], acceptLanguage)
+	}
+	if fp["platform"] != "Win32" || fp["timezone"] != "Europe/Berlin" {
+		t.Errorf("Unexpected platform or time zone: %v, %v", fp["platform"], fp["timezone"])
+	}
+	if fp["width"] != 1440 || fp["height"] != 900 {
+		t.Errorf("Expected a 1440x900 viewport, got %vx%v", fp["width"], fp["height"])
+	}
+	if fp["chrome"] != "object" {
+		t.Errorf("Expected window.chrome to exist, got %v", fp["chrome"])
+	}
+	if fp["webgl"] == true && (fp["vendor"] != "Intel Inc." || fp["renderer"] != "Intel Iris OpenGL Engine") {
+		t.Errorf("Expected the WebGL overrides, got %v / %v", fp["vendor"], fp["renderer"])
+	}
+
+	// Pages opened later get the same fingerprint
+	page, err := client.NewPage()
+	if err != nil {
+		t.Fatalf("NewPage() error = %v", err)
+	}
+	defer page.Close()
+	if _, err := page.Goto(server.URL + "/"); err != nil {
+		t.Fatalf("Goto() error = %v", err)
+	}
+	if platform, err := page.Evaluate("() => navigator.platform"); err != nil || platform != "Win32" {
+		t.Errorf("Expected NewPage to apply the overrides, got %v (%v)", platform, err)
+	}
+}
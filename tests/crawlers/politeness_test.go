@@ -0,0 +1,287 @@
+package crawlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// The politeness compliance suite runs every fetcher implementation against a
+// server that records when requests arrive and how many overlap, and asserts
+// the shared rate limit, connection cap and throttling backoff are honored
+
+// =============================================================================
+// Compliance Server
+// =============================================================================
+
+// complianceHit is one request seen by the compliance server
+type complianceHit struct {
+	path string
+	at   time.Time
+}
+
+// complianceServer records request arrival times and overlap
+type complianceServer struct {
+	*httptest.Server
+
+	hold      time.Duration // How long each response takes
+	throttleN int           // The first throttleN requests are answered 429
+
+	mu          sync.Mutex
+	hits        []complianceHit
+	inFlight    int
+	maxInFlight int
+	throttledAt time.Time // When the last 429 was sent
+}
+
+// newComplianceServer starts a compliance server
+func newComplianceServer(t *testing.T, hold time.Duration, throttleN int) *complianceServer {
+	t.Helper()
+	s := &complianceServer{hold: hold, throttleN: throttleN}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *complianceServer) handle(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.hits = append(s.hits, complianceHit{path: r.URL.Path, at: time.Now()})
+	throttle := len(s.hits) <= s.throttleN
+	s.inFlight++
+	s.maxInFlight = max(s.maxInFlight, s.inFlight)
+	s.mu.Unlock()
+
+	time.Sleep(s.hold)
+
+	s.mu.Lock()
+	s.inFlight--
+	if throttle {
+		s.throttledAt = time.Now()
+	}
+	s.mu.Unlock()
+
+	if throttle {
+		w.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html")
+	_, _ = fmt.Fprintf(w, "<html><body>%s</body></html>", r.URL.Path)
+}
+
+// urls returns n distinct page URLs on the server
+func (s *complianceServer) urls(n int) []string {
+	urls := make([]string, n)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/page/%d", s.URL, i)
+	}
+	return urls
+}
+
+// snapshot returns the recorded hits, peak concurrency and last 429 time
+func (s *complianceServer) snapshot() ([]complianceHit, int, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]complianceHit(nil), s.hits...), s.maxInFlight, s.throttledAt
+}
+
+// waitRecorder is a clock whose time stands still and whose timers fire at
+// once, recording every wait a limiter asks for without sleeping
+type waitRecorder struct {
+	*clock.Fake
+
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+// NewTimer records d and returns a timer that has already fired
+func (r *waitRecorder) NewTimer(d time.Duration) clock.Timer {
+	r.mu.Lock()
+	r.waits = append(r.waits, d)
+	r.mu.Unlock()
+
+	return r.Fake.NewTimer(0)
+}
+
+// sorted returns the recorded waits, shortest first
+func (r *waitRecorder) sorted() []time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	waits := append([]time.Duration(nil), r.waits...)
+	slices.Sort(waits)
+	return waits
+}
+
+// =============================================================================
+// Fetchers Under Test
+// =============================================================================
+
+// politeness holds the settings every fetcher is configured with
+type politeness struct {
+	limiter    *crawlers.RateLimiter
+	cooldown   *crawlers.DomainCooldown
+	sequential bool // One request at a time
+}
+
+// politeFetcher fetches URLs with one fetcher implementation
+type politeFetcher struct {
+	name  string
+	fetch func(t *testing.T, polite politeness, urls []string)
+}
+
+// politeFetchers returns every fetcher implementation under test
+// Errors are ignored; throttled fetches fail by design
+func politeFetchers() []politeFetcher {
+	return []politeFetcher{
+		{name: "soup", fetch: func(t *testing.T, polite politeness, urls []string) {
+			client := crawlers.NewSoupClient(crawlers.SoupConfig{RateLimiter: polite.limiter, Cooldown: polite.cooldown})
+			if polite.sequential {
+				for _, u := range urls {
+					_, _ = client.Get(u)
+				}
+				return
+			}
+			var wg sync.WaitGroup
+			for _, u := range urls {
+				wg.Add(1)
+				go func(u string) {
+					defer wg.Done()
+					_, _ = client.Get(u)
+				}(u)
+			}
+			wg.Wait()
+		}},
+		{name: "colly", fetch: func(t *testing.T, polite politeness, urls []string) {
+			client := crawlers.NewCollyClient(crawlers.CollyConfig{
+				Async:       !polite.sequential,
+				RateLimiter: polite.limiter,
+				Cooldown:    polite.cooldown,
+			})
+			for _, u := range urls {
+				_ = client.Visit(u)
+			}
+			client.Wait()
+		}},
+		{name: "spider", fetch: func(t *testing.T, polite politeness, urls []string) {
+			concurrency := len(urls)
+			if polite.sequential {
+				concurrency = 1
+			}
+			spider := crawlers.NewSpider(crawlers.SpiderConfig{
+				Concurrency: concurrency,
+				RateLimiter: polite.limiter,
+				Cooldown:    polite.cooldown,
+			})
+			spider.OnDocument(func(*goquery.Document, string) error { return nil })
+			for _, u := range urls {
+				spider.AddStartURL(u)
+			}
+			if err := spider.Run(); err != nil {
+				t.Errorf("Run() error = %v", err)
+			}
+		}},
+	}
+}
+
+// =============================================================================
+// Compliance Tests
+// =============================================================================
+
+func TestPoliteness_RequestDelay(t *testing.T) {
+	const delay = 40 * time.Millisecond
+
+	for _, fetcher := range politeFetchers() {
+		t.Run(fetcher.name, func(t *testing.T) {
+			server := newComplianceServer(t, 0, 0)
+			recorder := &waitRecorder{Fake: clock.NewFake(time.Time{})}
+			limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: delay, Clock: recorder})
+			fetcher.fetch(t, politeness{limiter: limiter}, server.urls(5))
+
+			hits, _, _ := server.snapshot()
+			if len(hits) != 5 {
+				t.Fatalf("Server saw %d requests, want 5", len(hits))
+			}
+			// Time stands still, so each request after the first waits one
+			// more delay than the one before it
+			want := []time.Duration{delay, 2 * delay, 3 * delay, 4 * delay}
+			if waits := recorder.sorted(); !slices.Equal(waits, want) {
+				t.Errorf("Limiter waits = %v, want %v", waits, want)
+			}
+		})
+	}
+}
+
+func TestPoliteness_ConnectionCap(t *testing.T) {
+	for _, fetcher := range politeFetchers() {
+		t.Run(fetcher.name, func(t *testing.T) {
+			server := newComplianceServer(t, 20*time.Millisecond, 0)
+			limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{MaxConcurrent: 2})
+			fetcher.fetch(t, politeness{limiter: limiter}, server.urls(8))
+
+			hits, peak, _ := server.snapshot()
+			if len(hits) != 8 {
+				t.Fatalf("Server saw %d requests, want 8", len(hits))
+			}
+			if peak > 2 {
+				t.Errorf("Peak concurrent connections = %d, want at most 2", peak)
+			}
+		})
+	}
+}
+
+func TestPoliteness_SharedConnectionCap(t *testing.T) {
+	// Every fetcher shares one limiter, so together they keep the cap
+	server := newComplianceServer(t, 10*time.Millisecond, 0)
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{MaxConcurrent: 1})
+
+	var wg sync.WaitGroup
+	for _, fetcher := range politeFetchers() {
+		wg.Add(1)
+		go func(fetcher politeFetcher) {
+			defer wg.Done()
+			urls := server.urls(4)
+			for i := range urls {
+				urls[i] += "?fetcher=" + fetcher.name
+			}
+			fetcher.fetch(t, politeness{limiter: limiter}, urls)
+		}(fetcher)
+	}
+	wg.Wait()
+
+	hits, peak, _ := server.snapshot()
+	if len(hits) != 12 {
+		t.Fatalf("Server saw %d requests, want 12", len(hits))
+	}
+	if peak != 1 {
+		t.Errorf("Peak concurrent connections = %d, want 1", peak)
+	}
+}
+
+func TestPoliteness_ThrottleBackoff(t *testing.T) {
+	const backoff = 100 * time.Millisecond
+
+	for _, fetcher := range politeFetchers() {
+		t.Run(fetcher.name, func(t *testing.T) {
+			server := newComplianceServer(t, 0, 1)
+			cooldown := crawlers.NewDomainCooldown(crawlers.CooldownConfig{BaseDelay: backoff})
+			fetcher.fetch(t, politeness{cooldown: cooldown, sequential: true}, server.urls(3))
+
+			hits, _, throttledAt := server.snapshot()
+			if len(hits) < 2 {
+				t.Fatalf("Server saw %d requests, want the crawl to continue after the 429", len(hits))
+			}
+			for _, hit := range hits[1:] {
+				if wait := hit.at.Sub(throttledAt); wait < backoff*3/4 {
+					t.Errorf("%s requested %v after the 429, want at least %v", hit.path, wait, backoff)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package crawlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Proxy Health Tests
+// =============================================================================
+
+// newToggleProxy returns a proxy that answers the canary with 200 while up
+// and 502 otherwise
+func newToggleProxy(t *testing.T, up *atomic.Bool) *httptest.Server {
+	t.Helper()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestProxyPool_CheckHealth(t *testing.T) {
+	var hits int32
+	good := newTestProxy(t, &hits)
+	var up atomic.Bool
+	failing := newToggleProxy(t, &up)
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close()
+
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{
+		Proxies:     []string{good.URL, failing.URL, deadURL},
+		MaxFailures: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+	config := crawlers.ProxyHealthConfig{CanaryURL: "http://canary.invalid/health", Timeout: 2 * time.Second}
+
+	for round := 0; round < 2; round++ {
+		if _, err := pool.CheckHealth(context.Background(), config); err != nil {
+			t.Fatalf("CheckHealth() error = %v", err)
+		}
+	}
+
+	if pool.Available() != 1 {
+		t.Fatalf("Available() = %d, want only the good proxy", pool.Available())
+	}
+	stats := pool.Stats()
+	if stats[0].Removed || stats[0].Probes != 2 || stats[0].ProbeFailures != 0 || stats[0].Latency <= 0 {
+		t.Errorf("Unexpected stats for good proxy: %+v", stats[0])
+	}
+	if stats[0].Score <= 0 || stats[0].Score > 1 {
+		t.Errorf("Score = %v, want within (0, 1]", stats[0].Score)
+	}
+	for _, s := range stats[1:] {
+		if !s.Removed || s.ProbeFailures != 2 || s.LastError == "" || s.LastProbe.IsZero() {
+			t.Errorf("Expected %s to be removed after failed probes, got %+v", s.URL, s)
+		}
+		if s.Score >= stats[0].Score {
+			t.Errorf("Expected %s to score below the good proxy", s.URL)
+		}
+	}
+}
+
+func TestProxyPool_CheckHealth_Recovers(t *testing.T) {
+	var up atomic.Bool
+	proxy := newToggleProxy(t, &up)
+
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{Proxies: []string{proxy.URL}, MaxFailures: 1})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+	config := crawlers.ProxyHealthConfig{CanaryURL: "http://canary.invalid/", RecoverAfter: 2}
+	ctx := context.Background()
+
+	_, _ = pool.CheckHealth(ctx, config)
+	if pool.Available() != 0 {
+		t.Fatal("Expected the proxy to be removed while down")
+	}
+	if _, err := pool.Next("https://example.com/"); err == nil {
+		t.Error("Expected no proxy while all are removed")
+	}
+
+	up.Store(true)
+	_, _ = pool.CheckHealth(ctx, config)
+	if pool.Available() != 0 {
+		t.Error("Expected one passed probe not to be enough")
+	}
+	stats, _ := pool.CheckHealth(ctx, config)
+	if pool.Available() != 1 || stats[0].LastError != "" {
+		t.Errorf("Expected the proxy back after two passed probes, got %+v", stats[0])
+	}
+}
+
+func TestProxyPool_CheckHealth_InvalidCanary(t *testing.T) {
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{Proxies: []string{"http://p1:8080"}})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+	if _, err := pool.CheckHealth(context.Background(), crawlers.ProxyHealthConfig{}); errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("CheckHealth() error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+	if err := pool.MonitorHealth(context.Background(), crawlers.ProxyHealthConfig{CanaryURL: "ftp://canary"}); err == nil {
+		t.Error("Expected MonitorHealth to reject a non-http canary")
+	}
+}
+
+func TestProxyPool_MonitorHealth(t *testing.T) {
+	var up atomic.Bool
+	up.Store(true)
+	proxy := newToggleProxy(t, &up)
+
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{Proxies: []string{proxy.URL}})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- pool.MonitorHealth(ctx, crawlers.ProxyHealthConfig{
+			CanaryURL: "http://canary.invalid/",
+			Interval:  10 * time.Millisecond,
+		})
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for pool.Stats()[0].Probes < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("MonitorHealth() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected MonitorHealth to return once ctx is done")
+	}
+	if probes := pool.Stats()[0].Probes; probes < 3 {
+		t.Errorf("Probes = %d, want at least 3", probes)
+	}
+}
+
+func TestProxyPool_ScoredStrategy(t *testing.T) {
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{
+		Proxies:     []string{"http://flaky:8080", "http://healthy:8080"},
+		Strategy:    crawlers.ProxyScored,
+		MaxFailures: 100,
+	})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+	for i := 0; i < 10; i++ {
+		pool.ReportFailure("http://flaky:8080")
+		pool.ReportSuccess("http://healthy:8080")
+	}
+
+	picks := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		proxy, err := pool.Next("https://example.com/")
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		picks[proxy.Host]++
+	}
+	if picks["healthy:8080"] < 800 {
+		t.Errorf("Expected the healthy proxy to get most requests, got %v", picks)
+	}
+	if picks["flaky:8080"] == 0 {
+		t.Error("Expected the flaky proxy to keep some traffic to recover")
+	}
+
+	stats := pool.Stats()
+	if stats[0].SuccessRate >= stats[1].SuccessRate {
+		t.Errorf("SuccessRate flaky = %v, healthy = %v", stats[0].SuccessRate, stats[1].SuccessRate)
+	}
+}
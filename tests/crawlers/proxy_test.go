@@ -0,0 +1,497 @@
+package crawlers_test
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Proxy Pool Tests
+// =============================================================================
+
+func TestNewProxyPool_Validation(t *testing.T) {
+	if _, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{}); err == nil {
+		t.Error("Expected error for empty proxy list")
+	}
+	if _, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{Proxies: []string{"not a url"}}); err == nil {
+		t.Error("Expected error for invalid proxy URL")
+	}
+	if _, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{
+		Proxies:  []string{"http://p1:8080"},
+		Strategy: "fastest",
+	}); err == nil {
+		t.Error("Expected error for unknown strategy")
+	}
+}
+
+func TestProxyPool_RoundRobin(t *testing.T) {
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{
+		Proxies: []string{"http://p1:8080", "http://p2:8080"},
+	})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		proxy, err := pool.Next("https://example.com/")
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		got = append(got, proxy.Host)
+	}
+
+	want := []string{"p1:8080", "p2:8080", "p1:8080", "p2:8080"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Next() sequence = %v, want %v", got, want)
+			break
+		}
+	}
+}
+
+func TestProxyPool_Sticky(t *testing.T) {
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{
+		Proxies:  []string{"http://p1:8080", "http://p2:8080"},
+		Strategy: crawlers.ProxySticky,
+	})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+
+	first, _ := pool.Next("https://a.example.com/1")
+	other, _ := pool.Next("https://b.example.com/1")
+	again, _ := pool.Next("https://a.example.com/2")
+
+	if first.String() != again.String() {
+		t.Errorf("Expected sticky proxy for domain, got %s then %s", first, again)
+	}
+	if first.String() == other.String() {
+		t.Error("Expected different domains to be spread across proxies")
+	}
+}
+
+func TestProxyPool_RemovesFailingProxy(t *testing.T) {
+	pool, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{
+		Proxies:     []string{"http://bad:8080", "http://good:8080"},
+		MaxFailures: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewProxyPool() error = %v", err)
+	}
+
+	pool.ReportFailure("http://bad:8080")
+	pool.ReportSuccess("http://bad:8080") // Resets consecutive failures
+	pool.ReportFailure("http://bad:8080")
+	if pool.Available() != 2 {
+		t.Fatal("Expected proxy to survive non-consecutive failures")
+	}
+
+	pool.ReportFailure("http://bad:8080")
+	if pool.Available() != 1 {
+		t.Fatalf("Available() = %d, want 1", pool.Available())
+	}
+
+	for i := 0; i < 3; i++ {
+		proxy, err := pool.Next("https://example.com/")
+		if err != nil || proxy.Host != "good:8080" {
+			t.Errorf("Next() = %v, %v; want good proxy", proxy, err)
+		}
+	}
+
+	stats := pool.Stats()
+	if !stats[0].Removed || stats[0].Failures != 3 || stats[0].Successes != 1 {
+		t.Errorf("Unexpected stats for bad proxy: %+v", stats[0])
+	}
+
+	pool.ReportFailure("http://good:8080")
+	pool.ReportFailure("http://good:8080")
+	if _, err := pool.Next("https://example.com/"); !errors.Is(err, crawlers.ErrNoProxyAvailable) {
+		t.Errorf("Next() error = %v, want ErrNoProxyAvailable", err)
+	}
+}
+
+// =============================================================================
+// Proxy Integration Tests
+// =============================================================================
+
+// newTestProxy returns a server that answers proxied requests itself
+func newTestProxy(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>via proxy " + r.URL.Host + "</title></head></html>"))
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy
+}
+
+func TestSoupClient_UsesProxy(t *testing.T) {
+	var hits int32
+	proxy := newTestProxy(t, &hits)
+
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Proxies: []string{proxy.URL}})
+	doc, err := client.Get("http://target.invalid/page")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+
+	if atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("Expected request to go through the proxy, hits = %d", hits)
+	}
+	if title := doc.Find("title").Text(); title != "via proxy target.invalid" {
+		t.Errorf("title = %q", title)
+	}
+	if stats := client.ProxyPool().Stats(); stats[0].Successes != 1 {
+		t.Errorf("Expected success to be recorded, got %+v", stats[0])
+	}
+}
+
+func TestSoupClient_RemovesDeadProxy(t *testing.T) {
+	var hits int32
+	good := newTestProxy(t, &hits)
+
+	dead := httptest.NewServer(http.NotFoundHandler())
+	deadURL := dead.URL
+	dead.Close() // Connections to this address now fail
+
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Proxies: []string{deadURL, good.URL}})
+
+	for i := 0; i < 6; i++ {
+		_, _ = client.Get("http://target.invalid/page")
+	}
+
+	if client.ProxyPool().Available() != 1 {
+		t.Errorf("Expected dead proxy to be removed, available = %d", client.ProxyPool().Available())
+	}
+}
+
+func TestCollyClient_UsesProxy(t *testing.T) {
+	var hits int32
+	proxy := newTestProxy(t, &hits)
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{
+		UserAgent: "test",
+		Proxies:   []string{proxy.URL},
+	})
+
+	var title string
+	client.OnHTML("title", func(e *colly.HTMLElement) {
+		title = e.Text
+	})
+
+	if err := client.Visit("http://target.invalid/page"); err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+	client.Wait()
+
+	if title != "via proxy target.invalid" {
+		t.Errorf("title = %q", title)
+	}
+	if client.ProxyPool() == nil {
+		t.Fatal("Expected proxy pool to be configured")
+	}
+	if stats := client.ProxyPool().Stats(); stats[0].Successes != 1 {
+		t.Errorf("Expected success to be recorded, got %+v", stats[0])
+	}
+}
+
+func TestNewCollyClient_InvalidProxyFallsBack(t *testing.T) {
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{Proxies: []string{"::bad"}})
+	if client.ProxyPool() != nil {
+		t.Error("Expected no proxy pool for invalid configuration")
+	}
+}
+
+// =============================================================================
+// SOCKS5 Tests
+// =============================================================================
+
+// socksServer is a minimal SOCKS5 proxy with username/password auth
+type socksServer struct {
+	listener net.Listener
+	user     string
+	password string
+	hits     int32 // Successful CONNECTs
+	rejected int32 // Failed authentications
+}
+
+// newSocksServer starts a SOCKS5 proxy requiring user and password
+func newSocksServer(t *testing.T, user, password string) *socksServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() error = %v", err)
+	}
+	s := &socksServer{listener: listener, user: user, password: password}
+	t.Cleanup(func() { _ = listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// url returns the proxy URL with the given credentials
+func (s *socksServer) url(user, password string) string {
+	return fmt.Sprintf("socks5://%s:%s@%s", user, password, s.listener.Addr())
+}
+
+func (s *socksServer) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+	r := bufio.NewReader(conn)
+
+	// Greeting: version, methods; only username/password (0x02) is accepted
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r, make([]byte, header[1])); err != nil {
+		return
+	}
+	_, _ = conn.Write([]byte{5, 2})
+
+	// RFC 1929 sub-negotiation
+	readField := func() string {
+		n, _ := r.ReadByte()
+		b := make([]byte, n)
+		_, _ = io.ReadFull(r, b)
+		return string(b)
+	}
+	_, _ = r.ReadByte() // Sub-negotiation version
+	user, password := readField(), readField()
+	if user != s.user || password != s.password {
+		atomic.AddInt32(&s.rejected, 1)
+		_, _ = conn.Write([]byte{1, 1})
+		return
+	}
+	_, _ = conn.Write([]byte{1, 0})
+
+	// Request: version, CONNECT, reserved, address
+	request := make([]byte, 4)
+	if _, err := io.ReadFull(r, request); err != nil {
+		return
+	}
+	var host string
+	switch request[3] {
+	case 1:
+		ip := make([]byte, 4)
+		_, _ = io.ReadFull(r, ip)
+		host = net.IP(ip).String()
+	case 3:
+		host = readField()
+	default:
+		return
+	}
+	port := make([]byte, 2)
+	_, _ = io.ReadFull(r, port)
+
+	target, err := net.Dial("tcp", net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1]))))
+	if err != nil {
+		_, _ = conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+		return
+	}
+	defer func() { _ = target.Close() }()
+	atomic.AddInt32(&s.hits, 1)
+	_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+
+	go func() { _, _ = io.Copy(target, r) }()
+	_, _ = io.Copy(conn, target)
+}
+
+// newTitleServer serves a page whose title is its path
+func newTitleServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>" + r.URL.Path + "</title></head></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestParseProxyURL(t *testing.T) {
+	for _, raw := range []string{"http://p:8080", "https://p", "socks5://u:p@p:1080", "SOCKS5H://p:1080"} {
+		if _, err := crawlers.ParseProxyURL(raw); err != nil {
+			t.Errorf("ParseProxyURL(%q) error = %v", raw, err)
+		}
+	}
+	for _, raw := range []string{"ftp://p:21", "socks4://p:1080", "p:8080", "::bad"} {
+		if _, err := crawlers.ParseProxyURL(raw); err == nil {
+			t.Errorf("Expected ParseProxyURL(%q) to fail", raw)
+		}
+	}
+	if _, err := crawlers.NewProxyPool(crawlers.ProxyPoolConfig{Proxies: []string{"socks4://p:1080"}}); err == nil {
+		t.Error("Expected NewProxyPool to reject unsupported schemes")
+	}
+}
+
+func TestSOCKS5_Fetchers(t *testing.T) {
+	target := newTitleServer(t)
+
+	fetchers := []struct {
+		name  string
+		fetch func(proxy string) (string, *crawlers.ProxyPool)
+	}{
+		{"soup", func(proxy string) (string, *crawlers.ProxyPool) {
+			client := crawlers.NewSoupClient(crawlers.SoupConfig{Proxies: []string{proxy}})
+			doc, err := client.Get(target.URL + "/soup")
+			if err != nil {
+				return "", client.ProxyPool()
+			}
+			return doc.Find("title").Text(), client.ProxyPool()
+		}},
+		{"colly", func(proxy string) (string, *crawlers.ProxyPool) {
+			client := crawlers.NewCollyClient(crawlers.CollyConfig{Proxies: []string{proxy}})
+			var title string
+			client.OnHTML("title", func(e *colly.HTMLElement) { title = e.Text })
+			_ = client.Visit(target.URL + "/colly")
+			client.Wait()
+			return title, client.ProxyPool()
+		}},
+		{"spider", func(proxy string) (string, *crawlers.ProxyPool) {
+			spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Proxies: []string{proxy}})
+			var title string
+			spider.OnDocument(func(doc *goquery.Document, _ string) error {
+				title = doc.Find("title").Text()
+				return nil
+			})
+			spider.AddStartURL(target.URL + "/spider")
+			_ = spider.Run()
+			return title, spider.ProxyPool()
+		}},
+	}
+
+	for _, fetcher := range fetchers {
+		t.Run(fetcher.name, func(t *testing.T) {
+			socks := newSocksServer(t, "user", "secret")
+
+			title, pool := fetcher.fetch(socks.url("user", "secret"))
+			if title != "/"+fetcher.name {
+				t.Errorf("title = %q, want the page fetched through the proxy", title)
+			}
+			if atomic.LoadInt32(&socks.hits) == 0 {
+				t.Error("Expected the request to go through the SOCKS5 proxy")
+			}
+			if pool == nil || pool.Stats()[0].Successes == 0 {
+				t.Errorf("Expected the success to be recorded")
+			}
+
+			title, pool = fetcher.fetch(socks.url("user", "wrong"))
+			if title != "" {
+				t.Errorf("title = %q, want the fetch to fail with bad credentials", title)
+			}
+			if atomic.LoadInt32(&socks.rejected) == 0 || pool.Stats()[0].Failures == 0 {
+				t.Errorf("Expected the rejected login to count as a proxy failure, stats %+v", pool.Stats())
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Proxy Relay Tests
+// =============================================================================
+
+// relayClient returns an HTTP client that sends everything to relay
+func relayClient(t *testing.T, relay *crawlers.ProxyRelay) *http.Client {
+	t.Helper()
+	relayURL, err := url.Parse(relay.URL())
+	if err != nil {
+		t.Fatalf("relay URL %q: %v", relay.URL(), err)
+	}
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(relayURL),
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // #nosec G402 -- test server certificate
+	}}
+}
+
+// getBody fetches rawURL and returns its body
+func getBody(t *testing.T, client *http.Client, rawURL string) string {
+	t.Helper()
+	resp, err := client.Get(rawURL)
+	if err != nil {
+		t.Fatalf("Get(%s) error = %v", rawURL, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	return string(body)
+}
+
+func TestProxyRelay_SOCKS5(t *testing.T) {
+	socks := newSocksServer(t, "user", "secret")
+	plain := newTitleServer(t)
+	secure := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tls " + r.URL.Path))
+	}))
+	defer secure.Close()
+
+	relay, err := crawlers.NewProxyRelay(socks.url("user", "secret"))
+	if err != nil {
+		t.Fatalf("NewProxyRelay() error = %v", err)
+	}
+	defer func() { _ = relay.Close() }()
+	if !strings.HasPrefix(relay.URL(), "http://127.0.0.1:") {
+		t.Errorf("URL() = %q, want a loopback address", relay.URL())
+	}
+
+	client := relayClient(t, relay)
+	if body := getBody(t, client, plain.URL+"/plain"); !strings.Contains(body, "<title>/plain</title>") {
+		t.Errorf("Plain HTTP through relay = %q", body)
+	}
+	if body := getBody(t, client, secure.URL+"/secure"); body != "tls /secure" {
+		t.Errorf("CONNECT through relay = %q", body)
+	}
+	if hits := atomic.LoadInt32(&socks.hits); hits != 2 {
+		t.Errorf("SOCKS5 proxy saw %d connections, want 2", hits)
+	}
+}
+
+func TestProxyRelay_HTTPUpstream(t *testing.T) {
+	var auth atomic.Value
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth.Store(r.Header.Get("Proxy-Authorization"))
+		_, _ = w.Write([]byte("via upstream " + r.URL.Host))
+	}))
+	defer upstream.Close()
+
+	upstreamURL, _ := url.Parse(upstream.URL)
+	upstreamURL.User = url.UserPassword("user", "secret")
+	relay, err := crawlers.NewProxyRelay(upstreamURL.String())
+	if err != nil {
+		t.Fatalf("NewProxyRelay() error = %v", err)
+	}
+	defer func() { _ = relay.Close() }()
+
+	if body := getBody(t, relayClient(t, relay), "http://target.invalid/"); body != "via upstream target.invalid" {
+		t.Errorf("body = %q", body)
+	}
+	if got, _ := auth.Load().(string); got != "Basic dXNlcjpzZWNyZXQ=" {
+		t.Errorf("Proxy-Authorization = %q, want the relay to add the credentials", got)
+	}
+
+	if _, err := crawlers.NewProxyRelay("ftp://p:21"); err == nil {
+		t.Error("Expected an error for an unsupported upstream")
+	}
+}
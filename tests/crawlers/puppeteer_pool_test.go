@@ -0,0 +1,86 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/chromedp/chromedp"
+)
+
+// =============================================================================
+// Puppeteer Tab Pool Tests
+// =============================================================================
+
+func TestPuppeteerClient_CheckoutAfterClose(t *testing.T) {
+	client, err := crawlers.NewPuppeteerClient(crawlers.PuppeteerConfig{Headless: true, Tabs: 3})
+	if err != nil {
+		t.Fatalf("NewPuppeteerClient() error = %v", err)
+	}
+	if stats := client.TabStats(); stats.Size != 3 || stats.Idle != 3 {
+		t.Errorf("TabStats() = %+v, want 3 idle", stats)
+	}
+
+	_ = client.Close()
+	_, err = client.Checkout(context.Background())
+	if !errors.Is(err, crawlers.ErrTabPoolClosed) || errs.CodeOf(err) != errs.CodeBrowserPoolClosed {
+		t.Errorf("Checkout() after Close error = %v, want ErrTabPoolClosed", err)
+	}
+}
+
+func TestPuppeteerClient_Tabs(t *testing.T) {
+	client, err := crawlers.NewPuppeteerClient(crawlers.PuppeteerConfig{Headless: true, Timeout: 10 * time.Second, Tabs: 2})
+	if err != nil {
+		t.Fatalf("NewPuppeteerClient() error = %v", err)
+	}
+	defer client.Close()
+
+	ctx := context.Background()
+	first, err := client.Checkout(ctx)
+	if err != nil {
+		t.Skipf("Skipping Puppeteer tab tests: browser not available (%v)", err)
+	}
+	second, err := client.Checkout(ctx)
+	if err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if stats := client.TabStats(); stats.InUse != 2 || stats.Idle != 0 {
+		t.Errorf("TabStats() = %+v, want 2 in use", stats)
+	}
+
+	// An exhausted pool waits until ctx is done
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Checkout(waitCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Checkout() on exhausted pool error = %v, want deadline exceeded", err)
+	}
+
+	// Tabs are independent of each other and of the default tab
+	var title string
+	if err := first.Run(chromedp.Navigate("data:text/html,%3Ctitle%3Efirst%3C/title%3E"), chromedp.Title(&title)); err != nil || title != "first" {
+		t.Errorf("Run() in first tab = %q, %v", title, err)
+	}
+	if err := second.Run(chromedp.Title(&title)); err != nil || title != "" {
+		t.Errorf("Run() in second tab = %q, %v, want an empty title", title, err)
+	}
+
+	second.Discard()
+	client.Checkin(first)
+	client.Checkin(second)
+	client.Checkin(second) // Double checkin is ignored
+	if stats := client.TabStats(); stats.Idle != 2 {
+		t.Errorf("TabStats() = %+v, want 2 idle", stats)
+	}
+
+	// Discarded tabs are reopened on checkout
+	for i := 0; i < 2; i++ {
+		tab, err := client.Checkout(ctx)
+		if err != nil {
+			t.Fatalf("Checkout() after discard error = %v", err)
+		}
+		defer client.Checkin(tab)
+	}
+}
@@ -0,0 +1,267 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Rate Limiter Tests
+// =============================================================================
+
+func TestRateLimitDomain(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://www.example.com/a", want: "example.com"},
+		{url: "https://cdn.example.com/b", want: "example.com"},
+		{url: "https://shop.example.co.uk/", want: "example.co.uk"},
+		{url: "http://127.0.0.1:8080/", want: "127.0.0.1"},
+		{url: "http://localhost/", want: "localhost"},
+		{url: "://bad", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := crawlers.RateLimitDomain(tt.url); got != tt.want {
+				t.Errorf("RateLimitDomain(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiter_SharesLimitAcrossSubdomains(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: 100 * time.Millisecond, Clock: fake})
+	ctx := context.Background()
+
+	if err := limiter.Wait(ctx, "https://www.example.com/"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- limiter.Wait(ctx, "https://api.example.com/") }()
+
+	// The second request to the same eTLD+1 waits for the delay
+	fake.BlockUntil(1)
+	fake.Advance(99 * time.Millisecond)
+	select {
+	case err := <-done:
+		t.Fatalf("Wait() returned before the delay elapsed: %v", err)
+	default:
+	}
+	fake.Advance(time.Millisecond)
+	if err := <-done; err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if limiter.Domains() != 1 {
+		t.Errorf("Domains() = %d, want 1", limiter.Domains())
+	}
+}
+
+func TestRateLimiter_IndependentDomains(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: time.Second, Clock: fake})
+
+	// The clock never moves, so any wait would run into the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, u := range []string{"https://a.com/", "https://b.com/", "https://c.com/"} {
+		if err := limiter.Wait(ctx, u); err != nil {
+			t.Fatalf("Wait(%s) error = %v, want different domains not to block each other", u, err)
+		}
+	}
+}
+
+func TestRateLimiter_MaxConcurrent(t *testing.T) {
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{MaxConcurrent: 1})
+
+	release, err := limiter.Acquire(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := limiter.Acquire(ctx, "https://www.example.com/"); err == nil {
+		t.Fatal("Expected second Acquire to block while the slot is held")
+	}
+
+	release()
+	release() // Releasing twice must not free an extra slot
+
+	second, err := limiter.Acquire(context.Background(), "https://example.com/")
+	if err != nil {
+		t.Fatalf("Acquire() after release error = %v", err)
+	}
+	defer second()
+}
+
+func TestRateLimiter_ContextCanceled(t *testing.T) {
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: time.Hour})
+	if err := limiter.Wait(context.Background(), "https://example.com/"); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := limiter.Wait(ctx, "https://example.com/"); err == nil {
+		t.Error("Expected error for canceled context")
+	}
+}
+
+func TestNewRateLimiterFromConfig(t *testing.T) {
+	if crawlers.NewRateLimiterFromConfig(configs.RateLimitConfig{Enabled: false}, nil) != nil {
+		t.Error("Expected nil limiter when rate limiting is disabled")
+	}
+
+	limiter := crawlers.NewRateLimiterFromConfig(configs.RateLimitConfig{
+		Enabled:        true,
+		RequestsPerSec: 10,
+		MaxConcurrent:  2,
+	}, nil)
+	if limiter == nil {
+		t.Fatal("Expected limiter when rate limiting is enabled")
+	}
+}
+
+func TestSoupClient_RateLimiterConcurrency(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		for {
+			prev := atomic.LoadInt32(&maxInFlight)
+			if n <= prev || atomic.CompareAndSwapInt32(&maxInFlight, prev, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		_, _ = w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{MaxConcurrent: 1})
+	a := crawlers.NewSoupClient(crawlers.SoupConfig{RateLimiter: limiter})
+	b := crawlers.NewSoupClient(crawlers.SoupConfig{RateLimiter: limiter})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		client := a
+		if i%2 == 1 {
+			client = b
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Get(server.URL); err != nil {
+				t.Errorf("Get() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&maxInFlight); got != 1 {
+		t.Errorf("Expected clients sharing a limiter to send one request at a time, got %d", got)
+	}
+}
+
+// throttleMetrics counts adaptive rate limiter events by action
+type throttleMetrics struct {
+	mu      sync.Mutex
+	actions map[string]int
+}
+
+func (m *throttleMetrics) RecordThrottle(action string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.actions == nil {
+		m.actions = make(map[string]int)
+	}
+	m.actions[action]++
+}
+
+func TestRateLimiter_AdaptiveSlowsAndRecovers(t *testing.T) {
+	metrics := &throttleMetrics{}
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{
+		Delay: 100 * time.Millisecond,
+		Clock: clock.NewFake(time.Time{}),
+		Adaptive: &crawlers.AdaptiveConfig{
+			MinDelay:     200 * time.Millisecond,
+			MaxDelay:     time.Second,
+			RecoverAfter: 2,
+			Metrics:      metrics,
+		},
+	})
+	const url = "https://www.example.com/"
+	retryAfter := http.Header{"Retry-After": []string{"3"}}
+
+	steps := []struct {
+		status    int
+		header    http.Header
+		throttled bool
+		want      time.Duration
+	}{
+		{http.StatusTooManyRequests, nil, true, 200 * time.Millisecond}, // MinDelay
+		{http.StatusServiceUnavailable, nil, true, 400 * time.Millisecond},
+		{http.StatusForbidden, retryAfter, true, 800 * time.Millisecond}, // Retry-After throttles; waiting it out is the cooldown's job
+		{http.StatusOK, nil, false, 800 * time.Millisecond},
+		{http.StatusOK, nil, false, 400 * time.Millisecond},
+		{http.StatusNotFound, nil, false, 400 * time.Millisecond},
+		{http.StatusOK, nil, false, 200 * time.Millisecond},
+		{http.StatusOK, nil, false, 200 * time.Millisecond},
+		{http.StatusOK, nil, false, 100 * time.Millisecond}, // Below MinDelay: back to the configured delay
+	}
+	for i, step := range steps {
+		_, throttled := limiter.Observe(url, step.status, step.header)
+		if got := limiter.Delay(url); throttled != step.throttled || got != step.want {
+			t.Errorf("step %d: Observe(%d) throttled = %v, Delay() = %s; want %v and %s", i, step.status, throttled, got, step.throttled, step.want)
+		}
+	}
+
+	if got := limiter.Delay("https://other.example.org/"); got != 100*time.Millisecond {
+		t.Errorf("Expected other domains to keep the configured delay, got %s", got)
+	}
+	if metrics.actions[crawlers.ThrottleActionSlowed] != 3 || metrics.actions[crawlers.ThrottleActionRecovered] != 1 || metrics.actions[crawlers.ThrottleActionPaused] != 0 {
+		t.Errorf("Unexpected throttle events: %v", metrics.actions)
+	}
+}
+
+func TestSoupClient_AdaptiveRateLimiter(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	limiter := crawlers.NewRateLimiterFromConfig(configs.RateLimitConfig{Enabled: true, Adaptive: true, MaxDelay: 20}, nil)
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{RateLimiter: limiter})
+
+	_, err := client.Get(server.URL)
+	var throttled *crawlers.ThrottledError
+	if !errors.As(err, &throttled) || throttled.Delay != 20*time.Millisecond {
+		t.Fatalf("Expected a ThrottledError with the raised delay, got %v", err)
+	}
+
+	start := time.Now()
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() after throttling error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("Expected the next request to wait for the raised delay, waited %s", elapsed)
+	}
+}
@@ -0,0 +1,61 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Redirect Tests
+// =============================================================================
+
+func TestCollyClient_OnRedirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			http.Redirect(w, r, "/new", http.StatusFound)
+		default:
+			_, _ = w.Write([]byte("<html></html>"))
+		}
+	}))
+	defer server.Close()
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1})
+	var hops []crawlers.Redirect
+	client.Clone().OnRedirect(func(r crawlers.Redirect) {
+		hops = append(hops, r)
+	})
+	if err := client.Visit(server.URL + "/old"); err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+
+	want := []crawlers.Redirect{
+		{From: server.URL + "/old", To: server.URL + "/moved", StatusCode: http.StatusMovedPermanently},
+		{From: server.URL + "/moved", To: server.URL + "/new", StatusCode: http.StatusFound},
+	}
+	if !reflect.DeepEqual(hops, want) {
+		t.Errorf("Redirects = %+v, want %+v", hops, want)
+	}
+	if got := crawlers.PermanentTarget(server.URL+"/old", hops); got != server.URL+"/moved" {
+		t.Errorf("PermanentTarget() = %q, want the 301 target", got)
+	}
+}
+
+func TestPermanentTarget(t *testing.T) {
+	hops := []crawlers.Redirect{
+		{From: "http://a.example/", To: "https://a.example/", StatusCode: http.StatusMovedPermanently},
+		{From: "https://a.example/", To: "https://www.a.example/", StatusCode: http.StatusPermanentRedirect},
+	}
+	if got := crawlers.PermanentTarget("http://a.example/", hops); got != "https://www.a.example/" {
+		t.Errorf("PermanentTarget() = %q", got)
+	}
+	if got := crawlers.PermanentTarget("http://a.example/", nil); got != "http://a.example/" {
+		t.Errorf("PermanentTarget() without redirects = %q", got)
+	}
+}
@@ -0,0 +1,169 @@
+package crawlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Request Options Tests
+// =============================================================================
+
+func TestRequestOptions_Validate(t *testing.T) {
+	valid := crawlers.RequestOptions{
+		Headers: map[string]string{"X-Tenant": "acme", "Cookie": "ab=b", "User-Agent": "tenant-bot"},
+		Query:   map[string]string{"tenant": "acme"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	invalid := []crawlers.RequestOptions{
+		{Headers: map[string]string{"Bad Header": "x"}},
+		{Headers: map[string]string{"X-Tenant": "a\r\nInjected: 1"}},
+		{Headers: map[string]string{"host": "other.example"}},
+		{Headers: map[string]string{"Content-Length": "0"}},
+		{Query: map[string]string{" ": "x"}},
+	}
+	for _, opts := range invalid {
+		if err := opts.Validate(); !errs.HasCode(err, errs.CodeInvalidRequest) {
+			t.Errorf("Validate(%+v) error = %v, want %s", opts, err, errs.CodeInvalidRequest)
+		}
+	}
+}
+
+func TestRequestOptions_Apply(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/search?q=go&tenant=old", nil)
+	req.Header.Set("User-Agent", "default")
+
+	crawlers.RequestOptions{
+		Headers: map[string]string{"User-Agent": "tenant-bot", "X-Tenant": "acme"},
+		Query:   map[string]string{"tenant": "acme", "variant": "b"},
+	}.Apply(req)
+
+	if req.Header.Get("User-Agent") != "tenant-bot" || req.Header.Get("X-Tenant") != "acme" {
+		t.Errorf("Headers = %v", req.Header)
+	}
+	want := url.Values{"q": {"go"}, "tenant": {"acme"}, "variant": {"b"}}
+	if got := req.URL.Query(); got.Encode() != want.Encode() {
+		t.Errorf("Query = %v, want %v", got, want)
+	}
+
+	ctx := crawlers.WithRequestOptions(context.Background(), crawlers.RequestOptions{})
+	if ctx != context.Background() || !crawlers.RequestOptionsFrom(ctx).IsZero() {
+		t.Error("Expected empty options to leave the context unchanged")
+	}
+}
+
+// optionsServer records the X-Tenant header and tenant parameter of each path
+type optionsServer struct {
+	*httptest.Server
+	mu   sync.Mutex
+	seen map[string]string
+}
+
+func newOptionsServer(t *testing.T) *optionsServer {
+	t.Helper()
+	s := &optionsServer{seen: map[string]string{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.seen[r.URL.Path] = r.Header.Get("X-Tenant") + "|" + r.URL.Query().Get("tenant")
+		s.mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/start" {
+			_, _ = w.Write([]byte(`<html><body><a href="/linked">next</a></body></html>`))
+			return
+		}
+		_, _ = w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// get returns what the server saw for path
+func (s *optionsServer) get(path string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.seen[path]
+}
+
+func TestRequestOptions_Fetchers(t *testing.T) {
+	opts := crawlers.RequestOptions{
+		Headers: map[string]string{"X-Tenant": "acme"},
+		Query:   map[string]string{"tenant": "acme"},
+	}
+	ctx := crawlers.WithRequestOptions(context.Background(), opts)
+
+	t.Run("soup", func(t *testing.T) {
+		server := newOptionsServer(t)
+		client := crawlers.NewSoupClient(crawlers.SoupConfig{})
+		if _, err := client.GetContext(ctx, server.URL+"/job"); err != nil {
+			t.Fatalf("GetContext() error = %v", err)
+		}
+		if _, err := client.Get(server.URL + "/plain"); err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+		if got := server.get("/job"); got != "acme|acme" {
+			t.Errorf("Job request saw %q, want acme|acme", got)
+		}
+		if got := server.get("/plain"); got != "|" {
+			t.Errorf("Request without options saw %q", got)
+		}
+	})
+
+	t.Run("colly", func(t *testing.T) {
+		server := newOptionsServer(t)
+		client := crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 2})
+		client.OnHTML("a[href]", func(e *colly.HTMLElement) {
+			_ = e.Request.Visit(e.Attr("href"))
+		})
+		if err := client.VisitContext(ctx, server.URL+"/start"); err != nil {
+			t.Fatalf("VisitContext() error = %v", err)
+		}
+		client.Wait()
+		if err := client.Visit(server.URL + "/plain"); err != nil {
+			t.Fatalf("Visit() error = %v", err)
+		}
+		client.Wait()
+
+		for _, path := range []string{"/start", "/linked"} {
+			if got := server.get(path); got != "acme|acme" {
+				t.Errorf("%s saw %q, want acme|acme", path, got)
+			}
+		}
+		if got := server.get("/plain"); got != "|" {
+			t.Errorf("Request without options saw %q", got)
+		}
+	})
+
+	t.Run("spider", func(t *testing.T) {
+		server := newOptionsServer(t)
+		spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 2, Concurrency: 1})
+		spider.OnDocument(func(doc *goquery.Document, pageURL string) error {
+			for _, link := range spider.ExtractLinks(doc, "a[href]") {
+				if resolved, err := spider.ResolveURL(pageURL, link); err == nil {
+					spider.AddURL(resolved, crawlers.CrawlContext{URL: pageURL})
+				}
+			}
+			return nil
+		})
+		spider.AddStartURL(server.URL + "/start")
+		if err := spider.RunContext(ctx); err != nil {
+			t.Fatalf("RunContext() error = %v", err)
+		}
+		for _, path := range []string{"/start", "/linked"} {
+			if got := server.get(path); got != "acme|acme" {
+				t.Errorf("%s saw %q, want acme|acme", path, got)
+			}
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package crawlers_test
+
+import (
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Resource Blocking Tests
+// =============================================================================
+
+func TestResourceBlocker_Blocks(t *testing.T) {
+	blocker, err := crawlers.NewResourceBlocker(
+		[]string{"image", "Font"},
+		append([]string{"*.css?*", "https://cdn.example.com/ads/*"}, crawlers.DefaultTrackerPatterns...),
+	)
+	if err != nil {
+		t.Fatalf("NewResourceBlocker() error = %v", err)
+	}
+
+	tests := []struct {
+		url          string
+		resourceType string
+		want         bool
+	}{
+		{"https://example.com/logo.png", "Image", true},
+		{"https://example.com/font.woff2", "font", true},
+		{"https://example.com/site.css?v=1", "Stylesheet", true},
+		{"https://cdn.example.com/ads/banner.js", "Script", true},
+		{"https://www.google-analytics.com/analytics.js", "Script", true},
+		{"https://example.com/app.js", "Script", false},
+		{"https://example.com/", "Document", false},
+		{"https://cdn.example.com/lib.js", "Script", false},
+	}
+	for _, tt := range tests {
+		if got := blocker.Blocks(tt.url, tt.resourceType); got != tt.want {
+			t.Errorf("Blocks(%q, %q) = %v, want %v", tt.url, tt.resourceType, got, tt.want)
+		}
+	}
+
+	if got := blocker.Blocked(); got != 5 {
+		t.Errorf("Blocked() = %d, want 5", got)
+	}
+}
+
+func TestResourceBlocker_Empty(t *testing.T) {
+	blocker, err := crawlers.NewResourceBlocker(nil, nil)
+	if err != nil {
+		t.Fatalf("NewResourceBlocker() error = %v", err)
+	}
+	if !blocker.Empty() {
+		t.Error("Empty() = false, want true")
+	}
+	if blocker.Blocks("https://example.com/logo.png", "Image") {
+		t.Error("Empty blocker should not block")
+	}
+}
@@ -0,0 +1,134 @@
+package crawlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+)
+
+// =============================================================================
+// Meta Robots Tests
+// =============================================================================
+
+func robotsDoc(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("NewDocumentFromReader() error = %v", err)
+	}
+	return doc
+}
+
+func TestParseRobotsDirectives(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		html   string
+		want   crawlers.RobotsDirectives
+	}{
+		{"none", nil, `<html></html>`, crawlers.RobotsDirectives{}},
+		{"meta", nil, `<meta name="ROBOTS" content="NoIndex, follow">`, crawlers.RobotsDirectives{NoIndex: true}},
+		{"meta none", nil, `<meta name="robots" content="none">`, crawlers.RobotsDirectives{NoIndex: true, NoFollow: true}},
+		{"meta for this agent", nil, `<meta name="golwarcbot" content="nofollow">`, crawlers.RobotsDirectives{NoFollow: true}},
+		{"meta for another agent", nil, `<meta name="googlebot" content="noindex">`, crawlers.RobotsDirectives{}},
+		{"header", http.Header{"X-Robots-Tag": {"noindex, nofollow"}}, ``, crawlers.RobotsDirectives{NoIndex: true, NoFollow: true}},
+		{"header for this agent", http.Header{"X-Robots-Tag": {"golwarcbot: nofollow", "googlebot: noindex"}}, ``, crawlers.RobotsDirectives{NoFollow: true}},
+		{"header scope ends with the value", http.Header{"X-Robots-Tag": {"googlebot: noindex", "nofollow"}}, ``, crawlers.RobotsDirectives{NoFollow: true}},
+		{"header value directive", http.Header{"X-Robots-Tag": {"unavailable_after: 25 Jun 2030 15:00:00 PST, noindex"}}, ``, crawlers.RobotsDirectives{NoIndex: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := crawlers.ParseRobotsDirectives(tt.header, robotsDoc(t, tt.html), "golwarcbot")
+			if got != tt.want {
+				t.Errorf("ParseRobotsDirectives() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRobotsAgent(t *testing.T) {
+	tests := map[string]string{
+		"Mozilla/5.0 (compatible; GolwarcBot/1.0)":           "golwarcbot",
+		"Mozilla/5.0 (compatible; ArchiveCrawler/2.1; +url)": "archivecrawler",
+		"Mozilla/5.0 (X11; Linux x86_64) Firefox/120.0":      "",
+	}
+	for ua, want := range tests {
+		if got := crawlers.RobotsAgent(ua); got != want {
+			t.Errorf("RobotsAgent(%q) = %q, want %q", ua, got, want)
+		}
+	}
+}
+
+func TestSpider_ExtractLinks_SkipsNoFollow(t *testing.T) {
+	doc := robotsDoc(t, `<a href="/a">A</a><a rel="nofollow noopener" href="/b">B</a><a rel="NOFOLLOW" href="/c">C</a>`)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{})
+	if got := spider.ExtractLinks(doc, "a[href]"); !reflect.DeepEqual(got, []string{"/a"}) {
+		t.Errorf("ExtractLinks() = %v, want [/a]", got)
+	}
+	if got := spider.ExtractLinksWithCascadia(doc, "a[href]"); !reflect.DeepEqual(got, []string{"/a"}) {
+		t.Errorf("ExtractLinksWithCascadia() = %v, want [/a]", got)
+	}
+
+	meta := robotsDoc(t, `<meta name="robots" content="nofollow"><a href="/a">A</a>`)
+	if got := spider.ExtractLinks(meta, "a[href]"); len(got) != 0 {
+		t.Errorf("ExtractLinks() on nofollow page = %v, want none", got)
+	}
+
+	archival := crawlers.NewSpider(crawlers.SpiderConfig{IgnoreRobotsMeta: true})
+	if got := archival.ExtractLinks(doc, "a[href]"); len(got) != 3 {
+		t.Errorf("ExtractLinks() ignoring robots meta = %v, want all 3", got)
+	}
+	if got := archival.ExtractLinks(meta, "a[href]"); len(got) != 1 {
+		t.Errorf("ExtractLinks() ignoring robots meta = %v, want /a", got)
+	}
+}
+
+func TestSpider_NoFollowHeaderStopsExpansion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			w.Header().Set("X-Robots-Tag", "nofollow")
+		}
+		_, _ = w.Write([]byte(`<html><body><a href="/next">next</a></body></html>`))
+	}))
+	defer server.Close()
+
+	crawl := func(ignore bool) []string {
+		spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 2, Concurrency: 1, IgnoreRobotsMeta: ignore})
+		var mu sync.Mutex
+		var visited []string
+		spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+			mu.Lock()
+			visited = append(visited, crawl.URL)
+			mu.Unlock()
+			for _, link := range spider.ExtractLinks(doc, "a[href]") {
+				if abs, err := spider.ResolveURL(crawl.URL, link); err == nil {
+					spider.AddURL(abs, crawl)
+				}
+			}
+			return nil
+		})
+		spider.AddStartURL(server.URL + "/")
+		if err := spider.Run(); err != nil {
+			t.Fatalf("Run() error = %v", err)
+		}
+		sort.Strings(visited)
+		return visited
+	}
+
+	if got := crawl(false); !reflect.DeepEqual(got, []string{server.URL + "/"}) {
+		t.Errorf("Visited %v, want only the start page", got)
+	}
+	if got := crawl(true); len(got) != 2 {
+		t.Errorf("Visited %v ignoring robots meta, want 2 pages", got)
+	}
+}
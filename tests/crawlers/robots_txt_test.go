@@ -0,0 +1,394 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/configs"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// robots.txt Tests
+// =============================================================================
+
+// robotsServer serves a robots.txt whose status and body tests can change,
+// counting how often it is fetched
+type robotsServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	status  int
+	body    string
+	fetches atomic.Int32
+}
+
+func newRobotsServer(t *testing.T, status int, body string) *robotsServer {
+	t.Helper()
+	s := &robotsServer{status: status, body: body}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			_, _ = w.Write([]byte("<html><body>page</body></html>"))
+			return
+		}
+		s.fetches.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		w.WriteHeader(s.status)
+		_, _ = w.Write([]byte(s.body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *robotsServer) set(status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.body = status, body
+}
+
+// robotsMetrics counts robots.txt outcomes
+type robotsMetrics struct {
+	mu       sync.Mutex
+	outcomes map[string]int
+}
+
+func (m *robotsMetrics) RecordRobotsFetch(outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.outcomes == nil {
+		m.outcomes = make(map[string]int)
+	}
+	m.outcomes[outcome]++
+}
+
+func assertAllowed(t *testing.T, robots *crawlers.RobotsTxt, url, agent string, want bool) {
+	t.Helper()
+	allowed, err := robots.Allowed(context.Background(), url, agent)
+	if err != nil {
+		t.Fatalf("Allowed(%s) failed: %v", url, err)
+	}
+	if allowed != want {
+		t.Errorf("Allowed(%s, %q) = %v, want %v", url, agent, allowed, want)
+	}
+}
+
+func TestRobotsTxt_Rules(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, `
+User-agent: *
+Disallow: /private
+Allow: /private/public
+
+User-agent: golwarcbot
+Disallow: /search
+`)
+	metrics := &robotsMetrics{}
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{Metrics: metrics})
+
+	assertAllowed(t, robots, server.URL+"/", "", true)
+	assertAllowed(t, robots, server.URL+"/private/page", "", false)
+	assertAllowed(t, robots, server.URL+"/private/public/page", "", true)
+	assertAllowed(t, robots, server.URL+"/private/page", "golwarcbot", true)
+	assertAllowed(t, robots, server.URL+"/search?q=go", "golwarcbot", false)
+	assertAllowed(t, robots, server.URL+"/robots.txt", "golwarcbot", true)
+
+	if n := server.fetches.Load(); n != 1 {
+		t.Errorf("Expected robots.txt to be fetched once, got %d", n)
+	}
+	if metrics.outcomes[crawlers.RobotsOutcomeFetched] != 1 {
+		t.Errorf("Expected one fetched outcome, got %v", metrics.outcomes)
+	}
+}
+
+func TestNewRobotsTxtFromConfig(t *testing.T) {
+	if robots := crawlers.NewRobotsTxtFromConfig(configs.RobotsConfig{}, nil, "", nil); robots != nil {
+		t.Error("Expected nil when disabled")
+	}
+
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private\n")
+	metrics := &robotsMetrics{}
+	robots := crawlers.NewRobotsTxtFromConfig(configs.RobotsConfig{Enabled: true}, nil, "golwarcbot", metrics)
+
+	assertAllowed(t, robots, server.URL+"/private", "", false)
+	if metrics.outcomes[crawlers.RobotsOutcomeFetched] != 1 {
+		t.Errorf("Expected the fetch to be recorded to the given metrics, got %v", metrics.outcomes)
+	}
+}
+
+func TestRobotsTxt_ClientErrorAllowsAll(t *testing.T) {
+	for _, status := range []int{http.StatusNotFound, http.StatusForbidden, http.StatusUnauthorized} {
+		server := newRobotsServer(t, status, "User-agent: *\nDisallow: /")
+		metrics := &robotsMetrics{}
+		robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{Metrics: metrics})
+
+		assertAllowed(t, robots, server.URL+"/page", "", true)
+		if metrics.outcomes[crawlers.RobotsOutcomeUnavailable] != 1 {
+			t.Errorf("status %d: expected an unavailable outcome, got %v", status, metrics.outcomes)
+		}
+	}
+}
+
+func TestRobotsTxt_ServerErrorDisallowsUntilRetry(t *testing.T) {
+	server := newRobotsServer(t, http.StatusServiceUnavailable, "")
+	fake := clock.NewFake(time.Now())
+	metrics := &robotsMetrics{}
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{ErrorTTL: time.Minute, Metrics: metrics, Clock: fake})
+
+	assertAllowed(t, robots, server.URL+"/page", "", false)
+
+	// Not retried within ErrorTTL
+	server.set(http.StatusOK, "User-agent: *\nDisallow: /private")
+	assertAllowed(t, robots, server.URL+"/page", "", false)
+	if n := server.fetches.Load(); n != 1 {
+		t.Errorf("Expected one fetch within ErrorTTL, got %d", n)
+	}
+
+	fake.Advance(2 * time.Minute)
+	assertAllowed(t, robots, server.URL+"/page", "", true)
+	assertAllowed(t, robots, server.URL+"/private", "", false)
+	if metrics.outcomes[crawlers.RobotsOutcomeServerError] != 1 || metrics.outcomes[crawlers.RobotsOutcomeFetched] != 1 {
+		t.Errorf("Expected a server_error then a fetched outcome, got %v", metrics.outcomes)
+	}
+}
+
+func TestRobotsTxt_TooManyRequestsIsServerError(t *testing.T) {
+	server := newRobotsServer(t, http.StatusTooManyRequests, "")
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{})
+
+	assertAllowed(t, robots, server.URL+"/page", "", false)
+}
+
+func TestRobotsTxt_UnreachableDisallows(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "")
+	url := server.URL
+	server.Close()
+
+	metrics := &robotsMetrics{}
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{Metrics: metrics})
+	assertAllowed(t, robots, url+"/page", "", false)
+	if metrics.outcomes[crawlers.RobotsOutcomeUnreachable] != 1 {
+		t.Errorf("Expected an unreachable outcome, got %v", metrics.outcomes)
+	}
+}
+
+func TestRobotsTxt_StaleCopyDuringServerErrors(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private")
+	fake := clock.NewFake(time.Now())
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{
+		TTL:      time.Hour,
+		ErrorTTL: time.Minute,
+		StaleTTL: 24 * time.Hour,
+		Clock:    fake,
+	})
+	assertAllowed(t, robots, server.URL+"/page", "", true)
+
+	// The expired copy stands in while the host fails
+	server.set(http.StatusInternalServerError, "")
+	fake.Advance(2 * time.Hour)
+	assertAllowed(t, robots, server.URL+"/page", "", true)
+	assertAllowed(t, robots, server.URL+"/private", "", false)
+
+	// Until it is older than StaleTTL
+	fake.Advance(24 * time.Hour)
+	assertAllowed(t, robots, server.URL+"/page", "", false)
+}
+
+func TestRobotsTxt_TooManyRedirectsAllowsAll(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/robots.txt?loop="+r.URL.RawQuery+"x", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{})
+	assertAllowed(t, robots, server.URL+"/page", "", true)
+}
+
+func TestRobotsTxt_SharedStore(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private")
+	store := crawlers.NewCacheRobotsStore(&mocks.MockCacheClient{})
+
+	first := crawlers.NewRobotsTxt(crawlers.RobotsConfig{Store: store})
+	assertAllowed(t, first, server.URL+"/private", "", false)
+
+	metrics := &robotsMetrics{}
+	second := crawlers.NewRobotsTxt(crawlers.RobotsConfig{Store: store, Metrics: metrics})
+	assertAllowed(t, second, server.URL+"/private", "", false)
+
+	if n := server.fetches.Load(); n != 1 {
+		t.Errorf("Expected the second worker to use the shared copy, got %d fetches", n)
+	}
+	if metrics.outcomes[crawlers.RobotsOutcomeShared] != 1 {
+		t.Errorf("Expected a shared outcome, got %v", metrics.outcomes)
+	}
+}
+
+func TestRobotsTxt_StoreTTL(t *testing.T) {
+	server := newRobotsServer(t, http.StatusServiceUnavailable, "")
+	var ttl time.Duration
+	client := &mocks.MockCacheClient{}
+	client.SetFunc = func(key string, value interface{}, d time.Duration) error {
+		if !strings.HasPrefix(key, "robots:") {
+			t.Errorf("Expected a robots: key, got %s", key)
+		}
+		ttl = d
+		return nil
+	}
+
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{
+		Store:    crawlers.NewCacheRobotsStore(client),
+		ErrorTTL: 5 * time.Minute,
+	})
+	assertAllowed(t, robots, server.URL+"/page", "", false)
+	if ttl != 5*time.Minute {
+		t.Errorf("Expected a failed robots.txt to be kept for ErrorTTL, got %v", ttl)
+	}
+}
+
+func TestRobotsMiddleware(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private")
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{})
+
+	c := crawlers.NewSoupCrawler(crawlers.NewDefaultSoupClient())
+	c.Use(crawlers.RobotsMiddleware(robots, ""))
+
+	if _, err := c.Fetch(context.Background(), server.URL+"/page"); err != nil {
+		t.Fatalf("Fetch of an allowed URL failed: %v", err)
+	}
+	_, err := c.Fetch(context.Background(), server.URL+"/private")
+	var disallowed *crawlers.RobotsDisallowedError
+	if !errors.As(err, &disallowed) || errs.CodeOf(err) != errs.CodeRobotsDisallowed {
+		t.Errorf("Expected a RobotsDisallowedError, got %v", err)
+	}
+}
+
+func TestCollyClient_Robots(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private")
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{
+		UserAgent: "GolwarcBot/1.0",
+		Robots:    crawlers.NewRobotsTxt(crawlers.RobotsConfig{}),
+	})
+
+	var visited []string
+	client.OnResponse(func(r *colly.Response) {
+		visited = append(visited, r.Request.URL.Path)
+	})
+	_ = client.Visit(server.URL + "/page")
+	_ = client.Visit(server.URL + "/private")
+	client.Wait()
+
+	if len(visited) != 1 || visited[0] != "/page" {
+		t.Errorf("Expected only /page to be visited, got %v", visited)
+	}
+}
+
+func TestSpider_Robots(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private")
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth: 1,
+		Robots:   crawlers.NewRobotsTxt(crawlers.RobotsConfig{}),
+	})
+
+	var mu sync.Mutex
+	var crawled []string
+	spider.OnDocument(func(doc *goquery.Document, url string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		crawled = append(crawled, url)
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/page")
+	spider.AddStartURL(server.URL + "/private")
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if len(crawled) != 1 || crawled[0] != server.URL+"/page" {
+		t.Errorf("Expected only /page to be crawled, got %v", crawled)
+	}
+}
+
+func TestRobotsTxt_CrawlDelay(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, `
+User-agent: *
+Crawl-delay: 2
+
+User-agent: golwarcbot
+Request-rate: 1/10s 0900-1700 # one page every ten seconds
+Crawl-delay: 1
+
+User-agent: slowbot
+User-agent: otherbot
+Request-rate: 3/1m
+`)
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{})
+
+	tests := []struct {
+		agent string
+		want  time.Duration
+	}{
+		{agent: "", want: 2 * time.Second},
+		{agent: "golwarcbot", want: 10 * time.Second}, // The slower of both directives
+		{agent: "slowbot", want: 20 * time.Second},
+		{agent: "otherbot", want: 20 * time.Second},
+	}
+	for _, tt := range tests {
+		delay, err := robots.CrawlDelay(context.Background(), server.URL+"/page", tt.agent)
+		if err != nil {
+			t.Fatalf("CrawlDelay(%q) failed: %v", tt.agent, err)
+		}
+		if delay != tt.want {
+			t.Errorf("CrawlDelay(%q) = %s, want %s", tt.agent, delay, tt.want)
+		}
+	}
+
+	empty := newRobotsServer(t, http.StatusNotFound, "")
+	if delay, err := robots.CrawlDelay(context.Background(), empty.URL+"/", "golwarcbot"); err != nil || delay != 0 {
+		t.Errorf("Expected no delay without robots.txt, got %s, %v", delay, err)
+	}
+}
+
+func TestRateLimiter_HonorCrawlDelay(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nCrawl-delay: 5\n")
+	robots := crawlers.NewRobotsTxt(crawlers.RobotsConfig{})
+
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: time.Second})
+	limiter.HonorCrawlDelay(robots, "golwarcbot", 0)
+	if err := limiter.Wait(context.Background(), server.URL+"/a"); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if got := limiter.Delay(server.URL + "/b"); got != 5*time.Second {
+		t.Errorf("Expected the robots.txt Crawl-delay, got %s", got)
+	}
+
+	capped := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: time.Second})
+	capped.HonorCrawlDelay(robots, "golwarcbot", 3*time.Second)
+	if err := capped.Wait(context.Background(), server.URL+"/a"); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if got := capped.Delay(server.URL + "/b"); got != 3*time.Second {
+		t.Errorf("Expected the Crawl-delay capped at 3s, got %s", got)
+	}
+
+	// A faster Crawl-delay does not speed up the configured limit
+	slow := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{Delay: 10 * time.Second})
+	slow.HonorCrawlDelay(robots, "golwarcbot", 0)
+	if err := slow.Wait(context.Background(), server.URL+"/a"); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if got := slow.Delay(server.URL + "/b"); got != 10*time.Second {
+		t.Errorf("Expected the configured delay to win, got %s", got)
+	}
+}
@@ -0,0 +1,127 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/tebeka/selenium"
+)
+
+// =============================================================================
+// Selenium Wait Tests
+// =============================================================================
+
+// fakeDriver answers lookups from the number of times it was polled
+type fakeDriver struct {
+	selenium.WebDriver
+	polls   atomic.Int32
+	appear  int32 // Poll from which the element exists
+	enabled bool
+	url     string
+	script  interface{}
+	err     error
+}
+
+func (d *fakeDriver) FindElement(by, value string) (selenium.WebElement, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+	if d.polls.Add(1) < d.appear {
+		return nil, &selenium.Error{Err: "no such element"}
+	}
+	return &fakeElement{displayed: true, enabled: d.enabled}, nil
+}
+
+func (d *fakeDriver) CurrentURL() (string, error) {
+	d.polls.Add(1)
+	return d.url, nil
+}
+
+func (d *fakeDriver) ExecuteScript(script string, args []interface{}) (interface{}, error) {
+	d.polls.Add(1)
+	return d.script, nil
+}
+
+type fakeElement struct {
+	selenium.WebElement
+	displayed bool
+	enabled   bool
+}
+
+func (e *fakeElement) IsDisplayed() (bool, error) { return e.displayed, nil }
+func (e *fakeElement) IsEnabled() (bool, error)   { return e.enabled, nil }
+
+func TestPollCondition_ElementAppears(t *testing.T) {
+	driver := &fakeDriver{appear: 3, enabled: true}
+
+	err := crawlers.PollCondition(context.Background(), driver, crawlers.ElementVisible(selenium.ByCSSSelector, "#results"), time.Second, time.Millisecond)
+	if err != nil {
+		t.Fatalf("PollCondition() error = %v", err)
+	}
+	if polls := driver.polls.Load(); polls != 3 {
+		t.Errorf("Expected 3 polls, got %d", polls)
+	}
+}
+
+func TestPollCondition_Timeout(t *testing.T) {
+	driver := &fakeDriver{appear: 1, enabled: false}
+
+	err := crawlers.PollCondition(context.Background(), driver, crawlers.ElementClickable(selenium.ByCSSSelector, "#submit"), 20*time.Millisecond, time.Millisecond)
+	if !errors.Is(err, context.DeadlineExceeded) || errs.CodeOf(err) != errs.CodeDeadlineExceeded {
+		t.Fatalf("PollCondition() error = %v, want deadline exceeded", err)
+	}
+	if driver.polls.Load() < 2 {
+		t.Errorf("Expected the condition to be polled repeatedly, got %d polls", driver.polls.Load())
+	}
+}
+
+func TestPollCondition_ErrorEndsWait(t *testing.T) {
+	driver := &fakeDriver{err: &selenium.Error{Err: "invalid session id"}}
+
+	err := crawlers.PollCondition(context.Background(), driver, crawlers.ElementPresent(selenium.ByCSSSelector, "#x"), time.Second, time.Millisecond)
+	var webErr *selenium.Error
+	if !errors.As(err, &webErr) || webErr.Err != "invalid session id" {
+		t.Errorf("PollCondition() error = %v, want the driver error", err)
+	}
+}
+
+func TestPollCondition_URLAndScript(t *testing.T) {
+	ctx := context.Background()
+	driver := &fakeDriver{url: "https://shop.example.com/checkout/done?id=7"}
+
+	if err := crawlers.PollCondition(ctx, driver, crawlers.URLMatches(regexp.MustCompile(`/checkout/done`)), time.Second, time.Millisecond); err != nil {
+		t.Errorf("URLMatches error = %v", err)
+	}
+	if err := crawlers.PollCondition(ctx, driver, crawlers.URLContains("id=7"), time.Second, time.Millisecond); err != nil {
+		t.Errorf("URLContains error = %v", err)
+	}
+
+	for _, result := range []interface{}{true, 1.0, "ready", map[string]interface{}{}} {
+		driver.script = result
+		if err := crawlers.PollCondition(ctx, driver, crawlers.ScriptCondition("return window.ready"), time.Second, time.Millisecond); err != nil {
+			t.Errorf("ScriptCondition with result %v error = %v", result, err)
+		}
+	}
+	for _, result := range []interface{}{nil, false, 0.0, ""} {
+		driver.script = result
+		if err := crawlers.PollCondition(ctx, driver, crawlers.ScriptCondition("return window.ready"), 5*time.Millisecond, time.Millisecond); err == nil {
+			t.Errorf("ScriptCondition with result %v succeeded, want a timeout", result)
+		}
+	}
+}
+
+func TestPollCondition_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := crawlers.PollCondition(ctx, &fakeDriver{url: "about:blank"}, crawlers.URLContains("example"), 0, time.Millisecond)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("PollCondition() error = %v, want canceled", err)
+	}
+}
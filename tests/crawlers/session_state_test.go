@@ -0,0 +1,127 @@
+package crawlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/playwright-community/playwright-go"
+)
+
+// =============================================================================
+// Session State Tests
+// =============================================================================
+
+func TestSessionState_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "login.state")
+	state := &playwright.StorageState{
+		Cookies: []playwright.Cookie{{Name: "sid", Value: "secret-token", Domain: "example.com", Path: "/", Expires: -1}},
+		Origins: []playwright.Origin{{Origin: "https://example.com", LocalStorage: []playwright.NameValue{{Name: "jwt", Value: "abc"}}}},
+	}
+	if err := crawlers.SaveSessionState(path, "correct horse", state); err != nil {
+		t.Fatalf("SaveSessionState() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("File mode = %v, want 0600", info.Mode().Perm())
+	}
+	data, _ := os.ReadFile(path)
+	if bytes.Contains(data, []byte("secret-token")) || bytes.Contains(data, []byte("jwt")) {
+		t.Error("Session state is stored in the clear")
+	}
+
+	loaded, err := crawlers.LoadSessionState(path, "correct horse")
+	if err != nil {
+		t.Fatalf("LoadSessionState() error = %v", err)
+	}
+	if len(loaded.Cookies) != 1 || loaded.Cookies[0].Value != "secret-token" {
+		t.Errorf("Cookies = %+v", loaded.Cookies)
+	}
+	if len(loaded.Origins) != 1 || loaded.Origins[0].LocalStorage[0].Value != "abc" {
+		t.Errorf("Origins = %+v", loaded.Origins)
+	}
+
+	if _, err := crawlers.LoadSessionState(path, "wrong"); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("LoadSessionState() with a wrong passphrase error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+	data[len(data)-1] ^= 1
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := crawlers.LoadSessionState(path, "correct horse"); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("LoadSessionState() of a tampered file error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+}
+
+func TestSessionState_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	if err := crawlers.SaveSessionState(filepath.Join(dir, "x.state"), "", &playwright.StorageState{}); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("SaveSessionState() without passphrase error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+
+	plain := filepath.Join(dir, "plain.json")
+	if err := os.WriteFile(plain, []byte(`{"cookies":[],"origins":[]}`), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := crawlers.LoadSessionState(plain, "key"); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("LoadSessionState() of a plain file error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+	if _, err := crawlers.LoadSessionState(filepath.Join(dir, "missing"), "key"); err == nil {
+		t.Error("LoadSessionState() of a missing file should fail")
+	}
+}
+
+func TestPlaywrightSession_ImportState(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "sid", Value: "42", Path: "/", MaxAge: 3600})
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><body><script>if (location.pathname === '/login') localStorage.setItem('user', 'ada')</script></body></html>`))
+	}))
+	defer server.Close()
+
+	client, err := crawlers.NewPlaywrightClient(crawlers.PlaywrightConfig{Headless: true})
+	if err != nil {
+		t.Skipf("Skipping Playwright session tests: browser not available (%v)", err)
+	}
+	defer client.Close()
+
+	if err := client.Navigate(server.URL + "/login"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "login.state")
+	if err := client.ExportSessionState(path, "key"); err != nil {
+		t.Fatalf("ExportSessionState() error = %v", err)
+	}
+
+	session, err := client.NewSession(crawlers.SessionOptions{StatePath: path, StatePassphrase: "key"})
+	if err != nil {
+		t.Fatalf("NewSession() error = %v", err)
+	}
+	defer session.Close()
+
+	cookies, err := session.GetCookies()
+	if err != nil || len(cookies) != 1 || cookies[0].Value != "42" {
+		t.Errorf("Imported cookies = %+v, %v", cookies, err)
+	}
+	if err := session.Navigate(server.URL + "/"); err != nil {
+		t.Fatalf("Navigate() error = %v", err)
+	}
+	if user, err := session.Evaluate(`() => localStorage.getItem('user')`); err != nil || user != "ada" {
+		t.Errorf("Imported localStorage user = %v, %v", user, err)
+	}
+
+	if _, err := client.NewSession(crawlers.SessionOptions{StatePath: path, StatePassphrase: "wrong"}); !errs.HasCode(err, errs.CodeInvalidConfig) {
+		t.Errorf("NewSession() with a wrong passphrase error = %v, want %s", err, errs.CodeInvalidConfig)
+	}
+}
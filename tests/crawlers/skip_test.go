@@ -0,0 +1,167 @@
+package crawlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+)
+
+// =============================================================================
+// Skip Reason Tests
+// =============================================================================
+
+func TestSkipDecisionOf(t *testing.T) {
+	err := &crawlers.SkippedError{Decision: crawlers.SkipDecision{URL: "https://example.com/", Reason: crawlers.SkipBudget, Rule: "max pages 10"}}
+	decision, ok := crawlers.SkipDecisionOf(errs.Wrap(err, errs.CodeFetchFailed, "visit failed"))
+	if !ok || decision.Reason != crawlers.SkipBudget || decision.String() != "budget (max pages 10)" {
+		t.Errorf("SkipDecisionOf() = %+v, %v", decision, ok)
+	}
+	if code := errs.CodeOf(err); code != errs.CodeURLSkipped {
+		t.Errorf("Expected %s, got %s", errs.CodeURLSkipped, code)
+	}
+
+	robots := &crawlers.SkippedError{Decision: crawlers.SkipDecision{Reason: crawlers.SkipRobots}}
+	if code := errs.CodeOf(robots); code != errs.CodeRobotsDisallowed {
+		t.Errorf("Expected %s for robots, got %s", errs.CodeRobotsDisallowed, code)
+	}
+
+	if _, ok := crawlers.SkipDecisionOf(errs.New(errs.CodeFetchFailed, "timeout")); ok {
+		t.Error("Expected no decision for a crawl failure")
+	}
+}
+
+func TestSpider_SkipReasons(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			_, _ = w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/":
+			_, _ = w.Write([]byte(`<html><body>
+				<a href="/">home</a>
+				<a href="/page">page</a>
+				<a href="/page">page again</a>
+				<a href="/admin/users">admin</a>
+				<a href="/login">login</a>
+				<a href="/private">private</a>
+				<a href="http://10.1.2.3/">internal</a>
+			</body></html>`))
+		default:
+			_, _ = w.Write([]byte(`<html><body><a href="/deep">deep</a></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:    1,
+		Concurrency: 1,
+		URLRules: urlmatch.MustCompile([]urlmatch.Rule{
+			{Pattern: "*/admin/*", Action: urlmatch.Deny},
+			{Pattern: "glob:*/login*", Action: urlmatch.Deny},
+		}),
+		URLPolicy: &libs.ValidationPolicy{AllowPrivate: true, BlockedCIDRs: []string{"10.0.0.0/8"}},
+		Robots:    crawlers.NewRobotsTxt(crawlers.RobotsConfig{}),
+	})
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		for _, link := range spider.ExtractLinks(doc, "a") {
+			resolved, err := spider.ResolveURL(crawl.URL, link)
+			if err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+
+	var mu sync.Mutex
+	byURL := make(map[string]crawlers.SkipDecision)
+	spider.OnSkip(func(d crawlers.SkipDecision) {
+		mu.Lock()
+		defer mu.Unlock()
+		byURL[d.URL] = d
+	})
+	spider.AddStartURL(server.URL + "/")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	tests := []struct {
+		url    string
+		reason string
+	}{
+		{server.URL + "/admin/users", crawlers.SkipURLRule},
+		{server.URL + "/login", crawlers.SkipURLRule},
+		{server.URL + "/private", crawlers.SkipRobots},
+		{server.URL + "/deep", crawlers.SkipDepth},
+		{server.URL + "/", crawlers.SkipDuplicate},
+		{server.URL + "/page", crawlers.SkipDuplicate},
+		{"http://10.1.2.3/", crawlers.SkipSSRF},
+	}
+	for _, tt := range tests {
+		if d, ok := byURL[tt.url]; !ok || d.Reason != tt.reason {
+			t.Errorf("Skip of %s = %+v, want reason %s", tt.url, d, tt.reason)
+		}
+	}
+	if d := byURL[server.URL+"/admin/users"]; d.Rule != "deny */admin/*" || d.ParentURL != server.URL+"/" {
+		t.Errorf("Unexpected URL rule decision: %+v", d)
+	}
+	if d := byURL[server.URL+"/login"]; d.Rule != "deny glob:*/login*" {
+		t.Errorf("Unexpected glob rule decision: %+v", d)
+	}
+
+	summary := spider.SkipSummary()
+	want := map[string]int{
+		crawlers.SkipURLRule:   2,
+		crawlers.SkipRobots:    1,
+		crawlers.SkipDepth:     1,
+		crawlers.SkipDuplicate: 2,
+		crawlers.SkipSSRF:      1,
+	}
+	for reason, n := range want {
+		if summary.ByReason[reason] != n {
+			t.Errorf("ByReason[%s] = %d, want %d (%v)", reason, summary.ByReason[reason], n, summary.ByReason)
+		}
+	}
+	if summary.Total != 7 || len(summary.First) != 7 {
+		t.Errorf("Expected 7 skips, got %d (%d recorded)", summary.Total, len(summary.First))
+	}
+	if summary.ByRule["url_rule (deny glob:*/login*)"] != 1 {
+		t.Errorf("Expected the filter rule to be counted, got %v", summary.ByRule)
+	}
+}
+
+func TestCollyClient_SkipReasons(t *testing.T) {
+	server := newRobotsServer(t, http.StatusOK, "User-agent: *\nDisallow: /private")
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{Robots: crawlers.NewRobotsTxt(crawlers.RobotsConfig{})})
+	var skipped []crawlers.SkipDecision
+	client.OnSkip(func(d crawlers.SkipDecision) {
+		skipped = append(skipped, d)
+	})
+
+	if err := client.VisitContext(context.Background(), server.URL+"/page"); err != nil {
+		t.Fatalf("VisitContext(/page) error = %v", err)
+	}
+	err := client.VisitContext(context.Background(), server.URL+"/private")
+	decision, ok := crawlers.SkipDecisionOf(err)
+	if !ok || decision.Reason != crawlers.SkipRobots || decision.URL != server.URL+"/private" {
+		t.Fatalf("VisitContext(/private) = %v, want a robots skip", err)
+	}
+	if code := errs.CodeOf(err); code != errs.CodeRobotsDisallowed {
+		t.Errorf("Expected %s, got %s", errs.CodeRobotsDisallowed, code)
+	}
+
+	if len(skipped) != 1 || skipped[0].Reason != crawlers.SkipRobots {
+		t.Errorf("Expected one robots skip, got %+v", skipped)
+	}
+	if summary := client.SkipSummary(); summary.Total != 1 || summary.ByReason[crawlers.SkipRobots] != 1 {
+		t.Errorf("Unexpected summary: %+v", summary)
+	}
+}
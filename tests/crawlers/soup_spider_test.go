@@ -10,6 +10,7 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/anaskhan96/soup"
 )
 
 // =============================================================================
@@ -303,8 +304,10 @@ func TestSpider_ExtractLinksWithCascadia(t *testing.T) {
 	spider := crawlers.NewDefaultSpider()
 	links := spider.ExtractLinksWithCascadia(doc, ".content a")
 
-	// Should extract links from .content div
-	t.Logf("Found %d links", len(links))
+	// Should extract links from .content div, each once
+	if len(links) != 2 || links[0] != "/page1" || links[1] != "/page2" {
+		t.Errorf("ExtractLinksWithCascadia() = %v, want [/page1 /page2]", links)
+	}
 }
 
 func TestSpider_ResolveURL(t *testing.T) {
@@ -421,6 +424,35 @@ func TestSpiderConfig_CustomValues(t *testing.T) {
 // Soup Client Method Tests (Unit)
 // =============================================================================
 
+func TestSoupClient_MissingElements(t *testing.T) {
+	client := crawlers.NewDefaultSoupClient()
+	doc := soup.HTMLParse(`<html><body><p>no title</p></body></html>`)
+
+	// A failed Find returns an empty Root that soup's accessors panic on
+	title := client.Find(doc, "title", nil)
+	if got := client.GetText(title); got != "" {
+		t.Errorf("GetText(missing) = %q, want empty", got)
+	}
+	if got := client.GetFullText(client.FindByID(doc, "main")); got != "" {
+		t.Errorf("GetFullText(missing) = %q, want empty", got)
+	}
+	if got := client.GetHTML(title); got != "" {
+		t.Errorf("GetHTML(missing) = %q, want empty", got)
+	}
+	if got := client.GetAllAttributes(title); got != nil {
+		t.Errorf("GetAllAttributes(missing) = %v, want nil", got)
+	}
+	if got := client.FindBySelector(doc, "id", "main"); len(got) != 0 {
+		t.Errorf("FindBySelector(id, missing) = %d elements, want 0", len(got))
+	}
+	if got := client.FindLinks(soup.Root{}); got != nil {
+		t.Errorf("FindLinks(empty root) = %v, want nil", got)
+	}
+	if got := client.ParseTable(soup.Root{}, nil); len(got) != 0 {
+		t.Errorf("ParseTable(empty root) = %v, want no rows", got)
+	}
+}
+
 func TestSoupConfig_Defaults(t *testing.T) {
 	config := crawlers.SoupConfig{}
 	client := crawlers.NewSoupClient(config)
@@ -458,3 +490,56 @@ func BenchmarkNewSoupClient(b *testing.B) {
 		crawlers.NewSoupClient(config)
 	}
 }
+
+func TestSpider_OnDocumentContext(t *testing.T) {
+	// A chain / -> /a -> /b -> /c
+	next := map[string]string{"/": "/a", "/a": "/b", "/b": "/c"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="` + next[r.URL.Path] + `">next</a></body></html>`))
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 2, Concurrency: 1})
+	spider.AddStartURL(server.URL + "/")
+
+	var mu sync.Mutex
+	seen := make(map[string]crawlers.CrawlContext)
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		mu.Lock()
+		seen[strings.TrimPrefix(crawl.URL, server.URL)] = crawl
+		mu.Unlock()
+		for _, link := range spider.ExtractLinks(doc, "a") {
+			if link != "" {
+				spider.AddURL(server.URL+link, crawl)
+			}
+		}
+		return nil
+	})
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(seen) != 3 {
+		t.Fatalf("Crawled %v, want /, /a and /b within MaxDepth 2", seen)
+	}
+	tests := []struct {
+		path   string
+		depth  int
+		parent string
+	}{
+		{"/", 0, ""},
+		{"/a", 1, server.URL + "/"},
+		{"/b", 2, server.URL + "/a"},
+	}
+	for _, tt := range tests {
+		crawl := seen[tt.path]
+		if crawl.Depth != tt.depth || crawl.ParentURL != tt.parent {
+			t.Errorf("%s: Depth = %d, ParentURL = %q; want %d, %q", tt.path, crawl.Depth, crawl.ParentURL, tt.depth, tt.parent)
+		}
+		if crawl.DiscoveredAt.IsZero() || crawl.Retries != 0 {
+			t.Errorf("%s: DiscoveredAt = %v, Retries = %d; want set and 0", tt.path, crawl.DiscoveredAt, crawl.Retries)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package crawlers_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+)
+
+// =============================================================================
+// Soup Body Size Tests
+// =============================================================================
+
+// newBodyServer serves size bytes of HTML at /fixed with a Content-Length and
+// at /chunked without one
+func newBodyServer(t *testing.T, size int) *httptest.Server {
+	t.Helper()
+	body := "<html><body>" + strings.Repeat("x", size) + "</body></html>"
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/chunked" {
+			_, _ = io.WriteString(w, body[:len(body)/2])
+			w.(http.Flusher).Flush()
+			_, _ = io.WriteString(w, body[len(body)/2:])
+			return
+		}
+		_, _ = io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSoupClient_MaxBodySize(t *testing.T) {
+	server := newBodyServer(t, 4096)
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{MaxBodySize: 1024})
+
+	for _, path := range []string{"/fixed", "/chunked"} {
+		_, err := client.Get(server.URL + path)
+		if errs.CodeOf(err) != errs.CodeBodyTooLarge {
+			t.Errorf("Get(%s) error = %v, want %s", path, err, errs.CodeBodyTooLarge)
+		}
+	}
+}
+
+func TestSoupClient_MaxBodySize_WithinLimit(t *testing.T) {
+	server := newBodyServer(t, 512)
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{MaxBodySize: 1024})
+
+	for _, path := range []string{"/fixed", "/chunked"} {
+		doc, err := client.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", path, err)
+		}
+		if got := len(client.GetText(client.Find(doc, "body", nil))); got != 512 {
+			t.Errorf("Get(%s) body text length = %d, want 512", path, got)
+		}
+	}
+}
+
+// =============================================================================
+// Soup Stream Tests
+// =============================================================================
+
+func TestSoupClient_GetStream(t *testing.T) {
+	server := newBodyServer(t, 4096)
+	// Streams bypass MaxBodySize so large downloads can go to disk
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{MaxBodySize: 1024})
+
+	resp, err := client.GetStream(context.Background(), server.URL+"/chunked")
+	if err != nil {
+		t.Fatalf("GetStream() error = %v", err)
+	}
+	defer func() {
+		_ = resp.Body.Close() // Error intentionally ignored on close
+	}()
+
+	path := filepath.Join(t.TempDir(), "page.html")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	written, err := io.Copy(file, resp.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+
+	want := int64(len("<html><body></body></html>") + 4096)
+	if written != want {
+		t.Errorf("Copied %d bytes, want %d", written, want)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != want {
+		t.Errorf("File size = %v, %v; want %d", info, err, want)
+	}
+}
+
+func TestSoupClient_GetStream_HoldsLimiterUntilClose(t *testing.T) {
+	server := newBodyServer(t, 64)
+	limiter := crawlers.NewRateLimiter(crawlers.RateLimiterConfig{MaxConcurrent: 1})
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{RateLimiter: limiter})
+
+	first, err := client.GetStream(context.Background(), server.URL+"/fixed")
+	if err != nil {
+		t.Fatalf("GetStream() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetStream(ctx, server.URL+"/fixed"); err == nil {
+		t.Fatal("Expected second stream to wait for the open body")
+	}
+
+	if err := first.Body.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	second, err := client.GetStream(context.Background(), server.URL+"/fixed")
+	if err != nil {
+		t.Fatalf("GetStream() after Close error = %v", err)
+	}
+	_, _ = io.Copy(io.Discard, second.Body)
+	_ = second.Body.Close() // Error intentionally ignored on close
+}
@@ -0,0 +1,213 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+)
+
+// =============================================================================
+// Spider Concurrency Tests
+// =============================================================================
+
+func TestFatal(t *testing.T) {
+	if crawlers.Fatal(nil) != nil {
+		t.Error("Fatal(nil) should be nil")
+	}
+
+	cause := errors.New("database gone")
+	err := crawlers.Fatal(cause)
+	if !errors.Is(err, cause) {
+		t.Error("Expected Fatal to wrap its cause")
+	}
+	var fatal *crawlers.FatalError
+	if !errors.As(err, &fatal) {
+		t.Fatal("Expected a *FatalError")
+	}
+	if fatal.Error() != "fatal crawl error: database gone" {
+		t.Errorf("Error() = %q", fatal.Error())
+	}
+}
+
+func TestSpider_FatalErrorEndsRun(t *testing.T) {
+	server := newStressSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 10, Concurrency: 8})
+	cause := errors.New("storage unavailable")
+	var pages atomic.Int32
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		if pages.Add(1) == 10 {
+			return crawlers.Fatal(cause)
+		}
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/n/0")
+
+	done := make(chan error, 1)
+	go func() { done <- spider.Run() }()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected a fatal error to end the crawl")
+	}
+	if !errors.Is(err, cause) {
+		t.Fatalf("Run() error = %v, want %v", err, cause)
+	}
+	var fatal *crawlers.FatalError
+	if !errors.As(err, &fatal) || fatal.URL == "" {
+		t.Errorf("Expected a FatalError naming the page, got %v", err)
+	}
+	if spider.IsRunning() {
+		t.Error("Expected spider to stop running")
+	}
+	if state := spider.State(); len(state.Queue) == 0 {
+		t.Error("Expected the rest of the crawl to stay queued")
+	}
+}
+
+func TestSpider_NonFatalErrorsContinue(t *testing.T) {
+	server := newStressSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Concurrency: 4})
+	var pages atomic.Int32
+	spider.OnDocument(func(*goquery.Document, string) error {
+		pages.Add(1)
+		return errors.New("parse failed")
+	})
+	for n := 0; n < 5; n++ {
+		spider.AddStartURL(fmt.Sprintf("%s/n/%d", server.URL, n))
+	}
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v, want nil", err)
+	}
+	if pages.Load() != 5 {
+		t.Errorf("Handled %d pages, want 5", pages.Load())
+	}
+}
+
+func TestSpider_CancelAbortsInFlight(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Concurrency: 2, Timeout: time.Minute})
+	for n := 0; n < 6; n++ {
+		spider.AddStartURL(fmt.Sprintf("%s/slow/%d", server.URL, n))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- spider.RunContext(ctx) }()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for requests.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("RunContext() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected cancellation to abort in-flight requests")
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("Server saw %d requests, want 2 with Concurrency 2", got)
+	}
+
+	// Aborted and unstarted URLs are all left for a later run
+	if state := spider.State(); len(state.Queue) != 6 {
+		t.Errorf("Expected 6 queued URLs after cancel, got %d", len(state.Queue))
+	}
+}
+
+func TestSpider_ConcurrencyBound(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, "<html><body>ok</body></html>")
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Concurrency: 3})
+	for n := 0; n < 12; n++ {
+		spider.AddStartURL(fmt.Sprintf("%s/page/%d", server.URL, n))
+	}
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := peak.Load(); got > 3 {
+		t.Errorf("Peak concurrency = %d, want at most 3", got)
+	}
+	if got := spider.GetVisitedCount(); got != 12 {
+		t.Errorf("GetVisitedCount() = %d, want 12", got)
+	}
+}
+
+func TestSpider_Frontier_FatalErrorEndsRun(t *testing.T) {
+	server := newStressSite(t)
+	shared := frontier.NewMemoryFrontier(frontier.MemoryConfig{})
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:     3,
+		Concurrency:  4,
+		Frontier:     shared,
+		FrontierPoll: 10 * time.Millisecond,
+	})
+	cause := errors.New("storage unavailable")
+	spider.OnDocument(func(*goquery.Document, string) error {
+		return crawlers.Fatal(cause)
+	})
+	spider.AddStartURL(server.URL + "/n/0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := spider.RunContext(ctx); !errors.Is(err, cause) {
+		t.Fatalf("RunContext() error = %v, want %v", err, cause)
+	}
+
+	// The failed URL is back in the frontier for a retry
+	stats, err := shared.Stats(context.Background())
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Idle() {
+		t.Error("Expected the failed URL to stay in the frontier")
+	}
+}
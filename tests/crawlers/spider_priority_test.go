@@ -0,0 +1,141 @@
+package crawlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+)
+
+// =============================================================================
+// Spider Priority Queue Tests
+// =============================================================================
+
+// orderServer serves pages linking to links and records the order paths
+// are fetched in
+func orderServer(t *testing.T, links ...string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		order []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		order = append(order, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path != "/" {
+			_, _ = fmt.Fprint(w, `<html><body></body></html>`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `<html><body>`)
+		for _, link := range links {
+			_, _ = fmt.Fprintf(w, `<a href="%s">%s</a>`, link, link)
+		}
+		_, _ = fmt.Fprint(w, `</body></html>`)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), order...)
+	}
+}
+
+// followChildLinks queues every link of a page one level deeper
+func followChildLinks(spider *crawlers.Spider) {
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if abs, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(abs, crawl)
+			}
+		}
+		return nil
+	})
+}
+
+func TestSpider_PriorityRules(t *testing.T) {
+	server, order := orderServer(t, "/low", "/news/a", "/other")
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:    1,
+		Concurrency: 1,
+		URLRules: urlmatch.MustCompile([]urlmatch.Rule{
+			{Pattern: "*/news/", Action: urlmatch.Allow, Priority: 10},
+			{Pattern: "*/low", Action: urlmatch.Allow, Priority: -5},
+		}),
+	})
+	followChildLinks(spider)
+	spider.AddStartURL(server.URL + "/")
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := fmt.Sprint(order())
+	if want := fmt.Sprint([]string{"/", "/news/a", "/other", "/low"}); got != want {
+		t.Errorf("Crawl order = %s, want %s", got, want)
+	}
+}
+
+func TestSpider_PriorityDomainQuota(t *testing.T) {
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Priority: crawlers.PriorityConfig{Domain: 1}})
+	spider.AddStartURL("https://a.example/1")
+	spider.AddStartURL("https://a.example/2")
+	spider.AddStartURL("https://a.example/3")
+	spider.AddStartURL("https://b.example/1")
+	spider.AddStartURL("https://b.example/2")
+
+	var got []string
+	for _, item := range spider.State().Queue {
+		got = append(got, item.URL)
+	}
+	want := []string{"https://a.example/1", "https://b.example/1", "https://a.example/2", "https://b.example/2", "https://a.example/3"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("Queue = %v, want hosts interleaved %v", got, want)
+	}
+}
+
+func TestSpider_PrioritySitemapUnderBudget(t *testing.T) {
+	server, order := orderServer(t)
+	now := time.Now()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		Concurrency: 1,
+		MaxPages:    2,
+		Priority:    crawlers.PriorityConfig{Sitemap: 1, Freshness: 1, FreshnessHalfLife: 24 * time.Hour},
+	})
+	spider.AddSitemapURL(server.URL+"/archive", 0.5, now.Add(-30*24*time.Hour))
+	spider.AddSitemapURL(server.URL+"/home", 1.0, now)
+	spider.AddSitemapURL(server.URL+"/fresh", 0.5, now.Add(-time.Hour))
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := fmt.Sprint(order())
+	if want := fmt.Sprint([]string{"/home", "/fresh"}); got != want {
+		t.Errorf("Crawl order = %s, want %s", got, want)
+	}
+	if skipped := spider.BudgetSummary().Skipped; skipped != 1 {
+		t.Errorf("Expected /archive to be skipped by the budget, skipped %d", skipped)
+	}
+}
+
+func TestSpider_PriorityStateRoundTrip(t *testing.T) {
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Priority: crawlers.PriorityConfig{Sitemap: 1}})
+	spider.AddSitemapURL("https://example.com/low", 0.1, time.Time{})
+	spider.AddSitemapURL("https://example.com/high", 0.9, time.Time{})
+
+	restored := crawlers.NewSpider(crawlers.SpiderConfig{})
+	if err := restored.RestoreState(spider.State()); err != nil {
+		t.Fatalf("RestoreState() error = %v", err)
+	}
+	if queue := restored.State().Queue; len(queue) != 2 || queue[0].URL != "https://example.com/high" {
+		t.Errorf("Expected the restored queue to keep its priorities, got %+v", queue)
+	}
+}
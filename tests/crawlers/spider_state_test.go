@@ -0,0 +1,167 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+)
+
+// =============================================================================
+// Spider State Tests
+// =============================================================================
+
+// recordingServer serves pages and records fetched paths; /slow blocks until
+// the client goes away while block is set
+func recordingServer(t *testing.T, block *atomic.Bool) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" && block.Load() {
+			<-r.Context().Done()
+			return
+		}
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+		_, _ = w.Write([]byte(`<html></html>`))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		got := append([]string(nil), paths...)
+		sort.Strings(got)
+		return got
+	}
+}
+
+func TestSpider_SaveLoadState_ResumesInterruptedCrawl(t *testing.T) {
+	var block atomic.Bool
+	block.Store(true)
+	server, fetched := recordingServer(t, &block)
+	store := crawlers.NewFileSpiderStateStore(filepath.Join(t.TempDir(), "state", "spider.json"))
+
+	first := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1})
+	first.OnDocument(func(*goquery.Document, string) error { return nil })
+	first.AddStartURL(server.URL + "/fast")
+	first.AddStartURL(server.URL + "/slow")
+	first.AddStartURL(server.URL + "/later")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	if err := first.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("RunContext() error = %v, want deadline exceeded", err)
+	}
+	if err := first.SaveState(store); err != nil {
+		t.Fatalf("SaveState() error = %v", err)
+	}
+
+	state := first.State()
+	if len(state.Visited) != 1 || state.Visited[0] != server.URL+"/fast" {
+		t.Errorf("Visited = %v, want only /fast", state.Visited)
+	}
+	if len(state.Queue) != 2 {
+		t.Errorf("Queue = %+v, want /slow and /later", state.Queue)
+	}
+
+	// A new process resumes where the first stopped
+	block.Store(false)
+	second := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1})
+	second.OnDocument(func(*goquery.Document, string) error { return nil })
+	loaded, err := second.LoadState(store)
+	if err != nil || !loaded {
+		t.Fatalf("LoadState() = %v, %v; want true", loaded, err)
+	}
+	if err := second.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	got := fetched()
+	want := []string{"/fast", "/later", "/slow"}
+	if len(got) != len(want) {
+		t.Fatalf("Fetched %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Fetched %v, want %v", got, want)
+		}
+	}
+}
+
+func TestSpider_State_KeepsCrawlContext(t *testing.T) {
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 3})
+	spider.AddURL("https://example.com/child", crawlers.CrawlContext{URL: "https://example.com/", Depth: 1})
+
+	restored := crawlers.NewSpider(crawlers.SpiderConfig{})
+	if err := restored.RestoreState(spider.State()); err != nil {
+		t.Fatalf("RestoreState() error = %v", err)
+	}
+
+	queue := restored.State().Queue
+	if len(queue) != 1 {
+		t.Fatalf("Queue = %+v, want one URL", queue)
+	}
+	if queue[0].Depth != 2 || queue[0].ParentURL != "https://example.com/" {
+		t.Errorf("Queue[0] = %+v, want depth 2 with parent https://example.com/", queue[0])
+	}
+}
+
+func TestSpider_LoadState_Empty(t *testing.T) {
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{})
+
+	loaded, err := spider.LoadState(crawlers.NewFileSpiderStateStore(filepath.Join(t.TempDir(), "missing.json")))
+	if err != nil || loaded {
+		t.Errorf("LoadState() = %v, %v; want false, nil", loaded, err)
+	}
+
+	loaded, err = spider.LoadState(crawlers.NewCacheSpiderStateStore(&mocks.MockCacheClient{}, "spider:state", 0))
+	if err != nil || loaded {
+		t.Errorf("LoadState() from empty cache = %v, %v; want false, nil", loaded, err)
+	}
+}
+
+func TestSpider_RestoreState_BadVersion(t *testing.T) {
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{})
+	if err := spider.RestoreState(crawlers.SpiderState{Version: 99}); err == nil {
+		t.Error("Expected error for unknown state version")
+	}
+}
+
+func TestSpider_Checkpoint(t *testing.T) {
+	var block atomic.Bool
+	server, _ := recordingServer(t, &block)
+	mockCache := &mocks.MockCacheClient{}
+	store := crawlers.NewCacheSpiderStateStore(mockCache, "spider:state", time.Hour)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Checkpoint: store, CheckpointEvery: 10 * time.Millisecond})
+	spider.OnDocument(func(*goquery.Document, string) error { return nil })
+	spider.AddStartURL(server.URL + "/a")
+	spider.AddStartURL(server.URL + "/b")
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// The final checkpoint records the finished crawl
+	resumed := crawlers.NewSpider(crawlers.SpiderConfig{})
+	loaded, err := resumed.LoadState(store)
+	if err != nil || !loaded {
+		t.Fatalf("LoadState() = %v, %v; want true", loaded, err)
+	}
+	state := resumed.State()
+	if len(state.Visited) != 2 || len(state.Queue) != 0 {
+		t.Errorf("State() = %d visited, %d queued; want 2 and 0", len(state.Visited), len(state.Queue))
+	}
+}
@@ -0,0 +1,338 @@
+package crawlers_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/frontier"
+)
+
+// Stress tests run the Spider with many workers over a densely linked site
+// while other goroutines read its state. Run them with -race
+
+// =============================================================================
+// Stress Site
+// =============================================================================
+
+// stressFanout is the number of child links on each stress site page
+const stressFanout = 6
+
+// newStressSite serves a tree of pages where /n/N links to its stressFanout
+// children, back to the root and to its parent, so most discovered links are
+// duplicates of URLs already queued or visited
+func newStressSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/n/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		var links strings.Builder
+		for i := 1; i <= stressFanout; i++ {
+			fmt.Fprintf(&links, `<a href="/n/%d">child</a>`, n*stressFanout+i)
+		}
+		fmt.Fprintf(&links, `<a href="/n/0">root</a><a href="/n/%d">parent</a>`, (n-1)/stressFanout)
+
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(w, "<html><body><h1>%d</h1>%s</body></html>", n, links.String())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// stressPages returns the number of pages within depth of the root
+func stressPages(depth int) int {
+	total, level := 0, 1
+	for d := 0; d <= depth; d++ {
+		total += level
+		level *= stressFanout
+	}
+	return total
+}
+
+// countAndFollow returns a document handler that queues every link on the page
+// and counts how often each URL was handled
+func countAndFollow(spider *crawlers.Spider, mu *sync.Mutex, handled map[string]int) func(*goquery.Document, crawlers.CrawlContext) error {
+	return func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		mu.Lock()
+		handled[crawl.URL]++
+		mu.Unlock()
+
+		for _, link := range spider.ExtractLinks(doc, "a[href]") {
+			if resolved, err := spider.ResolveURL(crawl.URL, link); err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	}
+}
+
+// assertHandledOnce checks that every page was handled exactly once
+func assertHandledOnce(t *testing.T, handled map[string]int, want int) {
+	t.Helper()
+	if len(handled) != want {
+		t.Errorf("Handled %d distinct pages, want %d", len(handled), want)
+	}
+	for url, count := range handled {
+		if count != 1 {
+			t.Errorf("%s handled %d times, want once", url, count)
+		}
+	}
+}
+
+// =============================================================================
+// Spider Stress Tests
+// =============================================================================
+
+func TestSpider_Stress_ConcurrentCrawl(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+	server := newStressSite(t)
+	const depth = 3
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: depth, Concurrency: 64})
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	spider.OnDocumentContext(countAndFollow(spider, &mu, handled))
+	spider.AddStartURL(server.URL + "/n/0")
+
+	// Read shared state from outside while the crawl runs
+	stop := make(chan struct{})
+	var readers sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				_ = spider.State()
+				_ = spider.GetVisitedCount()
+				_ = spider.IsRunning()
+				_ = spider.BudgetSummary()
+			}
+		}()
+	}
+
+	err := spider.Run()
+	close(stop)
+	readers.Wait()
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := stressPages(depth)
+	assertHandledOnce(t, handled, want)
+	if got := spider.GetVisitedCount(); got != want {
+		t.Errorf("GetVisitedCount() = %d, want %d", got, want)
+	}
+	if state := spider.State(); len(state.Queue) != 0 {
+		t.Errorf("Expected an empty queue after Run, got %d URLs", len(state.Queue))
+	}
+}
+
+func TestSpider_Stress_AddURLDuringRun(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+	server := newStressSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Concurrency: 32})
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	started := make(chan struct{})
+	var once sync.Once
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		once.Do(func() { close(started) })
+		// Slow the crawl so the seeds below arrive while it runs
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		handled[crawl.URL]++
+		mu.Unlock()
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/n/0")
+	spider.AddStartURL(server.URL + "/n/1")
+
+	// Seed the same URLs from several goroutines; each is crawled once
+	var seeders sync.WaitGroup
+	go func() {
+		<-started
+		for g := 0; g < 8; g++ {
+			seeders.Add(1)
+			go func() {
+				defer seeders.Done()
+				for n := 0; n < 50; n++ {
+					spider.AddStartURL(fmt.Sprintf("%s/n/%d", server.URL, n))
+				}
+			}()
+		}
+	}()
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	seeders.Wait()
+
+	// Seeds that arrived after Run drained the queue are still queued
+	mu.Lock()
+	defer mu.Unlock()
+	for url, count := range handled {
+		if count != 1 {
+			t.Errorf("%s handled %d times, want once", url, count)
+		}
+	}
+	state := spider.State()
+	for _, item := range state.Queue {
+		if handled[item.URL] > 0 {
+			t.Errorf("%s is queued although it was already crawled", item.URL)
+		}
+	}
+}
+
+func TestSpider_Stress_Stop(t *testing.T) {
+	server := newStressSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 10, Concurrency: 16})
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	var pages atomic.Int32
+	follow := countAndFollow(spider, &mu, handled)
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		if pages.Add(1) == 20 {
+			spider.Stop()
+		}
+		return follow(doc, crawl)
+	})
+	spider.AddStartURL(server.URL + "/n/0")
+
+	done := make(chan error, 1)
+	go func() { done <- spider.Run() }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run() after Stop error = %v, want nil", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Expected Stop to end the crawl")
+	}
+	if spider.IsRunning() {
+		t.Error("Expected spider to stop running")
+	}
+
+	// Unfinished URLs are back in the queue for a later run
+	mu.Lock()
+	defer mu.Unlock()
+	if state := spider.State(); len(state.Queue) == 0 {
+		t.Error("Expected the rest of the crawl to stay queued")
+	}
+	assertHandledOnce(t, handled, len(handled))
+}
+
+func TestSpider_Stress_ConcurrentRun(t *testing.T) {
+	server := newStressSite(t)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, Concurrency: 4})
+	release := make(chan struct{})
+	spider.OnDocument(func(*goquery.Document, string) error {
+		<-release
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/n/0")
+
+	// Only one of several simultaneous Run calls may start the crawl
+	var started, rejected atomic.Int32
+	var runs sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		runs.Add(1)
+		go func() {
+			defer runs.Done()
+			if err := spider.Run(); err != nil {
+				rejected.Add(1)
+				return
+			}
+			started.Add(1)
+		}()
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for rejected.Load() < 7 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	runs.Wait()
+
+	if started.Load() != 1 || rejected.Load() != 7 {
+		t.Errorf("started = %d, rejected = %d; want 1 and 7", started.Load(), rejected.Load())
+	}
+}
+
+func TestSpider_Stress_Frontier(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping stress test in short mode")
+	}
+	server := newStressSite(t)
+	shared := frontier.NewMemoryFrontier(frontier.MemoryConfig{})
+
+	// Several spiders share one frontier; together they crawl each page once
+	var mu sync.Mutex
+	handled := make(map[string]int)
+	spiders := make([]*crawlers.Spider, 4)
+	for i := range spiders {
+		spider := crawlers.NewSpider(crawlers.SpiderConfig{
+			MaxDepth:     3,
+			Concurrency:  16,
+			Frontier:     shared,
+			FrontierPoll: 10 * time.Millisecond,
+		})
+		spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+			mu.Lock()
+			handled[crawl.URL]++
+			mu.Unlock()
+
+			// Depth is not shared through the frontier; bound the tree by page number
+			for _, link := range spider.ExtractLinks(doc, "a[href]") {
+				n, err := strconv.Atoi(strings.TrimPrefix(link, "/n/"))
+				if err == nil && n < stressPages(2) {
+					spider.AddStartURL(server.URL + link)
+				}
+			}
+			return nil
+		})
+		spiders[i] = spider
+	}
+	spiders[0].AddStartURL(server.URL + "/n/0")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var runs sync.WaitGroup
+	for _, spider := range spiders {
+		runs.Add(1)
+		go func(spider *crawlers.Spider) {
+			defer runs.Done()
+			if err := spider.RunContext(ctx); err != nil {
+				t.Errorf("RunContext() error = %v", err)
+			}
+		}(spider)
+	}
+	runs.Wait()
+
+	assertHandledOnce(t, handled, stressPages(2))
+}
@@ -0,0 +1,254 @@
+package crawlers_test
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Transport Tests
+// =============================================================================
+
+func TestNewTransport_Defaults(t *testing.T) {
+	transport := crawlers.NewTransport(crawlers.TransportConfig{})
+	def := http.DefaultTransport.(*http.Transport)
+
+	if transport.MaxIdleConns != def.MaxIdleConns || transport.IdleConnTimeout != def.IdleConnTimeout {
+		t.Errorf("NewTransport() = %d idle conns, %v idle timeout; want the defaults", transport.MaxIdleConns, transport.IdleConnTimeout)
+	}
+	if !transport.ForceAttemptHTTP2 || transport.TLSNextProto != nil {
+		t.Error("HTTP/2 should be enabled by default")
+	}
+	if transport == def {
+		t.Error("NewTransport() must not return the shared default transport")
+	}
+}
+
+func TestNewTransport_Overrides(t *testing.T) {
+	transport := crawlers.NewTransport(crawlers.TransportConfig{
+		MaxIdleConns:          500,
+		MaxIdleConnsPerHost:   50,
+		MaxConnsPerHost:       64,
+		IdleConnTimeout:       time.Minute,
+		DisableHTTP2:          true,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: 15 * time.Second,
+		DialTimeout:           3 * time.Second,
+	})
+
+	if transport.MaxIdleConns != 500 || transport.MaxIdleConnsPerHost != 50 || transport.MaxConnsPerHost != 64 {
+		t.Errorf("connection limits = %d/%d/%d, want 500/50/64",
+			transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.IdleConnTimeout != time.Minute || transport.TLSHandshakeTimeout != 5*time.Second ||
+		transport.ResponseHeaderTimeout != 15*time.Second {
+		t.Error("timeouts were not applied")
+	}
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("DisableHTTP2 should stop h2 negotiation")
+	}
+}
+
+func TestSoupClient_TransportHTTP2(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>" + r.Proto + "</title></head></html>"))
+	}))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	roots := server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	tests := []struct {
+		name         string
+		disableHTTP2 bool
+		want         string
+	}{
+		{"http2 negotiated", false, "HTTP/2.0"},
+		{"http2 disabled", true, "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := crawlers.NewSoupClient(crawlers.SoupConfig{Transport: crawlers.TransportConfig{
+				TLSConfig:    roots,
+				DisableHTTP2: tt.disableHTTP2,
+			}})
+			doc, err := client.Get(server.URL)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got := doc.Find("title").Text(); got != tt.want {
+				t.Errorf("protocol = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSoupClient_TransportTLSConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><head><title>ok</title></head></html>"))
+	}))
+	t.Cleanup(server.Close)
+
+	if _, err := crawlers.NewDefaultSoupClient().Get(server.URL); err == nil {
+		t.Error("Expected certificate error without a TLS config")
+	}
+
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Transport: crawlers.TransportConfig{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true}, // Self-signed test server
+	}})
+	if _, err := client.Get(server.URL); err != nil {
+		t.Errorf("Get() with TLS config error = %v", err)
+	}
+}
+
+func TestSoupClient_TransportProxyFunc(t *testing.T) {
+	var hits int32
+	proxy := newTestProxy(t, &hits)
+	proxyURL, _ := url.Parse(proxy.URL)
+
+	var calls atomic.Int32
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{Transport: crawlers.TransportConfig{
+		Proxy: func(*http.Request) (*url.URL, error) {
+			calls.Add(1)
+			return proxyURL, nil
+		},
+	}})
+	doc, err := client.Get("http://target.invalid/page")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if calls.Load() != 1 || atomic.LoadInt32(&hits) != 1 {
+		t.Errorf("proxy func calls = %d, proxy hits = %d; want 1 and 1", calls.Load(), hits)
+	}
+	if title := doc.Find("title").Text(); title != "via proxy target.invalid" {
+		t.Errorf("title = %q", title)
+	}
+}
+
+// traceTransport tags every request it sends, standing in for an
+// instrumentation round tripper
+type traceTransport struct {
+	base  http.RoundTripper
+	calls atomic.Int32
+}
+
+func (t *traceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls.Add(1)
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Trace-Id", "trace-1")
+	return t.base.RoundTrip(req)
+}
+
+// traceServer serves a page over TLS and counts requests tagged by traceTransport
+func traceServer(t *testing.T, traced *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace-Id") == "trace-1" {
+			traced.Add(1)
+		}
+		_, _ = w.Write([]byte("<html><head><title>ok</title></head></html>"))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSoupClient_HTTPClient(t *testing.T) {
+	var traced atomic.Int32
+	server := traceServer(t, &traced)
+
+	// The test server's client trusts its certificate, like an mTLS client would
+	injected := server.Client()
+	transport := injected.Transport
+	client := crawlers.NewSoupClient(crawlers.SoupConfig{HTTPClient: injected, Proxies: []string{"http://127.0.0.1:1"}})
+	if _, err := client.Get(server.URL); err == nil {
+		t.Error("Expected the proxy pool to be used with the injected transport")
+	}
+
+	client = crawlers.NewSoupClient(crawlers.SoupConfig{HTTPClient: injected})
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if _, err := client.Post(server.URL, map[string]string{"q": "go"}); err != nil {
+		t.Fatalf("Post() error = %v", err)
+	}
+	if injected.Transport != transport {
+		t.Error("The injected client must not be modified")
+	}
+
+	trace := &traceTransport{base: transport}
+	client = crawlers.NewSoupClient(crawlers.SoupConfig{RoundTripper: trace, Proxies: []string{"http://127.0.0.1:1"}})
+	if client.ProxyPool() != nil {
+		t.Error("Proxies cannot be rotated through a custom round tripper")
+	}
+	if _, err := client.Get(server.URL); err != nil {
+		t.Fatalf("Get() with RoundTripper error = %v", err)
+	}
+	if trace.calls.Load() != 1 || traced.Load() != 1 {
+		t.Errorf("round trips = %d, traced requests = %d; want 1 and 1", trace.calls.Load(), traced.Load())
+	}
+}
+
+func TestSpider_HTTPClient(t *testing.T) {
+	var traced atomic.Int32
+	server := traceServer(t, &traced)
+
+	trace := &traceTransport{base: server.Client().Transport}
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		MaxDepth:   1,
+		HTTPClient: &http.Client{Transport: trace, Timeout: 5 * time.Second},
+	})
+	var titles []string
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		titles = append(titles, doc.Find("title").Text())
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(titles) != 1 || traced.Load() != 1 {
+		t.Errorf("titles = %v, traced requests = %d; want one page through the injected client", titles, traced.Load())
+	}
+}
+
+func TestCollyClient_HTTPClient(t *testing.T) {
+	var traced atomic.Int32
+	server := traceServer(t, &traced)
+
+	injected := server.Client()
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{HTTPClient: injected})
+	var title string
+	client.OnHTML("title", func(e *colly.HTMLElement) {
+		title = e.Text
+	})
+	if err := client.Visit(server.URL); err != nil {
+		t.Fatalf("Visit() error = %v", err)
+	}
+	if title != "ok" {
+		t.Errorf("title = %q, want ok", title)
+	}
+	if injected.Jar != nil {
+		t.Error("The injected client must not be modified")
+	}
+
+	trace := &traceTransport{base: injected.Transport}
+	client = crawlers.NewCollyClient(crawlers.CollyConfig{RoundTripper: trace})
+	if err := client.Visit(server.URL + "/traced"); err != nil {
+		t.Fatalf("Visit() with RoundTripper error = %v", err)
+	}
+	if traced.Load() != 1 {
+		t.Errorf("traced requests = %d, want 1", traced.Load())
+	}
+}
@@ -0,0 +1,234 @@
+package crawlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/crawlers/urlmatch"
+)
+
+// =============================================================================
+// URL Rule Matcher Tests
+// =============================================================================
+
+func TestParseRules(t *testing.T) {
+	rules, err := urlmatch.ParseRules(`
+# Blog first, never admin
+allow example.com/blog/* 10
+deny  example.com/admin
+`)
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if len(rules) != 2 || rules[0].Priority != 10 || rules[1].Action != urlmatch.Deny {
+		t.Errorf("ParseRules() = %+v", rules)
+	}
+
+	for _, bad := range []string{"block example.com", "allow", "allow example.com high"} {
+		if _, err := urlmatch.ParseRule(bad); err == nil {
+			t.Errorf("ParseRule(%q) should fail", bad)
+		}
+	}
+}
+
+func TestRuleSet_Decide(t *testing.T) {
+	rules := []urlmatch.Rule{
+		{Pattern: "example.com", Action: urlmatch.Allow, Priority: 1},
+		{Pattern: "example.com/blog/", Action: urlmatch.Allow, Priority: 10},
+		{Pattern: "example.com/blog/drafts", Action: urlmatch.Deny},
+		{Pattern: "example.com/*.pdf$", Action: urlmatch.Deny},
+		{Pattern: "example.com/search?*sort=", Action: urlmatch.Deny},
+		{Pattern: "example.com/about$", Action: urlmatch.Allow, Priority: 5},
+		{Pattern: "*.example.org/", Action: urlmatch.Deny},
+		{Pattern: "https://news.example.net/live", Action: urlmatch.Allow, Priority: 20},
+		{Pattern: "*/wp-login.php", Action: urlmatch.Deny},
+		{Pattern: `re:^http://example\.com/blog/`, Action: urlmatch.Deny},
+	}
+	rs, err := urlmatch.Compile(rules)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	if rs.Len() != len(rules) {
+		t.Errorf("Len() = %d, want %d", rs.Len(), len(rules))
+	}
+
+	tests := []struct {
+		url      string
+		allowed  bool
+		priority int
+	}{
+		{"https://example.com/", true, 1},
+		{"https://EXAMPLE.com/blog/post-1", true, 10},
+		{"https://example.com/blog/drafts/x", false, 0},
+		{"https://example.com/files/report.pdf", false, 0},
+		{"https://example.com/files/report.pdf?download=1", true, 1},
+		{"https://example.com/search?q=go&sort=date", false, 0},
+		{"https://example.com/search?q=go", true, 1},
+		{"https://example.com/about", true, 5},
+		{"https://example.com/about/team", true, 1},
+		{"https://www.example.org/page", false, 0},
+		{"https://example.org/page", true, 0}, // *.example.org excludes the apex
+		{"https://news.example.net/live/today", true, 20},
+		{"https://other.test/wp-login.php", false, 0},
+		{"http://example.com/blog/post-1", false, 0}, // Regex rule is longer than the blog rule
+		{"https://unmatched.test/", true, 0},
+	}
+	for _, tt := range tests {
+		d := rs.Decide(tt.url)
+		if d.Allowed != tt.allowed || d.Priority != tt.priority {
+			t.Errorf("Decide(%q) = allowed %v priority %d, want %v %d (rule %+v)", tt.url, d.Allowed, d.Priority, tt.allowed, tt.priority, d.Rule)
+		}
+	}
+}
+
+func TestRuleSet_DenyWinsTies(t *testing.T) {
+	rs := urlmatch.MustCompile([]urlmatch.Rule{
+		{Pattern: "example.com/a", Action: urlmatch.Allow},
+		{Pattern: "example.com/a", Action: urlmatch.Deny},
+	})
+	if rs.Allowed("https://example.com/a") {
+		t.Error("Deny should win a tie between equally specific rules")
+	}
+	if rule := rs.Match("https://example.com/b"); rule != nil {
+		t.Errorf("Match() = %+v, want nil", rule)
+	}
+}
+
+func TestCompile_InvalidPatterns(t *testing.T) {
+	for _, pattern := range []string{"ex*ample.com/", "re:(unclosed", "glob:"} {
+		if _, err := urlmatch.Compile([]urlmatch.Rule{{Pattern: pattern}}); err == nil {
+			t.Errorf("Compile(%q) should fail", pattern)
+		}
+	}
+}
+
+func TestRuleSet_Glob(t *testing.T) {
+	rs := urlmatch.MustCompile([]urlmatch.Rule{
+		{Pattern: "glob:https://*.example.com/products/*", Action: urlmatch.Allow},
+		{Pattern: "glob:https://*.example.com/products/*?session=*", Action: urlmatch.Deny},
+		{Pattern: "glob:*/p/??", Action: urlmatch.Deny},
+		{Pattern: "*", Action: urlmatch.Deny},
+	})
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://shop.example.com/products/1", true},
+		{"http://shop.example.com/products/1", false},
+		{"https://example.com/products/1", false},
+		{"https://shop.example.com/products/1?session=abc", false},
+		{"https://shop.example.com/about", false},
+		{"https://example.org/p/42", false},
+	}
+	for _, tt := range tests {
+		if got := rs.Allowed(tt.url); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	// ? matches exactly one character
+	if d := rs.Decide("https://example.org/p/4"); d.Rule == nil || d.Rule.Pattern != "*" {
+		t.Errorf("Decide() = %+v, want the catch-all rule", d)
+	}
+}
+
+func TestRuleSet_ManyRules(t *testing.T) {
+	rules := make([]urlmatch.Rule, 0, 20000)
+	for i := 0; i < 10000; i++ {
+		rules = append(rules,
+			urlmatch.Rule{Pattern: fmt.Sprintf("site%d.example.com/", i), Action: urlmatch.Allow, Priority: i},
+			urlmatch.Rule{Pattern: fmt.Sprintf("site%d.example.com/private/", i), Action: urlmatch.Deny},
+		)
+	}
+	rs := urlmatch.MustCompile(rules)
+
+	if d := rs.Decide("https://site9876.example.com/page"); !d.Allowed || d.Priority != 9876 {
+		t.Errorf("Decide() = %+v, want allowed priority 9876", d)
+	}
+	if rs.Allowed("https://site42.example.com/private/" + strings.Repeat("x", 100)) {
+		t.Error("Private path should be denied")
+	}
+}
+
+func TestSpider_URLRules(t *testing.T) {
+	fetched := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched <- r.URL.Path
+		_, _ = w.Write([]byte(`<html></html>`))
+	}))
+	defer server.Close()
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{
+		URLRules: urlmatch.MustCompile([]urlmatch.Rule{{Pattern: "*/private", Action: urlmatch.Deny}}),
+	})
+	spider.AddStartURL(server.URL + "/public")
+	spider.AddStartURL(server.URL + "/private/page")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	close(fetched)
+
+	var paths []string
+	for path := range fetched {
+		paths = append(paths, path)
+	}
+	if len(paths) != 1 || paths[0] != "/public" {
+		t.Errorf("Fetched %v, want [/public]", paths)
+	}
+}
+
+func TestSpider_URLRules_AllowList(t *testing.T) {
+	fetched := make(chan string, 8)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetched <- r.URL.Path
+		_, _ = w.Write([]byte(`<html><body>
+			<a href="/products/1">p1</a>
+			<a href="/products/2">p2</a>
+			<a href="/login">login</a>
+			<a href="/about">about</a>
+		</body></html>`))
+	}))
+	defer server.Close()
+
+	rules, err := urlmatch.ParseRules(`
+allow */$
+allow */products/*
+deny  *
+`)
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{MaxDepth: 1, URLRules: urlmatch.MustCompile(rules)})
+	spider.OnDocumentContext(func(doc *goquery.Document, crawl crawlers.CrawlContext) error {
+		for _, link := range spider.ExtractLinks(doc, "a") {
+			resolved, err := spider.ResolveURL(crawl.URL, link)
+			if err == nil {
+				spider.AddURL(resolved, crawl)
+			}
+		}
+		return nil
+	})
+	spider.AddStartURL(server.URL + "/")
+
+	if err := spider.Run(); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	close(fetched)
+
+	var paths []string
+	for path := range fetched {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	if want := []string{"/", "/products/1", "/products/2"}; strings.Join(paths, " ") != strings.Join(want, " ") {
+		t.Errorf("Fetched %v, want %v", paths, want)
+	}
+}
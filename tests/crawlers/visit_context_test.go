@@ -0,0 +1,145 @@
+package crawlers_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/gocolly/colly/v2"
+)
+
+// =============================================================================
+// Context-Aware Visit Tests
+// =============================================================================
+
+// slowServer answers after delay unless the client goes away first
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+			_, _ = w.Write([]byte("<html><head><title>slow</title></head></html>"))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCollyClient_VisitContext(t *testing.T) {
+	server := slowServer(t, 0)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{UserAgent: "test"})
+
+	var title string
+	client.OnHTML("title", func(e *colly.HTMLElement) { title = e.Text })
+
+	if err := client.VisitContext(context.Background(), server.URL); err != nil {
+		t.Fatalf("VisitContext() error = %v", err)
+	}
+	if title != "slow" {
+		t.Errorf("title = %q, want slow", title)
+	}
+}
+
+func TestCollyClient_VisitContext_Deadline(t *testing.T) {
+	server := slowServer(t, 5*time.Second)
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{UserAgent: "test"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	err := client.VisitContext(ctx, server.URL)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("VisitContext() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected in-flight request to be aborted, took %v", elapsed)
+	}
+}
+
+func TestCollyClient_VisitContext_Cancelled(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer server.Close()
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{UserAgent: "test"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := client.VisitContext(ctx, server.URL); !errors.Is(err, context.Canceled) {
+		t.Errorf("VisitContext() error = %v, want context.Canceled", err)
+	}
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("Expected no request for an already cancelled context")
+	}
+}
+
+func TestCollyClient_VisitContext_StopsChildRequests(t *testing.T) {
+	var hits int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&hits, 1)
+		if n == 1 {
+			cancel() // Cancel while the first page is being served
+		}
+		_, _ = fmt.Fprintf(w, `<html><body><a href="/page%d">next</a></body></html>`, n)
+	}))
+	defer server.Close()
+
+	client := crawlers.NewCollyClient(crawlers.CollyConfig{UserAgent: "test", MaxDepth: 5})
+	client.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		_ = e.Request.Visit(e.Attr("href")) // Aborted once the visit context is done
+	})
+
+	_ = client.VisitContext(ctx, server.URL)
+	if got := atomic.LoadInt32(&hits); got > 1 {
+		t.Errorf("Expected child requests to be skipped after cancel, got %d requests", got)
+	}
+}
+
+func TestSoupClient_GetContext_Deadline(t *testing.T) {
+	server := slowServer(t, 5*time.Second)
+	client := crawlers.NewDefaultSoupClient()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.GetContext(ctx, server.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetContext() error = %v, want deadline exceeded", err)
+	}
+}
+
+func TestSpider_RunContext_Cancel(t *testing.T) {
+	server := slowServer(t, 5*time.Second)
+
+	spider := crawlers.NewSpider(crawlers.SpiderConfig{Concurrency: 1})
+	spider.OnDocument(func(*goquery.Document, string) error { return nil })
+	spider.AddStartURL(server.URL + "/a")
+	spider.AddStartURL(server.URL + "/b")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := spider.RunContext(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("RunContext() error = %v, want deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected spider to stop promptly, took %v", elapsed)
+	}
+	if spider.IsRunning() {
+		t.Error("Expected spider to stop running")
+	}
+}
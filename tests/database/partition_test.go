@@ -0,0 +1,284 @@
+package database_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/database"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Partition Naming Tests
+// =============================================================================
+
+func TestPartitionStart(t *testing.T) {
+	now := time.Date(2026, 10, 16, 13, 45, 0, 0, time.UTC)
+
+	if got := database.PartitionStart(now, database.PartitionMonthly); !got.Equal(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("monthly PartitionStart() = %v", got)
+	}
+	if got := database.PartitionStart(now, database.PartitionDaily); !got.Equal(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("daily PartitionStart() = %v", got)
+	}
+}
+
+func TestPartitionSuffix(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	if got := database.PartitionSuffix(start, database.PartitionMonthly); got != "p202610" {
+		t.Errorf("monthly PartitionSuffix() = %q", got)
+	}
+	if got := database.PartitionSuffix(start, database.PartitionDaily); got != "p20261001" {
+		t.Errorf("daily PartitionSuffix() = %q", got)
+	}
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	now := time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)
+
+	got := database.RetentionCutoff(now, database.PartitionMonthly, 3)
+	if want := time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("RetentionCutoff() = %v, want %v", got, want)
+	}
+}
+
+// =============================================================================
+// MySQLPartitioner Tests
+// =============================================================================
+
+func newPartitionMySQL(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+	return gormDB, mock
+}
+
+func TestMySQLPartitioner_EnsurePartitions_Unpartitioned(t *testing.T) {
+	gormDB, mock := newPartitionMySQL(t)
+
+	mock.ExpectQuery("SELECT PARTITION_NAME FROM information_schema.PARTITIONS").
+		WillReturnRows(sqlmock.NewRows([]string{"PARTITION_NAME"}))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `crawl_logs` PARTITION BY RANGE COLUMNS(`created_at`) (" +
+		"PARTITION p202610 VALUES LESS THAN ('2026-11-01 00:00:00'), " +
+		"PARTITION p202611 VALUES LESS THAN ('2026-12-01 00:00:00'), " +
+		"PARTITION pmax VALUES LESS THAN (MAXVALUE))")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	p := database.NewMySQLPartitioner(gormDB, database.PartitionConfig{Table: "crawl_logs", Ahead: 1})
+	if err := p.EnsurePartitions(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("EnsurePartitions() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestMySQLPartitioner_EnsurePartitions_SplitsMax(t *testing.T) {
+	gormDB, mock := newPartitionMySQL(t)
+
+	mock.ExpectQuery("SELECT PARTITION_NAME FROM information_schema.PARTITIONS").
+		WillReturnRows(sqlmock.NewRows([]string{"PARTITION_NAME"}).AddRow("p202610").AddRow("pmax"))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `pages` REORGANIZE PARTITION pmax INTO (" +
+		"PARTITION p202611 VALUES LESS THAN ('2026-12-01 00:00:00'), " +
+		"PARTITION pmax VALUES LESS THAN (MAXVALUE))")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	p := database.NewMySQLPartitioner(gormDB, database.PartitionConfig{Table: "pages", Ahead: 1})
+	if err := p.EnsurePartitions(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("EnsurePartitions() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestMySQLPartitioner_EnsurePartitions_UpToDate(t *testing.T) {
+	gormDB, mock := newPartitionMySQL(t)
+
+	mock.ExpectQuery("SELECT PARTITION_NAME FROM information_schema.PARTITIONS").
+		WillReturnRows(sqlmock.NewRows([]string{"PARTITION_NAME"}).
+			AddRow("p202610").AddRow("p202611").AddRow("pmax"))
+
+	p := database.NewMySQLPartitioner(gormDB, database.PartitionConfig{Table: "pages", Ahead: 1})
+	if err := p.EnsurePartitions(time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("EnsurePartitions() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Expected no ALTER statement: %v", err)
+	}
+}
+
+func TestMySQLPartitioner_DropPartitionsBefore(t *testing.T) {
+	gormDB, mock := newPartitionMySQL(t)
+
+	mock.ExpectQuery("SELECT PARTITION_NAME FROM information_schema.PARTITIONS").
+		WillReturnRows(sqlmock.NewRows([]string{"PARTITION_NAME"}).
+			AddRow("p202607").AddRow("p202608").AddRow("p202609").AddRow("pmax"))
+	mock.ExpectExec(regexp.QuoteMeta("ALTER TABLE `pages` DROP PARTITION p202607, p202608")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	p := database.NewMySQLPartitioner(gormDB, database.PartitionConfig{Table: "pages"})
+	dropped, err := p.DropPartitionsBefore(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("DropPartitionsBefore() error = %v", err)
+	}
+	if len(dropped) != 2 {
+		t.Errorf("Expected 2 dropped partitions, got %v", dropped)
+	}
+}
+
+// =============================================================================
+// PostgreSQLPartitioner Tests
+// =============================================================================
+
+func newPartitionPostgreSQL(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+	return gormDB, mock
+}
+
+// expectRelkind expects the table kind lookup of EnsurePartitions
+func expectRelkind(mock sqlmock.Sqlmock, kind string) {
+	rows := sqlmock.NewRows([]string{"relkind"})
+	if kind != "" {
+		rows.AddRow(kind)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT relkind FROM pg_class")).WillReturnRows(rows)
+}
+
+func TestPostgreSQLPartitioner_EnsurePartitions(t *testing.T) {
+	gormDB, mock := newPartitionPostgreSQL(t)
+
+	expectRelkind(mock, "p")
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "crawl_logs_p20261016" PARTITION OF "crawl_logs" ` +
+		`FOR VALUES FROM ('2026-10-16 00:00:00') TO ('2026-10-17 00:00:00')`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "crawl_logs_p20261017" PARTITION OF "crawl_logs" ` +
+		`FOR VALUES FROM ('2026-10-17 00:00:00') TO ('2026-10-18 00:00:00')`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	p := database.NewPostgreSQLPartitioner(gormDB, database.PartitionConfig{
+		Table:    "crawl_logs",
+		Interval: database.PartitionDaily,
+		Ahead:    1,
+	})
+	if err := p.EnsurePartitions(time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("EnsurePartitions() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestPostgreSQLPartitioner_EnsurePartitions_ConvertsPlainTable(t *testing.T) {
+	gormDB, mock := newPartitionPostgreSQL(t)
+
+	expectRelkind(mock, "r")
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE "crawl_logs" RENAME TO "crawl_logs_unpartitioned"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE "crawl_logs" (LIKE "crawl_logs_unpartitioned" INCLUDING ALL) PARTITION BY RANGE ("created_at")`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE "crawl_logs_pdefault" PARTITION OF "crawl_logs" DEFAULT`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "crawl_logs_p20261016" PARTITION OF "crawl_logs"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS "crawl_logs_p20261017" PARTITION OF "crawl_logs"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "crawl_logs" SELECT * FROM "crawl_logs_unpartitioned"`)).
+		WillReturnResult(sqlmock.NewResult(0, 10))
+	mock.ExpectExec(regexp.QuoteMeta(`ALTER SEQUENCE`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DROP TABLE "crawl_logs_unpartitioned"`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	p := database.NewPostgreSQLPartitioner(gormDB, database.PartitionConfig{
+		Table:    "crawl_logs",
+		Interval: database.PartitionDaily,
+		Ahead:    1,
+	})
+	if err := p.EnsurePartitions(time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)); err != nil {
+		t.Fatalf("EnsurePartitions() error = %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestPostgreSQLPartitioner_EnsurePartitions_MissingTable(t *testing.T) {
+	gormDB, mock := newPartitionPostgreSQL(t)
+
+	expectRelkind(mock, "")
+	p := database.NewPostgreSQLPartitioner(gormDB, database.PartitionConfig{Table: "crawl_logs"})
+	if err := p.EnsurePartitions(time.Now()); err == nil {
+		t.Error("Expected an error for a table that does not exist")
+	}
+}
+
+func TestNewPartitioner(t *testing.T) {
+	mysqlDB, _ := newPartitionMySQL(t)
+	if _, ok := database.NewPartitioner(mysqlDB, database.PartitionConfig{Table: "crawl_logs"}).(*database.MySQLPartitioner); !ok {
+		t.Error("Expected a MySQLPartitioner for a MySQL connection")
+	}
+
+	pgDB, _ := newPartitionPostgreSQL(t)
+	if _, ok := database.NewPartitioner(pgDB, database.PartitionConfig{Table: "crawl_logs"}).(*database.PostgreSQLPartitioner); !ok {
+		t.Error("Expected a PostgreSQLPartitioner for a PostgreSQL connection")
+	}
+}
+
+// =============================================================================
+// TimeRange Tests
+// =============================================================================
+
+func TestTimeRange(t *testing.T) {
+	gormDB, _ := newPartitionMySQL(t)
+
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	sql := gormDB.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var rows []map[string]interface{}
+		return tx.Table("pages").Scopes(database.TimeRange("created_at", from, to)).Find(&rows)
+	})
+	if !regexp.MustCompile("created_at >= .* AND created_at < ").MatchString(sql) {
+		t.Errorf("Expected bounded range in SQL, got %s", sql)
+	}
+
+	open := gormDB.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var rows []map[string]interface{}
+		return tx.Table("pages").Scopes(database.TimeRange("created_at", time.Time{}, to)).Find(&rows)
+	})
+	if regexp.MustCompile("created_at >=").MatchString(open) {
+		t.Errorf("Expected open lower bound, got %s", open)
+	}
+}
@@ -0,0 +1,211 @@
+package errs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// =============================================================================
+// Code Tests
+// =============================================================================
+
+func TestCodes_Format(t *testing.T) {
+	pattern := regexp.MustCompile(`^GOLWARC-[A-Z]+-\d{3}$`)
+	seen := make(map[errs.Code]bool)
+	for _, code := range errs.Codes() {
+		if !pattern.MatchString(string(code)) {
+			t.Errorf("Code %q does not match %s", code, pattern)
+		}
+		if seen[code] {
+			t.Errorf("Code %q registered twice", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestCode_UnknownIsInternal(t *testing.T) {
+	if kind := errs.Code("GOLWARC-NOPE-999").Kind(); kind != errs.KindInternal {
+		t.Errorf("Kind() = %v, want KindInternal", kind)
+	}
+}
+
+// =============================================================================
+// Error Tests
+// =============================================================================
+
+func TestError_Message(t *testing.T) {
+	cause := errors.New("boom")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"plain", errs.New(errs.CodeInternal, "failed"), "failed"},
+		{"formatted", errs.Newf(errs.CodeInternal, "failed %d", 2), "failed 2"},
+		{"wrapped", errs.Wrap(cause, errs.CodeInternal, "failed"), "failed: boom"},
+		{"wrapped without message", errs.Wrap(cause, errs.CodeInternal, ""), "boom"},
+		{"wrapf", errs.Wrapf(cause, errs.CodeInternal, "step %d", 3), "step 3: boom"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.err.Error(); got != tt.want {
+				t.Errorf("Error() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrap_Nil(t *testing.T) {
+	if err := errs.Wrap(nil, errs.CodeInternal, "x"); err != nil {
+		t.Errorf("Wrap(nil) = %v, want nil", err)
+	}
+	if err := errs.Wrapf(nil, errs.CodeInternal, "x %d", 1); err != nil {
+		t.Errorf("Wrapf(nil) = %v, want nil", err)
+	}
+}
+
+func TestError_IsAndUnwrap(t *testing.T) {
+	err := fmt.Errorf("get: %w", cache.ErrCacheMiss)
+	if !errors.Is(err, cache.ErrCacheMiss) {
+		t.Error("errors.Is() should match the sentinel through fmt wrapping")
+	}
+	if !errors.Is(err, errs.New(errs.CodeCacheMiss, "other message")) {
+		t.Error("errors.Is() should match any error with the same code")
+	}
+	if errors.Is(err, errs.New(errs.CodeCacheConfig, "")) {
+		t.Error("errors.Is() should not match a different code")
+	}
+
+	cause := errors.New("disk full")
+	if !errors.Is(errs.Wrap(cause, errs.CodeInternal, "write"), cause) {
+		t.Error("errors.Is() should find the wrapped cause")
+	}
+}
+
+func TestCodeOf(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want errs.Code
+	}{
+		{"nil", nil, ""},
+		{"uncoded", errors.New("x"), errs.CodeInternal},
+		{"coded", errs.New(errs.CodeNoProxy, "x"), errs.CodeNoProxy},
+		{"fmt wrapped", fmt.Errorf("ctx: %w", errs.New(errs.CodeThrottled, "x")), errs.CodeThrottled},
+		{"outermost code wins", errs.Wrap(errs.New(errs.CodeCacheMiss, "x"), errs.CodeDBQuery, "y"), errs.CodeDBQuery},
+		{"canceled", fmt.Errorf("wait: %w", context.Canceled), errs.CodeCanceled},
+		{"deadline", context.DeadlineExceeded, errs.CodeDeadlineExceeded},
+		{"coder", &crawlers.ThrottledError{URL: "https://example.com/"}, errs.CodeThrottled},
+		{"invalid url", crawlers.ValidateURL("ftp://example.com"), errs.CodeInvalidURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errs.CodeOf(tt.err); got != tt.want {
+				t.Errorf("CodeOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasCode(t *testing.T) {
+	if errs.HasCode(nil, errs.CodeInternal) {
+		t.Error("HasCode(nil) should be false")
+	}
+	if !errs.HasCode(cache.ErrCacheMiss, errs.CodeCacheMiss) {
+		t.Error("HasCode() should be true for the sentinel's code")
+	}
+}
+
+func TestFields(t *testing.T) {
+	fields := errs.Fields(cache.ErrCacheMiss)
+	if len(fields) != 2 || fields[1].Key != "error_code" || fields[1].String != string(errs.CodeCacheMiss) {
+		t.Errorf("Fields() = %+v", fields)
+	}
+}
+
+// =============================================================================
+// Status Mapping Tests
+// =============================================================================
+
+func TestHTTPStatus(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, http.StatusOK},
+		{errors.New("x"), http.StatusInternalServerError},
+		{errs.New(errs.CodeInvalidURL, "x"), http.StatusBadRequest},
+		{cache.ErrCacheMiss, http.StatusNotFound},
+		{errs.New(errs.CodeWorkerConnected, "x"), http.StatusConflict},
+		{errs.New(errs.CodeThrottled, "x"), http.StatusTooManyRequests},
+		{errs.New(errs.CodeQueueFull, "x"), http.StatusServiceUnavailable},
+		{context.DeadlineExceeded, http.StatusGatewayTimeout},
+	}
+	for _, tt := range tests {
+		if got := errs.HTTPStatus(tt.err); got != tt.want {
+			t.Errorf("HTTPStatus(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCodeForHTTPStatus(t *testing.T) {
+	for status, want := range map[int]errs.Code{
+		http.StatusBadRequest:          errs.CodeInvalidRequest,
+		http.StatusNotFound:            errs.CodeNotFound,
+		http.StatusServiceUnavailable:  errs.CodeUnavailable,
+		http.StatusInternalServerError: errs.CodeInternal,
+	} {
+		if got := errs.CodeForHTTPStatus(status); got != want {
+			t.Errorf("CodeForHTTPStatus(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
+
+func TestGRPC_RoundTrip(t *testing.T) {
+	err := errs.ToGRPC(errs.New(errs.CodeUnknownWorker, "unknown worker w1"))
+
+	st, ok := status.FromError(err)
+	if !ok {
+		t.Fatalf("ToGRPC() did not return a status error: %v", err)
+	}
+	if st.Code() != codes.NotFound || st.Message() != "unknown worker w1" {
+		t.Errorf("status = %v %q", st.Code(), st.Message())
+	}
+
+	restored := errs.FromGRPC(err)
+	if errs.CodeOf(restored) != errs.CodeUnknownWorker {
+		t.Errorf("FromGRPC() code = %q, want %q", errs.CodeOf(restored), errs.CodeUnknownWorker)
+	}
+	if restored.Error() != "unknown worker w1" {
+		t.Errorf("FromGRPC() message = %q", restored.Error())
+	}
+}
+
+func TestGRPC_Plain(t *testing.T) {
+	if errs.ToGRPC(nil) != nil || errs.FromGRPC(nil) != nil {
+		t.Error("nil should stay nil")
+	}
+
+	plain := status.Error(codes.InvalidArgument, "bad")
+	if errs.ToGRPC(plain) != plain {
+		t.Error("ToGRPC() should pass through existing status errors")
+	}
+	if code := errs.CodeOf(errs.FromGRPC(plain)); code != errs.CodeInvalidRequest {
+		t.Errorf("FromGRPC() code = %q, want %q", code, errs.CodeInvalidRequest)
+	}
+
+	st, _ := status.FromError(errs.ToGRPC(context.Canceled))
+	if st.Code() != codes.Canceled {
+		t.Errorf("ToGRPC(context.Canceled) code = %v, want Canceled", st.Code())
+	}
+}
@@ -0,0 +1,37 @@
+package extractors_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alonecandies/golwarc/extractors"
+)
+
+// =============================================================================
+// Asset Discovery Tests
+// =============================================================================
+
+func TestDiscoverAssets(t *testing.T) {
+	doc := document(t, `<html><body>
+<img src="/img/logo.png">
+<img src="data:image/gif;base64,R0lGODlhAQABAAAAACw=">
+<picture><source src="hero.webp"><img src="/img/logo.png#top"></picture>
+<a href="/docs/report.PDF?v=2">Report</a>
+<a href="/export" download>Export</a>
+<a href="/about">About</a>
+<a href="mailto:files@example.com">Mail</a>
+<video src="https://cdn.example/clip.mp4"></video>
+</body></html>`)
+
+	got := extractors.DiscoverAssets(doc, "https://example.com/news/1")
+	want := []string{
+		"https://example.com/img/logo.png",
+		"https://example.com/news/hero.webp",
+		"https://example.com/docs/report.PDF?v=2",
+		"https://example.com/export",
+		"https://cdn.example/clip.mp4",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverAssets() = %v\nwant %v", got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package extractors_test
+
+import (
+	"testing"
+
+	"github.com/alonecandies/golwarc/extractors"
+)
+
+// =============================================================================
+// Canonical Link Tests
+// =============================================================================
+
+func TestCanonicalLink(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"absolute", `<link rel="canonical" href="https://shop.example/p/1">`, "https://shop.example/p/1"},
+		{"relative without fragment", `<link rel="Canonical" href="/p/1#reviews">`, "https://www.shop.example/p/1"},
+		{"same link twice", `<link rel="canonical" href="/p/1"><link rel="canonical" href="https://www.shop.example/p/1">`, "https://www.shop.example/p/1"},
+		{"conflicting links", `<link rel="canonical" href="/p/1"><link rel="canonical" href="/p/2">`, ""},
+		{"not http", `<link rel="canonical" href="javascript:void(0)">`, ""},
+		{"none", `<link rel="alternate" href="/p/1.xml">`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractors.CanonicalLink(document(t, tt.html), "https://www.shop.example/p/1?ref=home"); got != tt.want {
+				t.Errorf("CanonicalLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
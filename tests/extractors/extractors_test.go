@@ -0,0 +1,331 @@
+package extractors_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/models"
+)
+
+const productRules = `
+sites:
+  - name: shop
+    hosts: ["*.shop.example"]
+    paths: [/p/]
+    model: product
+    fields:
+      name: {selector: h1, transform: [squash], required: true}
+      price: {selector: .price}
+      currency: {default: USD}
+      image_url: {selector: img.main, attr: src, transform: [absolute]}
+      in_stock: {selector: .stock}
+      rating: {selector: .rating, transform: ["regex:([\\d.]+) of 5"]}
+      review_count: {selector: .reviews}
+      category: {selector: .crumbs a, all: true, join: " > "}
+      sku: {selector: .sku, transform: ["replace:SKU-|", upper]}
+`
+
+const productPage = `<html><body>
+<h1>  Super
+   Widget </h1>
+<span class="price">$1,299.00</span>
+<img class="main" src="/img/widget.png">
+<span class="stock">Out of stock</span>
+<span class="rating">4.5 of 5 stars</span>
+<span class="reviews">(1,024 reviews)</span>
+<nav class="crumbs"><a>Home</a><a>Tools</a><a>Widgets</a></nav>
+<span class="sku">SKU-ab12</span>
+</body></html>`
+
+// compile parses and compiles YAML rules
+func compile(t *testing.T, yaml string) *extractors.Registry {
+	t.Helper()
+	rules, err := extractors.ParseRules([]byte(yaml), "yaml")
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	registry, err := extractors.Compile(rules)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return registry
+}
+
+// document parses an HTML string
+func document(t *testing.T, html string) *goquery.Document {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(html))
+	if err != nil {
+		t.Fatalf("NewDocumentFromReader() error = %v", err)
+	}
+	return doc
+}
+
+// =============================================================================
+// Rules Tests
+// =============================================================================
+
+func TestParseRules_JSON(t *testing.T) {
+	rules, err := extractors.ParseRules([]byte(`{"sites":[{"name":"blog","hosts":["blog.example"],"model":"article",
+		"fields":{"title":{"selector":"h1","required":true}}}]}`), "json")
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if len(rules.Sites) != 1 || rules.Sites[0].Model != extractors.ModelArticle || !rules.Sites[0].Fields["title"].Required {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	if _, err := extractors.ParseRules([]byte("{"), "json"); !errs.HasCode(err, errs.CodeExtractRules) {
+		t.Errorf("Expected %s for invalid JSON, got %v", errs.CodeExtractRules, err)
+	}
+	if _, err := extractors.ParseRules(nil, "toml"); !errs.HasCode(err, errs.CodeExtractRules) {
+		t.Errorf("Expected %s for an unknown format, got %v", errs.CodeExtractRules, err)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "rules.yaml")
+	if err := os.WriteFile(path, []byte(productRules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	registry, err := extractors.LoadRules(path)
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	if registry.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", registry.Len())
+	}
+
+	if _, err := extractors.LoadRules(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestCompile_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		site  extractors.SiteRules
+		error string
+	}{
+		{"unknown model", extractors.SiteRules{Hosts: []string{"a"}, Model: "review"}, "unknown model"},
+		{"no hosts", extractors.SiteRules{Model: "page"}, "host"},
+		{"no fields", extractors.SiteRules{Hosts: []string{"a"}, Model: "page"}, "field"},
+		{"unknown field", extractors.SiteRules{Hosts: []string{"a"}, Model: "product",
+			Fields: map[string]extractors.FieldRule{"colour": {Selector: "p"}}}, "colour"},
+		{"reserved field", extractors.SiteRules{Hosts: []string{"a"}, Model: "product",
+			Fields: map[string]extractors.FieldRule{"source_url": {Selector: "p"}}}, "source_url"},
+		{"bad selector", extractors.SiteRules{Hosts: []string{"a"}, Model: "product",
+			Fields: map[string]extractors.FieldRule{"name": {Selector: "p[["}}}, "selector"},
+		{"no selector", extractors.SiteRules{Hosts: []string{"a"}, Model: "product",
+			Fields: map[string]extractors.FieldRule{"name": {}}}, "selector or default"},
+		{"bad transform", extractors.SiteRules{Hosts: []string{"a"}, Model: "product",
+			Fields: map[string]extractors.FieldRule{"name": {Selector: "p", Transform: []string{"reverse"}}}}, "reverse"},
+		{"bad regex", extractors.SiteRules{Hosts: []string{"a"}, Model: "product",
+			Fields: map[string]extractors.FieldRule{"name": {Selector: "p", Transform: []string{"regex:("}}}}, "regex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.site.Name = "site"
+			_, err := extractors.Compile(extractors.RuleSet{Sites: []extractors.SiteRules{tt.site}})
+			if !errs.HasCode(err, errs.CodeExtractRules) {
+				t.Fatalf("Compile() error = %v, want %s", err, errs.CodeExtractRules)
+			}
+			if !strings.Contains(err.Error(), tt.error) {
+				t.Errorf("Compile() error = %v, want it to mention %q", err, tt.error)
+			}
+		})
+	}
+}
+
+// =============================================================================
+// Matching Tests
+// =============================================================================
+
+func TestRegistry_For(t *testing.T) {
+	registry := compile(t, productRules)
+
+	tests := []struct {
+		url   string
+		match bool
+	}{
+		{"https://shop.example/p/1", true},
+		{"https://www.SHOP.example/p/1", true},
+		{"https://shop.example/blog/1", false},
+		{"https://othershop.example/p/1", false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		if got := registry.For(tt.url) != nil; got != tt.match {
+			t.Errorf("For(%q) matched = %v, want %v", tt.url, got, tt.match)
+		}
+	}
+
+	var empty *extractors.Registry
+	if empty.For("https://shop.example/p/1") != nil || empty.Len() != 0 {
+		t.Error("Expected a nil registry to match nothing")
+	}
+}
+
+// =============================================================================
+// Extraction Tests
+// =============================================================================
+
+func TestExtract_Product(t *testing.T) {
+	extractor := compile(t, productRules).For("https://shop.example/p/1")
+	if extractor.Name() != "shop" || extractor.Model() != extractors.ModelProduct {
+		t.Fatalf("Unexpected extractor %s/%s", extractor.Name(), extractor.Model())
+	}
+
+	result, err := extractor.Extract(document(t, productPage), "https://shop.example/p/1")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	product, ok := result.(*models.Product)
+	if !ok {
+		t.Fatalf("Extract() = %T, want *models.Product", result)
+	}
+
+	want := models.Product{
+		Name:        "Super Widget",
+		Price:       1299,
+		Currency:    "USD",
+		ImageURL:    "https://shop.example/img/widget.png",
+		SourceURL:   "https://shop.example/p/1",
+		Category:    "Home > Tools > Widgets",
+		SKU:         "AB12",
+		InStock:     false,
+		Rating:      4.5,
+		ReviewCount: 1024,
+	}
+	if *product != want {
+		t.Errorf("Extract() =\n%+v\nwant\n%+v", *product, want)
+	}
+}
+
+func TestExtract_Numbers(t *testing.T) {
+	registry := compile(t, `
+sites:
+  - hosts: [shop.example]
+    model: product
+    fields:
+      name: {default: x}
+      price: {selector: .price}
+`)
+	extractor := registry.For("https://shop.example/")
+
+	tests := []struct {
+		text string
+		want float64
+	}{
+		{"$1,299.00", 1299},
+		{"1.299,00 €", 1299},
+		{"12,50 €", 12.5},
+		{"€ 1.234.567", 1234567},
+		{"-3.25", -3.25},
+		{"Only 9.99 today, was 12.99", 9.99},
+		{"free", 0}, // Not a number: left empty
+	}
+	for _, tt := range tests {
+		result, err := extractor.Extract(document(t, `<span class="price">`+tt.text+`</span>`), "https://shop.example/")
+		if err != nil {
+			t.Fatalf("Extract(%q) error = %v", tt.text, err)
+		}
+		if got := result.(*models.Product).Price; got != tt.want {
+			t.Errorf("Price for %q = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExtract_ArticleTimes(t *testing.T) {
+	registry := compile(t, `
+sites:
+  - hosts: [news.example]
+    model: article
+    fields:
+      title: {selector: h1}
+      published_at: {selector: time, attr: datetime}
+      word_count: {selector: .words}
+      tags: {selector: .tag, all: true, transform: [lower]}
+  - hosts: [old.example]
+    model: article
+    fields:
+      title: {selector: h1}
+      published_at: {selector: .date, layout: "02/01/2006"}
+`)
+
+	result, err := registry.For("https://news.example/a").Extract(document(t, `<h1>Hello</h1>
+		<time datetime="2025-03-04T05:06:07Z">March 4</time><span class="words">1,200 words</span>
+		<a class="tag">Go</a><a class="tag">Web</a>`), "https://news.example/a")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	article := result.(*models.Article)
+	if article.PublishedAt == nil || !article.PublishedAt.Equal(time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("PublishedAt = %v", article.PublishedAt)
+	}
+	if article.WordCount != 1200 || article.Tags != "go, web" || article.SourceURL != "https://news.example/a" {
+		t.Errorf("Unexpected article: %+v", article)
+	}
+
+	result, err = registry.For("https://old.example/a").Extract(document(t, `<h1>Old</h1><p class="date">31/12/2020</p>`), "https://old.example/a")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	if got := result.(*models.Article).PublishedAt; got == nil || got.Format("2006-01-02") != "2020-12-31" {
+		t.Errorf("PublishedAt with layout = %v", got)
+	}
+}
+
+func TestExtract_Page(t *testing.T) {
+	registry := compile(t, `
+sites:
+  - hosts: [docs.example]
+    model: page
+    fields:
+      title: {selector: "meta[property='og:title']", attr: content}
+      content: {selector: main, transform: [squash]}
+`)
+	result, err := registry.For("https://docs.example/x").Extract(document(t,
+		`<head><meta property="og:title" content="Docs"></head><main> Read
+		the docs </main>`), "https://docs.example/x")
+	if err != nil {
+		t.Fatalf("Extract() error = %v", err)
+	}
+	page := result.(*models.Page)
+	if page.Title != "Docs" || page.Content != "Read the docs" || page.URL != "https://docs.example/x" || page.Domain != "docs.example" {
+		t.Errorf("Unexpected page: %+v", page)
+	}
+}
+
+func TestExtract_Required(t *testing.T) {
+	registry := compile(t, `
+sites:
+  - name: strict
+    hosts: [shop.example]
+    model: product
+    fields:
+      name: {selector: h1, required: true}
+      price: {selector: .price, required: true}
+      brand: {selector: .brand}
+`)
+	extractor := registry.For("https://shop.example/")
+
+	if _, err := extractor.Extract(document(t, `<span class="price">5</span>`), "https://shop.example/"); !errs.HasCode(err, errs.CodeMissingField) {
+		t.Errorf("Expected %s for a missing name, got %v", errs.CodeMissingField, err)
+	}
+	if _, err := extractor.Extract(document(t, `<h1>A</h1><span class="price">n/a</span>`), "https://shop.example/"); !errs.HasCode(err, errs.CodeMissingField) {
+		t.Errorf("Expected %s for an invalid price, got %v", errs.CodeMissingField, err)
+	}
+	if _, err := extractor.Extract(document(t, `<h1>A</h1><span class="price">5</span>`), "https://shop.example/"); err != nil {
+		t.Errorf("Expected optional fields to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package extractors_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alonecandies/golwarc/extractors"
+)
+
+// =============================================================================
+// Feed Discovery Tests
+// =============================================================================
+
+func TestDiscoverFeeds(t *testing.T) {
+	doc := document(t, `<html><head>
+<link rel="alternate" type="application/rss+xml" title=" Posts " href="/feed.xml">
+<link rel="Alternate" type="application/atom+xml; charset=utf-8" href="https://blog.example/atom">
+<link rel="alternate" type="application/feed+json" href="feed.json">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="alternate" hreflang="de" type="text/html" href="/de/">
+<link rel="stylesheet" type="application/rss+xml" href="/not-a-feed">
+<link rel="alternate" type="application/rss+xml">
+</head><body><link rel="alternate" type="application/json" href="/comments.json"></body></html>`)
+
+	got := extractors.DiscoverFeeds(doc, "https://blog.example/posts/1")
+	want := []extractors.FeedLink{
+		{URL: "https://blog.example/feed.xml", Title: "Posts", Type: extractors.FeedRSS},
+		{URL: "https://blog.example/atom", Type: extractors.FeedAtom},
+		{URL: "https://blog.example/posts/feed.json", Type: extractors.FeedJSON},
+		{URL: "https://blog.example/comments.json", Type: extractors.FeedJSON},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverFeeds() = %+v\nwant %+v", got, want)
+	}
+
+	if feeds := extractors.DiscoverFeeds(document(t, `<html><head><title>No feeds</title></head></html>`), "https://example.com/"); len(feeds) != 0 {
+		t.Errorf("DiscoverFeeds() = %+v, want none", feeds)
+	}
+}
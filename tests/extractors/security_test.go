@@ -0,0 +1,91 @@
+package extractors_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/alonecandies/golwarc/extractors"
+)
+
+// =============================================================================
+// Security Header Audit Tests
+// =============================================================================
+
+func TestAuditSecurityHeaders(t *testing.T) {
+	strict := http.Header{}
+	strict.Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'nonce-abc' 'unsafe-inline'; frame-ancestors 'none'")
+	strict.Set("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
+	strict.Set("X-Content-Type-Options", "nosniff")
+	strict.Set("Referrer-Policy", "no-referrer, strict-origin-when-cross-origin")
+	strict.Set("Permissions-Policy", "camera=()")
+
+	tests := []struct {
+		name       string
+		url        string
+		header     http.Header
+		wantScore  int
+		wantIssues []string
+	}{
+		{"all headers", "https://example.com/", strict, 100, nil},
+		{
+			name:       "no headers",
+			url:        "https://example.com/",
+			header:     http.Header{},
+			wantScore:  0,
+			wantIssues: []string{extractors.IssueMissingCSP, extractors.IssueMissingHSTS, extractors.IssueMissingFrameOptions, extractors.IssueMissingNoSniff, extractors.IssueMissingReferrer, extractors.IssueMissingPermissions},
+		},
+		{
+			name: "weak policies",
+			url:  "https://example.com/",
+			header: http.Header{
+				"Content-Security-Policy":   {"default-src * 'unsafe-inline' 'unsafe-eval'"},
+				"Strict-Transport-Security": {"max-age=3600"},
+				"X-Frame-Options":           {"sameorigin"},
+				"Referrer-Policy":           {"unsafe-url"},
+			},
+			wantScore:  35,
+			wantIssues: []string{extractors.IssueCSPUnsafeInline, extractors.IssueCSPUnsafeEval, extractors.IssueCSPWildcard, extractors.IssueHSTSShortMaxAge, extractors.IssueMissingNoSniff, extractors.IssueUnsafeReferrer, extractors.IssueMissingPermissions},
+		},
+		{
+			name:       "plain http ignores HSTS",
+			url:        "http://example.com/",
+			header:     http.Header{"Strict-Transport-Security": {"max-age=31536000"}, "X-Frame-Options": {"DENY"}},
+			wantScore:  20,
+			wantIssues: []string{extractors.IssueMissingCSP, extractors.IssueNotHTTPS, extractors.IssueMissingNoSniff, extractors.IssueMissingReferrer, extractors.IssueMissingPermissions},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := extractors.AuditSecurityHeaders(tt.url, tt.header)
+			if got.Score != tt.wantScore {
+				t.Errorf("Score = %d, want %d", got.Score, tt.wantScore)
+			}
+			if !reflect.DeepEqual(got.Issues, tt.wantIssues) {
+				t.Errorf("Issues = %v, want %v", got.Issues, tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestAuditSecurityHeaders_RecordsValues(t *testing.T) {
+	header := http.Header{}
+	header.Add("Content-Security-Policy", "default-src 'self'")
+	header.Add("Content-Security-Policy", "img-src https:")
+	header.Set("Content-Security-Policy-Report-Only", "default-src 'none'")
+	header.Set("X-Frame-Options", "DENY")
+
+	got := extractors.AuditSecurityHeaders("https://example.com/", header)
+	if got.CSP != "default-src 'self', img-src https:" {
+		t.Errorf("CSP = %q", got.CSP)
+	}
+	if got.XFrameOptions != "DENY" {
+		t.Errorf("XFrameOptions = %q, want DENY", got.XFrameOptions)
+	}
+
+	reportOnly := extractors.AuditSecurityHeaders("https://example.com/", http.Header{"Content-Security-Policy-Report-Only": {"default-src 'none'"}})
+	if reportOnly.CSP != "" || reportOnly.Issues[0] != extractors.IssueMissingCSP {
+		t.Errorf("report-only policy counted: %+v", reportOnly)
+	}
+}
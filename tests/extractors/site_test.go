@@ -0,0 +1,79 @@
+package extractors_test
+
+import (
+	"testing"
+
+	"github.com/alonecandies/golwarc/extractors"
+)
+
+// =============================================================================
+// Site Metadata Tests
+// =============================================================================
+
+func TestExtractSiteMetadata(t *testing.T) {
+	doc := document(t, `<html><head>
+<meta name="application-name" content="Shop App">
+<meta property="og:site_name" content=" Example Shop ">
+<meta name="theme-color" content="#112233">
+<link rel="shortcut icon" href="/favicon.ico">
+<link rel="icon" sizes="16x16 32x32" href="/icon-32.png">
+<link rel="apple-touch-icon" sizes="180x180" href="/touch.png">
+<link rel="manifest" href="/site.webmanifest">
+</head></html>`)
+
+	got := extractors.ExtractSiteMetadata(doc, "https://shop.example/p/1")
+	want := extractors.SiteMetadata{
+		SiteName:    "Example Shop",
+		FaviconURL:  "https://shop.example/icon-32.png",
+		ManifestURL: "https://shop.example/site.webmanifest",
+		ThemeColor:  "#112233",
+	}
+	if got != want {
+		t.Errorf("ExtractSiteMetadata() = %+v, want %+v", got, want)
+	}
+}
+
+func TestExtractSiteMetadata_FaviconFallbacks(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"touch icon", `<link rel="apple-touch-icon" href="/touch.png">`, "https://shop.example/touch.png"},
+		{"none declared", `<title>Shop</title>`, "https://shop.example/favicon.ico"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractors.ExtractSiteMetadata(document(t, tt.html), "https://shop.example/p/1").FaviconURL; got != tt.want {
+				t.Errorf("FaviconURL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseWebManifest(t *testing.T) {
+	manifest, err := extractors.ParseWebManifest([]byte(`{
+		"name": "Example Shop",
+		"short_name": "Shop",
+		"theme_color": "#112233",
+		"icons": [
+			{"src": "icons/192.png", "sizes": "192x192"},
+			{"src": "icons/mono.svg", "sizes": "any", "purpose": "monochrome"},
+			{"src": "icons/512.png", "sizes": "512x512", "purpose": "any maskable"}
+		]
+	}`), "https://shop.example/static/site.webmanifest")
+	if err != nil {
+		t.Fatalf("ParseWebManifest() error = %v", err)
+	}
+	if manifest.Name != "Example Shop" || manifest.ShortName != "Shop" || len(manifest.Icons) != 3 {
+		t.Errorf("ParseWebManifest() = %+v", manifest)
+	}
+	if got := manifest.BestIcon(); got != "https://shop.example/static/icons/512.png" {
+		t.Errorf("BestIcon() = %q", got)
+	}
+
+	if _, err := extractors.ParseWebManifest([]byte(`<html>`), "https://shop.example/m.json"); err == nil {
+		t.Error("Expected an error for an invalid manifest")
+	}
+}
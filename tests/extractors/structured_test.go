@@ -0,0 +1,143 @@
+package extractors_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/models"
+)
+
+// =============================================================================
+// Structured Data Tests
+// =============================================================================
+
+const jsonLDProductPage = `<html><head>
+<meta property="og:title" content="OG Widget">
+<meta property="og:description" content="From OpenGraph">
+<script type="application/ld+json">{not json</script>
+<script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "BreadcrumbList", "name": "Crumbs"},
+  {"@type": ["Product", "Thing"], "name": "Widget", "sku": "W-1",
+   "image": [{"@type": "ImageObject", "name": "Front", "url": "/img/w.png"}],
+   "brand": {"@type": "Brand", "name": "Acme"},
+   "offers": [{"@type": "Offer", "price": 19.5, "priceCurrency": "EUR",
+               "availability": "https://schema.org/OutOfStock"}],
+   "aggregateRating": {"ratingValue": "4.4", "reviewCount": 120}}
+]}
+</script></head><body></body></html>`
+
+func TestStructured_JSONLDProduct(t *testing.T) {
+	got := extractors.ExtractStructured(document(t, jsonLDProductPage), "https://shop.example/p/1")
+	p, ok := got.(*models.Product)
+	if !ok {
+		t.Fatalf("ExtractStructured() = %T, want *models.Product", got)
+	}
+
+	want := models.Product{
+		Name: "Widget", Description: "From OpenGraph", SKU: "W-1", Brand: "Acme",
+		ImageURL: "https://shop.example/img/w.png", Price: 19.5, Currency: "EUR",
+		InStock: false, Rating: 4.4, ReviewCount: 120, SourceURL: "https://shop.example/p/1",
+	}
+	if *p != want {
+		t.Errorf("Product = %+v\nwant %+v", *p, want)
+	}
+}
+
+const microdataProductPage = `<html><body>
+<div itemscope itemtype="https://schema.org/Product">
+  <h1 itemprop="name">Micro  Widget</h1>
+  <img itemprop="image" src="https://cdn.example/m.png">
+  <div itemprop="offers" itemscope itemtype="https://schema.org/Offer">
+    <span itemprop="price" content="1299.00">$1,299</span>
+    <meta itemprop="priceCurrency" content="USD">
+    <link itemprop="availability" href="https://schema.org/InStock">
+  </div>
+  <div itemprop="aggregateRating" itemscope itemtype="https://schema.org/AggregateRating">
+    <span itemprop="ratingValue">4.8</span> from <span itemprop="ratingCount">1,024</span>
+  </div>
+</div></body></html>`
+
+func TestStructured_MicrodataProduct(t *testing.T) {
+	p, ok := extractors.ExtractStructured(document(t, microdataProductPage), "https://shop.example/p/2").(*models.Product)
+	if !ok {
+		t.Fatal("Expected a product")
+	}
+	if p.Name != "Micro Widget" || p.Price != 1299 || p.Currency != "USD" || !p.InStock {
+		t.Errorf("Unexpected product: %+v", p)
+	}
+	if p.Rating != 4.8 || p.ReviewCount != 1024 || p.ImageURL != "https://cdn.example/m.png" {
+		t.Errorf("Unexpected rating or image: %+v", p)
+	}
+}
+
+const jsonLDArticlePage = `<html><head>
+<meta property="og:site_name" content="Daily Example">
+<meta property="og:locale" content="en_GB">
+<script type="application/ld+json">
+{"@type": "NewsArticle", "headline": "Go 2 Released",
+ "author": [{"@type": "Person", "name": "Ada"}, {"@type": "Person", "name": "Grace"}],
+ "datePublished": "2024-03-01T10:00:00Z", "articleSection": "Tech",
+ "keywords": ["go", "release"], "articleBody": "It finally happened today."}
+</script></head><body></body></html>`
+
+func TestStructured_JSONLDArticle(t *testing.T) {
+	a, ok := extractors.ExtractStructured(document(t, jsonLDArticlePage), "https://news.example/a/1").(*models.Article)
+	if !ok {
+		t.Fatal("Expected an article")
+	}
+	if a.Title != "Go 2 Released" || a.Author != "Ada, Grace" || a.Category != "Tech" || a.Tags != "go, release" {
+		t.Errorf("Unexpected article: %+v", a)
+	}
+	if a.SourceName != "Daily Example" || a.Language != "en-GB" || a.WordCount != 4 {
+		t.Errorf("Unexpected source, language or word count: %+v", a)
+	}
+	if a.PublishedAt == nil || !a.PublishedAt.Equal(time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("PublishedAt = %v", a.PublishedAt)
+	}
+}
+
+const openGraphArticlePage = `<html><head>
+<meta property="og:type" content="article">
+<meta property="og:title" content="Field Notes">
+<meta property="og:image" content="/cover.jpg">
+<meta property="article:published_time" content="2024-05-02">
+<meta property="article:tag" content="birds">
+<meta property="article:tag" content="spring">
+<meta name="twitter:description" content="Notes from the field">
+<meta name="twitter:creator" content="@naturalist">
+</head><body></body></html>`
+
+func TestStructured_OpenGraphArticle(t *testing.T) {
+	a, ok := extractors.ExtractStructured(document(t, openGraphArticlePage), "https://blog.example/notes").(*models.Article)
+	if !ok {
+		t.Fatal("Expected an article")
+	}
+	if a.Title != "Field Notes" || a.Summary != "Notes from the field" || a.Author != "@naturalist" {
+		t.Errorf("Unexpected article: %+v", a)
+	}
+	if a.Tags != "birds, spring" || a.ImageURL != "https://blog.example/cover.jpg" {
+		t.Errorf("Unexpected tags or image: %+v", a)
+	}
+	if a.PublishedAt == nil || a.PublishedAt.Format("2006-01-02") != "2024-05-02" {
+		t.Errorf("PublishedAt = %v", a.PublishedAt)
+	}
+}
+
+func TestStructured_None(t *testing.T) {
+	pages := []string{
+		`<html><head><title>Plain</title><meta property="og:title" content="Plain"></head></html>`,
+		`<html><head><script type="application/ld+json">{"@type": "Product"}</script></head></html>`, // No name
+	}
+	for _, html := range pages {
+		if got := extractors.ExtractStructured(document(t, html), "https://example.com/"); got != nil {
+			t.Errorf("ExtractStructured() = %+v, want nil", got)
+		}
+	}
+
+	data := extractors.ParseStructuredData(document(t, openGraphArticlePage))
+	if len(data.OpenGraph["article:tag"]) != 2 || data.Twitter["twitter:creator"] != "@naturalist" {
+		t.Errorf("Unexpected meta tags: %+v %+v", data.OpenGraph, data.Twitter)
+	}
+}
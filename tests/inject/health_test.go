@@ -0,0 +1,142 @@
+package inject_test
+
+import (
+	"context"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/inject"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newHealthContainer creates a container from an inline YAML config
+func newHealthContainer(t *testing.T, configContent string) *inject.Container {
+	t.Helper()
+
+	tmpFile, err := os.CreateTemp(t.TempDir(), "inject-config-*.yaml")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	if _, err := tmpFile.WriteString(configContent); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	_ = tmpFile.Close()
+
+	container, err := inject.NewContainer(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to create container: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Close() })
+	return container
+}
+
+// TestContainerRequiredServices tests that only configured services are required
+func TestContainerRequiredServices(t *testing.T) {
+	container := newHealthContainer(t, `
+cache:
+  lru:
+    size: 10
+  redis:
+    addr: "localhost:1"
+`)
+
+	required := container.RequiredServices()
+	for _, name := range []string{"logger", "config", "lru_cache", "redis"} {
+		if !slices.Contains(required, name) {
+			t.Errorf("Expected %s to be required, got %v", name, required)
+		}
+	}
+	for _, name := range []string{"mysql", "postgresql", "clickhouse", "kafka", "rabbitmq"} {
+		if slices.Contains(required, name) {
+			t.Errorf("Expected unconfigured %s to be optional", name)
+		}
+	}
+}
+
+// TestContainerReadiness tests readiness with and without failing required services
+func TestContainerReadiness(t *testing.T) {
+	ready, services := newHealthContainer(t, `
+cache:
+  lru:
+    size: 10
+`).Readiness()
+	if !ready {
+		t.Errorf("Expected ready container, services = %v", services)
+	}
+	if _, ok := services["mysql"]; ok {
+		t.Error("Optional services should not be reported")
+	}
+
+	// Redis is configured but unreachable
+	ready, services = newHealthContainer(t, `
+cache:
+  redis:
+    addr: "localhost:1"
+`).Readiness()
+	if ready {
+		t.Error("Expected container to be not ready when a required service is down")
+	}
+	if healthy, ok := services["redis"]; !ok || healthy {
+		t.Errorf("Expected unhealthy redis, services = %v", services)
+	}
+}
+
+// TestContainerMonitorHealth tests that the monitor feeds the health gauges
+func TestContainerMonitorHealth(t *testing.T) {
+	container := newHealthContainer(t, `
+cache:
+  lru:
+    size: 10
+`)
+	metrics := container.Metrics
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		container.MonitorHealth(ctx, metrics, 10*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for healthGauge(t, "lru_cache") != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected lru_cache health gauge to be set")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if v := healthGauge(t, "redis"); v != 0 {
+		t.Errorf("redis gauge = %v, want 0", v)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("MonitorHealth did not stop after cancel")
+	}
+}
+
+// healthGauge reads golwarc_health_status for a service, or -1 when unset
+func healthGauge(t *testing.T, service string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "golwarc_health_status" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "service" && label.GetValue() == service {
+					return metric.GetGauge().GetValue()
+				}
+			}
+		}
+	}
+	return -1
+}
@@ -1,10 +1,21 @@
 package inject_test
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/alonecandies/golwarc/chaos"
+	"github.com/alonecandies/golwarc/errs"
 	"github.com/alonecandies/golwarc/inject"
+	"github.com/alonecandies/golwarc/services"
+	"github.com/alonecandies/golwarc/storage"
+	"github.com/alonecandies/golwarc/warc"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 // TestNewContainer tests DI container creation with valid config
@@ -575,6 +586,38 @@ message_queue:
 	}
 }
 
+// TestContainerArchiveMaintenanceWithoutDatabase tests that compaction
+// relocates records without a database when MySQL is not configured
+func TestContainerArchiveMaintenanceWithoutDatabase(t *testing.T) {
+	container := newHealthContainer(t, "logger:\n  level: info\n")
+	if db := container.Database(); db != nil {
+		t.Fatalf("Database() = %#v, want nil", db)
+	}
+
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		w, err := warc.NewWriter(filepath.Join(dir, name+".warc.gz"))
+		if err != nil {
+			t.Fatalf("NewWriter() error = %v", err)
+		}
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+name)
+		rec.Content = []byte(name)
+		if _, _, err := w.WriteRecord(rec); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+		_ = w.Close()
+	}
+
+	archive := services.NewArchiveService(container.Logger, container.Database(), storage.LifecycleConfig{WARCDir: dir})
+	report, err := archive.RunMaintenance()
+	if err != nil {
+		t.Fatalf("RunMaintenance() error = %v", err)
+	}
+	if report.Relocated != 2 {
+		t.Errorf("Relocated = %d, want 2", report.Relocated)
+	}
+}
+
 // TestContainerHealthAllServices tests health with all service types
 func TestContainerHealthAllServices(t *testing.T) {
 	configContent := `
@@ -615,3 +658,174 @@ cache:
 		}
 	}
 }
+
+// TestContainerNewCrawler tests building crawlers by engine name
+func TestContainerNewCrawler(t *testing.T) {
+	container := newHealthContainer(t, `
+logger:
+  level: info
+crawler:
+  engine: soup
+`)
+
+	c, err := container.NewCrawler("")
+	if err != nil {
+		t.Fatalf("NewCrawler failed: %v", err)
+	}
+	defer c.Close()
+	if c.Engine() != "soup" {
+		t.Errorf("Expected the configured soup engine, got %s", c.Engine())
+	}
+
+	for _, engine := range []string{"colly", "spider"} {
+		c, err := container.NewCrawler(engine)
+		if err != nil {
+			t.Fatalf("NewCrawler(%s) failed: %v", engine, err)
+		}
+		if c.Engine() != engine {
+			t.Errorf("Expected engine %s, got %s", engine, c.Engine())
+		}
+		_ = c.Close()
+	}
+
+	if _, err := container.NewCrawler("lynx"); err == nil {
+		t.Error("Expected an error for an unknown engine")
+	}
+}
+
+func TestContainerBlockDetection(t *testing.T) {
+	container := newHealthContainer(t, `
+logger:
+  level: info
+crawler:
+  engine: soup
+  block_detection: true
+`)
+	if container.Blocks == nil {
+		t.Fatal("Expected a block detector")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cf-Mitigated", "challenge")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte("<html><body>Checking your browser</body></html>"))
+	}))
+	defer server.Close()
+
+	c, err := container.NewCrawler("")
+	if err != nil {
+		t.Fatalf("NewCrawler failed: %v", err)
+	}
+	defer c.Close()
+	if _, err := c.Fetch(context.Background(), server.URL); errs.CodeOf(err) != errs.CodeBlocked {
+		t.Errorf("Expected a blocked error, got %v", err)
+	}
+}
+
+// TestContainerMetrics tests that crawler engines record to the container metrics
+func TestContainerMetrics(t *testing.T) {
+	container := newHealthContainer(t, `
+logger:
+  level: info
+`)
+	if container.Metrics == nil {
+		t.Fatal("Expected container metrics")
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("<html><body>ok</body></html>"))
+	}))
+	defer server.Close()
+
+	for _, engine := range []string{"colly", "soup", "spider"} {
+		before := crawlerRequests(t, engine)
+		c, err := container.NewCrawler(engine)
+		if err != nil {
+			t.Fatalf("NewCrawler(%s) failed: %v", engine, err)
+		}
+		if _, err := c.Fetch(context.Background(), server.URL); err != nil {
+			t.Fatalf("Fetch(%s) failed: %v", engine, err)
+		}
+		_ = c.Close()
+		if after := crawlerRequests(t, engine); after <= before {
+			t.Errorf("Expected %s requests to be recorded, counter stayed at %v", engine, after)
+		}
+	}
+}
+
+// crawlerRequests reads golwarc_crawler_requests_total for a crawler type
+func crawlerRequests(t *testing.T, crawlerType string) float64 {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	var total float64
+	for _, family := range families {
+		if family.GetName() != "golwarc_crawler_requests_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "crawler_type" && label.GetValue() == crawlerType {
+					total += metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return total
+}
+
+func TestContainerChaos(t *testing.T) {
+	container := newHealthContainer(t, `
+logger:
+  level: info
+chaos:
+  enabled: true
+  seed: 1
+  cache:
+    latency: 20
+    error_rate: 0.5
+`)
+	if len(container.Chaos) != 3 {
+		t.Fatalf("Expected an injector per dependency, got %v", container.Chaos)
+	}
+	fault := container.Chaos[chaos.DependencyCache].Fault()
+	if fault.Latency != 20*time.Millisecond || fault.ErrorRate != 0.5 {
+		t.Errorf("Unexpected cache fault: %+v", fault)
+	}
+	if fault := container.Chaos[chaos.DependencyDatabase].Fault(); !fault.IsZero() {
+		t.Errorf("Expected no database fault, got %+v", fault)
+	}
+}
+
+func TestContainerChaos_Env(t *testing.T) {
+	t.Setenv(chaos.EnvVar, "queue:error_rate=1")
+	container := newHealthContainer(t, `
+logger:
+  level: info
+chaos:
+  cache:
+    error_rate: 0.5
+`)
+	if container.Chaos == nil {
+		t.Fatal("Expected GOLWARC_CHAOS to enable fault injection")
+	}
+	if fault := container.Chaos[chaos.DependencyQueue].Fault(); fault.ErrorRate != 1 {
+		t.Errorf("Expected the queue fault from the environment, got %+v", fault)
+	}
+	if fault := container.Chaos[chaos.DependencyCache].Fault(); !fault.IsZero() {
+		t.Errorf("Expected the environment to replace the configured faults, got %+v", fault)
+	}
+}
+
+func TestContainerChaos_Disabled(t *testing.T) {
+	container := newHealthContainer(t, `
+logger:
+  level: info
+`)
+	if container.Chaos != nil {
+		t.Errorf("Expected fault injection to be off by default, got %v", container.Chaos)
+	}
+}
@@ -0,0 +1,61 @@
+package libs_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alonecandies/golwarc/libs"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// =====================
+// Crawl ID Tests
+// =====================
+
+func TestNewCrawlID(t *testing.T) {
+	a, b := libs.NewCrawlID(), libs.NewCrawlID()
+	if len(a) != 16 || a == b {
+		t.Errorf("NewCrawlID() = %q, %q; want distinct 16-character IDs", a, b)
+	}
+}
+
+func TestCrawlIDContext(t *testing.T) {
+	if id := libs.CrawlIDFrom(context.Background()); id != "" {
+		t.Errorf("CrawlIDFrom(empty) = %q, want empty", id)
+	}
+
+	ctx := libs.WithCrawlID(context.Background(), "abc")
+	if id := libs.CrawlIDFrom(ctx); id != "abc" {
+		t.Errorf("CrawlIDFrom() = %q, want abc", id)
+	}
+
+	same, id := libs.EnsureCrawlID(ctx)
+	if id != "abc" || libs.CrawlIDFrom(same) != "abc" {
+		t.Errorf("EnsureCrawlID() kept %q, want abc", id)
+	}
+
+	fresh, id := libs.EnsureCrawlID(context.Background())
+	if id == "" || libs.CrawlIDFrom(fresh) != id {
+		t.Errorf("EnsureCrawlID() = %q, ctx has %q", id, libs.CrawlIDFrom(fresh))
+	}
+}
+
+func TestLoggerFrom(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	logger := zap.New(core)
+
+	libs.LoggerFrom(libs.WithCrawlID(context.Background(), "abc"), logger).Info("tagged")
+	libs.LoggerFrom(context.Background(), logger).Info("untagged")
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if got := entries[0].ContextMap()[libs.CrawlIDField]; got != "abc" {
+		t.Errorf("tagged crawl_id = %v, want abc", got)
+	}
+	if _, ok := entries[1].ContextMap()[libs.CrawlIDField]; ok {
+		t.Error("untagged entry should have no crawl_id")
+	}
+}
@@ -0,0 +1,53 @@
+package libs_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alonecandies/golwarc/libs"
+)
+
+// =============================================================================
+// Health Probe Tests
+// =============================================================================
+
+func TestMetricsServer_Probes(t *testing.T) {
+	server := libs.NewMetricsServer(0)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		server.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for _, path := range []string{"/livez", "/health", "/readyz"} {
+		if rec := get(path); rec.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, rec.Code)
+		}
+	}
+
+	server.SetReadinessCheck(func() (bool, map[string]bool) {
+		return false, map[string]bool{"mysql": false, "config": true}
+	})
+
+	rec := get("/readyz")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz = %d, want 503", rec.Code)
+	}
+	var body struct {
+		Status   string          `json:"status"`
+		Services map[string]bool `json:"services"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if body.Status != "not_ready" || body.Services["mysql"] || !body.Services["config"] {
+		t.Errorf("Unexpected body: %+v", body)
+	}
+
+	if rec := get("/livez"); rec.Code != http.StatusOK {
+		t.Errorf("GET /livez = %d, want 200 while not ready", rec.Code)
+	}
+}
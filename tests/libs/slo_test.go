@@ -0,0 +1,171 @@
+package libs_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/libs"
+	"github.com/prometheus/client_golang/prometheus"
+	"gopkg.in/yaml.v3"
+)
+
+// =============================================================================
+// SLO Tracker Tests
+// =============================================================================
+
+func TestSLOTracker_Defaults(t *testing.T) {
+	config := libs.NewSLOTracker(libs.SLOConfig{}).Config()
+
+	if config.Window != 5*time.Minute || config.SuccessRatioTarget != 0.99 || config.FetchLatencyP95 != 5*time.Second {
+		t.Errorf("Unexpected defaults: %+v", config)
+	}
+}
+
+func TestSLOTracker_EmptyWindow(t *testing.T) {
+	snap := libs.NewSLOTracker(libs.SLOConfig{}).Snapshot()
+
+	if snap.Requests != 0 || snap.SuccessRatio != 1 || snap.BurnRate != 0 || snap.QueueAge != 0 {
+		t.Errorf("Unexpected empty snapshot: %+v", snap)
+	}
+}
+
+func TestSLOTracker_Snapshot(t *testing.T) {
+	tracker := libs.NewSLOTracker(libs.SLOConfig{SuccessRatioTarget: 0.9})
+
+	for i := 1; i <= 20; i++ {
+		tracker.RecordFetch(time.Duration(i)*100*time.Millisecond, i%10 != 0) // 2 failures
+	}
+	tracker.SetQueueOldest(time.Now().Add(-time.Minute))
+
+	snap := tracker.Snapshot()
+	if snap.Requests != 20 {
+		t.Errorf("Requests = %d, want 20", snap.Requests)
+	}
+	if snap.SuccessRatio != 0.9 {
+		t.Errorf("SuccessRatio = %v, want 0.9", snap.SuccessRatio)
+	}
+	if snap.FetchLatencyP95 != 1900*time.Millisecond {
+		t.Errorf("FetchLatencyP95 = %v, want 1.9s", snap.FetchLatencyP95)
+	}
+	if snap.BurnRate < 0.99 || snap.BurnRate > 1.01 {
+		t.Errorf("BurnRate = %v, want 1", snap.BurnRate)
+	}
+	if snap.QueueAge < time.Minute {
+		t.Errorf("QueueAge = %v, want at least 1m", snap.QueueAge)
+	}
+
+	tracker.SetQueueOldest(time.Time{})
+	if age := tracker.Snapshot().QueueAge; age != 0 {
+		t.Errorf("QueueAge = %v after queue drained, want 0", age)
+	}
+}
+
+func TestSLOTracker_WindowAndSampleLimit(t *testing.T) {
+	fake := clock.NewFake(time.Time{})
+	tracker := libs.NewSLOTracker(libs.SLOConfig{Window: time.Minute, MaxSamples: 3, Clock: fake})
+
+	for i := 0; i < 5; i++ {
+		tracker.RecordFetch(time.Second, false)
+	}
+	if n := tracker.Snapshot().Requests; n != 3 {
+		t.Errorf("Requests = %d, want MaxSamples 3", n)
+	}
+
+	fake.Advance(time.Minute)
+	if n := tracker.Snapshot().Requests; n != 0 {
+		t.Errorf("Requests = %d after window elapsed, want 0", n)
+	}
+}
+
+func TestSLOTracker_Collect(t *testing.T) {
+	tracker := libs.NewSLOTracker(libs.SLOConfig{})
+	tracker.RecordFetch(2*time.Second, true)
+	tracker.RecordFetch(time.Second, false)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(tracker)
+
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+
+	values := make(map[string]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			name := family.GetName()
+			for _, label := range metric.GetLabel() {
+				name += "/" + label.GetValue()
+			}
+			values[name] = metric.GetGauge().GetValue()
+		}
+	}
+
+	want := map[string]float64{
+		"golwarc_slo_crawl_success_ratio":       0.5,
+		"golwarc_slo_fetch_latency_p95_seconds": 2,
+		"golwarc_slo_window_requests":           2,
+		"golwarc_slo_threshold/success_ratio":   0.99,
+		"golwarc_slo_threshold/burn_rate":       14.4,
+	}
+	for name, v := range want {
+		if got, ok := values[name]; !ok || got != v {
+			t.Errorf("%s = %v (present %v), want %v", name, got, ok, v)
+		}
+	}
+	if burn := values["golwarc_slo_error_budget_burn_rate"]; burn < 49.9 || burn > 50.1 {
+		t.Errorf("burn rate = %v, want 50", burn)
+	}
+}
+
+// =============================================================================
+// Alert Rule Tests
+// =============================================================================
+
+func TestSLOTracker_AlertRules(t *testing.T) {
+	tracker := libs.NewSLOTracker(libs.SLOConfig{FetchLatencyP95: 3 * time.Second, AlertFor: 90 * time.Second})
+	group := tracker.AlertRules()
+
+	if len(group.Rules) != 4 {
+		t.Fatalf("Expected 4 rules, got %d", len(group.Rules))
+	}
+
+	var latency *libs.AlertRule
+	for i := range group.Rules {
+		if group.Rules[i].Alert == "GolwarcFetchLatencyHigh" {
+			latency = &group.Rules[i]
+		}
+	}
+	if latency == nil {
+		t.Fatal("Missing latency alert")
+	}
+	if latency.Expr != "golwarc_slo_fetch_latency_p95_seconds > 3" {
+		t.Errorf("Expr = %q", latency.Expr)
+	}
+	if latency.For != "90s" {
+		t.Errorf("For = %q, want 90s", latency.For)
+	}
+}
+
+func TestWriteAlertRules(t *testing.T) {
+	var buf bytes.Buffer
+	if err := libs.WriteAlertRules(&buf, libs.NewSLOTracker(libs.SLOConfig{}).AlertRules()); err != nil {
+		t.Fatalf("WriteAlertRules() error = %v", err)
+	}
+
+	var file struct {
+		Groups []libs.AlertRuleGroup `yaml:"groups"`
+	}
+	if err := yaml.Unmarshal(buf.Bytes(), &file); err != nil {
+		t.Fatalf("Invalid YAML: %v", err)
+	}
+	if len(file.Groups) != 1 || file.Groups[0].Name != "golwarc-slo" {
+		t.Errorf("Unexpected groups: %+v", file.Groups)
+	}
+	if !strings.Contains(buf.String(), "for: 5m") {
+		t.Errorf("Expected default for duration, got:\n%s", buf.String())
+	}
+}
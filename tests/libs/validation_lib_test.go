@@ -4,6 +4,7 @@ import (
 	"net"
 	"testing"
 
+	"github.com/alonecandies/golwarc/errs"
 	"github.com/alonecandies/golwarc/libs"
 )
 
@@ -376,6 +377,79 @@ func TestValidateCacheConfig(t *testing.T) {
 	}
 }
 
+func TestValidateURL_Policy(t *testing.T) {
+	validator, err := libs.NewValidatorWithPolicy(libs.ValidationPolicy{
+		AllowPrivate: true,
+		AllowedHosts: []string{"wiki.corp", "*.intranet.example"},
+		BlockedCIDRs: []string{"10.99.0.0/16", "169.254.169.254"},
+	})
+	if err != nil {
+		t.Fatalf("NewValidatorWithPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{"private IP allowed", "http://10.0.0.1/", false},
+		{"localhost allowed", "http://localhost:8080/", false},
+		{"allowlisted host is not resolved", "https://wiki.corp/page", false},
+		{"allowlisted subdomain", "https://docs.intranet.example/", false},
+		{"wildcard excludes apex", "https://intranet.example/", true},
+		{"blocked CIDR", "http://10.99.1.2/", true},
+		{"blocked metadata IP", "http://169.254.169.254/latest/meta-data", true},
+		{"multicast still blocked", "http://224.0.0.1/", true},
+		{"scheme still checked", "file:///etc/passwd", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.ValidateURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateURL(%q) error = %v, wantErr %v", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidationPolicy_AllowsHost(t *testing.T) {
+	policy := libs.ValidationPolicy{AllowedHosts: []string{"Wiki.Corp", "*.intranet.example", "192.168.10.0/24"}}
+
+	tests := []struct {
+		host string
+		want bool
+	}{
+		{"wiki.corp", true},
+		{"WIKI.CORP.", true},
+		{"a.b.intranet.example", true},
+		{"intranet.example", false},
+		{"192.168.10.7", true},
+		{"192.168.11.7", false},
+		{"example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := policy.AllowsHost(tt.host); got != tt.want {
+				t.Errorf("AllowsHost(%q) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewValidatorWithPolicy_InvalidCIDR(t *testing.T) {
+	for _, policy := range []libs.ValidationPolicy{
+		{BlockedCIDRs: []string{"10.0.0.0/33"}},
+		{BlockedCIDRs: []string{"metadata.internal"}},
+		{AllowedHosts: []string{"10.0.0/8"}},
+	} {
+		if _, err := libs.NewValidatorWithPolicy(policy); errs.CodeOf(err) != errs.CodeInvalidConfig {
+			t.Errorf("NewValidatorWithPolicy(%+v) error = %v, want %s", policy, err, errs.CodeInvalidConfig)
+		}
+	}
+}
+
 func TestNewValidator(t *testing.T) {
 	validator := libs.NewValidator()
 	if validator == nil {
@@ -400,3 +474,21 @@ func BenchmarkValidateCrawlerConfig(b *testing.B) {
 		validator.ValidateCrawlerConfig("TestBot/1.0", 5, 10)
 	}
 }
+
+func FuzzValidationPolicy(f *testing.F) {
+	f.Add("wiki.corp", "*.corp", "10.0.0.0/8")
+	f.Add("192.168.1.7", "192.168.0.0/16", "::1")
+	f.Add("[::1]", "*.", "fe80::/10")
+	f.Add("", "", "")
+
+	f.Fuzz(func(t *testing.T, host, allowed, blocked string) {
+		policy := libs.ValidationPolicy{AllowedHosts: []string{allowed}, BlockedCIDRs: []string{blocked}}
+		if err := policy.Validate(); err != nil {
+			return
+		}
+		_ = policy.AllowsHost(host)
+		if ip := net.ParseIP(host); ip != nil {
+			_ = policy.Blocks(ip)
+		}
+	})
+}
@@ -0,0 +1,102 @@
+package libs_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
+)
+
+type structTestInner struct {
+	Delay int `mapstructure:"delay" validate:"min=0,max=100"`
+}
+
+type structTestConfig struct {
+	Name    string            `mapstructure:"name" validate:"required"`
+	Mode    string            `mapstructure:"mode" validate:"omitempty,oneof=fast slow"`
+	Workers int               `mapstructure:"workers" validate:"min=1,max=8"`
+	Tags    []string          `json:"tags" validate:"max=2"`
+	Inner   structTestInner   `mapstructure:"inner"`
+	Items   []structTestInner `mapstructure:"items"`
+	Ptr     *structTestInner  `mapstructure:"ptr"`
+	Skipped int               `validate:"-"`
+}
+
+func TestValidateStruct_Valid(t *testing.T) {
+	validator := libs.NewValidator()
+	cfg := structTestConfig{Name: "a", Workers: 2, Items: []structTestInner{{Delay: 5}}}
+
+	if err := validator.ValidateStruct(cfg); err != nil {
+		t.Errorf("ValidateStruct() error = %v", err)
+	}
+	if err := validator.ValidateStruct(&cfg); err != nil {
+		t.Errorf("ValidateStruct(pointer) error = %v", err)
+	}
+}
+
+func TestValidateStruct_FieldPaths(t *testing.T) {
+	validator := libs.NewValidator()
+	cfg := structTestConfig{
+		Mode:    "medium",
+		Workers: 9,
+		Tags:    []string{"a", "b", "c"},
+		Inner:   structTestInner{Delay: -1},
+		Items:   []structTestInner{{Delay: 1}, {Delay: 101}},
+		Ptr:     &structTestInner{Delay: 200},
+	}
+
+	err := validator.ValidateStruct(cfg)
+	var failures libs.ValidationErrors
+	if !errors.As(err, &failures) {
+		t.Fatalf("ValidateStruct() error = %v, want ValidationErrors", err)
+	}
+
+	want := map[string]string{
+		"name":           "required",
+		"mode":           "oneof",
+		"workers":        "max",
+		"tags":           "max",
+		"inner.delay":    "min",
+		"items[1].delay": "max",
+		"ptr.delay":      "max",
+	}
+	if len(failures) != len(want) {
+		t.Errorf("Got %d failures, want %d: %v", len(failures), len(want), err)
+	}
+	for _, failure := range failures {
+		if want[failure.Field] != failure.Rule {
+			t.Errorf("Unexpected failure %s (rule %s)", failure.Field, failure.Rule)
+		}
+	}
+
+	if !strings.Contains(err.Error(), "workers must be at most 8") {
+		t.Errorf("Error() = %q, want a field path message", err.Error())
+	}
+	if errs.CodeOf(err) != errs.CodeInvalidConfig {
+		t.Errorf("CodeOf() = %q, want %q", errs.CodeOf(err), errs.CodeInvalidConfig)
+	}
+}
+
+func TestValidateStruct_NotAStruct(t *testing.T) {
+	validator := libs.NewValidator()
+
+	if err := validator.ValidateStruct(42); err == nil {
+		t.Error("ValidateStruct(int) should fail")
+	}
+	if err := validator.ValidateStruct((*structTestConfig)(nil)); err == nil {
+		t.Error("ValidateStruct(nil) should fail")
+	}
+}
+
+func TestValidateStruct_UnknownRule(t *testing.T) {
+	cfg := struct {
+		Field string `validate:"email"`
+	}{"x"}
+
+	err := libs.NewValidator().ValidateStruct(cfg)
+	if err == nil || !strings.Contains(err.Error(), "unknown validation rule") {
+		t.Errorf("ValidateStruct() error = %v, want unknown rule", err)
+	}
+}
@@ -0,0 +1,128 @@
+package messagequeue_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"github.com/segmentio/kafka-go"
+)
+
+// =============================================================================
+// Back-Pressure Tests
+// =============================================================================
+
+func TestPressureGauge_Watermarks(t *testing.T) {
+	gauge := messagequeue.NewPressureGauge(10, 4)
+
+	gauge.Add(9)
+	if gauge.Overloaded() {
+		t.Error("Overloaded() below high watermark = true")
+	}
+	gauge.Add(1)
+	if !gauge.Overloaded() {
+		t.Error("Overloaded() at high watermark = false")
+	}
+
+	// Stays overloaded until drained to the low watermark
+	gauge.Done(5)
+	if !gauge.Overloaded() {
+		t.Error("Overloaded() between watermarks = false, want true")
+	}
+	gauge.Done(1)
+	if gauge.Overloaded() {
+		t.Error("Overloaded() at low watermark = true")
+	}
+	if gauge.Pending() != 4 {
+		t.Errorf("Pending() = %d, want 4", gauge.Pending())
+	}
+
+	gauge.Done(100)
+	if gauge.Pending() != 0 {
+		t.Errorf("Pending() after over-release = %d, want 0", gauge.Pending())
+	}
+}
+
+func TestPressureGauge_DefaultLowWatermark(t *testing.T) {
+	gauge := messagequeue.NewPressureGauge(10, 10)
+	gauge.Add(10)
+	gauge.Done(4)
+	if !gauge.Overloaded() {
+		t.Error("Overloaded() at 6 pending = false, want true with low watermark 5")
+	}
+	gauge.Done(1)
+	if gauge.Overloaded() {
+		t.Error("Overloaded() at 5 pending = true, want false")
+	}
+}
+
+func TestKafkaConsumer_PauseResume(t *testing.T) {
+	consumer := messagequeue.NewKafkaConsumer(messagequeue.KafkaConsumerConfig{
+		Brokers: []string{"localhost:9092"},
+		Topic:   "test-topic",
+		GroupID: "test-group",
+	})
+	defer consumer.Close()
+
+	if consumer.Paused() {
+		t.Fatal("New consumer is paused")
+	}
+	consumer.Pause()
+	consumer.Pause() // Idempotent
+	if !consumer.Paused() {
+		t.Fatal("Paused() after Pause = false")
+	}
+
+	// A paused consumer waits without fetching
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err := consumer.Consume(ctx, func(kafka.Message) error {
+		t.Error("Handler called while paused")
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Consume() while paused error = %v, want deadline exceeded", err)
+	}
+
+	consumer.Resume()
+	consumer.Resume() // Idempotent
+	if consumer.Paused() {
+		t.Error("Paused() after Resume = true")
+	}
+}
+
+func TestKafkaConsumer_BackPressure(t *testing.T) {
+	gauge := messagequeue.NewPressureGauge(1, 0)
+	consumer := messagequeue.NewKafkaConsumer(messagequeue.KafkaConsumerConfig{
+		Brokers:          []string{"localhost:9092"},
+		Topic:            "test-topic",
+		GroupID:          "test-group",
+		BackPressure:     gauge,
+		PressureInterval: 10 * time.Millisecond,
+	})
+	defer consumer.Close()
+
+	gauge.Add(1)
+	if !consumer.Paused() {
+		t.Fatal("Paused() with overloaded sink = false")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := consumer.Consume(ctx, func(kafka.Message) error { return nil }); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Consume() with overloaded sink error = %v, want deadline exceeded", err)
+	}
+
+	gauge.Done(1)
+	if consumer.Paused() {
+		t.Error("Paused() after sink drained = true")
+	}
+
+	// BackPressureFunc adapts a plain check
+	var signal messagequeue.BackPressure = messagequeue.BackPressureFunc(func() bool { return true })
+	if !signal.Overloaded() {
+		t.Error("BackPressureFunc.Overloaded() = false")
+	}
+}
@@ -0,0 +1,182 @@
+package messagequeue_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/errs"
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"github.com/segmentio/kafka-go"
+)
+
+// Ensure RedisClient can back consumer deduplication
+var _ messagequeue.DedupStore = (*cache.RedisClient)(nil)
+
+// memoryDedupStore is an in-memory DedupStore; TTLs are ignored
+type memoryDedupStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryDedupStore() *memoryDedupStore {
+	return &memoryDedupStore{values: make(map[string]string)}
+}
+
+func (s *memoryDedupStore) Get(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	val, ok := s.values[key]
+	if !ok {
+		return "", cache.ErrCacheMiss
+	}
+	return val, nil
+}
+
+func (s *memoryDedupStore) Set(key string, value interface{}, _ time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value.(string)
+	return nil
+}
+
+func (s *memoryDedupStore) SetNX(key string, value interface{}, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.values[key]; ok {
+		return false, nil
+	}
+	s.values[key] = value.(string)
+	return true, nil
+}
+
+func (s *memoryDedupStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+// =============================================================================
+// Consumer Idempotency Tests
+// =============================================================================
+
+func TestIdempotency_SkipsDuplicates(t *testing.T) {
+	idem := messagequeue.NewIdempotency(messagequeue.IdempotencyConfig{Store: newMemoryDedupStore()})
+
+	calls := 0
+	handler := idem.Handler(nil, func(body []byte) error {
+		calls++
+		return nil
+	})
+
+	for i := 0; i < 3; i++ {
+		if err := handler([]byte(`{"page_id":1}`)); err != nil {
+			t.Fatalf("handler() error = %v", err)
+		}
+	}
+	if err := handler([]byte(`{"page_id":2}`)); err != nil {
+		t.Fatalf("handler() error = %v", err)
+	}
+
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+	if stats := idem.Stats(); stats.Processed != 2 || stats.Duplicates != 2 {
+		t.Errorf("Stats() = %+v, want 2 processed and 2 duplicates", stats)
+	}
+}
+
+func TestIdempotency_FailureAllowsRetry(t *testing.T) {
+	idem := messagequeue.NewIdempotency(messagequeue.IdempotencyConfig{Store: newMemoryDedupStore()})
+
+	attempts := 0
+	fn := func() error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("insert failed")
+		}
+		return nil
+	}
+
+	if err := idem.Do("msg-1", fn); err == nil {
+		t.Fatal("Expected the handler error")
+	}
+	if err := idem.Do("msg-1", fn); err != nil {
+		t.Fatalf("Do() retry error = %v", err)
+	}
+	if err := idem.Do("msg-1", fn); err != nil {
+		t.Fatalf("Do() duplicate error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("handler ran %d times, want 2", attempts)
+	}
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	store := newMemoryDedupStore()
+	idem := messagequeue.NewIdempotency(messagequeue.IdempotencyConfig{Store: store, Prefix: "test:"})
+
+	err := idem.Do("msg-1", func() error {
+		// A redelivery while the first run is still going
+		inner := idem.Do("msg-1", func() error {
+			t.Error("Duplicate should not run while the first is in flight")
+			return nil
+		})
+		if errs.CodeOf(inner) != errs.CodeMessageInFlight {
+			t.Errorf("Do() error = %v, want %s", inner, errs.CodeMessageInFlight)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+
+	if val, _ := store.Get("test:msg-1"); val != "done" {
+		t.Errorf("stored state = %q, want done", val)
+	}
+}
+
+func TestIdempotency_EmptyIDAlwaysRuns(t *testing.T) {
+	idem := messagequeue.NewIdempotency(messagequeue.IdempotencyConfig{Store: newMemoryDedupStore()})
+
+	calls := 0
+	for i := 0; i < 2; i++ {
+		_ = idem.Do("", func() error { calls++; return nil })
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+func TestIdempotency_KafkaHandler(t *testing.T) {
+	idem := messagequeue.NewIdempotency(messagequeue.IdempotencyConfig{Store: newMemoryDedupStore()})
+
+	calls := 0
+	handler := idem.KafkaHandler(nil, func(msg kafka.Message) error {
+		calls++
+		return nil
+	})
+
+	withID := kafka.Message{Topic: "pages", Partition: 0, Offset: 1, Headers: []kafka.Header{{Key: messagequeue.HeaderMessageID, Value: []byte("page-7")}}}
+	republished := kafka.Message{Topic: "pages", Partition: 1, Offset: 9, Headers: withID.Headers}
+	plain := kafka.Message{Topic: "pages", Partition: 0, Offset: 2}
+
+	for _, msg := range []kafka.Message{withID, republished, plain, plain} {
+		if err := handler(msg); err != nil {
+			t.Fatalf("handler() error = %v", err)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+func TestKafkaMessageID(t *testing.T) {
+	msg := kafka.Message{Topic: "pages", Partition: 3, Offset: 42}
+	if got := messagequeue.KafkaMessageID(msg); got != "pages/3/42" {
+		t.Errorf("KafkaMessageID() = %q, want pages/3/42", got)
+	}
+}
@@ -0,0 +1,93 @@
+package messagequeue_test
+
+import (
+	"testing"
+
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"github.com/segmentio/kafka-go"
+)
+
+// =============================================================================
+// Kafka Partitioning Tests
+// =============================================================================
+
+func TestDomainPartitionKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"https://Example.com/page/1", "example.com"},
+		{"https://example.com:8443/page/2?q=1", "example.com"},
+		{"http://shop.example.com/", "shop.example.com"},
+		{"example.org", "example.org"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got := messagequeue.DomainPartitionKey(kafka.Message{Key: []byte(tt.key)})
+			if string(got) != tt.want {
+				t.Errorf("DomainPartitionKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeyBalancer_SameDomainSamePartition(t *testing.T) {
+	balancer := &messagequeue.KeyBalancer{Key: messagequeue.DomainPartitionKey}
+	partitions := []int{0, 1, 2, 3, 4, 5, 6, 7}
+
+	want := balancer.Balance(kafka.Message{Key: []byte("https://example.com/")}, partitions...)
+	for _, key := range []string{
+		"https://example.com/a",
+		"https://example.com/b?page=2",
+		"http://EXAMPLE.com/c",
+	} {
+		if got := balancer.Balance(kafka.Message{Key: []byte(key)}, partitions...); got != want {
+			t.Errorf("Balance(%q) = %d, want %d", key, got, want)
+		}
+	}
+
+	// Different domains spread over partitions
+	seen := make(map[int]bool)
+	for _, domain := range []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com", "g.com", "h.com"} {
+		seen[balancer.Balance(kafka.Message{Key: []byte("https://" + domain + "/")}, partitions...)] = true
+	}
+	if len(seen) < 2 {
+		t.Error("Expected different domains to use more than one partition")
+	}
+}
+
+func TestKeyBalancer_CustomKey(t *testing.T) {
+	balancer := &messagequeue.KeyBalancer{Key: func(msg kafka.Message) []byte {
+		for _, h := range msg.Headers {
+			if h.Key == "X-Crawl-ID" {
+				return h.Value
+			}
+		}
+		return nil
+	}}
+	partitions := []int{0, 1, 2, 3}
+
+	msg := func(key, crawlID string) kafka.Message {
+		return kafka.Message{Key: []byte(key), Headers: []kafka.Header{{Key: "X-Crawl-ID", Value: []byte(crawlID)}}}
+	}
+	first := balancer.Balance(msg("https://a.com/", "crawl-1"), partitions...)
+	if got := balancer.Balance(msg("https://b.com/", "crawl-1"), partitions...); got != first {
+		t.Errorf("Balance() = %d, want %d for the same crawl ID", got, first)
+	}
+}
+
+func TestNewKafkaProducer_PartitionStrategies(t *testing.T) {
+	for _, strategy := range []string{"", messagequeue.PartitionByDomain, messagequeue.PartitionByKey, messagequeue.PartitionLeastBytes} {
+		producer := messagequeue.NewKafkaProducer(messagequeue.KafkaProducerConfig{
+			Brokers:     []string{"localhost:9092"},
+			Topic:       "test-topic",
+			PartitionBy: strategy,
+		})
+		if producer == nil {
+			t.Fatalf("NewKafkaProducer() returned nil for strategy %q", strategy)
+		}
+		_ = producer.Close() // Error intentionally ignored on close
+	}
+}
@@ -0,0 +1,145 @@
+package messagequeue_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/alonecandies/golwarc/errs"
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+	"github.com/alonecandies/golwarc/storage"
+)
+
+// =============================================================================
+// Payload Encoding Tests
+// =============================================================================
+
+func TestPayloadConfig_Passthrough(t *testing.T) {
+	value := []byte(strings.Repeat("a", 1024))
+
+	payload, err := messagequeue.PayloadConfig{}.Encode(value)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !bytes.Equal(payload.Body, value) || payload.Encoding != "" || payload.Ref != "" {
+		t.Errorf("Encode() = %+v, want the value unchanged", payload)
+	}
+}
+
+func TestPayloadConfig_Compress(t *testing.T) {
+	config := messagequeue.PayloadConfig{CompressAbove: 100}
+	value := []byte(strings.Repeat("crawl event ", 100))
+
+	payload, err := config.Encode(value)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if payload.Encoding != messagequeue.EncodingGzip {
+		t.Fatalf("Encoding = %q, want gzip", payload.Encoding)
+	}
+	if len(payload.Body) >= len(value) {
+		t.Errorf("compressed size = %d, want less than %d", len(payload.Body), len(value))
+	}
+
+	decoded, err := messagequeue.DecodePayload(payload.Body, payload.Encoding, payload.Ref, nil)
+	if err != nil {
+		t.Fatalf("DecodePayload() error = %v", err)
+	}
+	if !bytes.Equal(decoded, value) {
+		t.Error("DecodePayload() did not restore the value")
+	}
+
+	// Small values are left alone
+	small, err := config.Encode([]byte("tiny"))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if small.Encoding != "" {
+		t.Errorf("Encoding = %q for a small value, want none", small.Encoding)
+	}
+}
+
+func TestPayloadConfig_Offload(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+	config := messagequeue.PayloadConfig{MaxMessageSize: 200, Offload: store}
+	value := []byte(strings.Repeat("x", 1024))
+
+	payload, err := config.Encode(value)
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if !strings.HasPrefix(payload.Ref, "mq-payloads/") {
+		t.Fatalf("Ref = %q, want an mq-payloads/ key", payload.Ref)
+	}
+	if len(payload.Body) > 200 || !strings.Contains(string(payload.Body), payload.Ref) {
+		t.Errorf("Body = %s, want a small reference to %s", payload.Body, payload.Ref)
+	}
+	if ok, _ := store.Exists(payload.Ref); !ok {
+		t.Error("offloaded payload was not stored")
+	}
+
+	decoded, err := messagequeue.DecodePayload(payload.Body, payload.Encoding, payload.Ref, store)
+	if err != nil {
+		t.Fatalf("DecodePayload() error = %v", err)
+	}
+	if !bytes.Equal(decoded, value) {
+		t.Error("DecodePayload() did not restore the offloaded value")
+	}
+}
+
+func TestPayloadConfig_CompressBeforeOffload(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+	config := messagequeue.PayloadConfig{CompressAbove: 100, MaxMessageSize: 512, Offload: store}
+
+	// Compresses below the limit, so it is published inline
+	payload, err := config.Encode([]byte(strings.Repeat("z", 4096)))
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if payload.Ref != "" || payload.Encoding != messagequeue.EncodingGzip {
+		t.Errorf("Encode() = encoding %q ref %q, want inline gzip", payload.Encoding, payload.Ref)
+	}
+}
+
+func TestPayloadConfig_TooLarge(t *testing.T) {
+	config := messagequeue.PayloadConfig{MaxMessageSize: 10}
+
+	_, err := config.Encode([]byte("more than ten bytes"))
+	if errs.CodeOf(err) != errs.CodeMessageTooLarge {
+		t.Errorf("Encode() error = %v, want %s", err, errs.CodeMessageTooLarge)
+	}
+}
+
+func TestDecodePayload_Errors(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		encoding string
+		ref      string
+		store    storage.ObjectStore
+	}{
+		{name: "corrupt gzip", encoding: messagequeue.EncodingGzip},
+		{name: "unknown encoding", encoding: "br"},
+		{name: "ref without store", ref: "mq-payloads/abc"},
+		{name: "missing ref", ref: "mq-payloads/missing", store: store},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := messagequeue.DecodePayload([]byte("not a payload"), tt.encoding, tt.ref, tt.store)
+			if errs.CodeOf(err) != errs.CodeBadPayload {
+				t.Errorf("DecodePayload() error = %v, want %s", err, errs.CodeBadPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package messagequeue_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/alonecandies/golwarc/errs"
+	messagequeue "github.com/alonecandies/golwarc/message-queue"
+)
+
+// =============================================================================
+// Schema Versioning Tests
+// =============================================================================
+
+// crawlEvent is version 3 of a test message: version 2 renamed "link" to
+// "url" and version 3 added "engine"
+type crawlEvent struct {
+	URL    string `json:"url"`
+	Engine string `json:"engine,omitempty"`
+}
+
+var crawlEventSchema = messagequeue.NewSchema("crawl_event", 3, map[int]messagequeue.Migration{
+	1: {
+		Up: func(doc map[string]any) error {
+			doc["url"] = doc["link"]
+			delete(doc, "link")
+			return nil
+		},
+		Down: func(doc map[string]any) error {
+			doc["link"] = doc["url"]
+			delete(doc, "url")
+			return nil
+		},
+	},
+	2: {
+		Down: func(doc map[string]any) error {
+			if doc["engine"] != nil {
+				return errors.New("engine needs version 3")
+			}
+			return nil
+		},
+	},
+})
+
+func TestSchema_Range(t *testing.T) {
+	if got := crawlEventSchema.Range(); got != (messagequeue.VersionRange{Min: 1, Max: 3}) {
+		t.Errorf("Range() = %+v, want 1-3", got)
+	}
+
+	// A missing migration ends the readable range
+	gapped := messagequeue.NewSchema("gapped", 3, map[int]messagequeue.Migration{2: {}})
+	if got := gapped.Range(); got != (messagequeue.VersionRange{Min: 2, Max: 3}) {
+		t.Errorf("Range() = %+v, want 2-3", got)
+	}
+}
+
+func TestSchema_Negotiate(t *testing.T) {
+	tests := []struct {
+		name string
+		peer messagequeue.VersionRange
+		want int
+	}{
+		{name: "same release", peer: messagequeue.VersionRange{Min: 1, Max: 3}, want: 3},
+		{name: "older peer", peer: messagequeue.VersionRange{Min: 1, Max: 2}, want: 2},
+		{name: "newer peer", peer: messagequeue.VersionRange{Min: 2, Max: 5}, want: 3},
+		{name: "unversioned peer", peer: messagequeue.VersionRange{}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := crawlEventSchema.Negotiate(tt.peer); err != nil || got != tt.want {
+				t.Errorf("Negotiate(%+v) = %d, %v; want %d", tt.peer, got, err, tt.want)
+			}
+		})
+	}
+
+	_, err := crawlEventSchema.Negotiate(messagequeue.VersionRange{Min: 4, Max: 5})
+	if errs.CodeOf(err) != errs.CodeSchemaUnsupported {
+		t.Errorf("Negotiate(4-5) error = %v, want %s", err, errs.CodeSchemaUnsupported)
+	}
+}
+
+func TestSchema_MarshalOlderVersion(t *testing.T) {
+	data, err := crawlEventSchema.Marshal(crawlEvent{URL: "https://example.com"}, 1)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Marshal() wrote invalid JSON: %v", err)
+	}
+	if doc["link"] != "https://example.com" || doc["url"] != nil || doc["schema_version"] != float64(1) {
+		t.Errorf("Marshal() at version 1 = %s", data)
+	}
+
+	_, err = crawlEventSchema.Marshal(crawlEvent{URL: "https://example.com", Engine: "playwright"}, 2)
+	if errs.CodeOf(err) != errs.CodeSchemaUnsupported {
+		t.Errorf("Marshal() of an inexpressible event error = %v, want %s", err, errs.CodeSchemaUnsupported)
+	}
+}
+
+func TestSchema_UnmarshalUpgrades(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version int
+	}{
+		{name: "unversioned", data: `{"link":"https://example.com"}`, version: 1},
+		{name: "version 2", data: `{"schema_version":2,"url":"https://example.com"}`, version: 2},
+		{name: "current", data: `{"schema_version":3,"url":"https://example.com","engine":"soup"}`, version: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event crawlEvent
+			version, err := crawlEventSchema.Unmarshal([]byte(tt.data), &event)
+			if err != nil || version != tt.version {
+				t.Fatalf("Unmarshal() = %d, %v; want version %d", version, err, tt.version)
+			}
+			if event.URL != "https://example.com" {
+				t.Errorf("Unmarshal() = %+v, want the URL upgraded", event)
+			}
+		})
+	}
+}
+
+func TestSchema_RoundTrip(t *testing.T) {
+	for version := 1; version <= 3; version++ {
+		data, err := crawlEventSchema.Marshal(crawlEvent{URL: "https://example.com"}, version)
+		if err != nil {
+			t.Fatalf("Marshal(%d) error = %v", version, err)
+		}
+		var event crawlEvent
+		if got, err := crawlEventSchema.Unmarshal(data, &event); err != nil || got != version || event.URL != "https://example.com" {
+			t.Errorf("Unmarshal(Marshal(%d)) = %+v, %d, %v", version, event, got, err)
+		}
+	}
+}
+
+func TestSchema_UnmarshalRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		code errs.Code
+	}{
+		{name: "newer release", data: `{"schema_version":4,"url":"x"}`, code: errs.CodeSchemaTooNew},
+		{name: "invalid version", data: `{"schema_version":"two"}`, code: errs.CodeBadPayload},
+		{name: "not an object", data: `["x"]`, code: errs.CodeBadPayload},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var event crawlEvent
+			if _, err := crawlEventSchema.Unmarshal([]byte(tt.data), &event); errs.CodeOf(err) != tt.code {
+				t.Errorf("Unmarshal() error = %v, want %s", err, tt.code)
+			}
+		})
+	}
+
+	// Versions without an upgrade path are no longer read
+	current := messagequeue.NewSchema("current", 2, nil)
+	var event crawlEvent
+	if _, err := current.Unmarshal([]byte(`{"url":"x"}`), &event); errs.CodeOf(err) != errs.CodeSchemaUnsupported {
+		t.Errorf("Unmarshal() of a retired version error = %v, want %s", err, errs.CodeSchemaUnsupported)
+	}
+}
+
+func TestSchema_Headers(t *testing.T) {
+	if got := crawlEventSchema.Headers(2)[messagequeue.HeaderSchemaVersion]; got != "2" {
+		t.Errorf("Headers(2) = %q, want 2", got)
+	}
+}
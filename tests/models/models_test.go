@@ -352,6 +352,13 @@ func TestAllModelsTableNames(t *testing.T) {
 		{"Page", models.Page{}, "pages"},
 		{"Product", models.Product{}, "products"},
 		{"Article", models.Article{}, "articles"},
+		{"PageContent", models.PageContent{}, "page_contents"},
+		{"CrawlLog", models.CrawlLog{}, "crawl_logs"},
+		{"Feed", models.Feed{}, "feeds"},
+		{"Asset", models.Asset{}, "assets"},
+		{"SecurityAudit", models.SecurityAudit{}, "security_audits"},
+		{"DomainCertificate", models.DomainCertificate{}, "domain_certificates"},
+		{"Domain", models.Domain{}, "domains"},
 	}
 
 	for _, tt := range tests {
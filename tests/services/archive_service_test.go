@@ -0,0 +1,85 @@
+package services_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/services"
+	"github.com/alonecandies/golwarc/storage"
+	"github.com/alonecandies/golwarc/warc"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// ArchiveService Unit Tests
+// =============================================================================
+
+func writeArchiveWARC(t *testing.T, path, body string) {
+	t.Helper()
+
+	w, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+body)
+	rec.Content = []byte(body)
+	if _, _, err := w.WriteRecord(rec); err != nil {
+		t.Fatalf("WriteRecord() error = %v", err)
+	}
+	_ = w.Close()
+}
+
+func TestArchiveService_RunMaintenance_RelocatesPages(t *testing.T) {
+	dir := t.TempDir()
+	writeArchiveWARC(t, filepath.Join(dir, "a.warc.gz"), "a")
+	writeArchiveWARC(t, filepath.Join(dir, "b.warc.gz"), "b")
+
+	gormDB, mock := newCorpusMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `pages` SET `body_ref`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `pages` SET `body_ref`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mockDB := &mocks.MockDatabaseClient{
+		DB: gormDB,
+		TransactFunc: func(fn func(*gorm.DB) error) error {
+			return gormDB.Transaction(fn)
+		},
+	}
+
+	service := services.NewArchiveService(zaptest.NewLogger(t), mockDB, storage.LifecycleConfig{WARCDir: dir})
+	report, err := service.RunMaintenance()
+	if err != nil {
+		t.Fatalf("RunMaintenance() error = %v", err)
+	}
+	if report.Relocated != 2 {
+		t.Errorf("Relocated = %d, want 2", report.Relocated)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestArchiveService_RunMaintenance_RelocatesDigests(t *testing.T) {
+	dir := t.TempDir()
+	writeArchiveWARC(t, filepath.Join(dir, "a.warc.gz"), "a")
+	writeArchiveWARC(t, filepath.Join(dir, "b.warc.gz"), "b")
+
+	digests := warc.NewMemoryDigestStore()
+	digests.Remember("sha1:AAA", warc.Capture{File: filepath.Join(dir, "a.warc.gz"), Offset: 0})
+
+	service := services.NewArchiveService(zaptest.NewLogger(t), nil, storage.LifecycleConfig{WARCDir: dir})
+	service.SetDigestStore(digests)
+	report, err := service.RunMaintenance()
+	if err != nil {
+		t.Fatalf("RunMaintenance() error = %v", err)
+	}
+
+	capture, _ := digests.Lookup("sha1:AAA")
+	if capture.File != report.CompactedInto {
+		t.Errorf("Capture file = %q, want %q", capture.File, report.CompactedInto)
+	}
+}
@@ -0,0 +1,143 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"github.com/alonecandies/golwarc/storage"
+	"go.uber.org/zap/zaptest"
+)
+
+// =============================================================================
+// Asset Downloader Tests
+// =============================================================================
+
+// pngHeader is enough of a PNG for content type sniffing
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func assetServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(`<html><head><title>Docs</title></head><body>
+<img src="/logo.png"><img src="/copy.png"><a href="/manual.pdf">Manual</a>
+<a href="/huge.pdf">Huge</a><a href="/page.html" download>Page</a><img src="/missing.png">
+</body></html>`))
+		case "/logo.png", "/copy.png":
+			_, _ = w.Write(pngHeader) // No Content-Type; sniffed as image/png
+		case "/manual.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.4 manual"))
+		case "/huge.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write(bytes.Repeat([]byte("x"), 2048))
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte("<html></html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAssetDownloader_Download(t *testing.T) {
+	server := assetServer(t)
+	objects, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	var saved []models.Asset
+	db := &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			saved = append(saved, *value.(*[]models.Asset)...)
+			return nil
+		},
+	}
+	downloader := services.NewAssetDownloader(zaptest.NewLogger(t), db, objects, services.AssetDownloaderConfig{MaxSize: 1024})
+
+	page := &models.Page{ID: 3, URL: server.URL + "/"}
+	assets, err := downloader.Download(context.Background(), page, []string{
+		server.URL + "/logo.png",
+		server.URL + "/copy.png",
+		server.URL + "/manual.pdf",
+		server.URL + "/huge.pdf",
+		server.URL + "/page.html",
+		server.URL + "/missing.png",
+	})
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if len(assets) != 3 || len(saved) != 3 {
+		t.Fatalf("Download() stored %d assets (%d saved), want 3: %+v", len(assets), len(saved), assets)
+	}
+
+	sum := sha256.Sum256(pngHeader)
+	logo := assets[0]
+	if logo.PageID != 3 || logo.ContentType != "image/png" || logo.Size != int64(len(pngHeader)) || logo.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Unexpected asset: %+v", logo)
+	}
+	if assets[1].StorageKey != logo.StorageKey {
+		t.Errorf("Identical files stored under %q and %q, want one object", logo.StorageKey, assets[1].StorageKey)
+	}
+	if data, err := objects.Get(logo.StorageKey); err != nil || !bytes.Equal(data, pngHeader) {
+		t.Errorf("Stored object = %q, %v", data, err)
+	}
+	if assets[2].URL != server.URL+"/manual.pdf" || assets[2].ContentType != "application/pdf" {
+		t.Errorf("Unexpected asset: %+v", assets[2])
+	}
+
+	if _, err := downloader.Download(context.Background(), &models.Page{URL: page.URL}, []string{server.URL + "/logo.png"}); !errs.HasCode(err, errs.CodeInvalidRequest) {
+		t.Errorf("Download() for an unsaved page error = %v, want %s", err, errs.CodeInvalidRequest)
+	}
+}
+
+func TestCrawlerService_Assets(t *testing.T) {
+	server := assetServer(t)
+	objects, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	var assets []models.Asset
+	db := &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			switch v := value.(type) {
+			case *models.Page:
+				v.ID = 9
+			case *[]models.Asset:
+				assets = append(assets, *v...)
+			}
+			return nil
+		},
+	}
+
+	service := services.NewCrawlerService(zaptest.NewLogger(t), nil, db)
+	service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+	service.SetAssetDownloader(services.NewAssetDownloader(zaptest.NewLogger(t), db, objects, services.AssetDownloaderConfig{MaxSize: 1024}))
+	if err := service.CrawlAndStore(server.URL + "/"); err != nil {
+		t.Fatalf("CrawlAndStore() error = %v", err)
+	}
+
+	if len(assets) != 3 {
+		t.Fatalf("Stored %d assets, want 3: %+v", len(assets), assets)
+	}
+	for _, a := range assets {
+		if a.PageID != 9 {
+			t.Errorf("Asset %s linked to page %d, want 9", a.URL, a.PageID)
+		}
+	}
+}
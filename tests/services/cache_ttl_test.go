@@ -0,0 +1,139 @@
+package services_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+)
+
+// =============================================================================
+// Cache TTL Policy Tests
+// =============================================================================
+
+// jsonCache is a mock cache that keeps JSON values and their TTLs
+func jsonCache() (*mocks.MockCacheClient, map[string]time.Duration) {
+	data := map[string][]byte{}
+	ttls := map[string]time.Duration{}
+	return &mocks.MockCacheClient{
+		GetJSONFunc: func(key string, dest interface{}) error {
+			raw, ok := data[key]
+			if !ok {
+				return cache.ErrCacheMiss
+			}
+			return json.Unmarshal(raw, dest)
+		},
+		SetJSONFunc: func(key string, value interface{}, ttl time.Duration) error {
+			raw, err := json.Marshal(value)
+			data[key], ttls[key] = raw, ttl
+			return err
+		},
+	}, ttls
+}
+
+func TestTTLPolicy_Rules(t *testing.T) {
+	policy, err := services.NewTTLPolicy(nil, services.TTLPolicyConfig{
+		Default: 12 * time.Hour,
+		Rules: []services.TTLRule{
+			{Kind: services.PageKindArticle, TTL: time.Hour},
+			{Pattern: "*/docs/", TTL: 7 * 24 * time.Hour},
+			{ContentType: "application/*", TTL: 48 * time.Hour},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewTTLPolicy() error = %v", err)
+	}
+
+	tests := []struct {
+		url, contentType, kind string
+		want                   time.Duration
+	}{
+		{"https://news.example.com/story", "text/html", services.PageKindArticle, time.Hour},
+		{"https://example.com/docs/install", "text/html; charset=utf-8", services.PageKindPage, 7 * 24 * time.Hour},
+		{"https://example.com/report", "application/pdf", services.PageKindPage, 48 * time.Hour},
+		{"https://example.com/about", "text/html", services.PageKindPage, 12 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := policy.TTL(&models.Page{URL: tt.url}, tt.contentType, tt.kind); got != tt.want {
+			t.Errorf("TTL(%s, %s, %s) = %v, want %v", tt.url, tt.contentType, tt.kind, got, tt.want)
+		}
+	}
+
+	if _, err := services.NewTTLPolicy(nil, services.TTLPolicyConfig{Rules: []services.TTLRule{{Kind: "page"}}}); err == nil {
+		t.Error("Expected an error for a rule without a TTL")
+	}
+	var none *services.TTLPolicy
+	if got := none.TTL(&models.Page{}, "", ""); got != 24*time.Hour {
+		t.Errorf("Expected a nil policy to cache for 24h, got %v", got)
+	}
+}
+
+func TestTTLPolicy_Adaptive(t *testing.T) {
+	client, _ := jsonCache()
+	fake := clock.NewFake(time.Time{})
+	policy, err := services.NewTTLPolicy(client, services.TTLPolicyConfig{
+		Default:  24 * time.Hour,
+		Adaptive: true,
+		MinTTL:   10 * time.Minute,
+		MaxTTL:   7 * 24 * time.Hour,
+		Clock:    fake,
+	})
+	if err != nil {
+		t.Fatalf("NewTTLPolicy() error = %v", err)
+	}
+	crawl := func(body string) time.Duration {
+		return policy.TTL(&models.Page{URL: "https://example.com/live", HTML: body}, "text/html", services.PageKindPage)
+	}
+
+	if got := crawl("v1"); got != 24*time.Hour {
+		t.Fatalf("Expected the default TTL before a change is seen, got %v", got)
+	}
+	fake.Advance(4 * time.Hour)
+	if got := crawl("v2"); got != 2*time.Hour {
+		t.Errorf("Expected half the 4h change interval, got %v", got)
+	}
+	fake.Advance(2 * time.Hour)
+	if got := crawl("v3"); got != 90*time.Minute {
+		t.Errorf("Expected half the smoothed 3h interval, got %v", got)
+	}
+	fake.Advance(30 * 24 * time.Hour)
+	if got := crawl("v3"); got != 7*24*time.Hour {
+		t.Errorf("Expected an unchanged page to reach MaxTTL, got %v", got)
+	}
+}
+
+func TestCrawlerService_CacheTTL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Docs</title></head></html>`))
+	}))
+	defer server.Close()
+
+	client, ttls := jsonCache()
+	policy, err := services.NewTTLPolicy(client, services.TTLPolicyConfig{
+		Rules: []services.TTLRule{{Pattern: "*/docs/", ContentType: "text/html", TTL: 7 * 24 * time.Hour}},
+	})
+	if err != nil {
+		t.Fatalf("NewTTLPolicy() error = %v", err)
+	}
+	service := services.NewCrawlerService(zaptest.NewLogger(t), client, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+	service.SetTTLPolicy(policy)
+
+	url := server.URL + "/docs/install"
+	if err := service.CrawlAndStore(url); err != nil {
+		t.Fatalf("CrawlAndStore() error = %v", err)
+	}
+	if got := ttls["page:"+url]; got != 7*24*time.Hour {
+		t.Errorf("Expected the page to be cached for 7d, got %v", got)
+	}
+}
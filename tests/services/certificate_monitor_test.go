@@ -0,0 +1,137 @@
+package services_test
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// TLS Certificate Monitor Tests
+// =============================================================================
+
+// certificateDB returns a database client over sqlmock
+func certificateDB(t *testing.T) (*mocks.MockDatabaseClient, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet expectations: %v", err)
+		}
+	})
+	return &mocks.MockDatabaseClient{DB: gormDB}, mock
+}
+
+func TestCertificateMonitor_Check(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	fake := clock.NewFake(server.Certificate().NotAfter.Add(-3 * 24 * time.Hour))
+
+	db, mock := certificateDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `domain_certificates` .* ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	var alerts []models.DomainCertificate
+	monitor := services.NewCertificateMonitor(zaptest.NewLogger(t), db, services.CertificateMonitorConfig{
+		ExpiryWarning: 7 * 24 * time.Hour,
+		RootCAs:       roots,
+		OnExpiring:    func(cert models.DomainCertificate) { alerts = append(alerts, cert) },
+		Clock:         fake,
+	})
+
+	cert, err := monitor.Check(context.Background(), server.URL+"/page")
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	host := strings.TrimPrefix(server.URL, "https://")
+	if cert.Host != host || !cert.Verified || cert.Fingerprint == "" || !cert.NotAfter.Equal(server.Certificate().NotAfter) {
+		t.Errorf("Check() = %+v", cert)
+	}
+	if !strings.Contains(cert.DNSNames, "example.com") {
+		t.Errorf("DNSNames = %q, want the SANs", cert.DNSNames)
+	}
+	if len(alerts) != 1 || alerts[0].Host != host {
+		t.Errorf("Alerts = %+v, want one for %s", alerts, host)
+	}
+
+	// Checked hosts are skipped until CheckEvery has passed
+	if cert, err := monitor.Check(context.Background(), server.URL+"/other"); cert != nil || err != nil {
+		t.Errorf("Second Check() = %+v, %v, want skipped", cert, err)
+	}
+	if cert, err := monitor.Check(context.Background(), "http://example.com/"); cert != nil || err != nil {
+		t.Errorf("Check(http) = %+v, %v, want skipped", cert, err)
+	}
+}
+
+func TestCertificateMonitor_CheckUnverified(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	db, mock := certificateDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `domain_certificates`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	alerted := false
+	monitor := services.NewCertificateMonitor(zaptest.NewLogger(t), db, services.CertificateMonitorConfig{
+		OnExpiring: func(models.DomainCertificate) { alerted = true },
+	})
+	cert, err := monitor.Check(context.Background(), server.URL)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if cert.Verified || cert.VerifyError == "" {
+		t.Errorf("Self-signed certificate verified: %+v", cert)
+	}
+	if alerted {
+		t.Error("Alerted on a certificate far from expiry")
+	}
+}
+
+func TestCertificateMonitor_Expiring(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	db, mock := certificateDB(t)
+	mock.ExpectQuery("SELECT \\* FROM `domain_certificates` WHERE not_after < \\? ORDER BY not_after").
+		WithArgs(now.Add(30 * 24 * time.Hour)).
+		WillReturnRows(sqlmock.NewRows([]string{"host", "not_after"}).AddRow("shop.example", now.Add(48*time.Hour)))
+
+	monitor := services.NewCertificateMonitor(zaptest.NewLogger(t), db, services.CertificateMonitorConfig{Clock: clock.NewFake(now)})
+	certs, err := monitor.Expiring(context.Background(), 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Expiring() error = %v", err)
+	}
+	if len(certs) != 1 || certs[0].Host != "shop.example" {
+		t.Errorf("Expiring() = %+v", certs)
+	}
+}
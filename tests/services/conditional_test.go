@@ -0,0 +1,341 @@
+package services_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/cache"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Validators Tests
+// =============================================================================
+
+func TestValidators_FromAndApply(t *testing.T) {
+	header := http.Header{}
+	header.Set("ETag", `"v1"`)
+	header.Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+
+	validators := services.ValidatorsFrom(header)
+	if validators.IsZero() {
+		t.Fatal("Expected validators from response headers")
+	}
+
+	request := http.Header{}
+	validators.Apply(request)
+	if request.Get("If-None-Match") != `"v1"` {
+		t.Errorf("If-None-Match = %q", request.Get("If-None-Match"))
+	}
+	if request.Get("If-Modified-Since") != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("If-Modified-Since = %q", request.Get("If-Modified-Since"))
+	}
+
+	if !services.ValidatorsFrom(http.Header{}).IsZero() {
+		t.Error("Expected zero validators without cache headers")
+	}
+}
+
+// =============================================================================
+// Validator Store Tests
+// =============================================================================
+
+func TestCacheValidatorStore(t *testing.T) {
+	data := map[string][]byte{}
+	client := &mocks.MockCacheClient{
+		GetJSONFunc: func(key string, dest interface{}) error {
+			raw, ok := data[key]
+			if !ok {
+				return cache.ErrCacheMiss
+			}
+			return json.Unmarshal(raw, dest)
+		},
+		SetJSONFunc: func(key string, value interface{}, ttl time.Duration) error {
+			raw, err := json.Marshal(value)
+			data[key] = raw
+			return err
+		},
+	}
+	store := services.NewCacheValidatorStore(client, time.Hour)
+
+	if got, err := store.LoadValidators("p", "https://example.com/"); err != nil || !got.IsZero() {
+		t.Fatalf("LoadValidators() = %+v, %v; want zero", got, err)
+	}
+
+	want := services.Validators{ETag: `"v1"`}
+	if err := store.SaveValidators("p", "https://example.com/", want); err != nil {
+		t.Fatalf("SaveValidators() error = %v", err)
+	}
+	if got, err := store.LoadValidators("p", "https://example.com/"); err != nil || got != want {
+		t.Errorf("LoadValidators() = %+v, %v; want %+v", got, err, want)
+	}
+	if got, _ := store.LoadValidators("other", "https://example.com/"); !got.IsZero() {
+		t.Errorf("Expected validators to be scoped to the project, got %+v", got)
+	}
+}
+
+func TestDBValidatorStore_Load(t *testing.T) {
+	gormDB, mock := newCorpusMockDB(t)
+	store := services.NewDBValidatorStore(&mocks.MockDatabaseClient{DB: gormDB})
+
+	mock.ExpectQuery("SELECT \\* FROM `url_validators`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "project", "url", "etag", "last_modified"}).
+			AddRow(1, "p", "https://example.com/", `"v1"`, ""))
+	got, err := store.LoadValidators("p", "https://example.com/")
+	if err != nil || got.ETag != `"v1"` {
+		t.Errorf("LoadValidators() = %+v, %v", got, err)
+	}
+
+	mock.ExpectQuery("SELECT \\* FROM `url_validators`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	if got, err := store.LoadValidators("p", "https://example.com/new"); err != nil || !got.IsZero() {
+		t.Errorf("LoadValidators(missing) = %+v, %v; want zero", got, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestPageValidatorStore(t *testing.T) {
+	gormDB, mock := newCorpusMockDB(t)
+	store := services.NewPageValidatorStore(&mocks.MockDatabaseClient{DB: gormDB})
+
+	mock.ExpectQuery("SELECT `etag`,`last_modified` FROM `pages`").
+		WillReturnRows(sqlmock.NewRows([]string{"etag", "last_modified"}).
+			AddRow(`"v1"`, "Mon, 02 Jan 2006 15:04:05 GMT"))
+	got, err := store.LoadValidators("p", "https://example.com/")
+	if err != nil || got.ETag != `"v1"` || got.LastModified != "Mon, 02 Jan 2006 15:04:05 GMT" {
+		t.Errorf("LoadValidators() = %+v, %v", got, err)
+	}
+
+	mock.ExpectQuery("SELECT `etag`,`last_modified` FROM `pages`").
+		WillReturnRows(sqlmock.NewRows([]string{"etag", "last_modified"}))
+	if got, err := store.LoadValidators("p", "https://example.com/new"); err != nil || !got.IsZero() {
+		t.Errorf("LoadValidators(missing) = %+v, %v; want zero", got, err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `pages` SET `etag`=\\?,`last_modified`=\\?").
+		WithArgs(`"v2"`, "", sqlmock.AnyArg(), "p", "https://example.com/").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	if err := store.SaveValidators("p", "https://example.com/", services.Validators{ETag: `"v2"`}); err != nil {
+		t.Errorf("SaveValidators() error = %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestTieredValidatorStore(t *testing.T) {
+	cached := &memoryValidatorStore{}
+	durable := &memoryValidatorStore{}
+	store := services.NewTieredValidatorStore(cached, durable)
+
+	want := services.Validators{ETag: `"v1"`}
+	if err := store.SaveValidators("p", "https://example.com/", want); err != nil {
+		t.Fatalf("SaveValidators() error = %v", err)
+	}
+	if got, _ := durable.LoadValidators("p", "https://example.com/"); got != want {
+		t.Errorf("Expected the durable store to be written, got %+v", got)
+	}
+
+	// A flushed cache is refilled from the durable store
+	cached.data = nil
+	if got, err := store.LoadValidators("p", "https://example.com/"); err != nil || got != want {
+		t.Errorf("LoadValidators() = %+v, %v; want %+v", got, err, want)
+	}
+	if got, _ := cached.LoadValidators("p", "https://example.com/"); got != want {
+		t.Errorf("Expected the cache to be refilled, got %+v", got)
+	}
+}
+
+func TestBackfillPageValidators(t *testing.T) {
+	gormDB, mock := newCorpusMockDB(t)
+	db := &mocks.MockDatabaseClient{
+		DB: gormDB,
+		TransactFunc: func(fn func(*gorm.DB) error) error {
+			return gormDB.Transaction(fn)
+		},
+	}
+
+	mock.ExpectBegin()
+	expectMigrationApplied(mock, "backfill_page_validators", 0)
+	mock.ExpectExec("UPDATE pages SET\\s+etag = \\(SELECT v.etag FROM url_validators v").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("INSERT INTO `schema_migrations`").
+		WithArgs("backfill_page_validators", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	updated, err := services.BackfillPageValidators(db)
+	if err != nil || updated != 3 {
+		t.Errorf("BackfillPageValidators() = %d, %v; want 3", updated, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestBackfillPageValidators_RunsOnce(t *testing.T) {
+	gormDB, mock := newCorpusMockDB(t)
+	db := &mocks.MockDatabaseClient{
+		DB: gormDB,
+		TransactFunc: func(fn func(*gorm.DB) error) error {
+			return gormDB.Transaction(fn)
+		},
+	}
+
+	// The migration is recorded, so pages are not scanned again
+	mock.ExpectBegin()
+	expectMigrationApplied(mock, "backfill_page_validators", 1)
+	mock.ExpectCommit()
+
+	updated, err := services.BackfillPageValidators(db)
+	if err != nil || updated != 0 {
+		t.Errorf("BackfillPageValidators() = %d, %v; want 0", updated, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+// expectMigrationApplied expects the schema_migrations lookup of a one-time migration
+func expectMigrationApplied(mock sqlmock.Sqlmock, name string, count int) {
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `schema_migrations` WHERE name = \\?").
+		WithArgs(name).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+// =============================================================================
+// Conditional Crawl Tests
+// =============================================================================
+
+// memoryValidatorStore keeps validators in a map
+type memoryValidatorStore struct {
+	mu   sync.Mutex
+	data map[string]services.Validators
+}
+
+func (s *memoryValidatorStore) LoadValidators(project, url string) (services.Validators, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data[project+" "+url], nil
+}
+
+func (s *memoryValidatorStore) SaveValidators(project, url string, validators services.Validators) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		s.data = map[string]services.Validators{}
+	}
+	s.data[project+" "+url] = validators
+	return nil
+}
+
+func TestCrawlerService_ConditionalRecrawl(t *testing.T) {
+	var fetches, revalidated int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidated++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		fetches++
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Cached</title></head></html>"))
+	}))
+	defer server.Close()
+
+	var pages []*models.Page
+	var logs []*models.CrawlLog
+	db := &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			switch v := value.(type) {
+			case *models.Page:
+				pages = append(pages, v)
+			case *models.CrawlLog:
+				logs = append(logs, v)
+			}
+			return nil
+		},
+	}
+	store := &memoryValidatorStore{}
+
+	// A fresh service per crawl, as Colly does not revisit URLs
+	crawl := func() error {
+		service := services.NewCrawlerService(zaptest.NewLogger(t), nil, db)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		service.SetValidatorStore(store)
+		return service.CrawlAndStore(server.URL)
+	}
+
+	if err := crawl(); err != nil {
+		t.Fatalf("first CrawlAndStore() error = %v", err)
+	}
+	if err := crawl(); err != nil {
+		t.Fatalf("second CrawlAndStore() error = %v", err)
+	}
+
+	if fetches != 1 || revalidated != 1 {
+		t.Errorf("fetches = %d, revalidated = %d; want 1 and 1", fetches, revalidated)
+	}
+	if len(pages) != 1 {
+		t.Errorf("Stored %d pages, want only the first crawl's", len(pages))
+	} else if pages[0].ETag != `"v1"` {
+		t.Errorf("Expected the page to keep its ETag, got %q", pages[0].ETag)
+	}
+	if len(logs) != 2 || logs[1].Status != http.StatusNotModified || logs[1].Error != "" {
+		t.Errorf("Expected the re-crawl to be logged as 304, got %+v", logs)
+	}
+}
+
+func TestCrawlerService_ConditionalRecrawl_DropsStaleValidators(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Header.Get("If-None-Match"))
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Replaced</title></head></html>"))
+	}))
+	defer server.Close()
+
+	store := &memoryValidatorStore{}
+	_ = store.SaveValidators("", server.URL, services.Validators{ETag: `"v1"`})
+
+	service := services.NewCrawlerService(zaptest.NewLogger(t), nil, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1, AllowURLRevisit: true}))
+	service.SetValidatorStore(store)
+
+	if err := service.CrawlAndStore(server.URL); err != nil {
+		t.Fatalf("first CrawlAndStore() error = %v", err)
+	}
+
+	// The page was deleted, so the next crawl has nothing to revalidate
+	store.mu.Lock()
+	store.data = nil
+	store.mu.Unlock()
+	if err := service.CrawlAndStore(server.URL); err != nil {
+		t.Fatalf("second CrawlAndStore() error = %v", err)
+	}
+
+	if len(sent) != 2 || sent[0] != `"v1"` || sent[1] != "" {
+		t.Errorf("If-None-Match sent = %q, want %q", sent, []string{`"v1"`, ""})
+	}
+}
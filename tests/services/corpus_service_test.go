@@ -0,0 +1,158 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// CorpusService Unit Tests
+// =============================================================================
+
+func newCorpusMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+func TestContentHash(t *testing.T) {
+	a := services.ContentHash("<html>same</html>")
+	b := services.ContentHash("<html>same</html>")
+	c := services.ContentHash("<html>different</html>")
+
+	if a != b {
+		t.Error("Expected identical bodies to produce identical hashes")
+	}
+	if a == c {
+		t.Error("Expected different bodies to produce different hashes")
+	}
+	if len(a) != 64 {
+		t.Errorf("Expected 64-character hex hash, got %d characters", len(a))
+	}
+}
+
+func TestCorpusService_Store_NewContent(t *testing.T) {
+	gormDB, mock := newCorpusMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `page_contents`.*ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	service := services.NewCorpusService(zaptest.NewLogger(t), &mocks.MockDatabaseClient{DB: gormDB})
+
+	page := &models.Page{URL: "https://example.com", HTML: "<html>body</html>"}
+	if err := service.Store(page); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if page.HTML != "" {
+		t.Error("Expected page HTML to be moved into the corpus")
+	}
+	if page.ContentHash != services.ContentHash("<html>body</html>") {
+		t.Errorf("Unexpected content hash: %s", page.ContentHash)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestCorpusService_Store_ExistingContent(t *testing.T) {
+	gormDB, mock := newCorpusMockDB(t)
+
+	// The hash is already stored, so the insert hits the unique index and
+	// affects no rows
+	hash := services.ContentHash("<html>shared</html>")
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `page_contents`.*ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	service := services.NewCorpusService(zaptest.NewLogger(t), &mocks.MockDatabaseClient{DB: gormDB})
+
+	page := &models.Page{URL: "https://example.org", HTML: "<html>shared</html>"}
+	if err := service.Store(page); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+
+	if page.ContentHash != hash {
+		t.Errorf("ContentHash = %s, want %s", page.ContentHash, hash)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
+
+func TestCorpusService_Store_EmptyHTML(t *testing.T) {
+	service := services.NewCorpusService(zaptest.NewLogger(t), &mocks.MockDatabaseClient{})
+
+	page := &models.Page{URL: "https://example.com"}
+	if err := service.Store(page); err != nil {
+		t.Fatalf("Store() error = %v", err)
+	}
+	if page.ContentHash != "" {
+		t.Error("Expected no content hash for empty HTML")
+	}
+}
+
+func TestCorpusService_Load(t *testing.T) {
+	mockDB := &mocks.MockDatabaseClient{
+		FirstFunc: func(dest interface{}, conds ...interface{}) error {
+			content := dest.(*models.PageContent)
+			content.Body = "<html>restored</html>"
+			return nil
+		},
+	}
+	service := services.NewCorpusService(zaptest.NewLogger(t), mockDB)
+
+	page := &models.Page{ContentHash: "abc"}
+	if err := service.Load(page); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if page.HTML != "<html>restored</html>" {
+		t.Errorf("HTML = %q, want restored body", page.HTML)
+	}
+}
+
+func TestCorpusService_Load_Error(t *testing.T) {
+	mockDB := &mocks.MockDatabaseClient{
+		FirstFunc: func(dest interface{}, conds ...interface{}) error {
+			return errors.New("record not found")
+		},
+	}
+	service := services.NewCorpusService(zaptest.NewLogger(t), mockDB)
+
+	if err := service.Load(&models.Page{ContentHash: "missing"}); err == nil {
+		t.Error("Expected error when content is missing")
+	}
+}
+
+func TestCrawlerService_SetProject(t *testing.T) {
+	service := services.NewCrawlerService(zaptest.NewLogger(t), &mocks.MockCacheClient{}, &mocks.MockDatabaseClient{})
+
+	// Should not panic when toggling the shared corpus
+	service.SetProject("project-a", true)
+	service.SetProject("project-b", false)
+}
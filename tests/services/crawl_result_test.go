@@ -0,0 +1,176 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+)
+
+// =============================================================================
+// Crawl Result and Batch Tests
+// =============================================================================
+
+func TestCrawlerService_CrawlAndStoreBatch(t *testing.T) {
+	var mu sync.Mutex
+	hits := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hits[r.URL.Path]++
+		n := hits[r.URL.Path]
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/broken":
+			if n == 1 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		case "/plain":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte("not a page"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>" + r.URL.Path + "</title></head></html>"))
+	}))
+	defer server.Close()
+
+	var pages []*models.Page
+	var dbFailures int
+	db := &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			page, ok := value.(*models.Page)
+			if !ok {
+				return nil
+			}
+			if strings.HasSuffix(page.URL, "/db") && dbFailures == 0 {
+				dbFailures++
+				return errors.New("connection reset")
+			}
+			pages = append(pages, page)
+			return nil
+		},
+	}
+	var cacheFailures int
+	cache := &mocks.MockCacheClient{
+		SetJSONFunc: func(key string, value interface{}, ttl time.Duration) error {
+			if strings.HasSuffix(key, "/ok") && cacheFailures == 0 {
+				cacheFailures++
+				return errors.New("redis down")
+			}
+			return nil
+		},
+	}
+
+	service := services.NewCrawlerService(zaptest.NewLogger(t), cache, db)
+	service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1, AllowURLRevisit: true}))
+
+	paths := []string{"/ok", "/broken", "/plain", "/db"}
+	urls := make([]string, len(paths))
+	for i, path := range paths {
+		urls[i] = server.URL + path
+	}
+	results := service.CrawlAndStoreBatch(context.Background(), urls)
+	if len(results) != len(urls) {
+		t.Fatalf("Expected %d results, got %d", len(urls), len(results))
+	}
+
+	want := []struct {
+		stage  string
+		code   errs.Code
+		stored bool
+	}{
+		{services.StageCache, errs.CodeCacheUnavailable, true},
+		{services.StageFetch, errs.CodeFetchFailed, false},
+		{services.StageExtract, errs.CodeExtractFailed, false},
+		{services.StageStore, errs.CodeStoreFailed, false},
+	}
+	for i, result := range results {
+		if result.URL != urls[i] || result.Stage() != want[i].stage || result.Stored != want[i].stored {
+			t.Errorf("results[%d] = %s at stage %q, stored %v; want %s at %q, stored %v",
+				i, result.URL, result.Stage(), result.Stored, urls[i], want[i].stage, want[i].stored)
+		}
+		failure := result.Err
+		if failure == nil && len(result.Warnings) > 0 {
+			failure = result.Warnings[0]
+		}
+		var crawlErr *services.CrawlError
+		if !errors.As(failure, &crawlErr) || errs.CodeOf(failure) != want[i].code {
+			t.Errorf("results[%d] error = %v (%s), want a CrawlError with %s", i, failure, errs.CodeOf(failure), want[i].code)
+		}
+		if result.Page != nil && result.Page.URL != urls[i] {
+			t.Errorf("results[%d] holds the page of %s", i, result.Page.URL)
+		}
+	}
+	if results[3].Page == nil {
+		t.Error("Expected the fetched page on a store failure")
+	}
+
+	// Retries resume at the failed stage
+	for i := range results {
+		results[i] = service.Retry(context.Background(), results[i])
+		if stage := results[i].Stage(); i != 2 && stage != "" {
+			t.Errorf("Retry of %s failed at %s: %v", urls[i], stage, results[i].Err)
+		}
+	}
+	if hits["/ok"] != 1 || hits["/db"] != 1 {
+		t.Errorf("Expected cache and store retries not to refetch, got %v", hits)
+	}
+	if hits["/broken"] != 2 || hits["/plain"] != 2 {
+		t.Errorf("Expected fetch and extract retries to refetch, got %v", hits)
+	}
+	if len(pages) != 3 {
+		t.Errorf("Expected each page to be saved once, got %d", len(pages))
+	}
+	if results[2].Stage() != services.StageExtract {
+		t.Errorf("Expected /plain to fail extraction again, got %q", results[2].Stage())
+	}
+}
+
+func TestCrawlerService_CrawlAndStoreBatch_Canceled(t *testing.T) {
+	crawler := &mocks.MockCrawlerClient{}
+	service := services.NewCrawlerService(zaptest.NewLogger(t), nil, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawler)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results := service.CrawlAndStoreBatch(ctx, []string{"https://example.com/a", "https://example.com/b"})
+
+	for _, result := range results {
+		if result.Stage() != services.StageFetch || errs.CodeOf(result.Err) != errs.CodeCanceled {
+			t.Errorf("Expected %s to be canceled at fetch, got %v", result.URL, result.Err)
+		}
+	}
+	if len(crawler.VisitedURLs) != 0 {
+		t.Errorf("Expected no visits after cancellation, got %v", crawler.VisitedURLs)
+	}
+}
+
+func TestCrawlerService_CrawlAndStore_TypedErrors(t *testing.T) {
+	crawler := &mocks.MockCrawlerClient{
+		VisitContextFunc: func(ctx context.Context, url string) error {
+			return &crawlers.SkippedError{Decision: crawlers.SkipDecision{URL: url, Reason: crawlers.SkipRobots}}
+		},
+	}
+	service := services.NewCrawlerService(zaptest.NewLogger(t), nil, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawler)
+
+	err := service.CrawlAndStore("https://example.com/private")
+	if services.StageOf(err) != services.StageFetch || errs.CodeOf(err) != errs.CodeRobotsDisallowed {
+		t.Errorf("Expected a fetch failure keeping the robots code, got %v (%s)", err, errs.CodeOf(err))
+	}
+	if services.StageOf(errors.New("other")) != "" {
+		t.Error("Expected no stage for a plain error")
+	}
+}
@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+)
+
+// =============================================================================
+// Crawl Statistics Tests
+// =============================================================================
+
+func TestCrawlStatsService_DomainStats(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return since.Add(time.Duration(minutes) * time.Minute) }
+
+	db, mock := certificateDB(t)
+	rows := sqlmock.NewRows([]string{"domain", "status", "duration_ms", "bytes", "error", "skip_reason", "created_at"})
+	for i := 1; i <= 10; i++ {
+		rows.AddRow("shop.example", 200, i*100, 1000*i, "", "", at(i))
+	}
+	rows.AddRow("blog.example", 200, 50, 4000, "", "", at(1)).
+		AddRow("blog.example", 503, 900, 0, "", "", at(2)).
+		AddRow("blog.example", 0, 3000, 0, "timeout", "", at(3)).
+		AddRow("blog.example", 0, 0, 0, "", "robots", at(4)) // Skipped, not a failed crawl
+	mock.ExpectQuery("SELECT `domain`,`status`,`duration_ms`,`bytes`,`error`,`skip_reason`,`created_at` FROM `crawl_logs` WHERE project = \\? AND created_at >= \\?").
+		WithArgs("shop", since).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT domain, MAX\\(created_at\\) AS last_success_at FROM `crawl_logs` WHERE .* GROUP BY `domain`").
+		WithArgs("shop", "", 400).
+		WillReturnRows(sqlmock.NewRows([]string{"domain", "last_success_at"}).
+			AddRow("shop.example", at(10)).
+			AddRow("blog.example", at(1)))
+
+	service := services.NewCrawlStatsService(zaptest.NewLogger(t), db)
+	stats, err := service.DomainStats(context.Background(), services.CrawlStatsQuery{Project: "shop", Since: since})
+	if err != nil {
+		t.Fatalf("DomainStats() error = %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("DomainStats() returned %d domains, want 2", len(stats))
+	}
+
+	shop := stats[0]
+	if shop.Domain != "shop.example" || shop.Crawls != 10 || shop.Errors != 0 || shop.ErrorRate != 0 {
+		t.Errorf("shop.example = %+v", shop)
+	}
+	if shop.LatencyP50Ms != 500 || shop.LatencyP90Ms != 900 || shop.LatencyP95Ms != 1000 || shop.LatencyP99Ms != 1000 {
+		t.Errorf("shop.example percentiles = %d/%d/%d/%d", shop.LatencyP50Ms, shop.LatencyP90Ms, shop.LatencyP95Ms, shop.LatencyP99Ms)
+	}
+	if shop.AvgPageBytes != 5500 || !shop.LastCrawlAt.Equal(at(10)) || shop.LastSuccess == nil || !shop.LastSuccess.Equal(at(10)) {
+		t.Errorf("shop.example = %+v", shop)
+	}
+
+	blog := stats[1]
+	if blog.Crawls != 3 || blog.Skipped != 1 || blog.Errors != 2 || blog.AvgPageBytes != 4000 || blog.LatencyP99Ms != 3000 {
+		t.Errorf("blog.example = %+v", blog)
+	}
+	if blog.ErrorRate < 0.66 || blog.ErrorRate > 0.67 || !blog.LastSuccess.Equal(at(1)) {
+		t.Errorf("blog.example = %+v", blog)
+	}
+}
+
+func TestCrawlStatsService_SkipStats(t *testing.T) {
+	since := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	db, mock := certificateDB(t)
+	mock.ExpectQuery("SELECT skip_reason AS reason, skip_rule AS rule, COUNT\\(\\*\\) AS count, COUNT\\(DISTINCT domain\\) AS domains, "+
+		"MIN\\(url\\) AS sample_url, MAX\\(created_at\\) AS last_skipped_at FROM `crawl_logs` "+
+		"WHERE \\(project = \\? AND created_at >= \\? AND skip_reason <> \\?\\) AND domain = \\? GROUP BY skip_reason, skip_rule").
+		WithArgs("shop", since, "", "shop.example").
+		WillReturnRows(sqlmock.NewRows([]string{"reason", "rule", "count", "domains", "sample_url", "last_skipped_at"}).
+			AddRow("duplicate", "cached", 4, 1, "https://shop.example/", since.Add(time.Hour)).
+			AddRow("robots", "user-agent golwarcbot", 12, 1, "https://shop.example/cart", since.Add(2*time.Hour)).
+			AddRow("url_rule", "deny /admin/*", 4, 1, "https://shop.example/admin/", since.Add(3*time.Hour)))
+
+	service := services.NewCrawlStatsService(zaptest.NewLogger(t), db)
+	stats, err := service.SkipStats(context.Background(), services.CrawlStatsQuery{Project: "shop", Domain: "Shop.Example", Since: since})
+	if err != nil {
+		t.Fatalf("SkipStats() error = %v", err)
+	}
+
+	// Most frequent first, then by reason
+	if len(stats) != 3 || stats[0].Reason != "robots" || stats[1].Reason != "duplicate" || stats[2].Reason != "url_rule" {
+		t.Fatalf("SkipStats() = %+v", stats)
+	}
+	if stats[0].Count != 12 || stats[0].Rule != "user-agent golwarcbot" || stats[0].SampleURL != "https://shop.example/cart" {
+		t.Errorf("robots = %+v", stats[0])
+	}
+	if stats[2].Rule != "deny /admin/*" || !stats[2].LastSkippedAt.Equal(since.Add(3*time.Hour)) {
+		t.Errorf("url_rule = %+v", stats[2])
+	}
+}
@@ -1,16 +1,24 @@
 package services_test
 
 import (
+	"context"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/libs"
 	"github.com/alonecandies/golwarc/mocks"
 	"github.com/alonecandies/golwarc/models"
 	"github.com/alonecandies/golwarc/services"
+	"github.com/gocolly/colly/v2"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"go.uber.org/zap/zaptest/observer"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 )
@@ -92,6 +100,36 @@ func TestCrawlerService_CrawlAndStore_CacheHit(t *testing.T) {
 	}
 }
 
+func TestCrawlerService_CrawlAndStore_RecordsSkip(t *testing.T) {
+	mockCache := &mocks.MockCacheClient{
+		ExistsFunc: func(key string) (bool, error) {
+			return true, nil
+		},
+	}
+	var logged []*models.CrawlLog
+	mockDB := &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			if entry, ok := value.(*models.CrawlLog); ok {
+				logged = append(logged, entry)
+			}
+			return nil
+		},
+	}
+
+	service := services.NewCrawlerService(zaptest.NewLogger(t), mockCache, mockDB)
+	if err := service.CrawlAndStore("https://example.com/page"); err != nil {
+		t.Fatalf("CrawlAndStore() error = %v", err)
+	}
+
+	if len(logged) != 1 {
+		t.Fatalf("Expected one crawl log entry, got %d", len(logged))
+	}
+	entry := logged[0]
+	if entry.SkipReason != crawlers.SkipDuplicate || entry.SkipRule != "cached" || entry.Domain != "example.com" {
+		t.Errorf("Unexpected skip entry: %+v", entry)
+	}
+}
+
 func TestCrawlerService_CrawlAndStore_NilCache(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 	mockDB := &mocks.MockDatabaseClient{}
@@ -104,6 +142,24 @@ func TestCrawlerService_CrawlAndStore_NilCache(t *testing.T) {
 	_ = service.CrawlAndStore("https://invalid-test-url-12345.invalid")
 }
 
+func TestCrawlerService_SetCrawler(t *testing.T) {
+	logger := zaptest.NewLogger(t)
+	crawler := &mocks.MockCrawlerClient{
+		VisitContextFunc: func(ctx context.Context, url string) error {
+			return errors.New("offline")
+		},
+	}
+
+	service := services.NewCrawlerService(logger, nil, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawler)
+	if err := service.CrawlAndStore("https://example.com"); err == nil {
+		t.Error("Expected the replacement crawler's error")
+	}
+	if len(crawler.VisitedURLs) != 1 || crawler.VisitedURLs[0] != "https://example.com" {
+		t.Errorf("VisitedURLs = %v, want the crawled URL", crawler.VisitedURLs)
+	}
+}
+
 func TestCrawlerService_GetStats(t *testing.T) {
 	logger := zaptest.NewLogger(t)
 
@@ -364,6 +420,71 @@ func TestCrawlerService_GetRecentPages(t *testing.T) {
 	}
 }
 
+// expectPageURLIndex expects the lookup of the legacy idx_pages_url index
+func expectPageURLIndex(mock sqlmock.Sqlmock, exists bool) {
+	count := 0
+	if exists {
+		count = 1
+	}
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT SCHEMA_NAME from Information_schema.SCHEMATA").
+		WillReturnRows(sqlmock.NewRows([]string{"SCHEMA_NAME"}).AddRow("golwarc"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM information_schema.statistics").
+		WithArgs("golwarc", "pages", "idx_pages_url").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(count))
+}
+
+func TestDropLegacyPageURLIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		exists bool
+	}{
+		{name: "legacy index present", exists: true},
+		{name: "already migrated", exists: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("Failed to create sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			gormDB, err := gorm.Open(mysql.New(mysql.Config{
+				Conn:                      db,
+				SkipInitializeWithVersion: true,
+			}), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("Failed to create gorm DB: %v", err)
+			}
+
+			expectPageURLIndex(mock, tt.exists)
+			if tt.exists {
+				mock.ExpectExec("DROP INDEX `idx_pages_url` ON `pages`").WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+			mock.ExpectCommit()
+
+			mockDB := &mocks.MockDatabaseClient{
+				DB: gormDB,
+				TransactFunc: func(fn func(*gorm.DB) error) error {
+					return gormDB.Transaction(fn)
+				},
+			}
+			dropped, err := services.DropLegacyPageURLIndex(mockDB)
+			if err != nil {
+				t.Fatalf("DropLegacyPageURLIndex() error = %v", err)
+			}
+			if dropped != tt.exists {
+				t.Errorf("dropped = %v, want %v", dropped, tt.exists)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Errorf("Unmet expectations: %v", err)
+			}
+		})
+	}
+}
+
 // =============================================================================
 // Integration-like Tests with Mocks
 // =============================================================================
@@ -387,17 +508,25 @@ func TestCrawlerService_InitializeWithModels(t *testing.T) {
 		t.Fatalf("Initialize failed: %v", err)
 	}
 
-	// Verify that 3 models were migrated (Page, Product, Article)
-	if len(migratedModels) != 3 {
-		t.Errorf("Expected 3 models to be migrated, got %d", len(migratedModels))
+	// Verify that 12 models were migrated (Page, Product, Article, PageContent, CrawlLog, URLValidator, Feed, Asset, SecurityAudit, DomainCertificate, Domain, SchemaMigration)
+	if len(migratedModels) != 12 {
+		t.Fatalf("Expected 12 models to be migrated, got %d", len(migratedModels))
 	}
 
 	// Verify the types
 	_, isPage := migratedModels[0].(*models.Page)
 	_, isProduct := migratedModels[1].(*models.Product)
 	_, isArticle := migratedModels[2].(*models.Article)
-
-	if !isPage || !isProduct || !isArticle {
+	_, isContent := migratedModels[3].(*models.PageContent)
+	_, isCrawlLog := migratedModels[4].(*models.CrawlLog)
+	_, isValidator := migratedModels[5].(*models.URLValidator)
+	_, isFeed := migratedModels[6].(*models.Feed)
+	_, isAsset := migratedModels[7].(*models.Asset)
+	_, isAudit := migratedModels[8].(*models.SecurityAudit)
+	_, isCertificate := migratedModels[9].(*models.DomainCertificate)
+	_, isMigration := migratedModels[11].(*models.SchemaMigration)
+
+	if !isPage || !isProduct || !isArticle || !isContent || !isCrawlLog || !isValidator || !isFeed || !isAsset || !isAudit || !isCertificate || !isMigration {
 		t.Error("Migrated models don't match expected types")
 	}
 }
@@ -456,6 +585,40 @@ func TestCrawlerService_CacheKeyFormat_DifferentURLs(t *testing.T) {
 	}
 }
 
+func TestCrawlerService_CacheKeyFormat_Canonical(t *testing.T) {
+	canonical, err := crawlers.NewCanonicalizer(crawlers.CanonicalConfig{
+		WWW:           crawlers.FoldStrip,
+		TrailingSlash: crawlers.FoldStrip,
+		IndexPages:    []string{"index.html"},
+	})
+	if err != nil {
+		t.Fatalf("NewCanonicalizer() error = %v", err)
+	}
+
+	variants := []string{
+		"https://example.com/docs",
+		"https://www.example.com/docs/",
+		"https://EXAMPLE.com:443/docs/index.html",
+		"https://www.example.com/docs#intro",
+	}
+	for _, url := range variants {
+		var checkedKey string
+		mockCache := &mocks.MockCacheClient{
+			ExistsFunc: func(key string) (bool, error) {
+				checkedKey = key
+				return true, nil
+			},
+		}
+		service := services.NewCrawlerService(zaptest.NewLogger(t), mockCache, &mocks.MockDatabaseClient{})
+		service.SetCanonicalizer(canonical)
+		_ = service.CrawlAndStore(url)
+
+		if checkedKey != "page:https://example.com/docs" {
+			t.Errorf("CrawlAndStore(%q) checked cache key %q, want the canonical URL", url, checkedKey)
+		}
+	}
+}
+
 // =============================================================================
 // Edge Cases
 // =============================================================================
@@ -613,3 +776,271 @@ func BenchmarkCrawlerService_CacheHit(b *testing.B) {
 		_ = service.CrawlAndStore("https://example.com")
 	}
 }
+
+func TestCrawlerService_CrawlAndStoreContext_LogsCrawlID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	mockCache := &mocks.MockCacheClient{
+		ExistsFunc: func(key string) (bool, error) {
+			return true, nil // Cache hit
+		},
+	}
+
+	service := services.NewCrawlerService(zap.New(core), mockCache, &mocks.MockDatabaseClient{})
+	ctx := libs.WithCrawlID(context.Background(), "crawl-42")
+	if err := service.CrawlAndStoreContext(ctx, "https://example.com"); err != nil {
+		t.Fatalf("CrawlAndStoreContext() error = %v", err)
+	}
+
+	if logs.Len() == 0 {
+		t.Fatal("Expected log entries")
+	}
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		if fields[libs.CrawlIDField] != "crawl-42" {
+			t.Errorf("%q: crawl_id = %v, want crawl-42", entry.Message, fields[libs.CrawlIDField])
+		}
+		if fields["url"] != "https://example.com" || fields["stage"] != "fetch" {
+			t.Errorf("%q: fields = %v", entry.Message, fields)
+		}
+	}
+}
+
+func TestCrawlerService_CrawlAndStore_GeneratesCrawlID(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	mockCache := &mocks.MockCacheClient{
+		ExistsFunc: func(key string) (bool, error) {
+			return true, nil
+		},
+	}
+
+	service := services.NewCrawlerService(zap.New(core), mockCache, &mocks.MockDatabaseClient{})
+	_ = service.CrawlAndStore("https://example.com")
+
+	for _, entry := range logs.All() {
+		if id, _ := entry.ContextMap()[libs.CrawlIDField].(string); len(id) != 16 {
+			t.Errorf("%q: crawl_id = %q, want a generated ID", entry.Message, id)
+		}
+	}
+}
+
+func TestNewPageEvent(t *testing.T) {
+	page := &models.Page{ID: 7, Project: "p", URL: "https://example.com/", Title: "Example", Domain: "example.com", Status: 200}
+	event := services.NewPageEvent(libs.WithCrawlID(context.Background(), "abc"), page)
+
+	if event.CrawlID != "abc" || event.PageID != 7 || event.URL != page.URL || event.Domain != page.Domain {
+		t.Errorf("NewPageEvent() = %+v", event)
+	}
+}
+
+func TestDecodePageEvent(t *testing.T) {
+	// Events published before versioning carry no schema version
+	legacy := `{"crawl_id":"abc","page_id":7,"url":"https://example.com/","title":"Example","domain":"example.com","status":200,"crawled_at":"2026-01-02T03:04:05Z"}`
+	event, err := services.DecodePageEvent([]byte(legacy))
+	if err != nil || event.PageID != 7 || event.URL != "https://example.com/" {
+		t.Errorf("DecodePageEvent(legacy) = %+v, %v", event, err)
+	}
+
+	data, err := services.PageEventSchema.Marshal(event, services.PageEventSchema.Current())
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if decoded, err := services.DecodePageEvent(data); err != nil || decoded != event {
+		t.Errorf("DecodePageEvent(Marshal()) = %+v, %v; want %+v", decoded, err, event)
+	}
+
+	newer := `{"schema_version":99,"page_id":7}`
+	if _, err := services.DecodePageEvent([]byte(newer)); errs.CodeOf(err) != errs.CodeSchemaTooNew {
+		t.Errorf("DecodePageEvent(newer) error = %v, want %s", err, errs.CodeSchemaTooNew)
+	}
+}
+
+func TestCrawlerService_CrawlAndStore_NoIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Draft</title><meta name="robots" content="noindex"></head></html>`))
+	}))
+	defer server.Close()
+
+	for _, ignore := range []bool{false, true} {
+		var stored []interface{}
+		mockDB := &mocks.MockDatabaseClient{
+			CreateFunc: func(value interface{}) error {
+				stored = append(stored, value)
+				return nil
+			},
+		}
+
+		service := services.NewCrawlerService(zaptest.NewLogger(t), nil, mockDB)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		service.SetIgnoreRobotsMeta(ignore)
+		if err := service.CrawlAndStore(server.URL + "/draft"); err != nil {
+			t.Fatalf("CrawlAndStore() error = %v", err)
+		}
+
+		pages := 0
+		for _, v := range stored {
+			if _, ok := v.(*models.Page); ok {
+				pages++
+			}
+		}
+		if want := map[bool]int{false: 0, true: 1}[ignore]; pages != want {
+			t.Errorf("ignore=%v: stored %d pages, want %d", ignore, pages, want)
+		}
+	}
+}
+
+func TestCrawlerService_CrawlAndStore_CanonicalURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/product", http.StatusMovedPermanently)
+		case "/product":
+			_, _ = w.Write([]byte(`<html><head><title>Product</title></head></html>`))
+		default:
+			_, _ = w.Write([]byte(`<html><head><title>Variant</title><link rel="canonical" href="/product"></head></html>`))
+		}
+	}))
+	defer server.Close()
+
+	crawl := func(t *testing.T, url string, dedupe bool, stored []models.Page) *models.Page {
+		t.Helper()
+		var saved *models.Page
+		mockDB := &mocks.MockDatabaseClient{
+			CreateFunc: func(value interface{}) error {
+				if page, ok := value.(*models.Page); ok {
+					saved = page
+				}
+				return nil
+			},
+			FindFunc: func(dest interface{}, conds ...interface{}) error {
+				*dest.(*[]models.Page) = stored
+				return nil
+			},
+		}
+		service := services.NewCrawlerService(zaptest.NewLogger(t), nil, mockDB)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		service.SetCanonicalDedupe(dedupe)
+		if err := service.CrawlAndStore(url); err != nil {
+			t.Fatalf("CrawlAndStore() error = %v", err)
+		}
+		return saved
+	}
+
+	t.Run("redirect", func(t *testing.T) {
+		page := crawl(t, server.URL+"/old", false, nil)
+		if page == nil || page.URL != server.URL+"/old" || page.CanonicalURL != server.URL+"/product" {
+			t.Errorf("Stored %+v", page)
+		}
+	})
+	t.Run("link", func(t *testing.T) {
+		page := crawl(t, server.URL+"/variant?color=red", false, nil)
+		if page == nil || page.CanonicalURL != server.URL+"/product" {
+			t.Errorf("Stored %+v", page)
+		}
+	})
+	t.Run("dedupe stores under canonical URL", func(t *testing.T) {
+		page := crawl(t, server.URL+"/variant?color=red", true, nil)
+		if page == nil || page.URL != server.URL+"/product" {
+			t.Errorf("Stored %+v", page)
+		}
+	})
+	t.Run("dedupe skips stored canonical URL", func(t *testing.T) {
+		if page := crawl(t, server.URL+"/variant?color=red", true, []models.Page{{ID: 3}}); page != nil {
+			t.Errorf("Stored %+v, want nothing", page)
+		}
+	})
+}
+
+func TestCrawlerService_CrawlAndStore_DedupKey(t *testing.T) {
+	const body = `<html><head><title>Item</title></head><body>v1</body></html>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	crawl := func(t *testing.T, strategy, url string, stored []models.Page) (created, updated *models.Page, query []interface{}) {
+		t.Helper()
+		mockDB := &mocks.MockDatabaseClient{
+			CreateFunc: func(value interface{}) error {
+				if page, ok := value.(*models.Page); ok {
+					created = page
+				}
+				return nil
+			},
+			UpdatesFunc: func(model interface{}, values interface{}) error {
+				updated = model.(*models.Page)
+				return nil
+			},
+			FindFunc: func(dest interface{}, conds ...interface{}) error {
+				query = conds
+				*dest.(*[]models.Page) = stored
+				return nil
+			},
+		}
+		key, err := crawlers.NewDedupKey(strategy)
+		if err != nil {
+			t.Fatalf("NewDedupKey() error = %v", err)
+		}
+		service := services.NewCrawlerService(zaptest.NewLogger(t), nil, mockDB)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		service.SetDedupKey(key)
+		if err := service.CrawlAndStore(url); err != nil {
+			t.Fatalf("CrawlAndStore() error = %v", err)
+		}
+		return created, updated, query
+	}
+
+	t.Run("normalized URL", func(t *testing.T) {
+		created, _, query := crawl(t, crawlers.DedupNormalized, server.URL+"/item?b=2&a=1#top", nil)
+		if want := server.URL + "/item?a=1&b=2"; created == nil || created.URL != want || query[2] != want {
+			t.Errorf("Stored %+v looked up with %v, want URL %s", created, query, want)
+		}
+	})
+	t.Run("unchanged content is skipped", func(t *testing.T) {
+		created, updated, _ := crawl(t, crawlers.DedupContent, server.URL+"/item", []models.Page{{ID: 4, URL: server.URL + "/item", HTML: body}})
+		if created != nil || updated != nil {
+			t.Errorf("Stored %+v / %+v, want nothing", created, updated)
+		}
+	})
+	t.Run("changed content replaces the stored page", func(t *testing.T) {
+		created, updated, _ := crawl(t, crawlers.DedupContent, server.URL+"/item", []models.Page{{ID: 4, URL: server.URL + "/item", HTML: "old"}})
+		if created != nil || updated == nil || updated.ID != 4 {
+			t.Errorf("Created %+v, updated %+v; want page 4 updated", created, updated)
+		}
+	})
+}
+
+// hookCountingCrawler counts the response hooks registered on a Colly client
+type hookCountingCrawler struct {
+	*crawlers.CollyClient
+	responseHooks int
+}
+
+func (c *hookCountingCrawler) OnResponse(handler func(*colly.Response)) {
+	c.responseHooks++
+	c.CollyClient.OnResponse(handler)
+}
+
+func TestCrawlerService_CrawlAndStore_RegistersHSTSObserverOnce(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte("<html><head><title>Page</title></head></html>"))
+	}))
+	defer server.Close()
+
+	crawler := &hookCountingCrawler{CollyClient: crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1})}
+	service := services.NewCrawlerService(zaptest.NewLogger(t), nil, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawler)
+	service.SetHSTS(crawlers.NewHSTS(crawlers.HSTSConfig{}))
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		if err := service.CrawlAndStore(server.URL + path); err != nil {
+			t.Fatalf("CrawlAndStore(%s) error = %v", path, err)
+		}
+	}
+	if crawler.responseHooks != 1 {
+		t.Errorf("Registered %d response hooks over 3 crawls, want 1", crawler.responseHooks)
+	}
+}
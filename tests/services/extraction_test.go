@@ -0,0 +1,140 @@
+package services_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+)
+
+// =============================================================================
+// Extraction Rules Tests
+// =============================================================================
+
+// extractionRules compiles rules for a product and a page site on host
+func extractionRules(t *testing.T, host string) *extractors.Registry {
+	t.Helper()
+	registry, err := extractors.Compile(extractors.RuleSet{Sites: []extractors.SiteRules{
+		{Name: "shop", Hosts: []string{host}, Paths: []string{"/p/"}, Model: extractors.ModelProduct,
+			Fields: map[string]extractors.FieldRule{
+				"name":  {Selector: "h1", Required: true},
+				"price": {Selector: ".price"},
+			}},
+		{Name: "docs", Hosts: []string{host}, Model: extractors.ModelPage,
+			Fields: map[string]extractors.FieldRule{
+				"title":   {Selector: "h1"},
+				"content": {Selector: "main", Transform: []string{"squash"}},
+			}},
+	}})
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+	return registry
+}
+
+func TestCrawlerService_Extractors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/p/1":
+			_, _ = w.Write([]byte(`<html><head><title>Shop</title></head><body><h1>Widget</h1><span class="price">$12.50</span></body></html>`))
+		case "/p/2":
+			_, _ = w.Write([]byte(`<html><head><title>Shop</title></head><body><span class="price">$1</span></body></html>`))
+		default:
+			_, _ = w.Write([]byte(`<html><head><title>Site</title></head><body><h1>Guide</h1><main> Step
+				one </main></body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	var pages []*models.Page
+	var products []*models.Product
+	db := &mocks.MockDatabaseClient{
+		CreateFunc: func(value interface{}) error {
+			switch v := value.(type) {
+			case *models.Page:
+				pages = append(pages, v)
+			case *models.Product:
+				products = append(products, v)
+			}
+			return nil
+		},
+	}
+	registry := extractionRules(t, "127.0.0.1")
+
+	crawl := func(path string) error {
+		service := services.NewCrawlerService(zaptest.NewLogger(t), nil, db)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		service.SetExtractors(registry)
+		return service.CrawlAndStore(server.URL + path)
+	}
+
+	for _, path := range []string{"/p/1", "/p/2", "/docs"} {
+		if err := crawl(path); err != nil {
+			t.Fatalf("CrawlAndStore(%s) error = %v", path, err)
+		}
+	}
+
+	if len(pages) != 3 {
+		t.Fatalf("Stored %d pages, want 3 even when extraction fails", len(pages))
+	}
+	if len(products) != 1 {
+		t.Fatalf("Stored %d products, want 1", len(products))
+	}
+	if p := products[0]; p.Name != "Widget" || p.Price != 12.5 || p.SourceURL != server.URL+"/p/1" {
+		t.Errorf("Unexpected product: %+v", p)
+	}
+	if pages[0].Title != "Shop" {
+		t.Errorf("Product page title = %q, want the <title>", pages[0].Title)
+	}
+	if pages[2].Title != "Guide" || pages[2].Content != "Step one" {
+		t.Errorf("Expected page rules to fill the stored page, got title %q content %q", pages[2].Title, pages[2].Content)
+	}
+}
+
+func TestCrawlerService_StructuredData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Post</title>
+<script type="application/ld+json">{"@type": "BlogPosting", "headline": "Hello", "author": "Ada"}</script>
+</head><body><h1>Hello</h1></body></html>`))
+	}))
+	defer server.Close()
+
+	crawl := func(enabled bool) []*models.Article {
+		var articles []*models.Article
+		db := &mocks.MockDatabaseClient{
+			CreateFunc: func(value interface{}) error {
+				if article, ok := value.(*models.Article); ok {
+					articles = append(articles, article)
+				}
+				return nil
+			},
+		}
+		service := services.NewCrawlerService(zaptest.NewLogger(t), nil, db)
+		service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+		service.SetStructuredData(enabled)
+		if err := service.CrawlAndStore(server.URL + "/post"); err != nil {
+			t.Fatalf("CrawlAndStore() error = %v", err)
+		}
+		return articles
+	}
+
+	if articles := crawl(false); len(articles) != 0 {
+		t.Fatalf("Stored %d articles without structured data, want 0", len(articles))
+	}
+
+	articles := crawl(true)
+	if len(articles) != 1 {
+		t.Fatalf("Stored %d articles, want 1", len(articles))
+	}
+	if article := articles[0]; article.Title != "Hello" || article.Author != "Ada" || article.SourceURL != server.URL+"/post" {
+		t.Errorf("Unexpected article: %+v", article)
+	}
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Feed Discovery Tests
+// =============================================================================
+
+// recordingFeedRegistry remembers registered feeds
+type recordingFeedRegistry struct {
+	project, source string
+	feeds           []extractors.FeedLink
+}
+
+func (r *recordingFeedRegistry) RegisterFeeds(_ context.Context, project, sourceURL string, feeds []extractors.FeedLink) (int, error) {
+	r.project, r.source = project, sourceURL
+	r.feeds = append(r.feeds, feeds...)
+	return len(feeds), nil
+}
+
+func TestCrawlerService_FeedDiscovery(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		_, _ = w.Write([]byte(`<html><head><title>Blog</title>
+<link rel="alternate" type="application/atom+xml" title="Blog" href="/atom.xml">
+</head><body></body></html>`))
+	}))
+	defer server.Close()
+
+	registry := &recordingFeedRegistry{}
+	service := services.NewCrawlerService(zaptest.NewLogger(t), nil, &mocks.MockDatabaseClient{})
+	service.SetCrawler(crawlers.NewCollyClient(crawlers.CollyConfig{MaxDepth: 1}))
+	service.SetProject("blogs", false)
+	service.SetFeedRegistry(registry)
+	if err := service.CrawlAndStore(server.URL + "/"); err != nil {
+		t.Fatalf("CrawlAndStore() error = %v", err)
+	}
+
+	if len(registry.feeds) != 1 || registry.feeds[0].URL != server.URL+"/atom.xml" || registry.feeds[0].Type != extractors.FeedAtom {
+		t.Fatalf("Registered feeds = %+v", registry.feeds)
+	}
+	if registry.project != "blogs" || registry.source != server.URL+"/" {
+		t.Errorf("Registered for project %q from %q", registry.project, registry.source)
+	}
+}
+
+func TestDBFeedRegistry_RegisterFeeds(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `feeds` .* ON DUPLICATE KEY UPDATE `id`=`id`").
+		WillReturnResult(sqlmock.NewResult(7, 1)) // One of the two was registered before
+	mock.ExpectCommit()
+
+	registry := services.NewDBFeedRegistry(&mocks.MockDatabaseClient{DB: gormDB})
+	added, err := registry.RegisterFeeds(context.Background(), "blogs", "https://blog.example/", []extractors.FeedLink{
+		{URL: "https://blog.example/rss", Type: extractors.FeedRSS},
+		{URL: "https://blog.example/atom", Type: extractors.FeedAtom},
+	})
+	if err != nil {
+		t.Fatalf("RegisterFeeds() error = %v", err)
+	}
+	if added != 1 {
+		t.Errorf("RegisterFeeds() added = %d, want 1", added)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+
+	if added, err := registry.RegisterFeeds(context.Background(), "blogs", "https://blog.example/", nil); added != 0 || err != nil {
+		t.Errorf("RegisterFeeds(nil) = %d, %v", added, err)
+	}
+}
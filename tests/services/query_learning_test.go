@@ -0,0 +1,143 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/crawlers"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Query Parameter Learning Tests
+// =============================================================================
+
+// shopFetch serves pages whose content depends only on the id and page
+// parameters; /live changes on every request and /down always fails
+func shopFetch(calls *int) func(ctx context.Context, rawURL string) ([]byte, error) {
+	return func(_ context.Context, rawURL string) ([]byte, error) {
+		*calls++
+		u, _ := url.Parse(rawURL)
+		switch u.Path {
+		case "/live":
+			return []byte(fmt.Sprintf("<p>visitor %d</p>", *calls)), nil
+		case "/down":
+			return nil, errors.New("connection refused")
+		}
+		q := u.Query()
+		return []byte(fmt.Sprintf("<html>  <p>item %s page %s</p></html>", q.Get("id"), q.Get("page"))), nil
+	}
+}
+
+func TestQueryLearningService_Learn(t *testing.T) {
+	canonical, err := crawlers.NewCanonicalizer(crawlers.CanonicalConfig{})
+	if err != nil {
+		t.Fatalf("NewCanonicalizer() error = %v", err)
+	}
+	calls := 0
+	learner := services.NewQueryLearningService(zaptest.NewLogger(t), nil, services.QueryLearningConfig{
+		SamplesPerParam: 3,
+		MinSamples:      2,
+		Fetch:           shopFetch(&calls),
+		Canonical:       canonical,
+	})
+
+	reports, err := learner.Learn(context.Background(), []string{
+		"https://shop.example/p?id=1&utm_source=mail&sid=a1",
+		"https://shop.example/p?id=2&utm_source=ads&page=2",
+		"https://shop.example/p?id=3&sid=b2&page=1",
+		"https://shop.example/live?ref=home",
+		"https://shop.example/live?ref=nav",
+		"https://shop.example/down?ref=x",
+		"https://blog.example/post?utm_campaign=x",
+		"https://blog.example/post?utm_campaign=y",
+		"https://blog.example/plain",
+	})
+	if err != nil {
+		t.Fatalf("Learn() error = %v", err)
+	}
+
+	want := []services.QueryParamReport{
+		{Host: "blog.example", Ignored: []string{"utm_campaign"}},
+		{
+			Host:        "shop.example",
+			Significant: []string{"id", "page"},
+			Ignored:     []string{"sid", "utm_source"},
+			Undecided:   []string{"ref"}, // Only unstable or failing pages
+		},
+	}
+	if !reflect.DeepEqual(reports, want) {
+		t.Fatalf("Learn() = %+v\nwant %+v", reports, want)
+	}
+
+	if got := canonical.Canonicalize("https://shop.example/p?sid=zz&utm_source=x&page=4&id=9&ref=a"); got != "https://shop.example/p?id=9&page=4&ref=a" {
+		t.Errorf("Canonicalize() = %q, want learned parameters only", got)
+	}
+	if got := canonical.Canonicalize("https://blog.example/post?utm_campaign=z"); got != "https://blog.example/post" {
+		t.Errorf("Canonicalize() = %q, want the campaign dropped", got)
+	}
+	if got := canonical.Canonicalize("https://other.example/?utm_campaign=z"); got != "https://other.example/?utm_campaign=z" {
+		t.Errorf("Canonicalize() = %q, want hosts without rules untouched", got)
+	}
+}
+
+func TestQueryLearningService_Cancelled(t *testing.T) {
+	calls := 0
+	learner := services.NewQueryLearningService(zaptest.NewLogger(t), nil, services.QueryLearningConfig{Fetch: shopFetch(&calls)})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := learner.Learn(ctx, []string{"https://shop.example/p?id=1"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Learn() error = %v, want context.Canceled", err)
+	}
+	if calls != 0 {
+		t.Errorf("Fetched %d URLs after cancellation", calls)
+	}
+}
+
+func TestQueryLearningService_Run(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	mock.ExpectQuery("SELECT `url` FROM `pages` WHERE \\(project = \\? AND url LIKE \\?\\) AND `pages`.`deleted_at` IS NULL ORDER BY id DESC LIMIT").
+		WithArgs("shop", "%?%", 10).
+		WillReturnRows(sqlmock.NewRows([]string{"url"}).
+			AddRow("https://shop.example/p?id=1&sid=a").
+			AddRow("https://shop.example/p?id=2&sid=b"))
+
+	calls := 0
+	learner := services.NewQueryLearningService(zaptest.NewLogger(t), &mocks.MockDatabaseClient{DB: gormDB}, services.QueryLearningConfig{
+		MaxURLs: 10,
+		Fetch:   shopFetch(&calls),
+	})
+	reports, err := learner.Run(context.Background(), "shop")
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(reports) != 1 || !reflect.DeepEqual(reports[0].Whitelist(), []string{"id"}) {
+		t.Errorf("Run() = %+v, want id whitelisted", reports)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("Unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package services_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Security Report Tests
+// =============================================================================
+
+// securityReportService returns a service over sqlmock rows of security
+// audits; args are the expected query arguments
+func securityReportService(t *testing.T, query string, args []driver.Value, rows [][]driver.Value) *services.SecurityReportService {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	result := sqlmock.NewRows([]string{"url", "domain", "csp", "hsts", "x_frame_options", "x_content_type_options", "referrer_policy", "permissions_policy", "score", "issues"})
+	for _, row := range rows {
+		result.AddRow(row...)
+	}
+	mock.ExpectQuery(query).WithArgs(args...).WillReturnRows(result)
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet expectations: %v", err)
+		}
+	})
+
+	return services.NewSecurityReportService(zaptest.NewLogger(t), &mocks.MockDatabaseClient{DB: gormDB})
+}
+
+const securityAuditSelect = "SELECT `url`,`domain`,`csp`,`hsts`,`x_frame_options`,`x_content_type_options`,`referrer_policy`,`permissions_policy`,`score`,`issues` FROM `security_audits` WHERE project = \\?"
+
+func TestSecurityReportService_Report(t *testing.T) {
+	s := securityReportService(t, securityAuditSelect+" ORDER BY id", []driver.Value{"estate"}, [][]driver.Value{
+		{"https://a.example/", "a.example", "", "", "", "", "", "", 0, "missing-csp,missing-hsts"},
+		{"https://a.example/", "a.example", "default-src 'self'", "max-age=31536000", "DENY", "nosniff", "no-referrer", "camera=()", 100, ""},
+		{"https://a.example/login", "a.example", "", "max-age=31536000", "", "nosniff", "", "", 35, "missing-csp,missing-frame-options"},
+		{"https://b.example/", "b.example", "", "", "", "", "", "", 0, "missing-csp,missing-hsts"},
+	})
+
+	reports, err := s.Report(context.Background(), "estate", "")
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(reports) != 2 || reports[0].Domain != "b.example" || reports[1].Domain != "a.example" {
+		t.Fatalf("Report() = %+v, want b.example then a.example", reports)
+	}
+
+	a := reports[1]
+	if a.Pages != 2 || a.AverageScore != 67.5 || a.MinScore != 35 || a.Grade != "D" {
+		t.Errorf("a.example = %+v", a)
+	}
+	if want := map[string]int{"Content-Security-Policy": 1, "Strict-Transport-Security": 2, "X-Frame-Options": 1, "X-Content-Type-Options": 2, "Referrer-Policy": 1, "Permissions-Policy": 1}; !reflect.DeepEqual(a.Coverage, want) {
+		t.Errorf("Coverage = %v, want %v", a.Coverage, want)
+	}
+	if want := map[string]int{"missing-csp": 1, "missing-frame-options": 1}; !reflect.DeepEqual(a.Issues, want) {
+		t.Errorf("Issues = %v, want %v", a.Issues, want)
+	}
+	want := []services.PageSecurity{
+		{URL: "https://a.example/login", Score: 35, Issues: []string{"missing-csp", "missing-frame-options"}},
+		{URL: "https://a.example/", Score: 100},
+	}
+	if !reflect.DeepEqual(a.WorstPages, want) {
+		t.Errorf("WorstPages = %+v, want %+v", a.WorstPages, want)
+	}
+	if reports[0].Grade != "F" {
+		t.Errorf("b.example grade = %s, want F", reports[0].Grade)
+	}
+}
+
+func TestSecurityReportService_ReportDomain(t *testing.T) {
+	s := securityReportService(t, securityAuditSelect+" AND domain = \\? ORDER BY id", []driver.Value{"", "a.example"}, nil)
+
+	reports, err := s.Report(context.Background(), "", "A.example")
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(reports) != 0 {
+		t.Errorf("Report() = %+v, want none", reports)
+	}
+}
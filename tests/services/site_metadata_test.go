@@ -0,0 +1,86 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/extractors"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+)
+
+// =============================================================================
+// Site Metadata Tests
+// =============================================================================
+
+func TestSiteMetadataService_Record(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/manifest+json")
+		_, _ = w.Write([]byte(`{"name": "Example Shop", "short_name": "Shop", "background_color": "#ffffff",
+			"icons": [{"src": "/icon-512.png", "sizes": "512x512"}]}`))
+	}))
+	defer server.Close()
+
+	db, mock := certificateDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `domains` .* ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	fake := clock.NewFake(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC))
+	service := services.NewSiteMetadataService(zaptest.NewLogger(t), db, services.SiteMetadataConfig{Clock: fake})
+	meta := extractors.SiteMetadata{
+		SiteName:    "Shop",
+		FaviconURL:  server.URL + "/favicon.ico",
+		ManifestURL: server.URL + "/site.webmanifest",
+		ThemeColor:  "#112233",
+	}
+
+	domain, err := service.Record(context.Background(), server.URL+"/p/1", meta)
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if domain.Host != strings.TrimPrefix(server.URL, "http://") || domain.SiteName != "Example Shop" || domain.ShortName != "Shop" {
+		t.Errorf("Record() = %+v", domain)
+	}
+	if domain.IconURL != server.URL+"/icon-512.png" || domain.FaviconURL != meta.FaviconURL {
+		t.Errorf("Icons = %q, %q", domain.IconURL, domain.FaviconURL)
+	}
+	if domain.ThemeColor != "#112233" || domain.BackgroundColor != "#ffffff" || !domain.CheckedAt.Equal(fake.Now()) {
+		t.Errorf("Record() = %+v", domain)
+	}
+
+	// The host was just updated
+	if domain, err := service.Record(context.Background(), server.URL+"/p/2", meta); domain != nil || err != nil {
+		t.Errorf("Second Record() = %+v, %v", domain, err)
+	}
+}
+
+func TestSiteMetadataService_RecordWithoutManifest(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	db, mock := certificateDB(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `domains`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	service := services.NewSiteMetadataService(zaptest.NewLogger(t), db, services.SiteMetadataConfig{})
+	domain, err := service.Record(context.Background(), server.URL+"/", extractors.SiteMetadata{
+		SiteName:    "Shop",
+		FaviconURL:  server.URL + "/favicon.ico",
+		ManifestURL: server.URL + "/missing.webmanifest",
+	})
+	if err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if domain.SiteName != "Shop" || domain.IconURL != server.URL+"/favicon.ico" {
+		t.Errorf("Record() = %+v", domain)
+	}
+}
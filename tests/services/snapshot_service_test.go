@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/alonecandies/golwarc/errs"
+	"github.com/alonecandies/golwarc/mocks"
+	"github.com/alonecandies/golwarc/services"
+	"go.uber.org/zap/zaptest"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// =============================================================================
+// Snapshot Comparison Tests
+// =============================================================================
+
+// snapshotService returns a service over sqlmock rows of crawl logs
+func snapshotService(t *testing.T, rows [][]driver.Value) *services.SnapshotService {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create sqlmock: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      db,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create gorm DB: %v", err)
+	}
+
+	result := sqlmock.NewRows([]string{"crawl_id", "url", "domain", "status", "error", "content_hash", "skip_reason"})
+	for _, row := range rows {
+		result.AddRow(row...)
+	}
+	mock.ExpectQuery("SELECT `crawl_id`,`url`,`domain`,`status`,`error`,`content_hash`,`skip_reason` FROM `crawl_logs` WHERE crawl_id IN \\(\\?,\\?\\) ORDER BY created_at, id").
+		WithArgs("before", "after").
+		WillReturnRows(result)
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("Unmet expectations: %v", err)
+		}
+	})
+
+	return services.NewSnapshotService(zaptest.NewLogger(t), &mocks.MockDatabaseClient{DB: gormDB})
+}
+
+func TestSnapshotService_Compare(t *testing.T) {
+	s := snapshotService(t, [][]driver.Value{
+		{"before", "https://shop.example/", "shop.example", 200, "", "h1", ""},
+		{"before", "https://shop.example/old", "shop.example", 200, "", "h2", ""},
+		{"before", "https://shop.example/p/1", "shop.example", 200, "", "h3", ""},
+		{"before", "https://shop.example/p/2", "shop.example", 200, "", "h4", ""},
+		{"before", "https://shop.example/p/2", "shop.example", 200, "", "h5", ""}, // Refetched; the last fetch wins
+		{"before", "https://cdn.example/app.js", "cdn.example", 200, "", "h6", ""},
+		{"after", "https://shop.example/", "shop.example", 200, "", "h1", ""},
+		{"after", "https://shop.example/new", "shop.example", 200, "", "h7", ""},
+		{"after", "https://shop.example/p/1", "", 0, "connection reset", "", ""}, // Failures carry no domain
+		{"after", "https://shop.example/p/2", "shop.example", 200, "", "h8", ""},
+		{"after", "https://blog.example/", "blog.example", 200, "", "h9", ""},
+		{"after", "https://shop.example/admin", "shop.example", 0, "", "", "url_rule"}, // Skipped, not fetched
+	})
+
+	diff, err := s.Compare(context.Background(), "before", "after", "")
+	if err != nil {
+		t.Fatalf("Compare() error = %v", err)
+	}
+
+	want := &services.SnapshotDiff{
+		Base:          "before",
+		Target:        "after",
+		Domains:       []string{"shop.example"},
+		Added:         []services.URLDiff{{URL: "https://shop.example/new", TargetStatus: 200}},
+		Removed:       []services.URLDiff{{URL: "https://shop.example/old", BaseStatus: 200}},
+		Changed:       []services.URLDiff{{URL: "https://shop.example/p/2", BaseStatus: 200, TargetStatus: 200}},
+		StatusChanged: []services.URLDiff{{URL: "https://shop.example/p/1", BaseStatus: 200, TargetError: "connection reset"}},
+		Unchanged:     1,
+	}
+	if !reflect.DeepEqual(diff, want) {
+		t.Errorf("Compare() = %+v\nwant %+v", diff, want)
+	}
+}
+
+func TestSnapshotService_CompareDomain(t *testing.T) {
+	s := snapshotService(t, [][]driver.Value{
+		{"before", "https://shop.example/", "shop.example", 200, "", "h1", ""},
+		{"after", "https://shop.example/", "shop.example", 200, "", "h1", ""},
+		{"before", "https://blog.example/", "blog.example", 200, "", "h2", ""},
+	})
+
+	if _, err := s.Compare(context.Background(), "before", "after", "blog.example"); !errs.HasCode(err, errs.CodeNotFound) {
+		t.Errorf("Compare() error = %v, want %s for a domain the target never fetched", err, errs.CodeNotFound)
+	}
+}
+
+func TestSnapshotService_CompareNoCommonDomain(t *testing.T) {
+	s := snapshotService(t, [][]driver.Value{
+		{"before", "https://shop.example/", "shop.example", 200, "", "h1", ""},
+		{"after", "https://blog.example/", "blog.example", 200, "", "h2", ""},
+	})
+
+	if _, err := s.Compare(context.Background(), "before", "after", ""); !errs.HasCode(err, errs.CodeInvalidRequest) {
+		t.Errorf("Compare() error = %v, want %s", err, errs.CodeInvalidRequest)
+	}
+}
+
+func TestSnapshotService_CompareValidation(t *testing.T) {
+	s := services.NewSnapshotService(zaptest.NewLogger(t), nil)
+	for _, ids := range [][2]string{{"", "after"}, {"same", "same"}} {
+		if _, err := s.Compare(context.Background(), ids[0], ids[1], ""); !errs.HasCode(err, errs.CodeInvalidRequest) {
+			t.Errorf("Compare(%q, %q) error = %v, want %s", ids[0], ids[1], err, errs.CodeInvalidRequest)
+		}
+	}
+}
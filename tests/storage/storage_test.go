@@ -0,0 +1,449 @@
+package storage_test
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/models"
+	"github.com/alonecandies/golwarc/storage"
+	"github.com/alonecandies/golwarc/warc"
+)
+
+// =============================================================================
+// Codec Tests
+// =============================================================================
+
+func roundTrip(t *testing.T, codec storage.BodyCodec, body []byte) *models.Page {
+	t.Helper()
+
+	page := &models.Page{URL: "https://example.com"}
+	if err := codec.Encode(page, body); err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	if page.BodyCodec != codec.Name() {
+		t.Errorf("BodyCodec = %q, want %q", page.BodyCodec, codec.Name())
+	}
+	if page.BodySize != int64(len(body)) {
+		t.Errorf("BodySize = %d, want %d", page.BodySize, len(body))
+	}
+
+	got, err := codec.Decode(page)
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Errorf("Decode() returned %d bytes, want %d", len(got), len(body))
+	}
+	return page
+}
+
+func TestInlineCodec(t *testing.T) {
+	page := roundTrip(t, storage.InlineCodec{}, []byte("<html>inline</html>"))
+	if page.HTML != "<html>inline</html>" {
+		t.Error("Expected inline body to stay in HTML")
+	}
+}
+
+func TestGzipCodec(t *testing.T) {
+	body := []byte(strings.Repeat("<p>compressible</p>", 1000))
+	page := roundTrip(t, storage.GzipCodec{}, body)
+
+	if page.HTML != "" {
+		t.Error("Expected HTML to be cleared")
+	}
+	if len(page.BodyData) >= len(body) {
+		t.Errorf("Expected compressed data smaller than %d bytes, got %d", len(body), len(page.BodyData))
+	}
+}
+
+func TestObjectCodec(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	page := roundTrip(t, storage.NewObjectCodec(store), []byte("<html>object</html>"))
+
+	exists, err := store.Exists(page.BodyRef)
+	if err != nil || !exists {
+		t.Errorf("Expected object %q to exist (err = %v)", page.BodyRef, err)
+	}
+}
+
+func TestWARCCodec(t *testing.T) {
+	writer, err := warc.NewWriter(filepath.Join(t.TempDir(), "bodies.warc.gz"))
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	codec := storage.NewWARCCodec(writer)
+	defer func() { _ = codec.Close() }()
+
+	roundTrip(t, codec, []byte("<html>first</html>"))
+	page := roundTrip(t, codec, []byte("<html>second</html>"))
+
+	path, offset, err := storage.ParseWARCRef(page.BodyRef)
+	if err != nil {
+		t.Fatalf("ParseWARCRef() error = %v", err)
+	}
+	if path != writer.Path() || offset == 0 {
+		t.Errorf("Unexpected reference %q", page.BodyRef)
+	}
+}
+
+func TestParseWARCRef_Invalid(t *testing.T) {
+	for _, ref := range []string{"", "no-offset", "#12", "file.warc.gz#abc", "file.warc.gz#-1"} {
+		if _, _, err := storage.ParseWARCRef(ref); err == nil {
+			t.Errorf("ParseWARCRef(%q) expected error", ref)
+		}
+	}
+}
+
+// =============================================================================
+// FileObjectStore Tests
+// =============================================================================
+
+func TestFileObjectStore(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	if err := store.Put("a/b", []byte("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	got, err := store.Get("a/b")
+	if err != nil || string(got) != "data" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+	if err := store.Delete("a/b"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if exists, _ := store.Exists("a/b"); exists {
+		t.Error("Expected object to be deleted")
+	}
+}
+
+func TestFileObjectStore_ConcurrentPut(t *testing.T) {
+	root := t.TempDir()
+	store, err := storage.NewFileObjectStore(root)
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	// Writers of one key must not share a temp file, or a rename fails or
+	// publishes a mix of two payloads
+	payloads := make(map[string]bool)
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := range 20 {
+		data := strings.Repeat(string(rune('a'+i)), 64<<10)
+		payloads[data] = true
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- store.Put("shared/key", []byte(data))
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Put() error = %v", err)
+		}
+	}
+
+	got, err := store.Get("shared/key")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if !payloads[string(got)] {
+		t.Errorf("Get() returned a mixed payload of %d bytes", len(got))
+	}
+	leftovers, _ := filepath.Glob(filepath.Join(root, "shared", "*.tmp"))
+	if len(leftovers) != 0 {
+		t.Errorf("temp files left behind: %v", leftovers)
+	}
+}
+
+func TestFileObjectStore_RejectsTraversal(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+	if err := store.Put("../escape", []byte("x")); err == nil {
+		t.Error("Expected error for key escaping the store root")
+	}
+}
+
+// =============================================================================
+// BodyStore Tests
+// =============================================================================
+
+func TestBodyStore_SelectsCodecBySize(t *testing.T) {
+	objects, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+
+	store := storage.NewBodyStore(storage.BodyStoreConfig{
+		InlineMaxSize:     10,
+		CompressedMaxSize: 100,
+		Large:             storage.NewObjectCodec(objects),
+	})
+
+	tests := []struct {
+		size int
+		want string
+	}{
+		{5, storage.CodecInline},
+		{50, storage.CodecGzip},
+		{500, storage.CodecObject},
+	}
+
+	for _, tt := range tests {
+		body := []byte(strings.Repeat("x", tt.size))
+		page := &models.Page{URL: "https://example.com"}
+		if err := store.Save(page, body); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		if page.BodyCodec != tt.want {
+			t.Errorf("size %d: BodyCodec = %q, want %q", tt.size, page.BodyCodec, tt.want)
+		}
+
+		got, err := store.Load(page)
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		if !bytes.Equal(got, body) {
+			t.Errorf("size %d: Load() mismatch", tt.size)
+		}
+	}
+}
+
+func TestBodyStore_LegacyPage(t *testing.T) {
+	store := storage.NewBodyStore(storage.BodyStoreConfig{})
+
+	got, err := store.Load(&models.Page{HTML: "<html>legacy</html>"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if string(got) != "<html>legacy</html>" {
+		t.Errorf("Load() = %q", got)
+	}
+}
+
+func TestBodyStore_UnknownCodec(t *testing.T) {
+	store := storage.NewBodyStore(storage.BodyStoreConfig{})
+
+	if _, err := store.Load(&models.Page{BodyCodec: "bogus"}); err == nil {
+		t.Error("Expected error for unknown codec")
+	}
+}
+
+// =============================================================================
+// Storage Class Tests
+// =============================================================================
+
+func TestFileObjectStore_StorageClass(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+	if err := store.Put("pages/a", []byte("data")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	if err := store.SetStorageClass("pages/a", storage.StorageClassCold); err != nil {
+		t.Fatalf("SetStorageClass() error = %v", err)
+	}
+
+	objects, err := store.List("pages/")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(objects) != 1 || objects[0].StorageClass != storage.StorageClassCold {
+		t.Fatalf("List() = %+v, want one cold object", objects)
+	}
+
+	// Cold objects remain readable
+	got, err := store.Get("pages/a")
+	if err != nil || string(got) != "data" {
+		t.Errorf("Get() = %q, %v", got, err)
+	}
+
+	if err := store.SetStorageClass("pages/a", "glacier"); err == nil {
+		t.Error("Expected error for unknown storage class")
+	}
+	if err := store.Put(".cold/x", []byte("x")); err == nil {
+		t.Error("Expected reserved cold prefix to be rejected")
+	}
+}
+
+// =============================================================================
+// Lifecycle Tests
+// =============================================================================
+
+func writeWARC(t *testing.T, path string, bodies ...string) {
+	t.Helper()
+
+	w, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	for _, body := range bodies {
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+body)
+		rec.Content = []byte(body)
+		if _, _, err := w.WriteRecord(rec); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+	}
+}
+
+func TestLifecycle_CompactsSmallFiles(t *testing.T) {
+	dir := t.TempDir()
+	active := filepath.Join(dir, "active.warc.gz")
+	writeWARC(t, filepath.Join(dir, "a.warc.gz"), "a")
+	writeWARC(t, filepath.Join(dir, "b.warc.gz"), "b")
+	writeWARC(t, active, "live")
+
+	var relocated []warc.Relocation
+	lifecycle := storage.NewLifecycle(storage.LifecycleConfig{
+		WARCDir:     dir,
+		ActiveFiles: []string{active},
+		OnRelocate: func(r []warc.Relocation) error {
+			relocated = r
+			return nil
+		},
+	})
+
+	report, err := lifecycle.RunOnce()
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if len(report.Compacted) != 2 || len(relocated) != 2 {
+		t.Fatalf("Expected 2 files and 2 records compacted, got %+v", report)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	if len(files) != 2 {
+		t.Errorf("Expected active and compacted files to remain, got %v", files)
+	}
+	if len(report.Reindexed) != 1 {
+		t.Errorf("Expected the active file to be reindexed, got %v", report.Reindexed)
+	}
+}
+
+func TestLifecycle_SkipsWriterFile(t *testing.T) {
+	dir := t.TempDir()
+	w, err := warc.NewWriter(filepath.Join(dir, "pages.warc.gz"))
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer w.Close()
+	w.SetRotation(warc.RotationConfig{MaxSize: 1})
+
+	for _, body := range []string{"a", "b", "c"} {
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+body)
+		rec.Content = []byte(body)
+		if _, _, err := w.AppendRecord(rec); err != nil {
+			t.Fatalf("AppendRecord() error = %v", err)
+		}
+	}
+	current := w.Path()
+
+	lifecycle := storage.NewLifecycle(storage.LifecycleConfig{
+		WARCDir: dir,
+		Writers: []*warc.Writer{w},
+		Now:     func() time.Time { return time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) },
+	})
+	report, err := lifecycle.RunOnce()
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if len(report.Compacted) != 2 {
+		t.Fatalf("Expected the 2 rotated files to be compacted, got %v", report.Compacted)
+	}
+	for _, path := range report.Compacted {
+		if path == current {
+			t.Errorf("Compacted the file being written: %s", path)
+		}
+	}
+}
+
+func TestLifecycle_RelocateFailureKeepsSources(t *testing.T) {
+	dir := t.TempDir()
+	writeWARC(t, filepath.Join(dir, "a.warc.gz"), "a")
+	writeWARC(t, filepath.Join(dir, "b.warc.gz"), "b")
+
+	lifecycle := storage.NewLifecycle(storage.LifecycleConfig{
+		WARCDir: dir,
+		OnRelocate: func([]warc.Relocation) error {
+			return errors.New("database unavailable")
+		},
+	})
+
+	if _, err := lifecycle.RunOnce(); err == nil {
+		t.Fatal("Expected error when references cannot be updated")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.warc.gz"))
+	if len(files) != 2 {
+		t.Errorf("Expected source files to remain, got %v", files)
+	}
+}
+
+func TestLifecycle_TransitionsOldObjects(t *testing.T) {
+	store, err := storage.NewFileObjectStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileObjectStore() error = %v", err)
+	}
+	if err := store.Put("pages/old", []byte("old")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	lifecycle := storage.NewLifecycle(storage.LifecycleConfig{
+		Objects:   store,
+		ColdAfter: time.Hour,
+		Now:       func() time.Time { return time.Now().Add(2 * time.Hour) },
+	})
+
+	report, err := lifecycle.RunOnce()
+	if err != nil {
+		t.Fatalf("RunOnce() error = %v", err)
+	}
+	if len(report.Transitioned) != 1 || report.Transitioned[0] != "pages/old" {
+		t.Errorf("Transitioned = %v, want [pages/old]", report.Transitioned)
+	}
+}
+
+func TestDedupWARCCodec_ReferencesOriginal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bodies.warc.gz")
+	writer, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	codec := storage.NewDedupWARCCodec(writer, nil)
+	defer func() { _ = codec.Close() }()
+
+	first := roundTrip(t, codec, []byte("<html>same</html>"))
+	second := roundTrip(t, codec, []byte("<html>same</html>"))
+
+	if first.BodyRef != second.BodyRef {
+		t.Errorf("Expected repeated payload to reference the original, got %q and %q", first.BodyRef, second.BodyRef)
+	}
+
+	records, err := warc.ReadAll(path)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(records) != 2 || records[1].Type() != warc.TypeRevisit {
+		t.Errorf("Expected a resource followed by a revisit record")
+	}
+}
@@ -0,0 +1,645 @@
+package warc_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+	"github.com/alonecandies/golwarc/warc"
+)
+
+// =============================================================================
+// Record Tests
+// =============================================================================
+
+func TestHeader_CaseInsensitive(t *testing.T) {
+	var h warc.Header
+	h.Set("WARC-Type", "response")
+
+	if got := h.Get("warc-type"); got != "response" {
+		t.Errorf("Get() = %q, want %q", got, "response")
+	}
+
+	h.Set("warc-type", "resource")
+	if names := h.Names(); len(names) != 1 {
+		t.Errorf("Expected replacing a header to keep a single name, got %v", names)
+	}
+}
+
+func TestNewRecord(t *testing.T) {
+	rec := warc.NewRecord(warc.TypeResource, "https://example.com")
+
+	if rec.Type() != warc.TypeResource {
+		t.Errorf("Type() = %q, want %q", rec.Type(), warc.TypeResource)
+	}
+	if rec.TargetURI() != "https://example.com" {
+		t.Errorf("TargetURI() = %q", rec.TargetURI())
+	}
+	if rec.Header.Get(warc.HeaderRecordID) == "" {
+		t.Error("Expected a record ID")
+	}
+	if _, err := rec.Date(); err != nil {
+		t.Errorf("Date() error = %v", err)
+	}
+}
+
+func TestNewRecordID_Unique(t *testing.T) {
+	if warc.NewRecordID() == warc.NewRecordID() {
+		t.Error("Expected unique record IDs")
+	}
+}
+
+// =============================================================================
+// Writer/Reader Tests
+// =============================================================================
+
+func writeRecords(t *testing.T, path string, bodies ...string) []int64 {
+	t.Helper()
+
+	w, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer func() { _ = w.Close() }()
+
+	var offsets []int64
+	for _, body := range bodies {
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+body)
+		rec.Content = []byte(body)
+		offset, length, err := w.WriteRecord(rec)
+		if err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+		if length <= 0 {
+			t.Errorf("Expected positive record length, got %d", length)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	writeRecords(t, path, "first", "second", "third")
+
+	records, err := warc.ReadAll(path)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		if string(records[i].Content) != want {
+			t.Errorf("record %d content = %q, want %q", i, records[i].Content, want)
+		}
+	}
+}
+
+func TestReadRecordAt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	offsets := writeRecords(t, path, "alpha", "beta", "gamma")
+
+	rec, err := warc.ReadRecordAt(path, offsets[1])
+	if err != nil {
+		t.Fatalf("ReadRecordAt() error = %v", err)
+	}
+	if string(rec.Content) != "beta" {
+		t.Errorf("Content = %q, want %q", rec.Content, "beta")
+	}
+}
+
+func TestWriter_AppendsToExistingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	writeRecords(t, path, "one")
+	offsets := writeRecords(t, path, "two")
+
+	if offsets[0] == 0 {
+		t.Error("Expected second writer to start at the end of the existing file")
+	}
+
+	rec, err := warc.ReadRecordAt(path, offsets[0])
+	if err != nil {
+		t.Fatalf("ReadRecordAt() error = %v", err)
+	}
+	if string(rec.Content) != "two" {
+		t.Errorf("Content = %q, want %q", rec.Content, "two")
+	}
+}
+
+func TestReader_Offsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	offsets := writeRecords(t, path, "a", "b")
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	reader := warc.NewReader(bytes.NewReader(data))
+	var total int64
+	for i := 0; ; i++ {
+		_, offset, length, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error = %v", err)
+		}
+		if offset != offsets[i] {
+			t.Errorf("record %d offset = %d, want %d", i, offset, offsets[i])
+		}
+		total += length
+	}
+	if total != int64(len(data)) {
+		t.Errorf("Sum of record lengths = %d, want file size %d", total, len(data))
+	}
+}
+
+func TestWriter_RotatesBySize(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "pages.warc.gz")
+
+	w, err := warc.NewWriter(first)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer w.Close()
+	if err := w.EnableCDX(); err != nil {
+		t.Fatalf("EnableCDX() error = %v", err)
+	}
+	w.SetRotation(warc.RotationConfig{MaxSize: 1})
+
+	paths := make(map[string]int64)
+	for _, body := range []string{"a", "b", "c"} {
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+body)
+		rec.Content = []byte(body)
+		path, offset, err := w.AppendRecord(rec)
+		if err != nil {
+			t.Fatalf("AppendRecord() error = %v", err)
+		}
+		if offset != 0 {
+			t.Errorf("Offset of %q = %d, want 0 in a new file", body, offset)
+		}
+		paths[path] = offset
+
+		got, err := warc.ReadRecordAt(path, offset)
+		if err != nil || string(got.Content) != body {
+			t.Errorf("ReadRecordAt(%s) = %v, %v; want %q", path, got, err, body)
+		}
+		if _, err := os.Stat(warc.CDXPath(path)); err != nil {
+			t.Errorf("Expected CDX index of %s: %v", path, err)
+		}
+	}
+
+	if len(paths) != 3 {
+		t.Fatalf("Expected 3 files, got %v", paths)
+	}
+	if _, ok := paths[first]; !ok {
+		t.Errorf("Expected the first record in %s, got %v", first, paths)
+	}
+	if w.Path() == first {
+		t.Errorf("Path() = %s, want the rotated file", w.Path())
+	}
+}
+
+func TestWriter_RotatesByAge(t *testing.T) {
+	fake := clock.NewFake(time.Date(2026, 10, 17, 12, 0, 0, 0, time.UTC))
+	first := filepath.Join(t.TempDir(), "pages.warc.gz")
+
+	w, err := warc.NewWriter(first)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	defer w.Close()
+	w.SetRotation(warc.RotationConfig{MaxAge: time.Hour, Clock: fake})
+
+	write := func(body string) string {
+		t.Helper()
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/"+body)
+		rec.Content = []byte(body)
+		path, _, err := w.AppendRecord(rec)
+		if err != nil {
+			t.Fatalf("AppendRecord() error = %v", err)
+		}
+		return path
+	}
+
+	if path := write("a"); path != first {
+		t.Errorf("First record written to %s, want %s", path, first)
+	}
+	fake.Advance(59 * time.Minute)
+	if path := write("b"); path != first {
+		t.Errorf("Record before MaxAge written to %s, want %s", path, first)
+	}
+
+	fake.Advance(time.Minute)
+	want := filepath.Join(filepath.Dir(first), "pages-20261017130000.warc.gz")
+	if path := write("c"); path != want {
+		t.Errorf("Record after MaxAge written to %s, want %s", path, want)
+	}
+}
+
+// =============================================================================
+// Index Tests
+// =============================================================================
+
+func TestRebuildIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	offsets := writeRecords(t, path, "one", "two")
+
+	entries, err := warc.RebuildIndex(path)
+	if err != nil {
+		t.Fatalf("RebuildIndex() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if entries[1].Offset != offsets[1] || entries[1].URI != "https://example.com/two" {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+
+	read, err := warc.ReadIndex(warc.IndexPath(path))
+	if err != nil {
+		t.Fatalf("ReadIndex() error = %v", err)
+	}
+	if len(read) != 2 || read[0].RecordID != entries[0].RecordID {
+		t.Errorf("ReadIndex() = %+v, want %+v", read, entries)
+	}
+}
+
+// =============================================================================
+// Compaction Tests
+// =============================================================================
+
+func TestCompact(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "a.warc.gz")
+	second := filepath.Join(dir, "b.warc.gz")
+	writeRecords(t, first, "a1", "a2")
+	writeRecords(t, second, "b1")
+
+	dest := filepath.Join(dir, "compacted.warc.gz")
+	relocations, err := warc.Compact([]string{first, second}, dest)
+	if err != nil {
+		t.Fatalf("Compact() error = %v", err)
+	}
+	if len(relocations) != 3 {
+		t.Fatalf("Expected 3 relocations, got %d", len(relocations))
+	}
+
+	for _, r := range relocations {
+		old, err := warc.ReadRecordAt(r.OldFile, r.OldOffset)
+		if err != nil {
+			t.Fatalf("ReadRecordAt(old) error = %v", err)
+		}
+		moved, err := warc.ReadRecordAt(r.NewFile, r.NewOffset)
+		if err != nil {
+			t.Fatalf("ReadRecordAt(new) error = %v", err)
+		}
+		if !bytes.Equal(old.Content, moved.Content) {
+			t.Errorf("Relocated content mismatch: %q vs %q", old.Content, moved.Content)
+		}
+		if old.Header.Get(warc.HeaderRecordID) != moved.Header.Get(warc.HeaderRecordID) {
+			t.Error("Expected record ID to be preserved")
+		}
+	}
+
+	if _, err := os.Stat(warc.IndexPath(dest)); err != nil {
+		t.Errorf("Expected index for compacted file: %v", err)
+	}
+}
+
+// =============================================================================
+// CDX Tests
+// =============================================================================
+
+func TestSURT(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.Example.com/a?b=1", "com,example)/a?b=1"},
+		{"http://example.com", "com,example)/"},
+		{"http://sub.example.co.uk:8080/Path", "uk,co,example,sub:8080)/path"},
+	}
+
+	for _, tt := range tests {
+		got, err := warc.SURT(tt.url)
+		if err != nil {
+			t.Fatalf("SURT(%q) error = %v", tt.url, err)
+		}
+		if got != tt.want {
+			t.Errorf("SURT(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestCDXJ_RoundTrip(t *testing.T) {
+	entry := warc.CDXEntry{
+		URLKey:    "com,example)/",
+		Timestamp: time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC),
+		URL:       "https://example.com/",
+		MIME:      "text/html",
+		Status:    200,
+		Filename:  "a.warc.gz",
+		Offset:    42,
+		Length:    100,
+	}
+
+	line, err := warc.FormatCDXJ(entry)
+	if err != nil {
+		t.Fatalf("FormatCDXJ() error = %v", err)
+	}
+	parsed, err := warc.ParseCDXJ(line)
+	if err != nil {
+		t.Fatalf("ParseCDXJ() error = %v", err)
+	}
+	if parsed != entry {
+		t.Errorf("ParseCDXJ() = %+v, want %+v", parsed, entry)
+	}
+}
+
+func TestParseCDXTimestamp(t *testing.T) {
+	got, err := warc.ParseCDXTimestamp("202610")
+	if err != nil {
+		t.Fatalf("ParseCDXTimestamp() error = %v", err)
+	}
+	if want := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("ParseCDXTimestamp() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"", "20", "2026101", "abcd"} {
+		if _, err := warc.ParseCDXTimestamp(bad); err == nil {
+			t.Errorf("ParseCDXTimestamp(%q) expected error", bad)
+		}
+	}
+}
+
+func TestWriter_EnableCDX(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "test.warc.gz")
+
+	w, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.EnableCDX(); err != nil {
+		t.Fatalf("EnableCDX() error = %v", err)
+	}
+
+	info := warc.NewRecord(warc.TypeWarcinfo, "")
+	if _, _, err := w.WriteRecord(info); err != nil {
+		t.Fatalf("WriteRecord() error = %v", err)
+	}
+	for _, body := range []string{"v1", "v2"} {
+		rec := warc.NewRecord(warc.TypeResource, "https://example.com/page")
+		rec.Header.Set(warc.HeaderContentType, "text/html")
+		rec.Content = []byte(body)
+		if _, _, err := w.WriteRecord(rec); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+	}
+	_ = w.Close()
+
+	index := warc.NewCDXIndex(dir)
+	if err := index.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if index.Len() != 2 {
+		t.Fatalf("Expected warcinfo to be skipped and 2 captures indexed, got %d", index.Len())
+	}
+
+	entries, err := index.Lookup("https://www.example.com/page")
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Lookup() = %v, %v", entries, err)
+	}
+	if entries[0].MIME != "text/html" || entries[0].Status != 200 {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+
+	rec, err := index.Open(entries[1])
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	if string(rec.Content) != "v2" {
+		t.Errorf("Content = %q, want %q", rec.Content, "v2")
+	}
+}
+
+func TestBuildCDX_ResponseRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	w, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	rec := warc.NewRecord(warc.TypeResponse, "https://example.com/missing")
+	rec.Content = []byte("HTTP/1.1 404 Not Found\r\nContent-Type: text/plain\r\nContent-Length: 2\r\n\r\nno")
+	if _, _, err := w.WriteRecord(rec); err != nil {
+		t.Fatalf("WriteRecord() error = %v", err)
+	}
+	_ = w.Close()
+
+	entries, err := warc.WriteCDXFile(path)
+	if err != nil {
+		t.Fatalf("WriteCDXFile() error = %v", err)
+	}
+	if len(entries) != 1 || entries[0].Status != 404 || entries[0].MIME != "text/plain" {
+		t.Errorf("Unexpected entries: %+v", entries)
+	}
+	if _, err := os.Stat(warc.CDXPath(path)); err != nil {
+		t.Errorf("Expected CDX file: %v", err)
+	}
+}
+
+func TestCDXIndex_Closest(t *testing.T) {
+	index := warc.NewCDXIndex(t.TempDir())
+	key, _ := warc.SURT("https://example.com/")
+	for _, day := range []int{1, 10, 20} {
+		index.Add(warc.CDXEntry{
+			URLKey:    key,
+			Timestamp: time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC),
+			URL:       "https://example.com/",
+			Offset:    int64(day),
+		})
+	}
+
+	entry, found, err := index.Closest("https://example.com/", time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC))
+	if err != nil || !found {
+		t.Fatalf("Closest() = %v, %v", found, err)
+	}
+	if entry.Offset != 10 {
+		t.Errorf("Closest() offset = %d, want 10", entry.Offset)
+	}
+
+	if _, found, _ := index.Closest("https://other.com/", time.Now()); found {
+		t.Error("Expected no capture for unknown URL")
+	}
+}
+
+// =============================================================================
+// Dedup Tests
+// =============================================================================
+
+func TestPayloadDigest(t *testing.T) {
+	// Known SHA-1 of the empty string in base32
+	if got := warc.PayloadDigest(nil); got != "sha1:3I42H3S6NNFQ2MSVX7XZKYAYSCX5QBYJ" {
+		t.Errorf("PayloadDigest(nil) = %q", got)
+	}
+}
+
+func TestDedupWriter_WritesRevisit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.warc.gz")
+	w, err := warc.NewWriter(path)
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	dedup := warc.NewDedupWriter(w, nil)
+
+	first := warc.NewRecord(warc.TypeResource, "https://example.com/a")
+	first.Content = []byte("same body")
+	firstOffset, original, revisit, err := dedup.WriteCapture(first)
+	if err != nil || revisit {
+		t.Fatalf("WriteCapture(first) revisit = %v, err = %v", revisit, err)
+	}
+
+	second := warc.NewRecord(warc.TypeResource, "https://example.com/b")
+	second.Content = []byte("same body")
+	secondOffset, capture, revisit, err := dedup.WriteCapture(second)
+	if err != nil || !revisit {
+		t.Fatalf("WriteCapture(second) revisit = %v, err = %v", revisit, err)
+	}
+	if capture != original || capture.Offset != firstOffset {
+		t.Errorf("Expected capture to point to original, got %+v", capture)
+	}
+	_ = w.Close()
+
+	rec, err := warc.ReadRecordAt(path, secondOffset)
+	if err != nil {
+		t.Fatalf("ReadRecordAt() error = %v", err)
+	}
+	if rec.Type() != warc.TypeRevisit {
+		t.Errorf("Type() = %q, want revisit", rec.Type())
+	}
+	if rec.Header.Get(warc.HeaderProfile) != warc.ProfileIdenticalPayload {
+		t.Errorf("Unexpected profile %q", rec.Header.Get(warc.HeaderProfile))
+	}
+	if rec.Header.Get(warc.HeaderRefersTo) != original.RecordID {
+		t.Error("Expected WARC-Refers-To to reference the original record")
+	}
+	if rec.Header.Get(warc.HeaderRefersToTargetURI) != "https://example.com/a" {
+		t.Errorf("Unexpected refers-to URI %q", rec.Header.Get(warc.HeaderRefersToTargetURI))
+	}
+	if len(rec.Content) != 0 {
+		t.Errorf("Expected empty revisit block, got %d bytes", len(rec.Content))
+	}
+}
+
+func TestMemoryDigestStore_Seed(t *testing.T) {
+	store := warc.NewMemoryDigestStore()
+	store.Seed("/archive", []warc.CDXEntry{
+		{URL: "https://example.com/", Digest: "sha1:AAA", Filename: "a.warc.gz", Offset: 10},
+		{URL: "https://example.com/", Digest: "sha1:AAA", MIME: "warc/revisit", Filename: "b.warc.gz", Offset: 5},
+	})
+
+	capture, ok := store.Lookup("sha1:AAA")
+	if !ok {
+		t.Fatal("Expected seeded digest")
+	}
+	if capture.File != filepath.Join("/archive", "a.warc.gz") || capture.Offset != 10 {
+		t.Errorf("Unexpected capture %+v", capture)
+	}
+}
+
+func TestMemoryDigestStore_Relocate(t *testing.T) {
+	store := warc.NewMemoryDigestStore()
+	store.Remember("sha1:AAA", warc.Capture{File: "/archive/a.warc.gz", Offset: 10})
+	store.Remember("sha1:BBB", warc.Capture{File: "/archive/b.warc.gz", Offset: 10})
+
+	store.Relocate([]warc.Relocation{
+		{OldFile: "/archive/a.warc.gz", OldOffset: 10, NewFile: "/archive/compacted.warc.gz", NewOffset: 250},
+	})
+
+	if capture, _ := store.Lookup("sha1:AAA"); capture.File != "/archive/compacted.warc.gz" || capture.Offset != 250 {
+		t.Errorf("Expected relocated capture, got %+v", capture)
+	}
+	if capture, _ := store.Lookup("sha1:BBB"); capture.File != "/archive/b.warc.gz" || capture.Offset != 10 {
+		t.Errorf("Expected untouched capture, got %+v", capture)
+	}
+}
+
+func writeDedupArchive(t *testing.T, dir string) *warc.CDXIndex {
+	t.Helper()
+
+	w, err := warc.NewWriter(filepath.Join(dir, "test.warc.gz"))
+	if err != nil {
+		t.Fatalf("NewWriter() error = %v", err)
+	}
+	if err := w.EnableCDX(); err != nil {
+		t.Fatalf("EnableCDX() error = %v", err)
+	}
+	dedup := warc.NewDedupWriter(w, nil)
+	for _, target := range []string{"https://example.com/a", "https://example.com/b"} {
+		rec := warc.NewRecord(warc.TypeResource, target)
+		rec.Header.Set(warc.HeaderContentType, "text/plain")
+		rec.Content = []byte("same body")
+		if _, _, _, err := dedup.WriteCapture(rec); err != nil {
+			t.Fatalf("WriteCapture() error = %v", err)
+		}
+	}
+	_ = w.Close()
+
+	index := warc.NewCDXIndex(dir)
+	if err := index.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	return index
+}
+
+func TestCDXIndex_Resolve_FollowsRevisit(t *testing.T) {
+	index := writeDedupArchive(t, t.TempDir())
+
+	entry, found, err := index.Closest("https://example.com/b", time.Now())
+	if err != nil || !found {
+		t.Fatalf("Closest() found = %v, err = %v", found, err)
+	}
+	if entry.MIME != "warc/revisit" {
+		t.Fatalf("Expected a revisit entry, got %q", entry.MIME)
+	}
+
+	original, rec, err := index.Resolve(entry)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if original.URL != "https://example.com/a" || original.MIME != "text/plain" {
+		t.Errorf("Unexpected original entry %+v", original)
+	}
+	if string(rec.Content) != "same body" {
+		t.Errorf("Content = %q", rec.Content)
+	}
+}
+
+func TestCDXIndex_Resolve_MissingOriginal(t *testing.T) {
+	dir := t.TempDir()
+	index := writeDedupArchive(t, dir)
+
+	entry, _, _ := index.Closest("https://example.com/b", time.Now())
+	original, _, _ := index.Closest("https://example.com/a", time.Now())
+
+	// An index without the original capture cannot resolve the revisit
+	partial := warc.NewCDXIndex(dir)
+	partial.Add(entry)
+	if _, _, err := partial.Resolve(entry); !errors.Is(err, warc.ErrUnresolvedRevisit) {
+		t.Errorf("Resolve() error = %v, want ErrUnresolvedRevisit", err)
+	}
+
+	partial.Add(original)
+	if _, _, err := partial.Resolve(entry); err != nil {
+		t.Errorf("Resolve() error = %v", err)
+	}
+}
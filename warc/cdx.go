@@ -0,0 +1,407 @@
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CDXTimestampFormat is the 14-digit timestamp layout used in CDX(J) lines
+const CDXTimestampFormat = "20060102150405"
+
+// CDXEntry maps a URL and capture time to a record location
+type CDXEntry struct {
+	URLKey    string    `json:"-"`
+	Timestamp time.Time `json:"-"`
+	URL       string    `json:"url"`
+	MIME      string    `json:"mime,omitempty"`
+	Status    int       `json:"status,omitempty"`
+	Digest    string    `json:"digest,omitempty"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	Filename  string    `json:"filename"`
+}
+
+// CDXPath returns the path of the CDXJ index kept next to a WARC file
+func CDXPath(warcPath string) string {
+	return warcPath + ".cdxj"
+}
+
+// SURT returns the sort-friendly URL key used by CDX indexes
+// e.g. https://www.Example.com/a?b=1 becomes com,example)/a?b=1
+func SURT(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	parts := strings.Split(host, ".")
+	for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+		parts[i], parts[j] = parts[j], parts[i]
+	}
+
+	key := strings.Join(parts, ",")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		key += ":" + port
+	}
+
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	key += ")" + strings.ToLower(path)
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, nil
+}
+
+// cdxEntryFor builds the CDX entry for a record, or returns false for records
+// that are not replayable captures
+func cdxEntryFor(rec *Record, filename string, offset, length int64) (CDXEntry, bool) {
+	switch rec.Type() {
+	case TypeResponse, TypeResource, TypeRevisit:
+	default:
+		return CDXEntry{}, false
+	}
+
+	key, err := SURT(rec.TargetURI())
+	if err != nil || rec.TargetURI() == "" {
+		return CDXEntry{}, false
+	}
+	date, _ := rec.Date() // Zero timestamps still index
+
+	entry := CDXEntry{
+		URLKey:    key,
+		Timestamp: date,
+		URL:       rec.TargetURI(),
+		Digest:    rec.Header.Get(HeaderPayloadDigest),
+		Length:    length,
+		Offset:    offset,
+		Filename:  filename,
+	}
+
+	if rec.Type() == TypeResponse {
+		if resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Content)), nil); err == nil {
+			entry.Status = resp.StatusCode
+			entry.MIME = resp.Header.Get("Content-Type")
+			_ = resp.Body.Close() // Error intentionally ignored on close
+		}
+	} else {
+		entry.MIME = rec.Header.Get(HeaderContentType)
+		if rec.Type() == TypeResource {
+			entry.Status = http.StatusOK
+		}
+	}
+	if rec.Type() == TypeRevisit {
+		entry.MIME = "warc/revisit"
+	}
+	return entry, true
+}
+
+// BuildCDX scans a WARC file and returns CDX entries sorted by URL key and timestamp
+func BuildCDX(path string) ([]CDXEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // Error intentionally ignored on close
+	}()
+
+	var entries []CDXEntry
+	reader := NewReader(file)
+	for {
+		rec, offset, length, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return entries, err
+		}
+		if entry, ok := cdxEntryFor(rec, filepath.Base(path), offset, length); ok {
+			entries = append(entries, entry)
+		}
+	}
+
+	SortCDX(entries)
+	return entries, nil
+}
+
+// SortCDX sorts entries by URL key then timestamp
+func SortCDX(entries []CDXEntry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].URLKey != entries[j].URLKey {
+			return entries[i].URLKey < entries[j].URLKey
+		}
+		return entries[i].Timestamp.Before(entries[j].Timestamp)
+	})
+}
+
+// FormatCDXJ formats an entry as a single CDXJ line without the trailing newline
+func FormatCDXJ(entry CDXEntry) (string, error) {
+	block, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+	return entry.URLKey + " " + entry.Timestamp.UTC().Format(CDXTimestampFormat) + " " + string(block), nil
+}
+
+// ParseCDXJ parses a single CDXJ line
+func ParseCDXJ(line string) (CDXEntry, error) {
+	key, rest, ok := strings.Cut(line, " ")
+	if !ok {
+		return CDXEntry{}, fmt.Errorf("malformed CDXJ line: %q", line)
+	}
+	stamp, block, ok := strings.Cut(rest, " ")
+	if !ok {
+		return CDXEntry{}, fmt.Errorf("malformed CDXJ line: %q", line)
+	}
+
+	var entry CDXEntry
+	if err := json.Unmarshal([]byte(block), &entry); err != nil {
+		return CDXEntry{}, fmt.Errorf("malformed CDXJ block: %w", err)
+	}
+	ts, err := time.Parse(CDXTimestampFormat, stamp)
+	if err != nil {
+		return CDXEntry{}, fmt.Errorf("malformed CDXJ timestamp: %q", stamp)
+	}
+
+	entry.URLKey = key
+	entry.Timestamp = ts
+	return entry, nil
+}
+
+// WriteCDXJ writes entries as CDXJ lines
+func WriteCDXJ(w io.Writer, entries []CDXEntry) error {
+	bw := bufio.NewWriter(w)
+	for _, entry := range entries {
+		line, err := FormatCDXJ(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := bw.WriteString(line + "\n"); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadCDXJ reads CDXJ lines, skipping blank lines
+func ReadCDXJ(r io.Reader) ([]CDXEntry, error) {
+	var entries []CDXEntry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		entry, err := ParseCDXJ(line)
+		if err != nil {
+			return entries, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// WriteCDXFile builds the CDXJ index for a WARC file and writes it alongside
+func WriteCDXFile(warcPath string) ([]CDXEntry, error) {
+	entries, err := BuildCDX(warcPath)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp := CDXPath(warcPath) + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CDX index: %w", err)
+	}
+	if err := WriteCDXJ(file, entries); err != nil {
+		_ = file.Close() // Best effort cleanup
+		return nil, fmt.Errorf("failed to write CDX index: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to close CDX index: %w", err)
+	}
+	return entries, os.Rename(tmp, CDXPath(warcPath))
+}
+
+// CDXIndex is an in-memory, sorted CDX index over a directory of WARC files
+type CDXIndex struct {
+	dir     string
+	entries []CDXEntry
+	mu      sync.RWMutex
+}
+
+// NewCDXIndex creates an empty index for WARC files in dir
+func NewCDXIndex(dir string) *CDXIndex {
+	return &CDXIndex{dir: dir}
+}
+
+// Load reads every .cdxj file in the index directory, replacing the current entries
+func (x *CDXIndex) Load() error {
+	matches, err := filepath.Glob(filepath.Join(x.dir, "*.cdxj"))
+	if err != nil {
+		return fmt.Errorf("failed to list CDX files: %w", err)
+	}
+
+	var entries []CDXEntry
+	for _, path := range matches {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open CDX file: %w", err)
+		}
+		read, err := ReadCDXJ(file)
+		_ = file.Close() // Error intentionally ignored on close
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		entries = append(entries, read...)
+	}
+	SortCDX(entries)
+
+	x.mu.Lock()
+	x.entries = entries
+	x.mu.Unlock()
+	return nil
+}
+
+// Add inserts entries into the index
+func (x *CDXIndex) Add(entries ...CDXEntry) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.entries = append(x.entries, entries...)
+	SortCDX(x.entries)
+}
+
+// Len returns the number of indexed captures
+func (x *CDXIndex) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.entries)
+}
+
+// Lookup returns all captures of a URL ordered by timestamp
+func (x *CDXIndex) Lookup(rawURL string) ([]CDXEntry, error) {
+	key, err := SURT(rawURL)
+	if err != nil {
+		return nil, err
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	start := sort.Search(len(x.entries), func(i int) bool {
+		return x.entries[i].URLKey >= key
+	})
+	var matches []CDXEntry
+	for i := start; i < len(x.entries) && x.entries[i].URLKey == key; i++ {
+		matches = append(matches, x.entries[i])
+	}
+	return matches, nil
+}
+
+// Closest returns the capture of a URL nearest to the given time
+func (x *CDXIndex) Closest(rawURL string, at time.Time) (CDXEntry, bool, error) {
+	matches, err := x.Lookup(rawURL)
+	if err != nil || len(matches) == 0 {
+		return CDXEntry{}, false, err
+	}
+
+	best := matches[0]
+	bestDelta := absDuration(best.Timestamp.Sub(at))
+	for _, entry := range matches[1:] {
+		if delta := absDuration(entry.Timestamp.Sub(at)); delta < bestDelta {
+			best, bestDelta = entry, delta
+		}
+	}
+	return best, true, nil
+}
+
+// Open reads the record an entry points to
+func (x *CDXIndex) Open(entry CDXEntry) (*Record, error) {
+	return ReadRecordAt(filepath.Join(x.dir, filepath.Base(entry.Filename)), entry.Offset)
+}
+
+// ErrUnresolvedRevisit is returned when the original capture of a revisit record is not indexed
+var ErrUnresolvedRevisit = errors.New("original capture of revisit record not found")
+
+// Resolve reads the record an entry points to, following revisit records to
+// the capture holding their payload. The original is matched by
+// WARC-Refers-To, or by WARC-Refers-To-Target-URI and date when the record
+// ID is absent, and must carry the same payload digest
+func (x *CDXIndex) Resolve(entry CDXEntry) (CDXEntry, *Record, error) {
+	rec, err := x.Open(entry)
+	if err != nil || rec.Type() != TypeRevisit {
+		return entry, rec, err
+	}
+
+	target := rec.Header.Get(HeaderRefersToTargetURI)
+	if target == "" {
+		target = rec.TargetURI()
+	}
+	candidates, err := x.Lookup(target)
+	if err != nil {
+		return entry, nil, err
+	}
+
+	// Try the capture closest to the referred date first
+	at := entry.Timestamp
+	if date, err := time.Parse(time.RFC3339, rec.Header.Get(HeaderRefersToDate)); err == nil {
+		at = date
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return absDuration(candidates[i].Timestamp.Sub(at)) < absDuration(candidates[j].Timestamp.Sub(at))
+	})
+
+	refersTo := rec.Header.Get(HeaderRefersTo)
+	for _, candidate := range candidates {
+		if candidate.MIME == "warc/revisit" || (entry.Digest != "" && candidate.Digest != entry.Digest) {
+			continue
+		}
+		original, err := x.Open(candidate)
+		if err != nil {
+			continue // Try the next capture
+		}
+		if refersTo != "" && original.Header.Get(HeaderRecordID) != refersTo {
+			continue
+		}
+		return candidate, original, nil
+	}
+	return entry, nil, ErrUnresolvedRevisit
+}
+
+// absDuration returns the absolute value of d
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// ParseCDXTimestamp parses a (possibly truncated) 14-digit timestamp
+// Missing trailing digits default to the earliest value, e.g. "2026" is 2026-01-01
+func ParseCDXTimestamp(value string) (time.Time, error) {
+	if len(value) < 4 || len(value) > 14 || len(value)%2 != 0 {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %q", value)
+	}
+	if _, err := strconv.ParseUint(value, 10, 64); err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp: %q", value)
+	}
+	return time.Parse(CDXTimestampFormat, value+"0101000000"[len(value)-4:])
+}
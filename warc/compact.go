@@ -0,0 +1,80 @@
+package warc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Relocation maps a record's location before compaction to its new location
+type Relocation struct {
+	OldFile   string
+	OldOffset int64
+	NewFile   string
+	NewOffset int64
+}
+
+// Compact copies every record of the source files into dest and writes its offset and CDX indexes
+// Sources are left in place so callers can update references before removing them
+func Compact(sources []string, dest string) ([]Relocation, error) {
+	writer, err := NewWriter(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	var relocations []Relocation
+	for _, source := range sources {
+		moved, err := copyRecords(source, writer)
+		relocations = append(relocations, moved...)
+		if err != nil {
+			_ = writer.Close() // Best effort cleanup
+			return relocations, fmt.Errorf("failed to compact %s: %w", source, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return relocations, fmt.Errorf("failed to close compacted file: %w", err)
+	}
+	if _, err := RebuildIndex(dest); err != nil {
+		return relocations, err
+	}
+	if _, err := WriteCDXFile(dest); err != nil {
+		return relocations, err
+	}
+	return relocations, nil
+}
+
+// copyRecords appends every record of source to writer
+func copyRecords(source string, writer *Writer) ([]Relocation, error) {
+	file, err := os.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // Error intentionally ignored on close
+	}()
+
+	var relocations []Relocation
+	reader := NewReader(file)
+	for {
+		rec, offset, _, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return relocations, nil
+		}
+		if err != nil {
+			return relocations, err
+		}
+
+		newOffset, _, err := writer.WriteRecord(rec)
+		if err != nil {
+			return relocations, err
+		}
+		relocations = append(relocations, Relocation{
+			OldFile:   source,
+			OldOffset: offset,
+			NewFile:   writer.Path(),
+			NewOffset: newOffset,
+		})
+	}
+}
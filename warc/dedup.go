@@ -0,0 +1,174 @@
+package warc
+
+import (
+	"crypto/sha1"
+	"encoding/base32"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Revisit profile and header names defined by WARC 1.1
+const (
+	ProfileIdenticalPayload = "http://netpreserve.org/warc/1.1/revisit/identical-payload-digest"
+
+	HeaderProfile           = "WARC-Profile"
+	HeaderRefersTo          = "WARC-Refers-To"
+	HeaderRefersToTargetURI = "WARC-Refers-To-Target-URI"
+	HeaderRefersToDate      = "WARC-Refers-To-Date"
+)
+
+// Capture identifies a previously archived payload
+type Capture struct {
+	RecordID  string
+	TargetURI string
+	Date      string
+	File      string
+	Offset    int64
+}
+
+// DigestStore remembers which payload digests have already been archived
+type DigestStore interface {
+	// Lookup returns the original capture of a payload digest
+	Lookup(digest string) (Capture, bool)
+
+	// Remember records the capture holding a payload digest
+	Remember(digest string, capture Capture)
+}
+
+// MemoryDigestStore keeps payload digests in memory
+type MemoryDigestStore struct {
+	captures map[string]Capture
+	mu       sync.RWMutex
+}
+
+// NewMemoryDigestStore creates an empty in-memory digest store
+func NewMemoryDigestStore() *MemoryDigestStore {
+	return &MemoryDigestStore{captures: make(map[string]Capture)}
+}
+
+// Lookup returns the original capture of a payload digest
+func (s *MemoryDigestStore) Lookup(digest string) (Capture, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	capture, ok := s.captures[digest]
+	return capture, ok
+}
+
+// Remember records the capture holding a payload digest, keeping the first one seen
+func (s *MemoryDigestStore) Remember(digest string, capture Capture) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.captures[digest]; !exists {
+		s.captures[digest] = capture
+	}
+}
+
+// Seed loads digests from existing CDX entries so recrawls dedup against older archives
+// dir is the directory holding the WARC files the entries point to
+func (s *MemoryDigestStore) Seed(dir string, entries []CDXEntry) {
+	for _, e := range entries {
+		if e.Digest == "" || e.MIME == "warc/revisit" {
+			continue
+		}
+		s.Remember(e.Digest, Capture{
+			TargetURI: e.URL,
+			Date:      e.Timestamp.UTC().Format(time.RFC3339),
+			File:      filepath.Join(dir, filepath.Base(e.Filename)),
+			Offset:    e.Offset,
+		})
+	}
+}
+
+// Relocate points captures moved by compaction at their new file and offset
+func (s *MemoryDigestStore) Relocate(relocations []Relocation) {
+	type location struct {
+		file   string
+		offset int64
+	}
+	moved := make(map[location]Relocation, len(relocations))
+	for _, r := range relocations {
+		moved[location{filepath.Clean(r.OldFile), r.OldOffset}] = r
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for digest, capture := range s.captures {
+		if r, ok := moved[location{filepath.Clean(capture.File), capture.Offset}]; ok {
+			capture.File, capture.Offset = r.NewFile, r.NewOffset
+			s.captures[digest] = capture
+		}
+	}
+}
+
+// PayloadDigest returns the WARC payload digest ("sha1:<base32>") of content
+func PayloadDigest(content []byte) string {
+	sum := sha1.Sum(content) // SHA-1 is the digest WARC tooling expects; not used for security
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// DedupWriter writes captures, replacing repeated payloads with revisit records
+type DedupWriter struct {
+	writer *Writer
+	store  DigestStore
+}
+
+// NewDedupWriter wraps a writer with payload-digest deduplication
+func NewDedupWriter(writer *Writer, store DigestStore) *DedupWriter {
+	if store == nil {
+		store = NewMemoryDigestStore()
+	}
+	return &DedupWriter{
+		writer: writer,
+		store:  store,
+	}
+}
+
+// WriteCapture writes rec, or a revisit record if its payload was archived before
+// The payload digest is computed from rec.Content. It returns the offset of the
+// written record and the capture holding the full payload.
+func (d *DedupWriter) WriteCapture(rec *Record) (int64, Capture, bool, error) {
+	digest := PayloadDigest(rec.Content)
+
+	if original, ok := d.store.Lookup(digest); ok {
+		revisit := NewRecord(TypeRevisit, rec.TargetURI())
+		revisit.Header.Set(HeaderProfile, ProfileIdenticalPayload)
+		revisit.Header.Set(HeaderPayloadDigest, digest)
+		if original.RecordID != "" {
+			revisit.Header.Set(HeaderRefersTo, original.RecordID)
+		}
+		if original.TargetURI != "" {
+			revisit.Header.Set(HeaderRefersToTargetURI, original.TargetURI)
+		}
+		if original.Date != "" {
+			revisit.Header.Set(HeaderRefersToDate, original.Date)
+		}
+
+		offset, _, err := d.writer.WriteRecord(revisit)
+		if err != nil {
+			return 0, Capture{}, false, err
+		}
+		return offset, original, true, nil
+	}
+
+	rec.Header.Set(HeaderPayloadDigest, digest)
+	file, offset, err := d.writer.AppendRecord(rec)
+	if err != nil {
+		return 0, Capture{}, false, err
+	}
+
+	capture := Capture{
+		RecordID:  rec.Header.Get(HeaderRecordID),
+		TargetURI: rec.TargetURI(),
+		Date:      rec.Header.Get(HeaderDate),
+		File:      file,
+		Offset:    offset,
+	}
+	d.store.Remember(digest, capture)
+	return offset, capture, false, nil
+}
+
+// Writer returns the underlying WARC writer
+func (d *DedupWriter) Writer() *Writer {
+	return d.writer
+}
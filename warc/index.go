@@ -0,0 +1,123 @@
+package warc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// IndexEntry locates a single record within a WARC file
+type IndexEntry struct {
+	URI      string    `json:"uri"`
+	Type     string    `json:"type"`
+	Date     time.Time `json:"date"`
+	RecordID string    `json:"record_id"`
+	File     string    `json:"file"`
+	Offset   int64     `json:"offset"`
+	Length   int64     `json:"length"`
+}
+
+// IndexPath returns the path of the offset index kept next to a WARC file
+func IndexPath(warcPath string) string {
+	return warcPath + ".idx"
+}
+
+// BuildIndex scans a WARC file and returns the location of every record
+func BuildIndex(path string) ([]IndexEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // Error intentionally ignored on close
+	}()
+
+	var entries []IndexEntry
+	reader := NewReader(file)
+	for {
+		rec, offset, length, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return entries, err
+		}
+
+		date, _ := rec.Date() // Records with unparsable dates are still indexed
+		entries = append(entries, IndexEntry{
+			URI:      rec.TargetURI(),
+			Type:     rec.Type(),
+			Date:     date,
+			RecordID: rec.Header.Get(HeaderRecordID),
+			File:     path,
+			Offset:   offset,
+			Length:   length,
+		})
+	}
+}
+
+// WriteIndex writes index entries as JSON lines, replacing the file atomically
+func WriteIndex(path string, entries []IndexEntry) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create index: %w", err)
+	}
+
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			_ = file.Close() // Best effort cleanup
+			return fmt.Errorf("failed to write index: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		_ = file.Close() // Best effort cleanup
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close index: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadIndex reads index entries written by WriteIndex
+func ReadIndex(path string) ([]IndexEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open index: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // Error intentionally ignored on close
+	}()
+
+	var entries []IndexEntry
+	dec := json.NewDecoder(file)
+	for {
+		var entry IndexEntry
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				return entries, nil
+			}
+			return entries, fmt.Errorf("failed to parse index: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// RebuildIndex scans a WARC file and rewrites its offset index
+func RebuildIndex(warcPath string) ([]IndexEntry, error) {
+	entries, err := BuildIndex(warcPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := WriteIndex(IndexPath(warcPath), entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,128 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Reader iterates over the records of a gzip-per-record WARC stream
+type Reader struct {
+	src    *countingReader
+	gz     *gzip.Reader
+	offset int64
+}
+
+// NewReader creates a reader over a .warc.gz stream
+func NewReader(r io.Reader) *Reader {
+	return &Reader{
+		src: &countingReader{r: bufio.NewReader(r)},
+	}
+}
+
+// Next returns the next record with its offset and compressed length
+// Returns io.EOF when the stream is exhausted
+func (r *Reader) Next() (*Record, int64, int64, error) {
+	offset := r.src.n
+
+	if _, err := r.src.r.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, 0, 0, io.EOF
+		}
+		return nil, 0, 0, err
+	}
+
+	var err error
+	if r.gz == nil {
+		r.gz, err = gzip.NewReader(r.src)
+	} else {
+		err = r.gz.Reset(r.src)
+	}
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("invalid gzip member at offset %d: %w", offset, err)
+	}
+	r.gz.Multistream(false)
+
+	rec, err := readRecord(bufio.NewReader(r.gz))
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("invalid record at offset %d: %w", offset, err)
+	}
+
+	// Drain the rest of the member so the next offset is exact
+	if _, err := io.Copy(io.Discard, r.gz); err != nil {
+		return nil, 0, 0, err
+	}
+
+	return rec, offset, r.src.n - offset, nil
+}
+
+// ReadRecordAt reads the single record stored at offset in a WARC file
+func ReadRecordAt(path string, offset int64) (*Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // Error intentionally ignored on close
+	}()
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+	}
+
+	rec, _, _, err := NewReader(file).Next()
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// ReadAll reads every record of a WARC file
+func ReadAll(path string) ([]*Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+	defer func() {
+		_ = file.Close() // Error intentionally ignored on close
+	}()
+
+	var records []*Record
+	reader := NewReader(file)
+	for {
+		rec, _, _, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+	}
+}
+
+// countingReader tracks how many bytes were consumed
+// It implements io.ByteReader so gzip never reads past a member boundary
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+// Read implements io.Reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadByte implements io.ByteReader
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
@@ -0,0 +1,191 @@
+package warc
+
+import (
+	"bufio"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WARC format version written by this package
+const Version = "WARC/1.1"
+
+// Record types used by golwarc
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeResponse = "response"
+	TypeResource = "resource"
+	TypeRequest  = "request"
+	TypeRevisit  = "revisit"
+)
+
+// Common WARC header names
+const (
+	HeaderType          = "WARC-Type"
+	HeaderRecordID      = "WARC-Record-ID"
+	HeaderDate          = "WARC-Date"
+	HeaderTargetURI     = "WARC-Target-URI"
+	HeaderPayloadDigest = "WARC-Payload-Digest"
+	HeaderContentType   = "Content-Type"
+	HeaderContentLength = "Content-Length"
+)
+
+// Header holds WARC named fields, preserving insertion order
+// Lookups are case-insensitive as required by the WARC specification
+type Header struct {
+	names  []string
+	values map[string]string
+}
+
+// Set sets a header field, replacing any existing value
+func (h *Header) Set(name, value string) {
+	if h.values == nil {
+		h.values = make(map[string]string)
+	}
+	key := strings.ToLower(name)
+	if _, exists := h.values[key]; !exists {
+		h.names = append(h.names, name)
+	}
+	h.values[key] = value
+}
+
+// Get returns a header field value (empty if absent)
+func (h *Header) Get(name string) string {
+	return h.values[strings.ToLower(name)]
+}
+
+// Names returns header names in insertion order
+func (h *Header) Names() []string {
+	return append([]string(nil), h.names...)
+}
+
+// Record is a single WARC record
+type Record struct {
+	Header  Header
+	Content []byte
+}
+
+// NewRecord creates a record with a fresh record ID and the current date
+func NewRecord(recordType, targetURI string) *Record {
+	rec := &Record{}
+	rec.Header.Set(HeaderType, recordType)
+	rec.Header.Set(HeaderRecordID, NewRecordID())
+	rec.Header.Set(HeaderDate, time.Now().UTC().Format(time.RFC3339))
+	if targetURI != "" {
+		rec.Header.Set(HeaderTargetURI, targetURI)
+	}
+	return rec
+}
+
+// Type returns the WARC-Type of the record
+func (r *Record) Type() string {
+	return r.Header.Get(HeaderType)
+}
+
+// TargetURI returns the WARC-Target-URI of the record
+func (r *Record) TargetURI() string {
+	return r.Header.Get(HeaderTargetURI)
+}
+
+// Date returns the parsed WARC-Date of the record
+func (r *Record) Date() (time.Time, error) {
+	return time.Parse(time.RFC3339, r.Header.Get(HeaderDate))
+}
+
+// WriteTo serializes the record in WARC format
+func (r *Record) WriteTo(w io.Writer) (int64, error) {
+	r.Header.Set(HeaderContentLength, strconv.Itoa(len(r.Content)))
+
+	var sb strings.Builder
+	sb.WriteString(Version + "\r\n")
+	for _, name := range r.Header.names {
+		sb.WriteString(name + ": " + r.Header.Get(name) + "\r\n")
+	}
+	sb.WriteString("\r\n")
+
+	written, err := io.WriteString(w, sb.String())
+	total := int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	written, err = w.Write(r.Content)
+	total += int64(written)
+	if err != nil {
+		return total, err
+	}
+
+	written, err = io.WriteString(w, "\r\n\r\n")
+	total += int64(written)
+	return total, err
+}
+
+// readRecord parses a single uncompressed WARC record
+func readRecord(br *bufio.Reader) (*Record, error) {
+	version, err := readLine(br)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC version line: %q", version)
+	}
+
+	rec := &Record{}
+	for {
+		line, err := readLine(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line: %q", line)
+		}
+		rec.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	length, err := strconv.ParseInt(rec.Header.Get(HeaderContentLength), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length: %q", rec.Header.Get(HeaderContentLength))
+	}
+
+	rec.Content = make([]byte, length)
+	if _, err := io.ReadFull(br, rec.Content); err != nil {
+		return nil, fmt.Errorf("failed to read record block: %w", err)
+	}
+
+	// Consume the two CRLF record separators
+	trailer := make([]byte, 4)
+	if _, err := io.ReadFull(br, trailer); err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, fmt.Errorf("failed to read record trailer: %w", err)
+	}
+
+	return rec, nil
+}
+
+// readLine reads a CRLF or LF terminated line without the terminator
+func readLine(br *bufio.Reader) (string, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		if err == io.EOF && line != "" {
+			return "", io.ErrUnexpectedEOF
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// NewRecordID generates a random urn:uuid record identifier
+func NewRecordID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // crypto/rand never returns an error on supported platforms
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
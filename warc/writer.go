@@ -0,0 +1,242 @@
+package warc
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alonecandies/golwarc/clock"
+)
+
+// Writer appends records to a WARC file
+// Each record is written as its own gzip member so it can be read
+// independently given its offset
+type Writer struct {
+	file     *os.File
+	path     string
+	first    string // Path the writer was opened with; rotated files are named after it
+	offset   int64
+	cdx      *os.File
+	rotation RotationConfig
+	opened   time.Time // When the current file was opened, on the rotation clock
+	mu       sync.Mutex
+}
+
+// RotationConfig holds the limits after which a Writer starts a new file
+// Closed files keep their names, so references to their records stay valid
+type RotationConfig struct {
+	MaxSize int64         // Bytes; a file this large is closed before the next record; 0 disables
+	MaxAge  time.Duration // A file open this long is closed before the next record; 0 disables
+	Clock   clock.Clock   // Defaults to the wall clock
+}
+
+// NewWriter opens (or creates) a .warc.gz file for appending
+func NewWriter(path string) (*Writer, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close() // Best effort cleanup
+		return nil, fmt.Errorf("failed to stat WARC file: %w", err)
+	}
+
+	return &Writer{
+		file:   file,
+		path:   path,
+		first:  path,
+		offset: info.Size(),
+	}, nil
+}
+
+// WriteRecord appends a record and returns its offset and compressed length
+// With rotation enabled, use AppendRecord to learn which file the offset is in
+func (w *Writer) WriteRecord(rec *Record) (int64, int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rotateLocked(); err != nil {
+		return 0, 0, err
+	}
+	return w.writeLocked(rec)
+}
+
+// AppendRecord appends a record and returns the file and offset it was written at
+func (w *Writer) AppendRecord(rec *Record) (string, int64, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rotateLocked(); err != nil {
+		return "", 0, err
+	}
+	offset, _, err := w.writeLocked(rec)
+	return w.path, offset, err
+}
+
+// writeLocked writes a record to the current file; w.mu must be held
+func (w *Writer) writeLocked(rec *Record) (int64, int64, error) {
+	offset := w.offset
+	counter := &countingWriter{w: w.file}
+
+	gz := gzip.NewWriter(counter)
+	if _, err := rec.WriteTo(gz); err != nil {
+		return 0, 0, fmt.Errorf("failed to write record: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return 0, 0, fmt.Errorf("failed to flush record: %w", err)
+	}
+
+	w.offset += counter.n
+
+	if w.cdx != nil {
+		if entry, ok := cdxEntryFor(rec, filepath.Base(w.path), offset, counter.n); ok {
+			line, err := FormatCDXJ(entry)
+			if err == nil {
+				_, err = w.cdx.WriteString(line + "\n")
+			}
+			if err != nil {
+				return offset, counter.n, fmt.Errorf("failed to write CDX entry: %w", err)
+			}
+		}
+	}
+
+	return offset, counter.n, nil
+}
+
+// EnableCDX appends a CDXJ line to CDXPath(w.Path()) for every capture written
+// Lines are in write order; CDXIndex sorts them on load
+func (w *Writer) EnableCDX() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cdx != nil {
+		return nil
+	}
+	file, err := os.OpenFile(CDXPath(w.path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open CDX index: %w", err)
+	}
+	w.cdx = file
+	return nil
+}
+
+// SetRotation closes the current file and continues in a new one once a limit is reached
+// New files sit next to the first one, named after it with a timestamp,
+// e.g. pages-20250101120000.warc.gz
+func (w *Writer) SetRotation(config RotationConfig) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	config.Clock = clock.Or(config.Clock)
+	w.rotation = config
+	w.opened = config.Clock.Now()
+}
+
+// rotateLocked starts a new file when the current one is due; w.mu must be held
+func (w *Writer) rotateLocked() error {
+	r := w.rotation
+	if w.offset == 0 {
+		return nil // Never leave an empty file behind
+	}
+	due := (r.MaxSize > 0 && w.offset >= r.MaxSize) || (r.MaxAge > 0 && r.Clock.Since(w.opened) >= r.MaxAge)
+	if !due {
+		return nil
+	}
+
+	now := r.Clock.Now()
+	file, path, err := createRotated(w.first, now)
+	if err != nil {
+		return err
+	}
+	var cdx *os.File
+	if w.cdx != nil {
+		cdx, err = os.OpenFile(CDXPath(path), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			_ = file.Close()    // Best effort cleanup
+			_ = os.Remove(path) // Keep writing the current file
+			return fmt.Errorf("failed to open CDX index: %w", err)
+		}
+	}
+	if err := w.file.Close(); err != nil {
+		_ = file.Close() // Best effort cleanup
+		if cdx != nil {
+			_ = cdx.Close() // Best effort cleanup
+		}
+		return fmt.Errorf("failed to close rotated WARC file: %w", err)
+	}
+	if w.cdx != nil {
+		_ = w.cdx.Close() // Error intentionally ignored; the WARC file is authoritative
+	}
+
+	w.file, w.path, w.offset, w.cdx, w.opened = file, path, 0, cdx, now
+	return nil
+}
+
+// createRotated creates a new file named after first with the time, and a
+// counter when that name is taken
+func createRotated(first string, now time.Time) (*os.File, string, error) {
+	dir, name := filepath.Split(first)
+	stem := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ".warc")
+	stamp := now.UTC().Format(CDXTimestampFormat)
+
+	for n := 0; ; n++ {
+		path := filepath.Join(dir, fmt.Sprintf("%s-%s.warc.gz", stem, stamp))
+		if n > 0 {
+			path = filepath.Join(dir, fmt.Sprintf("%s-%s-%d.warc.gz", stem, stamp, n))
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to open WARC file: %w", err)
+		}
+		return file, path, nil
+	}
+}
+
+// Path returns the file path of the WARC file currently written
+func (w *Writer) Path() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.path
+}
+
+// Size returns the current size of the WARC file in bytes
+func (w *Writer) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.offset
+}
+
+// Close flushes and closes the WARC file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.cdx != nil {
+		_ = w.cdx.Close() // Error intentionally ignored; the WARC file is authoritative
+	}
+	return w.file.Close()
+}
+
+// countingWriter counts bytes written through it
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write implements io.Writer
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}